@@ -0,0 +1,208 @@
+// Package config user / im / storage 共用的配置文件加载
+// 配置文件为 YAML 或 JSON（按扩展名 .yaml、.yml、.json 判断），多个模块可共用一个文档，各模块读取自己的顶层段：
+//
+//	im:
+//	  server_id: server-1
+//	user:
+//	  jwt_secret: secret
+//	storage:
+//	  base_url: http://localhost:8080
+//
+// 文档中没有该段时整个文档作为该模块的配置。加载后按字段的 env 标签读取环境变量覆盖文件中的值，便于在不同环境中覆盖密钥等配置
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedFormat 配置文件扩展名不是 .yaml、.yml 或 .json
+var ErrUnsupportedFormat = errors.New("unsupported config file format")
+
+// Load 读取配置文件的 section 段（为空或文档中没有该段时读取整个文档）解码到 v，再按 env 标签用环境变量覆盖
+// v 为结构体指针，字段按 json 标签解码，未知字段返回错误，避免拼写错误的配置被静默忽略
+func Load(path, section string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+
+	if m, ok := doc.(map[string]interface{}); ok && section != "" {
+		if sub, ok := m[section]; ok {
+			doc = sub
+		}
+	}
+	if doc != nil {
+		// YAML 先转为 JSON，两种格式共用 json 标签和 Duration 等类型的解码
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("decode %s: %w", path, err)
+		}
+	}
+	return LoadEnv(v)
+}
+
+// LoadEnv 按字段的 env 标签读取环境变量覆盖 v 中的值，未设置的环境变量不影响原值
+// 支持 string、bool、整数、浮点数、Duration 及其指针，嵌套结构体（含指针）递归处理
+func LoadEnv(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: %T is not a struct pointer", v)
+	}
+	return loadEnv(rv.Elem())
+}
+
+// loadEnv 处理结构体的各字段
+func loadEnv(rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			// 嵌套结构体：只有其中设置了环境变量时才创建指针，避免空段变为非 nil
+			switch {
+			case fv.Kind() == reflect.Struct:
+				if err := loadEnv(fv); err != nil {
+					return err
+				}
+			case fv.Kind() == reflect.Pointer && fv.Type().Elem().Kind() == reflect.Struct:
+				target := reflect.New(fv.Type().Elem())
+				if !fv.IsNil() {
+					target.Elem().Set(fv.Elem())
+				}
+				if err := loadEnv(target.Elem()); err != nil {
+					return err
+				}
+				if !fv.IsNil() || !target.Elem().IsZero() {
+					fv.Set(target)
+				}
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer {
+			ptr := reflect.New(fv.Type().Elem())
+			if err := setValue(ptr.Elem(), value); err != nil {
+				return fmt.Errorf("env %s: %w", name, err)
+			}
+			fv.Set(ptr)
+			continue
+		}
+		if err := setValue(fv, value); err != nil {
+			return fmt.Errorf("env %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setValue 将环境变量的文本值写入字段
+func setValue(fv reflect.Value, value string) error {
+	if fv.Type() == reflect.TypeOf(Duration(0)) {
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// Duration 配置文件中的时长，支持 "2h30m" 等 time.ParseDuration 格式，数字按秒解析
+type Duration time.Duration
+
+// UnmarshalJSON 解析时长
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var seconds float64
+		if err := json.Unmarshal(data, &seconds); err != nil {
+			return fmt.Errorf("invalid duration %s", data)
+		}
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	parsed, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// parseDuration 解析时长文本，纯数字按秒解析
+func parseDuration(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
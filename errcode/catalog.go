@@ -0,0 +1,143 @@
+package errcode
+
+import "net/http"
+
+// 错误码分段：通用 10000 起，user 20000 起，im 30000 起，storage 40000 起
+
+// 通用错误码（0 - 19999）
+const (
+	OK              Code = 0
+	Internal        Code = 10000
+	InvalidConfig   Code = 10001
+	InvalidParam    Code = 10002
+	Unauthorized    Code = 10003
+	Forbidden       Code = 10004
+	NotFound        Code = 10005
+	TooManyRequests Code = 10006
+)
+
+// 用户模块错误码（20000 - 29999）
+const (
+	UserNotFound          Code = 20001
+	UserDisabled          Code = 20002
+	PhoneExists           Code = 20003
+	InvalidCredentials    Code = 20004
+	InvalidOldPassword    Code = 20005
+	CodeNotFound          Code = 20006
+	CodeUsed              Code = 20007
+	CodeExpired           Code = 20008
+	InvalidCode           Code = 20009
+	InvalidToken          Code = 20010
+	TokenExpired          Code = 20011
+	OAuthUnsupported      Code = 20012
+	OAuthFailed           Code = 20013
+	OAuthAlreadyBound     Code = 20014
+	OAuthNotBound         Code = 20015
+	TwoFactorDisabled     Code = 20016
+	TwoFactorEnabled      Code = 20017
+	InvalidTwoFactor      Code = 20018
+	LoginLocked           Code = 20019
+	CaptchaRequired       Code = 20020
+	CodeRateLimited       Code = 20021
+	TooManyCodeAttempts   Code = 20022
+	RoleNotFound          Code = 20023
+	RoleExists            Code = 20024
+	PasswordResetRequired Code = 20025
+	UsernameExists        Code = 20026
+	UsernameChangeLimited Code = 20027
+	InvalidCaptcha        Code = 20028
+)
+
+// IM 模块错误码（30000 - 39999）
+const (
+	GroupNotFound         Code = 30001
+	NotGroupMember        Code = 30002
+	GroupPermissionDenied Code = 30003
+	GroupPolicyViolation  Code = 30004
+	MessageNotFound       Code = 30005
+	ReportNotFound        Code = 30006
+
+	SupportConversationNotFound Code = 30007
+	SupportAgentNotFound        Code = 30008
+	FileAccessDenied            Code = 30009
+)
+
+// 存储模块错误码（40000 - 49999）
+const (
+	FileNotFound         Code = 40001
+	FileTooLarge         Code = 40002
+	UnsupportedMimeType  Code = 40003
+	UnknownFileType      Code = 40004
+	InvalidUpload        Code = 40005
+	UploadNotFound       Code = 40006
+	UploadOffsetMismatch Code = 40007
+	UploadIncomplete     Code = 40008
+	QuotaExceeded        Code = 40009
+	MalwareDetected      Code = 40010
+	MimeTypeMismatch     Code = 40011
+	KeyEnvelopeNotFound  Code = 40012
+)
+
+// catalog 内置错误码目录（已发布的错误码不可修改含义）
+var catalog = []Definition{
+	{OK, http.StatusOK, map[string]string{LangZH: "成功", LangEN: "success"}},
+	{Internal, http.StatusInternalServerError, map[string]string{LangZH: "服务器内部错误", LangEN: "internal server error"}},
+	{InvalidConfig, http.StatusInternalServerError, map[string]string{LangZH: "服务配置错误", LangEN: "invalid service configuration"}},
+	{InvalidParam, http.StatusBadRequest, map[string]string{LangZH: "参数错误", LangEN: "invalid parameter"}},
+	{Unauthorized, http.StatusUnauthorized, map[string]string{LangZH: "未登录或登录已失效", LangEN: "unauthorized"}},
+	{Forbidden, http.StatusForbidden, map[string]string{LangZH: "没有权限", LangEN: "forbidden"}},
+	{NotFound, http.StatusNotFound, map[string]string{LangZH: "资源不存在", LangEN: "not found"}},
+	{TooManyRequests, http.StatusTooManyRequests, map[string]string{LangZH: "请求过于频繁，请稍后再试", LangEN: "too many requests"}},
+
+	{UserNotFound, http.StatusNotFound, map[string]string{LangZH: "用户不存在", LangEN: "user not found"}},
+	{UserDisabled, http.StatusForbidden, map[string]string{LangZH: "账号已被禁用", LangEN: "user is disabled"}},
+	{PhoneExists, http.StatusConflict, map[string]string{LangZH: "手机号已注册", LangEN: "phone already exists"}},
+	{InvalidCredentials, http.StatusUnauthorized, map[string]string{LangZH: "账号或密码错误", LangEN: "invalid account or password"}},
+	{InvalidOldPassword, http.StatusBadRequest, map[string]string{LangZH: "原密码错误", LangEN: "invalid old password"}},
+	{CodeNotFound, http.StatusBadRequest, map[string]string{LangZH: "验证码不存在或已过期", LangEN: "verification code not found or expired"}},
+	{CodeUsed, http.StatusBadRequest, map[string]string{LangZH: "验证码已使用", LangEN: "verification code already used"}},
+	{CodeExpired, http.StatusBadRequest, map[string]string{LangZH: "验证码已过期", LangEN: "verification code expired"}},
+	{InvalidCode, http.StatusBadRequest, map[string]string{LangZH: "验证码错误", LangEN: "invalid verification code"}},
+	{InvalidToken, http.StatusUnauthorized, map[string]string{LangZH: "登录凭证无效", LangEN: "invalid token"}},
+	{TokenExpired, http.StatusUnauthorized, map[string]string{LangZH: "登录已过期，请重新登录", LangEN: "token expired"}},
+	{OAuthUnsupported, http.StatusBadRequest, map[string]string{LangZH: "不支持的第三方登录方式", LangEN: "unsupported oauth provider"}},
+	{OAuthFailed, http.StatusUnauthorized, map[string]string{LangZH: "第三方授权失败", LangEN: "oauth authorization failed"}},
+	{OAuthAlreadyBound, http.StatusConflict, map[string]string{LangZH: "第三方账号已被绑定", LangEN: "oauth account already bound"}},
+	{OAuthNotBound, http.StatusNotFound, map[string]string{LangZH: "未绑定该第三方账号", LangEN: "oauth account not bound"}},
+	{TwoFactorDisabled, http.StatusBadRequest, map[string]string{LangZH: "未开启两步验证", LangEN: "two-factor authentication not enabled"}},
+	{TwoFactorEnabled, http.StatusConflict, map[string]string{LangZH: "已开启两步验证", LangEN: "two-factor authentication already enabled"}},
+	{InvalidTwoFactor, http.StatusUnauthorized, map[string]string{LangZH: "两步验证码错误", LangEN: "invalid two-factor code"}},
+	{LoginLocked, http.StatusTooManyRequests, map[string]string{LangZH: "登录失败次数过多，请稍后再试", LangEN: "too many failed login attempts, please try again later"}},
+	{CaptchaRequired, http.StatusForbidden, map[string]string{LangZH: "请先完成人机验证", LangEN: "captcha required"}},
+	{CodeRateLimited, http.StatusTooManyRequests, map[string]string{LangZH: "验证码发送过于频繁，请稍后再试", LangEN: "verification code requested too frequently, please try again later"}},
+	{TooManyCodeAttempts, http.StatusBadRequest, map[string]string{LangZH: "验证码错误次数过多，请重新获取", LangEN: "too many invalid attempts, please request a new verification code"}},
+	{RoleNotFound, http.StatusNotFound, map[string]string{LangZH: "角色不存在", LangEN: "role not found"}},
+	{RoleExists, http.StatusConflict, map[string]string{LangZH: "角色已存在", LangEN: "role already exists"}},
+	{PasswordResetRequired, http.StatusForbidden, map[string]string{LangZH: "需要重置密码，请通过验证码重置后登录", LangEN: "password reset required, please reset your password via verification code"}},
+	{UsernameExists, http.StatusConflict, map[string]string{LangZH: "用户名已被使用", LangEN: "username already exists"}},
+	{UsernameChangeLimited, http.StatusTooManyRequests, map[string]string{LangZH: "用户名修改过于频繁，请稍后再试", LangEN: "username changed too recently, please try again later"}},
+	{InvalidCaptcha, http.StatusForbidden, map[string]string{LangZH: "人机验证失败，请重试", LangEN: "captcha verification failed"}},
+
+	{GroupNotFound, http.StatusNotFound, map[string]string{LangZH: "群组不存在", LangEN: "group not found"}},
+	{NotGroupMember, http.StatusForbidden, map[string]string{LangZH: "不是群成员", LangEN: "not a group member"}},
+	{GroupPermissionDenied, http.StatusForbidden, map[string]string{LangZH: "没有群管理权限", LangEN: "group permission denied"}},
+	{GroupPolicyViolation, http.StatusForbidden, map[string]string{LangZH: "群组设置不允许发送该消息", LangEN: "message not allowed by group policy"}},
+	{MessageNotFound, http.StatusNotFound, map[string]string{LangZH: "消息不存在", LangEN: "message not found"}},
+	{ReportNotFound, http.StatusNotFound, map[string]string{LangZH: "举报不存在或已处理", LangEN: "report not found or already resolved"}},
+	{SupportConversationNotFound, http.StatusNotFound, map[string]string{LangZH: "客服会话不存在或已结束", LangEN: "support conversation not found or closed"}},
+	{SupportAgentNotFound, http.StatusNotFound, map[string]string{LangZH: "客服不存在", LangEN: "support agent not found"}},
+	{FileAccessDenied, http.StatusForbidden, map[string]string{LangZH: "无权在消息中引用该文件", LangEN: "file access denied"}},
+
+	{FileNotFound, http.StatusNotFound, map[string]string{LangZH: "文件不存在", LangEN: "file not found"}},
+	{FileTooLarge, http.StatusRequestEntityTooLarge, map[string]string{LangZH: "文件大小超过限制", LangEN: "file too large"}},
+	{UnsupportedMimeType, http.StatusUnsupportedMediaType, map[string]string{LangZH: "不支持的文件格式", LangEN: "unsupported file format"}},
+	{UnknownFileType, http.StatusBadRequest, map[string]string{LangZH: "未知的文件类型", LangEN: "unknown file type"}},
+	{InvalidUpload, http.StatusBadRequest, map[string]string{LangZH: "上传请求无效", LangEN: "invalid upload request"}},
+	{UploadNotFound, http.StatusNotFound, map[string]string{LangZH: "上传任务不存在或已过期", LangEN: "upload not found or expired"}},
+	{UploadOffsetMismatch, http.StatusConflict, map[string]string{LangZH: "上传位置与已接收的内容不一致", LangEN: "upload offset mismatch"}},
+	{UploadIncomplete, http.StatusBadRequest, map[string]string{LangZH: "文件尚未上传完成", LangEN: "upload incomplete"}},
+	{QuotaExceeded, http.StatusForbidden, map[string]string{LangZH: "存储空间已满", LangEN: "storage quota exceeded"}},
+	{MalwareDetected, http.StatusUnprocessableEntity, map[string]string{LangZH: "文件包含恶意内容", LangEN: "malware detected"}},
+	{MimeTypeMismatch, http.StatusUnsupportedMediaType, map[string]string{LangZH: "文件内容与文件格式不符", LangEN: "file content does not match its type"}},
+	{KeyEnvelopeNotFound, http.StatusNotFound, map[string]string{LangZH: "文件密钥不存在", LangEN: "file key not found"}},
+}
@@ -0,0 +1,235 @@
+// Package errcode 提供 user / im / storage 共用的错误码目录
+// 各模块将导出的错误绑定到稳定的数字错误码，HTTP 层通过 Resolve 得到错误码、HTTP 状态码和本地化提示
+package errcode
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// 内置语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+// DefaultLang 找不到对应语言的提示时使用的语言
+var DefaultLang = LangEN
+
+// Code 错误码
+type Code int
+
+// Definition 错误码定义
+type Definition struct {
+	Code       Code              // 错误码
+	HTTPStatus int               // 对应的 HTTP 状态码
+	Messages   map[string]string // 各语言的提示信息，key 为语言（如 "zh"、"en"）
+}
+
+// APIError 解析后的错误，可直接序列化返回给客户端
+type APIError struct {
+	Code       Code   `json:"code"`    // 错误码
+	Message    string `json:"message"` // 本地化提示信息
+	HTTPStatus int    `json:"-"`       // HTTP 状态码
+	Err        error  `json:"-"`       // 原始错误
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Unwrap 返回原始错误
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// binding 错误与错误码的绑定
+type binding struct {
+	err  error
+	code Code
+}
+
+// Registry 错误码注册表
+type Registry struct {
+	mutex       sync.RWMutex
+	definitions map[Code]*Definition
+	bindings    []binding
+}
+
+// NewRegistry 创建空的错误码注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		definitions: make(map[Code]*Definition),
+	}
+}
+
+// Define 定义错误码，重复定义时合并提示信息并覆盖 HTTP 状态码
+func (r *Registry) Define(def Definition) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	existing, ok := r.definitions[def.Code]
+	if !ok {
+		existing = &Definition{Code: def.Code, Messages: make(map[string]string)}
+		r.definitions[def.Code] = existing
+	}
+	if def.HTTPStatus != 0 {
+		existing.HTTPStatus = def.HTTPStatus
+	}
+	for lang, msg := range def.Messages {
+		existing.Messages[lang] = msg
+	}
+}
+
+// AddMessages 为已定义的错误码添加某种语言的提示信息，用于扩展其他语言
+func (r *Registry) AddMessages(lang string, messages map[Code]string) {
+	for code, msg := range messages {
+		r.Define(Definition{Code: code, Messages: map[string]string{lang: msg}})
+	}
+}
+
+// Bind 将错误绑定到错误码，Resolve 时通过 errors.Is 匹配
+// 后绑定的优先匹配，便于应用覆盖模块的默认绑定
+func (r *Registry) Bind(err error, code Code) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.bindings = append(r.bindings, binding{err: err, code: code})
+}
+
+// CodeOf 获取错误对应的错误码，未绑定的错误返回 Internal
+func (r *Registry) CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for i := len(r.bindings) - 1; i >= 0; i-- {
+		if errors.Is(err, r.bindings[i].err) {
+			return r.bindings[i].code
+		}
+	}
+	return Internal
+}
+
+// Message 获取错误码在指定语言下的提示信息
+// 依次尝试 lang、lang 的主语言（如 "zh-CN" -> "zh"）、DefaultLang
+func (r *Registry) Message(code Code, lang string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	def, ok := r.definitions[code]
+	if !ok {
+		return ""
+	}
+
+	lang = strings.ToLower(lang)
+	if msg, ok := def.Messages[lang]; ok {
+		return msg
+	}
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		if msg, ok := def.Messages[lang[:i]]; ok {
+			return msg
+		}
+	}
+	return def.Messages[DefaultLang]
+}
+
+// HTTPStatus 获取错误码对应的 HTTP 状态码，未定义时返回 500
+func (r *Registry) HTTPStatus(code Code) int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if def, ok := r.definitions[code]; ok && def.HTTPStatus != 0 {
+		return def.HTTPStatus
+	}
+	return http.StatusInternalServerError
+}
+
+// Resolve 将错误解析为错误码、HTTP 状态码和本地化提示，err 为 nil 时返回 nil
+func (r *Registry) Resolve(err error, lang string) *APIError {
+	if err == nil {
+		return nil
+	}
+
+	code := r.CodeOf(err)
+	return &APIError{
+		Code:       code,
+		Message:    r.Message(code, lang),
+		HTTPStatus: r.HTTPStatus(code),
+		Err:        err,
+	}
+}
+
+// New 创建指定错误码的错误，供 HTTP 层等直接返回目录中的错误
+func (r *Registry) New(code Code, lang string) *APIError {
+	return &APIError{
+		Code:       code,
+		Message:    r.Message(code, lang),
+		HTTPStatus: r.HTTPStatus(code),
+	}
+}
+
+// Default 默认注册表，内置错误码目录，各模块在 init 时绑定自己的错误
+var Default = NewRegistry()
+
+func init() {
+	for _, def := range catalog {
+		Default.Define(def)
+	}
+}
+
+// Define 在默认注册表中定义错误码
+func Define(def Definition) {
+	Default.Define(def)
+}
+
+// AddMessages 在默认注册表中添加某种语言的提示信息
+func AddMessages(lang string, messages map[Code]string) {
+	Default.AddMessages(lang, messages)
+}
+
+// Bind 在默认注册表中将错误绑定到错误码
+func Bind(err error, code Code) {
+	Default.Bind(err, code)
+}
+
+// CodeOf 获取错误对应的错误码
+func CodeOf(err error) Code {
+	return Default.CodeOf(err)
+}
+
+// Message 获取错误码在指定语言下的提示信息
+func Message(code Code, lang string) string {
+	return Default.Message(code, lang)
+}
+
+// Resolve 使用默认注册表解析错误
+func Resolve(err error, lang string) *APIError {
+	return Default.Resolve(err, lang)
+}
+
+// New 使用默认注册表创建指定错误码的错误
+func New(code Code, lang string) *APIError {
+	return Default.New(code, lang)
+}
+
+// LangFromHeader 从 Accept-Language 请求头中取第一个语言，如 "zh-CN,zh;q=0.9" -> "zh-cn"
+// 请求头为空时返回 DefaultLang
+func LangFromHeader(acceptLanguage string) string {
+	first := strings.TrimSpace(strings.SplitN(acceptLanguage, ",", 2)[0])
+	if i := strings.IndexByte(first, ';'); i >= 0 {
+		first = strings.TrimSpace(first[:i])
+	}
+	if first == "" || first == "*" {
+		return DefaultLang
+	}
+	return strings.ToLower(first)
+}
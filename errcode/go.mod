@@ -0,0 +1,3 @@
+module github.com/bbadbeef/go-base/errcode
+
+go 1.21
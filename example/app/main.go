@@ -0,0 +1,51 @@
+// 使用 server 包组合 user、storage、im 的最小示例
+//
+//	go run ./app -db "user:pass@tcp(localhost:3306)/im_user_test?parseTime=true"
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/observability"
+	"github.com/bbadbeef/go-base/server"
+	"github.com/bbadbeef/go-base/user"
+)
+
+var (
+	addr  = flag.String("addr", ":8080", "HTTP 监听地址")
+	dbDSN = flag.String("db", "root:yyy003014@tcp(localhost:3306)/im_user_test?parseTime=true", "数据库连接串")
+)
+
+func main() {
+	flag.Parse()
+
+	db, err := gorm.Open(mysql.Open(*dbDSN), &gorm.Config{})
+	if err != nil {
+		log.Fatal("数据库连接失败:", err)
+	}
+
+	app, err := server.New(&server.Config{
+		DB:      db,
+		Addr:    *addr,
+		Metrics: observability.NewPrometheus(),
+		User:    &user.Config{JWTSecret: "your-secret-key-change-in-production"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 收到 SIGINT / SIGTERM 后优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -4,14 +4,27 @@ go 1.21
 
 require (
 	github.com/bbadbeef/go-base/im v0.0.0
+	github.com/bbadbeef/go-base/log v0.0.0
+	github.com/bbadbeef/go-base/observability v0.0.0
+	github.com/bbadbeef/go-base/server v0.0.0
 	github.com/bbadbeef/go-base/user v0.0.0
 	gorm.io/driver/mysql v1.5.2
 	gorm.io/gorm v1.25.12
 )
 
-require github.com/google/uuid v1.6.0 // indirect
+require (
+	github.com/bbadbeef/go-base/config v0.0.0 // indirect
+	github.com/bbadbeef/go-base/errcode v0.0.0 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/redis/go-redis/v9 v9.7.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
 
 require (
+	github.com/bbadbeef/go-base/idgen v0.0.0 // indirect
+	github.com/bbadbeef/go-base/migrate v0.0.0 // indirect
 	github.com/bbadbeef/go-base/storage v0.0.0
 	github.com/go-sql-driver/mysql v1.7.1 // indirect
 	github.com/golang-jwt/jwt/v5 v5.2.0 // indirect
@@ -36,3 +49,17 @@ replace (
 )
 
 replace github.com/bbadbeef/go-base/storage => ../storage
+
+replace github.com/bbadbeef/go-base/errcode => ../errcode
+
+replace github.com/bbadbeef/go-base/observability => ../observability
+
+replace github.com/bbadbeef/go-base/log => ../log
+
+replace github.com/bbadbeef/go-base/config => ../config
+
+replace github.com/bbadbeef/go-base/server => ../server
+
+replace github.com/bbadbeef/go-base/idgen => ../idgen
+
+replace github.com/bbadbeef/go-base/migrate => ../migrate
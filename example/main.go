@@ -3,21 +3,26 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
 	"github.com/bbadbeef/go-base/im"
+	golog "github.com/bbadbeef/go-base/log"
+	"github.com/bbadbeef/go-base/log/gormlog"
+	"github.com/bbadbeef/go-base/observability"
+	goserver "github.com/bbadbeef/go-base/server"
 	"github.com/bbadbeef/go-base/storage"
 	"github.com/bbadbeef/go-base/user"
 )
@@ -27,12 +32,16 @@ var (
 	grpcPort = flag.Int("grpc", 50051, "gRPC端口")
 	dbDSN    = flag.String("db", "root:yyy003014@tcp(localhost:3306)/im_user_test?parseTime=true", "数据库连接串")
 	serverID = flag.String("id", "server-1", "服务器ID")
+	tunables = flag.String("tunables", "", "IM 运行期配置文件（可选），修改后自动热更新")
 )
 
 var (
 	userService    user.Service
 	imService      im.IMService
 	storageService storage.Storage
+
+	// 三个模块共用的监控指标，通过 /metrics 导出
+	metrics = observability.NewPrometheus()
 )
 
 func main() {
@@ -42,7 +51,8 @@ func main() {
 	log.Printf("启动集成服务器: %s", *serverID)
 
 	// 连接数据库 (使用 GORM)
-	db, err := gorm.Open(mysql.Open(*dbDSN), &gorm.Config{})
+	// SQL 错误和慢查询写入 IM 的日志，附带请求 ID
+	db, err := gorm.Open(mysql.Open(*dbDSN), &gorm.Config{Logger: gormlog.New(im.GetLogger(), gormlog.Config{})})
 	if err != nil {
 		log.Fatal("数据库连接失败:", err)
 	}
@@ -53,6 +63,19 @@ func main() {
 		DB:            db,
 		JWTSecret:     "your-secret-key-change-in-production",
 		TokenDuration: 7 * 24 * time.Hour,
+		// 示例页面没有人机验证，关闭失败后要求验证码的限制
+		LoginProtection: &user.LoginProtection{CaptchaAfter: -1},
+		Metrics:         metrics,
+		Logger:          im.GetLogger(), // 与 IM 模块共用 logrus 日志
+		// 注销账号时清理 IM 和存储中的用户数据
+		AccountDeletionHooks: []user.AccountDeletionHook{
+			func(ctx context.Context, userID int64) error {
+				return imService.PurgeUserData(ctx, userID)
+			},
+			func(ctx context.Context, userID int64) error {
+				return storageService.WithContext(ctx).DeleteByUser(userID)
+			},
+		},
 	})
 	if err != nil {
 		log.Fatal("创建用户服务失败:", err)
@@ -63,6 +86,8 @@ func main() {
 	storageService, err = storage.NewStorage(&storage.Config{
 		DB:      db,
 		BaseURL: fmt.Sprintf("http://localhost:%d", *httpPort),
+		Metrics: metrics,
+		Logger:  im.GetLogger(),
 	})
 	if err != nil {
 		log.Fatal("创建存储服务失败:", err)
@@ -71,14 +96,21 @@ func main() {
 
 	// 创建 IM 服务
 	grpcAddr := fmt.Sprintf("0.0.0.0:%d", *grpcPort)
-	imService = im.NewBuilder().
+	imBuilder := im.NewBuilder().
 		WithServerID(*serverID).
 		WithGRPCAddr(grpcAddr).
 		WithDB(db).
 		WithAuthFunc(validateToken). // 使用 JWT Token 认证
 		WithCacheTTL(30).
 		WithHeartbeatInterval(15).
-		MustBuild()
+		WithOfflinePushFunc(offlinePush).
+		WithMetrics(metrics)
+
+	// 启动前检查数据库、gRPC 端口和其他节点，一次报告全部问题
+	if err := imBuilder.Preflight(context.Background()); err != nil {
+		log.Fatal("IM 启动前检查失败:\n", err)
+	}
+	imService = imBuilder.MustBuild()
 
 	// 设置 IM 回调
 	setupIMCallbacks()
@@ -94,6 +126,13 @@ func main() {
 		}
 	}()
 
+	// 监听运行期配置文件，日志级别、消息大小和频率限制等修改后无需重启
+	if *tunables != "" {
+		if err := im.WatchTunables(ctx, imService, *tunables, 0); err != nil {
+			log.Fatal("加载 IM 运行期配置失败:", err)
+		}
+	}
+
 	// 启动 HTTP 服务
 	mux := http.NewServeMux()
 	setupRoutes(mux)
@@ -101,7 +140,7 @@ func main() {
 	httpAddr := fmt.Sprintf(":%d", *httpPort)
 	server := &http.Server{
 		Addr:    httpAddr,
-		Handler: enableCORS(mux),
+		Handler: golog.RequestIDMiddleware(enableCORS(mux)), // 请求 ID 写入 context 和响应头，用于关联各模块的日志
 	}
 
 	go func() {
@@ -112,15 +151,11 @@ func main() {
 		}
 	}()
 
-	// 优雅关闭
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Println("正在关闭服务器...")
-	cancel()
-	imService.Stop()
-	server.Close()
+	// 收到 SIGINT / SIGTERM 后优雅关闭：停止接受 HTTP 请求，通知并断开 WebSocket 连接，等待消息处理和离线推送，关闭 gRPC 和数据库
+	shutdown := goserver.NewShutdown(im.GetLogger()).HTTP(server).IM(imService).DB(db)
+	if err := shutdown.Wait(10 * time.Second); err != nil {
+		log.Printf("关闭服务器出错: %v", err)
+	}
 	log.Println("服务器已关闭")
 }
 
@@ -133,6 +168,21 @@ func validateToken(token string) (int64, error) {
 	return claims.UserID, nil
 }
 
+// offlinePush 接收方离线时向其已注册推送令牌的设备推送（示例仅打印日志，实际需接入 APNs / FCM 等）
+func offlinePush(msg *im.Message) {
+	devices, err := userService.ListDevices(msg.ToUserID)
+	if err != nil {
+		log.Printf("[离线推送] 获取用户 %d 的设备失败: %v", msg.ToUserID, err)
+		return
+	}
+	for _, device := range devices {
+		if device.PushToken == "" {
+			continue
+		}
+		log.Printf("[离线推送] 用户 %d 设备 %s(%s): %s", msg.ToUserID, device.DeviceID, device.Platform, msg.Content)
+	}
+}
+
 // setupIMCallbacks 设置 IM 回调
 func setupIMCallbacks() {
 	imService.OnMessage(func(msg *im.Message) {
@@ -154,11 +204,14 @@ func setupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/register", handleRegister)
 	mux.HandleFunc("/api/login", handleLogin)
 	mux.HandleFunc("/api/code/send", handleSendCode)
+	mux.HandleFunc("/api/token/refresh", handleRefreshToken)
+	mux.HandleFunc("/api/logout", handleLogout)
 
 	// 用户信息相关（需要认证）
 	mux.HandleFunc("/api/user/profile", authMiddleware(handleGetProfile))
 	mux.HandleFunc("/api/user/info", authMiddleware(handleGetUserInfo)) // 获取其他用户信息
 	mux.HandleFunc("/api/user/update", authMiddleware(handleUpdateProfile))
+	mux.HandleFunc("/api/user/devices", authMiddleware(handleRegisterDevice)) // 注册设备和推送令牌
 
 	// 文件上传相关（需要认证）
 	mux.HandleFunc("/api/upload/image", authMiddleware(handleUploadImage))
@@ -175,6 +228,12 @@ func setupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/send", authMiddleware(handleSendMessage))
 	mux.HandleFunc("/api/online", handleCheckOnline)
 
+	// 管理相关（需要 admin 角色）
+	mux.Handle("/api/admin/roles", user.RequireRole(userService, "admin")(http.HandlerFunc(handleListRoles)))
+
+	// 监控指标（Prometheus 文本格式）
+	mux.Handle("/metrics", metrics)
+
 	// 测试页面
 	mux.HandleFunc("/", handleTestPage)
 }
@@ -194,7 +253,7 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, token, err := userService.Register(&req)
+	u, tokens, err := userService.WithContext(clientContext(r)).Register(&req)
 	if err != nil {
 		httpError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -203,8 +262,9 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]interface{}{
 		"code": 200,
 		"data": map[string]interface{}{
-			"user":  u,
-			"token": token,
+			"user":          u,
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
 		},
 	})
 }
@@ -222,7 +282,47 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, token, err := userService.Login(&req)
+	u, tokens, err := userService.WithContext(clientContext(r)).Login(&req)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"code": 200,
+		"data": map[string]interface{}{
+			"user":          u,
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+		},
+	})
+}
+
+// clientContext 将客户端信息绑定到请求 ctx，登录时记录到会话
+func clientContext(r *http.Request) context.Context {
+	ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+	return user.WithClientInfo(r.Context(), user.ClientInfo{
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+	})
+}
+
+// 刷新令牌
+func handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := userService.WithContext(r.Context()).RefreshToken(req.RefreshToken)
 	if err != nil {
 		httpError(w, err.Error(), http.StatusUnauthorized)
 		return
@@ -231,12 +331,37 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, map[string]interface{}{
 		"code": 200,
 		"data": map[string]interface{}{
-			"user":  u,
-			"token": token,
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
 		},
 	})
 }
 
+// 退出登录
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := userService.WithContext(r.Context()).Logout(req.RefreshToken); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"code": 200,
+	})
+}
+
 // 发送验证码
 func handleSendCode(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -250,7 +375,7 @@ func handleSendCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	code, err := userService.SendVerificationCode(&req)
+	code, err := userService.WithContext(clientContext(r)).SendVerificationCode(&req)
 	if err != nil {
 		httpError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -267,7 +392,7 @@ func handleSendCode(w http.ResponseWriter, r *http.Request) {
 
 // 获取用户信息
 func handleGetProfile(w http.ResponseWriter, r *http.Request, userID int64) {
-	u, err := userService.GetUserByID(userID)
+	u, err := userService.WithContext(r.Context()).GetUserByID(userID)
 	if err != nil {
 		httpError(w, err.Error(), http.StatusNotFound)
 		return
@@ -280,7 +405,7 @@ func handleGetProfile(w http.ResponseWriter, r *http.Request, userID int64) {
 }
 
 // 获取其他用户的公开信息
-func handleGetUserInfo(w http.ResponseWriter, r *http.Request, _ int64) {
+func handleGetUserInfo(w http.ResponseWriter, r *http.Request, viewerID int64) {
 	// 从查询参数获取目标用户ID
 	userIDStr := r.URL.Query().Get("user_id")
 	if userIDStr == "" {
@@ -294,23 +419,16 @@ func handleGetUserInfo(w http.ResponseWriter, r *http.Request, _ int64) {
 		return
 	}
 
-	u, err := userService.GetUserByID(targetUserID)
+	// 只返回公开信息，手机号、生日等按目标用户的隐私设置返回
+	profile, err := userService.WithContext(r.Context()).GetUserProfileForViewer(viewerID, targetUserID)
 	if err != nil {
 		httpError(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	// 只返回公开信息
 	jsonResponse(w, map[string]interface{}{
 		"code": 200,
-		"data": map[string]interface{}{
-			"id":       u.ID,
-			"username": u.Username,
-			"nickname": u.Nickname,
-			"avatar":   u.Avatar,
-			"gender":   u.Gender,
-			"signature": u.Signature,
-		},
+		"data": profile,
 	})
 }
 
@@ -327,7 +445,7 @@ func handleUpdateProfile(w http.ResponseWriter, r *http.Request, userID int64) {
 		return
 	}
 
-	u, err := userService.UpdateProfile(userID, &req)
+	u, err := userService.WithContext(r.Context()).UpdateProfile(userID, &req)
 	if err != nil {
 		httpError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -339,6 +457,31 @@ func handleUpdateProfile(w http.ResponseWriter, r *http.Request, userID int64) {
 	})
 }
 
+// 注册设备和推送令牌
+func handleRegisterDevice(w http.ResponseWriter, r *http.Request, userID int64) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req user.RegisterDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	device, err := userService.WithContext(r.Context()).RegisterDevice(userID, &req)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"code": 200,
+		"data": device,
+	})
+}
+
 // ==================== IM 相关 API ====================
 
 // 获取会话列表
@@ -445,25 +588,17 @@ func handleUploadAvatar(w http.ResponseWriter, r *http.Request, userID int64) {
 		return
 	}
 
-	// 解析文件
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10MB
-		httpError(w, "解析文件失败", http.StatusBadRequest)
-		return
-	}
-
-	file, header, err := r.FormFile("file")
+	// 上传头像，裁剪为正方形并生成各尺寸
+	part, err := filePart(w, r, storage.FileTypeImage)
 	if err != nil {
-		httpError(w, "获取文件失败", http.StatusBadRequest)
+		httpError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
-
-	// 上传文件
-	fileInfo, err := storageService.Upload(&storage.UploadRequest{
-		File:     file,
-		Header:   header,
+	defer part.Close()
+	avatar, err := storageService.WithContext(r.Context()).UploadAvatar(part, &storage.UploadMeta{
+		FileName: part.FileName(),
+		MimeType: part.Header.Get("Content-Type"),
 		UserID:   userID,
-		FileType: storage.FileTypeImage,
 	})
 	if err != nil {
 		httpError(w, err.Error(), http.StatusBadRequest)
@@ -471,8 +606,8 @@ func handleUploadAvatar(w http.ResponseWriter, r *http.Request, userID int64) {
 	}
 
 	// 更新用户头像
-	_, err = userService.UpdateProfile(userID, &user.UpdateProfileRequest{
-		Avatar: &fileInfo.URL,
+	_, err = userService.WithContext(r.Context()).UpdateProfile(userID, &user.UpdateProfileRequest{
+		Avatar: &avatar.Medium,
 	})
 	if err != nil {
 		httpError(w, "更新用户头像失败", http.StatusInternalServerError)
@@ -481,7 +616,7 @@ func handleUploadAvatar(w http.ResponseWriter, r *http.Request, userID int64) {
 
 	jsonResponse(w, map[string]interface{}{
 		"code": 200,
-		"data": fileInfo,
+		"data": avatar,
 	})
 }
 
@@ -492,35 +627,65 @@ func handleUploadFile0(w http.ResponseWriter, r *http.Request, userID int64, fil
 		return
 	}
 
-	// 解析文件
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10MB
-		httpError(w, "解析文件失败", http.StatusBadRequest)
+	// 上传文件
+	fileInfo, err := streamUpload(w, r, userID, fileType)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	file, header, err := r.FormFile("file")
+	jsonResponse(w, map[string]interface{}{
+		"code": 200,
+		"data": fileInfo,
+	})
+}
+
+// streamUpload 从 multipart 表单的 file 字段流式上传，不将文件整体读入内存
+func streamUpload(w http.ResponseWriter, r *http.Request, userID int64, fileType string) (*storage.FileInfo, error) {
+	part, err := filePart(w, r, fileType)
 	if err != nil {
-		httpError(w, "获取文件失败", http.StatusBadRequest)
-		return
+		return nil, err
 	}
-	defer file.Close()
-
-	// 上传文件
-	fileInfo, err := storageService.Upload(&storage.UploadRequest{
-		File:     file,
-		Header:   header,
+	defer part.Close()
+	return storageService.WithContext(r.Context()).UploadStream(part, &storage.UploadMeta{
+		FileName: part.FileName(),
+		MimeType: part.Header.Get("Content-Type"),
 		UserID:   userID,
 		FileType: fileType,
 	})
+}
+
+// filePart 返回 multipart 表单中的 file 字段，其前面的字段被跳过
+// 请求体按文件类型的大小上限（加 1MB 表单开销）限制，Content-Length 已超过时不读取请求体
+func filePart(w http.ResponseWriter, r *http.Request, fileType string) (*multipart.Part, error) {
+	maxSize, err := storage.MaxSizeOf(fileType)
 	if err != nil {
-		httpError(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, err
 	}
+	limit := maxSize + 1<<20
+	if r.ContentLength > limit {
+		return nil, &storage.FileTooLargeError{Size: r.ContentLength, MaxSize: maxSize}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
 
-	jsonResponse(w, map[string]interface{}{
-		"code": 200,
-		"data": fileInfo,
-	})
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("解析文件失败: %w", err)
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("获取文件失败: 缺少 file 字段")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析文件失败: %w", err)
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		return part, nil
+	}
 }
 
 // 下载文件
@@ -533,21 +698,21 @@ func handleDownloadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 下载文件
-	data, fileInfo, err := storageService.Download(fileID)
+	// 下载文件，流式读取，不将文件整体读入内存
+	rc, fileInfo, err := storageService.WithContext(r.Context()).DownloadStream(fileID)
 	if err != nil {
 		httpError(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	defer rc.Close()
 
 	// 设置响应头
 	w.Header().Set("Content-Type", fileInfo.MimeType)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%s", fileInfo.FileName))
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.FileSize))
 	w.Header().Set("Cache-Control", "public, max-age=31536000") // 缓存1年
 
-	// 写入文件数据
-	w.Write(data)
+	// 写入文件数据，支持 Range 请求
+	http.ServeContent(w, r, fileInfo.FileName, fileInfo.UploadTime, rc.(io.ReadSeeker))
 }
 
 // 检查用户是否在线
@@ -565,6 +730,20 @@ func handleCheckOnline(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// 获取全部角色
+func handleListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := userService.WithContext(r.Context()).ListRoles()
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"code": 200,
+		"data": roles,
+	})
+}
+
 // ==================== 中间件 ====================
 
 // authMiddleware 认证中间件
@@ -740,7 +919,7 @@ func handleTestPage(w http.ResponseWriter, r *http.Request) {
             <div class="auth-panel" id="authPanel">
                 <div class="form-group">
                     <label>手机号/用户名:</label>
-                    <input type="text" id="phone" placeholder="13800138000 或 u13800138000">
+                    <input type="text" id="phone" placeholder="13800138000、+85291234567 或用户名">
                 </div>
                 <div class="form-group">
                     <label>密码（与验证码二选一）:</label>
@@ -855,7 +1034,7 @@ func handleTestPage(w http.ResponseWriter, r *http.Request) {
             }
 
             // 验证手机号格式
-            if (!/^1[3-9]\d{9}$/.test(phone)) {
+            if (!/^\+?[\d\s()-]{6,20}$/.test(phone)) {
                 alert('请输入正确的手机号格式');
                 return;
             }
@@ -912,7 +1091,7 @@ func handleTestPage(w http.ResponseWriter, r *http.Request) {
             }
 
             // 如果使用验证码登录，必须是手机号
-            if (code && !/^1[3-9]\d{9}$/.test(account)) {
+            if (code && !/^\+?[\d\s()-]{6,20}$/.test(account)) {
                 alert('验证码登录仅支持手机号');
                 return;
             }
@@ -960,7 +1139,7 @@ func handleTestPage(w http.ResponseWriter, r *http.Request) {
             }
 
             // 验证手机号格式
-            if (!/^1[3-9]\d{9}$/.test(phone)) {
+            if (!/^\+?[\d\s()-]{6,20}$/.test(phone)) {
                 alert('请输入正确的手机号格式');
                 return;
             }
@@ -1115,7 +1294,7 @@ func handleTestPage(w http.ResponseWriter, r *http.Request) {
 
                 if (result.code === 200) {
                     // 更新当前用户的头像
-                    currentUser.avatar = result.data.url;
+                    currentUser.avatar = result.data.medium;
                     updateUserInfo();
                     alert('头像更新成功！');
                 } else {
@@ -0,0 +1,3 @@
+module github.com/bbadbeef/go-base/idgen
+
+go 1.21
@@ -0,0 +1,25 @@
+// Package idgen user / im / storage 共用的 ID 生成接口
+// 各模块通过 Config.IDGenerator 接收同一个 Generator，用于消息 ID、文件 ID，以及可选的用户 ID；
+// 未设置时消息和文件使用随机 UUID，用户使用数据库自增 ID。NewSnowflake 生成按时间递增、带节点号的 int64，
+// 多个节点、多个分库写入时无需依赖数据库自增，便于分库分表
+package idgen
+
+import "strconv"
+
+// Generator ID 生成器，实现需并发安全，返回的 ID 需大于 0 且全局唯一
+type Generator interface {
+	NextID() int64
+}
+
+// Func 将函数适配为 Generator
+type Func func() int64
+
+// NextID 调用 f
+func (f Func) NextID() int64 {
+	return f()
+}
+
+// String 生成十进制字符串形式的 ID，用于消息 ID、文件 ID 等字符串主键
+func String(g Generator) string {
+	return strconv.FormatInt(g.NextID(), 10)
+}
@@ -0,0 +1,73 @@
+package idgen
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Snowflake 各部分位数：41 位毫秒时间戳（自 Epoch 起约 69 年）、10 位节点号、12 位序列号（每节点每毫秒 4096 个）
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	MaxNode     = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// Epoch Snowflake 时间戳的起点（2024-01-01 UTC）
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake 雪花算法 ID 生成器，ID 按时间递增，不同节点号生成的 ID 不会重复，并发安全
+// 每个进程（如每个 IM 节点）需使用不同的节点号，可按 ServerID、Pod 序号等分配
+// ID 超过 2^53，JavaScript 客户端需按字符串处理 JSON 中的用户 ID
+type Snowflake struct {
+	mu       sync.Mutex
+	node     int64
+	last     int64 // 上次生成 ID 的时间（自 Epoch 起的毫秒数）
+	sequence int64
+}
+
+// NewSnowflake 创建节点号为 node（0 ~ MaxNode）的生成器
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("idgen: snowflake node must be between 0 and %d, got %d", MaxNode, node)
+	}
+	return &Snowflake{node: node}, nil
+}
+
+// NextID 生成 ID，同一毫秒内序列号用尽或系统时钟回拨时等待到下一可用毫秒
+func (s *Snowflake) NextID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Since(Epoch).Milliseconds()
+	if now < s.last {
+		// 时钟回拨：沿用上次的时间继续分配序列号，用尽后等待时钟追上
+		now = s.last
+	}
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			for now <= s.last {
+				time.Sleep(time.Millisecond / 10)
+				now = time.Since(Epoch).Milliseconds()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = now
+
+	return now<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence
+}
+
+// Time 返回 Snowflake ID 的生成时间（毫秒精度）
+func Time(id int64) time.Time {
+	return Epoch.Add(time.Duration(id>>(nodeBits+sequenceBits)) * time.Millisecond)
+}
+
+// Node 返回 Snowflake ID 的节点号
+func Node(id int64) int64 {
+	return id >> sequenceBits & MaxNode
+}
@@ -1,13 +1,22 @@
 package im
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"strconv"
+	"time"
 
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 
+	"github.com/bbadbeef/go-base/config"
+	"github.com/bbadbeef/go-base/idgen"
 	"github.com/bbadbeef/go-base/im/internal/core"
+	"github.com/bbadbeef/go-base/observability"
 )
 
 // Builder IM 服务构建器，支持链式配置
@@ -44,6 +53,24 @@ func (b *Builder) WithGRPCAddr(addr string) *Builder {
 	return b
 }
 
+// WithClusterKey 设置集群密钥，节点间 gRPC 调用需携带相同密钥，同一集群的所有节点需配置相同的值
+func (b *Builder) WithClusterKey(key string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.ClusterKey = key
+	return b
+}
+
+// WithGRPCInterceptors 添加 gRPC 服务端拦截器，在内置的日志、指标、panic 恢复和认证拦截器之后依次执行
+func (b *Builder) WithGRPCInterceptors(interceptors ...grpc.UnaryServerInterceptor) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.GRPCInterceptors = append(b.config.GRPCInterceptors, interceptors...)
+	return b
+}
+
 // WithDB 设置数据库连接
 func (b *Builder) WithDB(db *gorm.DB) *Builder {
 	if b.err != nil {
@@ -53,8 +80,26 @@ func (b *Builder) WithDB(db *gorm.DB) *Builder {
 	return b
 }
 
-// WithAuthFunc 设置认证函数
+// WithReadDB 设置只读库连接（从库），历史消息、会话列表、路由查询走只读库
+func (b *Builder) WithReadDB(db *gorm.DB) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.ReadDB = db
+	return b
+}
+
+// WithAuthFunc 设置只校验 Token 的认证函数，需要连接信息时使用 WithConnAuthFunc
 func (b *Builder) WithAuthFunc(authFunc func(token string) (int64, error)) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.AuthFunc = TokenAuth(authFunc)
+	return b
+}
+
+// WithConnAuthFunc 设置认证函数，可按连接的客户端 IP、User-Agent 和设备 ID 做 IP 白名单、设备绑定等校验
+func (b *Builder) WithConnAuthFunc(authFunc AuthFunc) *Builder {
 	if b.err != nil {
 		return b
 	}
@@ -62,6 +107,37 @@ func (b *Builder) WithAuthFunc(authFunc func(token string) (int64, error)) *Buil
 	return b
 }
 
+// WithGuest 允许不带 Token 的访客连接，访客分配临时的负数用户 ID，只能向 sendTo 中的用户（如客服账号）发送单聊消息
+// sendTo 为空时访客只能接收消息
+func (b *Builder) WithGuest(sendTo ...int64) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.EnableGuest = true
+	b.config.GuestSendTo = sendTo
+	return b
+}
+
+// WithSupport 开启客服模式，发给 userID 的单聊消息按 strategy（least_busy / round_robin，为空时 least_busy）分配给客服
+// 访客模式下访客也可以向 userID 发送消息
+func (b *Builder) WithSupport(userID int64, strategy string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.SupportUserID = userID
+	b.config.SupportStrategy = strategy
+	return b
+}
+
+// WithTrustProxy 按 X-Forwarded-For / X-Real-IP 获取连接的客户端 IP，仅在可信的反向代理之后开启
+func (b *Builder) WithTrustProxy(trust bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.TrustProxy = trust
+	return b
+}
+
 // WithCacheTTL 设置路由缓存 TTL（秒）
 func (b *Builder) WithCacheTTL(seconds int) *Builder {
 	if b.err != nil {
@@ -80,12 +156,198 @@ func (b *Builder) WithHeartbeatInterval(seconds int) *Builder {
 	return b
 }
 
+// WithPresenceTimeout 设置在线状态超时（秒），用户路由心跳超过此时间视为离线，默认 3 倍心跳间隔
+func (b *Builder) WithPresenceTimeout(seconds int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.PresenceTimeout = seconds
+	return b
+}
+
+// WithAutoReplyInterval 设置离开状态自动回复的间隔（秒），同一发送者在间隔内只收到一次自动回复，默认 1 小时
+func (b *Builder) WithAutoReplyInterval(seconds int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.AutoReplyInterval = seconds
+	return b
+}
+
+// WithBandwidthLimit 设置每个连接每秒最多上行字节数（线上字节），0 表示不限制
+func (b *Builder) WithBandwidthLimit(bytesPerSecond int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.BandwidthLimit = bytesPerSecond
+	return b
+}
+
+// WithCompression 设置是否协商 WebSocket permessage-deflate 压缩
+func (b *Builder) WithCompression(enabled bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.EnableCompression = enabled
+	return b
+}
+
+// WithAffinity 设置节点亲和标识和亲和 Cookie 名
+// token 为空时使用 ServerID；cookie 不为空时 WebSocket 升级响应写入该 Cookie，供负载均衡 / Ingress 把重连路由回同一节点
+func (b *Builder) WithAffinity(token, cookie string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.AffinityToken = token
+	b.config.AffinityCookie = cookie
+	return b
+}
+
+// WithMaxMessageSize 设置单条消息内容最大字节数，0 表示不限制
+func (b *Builder) WithMaxMessageSize(bytes int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.MaxMessageSize = bytes
+	return b
+}
+
+// WithRateLimit 设置每个连接每秒最多发送的消息数，0 表示不限制
+func (b *Builder) WithRateLimit(perSecond int) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.RateLimit = perSecond
+	return b
+}
+
+// WithTablePrefix 设置表名前缀，如 "app_" -> "app_im_messages"
+func (b *Builder) WithTablePrefix(prefix string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.TablePrefix = prefix
+	return b
+}
+
+// WithTableName 覆盖单张表的表名，如 WithTableName("im_messages", "chat_messages")
+func (b *Builder) WithTableName(defaultName, name string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.config.TableNames == nil {
+		b.config.TableNames = make(map[string]string)
+	}
+	b.config.TableNames[defaultName] = name
+	return b
+}
+
+// WithTenantID 设置租户 ID，消息和会话仅在该租户内可见
+func (b *Builder) WithTenantID(tenantID string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.TenantID = tenantID
+	return b
+}
+
+// WithoutAutoMigrate 禁止启动时自动执行数据库迁移，需显式调用 Migrate
+func (b *Builder) WithoutAutoMigrate() *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.DisableAutoMigrate = true
+	return b
+}
+
+// WithOfflinePushFunc 设置离线推送钩子
+func (b *Builder) WithOfflinePushFunc(pushFunc func(msg *Message)) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.OfflinePushFunc = pushFunc
+	return b
+}
+
+// WithFileCheckFunc 设置发送消息时的文件引用校验
+func (b *Builder) WithFileCheckFunc(checkFunc FileCheckFunc) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.FileCheckFunc = checkFunc
+	return b
+}
+
+// WithMetrics 设置监控指标，如 observability.NewPrometheus()
+func (b *Builder) WithMetrics(metrics observability.Metrics) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.Metrics = metrics
+	return b
+}
+
+// WithSlowQueryFunc 设置慢 SQL 回调，threshold 为 0 时使用默认值 200ms
+func (b *Builder) WithSlowQueryFunc(threshold time.Duration, fn SlowQueryFunc) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.SlowQueryThreshold = threshold
+	b.config.SlowQueryFunc = fn
+	return b
+}
+
+// WithWriteBatch 开启消息写入合并，size 为每批最大条数，maxDelay 为最长等待时间（0 时默认 5ms）
+func (b *Builder) WithWriteBatch(size int, maxDelay time.Duration) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.WriteBatchSize = size
+	b.config.WriteBatchDelay = maxDelay
+	return b
+}
+
+// WithIDGenerator 设置消息 ID 生成器，如 idgen.NewSnowflake(node)
+func (b *Builder) WithIDGenerator(gen idgen.Generator) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.IDGenerator = gen
+	return b
+}
+
+// WithVersion 设置节点版本，如构建版本号，通过 ClusterInfo 展示
+func (b *Builder) WithVersion(version string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	b.config.Version = version
+	return b
+}
+
 // FromEnv 从环境变量加载配置
 // 支持的环境变量：
-//   IM_SERVER_ID      - 服务器 ID
-//   IM_GRPC_ADDR      - gRPC 地址
-//   IM_CACHE_TTL      - 缓存 TTL（秒）
-//   IM_HEARTBEAT      - 心跳间隔（秒）
+//   IM_SERVER_ID        - 服务器 ID
+//   IM_GRPC_ADDR        - gRPC 地址
+//   IM_CLUSTER_KEY      - 集群密钥
+//   IM_CACHE_TTL        - 缓存 TTL（秒）
+//   IM_HEARTBEAT        - 心跳间隔（秒）
+//   IM_PRESENCE_TIMEOUT - 在线状态超时（秒）
+//   IM_AFFINITY_TOKEN   - 节点亲和标识
+//   IM_AFFINITY_COOKIE  - 亲和 Cookie 名
+//   IM_MAX_MESSAGE_SIZE - 单条消息内容最大字节数
+//   IM_RATE_LIMIT       - 每个连接每秒最多发送的消息数
+//   IM_BANDWIDTH_LIMIT  - 每个连接每秒最多上行字节数
+//   IM_COMPRESSION      - 是否协商 WebSocket 压缩（true/false）
+//   IM_TRUST_PROXY      - 是否按代理转发的地址获取客户端 IP（true/false）
+//   IM_GUEST            - 是否允许访客连接（true/false）
+//   IM_SUPPORT_USER     - 客服入口的虚拟用户 ID
+//   IM_SUPPORT_STRATEGY - 客服分配策略（least_busy/round_robin）
+//   IM_AUTO_REPLY       - 离开状态自动回复的间隔（秒）
+//   IM_WRITE_BATCH_SIZE - 消息写入合并的最大条数
+//   IM_TABLE_PREFIX     - 表名前缀
+//   IM_TENANT_ID        - 租户 ID
+//   IM_AUTO_MIGRATE     - 是否启动时自动迁移（true/false）
 func (b *Builder) FromEnv() *Builder {
 	if b.err != nil {
 		return b
@@ -99,6 +361,10 @@ func (b *Builder) FromEnv() *Builder {
 		b.config.GRPCAddr = grpcAddr
 	}
 
+	if clusterKey := os.Getenv("IM_CLUSTER_KEY"); clusterKey != "" {
+		b.config.ClusterKey = clusterKey
+	}
+
 	if cacheTTL := os.Getenv("IM_CACHE_TTL"); cacheTTL != "" {
 		if ttl, err := strconv.Atoi(cacheTTL); err == nil {
 			b.config.CacheTTL = ttl
@@ -111,26 +377,325 @@ func (b *Builder) FromEnv() *Builder {
 		}
 	}
 
+	if presence := os.Getenv("IM_PRESENCE_TIMEOUT"); presence != "" {
+		if timeout, err := strconv.Atoi(presence); err == nil {
+			b.config.PresenceTimeout = timeout
+		}
+	}
+
+	if token := os.Getenv("IM_AFFINITY_TOKEN"); token != "" {
+		b.config.AffinityToken = token
+	}
+
+	if cookie := os.Getenv("IM_AFFINITY_COOKIE"); cookie != "" {
+		b.config.AffinityCookie = cookie
+	}
+
+	if maxSize := os.Getenv("IM_MAX_MESSAGE_SIZE"); maxSize != "" {
+		if size, err := strconv.Atoi(maxSize); err == nil {
+			b.config.MaxMessageSize = size
+		}
+	}
+
+	if rateLimit := os.Getenv("IM_RATE_LIMIT"); rateLimit != "" {
+		if limit, err := strconv.Atoi(rateLimit); err == nil {
+			b.config.RateLimit = limit
+		}
+	}
+
+	if bandwidth := os.Getenv("IM_BANDWIDTH_LIMIT"); bandwidth != "" {
+		if limit, err := strconv.Atoi(bandwidth); err == nil {
+			b.config.BandwidthLimit = limit
+		}
+	}
+
+	if compression := os.Getenv("IM_COMPRESSION"); compression != "" {
+		if enabled, err := strconv.ParseBool(compression); err == nil {
+			b.config.EnableCompression = enabled
+		}
+	}
+
+	if trustProxy := os.Getenv("IM_TRUST_PROXY"); trustProxy != "" {
+		if trust, err := strconv.ParseBool(trustProxy); err == nil {
+			b.config.TrustProxy = trust
+		}
+	}
+
+	if guest := os.Getenv("IM_GUEST"); guest != "" {
+		if enabled, err := strconv.ParseBool(guest); err == nil {
+			b.config.EnableGuest = enabled
+		}
+	}
+
+	if supportUser := os.Getenv("IM_SUPPORT_USER"); supportUser != "" {
+		if userID, err := strconv.ParseInt(supportUser, 10, 64); err == nil {
+			b.config.SupportUserID = userID
+		}
+	}
+
+	if strategy := os.Getenv("IM_SUPPORT_STRATEGY"); strategy != "" {
+		b.config.SupportStrategy = strategy
+	}
+
+	if autoReply := os.Getenv("IM_AUTO_REPLY"); autoReply != "" {
+		if interval, err := strconv.Atoi(autoReply); err == nil {
+			b.config.AutoReplyInterval = interval
+		}
+	}
+
+	if batchSize := os.Getenv("IM_WRITE_BATCH_SIZE"); batchSize != "" {
+		if size, err := strconv.Atoi(batchSize); err == nil {
+			b.config.WriteBatchSize = size
+		}
+	}
+
+	if prefix := os.Getenv("IM_TABLE_PREFIX"); prefix != "" {
+		b.config.TablePrefix = prefix
+	}
+
+	if tenantID := os.Getenv("IM_TENANT_ID"); tenantID != "" {
+		b.config.TenantID = tenantID
+	}
+
+	if autoMigrate := os.Getenv("IM_AUTO_MIGRATE"); autoMigrate != "" {
+		if enabled, err := strconv.ParseBool(autoMigrate); err == nil {
+			b.config.DisableAutoMigrate = !enabled
+		}
+	}
+
 	return b
 }
 
-// Build 构建 IM 服务实例
-func (b *Builder) Build() (IMService, error) {
+// fileConfig 配置文件中 im 段的字段，env 标签与 FromEnv 的环境变量一致
+type fileConfig struct {
+	ServerID          string            `json:"server_id" env:"IM_SERVER_ID"`
+	GRPCAddr          string            `json:"grpc_addr" env:"IM_GRPC_ADDR"`
+	ClusterKey        string            `json:"cluster_key" env:"IM_CLUSTER_KEY"`
+	CacheTTL          *int              `json:"cache_ttl" env:"IM_CACHE_TTL"`
+	HeartbeatInterval *int              `json:"heartbeat_interval" env:"IM_HEARTBEAT"`
+	PresenceTimeout   *int              `json:"presence_timeout" env:"IM_PRESENCE_TIMEOUT"`
+	AffinityToken     string            `json:"affinity_token" env:"IM_AFFINITY_TOKEN"`
+	AffinityCookie    string            `json:"affinity_cookie" env:"IM_AFFINITY_COOKIE"`
+	MaxMessageSize    *int              `json:"max_message_size" env:"IM_MAX_MESSAGE_SIZE"`
+	RateLimit         *int              `json:"rate_limit" env:"IM_RATE_LIMIT"`
+	BandwidthLimit    *int              `json:"bandwidth_limit" env:"IM_BANDWIDTH_LIMIT"`
+	Compression       *bool             `json:"compression" env:"IM_COMPRESSION"`
+	TrustProxy        *bool             `json:"trust_proxy" env:"IM_TRUST_PROXY"`
+	Guest             *bool             `json:"guest" env:"IM_GUEST"`
+	GuestSendTo       []int64           `json:"guest_send_to"`
+	SupportUserID     *int64            `json:"support_user_id" env:"IM_SUPPORT_USER"`
+	SupportStrategy   string            `json:"support_strategy" env:"IM_SUPPORT_STRATEGY"`
+	AutoReplyInterval *int              `json:"auto_reply_interval" env:"IM_AUTO_REPLY"`
+	WriteBatchSize    *int              `json:"write_batch_size" env:"IM_WRITE_BATCH_SIZE"`
+	TablePrefix       string            `json:"table_prefix" env:"IM_TABLE_PREFIX"`
+	TableNames        map[string]string `json:"table_names"`
+	TenantID          string            `json:"tenant_id" env:"IM_TENANT_ID"`
+	AutoMigrate       *bool             `json:"auto_migrate" env:"IM_AUTO_MIGRATE"`
+}
+
+// FromFile 从 YAML / JSON 配置文件加载配置，读取文件的 im 段（没有时读取整个文档），可与 user、storage 共用一个文档：
+//
+//	im:
+//	  server_id: server-1
+//	  grpc_addr: 0.0.0.0:50051
+//	  cluster_key: change-me
+//	  cache_ttl: 30
+//	  heartbeat_interval: 15
+//	  presence_timeout: 45
+//	  affinity_cookie: im_node
+//	  max_message_size: 4096
+//	  rate_limit: 10
+//	  bandwidth_limit: 65536
+//	  compression: true
+//	  trust_proxy: false
+//	  guest: true
+//	  guest_send_to: [10001, 10002]
+//	  support_user_id: 10000
+//	  support_strategy: least_busy
+//	  auto_reply_interval: 3600
+//	  write_batch_size: 100
+//	  table_prefix: app_
+//	  table_names: {im_messages: chat_messages}
+//	  tenant_id: app-a
+//	  auto_migrate: true
+//
+// FromEnv 支持的环境变量覆盖文件中的值；文件不存在、格式错误、含未知字段或环境变量不合法时 Build 返回错误
+// 数据库连接、认证函数等无法序列化的配置仍需通过 With 方法设置
+func (b *Builder) FromFile(path string) *Builder {
 	if b.err != nil {
-		return nil, b.err
+		return b
 	}
 
-	// 验证必需参数
-	if b.config.ServerID == "" {
-		return nil, fmt.Errorf("server_id is required")
+	var fc fileConfig
+	if err := config.Load(path, "im", &fc); err != nil {
+		b.err = fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		return b
+	}
+	if fc.ServerID != "" {
+		b.config.ServerID = fc.ServerID
+	}
+	if fc.GRPCAddr != "" {
+		b.config.GRPCAddr = fc.GRPCAddr
+	}
+	if fc.ClusterKey != "" {
+		b.config.ClusterKey = fc.ClusterKey
+	}
+	if fc.CacheTTL != nil {
+		b.config.CacheTTL = *fc.CacheTTL
+	}
+	if fc.HeartbeatInterval != nil {
+		b.config.HeartbeatInterval = *fc.HeartbeatInterval
+	}
+	if fc.PresenceTimeout != nil {
+		b.config.PresenceTimeout = *fc.PresenceTimeout
+	}
+	if fc.AffinityToken != "" {
+		b.config.AffinityToken = fc.AffinityToken
+	}
+	if fc.AffinityCookie != "" {
+		b.config.AffinityCookie = fc.AffinityCookie
+	}
+	if fc.MaxMessageSize != nil {
+		b.config.MaxMessageSize = *fc.MaxMessageSize
+	}
+	if fc.RateLimit != nil {
+		b.config.RateLimit = *fc.RateLimit
 	}
+	if fc.BandwidthLimit != nil {
+		b.config.BandwidthLimit = *fc.BandwidthLimit
+	}
+	if fc.Compression != nil {
+		b.config.EnableCompression = *fc.Compression
+	}
+	if fc.TrustProxy != nil {
+		b.config.TrustProxy = *fc.TrustProxy
+	}
+	if fc.Guest != nil {
+		b.config.EnableGuest = *fc.Guest
+	}
+	if fc.GuestSendTo != nil {
+		b.config.GuestSendTo = fc.GuestSendTo
+	}
+	if fc.SupportUserID != nil {
+		b.config.SupportUserID = *fc.SupportUserID
+	}
+	if fc.SupportStrategy != "" {
+		b.config.SupportStrategy = fc.SupportStrategy
+	}
+	if fc.AutoReplyInterval != nil {
+		b.config.AutoReplyInterval = *fc.AutoReplyInterval
+	}
+	if fc.WriteBatchSize != nil {
+		b.config.WriteBatchSize = *fc.WriteBatchSize
+	}
+	if fc.TablePrefix != "" {
+		b.config.TablePrefix = fc.TablePrefix
+	}
+	for defaultName, name := range fc.TableNames {
+		b.WithTableName(defaultName, name)
+	}
+	if fc.TenantID != "" {
+		b.config.TenantID = fc.TenantID
+	}
+	if fc.AutoMigrate != nil {
+		b.config.DisableAutoMigrate = !*fc.AutoMigrate
+	}
+	return b
+}
 
-	if b.config.DB == nil {
-		return nil, fmt.Errorf("database connection is required")
+// Validate 校验配置，返回全部不合法项（errors.Join），每项满足 errors.Is(err, ErrInvalidConfig)
+func (b *Builder) Validate() error {
+	if b.err != nil {
+		return b.err
 	}
 
+	var errs []error
+	invalid := func(msg string) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidConfig, msg))
+	}
+	if b.config.ServerID == "" {
+		invalid("server_id is required")
+	}
+	if b.config.DB == nil {
+		invalid("database connection is required")
+	}
 	if b.config.AuthFunc == nil {
-		return nil, fmt.Errorf("auth function is required")
+		invalid("auth function is required")
+	}
+	if b.config.CacheTTL < 0 {
+		invalid("cache ttl must not be negative")
+	}
+	if b.config.HeartbeatInterval <= 0 {
+		invalid("heartbeat interval must be positive")
+	}
+	if b.config.PresenceTimeout < 0 {
+		invalid("presence timeout must not be negative")
+	} else if b.config.PresenceTimeout > 0 && b.config.PresenceTimeout <= b.config.HeartbeatInterval {
+		invalid("presence timeout must be greater than heartbeat interval")
+	}
+	if b.config.AffinityCookie != "" {
+		token := b.config.AffinityToken
+		if token == "" {
+			token = b.config.ServerID
+		}
+		if err := (&http.Cookie{Name: b.config.AffinityCookie, Value: token}).Valid(); err != nil {
+			invalid("affinity cookie: " + err.Error())
+		}
+	}
+	if b.config.MaxMessageSize < 0 {
+		invalid("max message size must not be negative")
+	}
+	if b.config.RateLimit < 0 {
+		invalid("rate limit must not be negative")
+	}
+	if b.config.BandwidthLimit < 0 {
+		invalid("bandwidth limit must not be negative")
+	}
+	for _, id := range b.config.GuestSendTo {
+		if id <= 0 {
+			invalid("guest send to must be positive user ids")
+			break
+		}
+	}
+	if b.config.SupportUserID < 0 {
+		invalid("support user id must not be negative")
+	}
+	switch b.config.SupportStrategy {
+	case "", SupportStrategyLeastBusy, SupportStrategyRoundRobin:
+	default:
+		invalid("support strategy must be least_busy or round_robin")
+	}
+	if b.config.AutoReplyInterval < 0 {
+		invalid("auto reply interval must not be negative")
+	}
+	if b.config.WriteBatchSize < 0 || b.config.WriteBatchDelay < 0 {
+		invalid("write batch size and delay must not be negative")
+	}
+	if len(b.config.TenantID) > 64 {
+		invalid("tenant id must be at most 64 characters")
+	}
+	if b.config.GRPCAddr != "" {
+		if _, _, err := net.SplitHostPort(b.config.GRPCAddr); err != nil {
+			invalid("grpc addr: " + err.Error())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Preflight 校验配置并检查外部依赖，在 Build / Start 之前发现问题，而不是运行中才失败
+// 检查数据库连通（含只读库）、禁止自动迁移时迁移是否已执行、gRPC 地址能否监听、路由表中其他活跃节点能否连接
+// 返回全部失败项（errors.Join），配置错误满足 errors.Is(err, ErrInvalidConfig)，依赖检查失败满足 errors.Is(err, ErrPreflightFailed)
+func (b *Builder) Preflight(ctx context.Context) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	return core.Preflight(ctx, b.config)
+}
+
+// Build 构建 IM 服务实例
+func (b *Builder) Build() (IMService, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
 	}
 
 	return core.NewIMServer(b.config)
@@ -0,0 +1,18 @@
+package im
+
+import "github.com/bbadbeef/go-base/errcode"
+
+// 将 IM 模块的错误绑定到统一错误码目录
+func init() {
+	errcode.Bind(ErrInvalidConfig, errcode.InvalidConfig)
+	errcode.Bind(ErrInvalidParam, errcode.InvalidParam)
+	errcode.Bind(ErrGroupNotFound, errcode.GroupNotFound)
+	errcode.Bind(ErrNotGroupMember, errcode.NotGroupMember)
+	errcode.Bind(ErrGroupPermissionDenied, errcode.GroupPermissionDenied)
+	errcode.Bind(ErrGroupPolicy, errcode.GroupPolicyViolation)
+	errcode.Bind(ErrMessageNotFound, errcode.MessageNotFound)
+	errcode.Bind(ErrReportNotFound, errcode.ReportNotFound)
+	errcode.Bind(ErrFileAccessDenied, errcode.FileAccessDenied)
+	errcode.Bind(ErrSupportConversationNotFound, errcode.SupportConversationNotFound)
+	errcode.Bind(ErrSupportAgentNotFound, errcode.SupportAgentNotFound)
+}
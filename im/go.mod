@@ -3,6 +3,11 @@ module github.com/bbadbeef/go-base/im
 go 1.21
 
 require (
+	github.com/bbadbeef/go-base/config v0.0.0
+	github.com/bbadbeef/go-base/errcode v0.0.0
+	github.com/bbadbeef/go-base/log v0.0.0
+	github.com/bbadbeef/go-base/observability v0.0.0
+	github.com/glebarez/sqlite v1.11.0
 	github.com/gorilla/websocket v1.5.1
 	github.com/sirupsen/logrus v1.9.3
 	google.golang.org/grpc v1.60.1
@@ -10,16 +15,40 @@ require (
 )
 
 require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/glebarez/go-sqlite v1.21.2 // indirect
+	github.com/google/uuid v1.3.1 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/mattn/go-isatty v0.0.19 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+	modernc.org/libc v1.22.5 // indirect
+	modernc.org/mathutil v1.5.0 // indirect
+	modernc.org/memory v1.5.0 // indirect
+	modernc.org/sqlite v1.23.1 // indirect
 )
 
 require (
+	github.com/bbadbeef/go-base/idgen v0.0.0
+	github.com/bbadbeef/go-base/migrate v0.0.0
 	github.com/golang/protobuf v1.5.3 // indirect
 	golang.org/x/net v0.19.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231212172506-995d672761c0 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
-	gorm.io/gorm v1.25.5
+	gorm.io/gorm v1.25.7
 )
+
+replace github.com/bbadbeef/go-base/errcode => ../errcode
+
+replace github.com/bbadbeef/go-base/observability => ../observability
+
+replace github.com/bbadbeef/go-base/log => ../log
+
+replace github.com/bbadbeef/go-base/config => ../config
+
+replace github.com/bbadbeef/go-base/idgen => ../idgen
+
+replace github.com/bbadbeef/go-base/migrate => ../migrate
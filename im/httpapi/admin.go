@@ -0,0 +1,299 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/im"
+)
+
+// kickRequest 踢下线请求
+type kickRequest struct {
+	UserID int64  `json:"user_id"`
+	Reason string `json:"reason"` // admin（默认）、banned、logged_in_elsewhere
+}
+
+// NewKickHandler 创建踢下线接口（仅 POST），请求体 {"user_id":1,"reason":"banned"}，返回 kicked 表示用户是否在线
+//
+// 接口本身不做认证，应挂载在管理员权限校验之后，如 user.RequireRole(svc, "admin")
+func NewKickHandler(svc im.IMService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var req kickRequest
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<10)
+		if err := decode(r, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if req.Reason == "" {
+			req.Reason = im.KickReasonAdmin
+		}
+		kicked, err := svc.KickUser(r.Context(), req.UserID, req.Reason)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, r, map[string]bool{"kicked": kicked})
+	})
+}
+
+// NewClusterHandler 创建集群拓扑查询接口（仅 GET），返回活跃节点及各节点连接数、版本，见 im.IMService.ClusterInfo
+//
+// 接口本身不做认证，应挂载在管理员权限校验之后，如 user.RequireRole(svc, "admin")
+func NewClusterHandler(svc im.IMService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		info, err := svc.ClusterInfo(r.Context())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeData(w, r, info)
+	})
+}
+
+// NewTrafficHandler 创建当前节点 WebSocket 流量查询接口（仅 GET）
+//
+//	GET ?user_id=1   指定用户连接的流量，不在本节点时返回 404
+//	GET ?limit=20    按收发总字节数倒序的连接列表，limit 默认 20，最大 100
+//
+// 接口本身不做认证，应挂载在管理员权限校验之后，如 user.RequireRole(svc, "admin")
+func NewTrafficHandler(svc im.IMService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		userID, err := queryInt64(r, "user_id")
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if userID > 0 {
+			stats, ok := svc.UserTraffic(userID)
+			if !ok {
+				writeError(w, r, errcode.New(errcode.NotFound, requestLang(r)))
+				return
+			}
+			writeData(w, r, stats)
+			return
+		}
+
+		limit, err := queryLimit(r)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if limit == 0 {
+			limit = 20
+		}
+		writeData(w, r, map[string]interface{}{"connections": svc.Traffic(limit)})
+	})
+}
+
+// resolveReportRequest 处理举报请求
+type resolveReportRequest struct {
+	ReportID int64  `json:"report_id"`
+	Status   int    `json:"status"` // 2 已处理（确认违规）、3 已驳回
+	Note     string `json:"note"`
+}
+
+// NewReportHandler 创建举报审核接口
+//
+//	GET  ?status=1&cursor=&limit=20                  按举报时间倒序的举报列表，status 为空表示全部，limit 默认 20，最大 100
+//	POST {"report_id":1,"status":2,"note":"..."}     处理举报，举报不存在或已处理时返回 404
+//
+// auth 用于取当前管理员 ID 记录为处理人，为 nil 时不记录
+// 接口本身不做权限校验，应挂载在管理员权限校验之后，如 user.RequireRole(svc, "admin")
+func NewReportHandler(svc im.IMService, auth AuthFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			listReports(svc, w, r)
+		case http.MethodPost:
+			resolveReport(svc, auth, w, r)
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+			writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})
+}
+
+// listReports 分页获取举报
+func listReports(svc im.IMService, w http.ResponseWriter, r *http.Request) {
+	status, err := queryInt64(r, "status")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req := &im.ListReportsRequest{Status: int(status), Cursor: r.URL.Query().Get("cursor")}
+	if req.Limit, err = queryLimit(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := svc.ListReports(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, page)
+}
+
+// resolveReport 处理举报
+func resolveReport(svc im.IMService, auth AuthFunc, w http.ResponseWriter, r *http.Request) {
+	var resolverID int64
+	if auth != nil {
+		var err error
+		if resolverID, err = auth(r); err != nil {
+			writeUnauthorized(w, r, err)
+			return
+		}
+	}
+
+	var req resolveReportRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 4<<10)
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	report, err := svc.ResolveReport(r.Context(), req.ReportID, resolverID, req.Status, req.Note)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, report)
+}
+
+// supportAgentRequest 设置客服请求
+type supportAgentRequest struct {
+	UserID           int64 `json:"user_id"`
+	MaxConversations int   `json:"max_conversations"` // 同时接待的会话数上限，0 表示不限制
+}
+
+// NewSupportAgentHandler 创建客服管理接口，见 im.Config.SupportUserID
+//
+//	GET                                             全部客服及其进行中的会话数、在线状态
+//	PUT    {"user_id":1,"max_conversations":5}      添加客服或修改会话数上限
+//	DELETE ?user_id=1                               移除客服，不存在时返回 404，已接待的会话保留
+//
+// 接口本身不做认证，应挂载在管理员权限校验之后，如 user.RequireRole(svc, "admin")
+func NewSupportAgentHandler(svc im.IMService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			agents, err := svc.ListSupportAgents(r.Context())
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			writeData(w, r, map[string]interface{}{"agents": agents})
+		case http.MethodPut:
+			var req supportAgentRequest
+			r.Body = http.MaxBytesReader(w, r.Body, 1<<10)
+			if err := decode(r, &req); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if err := svc.AddSupportAgent(r.Context(), req.UserID, req.MaxConversations); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			writeData(w, r, nil)
+		case http.MethodDelete:
+			userID, err := queryInt64(r, "user_id")
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if err := svc.RemoveSupportAgent(r.Context(), userID); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			writeData(w, r, nil)
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPut+", "+http.MethodDelete)
+			writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})
+}
+
+// NewSupportConversationHandler 创建客服会话管理接口
+//
+//	GET    ?agent_id=&status=&cursor=&limit=20        按创建时间倒序的会话列表，agent_id、status 为空表示全部，limit 默认 20，最大 100
+//	POST   {"conversation_id":1,"agent_id":2}         转接会话，agent_id 为 0 时自动分配，会话不存在或已结束时返回 404
+//	DELETE ?conversation_id=1                         结束会话，会话不存在或已结束时返回 404
+//
+// 接口本身不做认证，应挂载在管理员权限校验之后，如 user.RequireRole(svc, "admin")
+func NewSupportConversationHandler(svc im.IMService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			listSupportConversations(svc, w, r)
+		case http.MethodPost:
+			var req supportConversationRequest
+			r.Body = http.MaxBytesReader(w, r.Body, 1<<10)
+			if err := decode(r, &req); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			conv, err := svc.TransferSupportConversation(r.Context(), req.ConversationID, req.AgentID)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			writeData(w, r, conv)
+		case http.MethodDelete:
+			id, err := queryInt64(r, "conversation_id")
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			conv, err := svc.CloseSupportConversation(r.Context(), id)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			writeData(w, r, conv)
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost+", "+http.MethodDelete)
+			writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})
+}
+
+// listSupportConversations 分页获取客服会话
+func listSupportConversations(svc im.IMService, w http.ResponseWriter, r *http.Request) {
+	agentID, err := queryInt64(r, "agent_id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	status, err := queryInt64(r, "status")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req := &im.ListSupportConversationsRequest{AgentID: agentID, Status: int(status), Cursor: r.URL.Query().Get("cursor")}
+	if req.Limit, err = queryLimit(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := svc.ListSupportConversations(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, page)
+}
@@ -0,0 +1,320 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bbadbeef/go-base/im"
+)
+
+// groupPath 解析 /groups/{id}[/members[/{userID}]]，返回群组 ID 和其后的路径段
+// 路径不合法时 ok 为 false
+func groupPath(path string) (groupID int64, rest []string, ok bool) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(strings.TrimPrefix(path, "/"), "groups/"), "/"), "/")
+	groupID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || groupID <= 0 {
+		return 0, nil, false
+	}
+	return groupID, parts[1:], true
+}
+
+// isMembersPath 路径段是否为 members
+func isMembersPath(rest []string) bool {
+	return len(rest) == 1 && rest[0] == "members"
+}
+
+// isPolicyPath 路径段是否为 policy
+func isPolicyPath(rest []string) bool {
+	return len(rest) == 1 && rest[0] == "policy"
+}
+
+// requireRole 要求用户在群内的角色不低于 role，不是群成员返回 ErrNotGroupMember，权限不足返回 ErrGroupPermissionDenied
+func (h *Handler) requireRole(ctx context.Context, groupID, userID int64, role int) (*im.GroupMember, error) {
+	member, err := h.svc.GetGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role < role {
+		return nil, im.ErrGroupPermissionDenied
+	}
+	return member, nil
+}
+
+// checkGroupUsers 校验单次提交的用户数
+func (h *Handler) checkGroupUsers(field string, ids []int64) error {
+	if len(ids) > h.config.MaxGroupUsers {
+		return &im.ParamError{Field: field, Message: "too many " + field}
+	}
+	return nil
+}
+
+// createGroup 创建群组，当前用户为群主
+func (h *Handler) createGroup(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req struct {
+		GroupName string  `json:"group_name"`
+		AvatarURL string  `json:"avatar_url"`
+		MemberIDs []int64 `json:"member_ids"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.checkGroupUsers("member_ids", req.MemberIDs); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	group := &im.Group{GroupName: req.GroupName, AvatarURL: req.AvatarURL, OwnerID: userID}
+	if err := h.svc.CreateGroup(r.Context(), group, req.MemberIDs); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, group)
+}
+
+// getUserGroups 获取当前用户加入的群组
+func (h *Handler) getUserGroups(w http.ResponseWriter, r *http.Request, userID int64) {
+	groups, err := h.svc.GetUserGroups(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, groups)
+}
+
+// getGroupResource 分发 GET /groups/{id}、GET /groups/{id}/members 和 GET /groups/{id}/policy
+func (h *Handler) getGroupResource(w http.ResponseWriter, r *http.Request, userID int64) {
+	groupID, rest, ok := groupPath(r.URL.Path)
+	switch {
+	case ok && len(rest) == 0:
+		h.getGroup(w, r, userID, groupID)
+	case ok && isMembersPath(rest):
+		h.getGroupMembers(w, r, userID, groupID)
+	case ok && isPolicyPath(rest):
+		h.getGroupPolicy(w, r, userID, groupID)
+	default:
+		writeStatus(w, r, http.StatusNotFound, "not found")
+	}
+}
+
+// updateGroupResource 分发 PUT /groups/{id} 和 PUT /groups/{id}/policy
+func (h *Handler) updateGroupResource(w http.ResponseWriter, r *http.Request, userID int64) {
+	groupID, rest, ok := groupPath(r.URL.Path)
+	switch {
+	case ok && len(rest) == 0:
+		h.updateGroup(w, r, userID, groupID)
+	case ok && isPolicyPath(rest):
+		h.updateGroupPolicy(w, r, userID, groupID)
+	default:
+		writeStatus(w, r, http.StatusNotFound, "not found")
+	}
+}
+
+// deleteGroupResource 分发 DELETE /groups/{id} 和 DELETE /groups/{id}/members/{userID}
+func (h *Handler) deleteGroupResource(w http.ResponseWriter, r *http.Request, userID int64) {
+	groupID, rest, ok := groupPath(r.URL.Path)
+	switch {
+	case ok && len(rest) == 0:
+		h.deleteGroup(w, r, userID, groupID)
+	case ok && len(rest) == 2 && rest[0] == "members":
+		memberID, err := strconv.ParseInt(rest[1], 10, 64)
+		if err != nil || memberID <= 0 {
+			writeError(w, r, &im.ParamError{Field: "user_id", Message: "invalid user id"})
+			return
+		}
+		h.removeGroupMember(w, r, userID, groupID, memberID)
+	default:
+		writeStatus(w, r, http.StatusNotFound, "not found")
+	}
+}
+
+// getGroup 获取群组信息，仅群成员可见
+func (h *Handler) getGroup(w http.ResponseWriter, r *http.Request, userID, groupID int64) {
+	group, err := h.svc.GetGroup(r.Context(), groupID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if _, err := h.svc.GetGroupMember(r.Context(), groupID, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, group)
+}
+
+// getGroupMembers 获取群成员列表，仅群成员可见
+func (h *Handler) getGroupMembers(w http.ResponseWriter, r *http.Request, userID, groupID int64) {
+	if _, err := h.svc.GetGroupMember(r.Context(), groupID, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	members, err := h.svc.GetGroupMembers(r.Context(), groupID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, members)
+}
+
+// updateGroup 修改群组名称和头像，需群主或管理员，未提供的字段保持不变
+func (h *Handler) updateGroup(w http.ResponseWriter, r *http.Request, userID, groupID int64) {
+	var req struct {
+		GroupName *string `json:"group_name"`
+		AvatarURL *string `json:"avatar_url"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	group, err := h.svc.GetGroup(r.Context(), groupID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if _, err := h.requireRole(r.Context(), groupID, userID, im.GroupRoleAdmin); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.GroupName != nil {
+		group.GroupName = *req.GroupName
+	}
+	if req.AvatarURL != nil {
+		group.AvatarURL = *req.AvatarURL
+	}
+	if err := h.svc.UpdateGroup(r.Context(), group); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, group)
+}
+
+// getGroupPolicy 获取群组防刷屏策略，仅群成员可见
+func (h *Handler) getGroupPolicy(w http.ResponseWriter, r *http.Request, userID, groupID int64) {
+	policy, err := h.svc.GetGroupPolicy(r.Context(), groupID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if _, err := h.svc.GetGroupMember(r.Context(), groupID, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, policy)
+}
+
+// updateGroupPolicy 修改群组防刷屏策略，需群主或管理员，未提供的字段保持不变
+func (h *Handler) updateGroupPolicy(w http.ResponseWriter, r *http.Request, userID, groupID int64) {
+	var req struct {
+		SlowMode     *int  `json:"slow_mode"`
+		BlockLinks   *bool `json:"block_links"`
+		BlockImages  *bool `json:"block_images"`
+		NewcomerMute *int  `json:"newcomer_mute"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	policy, err := h.svc.GetGroupPolicy(r.Context(), groupID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if _, err := h.requireRole(r.Context(), groupID, userID, im.GroupRoleAdmin); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.SlowMode != nil {
+		policy.SlowMode = *req.SlowMode
+	}
+	if req.BlockLinks != nil {
+		policy.BlockLinks = *req.BlockLinks
+	}
+	if req.BlockImages != nil {
+		policy.BlockImages = *req.BlockImages
+	}
+	if req.NewcomerMute != nil {
+		policy.NewcomerMute = *req.NewcomerMute
+	}
+	if err := h.svc.SetGroupPolicy(r.Context(), policy); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, policy)
+}
+
+// deleteGroup 解散群组，仅群主
+func (h *Handler) deleteGroup(w http.ResponseWriter, r *http.Request, userID, groupID int64) {
+	if _, err := h.svc.GetGroup(r.Context(), groupID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if _, err := h.requireRole(r.Context(), groupID, userID, im.GroupRoleOwner); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.svc.DeleteGroup(r.Context(), groupID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// addGroupMembers 添加群成员，需群主或管理员
+func (h *Handler) addGroupMembers(w http.ResponseWriter, r *http.Request, userID int64) {
+	groupID, rest, ok := groupPath(r.URL.Path)
+	if !ok || !isMembersPath(rest) {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	var req struct {
+		UserIDs []int64 `json:"user_ids"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.checkGroupUsers("user_ids", req.UserIDs); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if _, err := h.requireRole(r.Context(), groupID, userID, im.GroupRoleAdmin); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.svc.AddGroupMembers(r.Context(), groupID, req.UserIDs); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// removeGroupMember 移除群成员，移除自己即退群（群主需解散群组）
+// 其他情况需操作者角色高于被移除者：群主可移除管理员和成员，管理员只能移除成员
+func (h *Handler) removeGroupMember(w http.ResponseWriter, r *http.Request, userID, groupID, memberID int64) {
+	if memberID != userID {
+		operator, err := h.svc.GetGroupMember(r.Context(), groupID, userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		target, err := h.svc.GetGroupMember(r.Context(), groupID, memberID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if operator.Role <= target.Role {
+			writeError(w, r, im.ErrGroupPermissionDenied)
+			return
+		}
+	}
+	if err := h.svc.RemoveGroupMember(r.Context(), groupID, memberID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
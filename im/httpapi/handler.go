@@ -0,0 +1,190 @@
+// Package httpapi 提供可直接挂载的 IM REST 接口（会话、历史消息、发送、已读、举报、在线状态、离开自动回复、客服会话、群组管理）
+//
+//	mux.Handle("/api/im/", http.StripPrefix("/api/im", httpapi.NewHandler(imService, httpapi.BearerAuth(verifyToken), nil)))
+package httpapi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/im"
+)
+
+// AuthFunc 从请求中解析当前用户 ID，失败时返回错误
+// 返回的错误未绑定错误码时按 errcode.Unauthorized 响应
+type AuthFunc func(r *http.Request) (userID int64, err error)
+
+// BearerAuth 从 Authorization: Bearer <token> 请求头取令牌并调用 verify 校验
+// verify 通常与传给 Builder.WithAuthFunc 的函数相同
+func BearerAuth(verify func(token string) (userID int64, err error)) AuthFunc {
+	return func(r *http.Request) (int64, error) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			return 0, errcode.New(errcode.Unauthorized, requestLang(r))
+		}
+		return verify(strings.TrimSpace(token))
+	}
+}
+
+// Config HTTP 接口配置，零值字段使用默认值
+type Config struct {
+	MaxBodyBytes  int64 // 请求体大小上限，默认 1 MiB
+	MaxOnlineIDs  int   // /online、/presence 单次查询的用户数上限，默认 100
+	MaxGroupUsers int   // 创建群组、添加成员单次提交的用户数上限，默认 200
+}
+
+// withDefaults 填充默认值
+func (c Config) withDefaults() Config {
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+	if c.MaxOnlineIDs <= 0 {
+		c.MaxOnlineIDs = 100
+	}
+	if c.MaxGroupUsers <= 0 {
+		c.MaxGroupUsers = 200
+	}
+	return c
+}
+
+// authHandler 需要登录的处理函数，userID 为当前用户
+type authHandler func(w http.ResponseWriter, r *http.Request, userID int64)
+
+// Handler IM REST 接口，所有路由都需要登录
+type Handler struct {
+	svc    im.IMService
+	auth   AuthFunc
+	config Config
+	routes map[string]map[string]authHandler // path -> method -> handler
+}
+
+// NewHandler 创建 IM REST 接口，config 为 nil 时使用默认值
+// 路由相对于挂载点，通常配合 http.StripPrefix 使用：
+//
+//	GET    /sessions                      会话列表（cursor、limit），返回 sessions、next_cursor、has_more
+//	POST   /sessions/read                 会话已读（target_id、up_to_time）
+//	POST   /sessions/mute                 会话免打扰（target_id、session_type、duration 秒，0 表示永久）
+//	DELETE /sessions/mute                 取消会话免打扰（target_id、session_type）
+//	GET    /messages                      历史消息（target_id、session_type、cursor、before_time、limit），返回 messages、next_cursor、has_more
+//	POST   /send                          发送消息（群聊消息检查群成员和防刷屏策略）
+//	POST   /read                          标记发给我的消息已读（msg_ids，最多 100 个）
+//	POST   /report                        举报消息（msg_id、reason），返回 report_id、status
+//	GET    /online                        是否在线（user_ids=1,2,3），隐身用户显示为离线
+//	GET    /presence                      在线状态（user_ids=1,2,3），返回 {"<userID>": {status, text}}
+//	PUT    /presence                      设置我的在线状态（status：online、busy、away、invisible，text）
+//	GET    /away                          我的离开状态，未设置时 data 为 null
+//	PUT    /away                          设置离开状态（message 为自动回复内容）
+//	DELETE /away                          清除离开状态
+//	GET    /support/conversations         我接待的客服会话（status、cursor、limit），返回 conversations、next_cursor、has_more
+//	POST   /support/transfer              转接我接待的客服会话（conversation_id、agent_id，0 表示自动分配）
+//	POST   /support/close                 结束客服会话（conversation_id，接待的客服或客户）
+//	POST   /groups                        创建群组
+//	GET    /groups                        我加入的群组
+//	GET    /groups/{id}                   群组信息（群成员）
+//	PUT    /groups/{id}                   修改群组（群主、管理员，也支持 PATCH）
+//	DELETE /groups/{id}                   解散群组（群主）
+//	GET    /groups/{id}/members           群成员列表（群成员）
+//	GET    /groups/{id}/policy            群组防刷屏策略（群成员）
+//	PUT    /groups/{id}/policy            修改防刷屏策略（群主、管理员，也支持 PATCH）
+//	POST   /groups/{id}/members           添加群成员（群主、管理员）
+//	DELETE /groups/{id}/members/{userID}  移除群成员（群主、管理员），移除自己即退群
+func NewHandler(svc im.IMService, auth AuthFunc, config *Config) *Handler {
+	var cfg Config
+	if config != nil {
+		cfg = *config
+	}
+	h := &Handler{
+		svc:    svc,
+		auth:   auth,
+		config: cfg.withDefaults(),
+		routes: make(map[string]map[string]authHandler),
+	}
+
+	h.handle(http.MethodGet, "/sessions", h.getSessions)
+	h.handle(http.MethodPost, "/sessions/read", h.markConversationRead)
+	h.handle(http.MethodPost, "/sessions/mute", h.muteSession)
+	h.handle(http.MethodDelete, "/sessions/mute", h.unmuteSession)
+	h.handle(http.MethodGet, "/messages", h.getMessages)
+	h.handle(http.MethodPost, "/send", h.sendMessage)
+	h.handle(http.MethodPost, "/read", h.markAsRead)
+	h.handle(http.MethodPost, "/report", h.reportMessage)
+	h.handle(http.MethodGet, "/online", h.checkOnline)
+	h.handle(http.MethodGet, "/presence", h.getPresences)
+	h.handle(http.MethodPut, "/presence", h.setPresence)
+	h.handle(http.MethodGet, "/away", h.getAwayStatus)
+	h.handle(http.MethodPut, "/away", h.setAwayStatus)
+	h.handle(http.MethodDelete, "/away", h.clearAwayStatus)
+	h.handle(http.MethodGet, "/support/conversations", h.getSupportConversations)
+	h.handle(http.MethodPost, "/support/transfer", h.transferSupportConversation)
+	h.handle(http.MethodPost, "/support/close", h.closeSupportConversation)
+	h.handle(http.MethodPost, "/groups", h.createGroup)
+	h.handle(http.MethodGet, "/groups", h.getUserGroups)
+	h.handle(http.MethodGet, "/groups/", h.getGroupResource)
+	h.handle(http.MethodPut, "/groups/", h.updateGroupResource)
+	h.handle(http.MethodPatch, "/groups/", h.updateGroupResource)
+	h.handle(http.MethodDelete, "/groups/", h.deleteGroupResource)
+	h.handle(http.MethodPost, "/groups/", h.addGroupMembers)
+	return h
+}
+
+// handle 注册路由，以 / 结尾的路径按前缀匹配
+func (h *Handler) handle(method, path string, handler authHandler) {
+	if h.routes[path] == nil {
+		h.routes[path] = make(map[string]authHandler)
+	}
+	h.routes[path][method] = handler
+}
+
+// ServeHTTP 分发请求，未知路径返回 404，方法不支持返回 405，认证失败返回 401
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	methods, ok := h.match(r.URL.Path)
+	if !ok {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	handler, ok := methods[r.Method]
+	if !ok {
+		allowed := make([]string, 0, len(methods))
+		for m := range methods {
+			allowed = append(allowed, m)
+		}
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	userID, err := h.auth(r)
+	if err == nil && userID <= 0 {
+		err = errcode.New(errcode.Unauthorized, requestLang(r))
+	}
+	if err != nil {
+		writeUnauthorized(w, r, err)
+		return
+	}
+
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxBodyBytes)
+	}
+	handler(w, r, userID)
+}
+
+// match 按路径查找路由，先精确匹配再按前缀匹配
+func (h *Handler) match(path string) (map[string]authHandler, bool) {
+	if path == "" {
+		path = "/"
+	} else if path[0] != '/' {
+		path = "/" + path
+	}
+	if methods, ok := h.routes[path]; ok {
+		return methods, true
+	}
+	for p, methods := range h.routes {
+		if strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) && len(path) > len(p) {
+			return methods, true
+		}
+	}
+	return nil, false
+}
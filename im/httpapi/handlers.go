@@ -0,0 +1,374 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbadbeef/go-base/im"
+)
+
+// decode 解析 JSON 请求体
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &im.ParamError{Field: "body", Message: "request body too large"}
+		}
+		if errors.Is(err, io.EOF) {
+			return &im.ParamError{Field: "body", Message: "request body is required"}
+		}
+		return &im.ParamError{Field: "body", Message: "invalid JSON body"}
+	}
+	return nil
+}
+
+// queryInt64 解析查询参数，未提供时返回 0
+func queryInt64(r *http.Request, name string) (int64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, &im.ParamError{Field: name, Message: "invalid " + name}
+	}
+	return v, nil
+}
+
+// queryLimit 解析每页条数，超过上限时按上限处理
+func queryLimit(r *http.Request) (int, error) {
+	limit, err := queryInt64(r, "limit")
+	if err != nil {
+		return 0, err
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return int(limit), nil
+}
+
+// getSessions 分页获取会话列表
+func (h *Handler) getSessions(w http.ResponseWriter, r *http.Request, userID int64) {
+	req := &im.GetSessionsRequest{UserID: userID, Cursor: r.URL.Query().Get("cursor")}
+	var err error
+	if req.Limit, err = queryLimit(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page, err := h.svc.GetSessionPage(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, page)
+}
+
+// getMessages 分页获取历史消息，群聊需为群成员
+func (h *Handler) getMessages(w http.ResponseWriter, r *http.Request, userID int64) {
+	req := &im.GetMessagesRequest{UserID: userID, SessionType: im.SessionTypeSingle}
+	var err error
+	if req.TargetID, err = queryInt64(r, "target_id"); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.TargetID == 0 {
+		writeError(w, r, &im.ParamError{Field: "target_id", Message: "target_id is required"})
+		return
+	}
+	sessionType, err := queryInt64(r, "session_type")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if sessionType != 0 {
+		req.SessionType = int(sessionType)
+	}
+	if req.SessionType != im.SessionTypeSingle && req.SessionType != im.SessionTypeGroup {
+		writeError(w, r, &im.ParamError{Field: "session_type", Message: "invalid session_type"})
+		return
+	}
+	if req.BeforeTime, err = queryInt64(r, "before_time"); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req.Cursor = r.URL.Query().Get("cursor")
+	if req.Limit, err = queryLimit(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if req.SessionType == im.SessionTypeGroup {
+		if _, err := h.svc.GetGroupMember(r.Context(), req.TargetID, userID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	page, err := h.svc.GetMessagePage(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, page)
+}
+
+// sendMessage 以当前用户身份发送消息，群聊需为群成员
+func (h *Handler) sendMessage(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req im.SendMessageRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req.FromUserID = userID
+	if req.MsgType == 0 {
+		req.MsgType = im.MsgTypeText
+	}
+
+	if req.GroupID != 0 {
+		if err := h.svc.CheckGroupMessage(r.Context(), req.GroupID, userID, req.Content, req.MsgType); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	} else if req.ToUserID <= 0 {
+		writeError(w, r, &im.ParamError{Field: "to_user_id", Message: "to_user_id or group_id is required"})
+		return
+	}
+
+	if err := h.svc.SendMessage(r.Context(), &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// markAsRead 标记消息为已读
+func (h *Handler) markAsRead(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req struct {
+		MsgIDs []string `json:"msg_ids"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if len(req.MsgIDs) == 0 {
+		writeError(w, r, &im.ParamError{Field: "msg_ids", Message: "msg_ids is required"})
+		return
+	}
+	if err := h.svc.MarkAsRead(r.Context(), userID, req.MsgIDs); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// reportMessage 举报消息，只能举报自己可见的消息
+func (h *Handler) reportMessage(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req struct {
+		MsgID  string `json:"msg_id"`
+		Reason string `json:"reason"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	report, err := h.svc.ReportMessage(r.Context(), userID, req.MsgID, req.Reason)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, map[string]interface{}{"report_id": report.ID, "status": report.Status})
+}
+
+// markConversationRead 将会话中 up_to_time 之前的消息标记为已读，up_to_time 为空表示全部
+func (h *Handler) markConversationRead(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req struct {
+		TargetID int64 `json:"target_id"`
+		UpToTime int64 `json:"up_to_time"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.TargetID <= 0 {
+		writeError(w, r, &im.ParamError{Field: "target_id", Message: "target_id is required"})
+		return
+	}
+	if err := h.svc.MarkConversationRead(r.Context(), userID, req.TargetID, req.UpToTime); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// muteSession 会话免打扰 duration 秒，duration 为 0 表示永久
+func (h *Handler) muteSession(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req struct {
+		TargetID    int64 `json:"target_id"`
+		SessionType int   `json:"session_type"`
+		Duration    int64 `json:"duration"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if req.TargetID <= 0 {
+		writeError(w, r, &im.ParamError{Field: "target_id", Message: "target_id is required"})
+		return
+	}
+	if req.Duration < 0 {
+		writeError(w, r, &im.ParamError{Field: "duration", Message: "invalid duration"})
+		return
+	}
+	if err := h.svc.MuteSession(r.Context(), userID, req.TargetID, req.SessionType, time.Duration(req.Duration)*time.Second); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// unmuteSession 取消会话免打扰（恢复默认通知偏好）
+func (h *Handler) unmuteSession(w http.ResponseWriter, r *http.Request, userID int64) {
+	targetID, err := queryInt64(r, "target_id")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if targetID == 0 {
+		writeError(w, r, &im.ParamError{Field: "target_id", Message: "target_id is required"})
+		return
+	}
+	sessionType, err := queryInt64(r, "session_type")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if sessionType == 0 {
+		sessionType = im.SessionTypeSingle
+	}
+	if err := h.svc.DeleteNotificationPreference(r.Context(), userID, targetID, int(sessionType)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// checkOnline 查询用户是否在线，返回 {"<userID>": true}，隐身用户对其他人显示为离线
+func (h *Handler) checkOnline(w http.ResponseWriter, r *http.Request, userID int64) {
+	presences, ok := h.queryPresences(w, r, userID)
+	if !ok {
+		return
+	}
+	status := make(map[int64]bool, len(presences))
+	for id, presence := range presences {
+		status[id] = presence.Status != im.PresenceOffline
+	}
+	writeData(w, r, status)
+}
+
+// getPresences 查询用户在线状态，返回 {"<userID>": {"status":"busy","text":"..."}}
+func (h *Handler) getPresences(w http.ResponseWriter, r *http.Request, userID int64) {
+	presences, ok := h.queryPresences(w, r, userID)
+	if !ok {
+		return
+	}
+	writeData(w, r, presences)
+}
+
+// queryPresences 按 user_ids 查询当前用户看到的在线状态，失败时写入错误
+func (h *Handler) queryPresences(w http.ResponseWriter, r *http.Request, userID int64) (map[int64]*im.Presence, bool) {
+	ids, err := parseIDList(r.URL.Query().Get("user_ids"), "user_ids")
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	if len(ids) == 0 {
+		writeError(w, r, &im.ParamError{Field: "user_ids", Message: "user_ids is required"})
+		return nil, false
+	}
+	if len(ids) > h.config.MaxOnlineIDs {
+		writeError(w, r, &im.ParamError{Field: "user_ids", Message: "too many user_ids"})
+		return nil, false
+	}
+
+	presences, err := h.svc.GetPresences(r.Context(), userID, ids)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return presences, true
+}
+
+// setPresence 设置当前用户的在线状态
+func (h *Handler) setPresence(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req struct {
+		Status string `json:"status"`
+		Text   string `json:"text"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.svc.SetPresence(r.Context(), userID, req.Status, req.Text); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// getAwayStatus 获取当前用户的离开状态
+func (h *Handler) getAwayStatus(w http.ResponseWriter, r *http.Request, userID int64) {
+	status, err := h.svc.GetAwayStatus(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, status)
+}
+
+// setAwayStatus 设置离开状态，离开期间收到单聊消息时自动回复 message
+func (h *Handler) setAwayStatus(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.svc.SetAwayStatus(r.Context(), userID, req.Message); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// clearAwayStatus 清除离开状态
+func (h *Handler) clearAwayStatus(w http.ResponseWriter, r *http.Request, userID int64) {
+	if err := h.svc.ClearAwayStatus(r.Context(), userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// parseIDList 解析逗号分隔的 ID 列表
+func parseIDList(s, field string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, &im.ParamError{Field: field, Message: "invalid " + field}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/im"
+)
+
+// Response 统一的 JSON 响应，成功时 code 为 0
+type Response struct {
+	Code    errcode.Code `json:"code"`
+	Message string       `json:"message"`
+	Data    interface{}  `json:"data,omitempty"`
+	Field   string       `json:"field,omitempty"` // 参数错误的字段
+}
+
+// requestLang 请求的语言
+func requestLang(r *http.Request) string {
+	return errcode.LangFromHeader(r.Header.Get("Accept-Language"))
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeData 写入成功响应
+func writeData(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJSON(w, http.StatusOK, &Response{
+		Code:    errcode.OK,
+		Message: errcode.Message(errcode.OK, requestLang(r)),
+		Data:    data,
+	})
+}
+
+// writeError 按 errcode 写入错误响应，参数错误附带字段
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := errcode.Resolve(err, requestLang(r))
+	resp := &Response{Code: apiErr.Code, Message: apiErr.Message}
+
+	var paramErr *im.ParamError
+	if errors.As(err, &paramErr) {
+		resp.Field = paramErr.Field
+		resp.Message = paramErr.Message
+	}
+	var policyErr *im.GroupPolicyError
+	if errors.As(err, &policyErr) {
+		resp.Data = policyErr
+	}
+	writeJSON(w, apiErr.HTTPStatus, resp)
+}
+
+// writeUnauthorized 写入认证失败响应，AuthFunc 返回的错误未绑定错误码时使用 errcode.Unauthorized
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	if errcode.CodeOf(err) == errcode.Internal {
+		apiErr := errcode.New(errcode.Unauthorized, requestLang(r))
+		apiErr.Err = err
+		err = apiErr
+	}
+	writeError(w, r, err)
+}
+
+// writeStatus 写入路由错误（404、405），错误码为对应的通用错误码
+func writeStatus(w http.ResponseWriter, r *http.Request, status int, message string) {
+	code := errcode.NotFound
+	if status != http.StatusNotFound {
+		code = errcode.InvalidParam
+	}
+	writeJSON(w, status, &Response{Code: code, Message: message})
+}
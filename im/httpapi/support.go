@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/bbadbeef/go-base/im"
+)
+
+// supportConversationRequest 客服会话操作请求
+type supportConversationRequest struct {
+	ConversationID int64 `json:"conversation_id"`
+	AgentID        int64 `json:"agent_id"` // 转接目标客服，0 表示自动分配
+}
+
+// getSupportConversations 分页获取当前用户作为客服接待的会话（status、cursor、limit）
+func (h *Handler) getSupportConversations(w http.ResponseWriter, r *http.Request, userID int64) {
+	status, err := queryInt64(r, "status")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req := &im.ListSupportConversationsRequest{AgentID: userID, Status: int(status), Cursor: r.URL.Query().Get("cursor")}
+	if req.Limit, err = queryLimit(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := h.svc.ListSupportConversations(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, page)
+}
+
+// transferSupportConversation 转接当前用户接待的客服会话
+func (h *Handler) transferSupportConversation(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req supportConversationRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !h.canManageSupportConversation(w, r, req.ConversationID, userID, false) {
+		return
+	}
+	conv, err := h.svc.TransferSupportConversation(r.Context(), req.ConversationID, req.AgentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, conv)
+}
+
+// closeSupportConversation 结束客服会话，接待的客服和客户都可以结束
+func (h *Handler) closeSupportConversation(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req supportConversationRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !h.canManageSupportConversation(w, r, req.ConversationID, userID, true) {
+		return
+	}
+	conv, err := h.svc.CloseSupportConversation(r.Context(), req.ConversationID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, conv)
+}
+
+// canManageSupportConversation 检查客服会话是否由 userID 接待（allowCustomer 时也可以是客户），
+// 其他用户的会话按不存在处理，失败时写入错误
+func (h *Handler) canManageSupportConversation(w http.ResponseWriter, r *http.Request, id, userID int64, allowCustomer bool) bool {
+	if id <= 0 {
+		writeError(w, r, &im.ParamError{Field: "conversation_id", Message: "conversation_id is required"})
+		return false
+	}
+	conv, err := h.svc.GetSupportConversation(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	if conv.AgentID != userID && !(allowCustomer && conv.CustomerID == userID) {
+		writeError(w, r, im.ErrSupportConversationNotFound)
+		return false
+	}
+	return true
+}
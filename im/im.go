@@ -1,25 +1,90 @@
 // Package im 提供嵌入式即时通讯(IM)功能模块
 // 支持单聊、群聊、消息状态追踪、分布式节点间消息路由
+//
+// 消息顺序：同一会话（单聊用户对或群组）内的消息按服务端接收顺序持久化和投递，
+// server_time 单调不减，相同时按消息自增 ID 排序；不同会话之间不保证顺序
 package im
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/bbadbeef/go-base/im/internal/core"
 	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/im/internal/protocol"
+	"github.com/bbadbeef/go-base/migrate"
+	"github.com/bbadbeef/go-base/observability/gormmetrics"
 )
 
 // 重新导出类型给外部使用
 type (
-	Config                = core.Config
-	Message               = model.Message
-	Session               = model.Session
-	SendMessageRequest    = model.SendMessageRequest
-	GetMessagesRequest    = model.GetMessagesRequest
-	Group                 = model.Group
-	GroupMember           = model.GroupMember
+	Config                 = core.Config
+	AuthFunc               = core.AuthFunc
+	FileCheckFunc          = core.FileCheckFunc
+	ConnMeta               = model.ConnMeta
+	Message                = model.Message
+	Session                = model.Session
+	SendMessageRequest     = model.SendMessageRequest
+	GetMessagesRequest     = model.GetMessagesRequest
+	MessagePage            = model.MessagePage
+	GetSessionsRequest     = model.GetSessionsRequest
+	SessionPage            = model.SessionPage
+	Group                  = model.Group
+	GroupMember            = model.GroupMember
+	GroupPolicy            = model.GroupPolicy
+	GroupPolicyError       = model.GroupPolicyError
+	Draft                  = model.Draft
+	DNDSchedule            = model.DNDSchedule
+	AwayStatus             = model.AwayStatus
+	Presence               = model.Presence
+	NotificationPreference = model.NotificationPreference
+	MessageReport          = model.MessageReport
+	ListReportsRequest     = model.ListReportsRequest
+	ReportPage             = model.ReportPage
+	CallbackOptions        = model.CallbackOptions
+	Event                  = model.Event
+	EventType              = model.EventType
+	Subscription           = model.Subscription
+	SubscriptionMatch      = model.SubscriptionMatch
+	MigrationStatus        = migrate.Status
+	ParamError             = model.ParamError
+	Tunables               = core.Tunables
+	TrafficStats           = model.TrafficStats
+	NodeInfo               = model.NodeInfo
+	ClusterInfo            = model.ClusterInfo
+	SlowQuery              = gormmetrics.SlowQuery
+	SlowQueryFunc          = gormmetrics.SlowQueryFunc
+)
+
+// 重新导出客服模式类型
+type (
+	SupportAgent                    = model.SupportAgent
+	SupportConversation             = model.SupportConversation
+	ListSupportConversationsRequest = model.ListSupportConversationsRequest
+	SupportConversationPage         = model.SupportConversationPage
+)
+
+// 重新导出错误，可通过 errors.Is / errors.As 判断
+var (
+	ErrInvalidConfig = model.ErrInvalidConfig
+	ErrInvalidParam  = model.ErrInvalidParam
+
+	ErrPreflightFailed = model.ErrPreflightFailed
+
+	ErrGroupNotFound         = model.ErrGroupNotFound
+	ErrNotGroupMember        = model.ErrNotGroupMember
+	ErrGroupPermissionDenied = model.ErrGroupPermissionDenied
+	ErrGroupPolicy           = model.ErrGroupPolicy
+
+	ErrMessageNotFound = model.ErrMessageNotFound
+	ErrReportNotFound  = model.ErrReportNotFound
+
+	ErrFileAccessDenied = model.ErrFileAccessDenied
+
+	ErrSupportConversationNotFound = model.ErrSupportConversationNotFound
+	ErrSupportAgentNotFound        = model.ErrSupportAgentNotFound
 )
 
 // 重新导出消息类型常量
@@ -40,6 +105,94 @@ const (
 	MsgStatusFailed    = model.MsgStatusFailed
 )
 
+// 重新导出通知级别常量
+const (
+	NotifyLevelAll      = model.NotifyLevelAll
+	NotifyLevelMentions = model.NotifyLevelMentions
+	NotifyLevelNone     = model.NotifyLevelNone
+)
+
+// 重新导出群成员角色常量
+const (
+	GroupRoleMember = model.GroupRoleMember
+	GroupRoleAdmin  = model.GroupRoleAdmin
+	GroupRoleOwner  = model.GroupRoleOwner
+)
+
+// 重新导出群组策略拒绝原因常量
+const (
+	GroupRejectNotMember     = model.GroupRejectNotMember
+	GroupRejectSlowMode      = model.GroupRejectSlowMode
+	GroupRejectLinks         = model.GroupRejectLinks
+	GroupRejectImages        = model.GroupRejectImages
+	GroupRejectNewcomerMuted = model.GroupRejectNewcomerMuted
+)
+
+// 重新导出在线状态常量
+const (
+	PresenceOnline    = model.PresenceOnline
+	PresenceBusy      = model.PresenceBusy
+	PresenceAway      = model.PresenceAway
+	PresenceInvisible = model.PresenceInvisible
+	PresenceOffline   = model.PresenceOffline
+)
+
+// 重新导出举报处理状态常量
+const (
+	ReportStatusPending   = model.ReportStatusPending
+	ReportStatusResolved  = model.ReportStatusResolved
+	ReportStatusDismissed = model.ReportStatusDismissed
+)
+
+// 重新导出客服会话状态和分配策略常量
+const (
+	SupportStatusPending = model.SupportStatusPending
+	SupportStatusOpen    = model.SupportStatusOpen
+	SupportStatusClosed  = model.SupportStatusClosed
+
+	SupportStrategyLeastBusy  = model.SupportStrategyLeastBusy
+	SupportStrategyRoundRobin = model.SupportStrategyRoundRobin
+)
+
+// 重新导出事件类型
+const (
+	EventMessageReceived  = model.EventMessageReceived
+	EventMessageSaved     = model.EventMessageSaved
+	EventMessageDelivered = model.EventMessageDelivered
+	EventUserOnline       = model.EventUserOnline
+	EventUserOffline      = model.EventUserOffline
+	EventSessionUpdated   = model.EventSessionUpdated
+	EventGroupCreated     = model.EventGroupCreated
+	EventMessageReported  = model.EventMessageReported
+	EventSupportAssigned  = model.EventSupportAssigned
+	EventSupportClosed    = model.EventSupportClosed
+)
+
+// GuestRejectRestricted 访客连接不允许的操作，作为失败 ACK 的 code
+const GuestRejectRestricted = model.GuestRejectRestricted
+
+// IsGuest 是否为访客连接的临时用户 ID（负数）
+func IsGuest(userID int64) bool {
+	return model.IsGuest(userID)
+}
+
+// 重新导出踢下线原因常量
+const (
+	KickReasonAdmin             = model.KickReasonAdmin
+	KickReasonBanned            = model.KickReasonBanned
+	KickReasonLoggedInElsewhere = model.KickReasonLoggedInElsewhere
+)
+
+// 重新导出 WebSocket 协议版本和能力常量
+const (
+	ProtocolVersion    = protocol.ProtocolVersion
+	MinProtocolVersion = protocol.MinProtocolVersion
+
+	CapBinaryFraming = protocol.CapBinaryFraming
+	CapBatchReceipts = protocol.CapBatchReceipts
+	CapReactions     = protocol.CapReactions
+)
+
 // 重新导出会话类型常量
 const (
 	SessionTypeSingle = model.SessionTypeSingle
@@ -55,53 +208,268 @@ type IMService interface {
 	// Stop 停止 IM 服务
 	Stop() error
 
+	// Shutdown 优雅关闭 IM 服务，替代 Stop
+	// 拒绝新连接，通知所有连接节点即将关闭（shutdown 消息，客户端应重连到其他节点），
+	// 发送完缓冲的消息后断开，等待进行中的消息处理、离线推送钩子和回调完成，最后关闭 gRPC
+	// ctx 到期时不再等待，直接关闭并返回 ctx.Err()
+	Shutdown(ctx context.Context) error
+
 	// WebSocketHandler 获取 WebSocket Handler
 	// 用于嵌入到主应用的 HTTP 路由中
 	// 示例: http.HandleFunc("/ws", imService.WebSocketHandler())
 	WebSocketHandler() http.HandlerFunc
 
 	// SendMessage 发送消息（主动推送，如系统消息）
+	// 同一会话的消息串行持久化和投递，接收方按 server_time 顺序收到
+	// 设置 FileCheckFunc 时 FromUserID 不可引用 FileID 返回 ErrFileAccessDenied
 	SendMessage(ctx context.Context, req *SendMessageRequest) error
 
-	// IsUserOnline 检查用户是否在线
+	// IsUserOnline 检查用户是否在线（连接在集群任一节点），路由心跳超过 PresenceTimeout 视为离线
+	// 不在本节点时查询路由表（带 CacheTTL 本地缓存）
+	// 按连接判断，隐身用户也返回 true（消息照常路由和投递），展示给其他用户时使用 GetPresences
 	IsUserOnline(userID int64) bool
 
+	// IsUserOnlineLocal 检查用户是否连接在当前节点，不查询路由表
+	IsUserOnlineLocal(userID int64) bool
+
+	// SetPresence 设置用户在线状态（PresenceOnline、PresenceBusy、PresenceAway、PresenceInvisible）和状态文字（最多 100 字符）
+	// 断线重连后保持；客户端也可通过 WebSocket presence 消息设置。在线状态不影响消息路由和投递
+	SetPresence(ctx context.Context, userID int64, status, text string) error
+
+	// GetPresences 批量获取 viewerID 看到的用户在线状态
+	// 未连接的用户为 PresenceOffline；隐身用户对其他人显示为 PresenceOffline（不含状态文字），对自己显示 PresenceInvisible
+	GetPresences(ctx context.Context, viewerID int64, userIDs []int64) (map[int64]*Presence, error)
+
+	// KickUser 强制用户下线：推送 kicked 通知（reason 为 KickReasonAdmin、KickReasonBanned 或 KickReasonLoggedInElsewhere）后断开连接并删除路由
+	// 用户连接在其他节点时转发给该节点处理，返回用户是否在线（被踢下线）；客户端收到 kicked 后不应自动重连
+	KickUser(ctx context.Context, userID int64, reason string) (bool, error)
+
+	// ClusterInfo 获取集群拓扑：活跃节点（im_servers 中心跳未超时）及通过 gRPC 获取的各节点连接数、版本和启动时间
+	// 节点无法访问时其 Reachable 为 false，不计入连接数合计
+	ClusterInfo(ctx context.Context) (*ClusterInfo, error)
+
+	// UserTraffic 获取用户在当前节点连接的线上收发字节数，用户不在本节点时返回 false
+	UserTraffic(userID int64) (*TrafficStats, bool)
+
+	// Traffic 获取当前节点连接的线上收发字节数，按总字节数倒序，limit > 0 时只返回前 limit 个
+	Traffic(limit int) []*TrafficStats
+
 	// GetSessions 获取用户的会话列表
 	GetSessions(ctx context.Context, userID int64) ([]*Session, error)
 
 	// GetMessages 获取历史消息
 	GetMessages(ctx context.Context, req *GetMessagesRequest) ([]*Message, error)
 
-	// MarkAsRead 标记消息为已读
+	// GetSessionPage 分页获取会话列表，req.Cursor 为上一页返回的 NextCursor
+	GetSessionPage(ctx context.Context, req *GetSessionsRequest) (*SessionPage, error)
+
+	// GetMessagePage 分页获取历史消息，req.Cursor 为上一页返回的 NextCursor
+	// 游标由 server_time 和消息自增 ID 组成，适合很长的历史记录
+	GetMessagePage(ctx context.Context, req *GetMessagesRequest) (*MessagePage, error)
+
+	// MarkAsRead 将发给 userID 的消息标记为已读，只通知状态实际变化的消息的发送方
+	// 其他用户的消息忽略，msgIDs 最多 100 个，超出时返回 ErrInvalidParam
 	MarkAsRead(ctx context.Context, userID int64, msgIDs []string) error
 
+	// MarkConversationRead 将与 targetID 的单聊中 upToTime（含）之前对方发来的消息全部标记为已读
+	// 设置已读水位、清零未读并通知对方一次，适合一次追平大量未读；upToTime <= 0 表示全部
+	MarkConversationRead(ctx context.Context, userID, targetID, upToTime int64) error
+
+	// SaveDraft 保存会话草稿并同步到用户的其他设备（包括连接在其他节点的设备）
+	// draft.Content 为空时清除该会话草稿
+	SaveDraft(ctx context.Context, draft *Draft) error
+
+	// GetDrafts 获取用户的所有会话草稿
+	GetDrafts(ctx context.Context, userID int64) ([]*Draft, error)
+
+	// SetDNDSchedule 设置用户免打扰时段
+	// 免打扰期间消息仍正常投递，仅抑制离线推送钩子
+	SetDNDSchedule(ctx context.Context, schedule *DNDSchedule) error
+
+	// GetDNDSchedule 获取用户免打扰时段
+	GetDNDSchedule(ctx context.Context, userID int64) (*DNDSchedule, error)
+
+	// SetAwayStatus 设置离开状态，离开期间收到单聊消息时以 message 自动回复发送者
+	// 同一发送者在 Config.AutoReplyInterval 内只回复一次，自动回复不会触发对方的自动回复
+	SetAwayStatus(ctx context.Context, userID int64, message string) error
+
+	// ClearAwayStatus 清除离开状态
+	ClearAwayStatus(ctx context.Context, userID int64) error
+
+	// GetAwayStatus 获取离开状态，未设置时返回 nil
+	GetAwayStatus(ctx context.Context, userID int64) (*AwayStatus, error)
+
+	// SetNotificationPreference 设置会话通知偏好（全部 / 仅@我 / 不通知），MuteUntil 为生效截止时间（0 表示永久）
+	SetNotificationPreference(ctx context.Context, pref *NotificationPreference) error
+
+	// MuteSession 会话免打扰 duration 时长（如 time.Hour、8*time.Hour），duration <= 0 时永久免打扰
+	// 到期后服务端自动恢复全部通知，提前取消使用 DeleteNotificationPreference
+	MuteSession(ctx context.Context, userID, targetID int64, sessionType int, duration time.Duration) error
+
+	// GetNotificationPreference 获取会话通知偏好，未设置或已到期时返回默认值
+	GetNotificationPreference(ctx context.Context, userID, targetID int64, sessionType int) (*NotificationPreference, error)
+
+	// GetNotificationPreferences 获取用户设置过且未到期的所有会话通知偏好
+	GetNotificationPreferences(ctx context.Context, userID int64) ([]*NotificationPreference, error)
+
+	// DeleteNotificationPreference 删除会话通知偏好（恢复默认）
+	DeleteNotificationPreference(ctx context.Context, userID, targetID int64, sessionType int) error
+
+	// ShouldNotify 根据通知偏好判断消息是否应通知 userID
+	// 离线推送钩子内部已调用，主应用的 webhook 等通知渠道也可使用
+	ShouldNotify(ctx context.Context, userID int64, msg *Message) bool
+
+	// CreateGroup 创建群组，群主（OwnerID）自动加入，memberIDs 为初始成员（可选）
+	CreateGroup(ctx context.Context, group *Group, memberIDs []int64) error
+
+	// GetGroup 获取群组信息，不存在时返回 ErrGroupNotFound
+	GetGroup(ctx context.Context, groupID int64) (*Group, error)
+
+	// UpdateGroup 更新群组名称和头像
+	UpdateGroup(ctx context.Context, group *Group) error
+
+	// DeleteGroup 解散群组，删除全部成员
+	DeleteGroup(ctx context.Context, groupID int64) error
+
+	// GetUserGroups 获取用户加入的群组
+	GetUserGroups(ctx context.Context, userID int64) ([]*Group, error)
+
+	// GetGroupMembers 获取群成员列表
+	GetGroupMembers(ctx context.Context, groupID int64) ([]*GroupMember, error)
+
+	// GetGroupMember 获取群成员，不是群成员时返回 ErrNotGroupMember，可用于权限判断
+	GetGroupMember(ctx context.Context, groupID, userID int64) (*GroupMember, error)
+
+	// AddGroupMembers 添加群成员，已是成员的用户跳过
+	AddGroupMembers(ctx context.Context, groupID int64, userIDs []int64) error
+
+	// RemoveGroupMember 移除群成员（含主动退群），群主不能被移除
+	RemoveGroupMember(ctx context.Context, groupID, userID int64) error
+
+	// SetGroupPolicy 设置群组防刷屏策略（慢速模式、禁止链接 / 图片、新成员禁言）
+	SetGroupPolicy(ctx context.Context, policy *GroupPolicy) error
+
+	// GetGroupPolicy 获取群组防刷屏策略，不存在时返回 ErrGroupNotFound
+	GetGroupPolicy(ctx context.Context, groupID int64) (*GroupPolicy, error)
+
+	// CheckGroupMessage 检查 userID 是否可以在群内发送该消息，通过时计入慢速模式间隔
+	// 不是群成员返回 ErrNotGroupMember，违反群组策略返回 *GroupPolicyError（errors.Is(err, ErrGroupPolicy)）
+	// WebSocket 群聊消息已自动检查，主应用自行发送用户群聊消息前调用
+	CheckGroupMessage(ctx context.Context, groupID, userID int64, content string, msgType int) error
+
+	// ReportMessage 举报消息，只能举报自己可见的消息（单聊双方或群成员），否则返回 ErrMessageNotFound
+	// 保存举报时的消息快照并触发 OnMessageReported 回调，重复举报同一消息时返回已有举报
+	ReportMessage(ctx context.Context, reporterID int64, msgID, reason string) (*MessageReport, error)
+
+	// ListReports 分页获取举报，按举报时间倒序，可按处理状态过滤
+	ListReports(ctx context.Context, req *ListReportsRequest) (*ReportPage, error)
+
+	// ResolveReport 处理举报，status 为 ReportStatusResolved 或 ReportStatusDismissed
+	// 举报不存在或已处理时返回 ErrReportNotFound
+	ResolveReport(ctx context.Context, reportID, resolverID int64, status int, note string) (*MessageReport, error)
+
+	// AddSupportAgent 添加客服或更新其同时接待的会话数上限（0 表示不限制），见 Config.SupportUserID
+	AddSupportAgent(ctx context.Context, userID int64, maxConversations int) error
+
+	// RemoveSupportAgent 移除客服，不存在时返回 ErrSupportAgentNotFound，已接待的会话保留
+	RemoveSupportAgent(ctx context.Context, userID int64) error
+
+	// ListSupportAgents 获取全部客服及其进行中的会话数和在线状态
+	ListSupportAgents(ctx context.Context) ([]*SupportAgent, error)
+
+	// GetSupportConversation 获取客服会话，不存在时返回 ErrSupportConversationNotFound
+	GetSupportConversation(ctx context.Context, id int64) (*SupportConversation, error)
+
+	// ListSupportConversations 分页获取客服会话，按创建时间倒序，可按客服和状态过滤
+	ListSupportConversations(ctx context.Context, req *ListSupportConversationsRequest) (*SupportConversationPage, error)
+
+	// TransferSupportConversation 转接客服会话给 agentID，agentID 为 0 时自动选择其他可用客服（没有时转为待分配）
+	// 会话不存在或已结束返回 ErrSupportConversationNotFound，agentID 不是客服返回 ErrSupportAgentNotFound
+	TransferSupportConversation(ctx context.Context, id, agentID int64) (*SupportConversation, error)
+
+	// CloseSupportConversation 结束客服会话，客户的下一条消息开始新的会话
+	// 会话不存在或已结束返回 ErrSupportConversationNotFound
+	CloseSupportConversation(ctx context.Context, id int64) (*SupportConversation, error)
+
+	// CanAccessFile 检查用户是否可以访问消息中的文件（storage 文件ID）
+	// 发送或接收过引用该文件的消息，或是发送过该文件的群组的当前成员时返回 true
+	// 可用作 storage/httpapi 的下载权限校验，见 Config.AccessCheck
+	// 需设置 FileCheckFunc，否则任意用户可发送引用他人文件的消息获得访问权限
+	CanAccessFile(ctx context.Context, userID int64, fileID string) (bool, error)
+
+	// FilesReferenced 检查文件（storage 文件ID）是否被消息引用，返回被引用的文件
+	// 可用作 storage 垃圾回收的 GCOptions.IsReferenced
+	FilesReferenced(ctx context.Context, fileIDs []string) (map[string]bool, error)
+
+	// PurgeUserData 删除用户的 IM 数据（发送的消息、会话列表、草稿、免打扰和通知偏好、提交的举报、离开状态、在线状态、客服数据）
+	// 用于账号注销，可注册为 user 模块的账号删除钩子
+	PurgeUserData(ctx context.Context, userID int64) error
+
+	// Migrate 执行所有未执行的数据库迁移
+	// 未禁用自动迁移时创建服务已自动执行，禁用后需显式调用
+	Migrate(ctx context.Context) error
+
+	// MigrationStatus 获取所有数据库迁移的执行状态
+	MigrationStatus(ctx context.Context) ([]*MigrationStatus, error)
+
+	// Tunables 获取当前生效的运行期配置（日志级别、路由缓存时间、消息大小和频率限制）
+	Tunables() Tunables
+
+	// UpdateTunables 热更新运行期配置，无需重启服务，不合法时返回 ErrInvalidConfig 且保持原配置
+	// 可在配置中心的变更回调中调用，或使用 WatchTunables 监听配置文件
+	UpdateTunables(t Tunables) error
+
 	// OnMessage 设置消息回调
 	// 当收到新消息时触发（主应用可监听此事件做额外处理）
-	OnMessage(handler func(*Message))
+	// On 开头的回调可并发注册，返回删除回调的函数；opts 可设置优先级和同步调用（只取第一个）
+	// 回调按优先级顺序调用，默认在后台协程中执行；同步回调会阻塞触发事件的处理（如该连接的后续消息），应尽快返回
+	// 回调 panic 时记录日志和 im_panics_total，不影响服务和其他回调
+	OnMessage(handler func(*Message), opts ...CallbackOptions) (remove func())
 
 	// OnUserOnline 设置用户上线回调
-	OnUserOnline(handler func(userID int64))
+	OnUserOnline(handler func(userID int64), opts ...CallbackOptions) (remove func())
+
+	// OnUserOffline 设置用户下线回调，连接断开或心跳超时（如所在节点崩溃）时触发
+	// 用户已在本节点或其他节点重新连接时，旧连接断开不触发
+	OnUserOffline(handler func(userID int64), opts ...CallbackOptions) (remove func())
+
+	// Subscribe 注册关键词或自定义条件的消息订阅，用于告警、审计或自动回复机器人，返回取消订阅函数
+	// 与 OnMessage 相同，收到客户端消息时在后台匹配，所有订阅的关键词合并匹配，每条消息只扫描一遍内容
+	// 命中时异步调用 handler，SubscriptionMatch.Keywords 为命中的关键词
+	Subscribe(sub *Subscription, handler func(*SubscriptionMatch)) (unsubscribe func(), err error)
 
-	// OnUserOffline 设置用户下线回调
-	OnUserOffline(handler func(userID int64))
+	// OnMessageReported 设置消息举报回调，每条新举报触发一次，用于通知审核服务
+	OnMessageReported(handler func(*MessageReport), opts ...CallbackOptions) (remove func())
+
+	// OnEvent 注册事件回调，types 为空时接收所有事件类型，类型未知时返回 ErrInvalidParam
+	// 新增的事件类型通过 OnEvent 订阅，不再单独添加 On 方法；调用方式与 OnMessage 等回调相同
+	OnEvent(types []EventType, handler func(*Event), opts ...CallbackOptions) (remove func(), err error)
 }
 
 // New 创建 IM 服务实例
 func New(config *Config) (IMService, error) {
 	if config == nil {
-		return nil, errors.New("config is required")
+		return nil, fmt.Errorf("%w: config is required", ErrInvalidConfig)
 	}
 
 	if config.ServerID == "" {
-		return nil, errors.New("server_id is required")
+		return nil, fmt.Errorf("%w: server_id is required", ErrInvalidConfig)
 	}
 
 	if config.DB == nil {
-		return nil, errors.New("database connection is required")
+		return nil, fmt.Errorf("%w: database connection is required", ErrInvalidConfig)
 	}
 
 	if config.AuthFunc == nil {
-		return nil, errors.New("auth function is required")
+		return nil, fmt.Errorf("%w: auth function is required", ErrInvalidConfig)
+	}
+
+	if len(config.TenantID) > 64 {
+		return nil, fmt.Errorf("%w: tenant id must be at most 64 characters", ErrInvalidConfig)
+	}
+
+	if config.MaxMessageSize < 0 || config.RateLimit < 0 {
+		return nil, fmt.Errorf("%w: max message size and rate limit must not be negative", ErrInvalidConfig)
 	}
 
 	// 设置默认值
@@ -115,3 +483,14 @@ func New(config *Config) (IMService, error) {
 
 	return core.NewIMServer(config)
 }
+
+// TokenAuth 将只校验 Token 的认证函数转换为 AuthFunc（忽略连接信息），兼容原 func(token string) 签名的认证函数
+// verify 为 nil 时返回 nil
+func TokenAuth(verify func(token string) (userID int64, err error)) AuthFunc {
+	if verify == nil {
+		return nil
+	}
+	return func(_ context.Context, token string, _ ConnMeta) (int64, error) {
+		return verify(token)
+	}
+}
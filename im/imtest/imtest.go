@@ -0,0 +1,82 @@
+// Package imtest 提供基于 SQLite 内存数据库的 IM 测试辅助工具
+// 无需 MySQL 即可在单元测试 / CI 中运行完整的 IM 服务
+package imtest
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/bbadbeef/go-base/im"
+)
+
+var dbSeq int64
+
+// OpenDB 打开一个独立的 SQLite 内存数据库
+// 每次调用返回互不影响的新数据库
+func OpenDB() (*gorm.DB, error) {
+	name := fmt.Sprintf("file:imtest_%d?mode=memory&cache=shared", atomic.AddInt64(&dbSeq, 1))
+	db, err := gorm.Open(sqlite.Open(name), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 内存数据库在最后一个连接关闭时销毁，限制为单连接避免数据丢失和锁冲突
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// TokenAuth 测试用认证函数，Token 即为十进制用户 ID
+func TokenAuth(token string) (int64, error) {
+	userID, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, fmt.Errorf("invalid token: %s", token)
+	}
+	return userID, nil
+}
+
+// Token 生成 TokenAuth 可识别的 Token
+func Token(userID int64) string {
+	return strconv.FormatInt(userID, 10)
+}
+
+// NewBuilder 创建预配置好 SQLite 内存数据库和 TokenAuth 的 IM 构建器
+// 调用方可在此基础上继续链式配置
+func NewBuilder() (*im.Builder, *gorm.DB, error) {
+	db, err := OpenDB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	builder := im.NewBuilder().
+		WithServerID("imtest").
+		WithDB(db).
+		WithAuthFunc(TokenAuth)
+
+	return builder, db, nil
+}
+
+// NewService 创建基于 SQLite 内存数据库的 IM 服务
+func NewService() (im.IMService, *gorm.DB, error) {
+	builder, db, err := NewBuilder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service, err := builder.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return service, db, nil
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bbadbeef/go-base/im/internal/protocol"
+)
+
+// affinityToken 节点亲和标识，未配置时为 ServerID
+func (s *IMServer) affinityToken() string {
+	if s.config.AffinityToken != "" {
+		return s.config.AffinityToken
+	}
+	return s.config.ServerID
+}
+
+// affinityHeader WebSocket 升级响应头，配置了亲和 Cookie 时写入 Set-Cookie
+func (s *IMServer) affinityHeader() http.Header {
+	if s.config.AffinityCookie == "" {
+		return nil
+	}
+	cookie := &http.Cookie{
+		Name:     s.config.AffinityCookie,
+		Value:    s.affinityToken(),
+		Path:     "/",
+		HttpOnly: true,
+	}
+	return http.Header{"Set-Cookie": {cookie.String()}}
+}
+
+// sendConnectInfo 推送连接信息，客户端重连时带回亲和标识
+func (s *IMServer) sendConnectInfo(userID int64) {
+	data, _ := protocol.Encode(protocol.WSMsgTypeConnectInfo, "", time.Now().UnixMilli(), &protocol.WSConnectInfo{
+		UserID:         userID,
+		ServerID:       s.config.ServerID,
+		AffinityToken:  s.affinityToken(),
+		AffinityCookie: s.config.AffinityCookie,
+	})
+	s.hub.SendToUser(userID, data)
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+const maxAwayMessage = 500 // 自动回复内容最大长度（字符）
+
+// autoReplyKey ctx 中标记自动回复消息的键，自动回复不再触发对方的自动回复
+type autoReplyKey struct{}
+
+// SetAwayStatus 设置离开状态，离开期间收到单聊消息时以 message 自动回复发送者
+func (s *IMServer) SetAwayStatus(ctx context.Context, userID int64, message string) error {
+	if userID == 0 {
+		return model.NewParamError("user_id", "user_id is required")
+	}
+	if message == "" {
+		return model.NewParamError("message", "message is required")
+	}
+	if utf8.RuneCountInString(message) > maxAwayMessage {
+		return model.NewParamError("message", "message must be at most 500 characters")
+	}
+	return s.awayRepo.Save(ctx, &model.AwayStatus{UserID: userID, Message: message})
+}
+
+// ClearAwayStatus 清除离开状态
+func (s *IMServer) ClearAwayStatus(ctx context.Context, userID int64) error {
+	return s.awayRepo.Delete(ctx, userID)
+}
+
+// GetAwayStatus 获取离开状态，未设置时返回 nil
+func (s *IMServer) GetAwayStatus(ctx context.Context, userID int64) (*model.AwayStatus, error) {
+	status, err := s.awayRepo.Get(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return status, err
+}
+
+// autoReplyInterval 同一发送者两次自动回复的最小间隔（秒），默认 1 小时
+func (s *IMServer) autoReplyInterval() int {
+	if s.config.AutoReplyInterval > 0 {
+		return s.config.AutoReplyInterval
+	}
+	return 3600
+}
+
+// autoReply 接收方处于离开状态时在后台自动回复发送者，同一发送者在间隔内只回复一次
+// 系统消息、群聊消息和自动回复消息不触发
+func (s *IMServer) autoReply(ctx context.Context, msg *model.Message) {
+	if msg.FromUserID == 0 || msg.GroupID != 0 || msg.FromUserID == msg.ToUserID || ctx.Value(autoReplyKey{}) != nil {
+		return
+	}
+
+	s.goTask(func() {
+		ctx := s.serverContext()
+		status, err := s.GetAwayStatus(ctx, msg.ToUserID)
+		if err != nil {
+			log.Warnf("Failed to get away status for user %d: %v", msg.ToUserID, err)
+			return
+		}
+		if status == nil {
+			return
+		}
+		interval := int64(s.autoReplyInterval()) * 1000
+		if !s.autoReplies.allow(msg.ToUserID, msg.FromUserID, interval, time.Now().UnixMilli()) {
+			return
+		}
+
+		reply := &model.SendMessageRequest{
+			FromUserID: msg.ToUserID,
+			ToUserID:   msg.FromUserID,
+			Content:    status.Message,
+			MsgType:    model.MsgTypeText,
+		}
+		if err := s.SendMessage(context.WithValue(ctx, autoReplyKey{}, true), reply); err != nil {
+			log.Warnf("Failed to send auto reply from user %d to %d: %v", msg.ToUserID, msg.FromUserID, err)
+			return
+		}
+		s.metrics.Add("im_auto_replies_total", 1)
+	})
+}
+
+// autoReplyTracker 离开用户对每个发送者上一次自动回复的时间，按节点记录
+type autoReplyTracker struct {
+	mu   sync.Mutex
+	last map[[2]int64]int64 // (离开用户 ID, 发送者 ID) -> 回复时间（毫秒）
+}
+
+// autoReplySweepSize 记录数超过该值时清理已过间隔的记录
+const autoReplySweepSize = 10000
+
+// newAutoReplyTracker 创建自动回复记录
+func newAutoReplyTracker() *autoReplyTracker {
+	return &autoReplyTracker{last: make(map[[2]int64]int64)}
+}
+
+// allow 距上一次回复已满 interval 毫秒时记录本次回复并返回 true
+func (t *autoReplyTracker) allow(userID, senderID, interval, now int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := [2]int64{userID, senderID}
+	if last, ok := t.last[key]; ok && now-last < interval {
+		return false
+	}
+	if len(t.last) >= autoReplySweepSize {
+		for k, last := range t.last {
+			if now-last >= interval {
+				delete(t.last, k)
+			}
+		}
+	}
+	t.last[key] = now
+	return true
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/im/internal/repository"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// errBatcherClosed 合并器已关闭（服务停止后仍有写入）
+var errBatcherClosed = errors.New("message write batcher closed")
+
+// writeBatcher 消息写入合并器（write-behind）
+// 并发的消息插入和状态更新先进入队列，攒满 size 条或最早一条等待 delay 后，
+// 合并为一条多行 INSERT 和按状态分组的 UPDATE ... WHERE msg_id IN (...) 写入
+// 调用方阻塞到所在批次落库后返回，保持先持久化再 ACK 的语义
+type writeBatcher struct {
+	repo    *repository.MessageRepository
+	size    int
+	delay   time.Duration
+	metrics observability.Metrics
+
+	ops  chan *writeOp
+	stop chan struct{}
+	done chan struct{}
+}
+
+// writeOp 单个写入操作，msg 不为空时为插入，否则为状态更新
+type writeOp struct {
+	msg        *model.Message
+	msgID      string
+	status     int
+	updateTime int64
+	result     chan error
+}
+
+// statusKey 状态更新分组，状态和时间相同的更新合并为一条 UPDATE
+type statusKey struct {
+	status     int
+	updateTime int64
+}
+
+// newWriteBatcher 创建并启动合并器
+func newWriteBatcher(repo *repository.MessageRepository, size int, delay time.Duration, metrics observability.Metrics) *writeBatcher {
+	b := &writeBatcher{
+		repo:    repo,
+		size:    size,
+		delay:   delay,
+		metrics: metrics,
+		ops:     make(chan *writeOp, size),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Save 保存消息，所在批次写入后返回
+func (b *writeBatcher) Save(ctx context.Context, msg *model.Message) error {
+	return b.submit(ctx, &writeOp{msg: msg})
+}
+
+// UpdateStatus 更新消息状态，所在批次写入后返回
+func (b *writeBatcher) UpdateStatus(ctx context.Context, msgID string, status int, updateTime int64) error {
+	return b.submit(ctx, &writeOp{msgID: msgID, status: status, updateTime: updateTime})
+}
+
+// submit 提交操作并等待结果
+// ctx 结束时不再等待，返回 ctx.Err()，已入队的操作仍会写入
+func (b *writeBatcher) submit(ctx context.Context, op *writeOp) error {
+	op.result = make(chan error, 1)
+
+	select {
+	case b.ops <- op:
+	case <-b.stop:
+		return errBatcherClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-op.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.done:
+		// 关闭前最后一批可能已写入
+		select {
+		case err := <-op.result:
+			return err
+		default:
+			return errBatcherClosed
+		}
+	}
+}
+
+// Close 停止接收新操作，写入队列中剩余的操作后返回
+func (b *writeBatcher) Close() {
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+	<-b.done
+}
+
+// run 收集操作并按数量或延迟触发写入
+func (b *writeBatcher) run() {
+	defer close(b.done)
+
+	var (
+		pending []*writeOp
+		timer   *time.Timer
+		timeout <-chan time.Time
+	)
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if len(pending) > 0 {
+			b.flush(pending)
+			pending = nil
+		}
+	}
+
+	for {
+		select {
+		case op := <-b.ops:
+			pending = append(pending, op)
+			if len(pending) >= b.size {
+				flush()
+			} else if timer == nil {
+				timer = time.NewTimer(b.delay)
+				timeout = timer.C
+			}
+		case <-timeout:
+			timer, timeout = nil, nil
+			flush()
+		case <-b.stop:
+			for len(b.ops) > 0 {
+				pending = append(pending, <-b.ops)
+			}
+			flush()
+			return
+		}
+	}
+}
+
+// flush 写入一批操作：先插入消息，再按状态分组更新
+func (b *writeBatcher) flush(ops []*writeOp) {
+	ctx := context.Background()
+
+	var saves []*writeOp
+	updates := make(map[statusKey][]*writeOp)
+	var keys []statusKey
+	for _, op := range ops {
+		if op.msg != nil {
+			saves = append(saves, op)
+			continue
+		}
+		key := statusKey{status: op.status, updateTime: op.updateTime}
+		if _, ok := updates[key]; !ok {
+			keys = append(keys, key)
+		}
+		updates[key] = append(updates[key], op)
+	}
+
+	if len(saves) > 0 {
+		b.metrics.Observe("im_write_batch_size", float64(len(saves)), observability.L("op", "insert"))
+		b.saveAll(ctx, saves)
+	}
+	for _, key := range keys {
+		group := updates[key]
+		msgIDs := make([]string, len(group))
+		for i, op := range group {
+			msgIDs[i] = op.msgID
+		}
+		b.metrics.Observe("im_write_batch_size", float64(len(group)), observability.L("op", "update"))
+		err := b.repo.UpdateStatusBatch(ctx, msgIDs, key.status, key.updateTime)
+		for _, op := range group {
+			op.result <- err
+		}
+	}
+}
+
+// saveAll 多行插入消息，整批失败时逐条重试，只让出错的消息失败
+func (b *writeBatcher) saveAll(ctx context.Context, ops []*writeOp) {
+	msgs := make([]*model.Message, len(ops))
+	for i, op := range ops {
+		msgs[i] = op.msg
+	}
+
+	err := b.repo.SaveBatch(ctx, msgs)
+	if err == nil || len(ops) == 1 {
+		for _, op := range ops {
+			op.result <- err
+		}
+		return
+	}
+
+	log.Warnf("Batch insert of %d messages failed, retrying one by one: %v", len(ops), err)
+	for _, op := range ops {
+		op.result <- b.repo.Save(ctx, op.msg)
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// callbackList 回调列表，可并发注册和删除
+// 变更时按优先级重建有序列表（复制），触发时无锁读取
+type callbackList[T any] struct {
+	mu     sync.Mutex
+	nextID int64
+	list   atomic.Pointer[[]*callback[T]]
+}
+
+// callback 已注册的回调
+type callback[T any] struct {
+	id       int64
+	priority int
+	sync     bool
+	fn       func(T)
+}
+
+// add 注册回调，opts 只取第一个，返回删除回调的函数；fn 为 nil 时不注册
+func (l *callbackList[T]) add(fn func(T), opts []model.CallbackOptions) func() {
+	if fn == nil {
+		return func() {}
+	}
+	var opt model.CallbackOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	l.mu.Lock()
+	l.nextID++
+	cb := &callback[T]{id: l.nextID, priority: opt.Priority, sync: opt.Sync, fn: fn}
+	list := append(l.snapshot(), cb)
+	// 优先级高的在前，相同优先级按注册顺序
+	sort.SliceStable(list, func(i, j int) bool { return list[i].priority > list[j].priority })
+	l.list.Store(&list)
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() { once.Do(func() { l.remove(cb.id) }) }
+}
+
+// remove 删除回调
+func (l *callbackList[T]) remove(id int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var list []*callback[T]
+	for _, cb := range l.load() {
+		if cb.id != id {
+			list = append(list, cb)
+		}
+	}
+	l.list.Store(&list)
+}
+
+// snapshot 复制当前列表，需持有 mu
+func (l *callbackList[T]) snapshot() []*callback[T] {
+	return append([]*callback[T](nil), l.load()...)
+}
+
+// load 获取当前列表，只读
+func (l *callbackList[T]) load() []*callback[T] {
+	if list := l.list.Load(); list != nil {
+		return *list
+	}
+	return nil
+}
+
+// fireCallbacks 按顺序触发回调：同步回调在当前协程中执行，异步回调在后台执行
+// 回调 panic 时记录日志，不影响服务和其他回调
+func fireCallbacks[T any](s *IMServer, name string, l *callbackList[T], arg T) {
+	for _, cb := range l.load() {
+		fn := cb.fn
+		if cb.sync {
+			s.runCallback(name, func() { fn(arg) })
+		} else {
+			s.goCallback(name, func() { fn(arg) })
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	imgrpc "github.com/bbadbeef/go-base/im/internal/grpc"
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/im/internal/protocol"
+)
+
+// nodeStatsTimeout 获取单个节点统计的超时
+const nodeStatsTimeout = 2 * time.Second
+
+// version 节点版本，未配置时为主模块的构建版本
+func (s *IMServer) version() string {
+	if s.config.Version != "" {
+		return s.config.Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Version
+	}
+	return ""
+}
+
+// localStats 当前节点统计
+func (s *IMServer) localStats() *imgrpc.NodeStatsResponse {
+	return &imgrpc.NodeStatsResponse{
+		ServerID:        s.config.ServerID,
+		Connections:     int64(s.hub.Count()),
+		Version:         s.version(),
+		ProtocolVersion: protocol.ProtocolVersion,
+		StartedAt:       s.startedAt,
+		Draining:        s.draining.Load(),
+	}
+}
+
+// NodeStats gRPC 服务端实现（其他节点获取集群拓扑时调用）
+func (s *IMServer) NodeStats(ctx context.Context, req *imgrpc.NodeStatsRequest) (*imgrpc.NodeStatsResponse, error) {
+	return s.localStats(), nil
+}
+
+// ClusterInfo 获取集群拓扑：im_servers 中心跳未超时的节点，以及通过 gRPC 获取的各节点连接数和版本
+// 节点无法访问时 Reachable 为 false 并记录原因，不影响其他节点
+func (s *IMServer) ClusterInfo(ctx context.Context) (*model.ClusterInfo, error) {
+	servers, err := s.routeRepo.GetActiveServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*model.NodeInfo, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		node := &model.NodeInfo{
+			ServerID:      server.ServerID,
+			GRPCAddr:      server.GRPCAddr,
+			LastHeartbeat: server.LastHeartbeat,
+		}
+		nodes[i] = node
+
+		if server.ServerID == s.config.ServerID {
+			fillNodeStats(node, s.localStats())
+			continue
+		}
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			stats, err := s.peerStats(ctx, addr)
+			if err != nil {
+				node.Error = err.Error()
+				return
+			}
+			fillNodeStats(node, stats)
+		}(server.GRPCAddr)
+	}
+	wg.Wait()
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ServerID < nodes[j].ServerID })
+	info := &model.ClusterInfo{ServerID: s.config.ServerID, Nodes: nodes}
+	for _, node := range nodes {
+		info.Connections += node.Connections
+	}
+	return info, nil
+}
+
+// peerStats 通过 gRPC 获取其他节点的统计
+func (s *IMServer) peerStats(ctx context.Context, addr string) (*imgrpc.NodeStatsResponse, error) {
+	client, err := s.peerClient(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, nodeStatsTimeout)
+	defer cancel()
+	return client.NodeStats(imgrpc.OutgoingContext(ctx), &imgrpc.NodeStatsRequest{})
+}
+
+// fillNodeStats 填充节点统计
+func fillNodeStats(node *model.NodeInfo, stats *imgrpc.NodeStatsResponse) {
+	node.Reachable = true
+	node.Connections = int(stats.Connections)
+	node.Version = stats.Version
+	node.ProtocolVersion = int(stats.ProtocolVersion)
+	node.StartedAt = stats.StartedAt
+	node.Draining = stats.Draining
+}
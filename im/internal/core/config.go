@@ -1,6 +1,23 @@
 package core
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/idgen"
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/observability"
+	"github.com/bbadbeef/go-base/observability/gormmetrics"
+)
+
+// AuthFunc 认证函数，ctx 为 WebSocket 升级请求的上下文，返回错误时拒绝连接
+type AuthFunc func(ctx context.Context, token string, meta model.ConnMeta) (userID int64, err error)
+
+// FileCheckFunc 文件引用校验函数，返回用户是否可以在消息中引用该文件
+type FileCheckFunc func(ctx context.Context, userID int64, fileID string) (bool, error)
 
 // Config IM 模块配置
 type Config struct {
@@ -10,16 +27,128 @@ type Config struct {
 	// GRPCAddr gRPC 监听地址，用于节点间通信 (例如: "0.0.0.0:50051")
 	GRPCAddr string
 
+	// ClusterKey 集群密钥（可选），设置后节点间 gRPC 调用需携带相同密钥，否则返回 Unauthenticated
+	// 同一集群的所有节点需配置相同的值
+	ClusterKey string
+
+	// GRPCInterceptors 额外的 gRPC 服务端拦截器（可选），在内置的日志、指标、panic 恢复和认证拦截器之后依次执行
+	GRPCInterceptors []grpc.UnaryServerInterceptor
+
 	// DB 数据库连接（由主应用提供）
 	DB *gorm.DB
 
+	// ReadDB 只读库连接（可选），历史消息、会话列表、路由查询走只读库，写操作始终走 DB
+	// 也可以不设置此项，直接在 DB 上注册 GORM dbresolver 插件实现读写分离
+	ReadDB *gorm.DB
+
 	// AuthFunc 认证函数，验证 Token 并返回用户 ID
-	// 由主应用实现，用于验证 WebSocket 连接时的 Token
-	AuthFunc func(token string) (userID int64, err error)
+	// 由主应用实现，用于验证 WebSocket 连接时的 Token，可按 ConnMeta 做 IP 白名单、设备绑定等校验
+	// 只校验 Token 的函数可通过 im.TokenAuth 转换
+	AuthFunc AuthFunc
+
+	// EnableGuest 允许不带 Token 的访客连接（可选），如登录前的客服咨询窗口
+	// 访客每次连接分配临时的负数用户 ID（通过 connect_info 告知客户端），只能接收消息、发送心跳（不能发送回执），
+	// 以及向 GuestSendTo 中的用户发送单聊消息；其他操作回复 code 为 guest_restricted 的失败 ACK
+	EnableGuest bool
+
+	// GuestSendTo 访客可以发送单聊消息的用户 ID（可选），如客服账号，为空时访客只能接收消息
+	GuestSendTo []int64
+
+	// SupportUserID 客服入口的虚拟用户 ID（可选），大于 0 时开启客服模式
+	// 发给该用户的单聊消息按 SupportStrategy 分配给在线且未满的客服（AddSupportAgent），同一客户的后续消息发给同一客服；
+	// 没有可用客服时会话待分配，消息仍发给该用户，客户的下一条消息或 TransferSupportConversation 时再分配
+	// 开启访客连接时访客也可以向该用户发送消息
+	SupportUserID int64
+
+	// SupportStrategy 客服分配策略，least_busy（默认）或 round_robin
+	SupportStrategy string
+
+	// TrustProxy 按 X-Forwarded-For / X-Real-IP 获取连接的客户端 IP（ConnMeta.RemoteIP），仅在可信的反向代理之后开启
+	TrustProxy bool
 
 	// CacheTTL 路由缓存时间（秒），默认 30 秒
+	// CacheTTL、MaxMessageSize、RateLimit、BandwidthLimit 为初始值，运行中可通过 UpdateTunables 热更新
 	CacheTTL int
 
 	// HeartbeatInterval 心跳间隔（秒），默认 15 秒
 	HeartbeatInterval int
+
+	// PresenceTimeout 在线状态超时（秒），默认 3 倍心跳间隔
+	// 节点崩溃或连接未正常断开时，用户路由心跳超过此时间即视为离线：
+	// 由任一存活节点删除路由并触发 OnUserOffline（每个用户只触发一次），IsUserOnline 和消息路由不再认为其在线
+	PresenceTimeout int
+
+	// AffinityToken 节点亲和标识（可选），默认为 ServerID
+	// 连接建立后通过 connect_info 推送给客户端，负载均衡 / Ingress 可据此把重连路由回同一节点
+	AffinityToken string
+
+	// AffinityCookie 亲和 Cookie 名（可选），设置后 WebSocket 升级响应写入该 Cookie，值为 AffinityToken
+	AffinityCookie string
+
+	// MaxMessageSize 单条消息内容最大字节数（可选），0 表示不限制
+	// 超出时 WebSocket 消息回复失败 ACK，SendMessage 返回 ErrInvalidParam
+	MaxMessageSize int
+
+	// RateLimit 每个连接每秒最多发送的消息数（可选），超出的消息回复失败 ACK，0 表示不限制
+	RateLimit int
+
+	// BandwidthLimit 每个连接每秒最多上行字节数（可选），按线上字节（开启压缩时为压缩后大小）计算，
+	// 超出后的聊天消息回复失败 ACK，0 表示不限制；连接按读缓冲（约 4KB）预读，限制值应远大于缓冲大小
+	BandwidthLimit int
+
+	// EnableCompression 协商 WebSocket permessage-deflate 压缩（可选），客户端支持时生效
+	EnableCompression bool
+
+	// TablePrefix 表名前缀（可选），拼接在默认表名之前，如 "app_" -> "app_im_messages"
+	TablePrefix string
+
+	// TableNames 按默认表名覆盖实际表名（可选），如 {"im_messages": "chat_messages"}
+	// 优先于 TablePrefix
+	TableNames map[string]string
+
+	// TenantID 租户 ID（可选），最长 64 个字符
+	// 设置后消息和会话仅在该租户内可见，多个租户可共用同一数据库，用户 ID 需在租户间唯一
+	// AuthFunc 需拒绝其他租户的 Token，如使用用户模块相同 TenantID 签发的令牌
+	TenantID string
+
+	// DisableAutoMigrate 禁止启动时自动执行数据库迁移（可选）
+	// 禁止后需通过 Migrate 显式执行迁移，适合由发布流程统一管理表结构变更的场景
+	DisableAutoMigrate bool
+
+	// AutoReplyInterval 离开状态自动回复的间隔（秒），默认 1 小时
+	// 同一发送者在间隔内只收到一次自动回复，按节点记录
+	AutoReplyInterval int
+
+	// OfflinePushFunc 离线推送钩子（可选）
+	// 接收方不在线时调用，由主应用对接 APNs/FCM/厂商推送
+	// 接收方处于免打扰时段时不会调用
+	OfflinePushFunc func(msg *model.Message)
+
+	// FileCheckFunc 发送带 file_id 的消息时校验发送方可以引用该文件（可选），如上传者本人或 CanAccessFile 为 true
+	// 不可引用时拒绝发送（ErrFileAccessDenied）；未设置时不校验，CanAccessFile 会放行引用他人文件的消息
+	FileCheckFunc FileCheckFunc
+
+	// Metrics 监控指标（可选），如 observability.NewPrometheus()，记录在线连接数、消息处理和投递次数
+	Metrics observability.Metrics
+
+	// SlowQueryThreshold 慢 SQL 阈值（可选），默认 200ms，小于 0 时不回调 SlowQueryFunc
+	SlowQueryThreshold time.Duration
+
+	// SlowQueryFunc 慢 SQL 回调（可选），附带发起查询的仓库方法，如 MessageRepository.GetMessages
+	// 设置 Metrics 或 SlowQueryFunc 时在 DB 和 ReadDB 上注册 gormmetrics 插件，按仓库方法记录 im_db_query_duration_seconds
+	SlowQueryFunc gormmetrics.SlowQueryFunc
+
+	// WriteBatchSize 消息写入合并的最大条数（可选），大于 1 时开启 write-behind 合并写入，默认不开启
+	// 并发的消息插入和状态更新合并为多行 INSERT / UPDATE ... WHERE msg_id IN (...)，落库后才回复 ACK
+	WriteBatchSize int
+
+	// WriteBatchDelay 消息写入合并的最长等待时间（可选），默认 5ms，开启 WriteBatchSize 时生效
+	WriteBatchDelay time.Duration
+
+	// Version 节点版本（可选），如构建版本号，通过 ClusterInfo 展示，默认为主模块的构建版本
+	Version string
+
+	// IDGenerator 消息 ID 生成器（可选），如 idgen.NewSnowflake(node)，生成的 ID 以十进制字符串作为消息 ID
+	// 未设置时使用随机 UUID；客户端自带 msg_id 时不替换
+	IDGenerator idgen.Generator
 }
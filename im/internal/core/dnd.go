@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// SetDNDSchedule 设置用户免打扰时段
+func (s *IMServer) SetDNDSchedule(ctx context.Context, schedule *model.DNDSchedule) error {
+	if schedule.UserID == 0 {
+		return model.NewParamError("user_id", "user_id is required")
+	}
+	if _, err := parseClock(schedule.StartTime); err != nil {
+		return model.NewParamError("start_time", "invalid start_time: "+err.Error())
+	}
+	if _, err := parseClock(schedule.EndTime); err != nil {
+		return model.NewParamError("end_time", "invalid end_time: "+err.Error())
+	}
+	if schedule.Timezone != "" {
+		if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+			return model.NewParamError("timezone", "invalid timezone: "+err.Error())
+		}
+	}
+
+	return s.dndRepo.Save(ctx, schedule)
+}
+
+// GetDNDSchedule 获取用户免打扰时段，未设置时返回未启用的空时段
+func (s *IMServer) GetDNDSchedule(ctx context.Context, userID int64) (*model.DNDSchedule, error) {
+	schedule, err := s.dndRepo.Get(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.DNDSchedule{UserID: userID}, nil
+	}
+	return schedule, err
+}
+
+// isInDND 判断用户当前是否处于免打扰时段
+func (s *IMServer) isInDND(ctx context.Context, userID int64, now time.Time) bool {
+	schedule, err := s.dndRepo.Get(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warnf("Failed to get DND schedule for user %d: %v", userID, err)
+		}
+		return false
+	}
+	return dndActive(schedule, now)
+}
+
+// dndActive 判断给定时刻是否落在免打扰时段内
+func dndActive(schedule *model.DNDSchedule, now time.Time) bool {
+	if !schedule.Enabled {
+		return false
+	}
+
+	start, err := parseClock(schedule.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(schedule.EndTime)
+	if err != nil {
+		return false
+	}
+
+	if schedule.Timezone != "" {
+		if loc, err := time.LoadLocation(schedule.Timezone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	minute := now.Hour()*60 + now.Minute()
+
+	if start == end {
+		// 开始等于结束视为全天免打扰
+		return true
+	}
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// 跨天，如 22:00 - 08:00
+	return minute >= start || minute < end
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
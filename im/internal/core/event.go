@@ -0,0 +1,67 @@
+package core
+
+import (
+	"time"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// eventBus 事件总线，每种事件类型一个回调列表
+// 新增事件只需在 model.EventTypes 中添加类型并在对应位置 emit，不需要修改 IMService 接口
+type eventBus struct {
+	lists map[model.EventType]*callbackList[*model.Event] // 创建后只读
+}
+
+// newEventBus 创建事件总线
+func newEventBus() *eventBus {
+	b := &eventBus{lists: make(map[model.EventType]*callbackList[*model.Event], len(model.EventTypes))}
+	for _, t := range model.EventTypes {
+		b.lists[t] = &callbackList[*model.Event]{}
+	}
+	return b
+}
+
+// on 注册单个事件类型的回调，返回删除回调的函数
+func (b *eventBus) on(t model.EventType, handler func(*model.Event), opts []model.CallbackOptions) func() {
+	return b.lists[t].add(handler, opts)
+}
+
+// OnEvent 注册事件回调，types 为空时接收所有事件，返回删除回调的函数
+// 回调按优先级顺序调用，默认在后台协程中执行，panic 时记录日志和 im_panics_total
+func (s *IMServer) OnEvent(types []model.EventType, handler func(*model.Event), opts ...model.CallbackOptions) (func(), error) {
+	if handler == nil {
+		return nil, model.NewParamError("handler", "handler is required")
+	}
+	if len(types) == 0 {
+		types = model.EventTypes
+	}
+	seen := make(map[model.EventType]bool, len(types))
+	for _, t := range types {
+		if _, ok := s.events.lists[t]; !ok {
+			return nil, model.NewParamError("types", "unknown event type "+string(t))
+		}
+		seen[t] = true
+	}
+
+	removes := make([]func(), 0, len(seen))
+	for t := range seen {
+		removes = append(removes, s.events.on(t, handler, opts))
+	}
+	return func() {
+		for _, remove := range removes {
+			remove()
+		}
+	}, nil
+}
+
+// emit 触发事件，没有回调时直接返回；Time 为 0 时取当前时间
+func (s *IMServer) emit(event *model.Event) {
+	list := s.events.lists[event.Type]
+	if len(list.load()) == 0 {
+		return
+	}
+	if event.Time == 0 {
+		event.Time = time.Now().UnixMilli()
+	}
+	fireCallbacks(s, string(event.Type), list, event)
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// maxGroupNameLength 群组名称最大长度（字符）
+const maxGroupNameLength = 100
+
+// CreateGroup 创建群组，群主自动加入，memberIDs 为初始成员（可选）
+func (s *IMServer) CreateGroup(ctx context.Context, group *model.Group, memberIDs []int64) error {
+	if group.OwnerID == 0 {
+		return model.NewParamError("owner_id", "owner_id is required")
+	}
+	if err := validateGroup(group); err != nil {
+		return err
+	}
+
+	members := []*model.GroupMember{{UserID: group.OwnerID, Role: model.GroupRoleOwner}}
+	seen := map[int64]bool{group.OwnerID: true}
+	for _, id := range memberIDs {
+		if id <= 0 {
+			return model.NewParamError("member_ids", "invalid member id")
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		members = append(members, &model.GroupMember{UserID: id, Role: model.GroupRoleMember})
+	}
+
+	if err := s.groupRepo.CreateGroupWithMembers(ctx, group, members); err != nil {
+		return err
+	}
+	snapshot := *group
+	s.emit(&model.Event{Type: model.EventGroupCreated, UserID: group.OwnerID, Group: &snapshot})
+	return nil
+}
+
+// GetGroup 获取群组信息，不存在时返回 ErrGroupNotFound
+func (s *IMServer) GetGroup(ctx context.Context, groupID int64) (*model.Group, error) {
+	group, err := s.groupRepo.GetGroup(ctx, groupID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, model.ErrGroupNotFound
+	}
+	return group, err
+}
+
+// UpdateGroup 更新群组名称和头像
+func (s *IMServer) UpdateGroup(ctx context.Context, group *model.Group) error {
+	if err := validateGroup(group); err != nil {
+		return err
+	}
+	if _, err := s.GetGroup(ctx, group.GroupID); err != nil {
+		return err
+	}
+	return s.groupRepo.UpdateGroup(ctx, group)
+}
+
+// DeleteGroup 解散群组，删除全部成员
+func (s *IMServer) DeleteGroup(ctx context.Context, groupID int64) error {
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return err
+	}
+	return s.groupRepo.DeleteGroup(ctx, groupID)
+}
+
+// GetUserGroups 获取用户加入的群组
+func (s *IMServer) GetUserGroups(ctx context.Context, userID int64) ([]*model.Group, error) {
+	return s.groupRepo.GetUserGroups(ctx, userID)
+}
+
+// GetGroupMembers 获取群成员列表
+func (s *IMServer) GetGroupMembers(ctx context.Context, groupID int64) ([]*model.GroupMember, error) {
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.GetMembers(ctx, groupID)
+}
+
+// GetGroupMember 获取群成员，不是群成员时返回 ErrNotGroupMember
+func (s *IMServer) GetGroupMember(ctx context.Context, groupID, userID int64) (*model.GroupMember, error) {
+	member, err := s.groupRepo.GetMember(ctx, groupID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, model.ErrNotGroupMember
+	}
+	return member, err
+}
+
+// AddGroupMembers 添加群成员，已是成员的用户跳过
+func (s *IMServer) AddGroupMembers(ctx context.Context, groupID int64, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return model.NewParamError("user_ids", "user_ids is required")
+	}
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	for _, id := range userIDs {
+		if id <= 0 {
+			return model.NewParamError("user_ids", "invalid user id")
+		}
+		exists, err := s.groupRepo.IsMember(ctx, groupID, id)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := s.groupRepo.AddMember(ctx, &model.GroupMember{GroupID: groupID, UserID: id, Role: model.GroupRoleMember}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveGroupMember 移除群成员，群主不能被移除（需先解散群组）
+func (s *IMServer) RemoveGroupMember(ctx context.Context, groupID, userID int64) error {
+	member, err := s.GetGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == model.GroupRoleOwner {
+		return model.NewParamError("user_id", "group owner cannot be removed")
+	}
+	return s.groupRepo.RemoveMember(ctx, groupID, userID)
+}
+
+// validateGroup 校验群组名称和头像
+func validateGroup(group *model.Group) error {
+	group.GroupName = strings.TrimSpace(group.GroupName)
+	if group.GroupName == "" {
+		return model.NewParamError("group_name", "group_name is required")
+	}
+	if utf8.RuneCountInString(group.GroupName) > maxGroupNameLength {
+		return model.NewParamError("group_name", "group_name is too long")
+	}
+	if len(group.AvatarURL) > 255 {
+		return model.NewParamError("avatar_url", "avatar_url is too long")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+const (
+	maxSlowMode     = 3600      // 慢速模式最大间隔（秒）
+	maxNewcomerMute = 7 * 86400 // 新成员禁言最长时长（秒）
+)
+
+// linkPattern 链接：http(s)://、www. 开头，或常见顶级域名的域名
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+(\.[a-z0-9-]+)*\.(com|net|org|io|me|cn|co|xyz|top|info|cc|ly|gg)\b`)
+
+// SetGroupPolicy 设置群组防刷屏策略
+func (s *IMServer) SetGroupPolicy(ctx context.Context, policy *model.GroupPolicy) error {
+	if policy.SlowMode < 0 || policy.SlowMode > maxSlowMode {
+		return model.NewParamError("slow_mode", "slow_mode must be between 0 and 3600 seconds")
+	}
+	if policy.NewcomerMute < 0 || policy.NewcomerMute > maxNewcomerMute {
+		return model.NewParamError("newcomer_mute", "newcomer_mute must be between 0 and 7 days")
+	}
+	if _, err := s.GetGroup(ctx, policy.GroupID); err != nil {
+		return err
+	}
+	return s.groupRepo.UpdatePolicy(ctx, policy)
+}
+
+// GetGroupPolicy 获取群组防刷屏策略，不存在时返回 ErrGroupNotFound
+func (s *IMServer) GetGroupPolicy(ctx context.Context, groupID int64) (*model.GroupPolicy, error) {
+	policy, err := s.groupRepo.GetPolicy(ctx, groupID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, model.ErrGroupNotFound
+	}
+	return policy, err
+}
+
+// CheckGroupMessage 检查用户是否可以在群内发送消息，群主和管理员不受策略限制
+// 依次检查新成员禁言、图片、链接和慢速模式，通过时记录发送时间
+func (s *IMServer) CheckGroupMessage(ctx context.Context, groupID, userID int64, content string, msgType int) error {
+	member, err := s.GetGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role >= model.GroupRoleAdmin {
+		return nil
+	}
+	policy, err := s.GetGroupPolicy(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	if mutedUntil := member.JoinedAt + int64(policy.NewcomerMute)*1000; policy.NewcomerMute > 0 && now < mutedUntil {
+		return &model.GroupPolicyError{Reason: model.GroupRejectNewcomerMuted, RetryAfter: mutedUntil - now}
+	}
+	if policy.BlockImages && msgType == model.MsgTypeImage {
+		return &model.GroupPolicyError{Reason: model.GroupRejectImages}
+	}
+	if policy.BlockLinks && linkPattern.MatchString(content) {
+		return &model.GroupPolicyError{Reason: model.GroupRejectLinks}
+	}
+	if policy.SlowMode > 0 {
+		if wait := s.slowMode.allow(groupID, userID, int64(policy.SlowMode)*1000, now); wait > 0 {
+			return &model.GroupPolicyError{Reason: model.GroupRejectSlowMode, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// slowModeTracker 慢速模式下成员上一条消息的发送时间，按节点记录
+type slowModeTracker struct {
+	mu   sync.Mutex
+	last map[[2]int64]int64 // (群组 ID, 用户 ID) -> 发送时间（毫秒）
+}
+
+// slowModeSweepSize 记录数超过该值时清理超过最大间隔的记录
+const slowModeSweepSize = 10000
+
+// newSlowModeTracker 创建慢速模式记录
+func newSlowModeTracker() *slowModeTracker {
+	return &slowModeTracker{last: make(map[[2]int64]int64)}
+}
+
+// allow 距上一条消息已满 interval 毫秒时记录本次发送并返回 0，否则返回还需等待的毫秒数
+func (t *slowModeTracker) allow(groupID, userID, interval, now int64) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := [2]int64{groupID, userID}
+	if last, ok := t.last[key]; ok && now-last < interval {
+		return last + interval - now
+	}
+	if len(t.last) >= slowModeSweepSize {
+		for k, last := range t.last {
+			if now-last >= maxSlowMode*1000 {
+				delete(t.last, k)
+			}
+		}
+	}
+	t.last[key] = now
+	return 0
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/im/internal/protocol"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// maxGuestID 访客 ID 的绝对值上限，保持在 JavaScript 安全整数范围内
+const maxGuestID = 1<<53 - 1
+
+// newGuestID 生成临时访客 ID（负数），随机生成，不同节点之间无需协调
+func newGuestID() int64 {
+	return -(rand.Int63n(maxGuestID) + 1)
+}
+
+// guestAllowed 访客可以发送的 WebSocket 消息类型，单聊消息还需接收方在 GuestSendTo 中
+// 访客没有经过认证，不能发送回执等修改消息状态的操作
+func guestAllowed(msgType string) bool {
+	switch msgType {
+	case protocol.WSMsgTypePing, protocol.WSMsgTypeHello, protocol.WSMsgTypeChatMsg:
+		return true
+	}
+	return false
+}
+
+// guestCanSendTo 访客是否可以向 userID 发送单聊消息，客服模式下可以发给客服入口
+func (s *IMServer) guestCanSendTo(userID int64) bool {
+	if s.config.SupportUserID != 0 && userID == s.config.SupportUserID {
+		return true
+	}
+	for _, id := range s.config.GuestSendTo {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectGuest 回复访客不允许的操作，失败 ACK 的 code 为 guest_restricted
+func (s *IMServer) rejectGuest(userID int64, msgID string) {
+	s.metrics.Add("im_messages_rejected_total", 1, observability.L("reason", model.GuestRejectRestricted))
+	now := time.Now().UnixMilli()
+	ack := &protocol.WSAckMessage{
+		MsgID:      msgID,
+		Status:     model.MsgStatusFailed,
+		ServerTime: now,
+		Error:      "not allowed for guest connections",
+		Code:       model.GuestRejectRestricted,
+	}
+	s.hub.SendToUser(userID, protocol.EncodeAck(ack, now))
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/protocol"
+)
+
+// 处理握手，协商协议版本和能力
+func (s *IMServer) handleHello(client *Client, wsMsg *protocol.WSMessage) {
+	var hello protocol.WSHello
+	if err := wsMsg.DecodeData(&hello); err != nil {
+		log.Errorf("Invalid hello from user %d: %v", client.UserID, err)
+		return
+	}
+
+	ack := &protocol.WSHelloAck{
+		MinVersion:   protocol.MinProtocolVersion,
+		MaxVersion:   protocol.ProtocolVersion,
+		Capabilities: []string{},
+	}
+
+	version := hello.Version
+	if version == 0 {
+		version = protocol.MinProtocolVersion
+	}
+	if version > protocol.ProtocolVersion {
+		// 客户端版本更高，降级到服务端版本
+		version = protocol.ProtocolVersion
+	}
+
+	if version < protocol.MinProtocolVersion {
+		ack.Error = fmt.Sprintf("protocol version %d not supported, min version is %d",
+			hello.Version, protocol.MinProtocolVersion)
+		s.sendHelloAck(client, wsMsg.MsgID, ack)
+		log.Warnf("User %d uses unsupported protocol version %d", client.UserID, hello.Version)
+		return
+	}
+
+	ack.Version = version
+	ack.Capabilities = negotiateCapabilities(hello.Capabilities)
+	client.SetProtocol(version, ack.Capabilities)
+
+	log.Debugf("User %d negotiated protocol v%d, capabilities=%v", client.UserID, version, ack.Capabilities)
+	s.sendHelloAck(client, wsMsg.MsgID, ack)
+}
+
+// 发送握手响应
+func (s *IMServer) sendHelloAck(client *Client, msgID string, ack *protocol.WSHelloAck) {
+	data, _ := protocol.Encode(protocol.WSMsgTypeHelloAck, msgID, time.Now().UnixMilli(), ack)
+	s.hub.SendToClient(client, data)
+}
+
+// negotiateCapabilities 计算客户端与服务端能力的交集
+func negotiateCapabilities(clientCaps []string) []string {
+	supported := make(map[string]bool, len(protocol.SupportedCapabilities))
+	for _, capability := range protocol.SupportedCapabilities {
+		supported[capability] = true
+	}
+
+	caps := make([]string, 0, len(clientCaps))
+	for _, capability := range clientCaps {
+		if supported[capability] {
+			caps = append(caps, capability)
+			delete(supported, capability) // 去重
+		}
+	}
+	return caps
+}
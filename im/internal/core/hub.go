@@ -1,13 +1,26 @@
 package core
 
 import (
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/bbadbeef/go-base/im/internal/model"
 	"github.com/gorilla/websocket"
 )
 
+// kickCloseTimeout 被踢的连接发送缓冲消息的最长时间，超时后直接关闭
+const kickCloseTimeout = 5 * time.Second
+
 // Hub WebSocket 连接管理中心
+// 连接按用户 ID 分片，每个分片有独立的锁和广播协程，广播按分片并行投递
 type Hub struct {
+	shards []*hubShard
+}
+
+// hubShard 连接分片
+type hubShard struct {
 	clients   map[int64]*Client
 	mutex     sync.RWMutex
 	broadcast chan *BroadcastMessage
@@ -18,6 +31,26 @@ type Client struct {
 	UserID int64
 	Conn   *websocket.Conn
 	Send   chan []byte
+
+	// 写协程退出时关闭；closeCode 不为 0 时写协程发送完缓冲的消息后发送关闭帧
+	done      chan struct{}
+	closeCode int
+	closeText string
+
+	// 已移出分片（被踢、注销或服务停止），读协程不再处理该连接的消息
+	closed atomic.Bool
+
+	// 协议协商结果（未握手的客户端视为版本 1、无扩展能力）
+	protoMutex   sync.RWMutex
+	version      int
+	capabilities map[string]bool
+
+	// 发送频率限制（固定 1 秒窗口），仅在读协程中访问
+	rateWindow int64
+	rateCount  int
+
+	// 连接流量计数，未经 WebSocketHandler 接入的客户端为 nil
+	traffic *connTraffic
 }
 
 // BroadcastMessage 广播消息
@@ -26,49 +59,92 @@ type BroadcastMessage struct {
 	Data    []byte
 }
 
-// NewHub 创建 Hub
+// NewHub 创建 Hub，分片数为 GOMAXPROCS
 func NewHub() *Hub {
-	return &Hub{
-		clients:   make(map[int64]*Client),
-		broadcast: make(chan *BroadcastMessage, 256),
+	return NewShardedHub(runtime.GOMAXPROCS(0))
+}
+
+// NewShardedHub 创建指定分片数的 Hub，shards 小于 1 时按 1 处理
+func NewShardedHub(shards int) *Hub {
+	if shards < 1 {
+		shards = 1
 	}
+	h := &Hub{shards: make([]*hubShard, shards)}
+	for i := range h.shards {
+		h.shards[i] = &hubShard{
+			clients:   make(map[int64]*Client),
+			broadcast: make(chan *BroadcastMessage, 256),
+		}
+	}
+	return h
 }
 
-// Run 启动 Hub
+// shard 用户所在分片
+func (h *Hub) shard(userID int64) *hubShard {
+	return h.shards[h.shardIndex(userID)]
+}
+
+// shardIndex 用户所在分片的下标
+func (h *Hub) shardIndex(userID int64) int {
+	i := userID % int64(len(h.shards))
+	if i < 0 {
+		i = -i
+	}
+	return int(i)
+}
+
+// Run 启动各分片的广播协程
 func (h *Hub) Run() {
-	for msg := range h.broadcast {
-		h.mutex.RLock()
+	var wg sync.WaitGroup
+	for _, shard := range h.shards {
+		wg.Add(1)
+		go func(shard *hubShard) {
+			defer wg.Done()
+			shard.run()
+		}(shard)
+	}
+	wg.Wait()
+}
+
+// run 投递本分片的广播消息，发送缓冲已满的客户端断开连接
+func (s *hubShard) run() {
+	for msg := range s.broadcast {
+		var slow []*Client
+		s.mutex.RLock()
 		for _, userID := range msg.UserIDs {
-			if client, ok := h.clients[userID]; ok {
+			if client, ok := s.clients[userID]; ok {
 				select {
 				case client.Send <- msg.Data:
 				default:
-					// 发送失败，关闭连接
-					close(client.Send)
-					delete(h.clients, userID)
+					slow = append(slow, client)
 				}
 			}
 		}
-		h.mutex.RUnlock()
+		s.mutex.RUnlock()
+
+		for _, client := range slow {
+			s.remove(client.UserID, client)
+		}
 	}
 }
 
-// Register 注册客户端
-func (h *Hub) Register(userID int64, conn *websocket.Conn) *Client {
-	client := &Client{
-		UserID: userID,
-		Conn:   conn,
-		Send:   make(chan []byte, 256),
+// remove 移除并断开用户的客户端，client 不为 nil 时仅当仍是该连接时移除（用户可能已重连）
+// 返回用户是否已没有客户端
+func (s *hubShard) remove(userID int64, client *Client) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	current, ok := s.clients[userID]
+	if ok && (client == nil || current == client) {
+		current.close()
+		delete(s.clients, userID)
+		return true
 	}
+	return !ok
+}
 
-	h.mutex.Lock()
-	// 如果用户已存在连接，关闭旧连接
-	if oldClient, exists := h.clients[userID]; exists {
-		close(oldClient.Send)
-		oldClient.Conn.Close()
-	}
-	h.clients[userID] = client
-	h.mutex.Unlock()
+// Register 注册客户端，traffic 为连接流量计数（可为 nil）
+func (h *Hub) Register(userID int64, conn *websocket.Conn, traffic *connTraffic) *Client {
+	client := h.add(userID, conn, make(chan []byte, 256), traffic)
 
 	// 启动写协程
 	go client.writePump()
@@ -76,23 +152,80 @@ func (h *Hub) Register(userID int64, conn *websocket.Conn) *Client {
 	return client
 }
 
+// Attach 注册不带 WebSocket 连接的客户端，推送给该用户的消息写入 send，由调用方读取
+// 用于基准测试和进程内投递，Unregister 时关闭 send
+func (h *Hub) Attach(userID int64, send chan []byte) *Client {
+	client := h.add(userID, nil, send, nil)
+	close(client.done)
+	return client
+}
+
+// add 加入客户端，用户已存在连接时通知旧连接在其他设备登录并关闭
+func (h *Hub) add(userID int64, conn *websocket.Conn, send chan []byte, traffic *connTraffic) *Client {
+	client := &Client{
+		UserID:  userID,
+		Conn:    conn,
+		Send:    send,
+		done:    make(chan struct{}),
+		version: 1,
+		traffic: traffic,
+	}
+
+	shard := h.shard(userID)
+	shard.mutex.Lock()
+	if oldClient, exists := shard.clients[userID]; exists {
+		oldClient.kick(kickNotice(model.KickReasonLoggedInElsewhere))
+	}
+	shard.clients[userID] = client
+	shard.mutex.Unlock()
+
+	return client
+}
+
 // Unregister 注销客户端
 func (h *Hub) Unregister(userID int64) {
-	h.mutex.Lock()
-	if client, ok := h.clients[userID]; ok {
-		close(client.Send)
-		client.Conn.Close()
-		delete(h.clients, userID)
+	h.shard(userID).remove(userID, nil)
+}
+
+// UnregisterClient 注销指定连接，返回该用户在本节点是否已没有连接
+// 用户已在本节点重新连接时旧连接断开不影响新连接，返回 false
+func (h *Hub) UnregisterClient(client *Client) bool {
+	return h.shard(client.UserID).remove(client.UserID, client)
+}
+
+// CloseAll 向所有客户端发送 data 后断开连接，写协程发送完缓冲的消息后发送关闭帧
+// 返回被断开的客户端，可通过 Done 等待缓冲的消息发送完成
+func (h *Hub) CloseAll(data []byte) []*Client {
+	var clients []*Client
+	for _, shard := range h.shards {
+		shard.mutex.Lock()
+		for userID, client := range shard.clients {
+			client.closeWith(data, websocket.CloseGoingAway, "server shutting down")
+			delete(shard.clients, userID)
+			clients = append(clients, client)
+		}
+		shard.mutex.Unlock()
+	}
+	return clients
+}
+
+// Kick 向用户的客户端发送 data 后断开连接，返回用户是否连接在本节点
+func (h *Hub) Kick(userID int64, data []byte) bool {
+	shard := h.shard(userID)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	client, ok := shard.clients[userID]
+	if !ok {
+		return false
 	}
-	h.mutex.Unlock()
+	client.kick(data)
+	delete(shard.clients, userID)
+	return true
 }
 
 // SendToUser 发送消息给指定用户
 func (h *Hub) SendToUser(userID int64, data []byte) bool {
-	h.mutex.RLock()
-	client, exists := h.clients[userID]
-	h.mutex.RUnlock()
-
+	client, exists := h.GetClient(userID)
 	if !exists {
 		return false
 	}
@@ -105,38 +238,176 @@ func (h *Hub) SendToUser(userID int64, data []byte) bool {
 	}
 }
 
-// SendToUsers 发送消息给多个用户
+// SendToUsers 发送消息给多个用户，按分片拆分后由各分片的广播协程并行投递
 func (h *Hub) SendToUsers(userIDs []int64, data []byte) {
-	h.broadcast <- &BroadcastMessage{
-		UserIDs: userIDs,
-		Data:    data,
+	if len(h.shards) == 1 {
+		h.shards[0].broadcast <- &BroadcastMessage{UserIDs: userIDs, Data: data}
+		return
+	}
+
+	groups := make([][]int64, len(h.shards))
+	for _, userID := range userIDs {
+		i := h.shardIndex(userID)
+		groups[i] = append(groups[i], userID)
+	}
+	for i, ids := range groups {
+		if len(ids) > 0 {
+			h.shards[i].broadcast <- &BroadcastMessage{UserIDs: ids, Data: data}
+		}
 	}
 }
 
+// SendToClient 发送消息给指定连接，连接已移出分片（如被踢下线）或发送缓冲已满时丢弃
+// 与 SendToUser 不同，用户在本节点重新连接后不会发给新连接
+func (h *Hub) SendToClient(client *Client, data []byte) bool {
+	shard := h.shard(client.UserID)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+	// Send 只在移出分片时持有写锁关闭，仍在分片中时可以安全写入
+	if shard.clients[client.UserID] != client {
+		return false
+	}
+	select {
+	case client.Send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetClient 获取用户的连接
+func (h *Hub) GetClient(userID int64) (*Client, bool) {
+	shard := h.shard(userID)
+	shard.mutex.RLock()
+	client, exists := shard.clients[userID]
+	shard.mutex.RUnlock()
+	return client, exists
+}
+
 // HasClient 检查用户是否在线
 func (h *Hub) HasClient(userID int64) bool {
-	h.mutex.RLock()
-	_, exists := h.clients[userID]
-	h.mutex.RUnlock()
+	_, exists := h.GetClient(userID)
 	return exists
 }
 
+// Count 获取在线连接数
+func (h *Hub) Count() int {
+	count := 0
+	for _, shard := range h.shards {
+		shard.mutex.RLock()
+		count += len(shard.clients)
+		shard.mutex.RUnlock()
+	}
+	return count
+}
+
 // GetOnlineUsers 获取所有在线用户
 func (h *Hub) GetOnlineUsers() []int64 {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
-
-	userIDs := make([]int64, 0, len(h.clients))
-	for userID := range h.clients {
-		userIDs = append(userIDs, userID)
+	userIDs := make([]int64, 0, h.Count())
+	for _, shard := range h.shards {
+		shard.mutex.RLock()
+		for userID := range shard.clients {
+			userIDs = append(userIDs, userID)
+		}
+		shard.mutex.RUnlock()
 	}
 	return userIDs
 }
 
-// writePump 写协程
+// Clients 获取所有客户端
+func (h *Hub) Clients() []*Client {
+	clients := make([]*Client, 0, h.Count())
+	for _, shard := range h.shards {
+		shard.mutex.RLock()
+		for _, client := range shard.clients {
+			clients = append(clients, client)
+		}
+		shard.mutex.RUnlock()
+	}
+	return clients
+}
+
+// SetProtocol 设置协商后的协议版本和能力
+func (c *Client) SetProtocol(version int, capabilities []string) {
+	caps := make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		caps[capability] = true
+	}
+
+	c.protoMutex.Lock()
+	c.version = version
+	c.capabilities = caps
+	c.protoMutex.Unlock()
+}
+
+// Version 获取协商后的协议版本
+func (c *Client) Version() int {
+	c.protoMutex.RLock()
+	defer c.protoMutex.RUnlock()
+	return c.version
+}
+
+// HasCapability 检查客户端是否协商了指定能力
+func (c *Client) HasCapability(capability string) bool {
+	c.protoMutex.RLock()
+	defer c.protoMutex.RUnlock()
+	return c.capabilities[capability]
+}
+
+// allow 判断当前 1 秒窗口内发送的消息数是否未超过 limit，并计入本条消息
+func (c *Client) allow(limit int, now time.Time) bool {
+	if window := now.Unix(); window != c.rateWindow {
+		c.rateWindow = window
+		c.rateCount = 0
+	}
+	c.rateCount++
+	return c.rateCount <= limit
+}
+
+// Done 返回写协程退出时关闭的 channel
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// closeWith 发送 data 后关闭发送缓冲，写协程发送完缓冲的消息后发送关闭帧
+// 与 close 一样只能在客户端移出分片时调用一次
+func (c *Client) closeWith(data []byte, code int, text string) {
+	select {
+	case c.Send <- data:
+	default:
+	}
+	c.closeCode = code
+	c.closeText = text
+	c.closed.Store(true)
+	close(c.Send)
+}
+
+// kick 发送踢下线通知后断开连接，超过 kickCloseTimeout 仍未发送完时直接关闭
+func (c *Client) kick(data []byte) {
+	c.closeWith(data, websocket.ClosePolicyViolation, "kicked")
+	if c.Conn != nil {
+		conn := c.Conn
+		time.AfterFunc(kickCloseTimeout, func() { conn.Close() })
+	}
+}
+
+// close 关闭发送缓冲和连接
+func (c *Client) close() {
+	c.closed.Store(true)
+	close(c.Send)
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
+}
+
+// writePump 写协程，panic 时记录日志并关闭连接
 func (c *Client) writePump() {
 	defer func() {
+		if r := recover(); r != nil {
+			logPanic("write_loop", r)
+		}
 		c.Conn.Close()
+		close(c.done)
 	}()
 
 	for data := range c.Send {
@@ -144,4 +415,9 @@ func (c *Client) writePump() {
 			return
 		}
 	}
+
+	if c.closeCode != 0 {
+		closeMsg := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+		c.Conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+	}
 }
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Hub 基准测试：在进程内对注册、单发和广播进行测试，不依赖数据库和网络，比较不同分片数下的吞吐
+//
+//	go test ./internal/core -run '^$' -bench 'Hub' -benchmem
+//
+// 每项按在线客户端数（10k/100k）和分片数（1/GOMAXPROCS）拆分子测试，如 BenchmarkHubBroadcast/clients=100000/shards=8
+
+// benchGroupSize 每次广播的接收人数
+const benchGroupSize = 100
+
+// hubBench 一组 (客户端数, 分片数) 的测试环境
+// 模拟客户端不建立 WebSocket 连接，每个客户端一个读取协程（对应真实连接的写协程）读取推送的消息并计数
+type hubBench struct {
+	hub      *Hub
+	clients  int
+	received atomic.Int64
+}
+
+// runHubBench 按客户端数和分片数运行子测试，每组共用一个已注册全部客户端的 Hub
+func runHubBench(b *testing.B, fn func(b *testing.B, env *hubBench)) {
+	shardCounts := []int{1}
+	if n := runtime.GOMAXPROCS(0); n > 1 {
+		shardCounts = append(shardCounts, n)
+	}
+	for _, clients := range []int{10000, 100000} {
+		for _, shards := range shardCounts {
+			env := newHubBench(clients, shards)
+			b.Run(fmt.Sprintf("clients=%d/shards=%d", clients, shards), func(b *testing.B) {
+				fn(b, env)
+			})
+			env.close()
+		}
+	}
+}
+
+// newHubBench 创建 Hub 并注册 clients 个模拟客户端
+func newHubBench(clients, shards int) *hubBench {
+	env := &hubBench{
+		hub:     NewShardedHub(shards),
+		clients: clients,
+	}
+	go env.hub.Run()
+
+	for i := 0; i < clients; i++ {
+		send := make(chan []byte, 32)
+		env.hub.Attach(int64(i+1), send)
+		go env.drain(send)
+	}
+	return env
+}
+
+// drain 读取推送的消息并计数，客户端注销后退出
+func (e *hubBench) drain(send chan []byte) {
+	for range send {
+		e.received.Add(1)
+	}
+}
+
+// close 注销所有模拟客户端
+func (e *hubBench) close() {
+	for i := 0; i < e.clients; i++ {
+		e.hub.Unregister(int64(i + 1))
+	}
+}
+
+// BenchmarkHubRegister 注册并注销客户端
+func BenchmarkHubRegister(b *testing.B) {
+	runHubBench(b, func(b *testing.B, env *hubBench) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			userID := int64(env.clients) + 1 + rand.Int63n(1<<40)
+			for pb.Next() {
+				env.hub.Attach(userID, make(chan []byte, 1))
+				env.hub.Unregister(userID)
+				userID++
+			}
+		})
+	})
+}
+
+// BenchmarkHubSendToUser 并发发送给随机在线用户
+func BenchmarkHubSendToUser(b *testing.B) {
+	data := []byte(`{"type":"chat_msg"}`)
+	runHubBench(b, func(b *testing.B, env *hubBench) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			rnd := rand.New(rand.NewSource(rand.Int63()))
+			for pb.Next() {
+				env.hub.SendToUser(rnd.Int63n(int64(env.clients))+1, data)
+			}
+		})
+	})
+}
+
+// BenchmarkHubBroadcast 并发广播给随机的 benchGroupSize 个在线用户，计时包含投递完成
+func BenchmarkHubBroadcast(b *testing.B) {
+	data := []byte(`{"type":"group_msg"}`)
+	runHubBench(b, func(b *testing.B, env *hubBench) {
+		start := env.received.Load()
+		var expected atomic.Int64
+
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			rnd := rand.New(rand.NewSource(rand.Int63()))
+			userIDs := make([]int64, benchGroupSize)
+			for pb.Next() {
+				for i := range userIDs {
+					userIDs[i] = rnd.Int63n(int64(env.clients)) + 1
+				}
+				env.hub.SendToUsers(append([]int64(nil), userIDs...), data)
+				expected.Add(int64(len(userIDs)))
+			}
+		})
+
+		// 广播异步投递，等待投递完成
+		// 接收缓冲满的客户端会被断开，收不到的消息不再等待：连续 50ms 没有新投递即结束
+		last, idleSince := env.received.Load(), time.Now()
+		for env.received.Load()-start < expected.Load() && time.Since(idleSince) < 50*time.Millisecond {
+			runtime.Gosched()
+			if n := env.received.Load(); n != last {
+				last, idleSince = n, time.Now()
+			}
+		}
+	})
+}
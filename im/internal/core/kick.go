@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	imgrpc "github.com/bbadbeef/go-base/im/internal/grpc"
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/im/internal/protocol"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// kickNotice 编码踢下线通知帧
+func kickNotice(reason string) []byte {
+	data, _ := protocol.Encode(protocol.WSMsgTypeKicked, "", time.Now().UnixMilli(), &protocol.WSKicked{Reason: reason})
+	return data
+}
+
+// validKickReason 检查踢下线原因
+func validKickReason(reason string) bool {
+	switch reason {
+	case model.KickReasonAdmin, model.KickReasonBanned, model.KickReasonLoggedInElsewhere:
+		return true
+	}
+	return false
+}
+
+// KickUser 强制用户下线：向其连接发送 kicked 通知后断开，并删除其路由
+// 用户连接在其他节点时转发给该节点处理，返回用户是否在线（被踢下线）
+func (s *IMServer) KickUser(ctx context.Context, userID int64, reason string) (bool, error) {
+	if userID <= 0 {
+		return false, model.NewParamError("user_id", "invalid user_id")
+	}
+	if !validKickReason(reason) {
+		return false, model.NewParamError("reason", "invalid reason")
+	}
+
+	if s.kickLocal(userID, reason) {
+		return true, nil
+	}
+
+	gatewayID, gatewayAddr, online := s.routeManager.GetUserRoute(ctx, userID)
+	if !online || gatewayID == s.config.ServerID {
+		return false, nil
+	}
+
+	client, err := s.peerClient(ctx, gatewayAddr)
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.DisconnectUser(imgrpc.OutgoingContext(ctx), &imgrpc.DisconnectUserRequest{UserID: userID, Reason: reason})
+	if err != nil {
+		log.Ctx(ctx).Errorf("Failed to kick user %d on %s: %v", userID, gatewayID, err)
+		return false, err
+	}
+	s.routeManager.Forget(userID)
+	return resp.Kicked, nil
+}
+
+// kickLocal 断开用户在本节点的连接并注销路由，返回用户是否连接在本节点
+func (s *IMServer) kickLocal(userID int64, reason string) bool {
+	if !s.hub.Kick(userID, kickNotice(reason)) {
+		return false
+	}
+	s.metrics.Set("im_connections", float64(s.hub.Count()))
+	s.metrics.Add("im_kicks_total", 1, observability.L("reason", reason))
+	log.Infof("User %d kicked: %s", userID, reason)
+
+	// 读协程随后退出时路由已删除，不会重复触发下线回调
+	released, err := s.routeManager.Unregister(context.Background(), userID)
+	if err != nil {
+		log.Warnf("Failed to unregister route of user %d: %v", userID, err)
+	}
+	if released || err != nil {
+		s.fireUserOffline(userID)
+	}
+	return true
+}
+
+// DisconnectUser gRPC 服务端实现（其他节点转发的踢下线请求）
+func (s *IMServer) DisconnectUser(ctx context.Context, req *imgrpc.DisconnectUserRequest) (*imgrpc.DisconnectUserResponse, error) {
+	if !validKickReason(req.Reason) {
+		return nil, model.NewParamError("reason", "invalid reason")
+	}
+	return &imgrpc.DisconnectUserResponse{Kicked: s.kickLocal(req.UserID, req.Reason)}, nil
+}
@@ -0,0 +1,17 @@
+package core
+
+import (
+	"context"
+
+	"github.com/bbadbeef/go-base/migrate"
+)
+
+// Migrate 执行所有未执行的数据库迁移
+func (s *IMServer) Migrate(ctx context.Context) error {
+	return s.migrator.Migrate(ctx)
+}
+
+// MigrationStatus 获取所有数据库迁移的执行状态
+func (s *IMServer) MigrationStatus(ctx context.Context) ([]*migrate.Status, error) {
+	return s.migrator.Status(ctx)
+}
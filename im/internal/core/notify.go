@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// SetNotificationPreference 设置会话通知偏好，MuteUntil 不为 0 时到期后自动恢复全部通知
+func (s *IMServer) SetNotificationPreference(ctx context.Context, pref *model.NotificationPreference) error {
+	if pref.UserID == 0 || pref.TargetID == 0 {
+		return model.NewParamError("target_id", "user_id and target_id are required")
+	}
+	if pref.Level < model.NotifyLevelAll || pref.Level > model.NotifyLevelNone {
+		return model.NewParamError("level", fmt.Sprintf("invalid notify level: %d", pref.Level))
+	}
+	if pref.MuteUntil < 0 || (pref.MuteUntil > 0 && pref.MuteUntil <= time.Now().UnixMilli()) {
+		return model.NewParamError("mute_until", "mute_until must be 0 or in the future")
+	}
+	if pref.SessionType == 0 {
+		pref.SessionType = model.SessionTypeSingle
+	}
+	return s.notifyRepo.Save(ctx, pref)
+}
+
+// MuteSession 会话免打扰 duration 时长（如 1 小时、8 小时），duration <= 0 时永久免打扰
+// 到期后自动恢复全部通知，提前取消使用 DeleteNotificationPreference
+func (s *IMServer) MuteSession(ctx context.Context, userID, targetID int64, sessionType int, duration time.Duration) error {
+	pref := &model.NotificationPreference{
+		UserID:      userID,
+		TargetID:    targetID,
+		SessionType: sessionType,
+		Level:       model.NotifyLevelNone,
+	}
+	if duration > 0 {
+		pref.MuteUntil = time.Now().Add(duration).UnixMilli()
+	}
+	return s.SetNotificationPreference(ctx, pref)
+}
+
+// GetNotificationPreference 获取会话通知偏好，未设置或已到期时返回默认（全部通知）
+func (s *IMServer) GetNotificationPreference(ctx context.Context, userID, targetID int64, sessionType int) (*model.NotificationPreference, error) {
+	pref, err := s.notifyRepo.Get(ctx, userID, targetID, sessionType)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.NotificationPreference{
+			UserID:      userID,
+			TargetID:    targetID,
+			SessionType: sessionType,
+			Level:       model.NotifyLevelAll,
+		}, nil
+	}
+	return pref, err
+}
+
+// GetNotificationPreferences 获取用户设置过的所有会话通知偏好
+func (s *IMServer) GetNotificationPreferences(ctx context.Context, userID int64) ([]*model.NotificationPreference, error) {
+	return s.notifyRepo.GetUserPreferences(ctx, userID)
+}
+
+// DeleteNotificationPreference 删除会话通知偏好（恢复默认）
+func (s *IMServer) DeleteNotificationPreference(ctx context.Context, userID, targetID int64, sessionType int) error {
+	return s.notifyRepo.Delete(ctx, userID, targetID, sessionType)
+}
+
+// ShouldNotify 判断消息是否应通知接收方
+// 供离线推送以及主应用自行实现的 webhook 等通知渠道使用
+func (s *IMServer) ShouldNotify(ctx context.Context, userID int64, msg *model.Message) bool {
+	targetID, sessionType := msg.FromUserID, model.SessionTypeSingle
+	if msg.GroupID != 0 {
+		targetID, sessionType = msg.GroupID, model.SessionTypeGroup
+	}
+
+	pref, err := s.notifyRepo.Get(ctx, userID, targetID, sessionType)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warnf("Failed to get notification preference for user %d: %v", userID, err)
+		}
+		return true
+	}
+
+	switch pref.Level {
+	case model.NotifyLevelNone:
+		return false
+	case model.NotifyLevelMentions:
+		return isMentioned(msg, userID)
+	default:
+		return true
+	}
+}
+
+// isMentioned 判断消息是否 @ 了指定用户
+func isMentioned(msg *model.Message, userID int64) bool {
+	for _, id := range msg.AtUserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/im/internal/repository"
+)
+
+// peerDialTimeout 检查其他节点时单个节点的连接超时
+const peerDialTimeout = 3 * time.Second
+
+// Preflight 启动前检查外部依赖，返回全部失败项（errors.Join），每项满足 errors.Is(err, model.ErrPreflightFailed)：
+//   - 数据库可连接（DB 和 ReadDB）
+//   - 禁止自动迁移时没有未执行的迁移（自动迁移时由创建服务执行）
+//   - gRPC 地址可以监听
+//   - 路由表中其他活跃节点的 gRPC 地址可以连接
+func Preflight(ctx context.Context, config *Config) error {
+	var errs []error
+	if err := pingDB(ctx, config); err != nil {
+		// 数据库不可用时后续的迁移和节点检查没有意义
+		return fmt.Errorf("%w: database: %w", model.ErrPreflightFailed, err)
+	}
+
+	namer := &repository.TableNamer{
+		Prefix:    config.TablePrefix,
+		Overrides: config.TableNames,
+	}
+	if config.DisableAutoMigrate {
+		pending, err := repository.NewMigrator(config.DB, namer).Pending(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: migrations: %w", model.ErrPreflightFailed, err))
+		} else if pending > 0 {
+			errs = append(errs, fmt.Errorf("%w: %d database migrations pending, call Migrate to apply", model.ErrPreflightFailed, pending))
+		}
+	}
+
+	if config.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", config.GRPCAddr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: grpc addr: %w", model.ErrPreflightFailed, err))
+		} else {
+			lis.Close()
+		}
+	}
+
+	// 首次部署时节点表尚未创建，没有需要检查的节点
+	routeRepo := repository.NewRouteRepository(config.DB, namer)
+	if routeRepo.HasServerTable(ctx) {
+		servers, err := routeRepo.GetActiveServers(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: peers: %w", model.ErrPreflightFailed, err))
+		}
+		dialer := &net.Dialer{Timeout: peerDialTimeout}
+		for _, server := range servers {
+			if server.ServerID == config.ServerID || server.GRPCAddr == "" {
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, "tcp", server.GRPCAddr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%w: peer %s (%s): %w", model.ErrPreflightFailed, server.ServerID, server.GRPCAddr, err))
+				continue
+			}
+			conn.Close()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// pingDB 检查主库和只读库的连接
+func pingDB(ctx context.Context, config *Config) error {
+	sqlDB, err := config.DB.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return err
+	}
+	if config.ReadDB == nil {
+		return nil
+	}
+	readDB, err := config.ReadDB.DB()
+	if err != nil {
+		return fmt.Errorf("read db: %w", err)
+	}
+	if err := readDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("read db: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/im/internal/protocol"
+)
+
+// presenceSweepLimit 每轮最多处理的过期用户数，其余留到下一轮
+const presenceSweepLimit = 500
+
+// presenceTimeout 在线状态超时（秒），未配置时为 3 倍心跳间隔
+func (s *IMServer) presenceTimeout() int {
+	if s.config.PresenceTimeout > 0 {
+		return s.config.PresenceTimeout
+	}
+	return 3 * s.config.HeartbeatInterval
+}
+
+// presenceWorker 按心跳间隔清理心跳超时的用户路由
+// 连接所在节点崩溃后无人更新其用户心跳，由存活节点判定离线
+func (s *IMServer) presenceWorker() {
+	ticker := time.NewTicker(time.Duration(s.config.HeartbeatInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.expirePresence(s.ctx)
+		}
+	}
+}
+
+// expirePresence 删除心跳超时的用户路由并触发下线回调
+// 路由按条件删除，多个节点同时清理时每个用户只由一个节点触发 OnUserOffline
+func (s *IMServer) expirePresence(ctx context.Context) {
+	before := time.Now().Unix() - int64(s.presenceTimeout())
+	userIDs, err := s.routeRepo.GetStaleUserRoutes(ctx, before, presenceSweepLimit)
+	if err != nil {
+		log.Warnf("Failed to get stale user routes: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		// 连接仍在本节点（如心跳写入失败），刷新路由而不是判定离线
+		if s.hub.HasClient(userID) {
+			s.routeManager.Register(ctx, userID, s.config.ServerID)
+			continue
+		}
+
+		expired, err := s.routeRepo.ExpireUserRoute(ctx, userID, before)
+		if err != nil {
+			log.Warnf("Failed to expire route of user %d: %v", userID, err)
+			continue
+		}
+		if !expired {
+			continue
+		}
+
+		s.routeManager.Forget(userID)
+		s.metrics.Add("im_presence_expired_total", 1)
+		log.Infof("User %d presence expired, no heartbeat for %ds", userID, s.presenceTimeout())
+		s.fireUserOffline(userID)
+	}
+}
+
+// maxPresenceText 状态文字最大长度（字符）
+const maxPresenceText = 100
+
+// SetPresence 设置用户在线状态（online、busy、away、invisible）和状态文字，断线重连后保持
+// 在线状态只影响 GetPresences 展示给其他用户的结果，不影响消息路由和投递
+func (s *IMServer) SetPresence(ctx context.Context, userID int64, status, text string) error {
+	if userID == 0 {
+		return model.NewParamError("user_id", "user_id is required")
+	}
+	switch status {
+	case model.PresenceOnline, model.PresenceBusy, model.PresenceAway, model.PresenceInvisible:
+	default:
+		return model.NewParamError("status", "status must be online, busy, away or invisible")
+	}
+	if utf8.RuneCountInString(text) > maxPresenceText {
+		return model.NewParamError("text", "text must be at most 100 characters")
+	}
+
+	if status == model.PresenceOnline && text == "" {
+		return s.presenceRepo.Delete(ctx, userID)
+	}
+	return s.presenceRepo.Save(ctx, &model.Presence{UserID: userID, Status: status, Text: text})
+}
+
+// GetPresences 批量获取 viewerID 看到的用户在线状态
+// 未连接的用户为 offline；隐身用户对其他人显示为 offline，对自己显示 invisible
+func (s *IMServer) GetPresences(ctx context.Context, viewerID int64, userIDs []int64) (map[int64]*model.Presence, error) {
+	result := make(map[int64]*model.Presence, len(userIDs))
+	var connected []int64
+	for _, userID := range userIDs {
+		if s.IsUserOnline(userID) {
+			connected = append(connected, userID)
+		} else {
+			result[userID] = &model.Presence{UserID: userID, Status: model.PresenceOffline}
+		}
+	}
+
+	stored, err := s.presenceRepo.GetBatch(ctx, connected)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range connected {
+		presence, ok := stored[userID]
+		if !ok {
+			presence = &model.Presence{UserID: userID, Status: model.PresenceOnline}
+		}
+		if presence.Status == model.PresenceInvisible && userID != viewerID {
+			presence = &model.Presence{UserID: userID, Status: model.PresenceOffline}
+		}
+		result[userID] = presence
+	}
+	return result, nil
+}
+
+// handlePresence 处理客户端设置在线状态
+func (s *IMServer) handlePresence(ctx context.Context, userID int64, wsMsg *protocol.WSMessage) {
+	var wsPresence protocol.WSPresence
+	if err := wsMsg.DecodeData(&wsPresence); err != nil {
+		log.Errorf("Invalid presence from user %d: %v", userID, err)
+		return
+	}
+	if err := s.SetPresence(ctx, userID, wsPresence.Status, wsPresence.Text); err != nil {
+		log.Errorf("Failed to set presence for user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"runtime/debug"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// recoverPanic 恢复 panic，记录堆栈和 im_panics_total，需直接 defer 调用
+// source 为发生 panic 的位置，如回调名、read_loop
+func (s *IMServer) recoverPanic(source string) {
+	if r := recover(); r != nil {
+		logPanic(source, r)
+		s.metrics.Add("im_panics_total", 1, observability.L("source", source))
+	}
+}
+
+// logPanic 记录恢复的 panic 和堆栈
+func logPanic(source string, r interface{}) {
+	log.Errorf("Recovered from panic in %s: %v\n%s", source, r, debug.Stack())
+}
+
+// runCallback 执行主应用注册的回调，回调 panic 时记录日志，不影响服务和其他回调
+func (s *IMServer) runCallback(name string, fn func()) {
+	defer s.recoverPanic(name)
+	fn()
+}
+
+// goCallback 在后台执行主应用注册的回调
+func (s *IMServer) goCallback(name string, fn func()) {
+	s.goTask(func() { s.runCallback(name, fn) })
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+const maxReportText = 500 // 举报原因和处理备注最大长度（字符）
+
+// ReportMessage 举报消息，只能举报自己可见的消息（单聊双方或群成员）
+// 保存举报时的消息快照并异步通知 OnMessageReported 回调，同一用户重复举报同一消息时返回已有举报且不再通知
+func (s *IMServer) ReportMessage(ctx context.Context, reporterID int64, msgID, reason string) (*model.MessageReport, error) {
+	if msgID == "" {
+		return nil, model.NewParamError("msg_id", "msg_id is required")
+	}
+	if reason == "" {
+		return nil, model.NewParamError("reason", "reason is required")
+	}
+	if utf8.RuneCountInString(reason) > maxReportText {
+		return nil, model.NewParamError("reason", "reason must be at most 500 characters")
+	}
+
+	msg, err := s.messageRepo.GetByMsgID(ctx, msgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, model.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if msg.GroupID != 0 {
+		ok, err := s.groupRepo.IsMember(ctx, msg.GroupID, reporterID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, model.ErrMessageNotFound
+		}
+	} else if reporterID != msg.FromUserID && reporterID != msg.ToUserID {
+		return nil, model.ErrMessageNotFound
+	}
+
+	report := &model.MessageReport{
+		MsgID:      msg.MsgID,
+		ReporterID: reporterID,
+		Reason:     reason,
+		FromUserID: msg.FromUserID,
+		GroupID:    msg.GroupID,
+		Content:    msg.Content,
+		MsgType:    msg.MsgType,
+		FileID:     msg.FileID,
+	}
+	created, err := s.reportRepo.Create(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return s.reportRepo.GetByReporter(ctx, msgID, reporterID)
+	}
+
+	s.metrics.Add("im_reports_total", 1)
+	snapshot := *report
+	s.emit(&model.Event{Type: model.EventMessageReported, UserID: reporterID, Report: &snapshot})
+	return report, nil
+}
+
+// ListReports 分页获取举报，按举报时间倒序
+func (s *IMServer) ListReports(ctx context.Context, req *model.ListReportsRequest) (*model.ReportPage, error) {
+	if req.Status < 0 || req.Status > model.ReportStatusDismissed {
+		return nil, model.NewParamError("status", "invalid report status")
+	}
+	if req.Limit < 0 {
+		return nil, model.NewParamError("limit", "invalid limit")
+	}
+	return s.reportRepo.List(ctx, req)
+}
+
+// ResolveReport 处理举报，status 为 ReportStatusResolved 或 ReportStatusDismissed
+// 举报不存在或已处理时返回 ErrReportNotFound
+func (s *IMServer) ResolveReport(ctx context.Context, reportID, resolverID int64, status int, note string) (*model.MessageReport, error) {
+	if status != model.ReportStatusResolved && status != model.ReportStatusDismissed {
+		return nil, model.NewParamError("status", "status must be resolved or dismissed")
+	}
+	if utf8.RuneCountInString(note) > maxReportText {
+		return nil, model.NewParamError("note", "note must be at most 500 characters")
+	}
+	updated, err := s.reportRepo.Resolve(ctx, reportID, status, resolverID, note, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, model.ErrReportNotFound
+	}
+	return s.reportRepo.Get(ctx, reportID)
+}
+
+// OnMessageReported 设置消息举报回调（EventMessageReported），用于通知审核服务，每条新举报触发一次，返回删除回调的函数
+func (s *IMServer) OnMessageReported(handler func(*model.MessageReport), opts ...model.CallbackOptions) func() {
+	if handler == nil {
+		return func() {}
+	}
+	return s.events.on(model.EventMessageReported, func(e *model.Event) { handler(e.Report) }, opts)
+}
@@ -1,7 +1,9 @@
 package core
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bbadbeef/go-base/im/internal/repository"
@@ -11,7 +13,7 @@ import (
 type RouteManager struct {
 	serverID  string
 	routeRepo *repository.RouteRepository
-	cacheTTL  int
+	cacheTTL  atomic.Int64 // 秒，可通过 SetCacheTTL 热更新
 
 	// 本地缓存
 	userRoutes   map[int64]*RouteCache
@@ -27,19 +29,25 @@ type RouteCache struct {
 
 // NewRouteManager 创建路由管理器
 func NewRouteManager(serverID string, routeRepo *repository.RouteRepository, cacheTTL int) *RouteManager {
-	return &RouteManager{
+	rm := &RouteManager{
 		serverID:     serverID,
 		routeRepo:    routeRepo,
-		cacheTTL:     cacheTTL,
 		userRoutes:   make(map[int64]*RouteCache),
 		gatewayAddrs: make(map[string]string),
 	}
+	rm.SetCacheTTL(cacheTTL)
+	return rm
 }
 
-// Register 注册用户路由
-func (rm *RouteManager) Register(userID int64, gatewayID string) error {
+// SetCacheTTL 设置路由缓存时间（秒），已缓存的路由按新的 TTL 判断是否过期
+func (rm *RouteManager) SetCacheTTL(seconds int) {
+	rm.cacheTTL.Store(int64(seconds))
+}
+
+// Register 注册用户路由，覆盖指向其他节点的旧路由（最新的连接优先）
+func (rm *RouteManager) Register(ctx context.Context, userID int64, gatewayID string) error {
 	// 写入数据库
-	if err := rm.routeRepo.RegisterUserRoute(userID, gatewayID); err != nil {
+	if err := rm.routeRepo.RegisterUserRoute(ctx, userID, gatewayID); err != nil {
 		return err
 	}
 
@@ -54,28 +62,28 @@ func (rm *RouteManager) Register(userID int64, gatewayID string) error {
 	return nil
 }
 
-// Unregister 注销用户路由
-func (rm *RouteManager) Unregister(userID int64) error {
+// Unregister 注销本节点持有的用户路由，返回是否删除
+// 用户已重新连接到其他节点时保留新路由，返回 false
+func (rm *RouteManager) Unregister(ctx context.Context, userID int64) (bool, error) {
 	// 从数据库删除
-	if err := rm.routeRepo.UnregisterUserRoute(userID); err != nil {
-		return err
+	released, err := rm.routeRepo.ReleaseUserRoute(ctx, userID, rm.serverID)
+	if err != nil {
+		return false, err
 	}
 
 	// 清理本地缓存
-	rm.mutex.Lock()
-	delete(rm.userRoutes, userID)
-	rm.mutex.Unlock()
+	rm.Forget(userID)
 
-	return nil
+	return released, nil
 }
 
 // GetUserRoute 获取用户路由
 // 返回: gatewayID, gatewayAddr, online
-func (rm *RouteManager) GetUserRoute(userID int64) (string, string, bool) {
+func (rm *RouteManager) GetUserRoute(ctx context.Context, userID int64) (string, string, bool) {
 	// 1. 查本地缓存
 	rm.mutex.RLock()
 	if route, exists := rm.userRoutes[userID]; exists {
-		if time.Now().Unix()-route.CacheTime < int64(rm.cacheTTL) {
+		if time.Now().Unix()-route.CacheTime < rm.cacheTTL.Load() {
 			addr := rm.gatewayAddrs[route.GatewayID]
 			rm.mutex.RUnlock()
 			return route.GatewayID, addr, true
@@ -84,7 +92,7 @@ func (rm *RouteManager) GetUserRoute(userID int64) (string, string, bool) {
 	rm.mutex.RUnlock()
 
 	// 2. 缓存未命中或过期，查询数据库
-	userRoute, err := rm.routeRepo.GetUserRoute(userID)
+	userRoute, err := rm.routeRepo.GetUserRoute(ctx, userID)
 	if err != nil {
 		return "", "", false
 	}
@@ -101,7 +109,37 @@ func (rm *RouteManager) GetUserRoute(userID int64) (string, string, bool) {
 	return userRoute.ServerID, userRoute.GRPCAddr, true
 }
 
-// BatchUpdateHeartbeat 批量更新用户心跳
-func (rm *RouteManager) BatchUpdateHeartbeat(userIDs []int64) error {
-	return rm.routeRepo.BatchUpdateHeartbeat(userIDs)
+// Forget 清理用户路由的本地缓存
+func (rm *RouteManager) Forget(userID int64) {
+	rm.mutex.Lock()
+	delete(rm.userRoutes, userID)
+	rm.mutex.Unlock()
+}
+
+// BatchUpdateHeartbeat 批量更新本节点用户的路由心跳
+// 部分用户的路由已被删除（如被其他节点判定心跳超时）时补建，避免仍在线的用户被视为离线；
+// 已指向其他节点的路由说明用户已在别处重新连接，不覆盖
+func (rm *RouteManager) BatchUpdateHeartbeat(ctx context.Context, userIDs []int64) error {
+	updated, err := rm.routeRepo.BatchUpdateHeartbeat(ctx, userIDs, rm.serverID)
+	if err != nil || updated >= int64(len(userIDs)) {
+		return err
+	}
+	// 只为路由不在本节点的用户补建
+	held, err := rm.routeRepo.FilterServerUsers(ctx, userIDs, rm.serverID)
+	if err != nil {
+		return err
+	}
+	heldSet := make(map[int64]struct{}, len(held))
+	for _, userID := range held {
+		heldSet[userID] = struct{}{}
+	}
+	for _, userID := range userIDs {
+		if _, ok := heldSet[userID]; ok {
+			continue
+		}
+		if err := rm.routeRepo.EnsureUserRoute(ctx, userID, rm.serverID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/im/internal/repository"
+)
+
+// 会话内消息顺序保证：
+// 同一会话（单聊为有序用户对，群聊为群组）的消息在会话锁内依次分配服务端时间、持久化、ACK 和投递，
+// 前一条投递完成（跨节点时 ForwardMessage 返回）后才处理下一条，因此
+//   - 接收方在线时，同一会话的推送按 server_time 顺序到达
+//   - 同一会话的 server_time 单调不减，相同时按消息自增 ID 排序，离线推送和历史消息按 (server_time, id) 排序
+// 不同会话之间不保证顺序；异步的 OnMessage 回调不保证顺序
+// 用户上线时离线推送与新消息并发，客户端应按 msg_id 去重并在会话内按 server_time 排序
+
+// convSequencer 会话锁，只保留有消息在处理中的会话
+type convSequencer struct {
+	mu    sync.Mutex
+	convs map[string]*convSeq
+}
+
+// convSeq 单个会话的锁和时间
+type convSeq struct {
+	mu       sync.Mutex
+	refs     int   // 持有和等待锁的数量，归零时删除
+	lastTime int64 // 上一条消息的服务端时间（毫秒）
+}
+
+// newConvSequencer 创建会话锁
+func newConvSequencer() *convSequencer {
+	return &convSequencer{convs: make(map[string]*convSeq)}
+}
+
+// acquire 获取会话锁，处理完成后调用 release
+func (q *convSequencer) acquire(key string) *convSeq {
+	q.mu.Lock()
+	c, ok := q.convs[key]
+	if !ok {
+		c = &convSeq{}
+		q.convs[key] = c
+	}
+	c.refs++
+	q.mu.Unlock()
+
+	c.mu.Lock()
+	return c
+}
+
+// release 释放会话锁
+func (q *convSequencer) release(key string, c *convSeq) {
+	c.mu.Unlock()
+
+	q.mu.Lock()
+	c.refs--
+	if c.refs == 0 {
+		delete(q.convs, key)
+	}
+	q.mu.Unlock()
+}
+
+// nextTime 分配服务端时间，连续处理期间时钟回拨也不回退
+func (c *convSeq) nextTime(now int64) int64 {
+	if now < c.lastTime {
+		now = c.lastTime
+	}
+	c.lastTime = now
+	return now
+}
+
+// conversationKey 消息所属会话
+func conversationKey(msg *model.Message) string {
+	if msg.GroupID != 0 {
+		return "group:" + strconv.FormatInt(msg.GroupID, 10)
+	}
+	return repository.ConversationID(msg.FromUserID, msg.ToUserID)
+}
@@ -2,21 +2,29 @@ package core
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"google.golang.org/grpc"
 
+	"github.com/bbadbeef/go-base/idgen"
 	imgrpc "github.com/bbadbeef/go-base/im/internal/grpc"
 	"github.com/bbadbeef/go-base/im/internal/log"
 	"github.com/bbadbeef/go-base/im/internal/model"
 	"github.com/bbadbeef/go-base/im/internal/protocol"
 	"github.com/bbadbeef/go-base/im/internal/repository"
 	"github.com/bbadbeef/go-base/im/internal/util"
+	golog "github.com/bbadbeef/go-base/log"
+	"github.com/bbadbeef/go-base/migrate"
+	"github.com/bbadbeef/go-base/observability"
+	"github.com/bbadbeef/go-base/observability/gormmetrics"
 )
 
 // IMServer IM 服务器实现
@@ -35,14 +43,54 @@ type IMServer struct {
 	peerMutex   sync.RWMutex
 
 	// 数据访问
-	messageRepo *repository.MessageRepository
-	routeRepo   *repository.RouteRepository
-	sessionRepo *repository.SessionRepository
+	messageRepo  *repository.MessageRepository
+	routeRepo    *repository.RouteRepository
+	sessionRepo  *repository.SessionRepository
+	draftRepo    *repository.DraftRepository
+	dndRepo      *repository.DNDRepository
+	notifyRepo   *repository.NotificationRepository
+	groupRepo    *repository.GroupRepository
+	reportRepo   *repository.ReportRepository
+	awayRepo     *repository.AwayRepository
+	presenceRepo *repository.PresenceRepository
+	supportRepo  *repository.SupportRepository
 
-	// 回调函数
-	onMessageHandlers     []func(*model.Message)
-	onUserOnlineHandlers  []func(int64)
-	onUserOfflineHandlers []func(int64)
+	// 消息写入合并器，未开启合并写入时为 nil
+	batcher *writeBatcher
+
+	// 会话锁，保证同一会话内消息顺序
+	sequencer *convSequencer
+
+	// 群组慢速模式的成员发送时间
+	slowMode *slowModeTracker
+
+	// 离开状态的自动回复时间
+	autoReplies *autoReplyTracker
+
+	// 客服轮流分配：上次分配的客服 ID
+	supportLastAgent atomic.Int64
+
+	// 数据库迁移
+	migrator *migrate.Migrator
+
+	// 监控指标
+	metrics observability.Metrics
+
+	// 运行期可热更新的配置
+	tunables atomic.Pointer[Tunables]
+
+	// 启动时间戳（毫秒）
+	startedAt int64
+
+	// 优雅关闭：draining 后拒绝新连接，tasks 跟踪连接处理、离线推送和回调等后台任务
+	draining atomic.Bool
+	tasks    sync.WaitGroup
+
+	// 事件回调（OnEvent 及 OnMessage 等回调）
+	events *eventBus
+
+	// 消息订阅
+	subscriptions subscriptionSet
 
 	// 上下文
 	ctx    context.Context
@@ -55,33 +103,97 @@ func NewIMServer(config *Config) (*IMServer, error) {
 		config:      config,
 		hub:         NewHub(),
 		peerClients: make(map[string]imgrpc.IMServerClient),
+		sequencer:   newConvSequencer(),
+		slowMode:    newSlowModeTracker(),
+		autoReplies: newAutoReplyTracker(),
+		events:      newEventBus(),
+		metrics:     observability.OrNop(config.Metrics),
 	}
 
-	// 初始化数据访问层
-	s.messageRepo = repository.NewMessageRepository(config.DB)
-	s.routeRepo = repository.NewRouteRepository(config.DB)
-	s.sessionRepo = repository.NewSessionRepository(config.DB)
+	// SQL 耗时指标和慢查询回调
+	if config.Metrics != nil || config.SlowQueryFunc != nil {
+		queryMetrics := gormmetrics.Config{
+			Module:        "im",
+			Package:       "github.com/bbadbeef/go-base/im/",
+			Metrics:       config.Metrics,
+			SlowThreshold: config.SlowQueryThreshold,
+			OnSlowQuery:   config.SlowQueryFunc,
+		}
+		if err := gormmetrics.Register(config.DB, queryMetrics); err != nil {
+			return nil, err
+		}
+		if err := gormmetrics.Register(config.ReadDB, queryMetrics); err != nil {
+			return nil, err
+		}
+	}
 
-	// 自动创建表
-	if err := s.messageRepo.InitTables(); err != nil {
-		return nil, err
+	// 初始化数据访问层
+	namer := &repository.TableNamer{
+		Prefix:    config.TablePrefix,
+		Overrides: config.TableNames,
 	}
-	if err := s.routeRepo.InitTables(); err != nil {
-		return nil, err
+	s.messageRepo = repository.NewMessageRepository(config.DB, namer)
+	s.routeRepo = repository.NewRouteRepository(config.DB, namer)
+	s.routeRepo.SetPresenceTimeout(s.presenceTimeout())
+	s.sessionRepo = repository.NewSessionRepository(config.DB, namer)
+	s.draftRepo = repository.NewDraftRepository(config.DB, namer)
+	s.dndRepo = repository.NewDNDRepository(config.DB, namer)
+	s.notifyRepo = repository.NewNotificationRepository(config.DB, namer)
+	s.groupRepo = repository.NewGroupRepository(config.DB, namer)
+	s.reportRepo = repository.NewReportRepository(config.DB, namer)
+	s.awayRepo = repository.NewAwayRepository(config.DB, namer)
+	s.presenceRepo = repository.NewPresenceRepository(config.DB, namer)
+	s.supportRepo = repository.NewSupportRepository(config.DB, namer)
+	s.messageRepo.SetTenant(config.TenantID)
+	s.sessionRepo.SetTenant(config.TenantID)
+
+	// 读写分离：热点读查询走只读库
+	if config.ReadDB != nil {
+		s.messageRepo.SetReadDB(config.ReadDB)
+		s.sessionRepo.SetReadDB(config.ReadDB)
+		s.routeRepo.SetReadDB(config.ReadDB)
 	}
-	if err := s.sessionRepo.InitTables(); err != nil {
-		return nil, err
+
+	// 合并写入
+	if config.WriteBatchSize > 1 {
+		delay := config.WriteBatchDelay
+		if delay <= 0 {
+			delay = 5 * time.Millisecond
+		}
+		s.batcher = newWriteBatcher(s.messageRepo, config.WriteBatchSize, delay, s.metrics)
+	}
+
+	s.migrator = repository.NewMigrator(config.DB, namer)
+
+	// 自动执行数据库迁移
+	if !config.DisableAutoMigrate {
+		if err := s.migrator.Migrate(context.Background()); err != nil {
+			return nil, err
+		}
+	} else if pending, err := s.migrator.Pending(context.Background()); err == nil && pending > 0 {
+		log.Warnf("%d database migrations pending, call Migrate to apply", pending)
 	}
 
 	// 初始化路由管理器
 	s.routeManager = NewRouteManager(config.ServerID, s.routeRepo, config.CacheTTL)
 
+	// 初始运行期配置，之后可通过 UpdateTunables 热更新
+	if err := s.UpdateTunables(Tunables{
+		CacheTTL:       config.CacheTTL,
+		MaxMessageSize: config.MaxMessageSize,
+		RateLimit:      config.RateLimit,
+		BandwidthLimit: config.BandwidthLimit,
+	}); err != nil {
+		return nil, err
+	}
+
 	return s, nil
 }
 
 // Start 启动 IM 服务
 func (s *IMServer) Start(ctx context.Context) error {
 	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.startedAt = time.Now().UnixMilli()
 
 	// 1. 注册当前节点
 	if err := s.registerNode(); err != nil {
@@ -91,8 +203,9 @@ func (s *IMServer) Start(ctx context.Context) error {
 	// 2. 启动连接管理器
 	go s.hub.Run()
 
-	// 3. 启动心跳
+	// 3. 启动心跳和在线状态过期
 	go s.heartbeatWorker()
+	go s.presenceWorker()
 
 	// 4. 启动 gRPC Server（节点间通信）
 	if s.config.GRPCAddr != "" {
@@ -125,80 +238,244 @@ func (s *IMServer) Stop() error {
 		s.grpcServer.GracefulStop()
 	}
 
+	// 4. 写入合并器中剩余的消息
+	if s.batcher != nil {
+		s.batcher.Close()
+	}
+
 	log.Infof("Server stopped")
 	return nil
 }
 
+// Shutdown 优雅关闭 IM 服务：拒绝新连接，注销节点，通知所有连接节点即将关闭，
+// 发送完各连接缓冲的消息后断开，等待进行中的消息处理、离线推送和回调完成，最后关闭 gRPC
+// ctx 到期时不再等待，直接关闭并返回 ctx.Err()
+func (s *IMServer) Shutdown(ctx context.Context) error {
+	log.Infof("Server shutting down, draining %d connections", s.hub.Count())
+	s.draining.Store(true)
+	s.unregisterNode()
+
+	notice, _ := protocol.Encode(protocol.WSMsgTypeShutdown, "", time.Now().UnixMilli(),
+		&protocol.WSShutdown{Reason: "server shutting down"})
+	clients := s.hub.CloseAll(notice)
+	s.metrics.Set("im_connections", 0)
+
+	err := waitContext(ctx, func() {
+		for _, client := range clients {
+			<-client.Done()
+		}
+		s.tasks.Wait()
+	})
+	if err != nil {
+		log.Warnf("Shutdown timed out, closing with pending work: %v", err)
+	}
+
+	s.Stop()
+	return err
+}
+
+// waitContext 等待 wait 返回或 ctx 结束
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newMsgID 生成消息 ID，配置了 IDGenerator 时使用其生成的 ID
+func (s *IMServer) newMsgID() string {
+	if s.config.IDGenerator != nil {
+		return idgen.String(s.config.IDGenerator)
+	}
+	return util.GenerateMsgID()
+}
+
+// saveMessage 持久化消息，开启合并写入时等待所在批次写入，成功后触发 EventMessageSaved
+func (s *IMServer) saveMessage(ctx context.Context, msg *model.Message) error {
+	var err error
+	if s.batcher != nil {
+		err = s.batcher.Save(ctx, msg)
+	} else {
+		err = s.messageRepo.Save(ctx, msg)
+	}
+	if err == nil {
+		s.emit(&model.Event{Type: model.EventMessageSaved, Message: msg})
+	}
+	return err
+}
+
+// updateStatus 更新消息状态，开启合并写入时等待所在批次写入
+func (s *IMServer) updateStatus(ctx context.Context, msgID string, status int, updateTime int64) error {
+	if s.batcher != nil {
+		return s.batcher.UpdateStatus(ctx, msgID, status, updateTime)
+	}
+	return s.messageRepo.UpdateStatus(ctx, msgID, status, updateTime)
+}
+
+// goTask 在后台执行 fn，Shutdown 等待其完成，fn panic 时记录日志
+func (s *IMServer) goTask(fn func()) {
+	s.tasks.Add(1)
+	go func() {
+		defer s.tasks.Done()
+		defer s.recoverPanic("task")
+		fn()
+	}()
+}
+
 // WebSocketHandler 获取 WebSocket Handler
 func (s *IMServer) WebSocketHandler() http.HandlerFunc {
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
 			return true
 		},
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: s.config.EnableCompression,
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		// 1. 获取 Token
-		token := r.URL.Query().Get("token")
-		if token == "" {
-			http.Error(w, "Missing token", http.StatusUnauthorized)
+		// 关闭中不再接受新连接，客户端应重连到其他节点
+		if s.draining.Load() {
+			http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
 			return
 		}
 
-		// 2. 调用主应用的认证函数
-		userID, err := s.config.AuthFunc(token)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		// 1. 获取 Token，开启访客模式时不带 Token 的连接分配临时访客 ID
+		var userID int64
+		token := r.URL.Query().Get("token")
+		switch {
+		case token != "":
+			// 2. 调用主应用的认证函数
+			var err error
+			userID, err = s.config.AuthFunc(golog.EnsureRequestID(r.Context()), token, s.connMeta(r))
+			if err != nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+		case s.config.EnableGuest:
+			userID = newGuestID()
+		default:
+			http.Error(w, "Missing token", http.StatusUnauthorized)
 			return
 		}
 
-		// 3. 升级为 WebSocket
-		conn, err := upgrader.Upgrade(w, r, nil)
+		// 3. 升级为 WebSocket，配置了亲和 Cookie 时写入本节点标识，接管的连接统计线上字节数
+		traffic := &connTraffic{userID: userID, connectedAt: time.Now().UnixMilli()}
+		cw := &countingWriter{ResponseWriter: w, traffic: traffic, metrics: s.metrics}
+		conn, err := upgrader.Upgrade(cw, r, s.affinityHeader())
 		if err != nil {
 			log.Errorf("Failed to upgrade websocket: %v", err)
 			return
 		}
 
 		// 4. 处理连接
-		s.onUserConnect(userID, conn)
+		s.onUserConnect(userID, conn, traffic)
+	}
+}
+
+// connMeta 获取 WebSocket 升级请求的连接信息
+func (s *IMServer) connMeta(r *http.Request) model.ConnMeta {
+	deviceID := r.Header.Get("X-Device-ID")
+	if deviceID == "" {
+		deviceID = r.URL.Query().Get("device_id")
+	}
+	return model.ConnMeta{
+		RemoteIP:  s.clientIP(r),
+		UserAgent: r.UserAgent(),
+		DeviceID:  deviceID,
+	}
+}
+
+// clientIP 客户端 IP，开启 TrustProxy 时优先使用代理转发的地址
+func (s *IMServer) clientIP(r *http.Request) string {
+	if s.config.TrustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return strings.TrimSpace(ip)
+		}
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return ip
 }
 
 // SendMessage 发送消息（主动推送，如系统消息）
 func (s *IMServer) SendMessage(ctx context.Context, req *model.SendMessageRequest) error {
+	ctx = golog.EnsureRequestID(ctx)
+	if limit := s.tunables.Load().MaxMessageSize; limit > 0 && len(req.Content) > limit {
+		return model.NewParamError("content", fmt.Sprintf("content exceeds %d bytes", limit))
+	}
+	if err := s.checkFile(ctx, req.FromUserID, req.FileID); err != nil {
+		return err
+	}
+
 	msg := &model.Message{
-		MsgID:      util.GenerateMsgID(),
+		MsgID:      s.newMsgID(),
 		FromUserID: req.FromUserID,
 		ToUserID:   req.ToUserID,
 		GroupID:    req.GroupID,
 		Content:    req.Content,
 		MsgType:    req.MsgType,
+		FileID:     req.FileID,
 		Status:     model.MsgStatusSent,
-		ServerTime: time.Now().UnixMilli(),
+		AtUserIDs:  req.AtUserIDs,
+	}
+
+	// 客服模式：发给客服入口的消息改发给分配的客服
+	if err := s.routeSupport(ctx, msg); err != nil {
+		return err
 	}
 
+	// 同一会话串行处理，保证会话内顺序
+	key := conversationKey(msg)
+	conv := s.sequencer.acquire(key)
+	defer s.sequencer.release(key, conv)
+	msg.ServerTime = conv.nextTime(time.Now().UnixMilli())
+
 	// 1. 持久化
-	if err := s.messageRepo.Save(msg); err != nil {
+	if err := s.saveMessage(ctx, msg); err != nil {
 		return err
 	}
 
 	// 2. 更新会话
-	s.updateSession(msg)
+	s.updateSession(ctx, msg)
+	s.autoReply(ctx, msg)
 
 	// 3. 路由转发
-	return s.routeAndDeliver(msg)
+	return s.routeAndDeliver(ctx, msg)
 }
 
-// IsUserOnline 检查用户是否在线
+// IsUserOnline 检查用户是否在线（连接在集群任一节点），路由心跳超时的用户视为离线
+// 按连接判断，隐身用户也返回 true
 func (s *IMServer) IsUserOnline(userID int64) bool {
+	if s.hub.HasClient(userID) {
+		return true
+	}
+	gatewayID, _, online := s.routeManager.GetUserRoute(s.serverContext(), userID)
+	return online && gatewayID != s.config.ServerID
+}
+
+// IsUserOnlineLocal 检查用户是否连接在当前节点
+func (s *IMServer) IsUserOnlineLocal(userID int64) bool {
 	return s.hub.HasClient(userID)
 }
 
 // GetSessions 获取会话列表
 func (s *IMServer) GetSessions(ctx context.Context, userID int64) ([]*model.Session, error) {
-	return s.sessionRepo.GetUserSessions(userID)
+	return s.sessionRepo.GetUserSessions(ctx, userID)
 }
 
 // GetMessages 获取历史消息
@@ -206,112 +483,348 @@ func (s *IMServer) GetMessages(ctx context.Context, req *model.GetMessagesReques
 	if req.Limit == 0 {
 		req.Limit = 20
 	}
-	return s.messageRepo.GetMessages(req)
+	return s.messageRepo.GetMessages(ctx, req)
+}
+
+// GetSessionPage 分页获取会话列表
+func (s *IMServer) GetSessionPage(ctx context.Context, req *model.GetSessionsRequest) (*model.SessionPage, error) {
+	if req.Limit < 0 {
+		return nil, model.NewParamError("limit", "invalid limit")
+	}
+	return s.sessionRepo.GetSessionPage(ctx, req)
+}
+
+// GetMessagePage 分页获取历史消息
+func (s *IMServer) GetMessagePage(ctx context.Context, req *model.GetMessagesRequest) (*model.MessagePage, error) {
+	if req.Limit < 0 {
+		return nil, model.NewParamError("limit", "invalid limit")
+	}
+	return s.messageRepo.GetMessagePage(ctx, req)
 }
 
-// MarkAsRead 标记消息为已读
+// MarkAsRead 将发给 userID 的消息标记为已读，并推进对应会话的已读水位
 func (s *IMServer) MarkAsRead(ctx context.Context, userID int64, msgIDs []string) error {
+	messages, err := s.markStatus(ctx, userID, msgIDs, model.MsgStatusRead, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	s.advanceReadTime(ctx, userID, messages)
+	return nil
+}
+
+// MarkConversationRead 将与 targetID 的单聊中 upToTime（含）之前对方发来的消息全部标记为已读
+// 推进已读水位、重新计算未读数，并通知对方一次；upToTime <= 0 表示截至当前的全部消息
+func (s *IMServer) MarkConversationRead(ctx context.Context, userID, targetID, upToTime int64) error {
+	if targetID <= 0 {
+		return model.NewParamError("target_id", "invalid target_id")
+	}
 	readTime := time.Now().UnixMilli()
+	if upToTime <= 0 {
+		upToTime = readTime
+	}
+
+	updated, err := s.messageRepo.MarkConversationRead(ctx, userID, targetID, upToTime, readTime)
+	if err != nil {
+		log.Ctx(ctx).Warnf("Failed to mark conversation %d-%d read: %v", userID, targetID, err)
+		return err
+	}
+	if err := s.sessionRepo.MarkRead(ctx, userID, targetID, model.SessionTypeSingle, upToTime); err != nil {
+		return err
+	}
+
+	// 有消息变为已读时通知对方，一条消息代替逐条状态更新
+	if updated > 0 {
+		data, _ := protocol.Encode(protocol.WSMsgTypeConversationRead, "", readTime, &protocol.WSConversationRead{
+			TargetID: userID,
+			ReadTime: upToTime,
+		})
+		s.hub.SendToUser(targetID, data)
+	}
+	return nil
+}
+
+// markStatus 批量更新发给 userID 的消息状态（一条 UPDATE），只通知状态实际变化的消息的发送方，返回这些消息
+// 其他用户的消息和状态已不低于 status 的消息忽略，msgIDs 超过 MaxReceiptMsgIDs 时返回参数错误
+func (s *IMServer) markStatus(ctx context.Context, userID int64, msgIDs []string, status int, updateTime int64) ([]*model.Message, error) {
+	if len(msgIDs) == 0 {
+		return nil, nil
+	}
+	if len(msgIDs) > protocol.MaxReceiptMsgIDs {
+		return nil, model.NewParamError("msg_ids", fmt.Sprintf("at most %d msg_ids", protocol.MaxReceiptMsgIDs))
+	}
+
+	messages, err := s.messageRepo.UpdateRecipientStatus(ctx, userID, msgIDs, status, updateTime)
+	if err != nil {
+		log.Ctx(ctx).Warnf("Failed to update status of %d messages to %d: %v", len(msgIDs), status, err)
+		return nil, err
+	}
 
-	for _, msgID := range msgIDs {
-		// 更新消息状态
-		if err := s.messageRepo.UpdateStatus(msgID, model.MsgStatusRead, readTime); err != nil {
-			log.Warnf("Failed to mark message as read: %v", err)
+	// 通知发送方
+	s.notifyStatusUpdates(messages, status, updateTime)
+	return messages, nil
+}
+
+// advanceReadTime 按会话把已读水位推进到已读消息中最新的服务端时间，并重新计算未读数
+func (s *IMServer) advanceReadTime(ctx context.Context, userID int64, messages []*model.Message) {
+	readTimes := make(map[int64]int64)
+	for _, msg := range messages {
+		if msg.ToUserID != userID || msg.GroupID != 0 {
 			continue
 		}
+		if msg.ServerTime > readTimes[msg.FromUserID] {
+			readTimes[msg.FromUserID] = msg.ServerTime
+		}
+	}
 
-		// 查询消息的发送方
-		msg, err := s.messageRepo.GetByMsgID(msgID)
-		if err != nil {
-			continue
+	for targetID, readTime := range readTimes {
+		if err := s.sessionRepo.MarkRead(ctx, userID, targetID, model.SessionTypeSingle, readTime); err != nil {
+			log.Ctx(ctx).Warnf("Failed to advance read time of session %d-%d: %v", userID, targetID, err)
 		}
+	}
+}
+
+// SaveDraft 保存会话草稿，并同步到该用户的其他在线设备（包括连接在其他节点的设备）
+// Content 为空时清除草稿，草稿不存在时不推送
+func (s *IMServer) SaveDraft(ctx context.Context, draft *model.Draft) error {
+	return s.saveDraft(ctx, draft, nil)
+}
 
-		// 通知发送方
-		s.notifyStatusUpdate(msg.FromUserID, msgID, model.MsgStatusRead, readTime)
+// saveDraft 保存会话草稿，origin 为保存草稿的连接（不向其回推），nil 表示通过 API 保存
+func (s *IMServer) saveDraft(ctx context.Context, draft *model.Draft, origin *Client) error {
+	if draft.SessionType == 0 {
+		draft.SessionType = model.SessionTypeSingle
+	}
+	if draft.UpdatedAt == 0 {
+		draft.UpdatedAt = time.Now().UnixMilli()
+	}
+
+	if draft.Content == "" {
+		deleted, err := s.draftRepo.Delete(ctx, draft.UserID, draft.TargetID, draft.SessionType)
+		if err != nil || !deleted {
+			return err
+		}
+	} else if err := s.draftRepo.Save(ctx, draft); err != nil {
+		return err
 	}
 
+	s.notifyDraftSync(draft, origin)
 	return nil
 }
 
-// OnMessage 设置消息回调
-func (s *IMServer) OnMessage(handler func(*model.Message)) {
-	s.onMessageHandlers = append(s.onMessageHandlers, handler)
+// GetDrafts 获取用户的所有草稿
+func (s *IMServer) GetDrafts(ctx context.Context, userID int64) ([]*model.Draft, error) {
+	return s.draftRepo.GetUserDrafts(ctx, userID)
 }
 
-// OnUserOnline 设置用户上线回调
-func (s *IMServer) OnUserOnline(handler func(int64)) {
-	s.onUserOnlineHandlers = append(s.onUserOnlineHandlers, handler)
+// CanAccessFile 检查用户是否可以访问消息中的文件：发送或接收过引用该文件的消息，或是发送过该文件的群组成员
+// 消息中的文件引用由 FileCheckFunc 在发送时校验
+func (s *IMServer) CanAccessFile(ctx context.Context, userID int64, fileID string) (bool, error) {
+	ok, err := s.messageRepo.HasFileMessage(ctx, fileID, userID)
+	if err != nil || ok {
+		return ok, err
+	}
+	groupIDs, err := s.messageRepo.GetFileGroupIDs(ctx, fileID)
+	if err != nil {
+		return false, err
+	}
+	for _, groupID := range groupIDs {
+		if ok, err := s.groupRepo.IsMember(ctx, groupID, userID); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
 }
 
-// OnUserOffline 设置用户下线回调
-func (s *IMServer) OnUserOffline(handler func(int64)) {
-	s.onUserOfflineHandlers = append(s.onUserOfflineHandlers, handler)
+// checkFile 校验发送方可以在消息中引用该文件，未设置 FileCheckFunc 时不校验
+func (s *IMServer) checkFile(ctx context.Context, userID int64, fileID string) error {
+	if fileID == "" || s.config.FileCheckFunc == nil {
+		return nil
+	}
+	ok, err := s.config.FileCheckFunc(ctx, userID, fileID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.ErrFileAccessDenied
+	}
+	return nil
+}
+
+// FilesReferenced 检查文件是否被消息引用，返回被引用的文件
+func (s *IMServer) FilesReferenced(ctx context.Context, fileIDs []string) (map[string]bool, error) {
+	referenced, err := s.messageRepo.GetReferencedFileIDs(ctx, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(referenced))
+	for _, fileID := range referenced {
+		result[fileID] = true
+	}
+	return result, nil
+}
+
+// PurgeUserData 删除用户的 IM 数据：发送的消息、会话列表、草稿、免打扰和通知偏好、提交的举报、离开状态、在线状态、客服会话和客服身份、路由
+// 其他用户与该用户的会话和收到的消息保留
+func (s *IMServer) PurgeUserData(ctx context.Context, userID int64) error {
+	if err := s.messageRepo.DeleteByFromUser(ctx, userID); err != nil {
+		return fmt.Errorf("delete messages failed: %w", err)
+	}
+	if err := s.sessionRepo.DeleteByUser(ctx, userID); err != nil {
+		return fmt.Errorf("delete sessions failed: %w", err)
+	}
+	if err := s.draftRepo.DeleteByUser(ctx, userID); err != nil {
+		return fmt.Errorf("delete drafts failed: %w", err)
+	}
+	if err := s.dndRepo.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("delete dnd schedule failed: %w", err)
+	}
+	if err := s.notifyRepo.DeleteByUser(ctx, userID); err != nil {
+		return fmt.Errorf("delete notification preferences failed: %w", err)
+	}
+	if err := s.reportRepo.DeleteByReporter(ctx, userID); err != nil {
+		return fmt.Errorf("delete reports failed: %w", err)
+	}
+	if err := s.awayRepo.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("delete away status failed: %w", err)
+	}
+	if err := s.presenceRepo.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("delete presence failed: %w", err)
+	}
+	if err := s.supportRepo.DeleteByUser(ctx, userID); err != nil {
+		return fmt.Errorf("delete support data failed: %w", err)
+	}
+	return s.routeRepo.UnregisterUserRoute(ctx, userID)
+}
+
+// OnMessage 设置消息回调（EventMessageReceived），返回删除回调的函数
+func (s *IMServer) OnMessage(handler func(*model.Message), opts ...model.CallbackOptions) func() {
+	if handler == nil {
+		return func() {}
+	}
+	return s.events.on(model.EventMessageReceived, func(e *model.Event) { handler(e.Message) }, opts)
+}
+
+// OnUserOnline 设置用户上线回调（EventUserOnline），返回删除回调的函数
+func (s *IMServer) OnUserOnline(handler func(int64), opts ...model.CallbackOptions) func() {
+	if handler == nil {
+		return func() {}
+	}
+	return s.events.on(model.EventUserOnline, func(e *model.Event) { handler(e.UserID) }, opts)
+}
+
+// OnUserOffline 设置用户下线回调（EventUserOffline），返回删除回调的函数
+func (s *IMServer) OnUserOffline(handler func(int64), opts ...model.CallbackOptions) func() {
+	if handler == nil {
+		return func() {}
+	}
+	return s.events.on(model.EventUserOffline, func(e *model.Event) { handler(e.UserID) }, opts)
 }
 
 // ========== 内部实现方法 ==========
 
+// 服务运行期间的上下文（服务停止时取消），未启动时返回 context.Background()
+func (s *IMServer) serverContext() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return context.Background()
+}
+
 // 用户连接处理
-func (s *IMServer) onUserConnect(userID int64, conn *websocket.Conn) {
+func (s *IMServer) onUserConnect(userID int64, conn *websocket.Conn, traffic *connTraffic) {
 	log.Infof("User connected: %d", userID)
 
 	// 1. 注册到 Hub
-	client := s.hub.Register(userID, conn)
+	client := s.hub.Register(userID, conn, traffic)
+	s.metrics.Set("im_connections", float64(s.hub.Count()))
 
-	// 2. 更新路由表
-	s.routeManager.Register(userID, s.config.ServerID)
+	// 2. 更新路由表（覆盖其他节点上的旧路由），并告知客户端所在节点和亲和标识
+	s.routeManager.Register(s.serverContext(), userID, s.config.ServerID)
+	s.sendConnectInfo(userID)
 
 	// 3. 触发上线回调
-	for _, handler := range s.onUserOnlineHandlers {
-		go handler(userID)
-	}
+	s.emit(&model.Event{Type: model.EventUserOnline, UserID: userID})
 
 	// 4. 推送离线消息（如果有）
-	go s.pushOfflineMessages(userID)
+	s.goTask(func() { s.pushOfflineMessages(s.serverContext(), userID) })
 
 	// 5. 启动消息处理
-	go s.handleClientMessages(client)
+	s.goTask(func() { s.handleClientMessages(client) })
 }
 
 // 用户断开处理
-func (s *IMServer) onUserDisconnect(userID int64) {
+func (s *IMServer) onUserDisconnect(client *Client) {
+	userID := client.UserID
 	log.Infof("User disconnected: %d", userID)
 
-	// 1. 从 Hub 移除
-	s.hub.Unregister(userID)
+	// 1. 从 Hub 移除，用户已在本节点重新连接时旧连接断开不注销路由、不触发下线
+	last := s.hub.UnregisterClient(client)
+	s.metrics.Set("im_connections", float64(s.hub.Count()))
+	if !last {
+		return
+	}
 
-	// 2. 更新路由表
-	s.routeManager.Unregister(userID)
+	// 2. 更新路由表（服务停止时也需要清理，不随服务上下文取消）
+	// 已在其他节点重新连接或路由已因心跳超时删除时不再触发下线回调
+	released, err := s.routeManager.Unregister(context.Background(), userID)
+	if err != nil {
+		log.Warnf("Failed to unregister route of user %d: %v", userID, err)
+	}
 
 	// 3. 触发下线回调
-	for _, handler := range s.onUserOfflineHandlers {
-		go handler(userID)
+	if released || err != nil {
+		s.fireUserOffline(userID)
 	}
 }
 
-// 处理客户端消息
+// fireUserOffline 触发下线事件
+func (s *IMServer) fireUserOffline(userID int64) {
+	s.emit(&model.Event{Type: model.EventUserOffline, UserID: userID})
+}
+
+// 处理客户端消息（读协程），处理中 panic 时断开该连接，不影响其他连接
 func (s *IMServer) handleClientMessages(client *Client) {
-	defer s.onUserDisconnect(client.UserID)
+	defer s.recoverPanic("read_loop")
+	defer s.onUserDisconnect(client)
 
+	ctx := s.serverContext()
 	for {
 		var wsMsg protocol.WSMessage
 		if err := client.Conn.ReadJSON(&wsMsg); err != nil {
 			log.Debugf("Read error from user %d: %v", client.UserID, err)
 			break
 		}
+		// 被踢下线后连接最多保留 kickCloseTimeout 发送缓冲的消息，不再处理客户端发来的消息
+		if client.closed.Load() {
+			break
+		}
 
 		log.Debugf("Received message type: %s from user %d", wsMsg.Type, client.UserID)
 
+		if model.IsGuest(client.UserID) && !guestAllowed(wsMsg.Type) {
+			s.rejectGuest(client.UserID, wsMsg.MsgID)
+			continue
+		}
+
 		switch wsMsg.Type {
 		case protocol.WSMsgTypePing:
 			s.handlePing(client)
 		case protocol.WSMsgTypeChatMsg:
-			s.handleChatMessage(client.UserID, &wsMsg)
+			s.handleChatMessage(ctx, client, &wsMsg)
 		case protocol.WSMsgTypeGroupMsg:
-			s.handleGroupMessage(client.UserID, &wsMsg)
+			s.handleGroupMessage(ctx, client, &wsMsg)
+		case protocol.WSMsgTypeHello:
+			s.handleHello(client, &wsMsg)
 		case protocol.WSMsgTypeReadReceipt:
-			s.handleReadReceipt(client.UserID, &wsMsg)
+			s.handleReadReceipt(ctx, client, &wsMsg)
 		case protocol.WSMsgTypeDeliveredReceipt:
-			s.handleDeliveredReceipt(client.UserID, &wsMsg)
+			s.handleDeliveredReceipt(ctx, client, &wsMsg)
+		case protocol.WSMsgTypeDraft:
+			s.handleDraft(ctx, client, &wsMsg)
+		case protocol.WSMsgTypeConversationRead:
+			s.handleConversationRead(ctx, client.UserID, &wsMsg)
+		case protocol.WSMsgTypePresence:
+			s.handlePresence(ctx, client.UserID, &wsMsg)
 		default:
 			log.Warnf("Unknown message type: %s from user %d", wsMsg.Type, client.UserID)
 		}
@@ -320,36 +833,51 @@ func (s *IMServer) handleClientMessages(client *Client) {
 
 // 处理心跳
 func (s *IMServer) handlePing(client *Client) {
-	pong := &protocol.WSMessage{
-		Type:      protocol.WSMsgTypePong,
-		Timestamp: time.Now().UnixMilli(),
-	}
-	data, _ := json.Marshal(pong)
-	client.Send <- data
+	data, _ := protocol.Encode(protocol.WSMsgTypePong, "", time.Now().UnixMilli(), nil)
+	s.hub.SendToClient(client, data)
 }
 
 // 处理聊天消息
-func (s *IMServer) handleChatMessage(fromUserID int64, wsMsg *protocol.WSMessage) {
-	log.Debugf("handleChatMessage from user %d", fromUserID)
+func (s *IMServer) handleChatMessage(ctx context.Context, client *Client, wsMsg *protocol.WSMessage) {
+	fromUserID := client.UserID
+	// 每条消息一个请求 ID，关联持久化、路由和节点间转发的日志
+	ctx = golog.EnsureRequestID(ctx)
+	log.Ctx(ctx).Debugf("handleChatMessage from user %d", fromUserID)
 	
 	var chatMsg protocol.WSChatMessage
-	data, _ := json.Marshal(wsMsg.Data)
-	log.Debugf("Message data: %s", string(data))
-	
-	if err := json.Unmarshal(data, &chatMsg); err != nil {
-		log.Errorf("Invalid chat message from user %d: %v", fromUserID, err)
+	if err := wsMsg.DecodeData(&chatMsg); err != nil {
+		log.Ctx(ctx).Errorf("Invalid chat message from user %d: %v", fromUserID, err)
 		return
 	}
 
 	// 如果客户端没有提供 msg_id，服务器生成一个
 	if chatMsg.MsgID == "" {
-		chatMsg.MsgID = util.GenerateMsgID()
-		log.Debugf("Generated msg_id: %s", chatMsg.MsgID)
+		chatMsg.MsgID = s.newMsgID()
+		log.Ctx(ctx).Debugf("Generated msg_id: %s", chatMsg.MsgID)
+	}
+
+	// 访客只能向 GuestSendTo 中的用户发送
+	if model.IsGuest(fromUserID) && !s.guestCanSendTo(chatMsg.ToUserID) {
+		log.Ctx(ctx).Infof("Message %s from guest %d to user %d rejected", chatMsg.MsgID, fromUserID, chatMsg.ToUserID)
+		s.rejectGuest(fromUserID, chatMsg.MsgID)
+		return
+	}
+
+	// 大小和频率限制
+	if reason := s.checkLimits(client, chatMsg.Content); reason != "" {
+		log.Ctx(ctx).Warnf("Message %s from user %d rejected: %s", chatMsg.MsgID, fromUserID, reason)
+		s.sendAck(fromUserID, chatMsg.MsgID, model.MsgStatusFailed, reason)
+		return
 	}
 
-	log.Debugf("Chat message: msgID=%s, toUserID=%d", chatMsg.MsgID, chatMsg.ToUserID)
+	// 文件引用校验
+	if err := s.checkFile(ctx, fromUserID, chatMsg.FileID); err != nil {
+		log.Ctx(ctx).Warnf("Message %s from user %d rejected: %v", chatMsg.MsgID, fromUserID, err)
+		s.sendAck(fromUserID, chatMsg.MsgID, model.MsgStatusFailed, err.Error())
+		return
+	}
 
-	serverTime := time.Now().UnixMilli()
+	log.Ctx(ctx).Debugf("Chat message: msgID=%s, toUserID=%d", chatMsg.MsgID, chatMsg.ToUserID)
 
 	// 创建消息
 	msg := &model.Message{
@@ -361,204 +889,553 @@ func (s *IMServer) handleChatMessage(fromUserID int64, wsMsg *protocol.WSMessage
 		FileID:     chatMsg.FileID,
 		Status:     model.MsgStatusSent,
 		ClientTime: chatMsg.ClientTime,
-		ServerTime: serverTime,
+		AtUserIDs:  chatMsg.AtUserIDs,
+	}
+
+	// 客服模式：发给客服入口的消息改发给分配的客服
+	if err := s.routeSupport(ctx, msg); err != nil {
+		log.Ctx(ctx).Errorf("Failed to route support message %s: %v", msg.MsgID, err)
+		s.sendAck(fromUserID, chatMsg.MsgID, model.MsgStatusFailed, err.Error())
+		return
 	}
 
+	// 同一会话串行处理，保证会话内顺序
+	key := conversationKey(msg)
+	conv := s.sequencer.acquire(key)
+	defer s.sequencer.release(key, conv)
+	msg.ServerTime = conv.nextTime(time.Now().UnixMilli())
+
 	// 1. 持久化
-	if err := s.messageRepo.Save(msg); err != nil {
-		log.Errorf("Failed to save message %s: %v", msg.MsgID, err)
+	err := s.saveMessage(ctx, msg)
+	s.metrics.Add("im_messages_total", 1, observability.L("result", observability.Result(err)))
+	if err != nil {
+		log.Ctx(ctx).Errorf("Failed to save message %s: %v", msg.MsgID, err)
 		s.sendAck(fromUserID, chatMsg.MsgID, model.MsgStatusFailed, err.Error())
 		return
 	}
 
-	log.Infof("Message saved: %s (%d -> %d)", msg.MsgID, msg.FromUserID, msg.ToUserID)
+	log.Ctx(ctx).Infof("Message saved: %s (%d -> %d)", msg.MsgID, msg.FromUserID, msg.ToUserID)
 
 	// 2. 发送 ACK
 	s.sendAck(fromUserID, chatMsg.MsgID, model.MsgStatusSent, "")
 
 	// 3. 更新会话
-	s.updateSession(msg)
+	s.updateSession(ctx, msg)
+	s.clearDraft(ctx, fromUserID, msg.ToUserID, model.SessionTypeSingle)
 
-	// 4. 触发回调
-	for _, handler := range s.onMessageHandlers {
-		go handler(msg)
-	}
+	// 4. 触发回调和自动回复
+	s.fireMessage(msg)
+	s.autoReply(ctx, msg)
 
 	// 5. 路由转发
-	s.routeAndDeliver(msg)
+	s.routeAndDeliver(ctx, msg)
 }
 
-// 处理群聊消息
-func (s *IMServer) handleGroupMessage(fromUserID int64, wsMsg *protocol.WSMessage) {
-	// TODO: 实现群聊消息处理
-	log.Warnf("Group message not implemented yet")
+// 处理群聊消息：检查群成员和群组策略，被拒绝时回复带 code 的失败 ACK
+func (s *IMServer) handleGroupMessage(ctx context.Context, client *Client, wsMsg *protocol.WSMessage) {
+	fromUserID := client.UserID
+	ctx = golog.EnsureRequestID(ctx)
+
+	var groupMsg protocol.WSGroupMessage
+	if err := wsMsg.DecodeData(&groupMsg); err != nil {
+		log.Ctx(ctx).Errorf("Invalid group message from user %d: %v", fromUserID, err)
+		return
+	}
+	if groupMsg.MsgID == "" {
+		groupMsg.MsgID = s.newMsgID()
+	}
+
+	// 大小和频率限制
+	if reason := s.checkLimits(client, groupMsg.Content); reason != "" {
+		log.Ctx(ctx).Warnf("Message %s from user %d rejected: %s", groupMsg.MsgID, fromUserID, reason)
+		s.sendAck(fromUserID, groupMsg.MsgID, model.MsgStatusFailed, reason)
+		return
+	}
+
+	// 文件引用校验
+	if err := s.checkFile(ctx, fromUserID, groupMsg.FileID); err != nil {
+		log.Ctx(ctx).Warnf("Message %s from user %d rejected: %v", groupMsg.MsgID, fromUserID, err)
+		s.sendAck(fromUserID, groupMsg.MsgID, model.MsgStatusFailed, err.Error())
+		return
+	}
+
+	// 群成员和群组策略
+	if err := s.CheckGroupMessage(ctx, groupMsg.GroupID, fromUserID, groupMsg.Content, groupMsg.MsgType); err != nil {
+		log.Ctx(ctx).Infof("Group message %s from user %d to group %d rejected: %v", groupMsg.MsgID, fromUserID, groupMsg.GroupID, err)
+		s.sendRejectAck(fromUserID, groupMsg.MsgID, err)
+		return
+	}
+
+	msg := &model.Message{
+		MsgID:      groupMsg.MsgID,
+		FromUserID: fromUserID,
+		GroupID:    groupMsg.GroupID,
+		Content:    groupMsg.Content,
+		MsgType:    groupMsg.MsgType,
+		FileID:     groupMsg.FileID,
+		Status:     model.MsgStatusSent,
+		ClientTime: groupMsg.ClientTime,
+		AtUserIDs:  groupMsg.AtUserIDs,
+	}
+
+	// 同一群组串行处理，保证群内顺序
+	key := conversationKey(msg)
+	conv := s.sequencer.acquire(key)
+	defer s.sequencer.release(key, conv)
+	msg.ServerTime = conv.nextTime(time.Now().UnixMilli())
+
+	// 1. 持久化
+	err := s.saveMessage(ctx, msg)
+	s.metrics.Add("im_messages_total", 1, observability.L("result", observability.Result(err)))
+	if err != nil {
+		log.Ctx(ctx).Errorf("Failed to save message %s: %v", msg.MsgID, err)
+		s.sendAck(fromUserID, msg.MsgID, model.MsgStatusFailed, err.Error())
+		return
+	}
+
+	// 2. 发送 ACK
+	s.sendAck(fromUserID, msg.MsgID, model.MsgStatusSent, "")
+	s.clearDraft(ctx, fromUserID, msg.GroupID, model.SessionTypeGroup)
+
+	// 3. 触发回调
+	s.fireMessage(msg)
+
+	// 4. 投递给群成员
+	s.routeAndDeliver(ctx, msg)
 }
 
 // 处理已读回执
-func (s *IMServer) handleReadReceipt(userID int64, wsMsg *protocol.WSMessage) {
+func (s *IMServer) handleReadReceipt(ctx context.Context, client *Client, wsMsg *protocol.WSMessage) {
 	var receipt protocol.WSReceipt
-	data, _ := json.Marshal(wsMsg.Data)
-	if err := json.Unmarshal(data, &receipt); err != nil {
+	if err := wsMsg.DecodeData(&receipt); err != nil {
 		return
 	}
 
-	s.MarkAsRead(context.Background(), userID, []string{receipt.MsgID})
+	s.MarkAsRead(ctx, client.UserID, receiptMsgIDs(client, &receipt))
+}
+
+// 处理会话已读
+func (s *IMServer) handleConversationRead(ctx context.Context, userID int64, wsMsg *protocol.WSMessage) {
+	var read protocol.WSConversationRead
+	if err := wsMsg.DecodeData(&read); err != nil {
+		log.Errorf("Invalid conversation read from user %d: %v", userID, err)
+		return
+	}
+	s.MarkConversationRead(ctx, userID, read.TargetID, read.ReadTime)
 }
 
 // 处理送达回执
-func (s *IMServer) handleDeliveredReceipt(userID int64, wsMsg *protocol.WSMessage) {
+func (s *IMServer) handleDeliveredReceipt(ctx context.Context, client *Client, wsMsg *protocol.WSMessage) {
 	var receipt protocol.WSReceipt
-	data, _ := json.Marshal(wsMsg.Data)
-	if err := json.Unmarshal(data, &receipt); err != nil {
+	if err := wsMsg.DecodeData(&receipt); err != nil {
 		return
 	}
 
-	deliveredTime := time.Now().UnixMilli()
+	s.markStatus(ctx, client.UserID, receiptMsgIDs(client, &receipt), model.MsgStatusDelivered, time.Now().UnixMilli())
+}
+
+// 获取回执中的消息 ID（仅协商了批量回执能力的客户端可使用 msg_ids）
+func receiptMsgIDs(client *Client, receipt *protocol.WSReceipt) []string {
+	if client.HasCapability(protocol.CapBatchReceipts) {
+		return receipt.AllMsgIDs()
+	}
+	return []string{receipt.MsgID}
+}
 
-	// 更新消息状态
-	if err := s.messageRepo.UpdateStatus(receipt.MsgID, model.MsgStatusDelivered, deliveredTime); err != nil {
+// 处理草稿保存
+func (s *IMServer) handleDraft(ctx context.Context, client *Client, wsMsg *protocol.WSMessage) {
+	userID := client.UserID
+	var wsDraft protocol.WSDraft
+	if err := wsMsg.DecodeData(&wsDraft); err != nil {
+		log.Errorf("Invalid draft from user %d: %v", userID, err)
 		return
 	}
 
-	// 查询消息的发送方
-	msg, err := s.messageRepo.GetByMsgID(receipt.MsgID)
+	draft := &model.Draft{
+		UserID:      userID,
+		TargetID:    wsDraft.TargetID,
+		SessionType: wsDraft.SessionType,
+		Content:     wsDraft.Content,
+		UpdatedAt:   wsDraft.UpdatedAt,
+	}
+	if err := s.saveDraft(ctx, draft, client); err != nil {
+		log.Errorf("Failed to save draft for user %d: %v", userID, err)
+	}
+}
+
+// 清除草稿（消息发出后），没有草稿时不推送
+func (s *IMServer) clearDraft(ctx context.Context, userID, targetID int64, sessionType int) {
+	deleted, err := s.draftRepo.Delete(ctx, userID, targetID, sessionType)
 	if err != nil {
+		log.Warnf("Failed to clear draft for user %d: %v", userID, err)
+		return
+	}
+	if !deleted {
 		return
 	}
 
-	// 通知发送方
-	s.notifyStatusUpdate(msg.FromUserID, receipt.MsgID, model.MsgStatusDelivered, deliveredTime)
+	s.notifyDraftSync(&model.Draft{
+		UserID:      userID,
+		TargetID:    targetID,
+		SessionType: sessionType,
+		UpdatedAt:   time.Now().UnixMilli(),
+	}, nil)
 }
 
-// 发送 ACK
-func (s *IMServer) sendAck(userID int64, msgID string, status int, errMsg string) {
-	ack := &protocol.WSMessage{
-		Type:      protocol.WSMsgTypeAck,
-		MsgID:     msgID,
-		Timestamp: time.Now().UnixMilli(),
-		Data: &protocol.WSAckMessage{
-			MsgID:      msgID,
-			Status:     status,
-			ServerTime: time.Now().UnixMilli(),
-			Error:      errMsg,
-		},
+// 通知草稿同步
+// 每个用户在每个节点只保留一个连接，其他设备可能连接在任意节点，
+// 因此推送给本节点的连接（origin 除外）并转发给其他所有在线节点
+func (s *IMServer) notifyDraftSync(draft *model.Draft, origin *Client) {
+	if client, ok := s.hub.GetClient(draft.UserID); ok && client != origin {
+		s.hub.SendToClient(client, encodeDraftSync(draft))
+	}
+	if s.config.GRPCAddr == "" {
+		return
+	}
+	snapshot := *draft
+	s.goTask(func() { s.forwardDraftSync(s.serverContext(), &snapshot) })
+}
+
+// draftSyncTimeout 转发草稿同步到单个节点的超时
+const draftSyncTimeout = 2 * time.Second
+
+// forwardDraftSync 将草稿同步转发给其他在线节点，由各节点推送给用户连接在该节点的设备
+func (s *IMServer) forwardDraftSync(ctx context.Context, draft *model.Draft) {
+	servers, err := s.routeRepo.GetActiveServers(ctx)
+	if err != nil {
+		log.Ctx(ctx).Warnf("Failed to list servers for draft sync of user %d: %v", draft.UserID, err)
+		return
 	}
 
-	data, _ := json.Marshal(ack)
+	req := &imgrpc.SyncDraftRequest{
+		UserID:      draft.UserID,
+		TargetID:    draft.TargetID,
+		SessionType: int32(draft.SessionType),
+		Content:     draft.Content,
+		UpdatedAt:   draft.UpdatedAt,
+	}
+	for _, server := range servers {
+		if server.ServerID == s.config.ServerID {
+			continue
+		}
+		client, err := s.peerClient(ctx, server.GRPCAddr)
+		if err != nil {
+			continue
+		}
+		rpcCtx, cancel := context.WithTimeout(ctx, draftSyncTimeout)
+		if _, err := client.SyncDraft(imgrpc.OutgoingContext(rpcCtx), req); err != nil {
+			log.Ctx(ctx).Warnf("Failed to sync draft of user %d to %s: %v", draft.UserID, server.ServerID, err)
+		}
+		cancel()
+	}
+}
+
+// SyncDraft gRPC 服务端实现（其他节点转发的草稿同步）
+func (s *IMServer) SyncDraft(ctx context.Context, req *imgrpc.SyncDraftRequest) (*imgrpc.SyncDraftResponse, error) {
+	data := encodeDraftSync(&model.Draft{
+		UserID:      req.UserID,
+		TargetID:    req.TargetID,
+		SessionType: int(req.SessionType),
+		Content:     req.Content,
+		UpdatedAt:   req.UpdatedAt,
+	})
+	return &imgrpc.SyncDraftResponse{Delivered: s.hub.SendToUser(req.UserID, data)}, nil
+}
+
+// encodeDraftSync 编码草稿同步帧
+func encodeDraftSync(draft *model.Draft) []byte {
+	data, _ := protocol.Encode(protocol.WSMsgTypeDraftSync, "", draft.UpdatedAt, &protocol.WSDraft{
+		TargetID:    draft.TargetID,
+		SessionType: draft.SessionType,
+		Content:     draft.Content,
+		UpdatedAt:   draft.UpdatedAt,
+	})
+	return data
+}
+
+// 发送 ACK
+func (s *IMServer) sendAck(userID int64, msgID string, status int, errMsg string) {
+	now := time.Now().UnixMilli()
+	data := protocol.EncodeAck(&protocol.WSAckMessage{
+		MsgID:      msgID,
+		Status:     status,
+		ServerTime: now,
+		Error:      errMsg,
+	}, now)
 	s.hub.SendToUser(userID, data)
 }
 
+// 回复群组消息被拒绝的 ACK，code 为拒绝原因（群组策略原因或 not_group_member）
+func (s *IMServer) sendRejectAck(userID int64, msgID string, err error) {
+	ack := &protocol.WSAckMessage{MsgID: msgID, Status: model.MsgStatusFailed, Error: err.Error()}
+	var policyErr *model.GroupPolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		ack.Code = policyErr.Reason
+		ack.RetryAfter = policyErr.RetryAfter
+		s.metrics.Add("im_messages_rejected_total", 1, observability.L("reason", policyErr.Reason))
+	case errors.Is(err, model.ErrNotGroupMember), errors.Is(err, model.ErrGroupNotFound):
+		ack.Code = model.GroupRejectNotMember
+	}
+	ack.ServerTime = time.Now().UnixMilli()
+	s.hub.SendToUser(userID, protocol.EncodeAck(ack, ack.ServerTime))
+}
+
 // 通知状态更新
 func (s *IMServer) notifyStatusUpdate(userID int64, msgID string, status int, updateTime int64) {
-	update := &protocol.WSMessage{
-		Type:      protocol.WSMsgTypeStatusUpdate,
-		MsgID:     msgID,
-		Timestamp: updateTime,
-		Data: &protocol.WSStatusUpdate{
-			MsgID:      msgID,
+	data := protocol.EncodeStatusUpdate(&protocol.WSStatusUpdate{
+		MsgID:      msgID,
+		Status:     status,
+		UpdateTime: updateTime,
+	})
+	s.hub.SendToUser(userID, data)
+}
+
+// notifyStatusUpdates 按发送方合并通知状态更新
+// 协商了批量回执能力的发送方每人收到一条带 msg_ids 的状态更新，其他发送方逐条通知
+func (s *IMServer) notifyStatusUpdates(messages []*model.Message, status int, updateTime int64) {
+	bySender := make(map[int64][]string)
+	var senders []int64
+	for _, msg := range messages {
+		if _, ok := bySender[msg.FromUserID]; !ok {
+			senders = append(senders, msg.FromUserID)
+		}
+		bySender[msg.FromUserID] = append(bySender[msg.FromUserID], msg.MsgID)
+	}
+
+	for _, userID := range senders {
+		msgIDs := bySender[userID]
+		client, online := s.hub.GetClient(userID)
+		if !online {
+			continue
+		}
+		if len(msgIDs) == 1 || !client.HasCapability(protocol.CapBatchReceipts) {
+			for _, msgID := range msgIDs {
+				s.notifyStatusUpdate(userID, msgID, status, updateTime)
+			}
+			continue
+		}
+
+		data := protocol.EncodeStatusUpdate(&protocol.WSStatusUpdate{
+			MsgIDs:     msgIDs,
 			Status:     status,
 			UpdateTime: updateTime,
-		},
+		})
+		s.hub.SendToUser(userID, data)
 	}
-
-	data, _ := json.Marshal(update)
-	s.hub.SendToUser(userID, data)
 }
 
 // 路由并投递消息（核心转发逻辑）
-func (s *IMServer) routeAndDeliver(msg *model.Message) error {
+func (s *IMServer) routeAndDeliver(ctx context.Context, msg *model.Message) error {
+	if msg.GroupID != 0 {
+		return s.deliverGroupMessage(ctx, msg)
+	}
+
 	// 查询接收方路由
-	gatewayID, gatewayAddr, online := s.routeManager.GetUserRoute(msg.ToUserID)
+	gatewayID, gatewayAddr, online := s.routeManager.GetUserRoute(ctx, msg.ToUserID)
 
 	if !online {
-		log.Debugf("User %d offline, message saved", msg.ToUserID)
+		log.Ctx(ctx).Debugf("User %d offline, message saved", msg.ToUserID)
+		s.metrics.Add("im_deliveries_total", 1, observability.L("route", "offline"))
+		s.triggerOfflinePush(ctx, msg)
 		return nil
 	}
 
 	if gatewayID == s.config.ServerID {
 		// 本地推送
-		log.Debugf("Delivering message locally to user %d", msg.ToUserID)
-		s.pushToLocalUser(msg)
+		log.Ctx(ctx).Debugf("Delivering message locally to user %d", msg.ToUserID)
+		s.metrics.Add("im_deliveries_total", 1, observability.L("route", "local"))
+		s.pushToLocalUser(ctx, msg)
 	} else {
 		// 远程转发到其他节点
-		log.Debugf("Forwarding message to remote gateway %s", gatewayID)
-		s.forwardToRemoteGateway(gatewayAddr, msg)
+		log.Ctx(ctx).Debugf("Forwarding message to remote gateway %s", gatewayID)
+		s.metrics.Add("im_deliveries_total", 1, observability.L("route", "remote"))
+		s.forwardToRemoteGateway(ctx, gatewayAddr, msg)
 	}
 
 	return nil
 }
 
-// 本地推送
-func (s *IMServer) pushToLocalUser(msg *model.Message) {
-	pushMsg := &protocol.WSMessage{
-		Type:      protocol.WSMsgTypeChatMsg,
-		MsgID:     msg.MsgID,
-		Timestamp: msg.ServerTime,
-		Data: &protocol.WSPushMessage{
-			MsgID:      msg.MsgID,
-			FromUserID: msg.FromUserID,
-			Content:    msg.Content,
-			MsgType:    msg.MsgType,
-			FileID:     msg.FileID,
-			Status:     msg.Status,
-			ClientTime: msg.ClientTime,
-			ServerTime: msg.ServerTime,
-		},
+// deliverGroupMessage 投递群消息给发送者以外的群成员：本节点成员一次广播，其他节点的成员按节点合并转发
+// 离线成员在后台触发离线推送，不占用会话的串行处理；群消息只有一个状态，不按成员更新为已送达
+func (s *IMServer) deliverGroupMessage(ctx context.Context, msg *model.Message) error {
+	members, err := s.groupRepo.GetMembers(ctx, msg.GroupID)
+	if err != nil {
+		log.Ctx(ctx).Errorf("Failed to get members of group %d for message %s: %v", msg.GroupID, msg.MsgID, err)
+		return err
+	}
+
+	var local, offline []int64
+	remote := make(map[string][]int64)
+	for _, member := range members {
+		if member.UserID == msg.FromUserID {
+			continue
+		}
+		gatewayID, gatewayAddr, online := s.routeManager.GetUserRoute(ctx, member.UserID)
+		switch {
+		case !online:
+			offline = append(offline, member.UserID)
+		case gatewayID == s.config.ServerID:
+			local = append(local, member.UserID)
+		default:
+			remote[gatewayAddr] = append(remote[gatewayAddr], member.UserID)
+		}
+	}
+
+	if len(local) > 0 {
+		s.metrics.Add("im_deliveries_total", float64(len(local)), observability.L("route", "local"))
+		s.hub.SendToUsers(local, encodePush(msg))
+	}
+	for addr, userIDs := range remote {
+		s.metrics.Add("im_deliveries_total", float64(len(userIDs)), observability.L("route", "remote"))
+		s.forwardGroupToRemoteGateway(ctx, addr, msg, userIDs)
+	}
+	if len(offline) > 0 {
+		s.metrics.Add("im_deliveries_total", float64(len(offline)), observability.L("route", "offline"))
+		s.pushGroupOffline(msg, offline)
+	}
+	log.Ctx(ctx).Debugf("Group message %s delivered: %d local, %d remote nodes, %d offline", msg.MsgID, len(local), len(remote), len(offline))
+	return nil
+}
+
+// pushGroupOffline 在后台逐个触发离线群成员的离线推送，按成员判断免打扰和通知偏好
+func (s *IMServer) pushGroupOffline(msg *model.Message, userIDs []int64) {
+	if s.config.OfflinePushFunc == nil {
+		return
+	}
+	snapshot := *msg
+	s.goTask(func() {
+		ctx := s.serverContext()
+		for _, userID := range userIDs {
+			// ToUserID 为该成员
+			pushMsg := snapshot
+			pushMsg.ToUserID = userID
+			s.triggerOfflinePush(ctx, &pushMsg)
+		}
+	})
+}
+
+// 触发离线推送（免打扰期间或通知偏好关闭时跳过）
+func (s *IMServer) triggerOfflinePush(ctx context.Context, msg *model.Message) {
+	if s.config.OfflinePushFunc == nil {
+		return
+	}
+
+	if s.isInDND(ctx, msg.ToUserID, time.Now()) {
+		log.Ctx(ctx).Debugf("User %d in DND, offline push suppressed for message %s", msg.ToUserID, msg.MsgID)
+		return
+	}
+
+	if !s.ShouldNotify(ctx, msg.ToUserID, msg) {
+		log.Ctx(ctx).Debugf("Notification disabled by user %d, offline push suppressed for message %s", msg.ToUserID, msg.MsgID)
+		return
 	}
 
-	data, _ := json.Marshal(pushMsg)
+	s.goCallback("OfflinePushFunc", func() { s.config.OfflinePushFunc(msg) })
+}
+
+// encodePush 编码推送给接收方的消息帧
+func encodePush(msg *model.Message) []byte {
+	return protocol.EncodePush(&protocol.WSPushMessage{
+		MsgID:      msg.MsgID,
+		FromUserID: msg.FromUserID,
+		Content:    msg.Content,
+		MsgType:    msg.MsgType,
+		FileID:     msg.FileID,
+		Status:     msg.Status,
+		ClientTime: msg.ClientTime,
+		ServerTime: msg.ServerTime,
+		GroupID:    msg.GroupID,
+	})
+}
+
+// 本地推送
+func (s *IMServer) pushToLocalUser(ctx context.Context, msg *model.Message) {
+	data := encodePush(msg)
 	delivered := s.hub.SendToUser(msg.ToUserID, data)
 
 	if delivered {
 		// 自动更新为已送达
 		deliveredTime := time.Now().UnixMilli()
-		s.messageRepo.UpdateStatus(msg.MsgID, model.MsgStatusDelivered, deliveredTime)
+		s.updateStatus(ctx, msg.MsgID, model.MsgStatusDelivered, deliveredTime)
 		s.notifyStatusUpdate(msg.FromUserID, msg.MsgID, model.MsgStatusDelivered, deliveredTime)
-		log.Debugf("Message %s delivered to user %d", msg.MsgID, msg.ToUserID)
+		s.emit(&model.Event{Type: model.EventMessageDelivered, Time: deliveredTime, UserID: msg.ToUserID, Message: msg})
+		log.Ctx(ctx).Debugf("Message %s delivered to user %d", msg.MsgID, msg.ToUserID)
 	} else {
-		log.Warnf("Failed to deliver message %s to user %d", msg.MsgID, msg.ToUserID)
+		log.Ctx(ctx).Warnf("Failed to deliver message %s to user %d", msg.MsgID, msg.ToUserID)
 	}
 }
 
-// 远程转发（节点间通信）
-func (s *IMServer) forwardToRemoteGateway(addr string, msg *model.Message) {
+// errPeerUnavailable 节点间 gRPC 客户端不可用
+var errPeerUnavailable = errors.New("peer client unavailable")
+
+// peerClient 获取节点的 gRPC 客户端，没有时建立连接
+func (s *IMServer) peerClient(ctx context.Context, addr string) (imgrpc.IMServerClient, error) {
 	s.peerMutex.RLock()
 	client, exists := s.peerClients[addr]
 	s.peerMutex.RUnlock()
 
 	if !exists {
-		log.Debugf("No peer client for %s, attempting to connect", addr)
+		log.Ctx(ctx).Debugf("No peer client for %s, attempting to connect", addr)
 		// 尝试建立连接
-		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		conn, err := grpc.Dial(addr, s.dialOptions()...)
 		if err != nil {
-			log.Errorf("Failed to connect to peer %s: %v", addr, err)
-			return
+			log.Ctx(ctx).Errorf("Failed to connect to peer %s: %v", addr, err)
+			return nil, err
 		}
 		client = imgrpc.NewIMServerClient(conn)
 		s.peerMutex.Lock()
 		s.peerClients[addr] = client
 		s.peerMutex.Unlock()
 	}
+	if client == nil {
+		return nil, errPeerUnavailable
+	}
+	return client, nil
+}
+
+// 远程转发（节点间通信）
+func (s *IMServer) forwardToRemoteGateway(ctx context.Context, addr string, msg *model.Message) {
+	client, err := s.peerClient(ctx, addr)
+	if err != nil {
+		log.Ctx(ctx).Errorf("Failed to forward message %s: %v", msg.MsgID, err)
+		return
+	}
 
 	// 转发消息
 	req := imgrpc.MessageToForwardRequest(msg)
-	resp, err := client.ForwardMessage(context.Background(), req)
+	resp, err := client.ForwardMessage(imgrpc.OutgoingContext(ctx), req)
 	if err != nil {
-		log.Errorf("Failed to forward message: %v", err)
+		log.Ctx(ctx).Errorf("Failed to forward message: %v", err)
 		return
 	}
 
 	if resp.Delivered {
-		log.Debugf("Message %s forwarded successfully", msg.MsgID)
+		log.Ctx(ctx).Debugf("Message %s forwarded successfully", msg.MsgID)
 	} else {
-		log.Errorf("Message %s forward failed: %s", msg.MsgID, resp.Error)
+		log.Ctx(ctx).Errorf("Message %s forward failed: %s", msg.MsgID, resp.Error)
+	}
+}
+
+// forwardGroupToRemoteGateway 转发群消息给连接在其他节点的群成员，每个节点一次请求
+func (s *IMServer) forwardGroupToRemoteGateway(ctx context.Context, addr string, msg *model.Message, userIDs []int64) {
+	client, err := s.peerClient(ctx, addr)
+	if err != nil {
+		log.Ctx(ctx).Errorf("Failed to forward group message %s: %v", msg.MsgID, err)
+		return
+	}
+
+	req := imgrpc.MessageToForwardRequest(msg)
+	req.ToUserIDs = userIDs
+	if _, err := client.ForwardMessage(imgrpc.OutgoingContext(ctx), req); err != nil {
+		log.Ctx(ctx).Errorf("Failed to forward group message %s to %s: %v", msg.MsgID, addr, err)
 	}
 }
 
 // 推送离线消息
-func (s *IMServer) pushOfflineMessages(userID int64) {
+func (s *IMServer) pushOfflineMessages(ctx context.Context, userID int64) {
 	// 1. 查询该用户的未送达消息
-	messages, err := s.messageRepo.GetUndeliveredMessages(userID, 100)
+	messages, err := s.messageRepo.GetUndeliveredMessages(ctx, userID, 100)
 	if err != nil {
 		log.Errorf("Failed to get offline messages for user %d: %v", userID, err)
 		return
@@ -571,78 +1448,74 @@ func (s *IMServer) pushOfflineMessages(userID int64) {
 
 	log.Infof("Pushing %d offline messages to user %d", len(messages), userID)
 
-	// 2. 批量推送
+	// 2. 批量推送，推送成功的消息统一标记为已送达
+	var delivered []*model.Message
 	for _, msg := range messages {
-		pushMsg := &protocol.WSMessage{
-			Type:      protocol.WSMsgTypeChatMsg,
-			MsgID:     msg.MsgID,
-			Timestamp: msg.ServerTime,
-			Data: &protocol.WSPushMessage{
-				MsgID:      msg.MsgID,
-				FromUserID: msg.FromUserID,
-				Content:    msg.Content,
-				MsgType:    msg.MsgType,
-				FileID:     msg.FileID,
-				Status:     msg.Status,
-				ClientTime: msg.ClientTime,
-				ServerTime: msg.ServerTime,
-			},
-		}
-
-		data, _ := json.Marshal(pushMsg)
-		delivered := s.hub.SendToUser(userID, data)
-
-		if delivered {
-			// 更新为已送达
-			deliveredTime := time.Now().UnixMilli()
-			s.messageRepo.UpdateStatus(msg.MsgID, model.MsgStatusDelivered, deliveredTime)
-			
-			// 通知发送方
-			s.notifyStatusUpdate(msg.FromUserID, msg.MsgID, model.MsgStatusDelivered, deliveredTime)
-			
-			log.Debugf("Offline message %s delivered to user %d", msg.MsgID, userID)
-		} else {
+		data := encodePush(msg)
+		if !s.hub.SendToUser(userID, data) {
 			log.Warnf("Failed to deliver offline message %s to user %d", msg.MsgID, userID)
 			break // 如果一条消息发送失败，停止发送后续消息
 		}
+		delivered = append(delivered, msg)
+		log.Debugf("Offline message %s delivered to user %d", msg.MsgID, userID)
 
 		// 避免一次性发送过多，稍微延迟
 		time.Sleep(10 * time.Millisecond)
 	}
 
+	// 3. 更新为已送达（一条 UPDATE），并按发送方合并通知
+	if len(delivered) > 0 {
+		deliveredTime := time.Now().UnixMilli()
+		msgIDs := make([]string, len(delivered))
+		for i, msg := range delivered {
+			msgIDs[i] = msg.MsgID
+		}
+		if err := s.messageRepo.UpdateStatusBatch(ctx, msgIDs, model.MsgStatusDelivered, deliveredTime); err != nil {
+			log.Errorf("Failed to mark %d offline messages delivered for user %d: %v", len(msgIDs), userID, err)
+		} else {
+			s.notifyStatusUpdates(delivered, model.MsgStatusDelivered, deliveredTime)
+		}
+	}
+
 	log.Debugf("Finished pushing offline messages to user %d", userID)
 }
 
 // 更新会话
-func (s *IMServer) updateSession(msg *model.Message) {
+func (s *IMServer) updateSession(ctx context.Context, msg *model.Message) {
 	// 更新发送方会话
-	s.sessionRepo.UpdateSession(&model.Session{
+	sender := &model.Session{
 		UserID:         msg.FromUserID,
 		TargetID:       msg.ToUserID,
 		SessionType:    model.SessionTypeSingle,
 		LastMsgContent: msg.Content,
 		LastMsgTime:    msg.ServerTime,
-	})
+	}
+	if err := s.sessionRepo.UpdateSession(ctx, sender); err == nil {
+		s.emit(&model.Event{Type: model.EventSessionUpdated, UserID: sender.UserID, Session: sender, Message: msg})
+	}
 
-	// 更新接收方会话（增加未读数）
-	s.sessionRepo.UpdateSession(&model.Session{
+	// 更新接收方会话，按已读水位重新计算未读数
+	recipient := &model.Session{
 		UserID:         msg.ToUserID,
 		TargetID:       msg.FromUserID,
 		SessionType:    model.SessionTypeSingle,
 		LastMsgContent: msg.Content,
 		LastMsgTime:    msg.ServerTime,
-		UnreadCount:    1, // 会累加
-	})
+	}
+	if err := s.sessionRepo.UpdateSession(ctx, recipient); err == nil {
+		s.emit(&model.Event{Type: model.EventSessionUpdated, UserID: recipient.UserID, Session: recipient, Message: msg})
+	}
+	s.sessionRepo.RefreshUnread(ctx, msg.ToUserID, msg.FromUserID, model.SessionTypeSingle)
 }
 
 // 注册节点
 func (s *IMServer) registerNode() error {
-	return s.routeRepo.RegisterServer(s.config.ServerID, s.config.GRPCAddr)
+	return s.routeRepo.RegisterServer(s.serverContext(), s.config.ServerID, s.config.GRPCAddr)
 }
 
 // 注销节点
 func (s *IMServer) unregisterNode() {
-	s.routeRepo.UnregisterServer(s.config.ServerID)
+	s.routeRepo.UnregisterServer(context.Background(), s.config.ServerID)
 }
 
 // 心跳工作器
@@ -656,12 +1529,12 @@ func (s *IMServer) heartbeatWorker() {
 			return
 		case <-ticker.C:
 			// 更新服务器心跳
-			s.routeRepo.UpdateServerHeartbeat(s.config.ServerID)
+			s.routeRepo.UpdateServerHeartbeat(s.ctx, s.config.ServerID)
 
 			// 批量更新在线用户心跳
 			userIDs := s.hub.GetOnlineUsers()
 			if len(userIDs) > 0 {
-				s.routeManager.BatchUpdateHeartbeat(userIDs)
+				s.routeManager.BatchUpdateHeartbeat(s.ctx, userIDs)
 			}
 		}
 	}
@@ -675,7 +1548,7 @@ func (s *IMServer) startGRPCServer() {
 		return
 	}
 
-	s.grpcServer = grpc.NewServer()
+	s.grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(s.grpcInterceptors()...))
 	imgrpc.RegisterIMServerServer(s.grpcServer, s)
 
 	log.Infof("gRPC server listening on %s", s.config.GRPCAddr)
@@ -685,6 +1558,28 @@ func (s *IMServer) startGRPCServer() {
 	}
 }
 
+// grpcInterceptors gRPC 服务端拦截器链：请求日志（关联请求 ID）、指标、panic 恢复、集群密钥认证，之后为 Config.GRPCInterceptors
+func (s *IMServer) grpcInterceptors() []grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{
+		imgrpc.LoggingInterceptor(),
+		imgrpc.MetricsInterceptor(s.metrics),
+		imgrpc.RecoveryInterceptor(),
+	}
+	if s.config.ClusterKey != "" {
+		interceptors = append(interceptors, imgrpc.AuthInterceptor(s.config.ClusterKey))
+	}
+	return append(interceptors, s.config.GRPCInterceptors...)
+}
+
+// dialOptions 连接其他节点的 gRPC 选项，配置 ClusterKey 时在请求中携带集群密钥
+func (s *IMServer) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if s.config.ClusterKey != "" {
+		opts = append(opts, grpc.WithUnaryInterceptor(imgrpc.ClusterKeyInterceptor(s.config.ClusterKey)))
+	}
+	return opts
+}
+
 // 发现其他节点
 func (s *IMServer) discoverPeers() {
 	ticker := time.NewTicker(30 * time.Second)
@@ -695,7 +1590,7 @@ func (s *IMServer) discoverPeers() {
 		case <-s.ctx.Done():
 			return
 		case <-ticker.C:
-			servers, err := s.routeRepo.GetActiveServers()
+			servers, err := s.routeRepo.GetActiveServers(s.ctx)
 			if err != nil {
 				continue
 			}
@@ -708,7 +1603,7 @@ func (s *IMServer) discoverPeers() {
 				s.peerMutex.Lock()
 				if _, exists := s.peerClients[server.ServerID]; !exists {
 					// 建立新连接
-					conn, err := grpc.Dial(server.GRPCAddr, grpc.WithInsecure())
+					conn, err := grpc.Dial(server.GRPCAddr, s.dialOptions()...)
 					if err != nil {
 						log.Errorf("Failed to connect to peer %s: %v", server.ServerID, err)
 						s.peerMutex.Unlock()
@@ -725,7 +1620,8 @@ func (s *IMServer) discoverPeers() {
 
 // ForwardMessage gRPC 服务端实现（接收其他节点转发的消息）
 func (s *IMServer) ForwardMessage(ctx context.Context, req *imgrpc.ForwardMessageRequest) (*imgrpc.ForwardMessageResponse, error) {
-	log.Debugf("Received forwarded message %s from remote gateway", req.MsgID)
+	ctx = imgrpc.IncomingContext(ctx)
+	log.Ctx(ctx).Debugf("Received forwarded message %s from remote gateway", req.MsgID)
 
 	// 推送给本地用户
 	msg := &model.Message{
@@ -737,9 +1633,16 @@ func (s *IMServer) ForwardMessage(ctx context.Context, req *imgrpc.ForwardMessag
 		Status:     model.MsgStatusSent,
 		ClientTime: req.ClientTime,
 		ServerTime: req.ServerTime,
+		GroupID:    req.GroupID,
+	}
+
+	// 群消息推送给本节点的群成员，不更新送达状态
+	if msg.GroupID != 0 {
+		s.hub.SendToUsers(req.ToUserIDs, encodePush(msg))
+		return &imgrpc.ForwardMessageResponse{Delivered: true}, nil
 	}
 
-	s.pushToLocalUser(msg)
+	s.pushToLocalUser(ctx, msg)
 
 	return &imgrpc.ForwardMessageResponse{
 		Delivered: true,
@@ -0,0 +1,270 @@
+package core
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// subscriptionSet 消息订阅集合
+// 订阅变更时重建索引（所有订阅的关键词合并为一个多模式匹配器），每条消息只扫描一遍内容，匹配时无锁读取
+type subscriptionSet struct {
+	mu     sync.Mutex
+	nextID int64
+	subs   map[int64]*subscription
+	index  atomic.Pointer[subscriptionIndex]
+}
+
+// subscription 已注册的订阅
+type subscription struct {
+	id       int64
+	name     string
+	keywords []string
+	match    func(*model.Message) bool
+	handler  func(*model.SubscriptionMatch)
+}
+
+// subscriptionIndex 订阅索引，构建后只读
+type subscriptionIndex struct {
+	matcher  *keywordMatcher
+	hits     [][]keywordHit  // 匹配器关键词序号 -> 包含该关键词的订阅
+	noFilter []*subscription // 没有关键词的订阅，只按 Match 过滤
+}
+
+// keywordHit 订阅中的关键词，keyword 为订阅时的原文
+type keywordHit struct {
+	sub     *subscription
+	keyword string
+}
+
+// Subscribe 注册消息订阅，收到客户端消息时（与 OnMessage 相同）匹配，命中时异步调用 handler
+// 返回取消订阅的函数
+func (s *IMServer) Subscribe(sub *model.Subscription, handler func(*model.SubscriptionMatch)) (func(), error) {
+	if handler == nil {
+		return nil, model.NewParamError("handler", "handler is required")
+	}
+	if len(sub.Keywords) == 0 && sub.Match == nil {
+		return nil, model.NewParamError("keywords", "keywords or match is required")
+	}
+	for _, keyword := range sub.Keywords {
+		if strings.TrimSpace(keyword) == "" {
+			return nil, model.NewParamError("keywords", "keywords must not be empty")
+		}
+	}
+
+	id := s.subscriptions.add(&subscription{
+		name:     sub.Name,
+		keywords: append([]string(nil), sub.Keywords...),
+		match:    sub.Match,
+		handler:  handler,
+	})
+	var once sync.Once
+	return func() { once.Do(func() { s.subscriptions.remove(id) }) }, nil
+}
+
+// fireMessage 触发消息回调，并在后台匹配订阅、触发命中的订阅回调
+func (s *IMServer) fireMessage(msg *model.Message) {
+	s.emit(&model.Event{Type: model.EventMessageReceived, UserID: msg.FromUserID, Message: msg})
+
+	if !s.subscriptions.empty() {
+		s.goCallback("Subscribe", func() {
+			for _, result := range s.subscriptions.match(msg) {
+				result := result
+				s.metrics.Add("im_subscription_matches_total", 1, observability.L("subscription", result.Subscription))
+				s.goCallback("Subscribe", func() { result.handler(&result.SubscriptionMatch) })
+			}
+		})
+	}
+}
+
+// add 添加订阅并重建索引，返回订阅 ID
+func (c *subscriptionSet) add(sub *subscription) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subs == nil {
+		c.subs = make(map[int64]*subscription)
+	}
+	c.nextID++
+	sub.id = c.nextID
+	c.subs[sub.id] = sub
+	c.rebuild()
+	return sub.id
+}
+
+// remove 删除订阅并重建索引
+func (c *subscriptionSet) remove(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subs, id)
+	c.rebuild()
+}
+
+// rebuild 重建索引，需持有 mu
+func (c *subscriptionSet) rebuild() {
+	if len(c.subs) == 0 {
+		c.index.Store(nil)
+		return
+	}
+
+	index := &subscriptionIndex{}
+	positions := make(map[string]int) // 小写关键词 -> 匹配器关键词序号
+	var patterns []string
+	for _, sub := range c.subs {
+		if len(sub.keywords) == 0 {
+			index.noFilter = append(index.noFilter, sub)
+			continue
+		}
+		for _, keyword := range sub.keywords {
+			lower := strings.ToLower(keyword)
+			pos, ok := positions[lower]
+			if !ok {
+				pos = len(patterns)
+				positions[lower] = pos
+				patterns = append(patterns, lower)
+				index.hits = append(index.hits, nil)
+			}
+			index.hits[pos] = append(index.hits[pos], keywordHit{sub: sub, keyword: keyword})
+		}
+	}
+	if len(patterns) > 0 {
+		index.matcher = newKeywordMatcher(patterns)
+	}
+	c.index.Store(index)
+}
+
+// subscriptionResult 命中的订阅和回调
+type subscriptionResult struct {
+	model.SubscriptionMatch
+	handler func(*model.SubscriptionMatch)
+}
+
+// empty 是否没有订阅
+func (c *subscriptionSet) empty() bool {
+	return c.index.Load() == nil
+}
+
+// match 返回消息命中的订阅
+func (c *subscriptionSet) match(msg *model.Message) []*subscriptionResult {
+	index := c.index.Load()
+	if index == nil {
+		return nil
+	}
+
+	var results []*subscriptionResult
+	if index.matcher != nil {
+		bySub := make(map[int64]*subscriptionResult)
+		for _, pos := range index.matcher.find(strings.ToLower(msg.Content)) {
+			for _, hit := range index.hits[pos] {
+				result, ok := bySub[hit.sub.id]
+				if !ok {
+					if hit.sub.match != nil && !hit.sub.match(msg) {
+						bySub[hit.sub.id] = nil
+						continue
+					}
+					result = &subscriptionResult{
+						SubscriptionMatch: model.SubscriptionMatch{Subscription: hit.sub.name, Message: msg},
+						handler:           hit.sub.handler,
+					}
+					bySub[hit.sub.id] = result
+					results = append(results, result)
+				}
+				if result != nil {
+					result.Keywords = append(result.Keywords, hit.keyword)
+				}
+			}
+		}
+	}
+	for _, sub := range index.noFilter {
+		if sub.match(msg) {
+			results = append(results, &subscriptionResult{
+				SubscriptionMatch: model.SubscriptionMatch{Subscription: sub.name, Message: msg},
+				handler:           sub.handler,
+			})
+		}
+	}
+	return results
+}
+
+// keywordMatcher 多关键词匹配器（Aho-Corasick），按字节匹配，扫描一遍内容找出所有出现的关键词
+type keywordMatcher struct {
+	next []map[byte]int32 // 节点的子节点
+	fail []int32          // 失配时跳转的节点
+	out  [][]int32        // 在该节点结束的关键词序号（含失配链上的节点）
+}
+
+// newKeywordMatcher 创建匹配器，patterns 不能为空串
+func newKeywordMatcher(patterns []string) *keywordMatcher {
+	m := &keywordMatcher{next: []map[byte]int32{{}}, fail: []int32{0}, out: [][]int32{nil}}
+	for i, pattern := range patterns {
+		node := int32(0)
+		for j := 0; j < len(pattern); j++ {
+			child, ok := m.next[node][pattern[j]]
+			if !ok {
+				child = int32(len(m.next))
+				m.next = append(m.next, map[byte]int32{})
+				m.fail = append(m.fail, 0)
+				m.out = append(m.out, nil)
+				m.next[node][pattern[j]] = child
+			}
+			node = child
+		}
+		m.out[node] = append(m.out[node], int32(i))
+	}
+
+	// 按层序计算失配跳转，子节点继承失配节点的输出
+	queue := make([]int32, 0, len(m.next))
+	for _, child := range m.next[0] {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for b, child := range m.next[node] {
+			fail := m.fail[node]
+			for fail != 0 {
+				if _, ok := m.next[fail][b]; ok {
+					break
+				}
+				fail = m.fail[fail]
+			}
+			if target, ok := m.next[fail][b]; ok && target != child {
+				m.fail[child] = target
+			}
+			m.out[child] = append(m.out[child], m.out[m.fail[child]]...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// find 返回 text 中出现的关键词序号，按首次出现（结束位置）的顺序去重
+func (m *keywordMatcher) find(text string) []int32 {
+	var found []int32
+	var seen map[int32]bool
+	node := int32(0)
+	for i := 0; i < len(text); i++ {
+		b := text[i]
+		for node != 0 {
+			if _, ok := m.next[node][b]; ok {
+				break
+			}
+			node = m.fail[node]
+		}
+		if child, ok := m.next[node][b]; ok {
+			node = child
+		}
+		for _, pos := range m.out[node] {
+			if seen == nil {
+				seen = make(map[int32]bool)
+			}
+			if !seen[pos] {
+				seen[pos] = true
+				found = append(found, pos)
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// AddSupportAgent 添加客服或更新其同时接待的会话数上限，maxConversations 为 0 表示不限制
+func (s *IMServer) AddSupportAgent(ctx context.Context, userID int64, maxConversations int) error {
+	if userID <= 0 {
+		return model.NewParamError("user_id", "user_id must be positive")
+	}
+	if maxConversations < 0 {
+		return model.NewParamError("max_conversations", "max_conversations must not be negative")
+	}
+	return s.supportRepo.SaveAgent(ctx, userID, maxConversations)
+}
+
+// RemoveSupportAgent 移除客服，不再参与分配，已接待的会话保留，可通过 TransferSupportConversation 转接
+func (s *IMServer) RemoveSupportAgent(ctx context.Context, userID int64) error {
+	deleted, err := s.supportRepo.DeleteAgent(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return model.ErrSupportAgentNotFound
+	}
+	return nil
+}
+
+// ListSupportAgents 获取全部客服及其进行中的会话数和在线状态，按用户 ID 升序
+func (s *IMServer) ListSupportAgents(ctx context.Context) ([]*model.SupportAgent, error) {
+	agents, err := s.supportRepo.ListAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.supportRepo.CountOpenByAgent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, agent := range agents {
+		agent.ActiveConversations = counts[agent.UserID]
+		agent.Online = s.IsUserOnline(agent.UserID)
+	}
+	return agents, nil
+}
+
+// GetSupportConversation 获取客服会话，不存在时返回 ErrSupportConversationNotFound
+func (s *IMServer) GetSupportConversation(ctx context.Context, id int64) (*model.SupportConversation, error) {
+	conv, err := s.supportRepo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, model.ErrSupportConversationNotFound
+	}
+	return conv, err
+}
+
+// ListSupportConversations 分页获取客服会话，按创建时间倒序，可按客服和状态过滤
+func (s *IMServer) ListSupportConversations(ctx context.Context, req *model.ListSupportConversationsRequest) (*model.SupportConversationPage, error) {
+	if req.Status < 0 || req.Status > model.SupportStatusClosed {
+		return nil, model.NewParamError("status", "invalid support conversation status")
+	}
+	if req.Limit < 0 {
+		return nil, model.NewParamError("limit", "invalid limit")
+	}
+	return s.supportRepo.List(ctx, req)
+}
+
+// TransferSupportConversation 转接未结束的客服会话，agentID 为 0 时按分配策略选择当前客服以外的可用客服，
+// 没有可用客服时转为待分配；指定的客服不受在线和会话数上限限制
+// 会话不存在或已结束返回 ErrSupportConversationNotFound，agentID 不是客服返回 ErrSupportAgentNotFound
+func (s *IMServer) TransferSupportConversation(ctx context.Context, id, agentID int64) (*model.SupportConversation, error) {
+	conv, err := s.GetSupportConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if conv.Status == model.SupportStatusClosed {
+		return nil, model.ErrSupportConversationNotFound
+	}
+
+	if agentID != 0 {
+		if _, err := s.supportRepo.GetAgent(ctx, agentID); errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, model.ErrSupportAgentNotFound
+		} else if err != nil {
+			return nil, err
+		}
+	} else if agentID, err = s.pickSupportAgent(ctx, conv.CustomerID, conv.AgentID); err != nil {
+		return nil, err
+	}
+	return s.assignSupport(ctx, conv, agentID)
+}
+
+// CloseSupportConversation 结束客服会话，客户的下一条消息会开始新的会话
+// 会话不存在或已结束返回 ErrSupportConversationNotFound
+func (s *IMServer) CloseSupportConversation(ctx context.Context, id int64) (*model.SupportConversation, error) {
+	closed, err := s.supportRepo.Close(ctx, id, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	if !closed {
+		return nil, model.ErrSupportConversationNotFound
+	}
+	conv, err := s.supportRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *conv
+	s.emit(&model.Event{Type: model.EventSupportClosed, UserID: conv.CustomerID, Support: &snapshot})
+	return conv, nil
+}
+
+// routeSupport 客服模式下将发给 SupportUserID 的单聊消息改发给客户当前会话的客服
+// 客户没有未结束的会话时新建，会话待分配时尝试分配；没有可用客服时消息仍发给 SupportUserID
+func (s *IMServer) routeSupport(ctx context.Context, msg *model.Message) error {
+	if s.config.SupportUserID == 0 || msg.GroupID != 0 || msg.ToUserID != s.config.SupportUserID {
+		return nil
+	}
+
+	conv, err := s.supportRepo.GetOpenByCustomer(ctx, msg.FromUserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 并发创建时唯一索引保证只有一个成功，都重新读取
+		if _, err := s.supportRepo.Create(ctx, msg.FromUserID); err != nil {
+			return err
+		}
+		conv, err = s.supportRepo.GetOpenByCustomer(ctx, msg.FromUserID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if conv.Status == model.SupportStatusPending {
+		agentID, err := s.pickSupportAgent(ctx, conv.CustomerID, 0)
+		if err != nil {
+			return err
+		}
+		if agentID != 0 {
+			if conv, err = s.assignSupport(ctx, conv, agentID); err != nil {
+				return err
+			}
+		}
+	}
+	if conv.AgentID != 0 {
+		msg.ToUserID = conv.AgentID
+	} else {
+		log.Ctx(ctx).Infof("No support agent available for customer %d, conversation %d pending", conv.CustomerID, conv.ID)
+	}
+	return nil
+}
+
+// assignSupport 将会话分配给 agentID（0 表示转为待分配）并触发 EventSupportAssigned
+// 会话已被并发转接时以先完成的为准，返回最新的会话
+func (s *IMServer) assignSupport(ctx context.Context, conv *model.SupportConversation, agentID int64) (*model.SupportConversation, error) {
+	assigned, err := s.supportRepo.Assign(ctx, conv.ID, conv.AgentID, agentID, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.supportRepo.Get(ctx, conv.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !assigned {
+		if updated.Status == model.SupportStatusClosed {
+			return nil, model.ErrSupportConversationNotFound
+		}
+		return updated, nil
+	}
+
+	if updated.AgentID != 0 {
+		s.supportLastAgent.Store(updated.AgentID)
+	}
+	snapshot := *updated
+	s.emit(&model.Event{Type: model.EventSupportAssigned, UserID: updated.CustomerID, Support: &snapshot})
+	return updated, nil
+}
+
+// pickSupportAgent 按分配策略选择在线且未满的客服，排除客户本人和 exclude，没有可用客服时返回 0
+// least_busy 选择进行中会话最少的客服（相同时选用户 ID 最小的），round_robin 选择上次分配的客服之后的下一个
+func (s *IMServer) pickSupportAgent(ctx context.Context, customerID, exclude int64) (int64, error) {
+	agents, err := s.supportRepo.ListAgents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	counts, err := s.supportRepo.CountOpenByAgent(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var available []*model.SupportAgent
+	for _, agent := range agents {
+		if agent.UserID == customerID || agent.UserID == exclude {
+			continue
+		}
+		if agent.MaxConversations > 0 && counts[agent.UserID] >= agent.MaxConversations {
+			continue
+		}
+		if !s.IsUserOnline(agent.UserID) {
+			continue
+		}
+		agent.ActiveConversations = counts[agent.UserID]
+		available = append(available, agent)
+	}
+	if len(available) == 0 {
+		return 0, nil
+	}
+
+	// agents 按用户 ID 升序
+	if s.config.SupportStrategy == model.SupportStrategyRoundRobin {
+		last := s.supportLastAgent.Load()
+		for _, agent := range available {
+			if agent.UserID > last {
+				return agent.UserID, nil
+			}
+		}
+		return available[0].UserID, nil
+	}
+	best := available[0]
+	for _, agent := range available[1:] {
+		if agent.ActiveConversations < best.ActiveConversations {
+			best = agent
+		}
+	}
+	return best.UserID, nil
+}
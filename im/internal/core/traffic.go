@@ -0,0 +1,128 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// connTraffic 连接流量计数，统计 WebSocket 连接的线上字节数（含帧头，开启压缩时为压缩后大小）
+type connTraffic struct {
+	userID      int64
+	connectedAt int64
+	in          atomic.Int64
+	out         atomic.Int64
+
+	// 上行带宽配额（固定 1 秒窗口），仅在读协程中访问
+	bwWindow int64
+	bwStart  int64
+	bwLast   int64
+}
+
+// allowIn 判断当前 1 秒窗口内收到的字节数是否未超过 limit（含刚读取的消息）
+func (t *connTraffic) allowIn(limit int, now time.Time) bool {
+	in := t.in.Load()
+	if window := now.Unix(); window != t.bwWindow {
+		t.bwWindow = window
+		t.bwStart = t.bwLast
+	}
+	t.bwLast = in
+	return in-t.bwStart <= int64(limit)
+}
+
+// stats 转换为统计结果
+func (t *connTraffic) stats() *model.TrafficStats {
+	return &model.TrafficStats{
+		UserID:      t.userID,
+		BytesIn:     t.in.Load(),
+		BytesOut:    t.out.Load(),
+		ConnectedAt: t.connectedAt,
+	}
+}
+
+// countingConn 统计读写字节数的连接
+type countingConn struct {
+	net.Conn
+	traffic *connTraffic
+	metrics observability.Metrics
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.traffic.in.Add(int64(n))
+		c.metrics.Add("im_ws_bytes_total", float64(n), observability.L("direction", "in"))
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.traffic.out.Add(int64(n))
+		c.metrics.Add("im_ws_bytes_total", float64(n), observability.L("direction", "out"))
+	}
+	return n, err
+}
+
+// countingWriter 在 WebSocket 升级接管连接时替换为 countingConn
+type countingWriter struct {
+	http.ResponseWriter
+	traffic *connTraffic
+	metrics observability.Metrics
+}
+
+func (w *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &countingConn{Conn: conn, traffic: w.traffic, metrics: w.metrics}, rw, nil
+}
+
+// checkBandwidth 检查连接上行流量是否超出配额，超出时返回错误描述
+func (s *IMServer) checkBandwidth(client *Client) string {
+	limit := s.tunables.Load().BandwidthLimit
+	if limit <= 0 || client.traffic == nil || client.traffic.allowIn(limit, time.Now()) {
+		return ""
+	}
+	s.metrics.Add("im_messages_rejected_total", 1, observability.L("reason", "bandwidth"))
+	return "bandwidth limit exceeded"
+}
+
+// UserTraffic 获取用户在当前节点连接的流量统计，用户不在本节点时返回 false
+func (s *IMServer) UserTraffic(userID int64) (*model.TrafficStats, bool) {
+	client, ok := s.hub.GetClient(userID)
+	if !ok || client.traffic == nil {
+		return nil, false
+	}
+	return client.traffic.stats(), true
+}
+
+// Traffic 获取当前节点所有连接的流量统计，按收发总字节数倒序，limit > 0 时只返回前 limit 个
+func (s *IMServer) Traffic(limit int) []*model.TrafficStats {
+	var stats []*model.TrafficStats
+	for _, client := range s.hub.Clients() {
+		if client.traffic != nil {
+			stats = append(stats, client.traffic.stats())
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].BytesIn+stats[i].BytesOut > stats[j].BytesIn+stats[j].BytesOut
+	})
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/im/internal/model"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// Tunables 运行期可调整的配置，通过 UpdateTunables 热更新，无需重启服务
+type Tunables struct {
+	LogLevel       string `json:"log_level"`        // 日志级别 debug/info/warn/error，为空时不修改，仅对内置的 logrus 日志有效
+	CacheTTL       int    `json:"cache_ttl"`        // 路由缓存时间（秒），0 时使用默认值 30 秒
+	MaxMessageSize int    `json:"max_message_size"` // 单条消息内容最大字节数，0 表示不限制
+	RateLimit      int    `json:"rate_limit"`       // 每个连接每秒最多发送的消息数，超出的消息回复失败 ACK，0 表示不限制
+	BandwidthLimit int    `json:"bandwidth_limit"`  // 每个连接每秒最多上行字节数（线上字节），超出后的消息回复失败 ACK，0 表示不限制
+}
+
+// validate 校验并补全默认值
+func (t *Tunables) validate() error {
+	switch strings.ToLower(t.LogLevel) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		return fmt.Errorf("%w: unknown log level %q", model.ErrInvalidConfig, t.LogLevel)
+	}
+	if t.CacheTTL < 0 {
+		return fmt.Errorf("%w: cache ttl must not be negative", model.ErrInvalidConfig)
+	}
+	if t.MaxMessageSize < 0 {
+		return fmt.Errorf("%w: max message size must not be negative", model.ErrInvalidConfig)
+	}
+	if t.RateLimit < 0 {
+		return fmt.Errorf("%w: rate limit must not be negative", model.ErrInvalidConfig)
+	}
+	if t.BandwidthLimit < 0 {
+		return fmt.Errorf("%w: bandwidth limit must not be negative", model.ErrInvalidConfig)
+	}
+	if t.CacheTTL == 0 {
+		t.CacheTTL = 30
+	}
+	return nil
+}
+
+// Tunables 获取当前生效的运行期配置
+func (s *IMServer) Tunables() Tunables {
+	return *s.tunables.Load()
+}
+
+// UpdateTunables 热更新运行期配置，立即对所有连接生效，不合法时返回 ErrInvalidConfig 且保持原配置
+func (s *IMServer) UpdateTunables(t Tunables) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	if t.LogLevel != "" {
+		log.SetLogLevel(t.LogLevel)
+	}
+	s.routeManager.SetCacheTTL(t.CacheTTL)
+	s.tunables.Store(&t)
+	return nil
+}
+
+// 检查客户端消息是否超出大小和频率限制，超出时返回错误描述
+func (s *IMServer) checkLimits(client *Client, content string) string {
+	t := s.tunables.Load()
+	if t.MaxMessageSize > 0 && len(content) > t.MaxMessageSize {
+		s.metrics.Add("im_messages_rejected_total", 1, observability.L("reason", "too_large"))
+		return "message too large"
+	}
+	if t.RateLimit > 0 && !client.allow(t.RateLimit, time.Now()) {
+		s.metrics.Add("im_messages_rejected_total", 1, observability.L("reason", "rate_limited"))
+		return "rate limit exceeded"
+	}
+	return s.checkBandwidth(client)
+}
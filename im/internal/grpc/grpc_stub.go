@@ -11,22 +11,30 @@ import (
 // IMServerClient gRPC 客户端接口（临时桩代码）
 type IMServerClient interface {
 	ForwardMessage(ctx context.Context, in *ForwardMessageRequest, opts ...grpc.CallOption) (*ForwardMessageResponse, error)
+	DisconnectUser(ctx context.Context, in *DisconnectUserRequest, opts ...grpc.CallOption) (*DisconnectUserResponse, error)
+	NodeStats(ctx context.Context, in *NodeStatsRequest, opts ...grpc.CallOption) (*NodeStatsResponse, error)
+	SyncDraft(ctx context.Context, in *SyncDraftRequest, opts ...grpc.CallOption) (*SyncDraftResponse, error)
 }
 
 // IMServerServer gRPC 服务端接口（临时桩代码）
 type IMServerServer interface {
 	ForwardMessage(context.Context, *ForwardMessageRequest) (*ForwardMessageResponse, error)
+	DisconnectUser(context.Context, *DisconnectUserRequest) (*DisconnectUserResponse, error)
+	NodeStats(context.Context, *NodeStatsRequest) (*NodeStatsResponse, error)
+	SyncDraft(context.Context, *SyncDraftRequest) (*SyncDraftResponse, error)
 }
 
-// ForwardMessageRequest 转发消息请求
+// ForwardMessageRequest 转发消息请求，群消息的 GroupID 不为 0，ToUserIDs 为连接在目标节点的群成员
 type ForwardMessageRequest struct {
-	ToUserID   int64  `json:"to_user_id"`
-	MsgID      string `json:"msg_id"`
-	FromUserID int64  `json:"from_user_id"`
-	Content    string `json:"content"`
-	MsgType    int32  `json:"msg_type"`
-	ClientTime int64  `json:"client_time"`
-	ServerTime int64  `json:"server_time"`
+	ToUserID   int64   `json:"to_user_id"`
+	MsgID      string  `json:"msg_id"`
+	FromUserID int64   `json:"from_user_id"`
+	Content    string  `json:"content"`
+	MsgType    int32   `json:"msg_type"`
+	ClientTime int64   `json:"client_time"`
+	ServerTime int64   `json:"server_time"`
+	GroupID    int64   `json:"group_id"`
+	ToUserIDs  []int64 `json:"to_user_ids"`
 }
 
 // ForwardMessageResponse 转发消息响应
@@ -35,6 +43,44 @@ type ForwardMessageResponse struct {
 	Error     string `json:"error"`
 }
 
+// DisconnectUserRequest 踢下线请求（由用户所在节点断开连接）
+type DisconnectUserRequest struct {
+	UserID int64  `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+// DisconnectUserResponse 踢下线响应
+type DisconnectUserResponse struct {
+	Kicked bool `json:"kicked"` // 用户是否连接在该节点
+}
+
+// NodeStatsRequest 节点统计请求
+type NodeStatsRequest struct{}
+
+// NodeStatsResponse 节点统计响应
+type NodeStatsResponse struct {
+	ServerID        string `json:"server_id"`
+	Connections     int64  `json:"connections"`
+	Version         string `json:"version"`
+	ProtocolVersion int32  `json:"protocol_version"`
+	StartedAt       int64  `json:"started_at"`
+	Draining        bool   `json:"draining"`
+}
+
+// SyncDraftRequest 草稿同步请求（推送给用户连接在该节点的设备）
+type SyncDraftRequest struct {
+	UserID      int64  `json:"user_id"`
+	TargetID    int64  `json:"target_id"`
+	SessionType int32  `json:"session_type"`
+	Content     string `json:"content"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
+
+// SyncDraftResponse 草稿同步响应
+type SyncDraftResponse struct {
+	Delivered bool `json:"delivered"` // 用户是否连接在该节点
+}
+
 // RegisterIMServerServer 注册 gRPC 服务（临时桩代码）
 func RegisterIMServerServer(s *grpc.Server, srv IMServerServer) {
 	// TODO: 使用 protobuf 生成的代码替换
@@ -56,5 +102,6 @@ func MessageToForwardRequest(msg *model.Message) *ForwardMessageRequest {
 		MsgType:    int32(msg.MsgType),
 		ClientTime: msg.ClientTime,
 		ServerTime: msg.ServerTime,
+		GroupID:    msg.GroupID,
 	}
 }
@@ -0,0 +1,78 @@
+package imgrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/bbadbeef/go-base/im/internal/log"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// clusterKeyMetadataKey 节点间调用传递集群密钥的 metadata 键
+const clusterKeyMetadataKey = "x-im-cluster-key"
+
+// LoggingInterceptor 请求日志拦截器，从 metadata 读取请求 ID 写入 ctx，记录方法、耗时和状态码
+// 失败的请求（含认证失败）记录为警告，成功的请求记录为调试日志
+func LoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = IncomingContext(ctx)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		if err != nil {
+			log.Ctx(ctx).Warnf("gRPC %s failed in %s: %v", info.FullMethod, time.Since(start), err)
+		} else {
+			log.Ctx(ctx).Debugf("gRPC %s completed in %s", info.FullMethod, time.Since(start))
+		}
+		return resp, err
+	}
+}
+
+// MetricsInterceptor 指标拦截器，按方法和状态码记录 im_grpc_server_duration_seconds
+func MetricsInterceptor(metrics observability.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		metrics.Observe("im_grpc_server_duration_seconds", time.Since(start).Seconds(),
+			observability.L("method", info.FullMethod), observability.L("code", status.Code(err).String()))
+		return resp, err
+	}
+}
+
+// RecoveryInterceptor panic 恢复拦截器，记录堆栈并返回 codes.Internal，单个请求 panic 不会导致节点退出
+func RecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Ctx(ctx).Errorf("gRPC %s panic: %v\n%s", info.FullMethod, r, debug.Stack())
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// AuthInterceptor 集群密钥认证拦截器，metadata 中的密钥与 key 不一致时返回 codes.Unauthenticated
+func AuthInterceptor(key string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		values := metadata.ValueFromIncomingContext(ctx, clusterKeyMetadataKey)
+		if len(values) == 0 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(key)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid cluster key")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ClusterKeyInterceptor 客户端拦截器，将集群密钥写入请求的 metadata
+func ClusterKeyInterceptor(key string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, clusterKeyMetadataKey, key)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,28 @@
+package imgrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+
+	golog "github.com/bbadbeef/go-base/log"
+)
+
+// requestIDMetadataKey 节点间调用传递请求 ID 的 metadata 键
+const requestIDMetadataKey = "x-request-id"
+
+// OutgoingContext 将 ctx 中的请求 ID 写入 gRPC metadata，转发到其他节点后日志仍可按请求 ID 关联
+func OutgoingContext(ctx context.Context) context.Context {
+	if id := golog.RequestID(ctx); id != "" {
+		return metadata.AppendToOutgoingContext(ctx, requestIDMetadataKey, id)
+	}
+	return ctx
+}
+
+// IncomingContext 从 gRPC metadata 读取请求 ID 写入 ctx，没有时生成新的请求 ID
+func IncomingContext(ctx context.Context) context.Context {
+	if values := metadata.ValueFromIncomingContext(ctx, requestIDMetadataKey); len(values) > 0 && values[0] != "" {
+		return golog.WithRequestID(ctx, values[0])
+	}
+	return golog.EnsureRequestID(ctx)
+}
@@ -1,7 +1,10 @@
 package log
 
 import (
+	"context"
 	"sync"
+
+	golog "github.com/bbadbeef/go-base/log"
 )
 
 var (
@@ -115,3 +118,8 @@ func WithFields(fields map[string]interface{}) Logger {
 	}
 	return logger
 }
+
+// Ctx 返回附带 ctx 中请求 ID 的 logger（如果 logger 支持结构化日志）
+func Ctx(ctx context.Context) Logger {
+	return golog.FromContext(GetLogger(), ctx)
+}
@@ -0,0 +1,63 @@
+package model
+
+import "errors"
+
+// 错误定义，可通过 errors.Is 判断
+var (
+	ErrInvalidConfig = errors.New("invalid config")
+	ErrInvalidParam  = errors.New("invalid parameter")
+
+	// ErrPreflightFailed 启动前检查失败，如数据库不可用、迁移未执行、gRPC 地址无法监听
+	ErrPreflightFailed = errors.New("preflight check failed")
+
+	ErrGroupNotFound         = errors.New("group not found")
+	ErrNotGroupMember        = errors.New("not a group member")
+	ErrGroupPermissionDenied = errors.New("group permission denied")
+	ErrGroupPolicy           = errors.New("message not allowed by group policy")
+
+	ErrMessageNotFound = errors.New("message not found")
+	ErrReportNotFound  = errors.New("report not found or already resolved")
+
+	// ErrFileAccessDenied 消息引用了发送方不可访问的文件
+	ErrFileAccessDenied = errors.New("file access denied")
+
+	ErrSupportConversationNotFound = errors.New("support conversation not found or closed")
+	ErrSupportAgentNotFound        = errors.New("support agent not found")
+)
+
+// ParamError 参数校验错误
+// errors.Is(err, ErrInvalidParam) 为 true，可通过 errors.As 获取出错字段
+type ParamError struct {
+	Field   string // 出错字段，如 "start_time"
+	Message string // 错误描述
+}
+
+// NewParamError 创建参数校验错误
+func NewParamError(field, message string) *ParamError {
+	return &ParamError{Field: field, Message: message}
+}
+
+func (e *ParamError) Error() string {
+	return e.Message
+}
+
+// Is 支持 errors.Is(err, ErrInvalidParam)
+func (e *ParamError) Is(target error) bool {
+	return target == ErrInvalidParam
+}
+
+// GroupPolicyError 群组策略拒绝发送的错误
+// errors.Is(err, ErrGroupPolicy) 为 true，可通过 errors.As 获取原因
+type GroupPolicyError struct {
+	Reason     string `json:"reason"`                // 拒绝原因（GroupReject* 常量）
+	RetryAfter int64  `json:"retry_after,omitempty"` // 多久后可以再发送（毫秒），慢速模式和新成员禁言时有值
+}
+
+func (e *GroupPolicyError) Error() string {
+	return "group policy: " + e.Reason
+}
+
+// Is 支持 errors.Is(err, ErrGroupPolicy)
+func (e *GroupPolicyError) Is(target error) bool {
+	return target == ErrGroupPolicy
+}
@@ -18,37 +18,106 @@ const (
 	MsgStatusFailed    = 5 // 发送失败
 )
 
+// 通知级别常量
+const (
+	NotifyLevelAll      = 0 // 所有消息都通知（默认）
+	NotifyLevelMentions = 1 // 仅 @我 时通知
+	NotifyLevelNone     = 2 // 不通知
+)
+
 // 会话类型常量
 const (
 	SessionTypeSingle = 1 // 单聊
 	SessionTypeGroup  = 2 // 群聊
 )
 
+// 群成员角色常量
+const (
+	GroupRoleMember = 0 // 普通成员
+	GroupRoleAdmin  = 1 // 管理员
+	GroupRoleOwner  = 2 // 群主
+)
+
+// 在线状态常量
+const (
+	PresenceOnline    = "online"    // 在线
+	PresenceBusy      = "busy"      // 忙碌
+	PresenceAway      = "away"      // 离开
+	PresenceInvisible = "invisible" // 隐身：对其他用户显示为离线，仍正常接收消息
+	PresenceOffline   = "offline"   // 离线（未连接或隐身，不可设置）
+)
+
+// 举报处理状态常量
+const (
+	ReportStatusPending   = 1 // 待处理
+	ReportStatusResolved  = 2 // 已处理（确认违规）
+	ReportStatusDismissed = 3 // 已驳回
+)
+
+// 客服会话状态常量
+const (
+	SupportStatusPending = 1 // 待分配（没有可用客服）
+	SupportStatusOpen    = 2 // 进行中
+	SupportStatusClosed  = 3 // 已结束
+)
+
+// 客服分配策略常量
+const (
+	SupportStrategyLeastBusy  = "least_busy"  // 分配给进行中会话最少的客服（默认）
+	SupportStrategyRoundRobin = "round_robin" // 在可用客服中按用户 ID 轮流分配
+)
+
+// 群组策略拒绝原因常量
+const (
+	GroupRejectNotMember     = "not_group_member" // 不是群成员
+	GroupRejectSlowMode      = "slow_mode"        // 慢速模式，发送间隔过短
+	GroupRejectLinks         = "links_blocked"    // 禁止普通成员发送链接
+	GroupRejectImages        = "images_blocked"   // 禁止普通成员发送图片
+	GroupRejectNewcomerMuted = "newcomer_muted"   // 新成员禁言期内
+)
+
+// GuestRejectRestricted 访客连接不允许的操作（如向 GuestSendTo 以外的用户发消息、发群消息），作为失败 ACK 的 code
+const GuestRejectRestricted = "guest_restricted"
+
+// IsGuest 是否为访客连接的临时用户 ID（负数）
+func IsGuest(userID int64) bool {
+	return userID < 0
+}
+
+// 踢下线原因常量
+const (
+	KickReasonAdmin             = "admin"               // 管理员操作
+	KickReasonBanned            = "banned"              // 账号被封禁
+	KickReasonLoggedInElsewhere = "logged_in_elsewhere" // 在其他设备登录
+)
+
 // SendMessageRequest 发送消息请求
 type SendMessageRequest struct {
-	FromUserID int64  `json:"from_user_id"` // 发送者用户 ID（0 表示系统消息）
-	ToUserID   int64  `json:"to_user_id"`   // 接收者用户 ID（单聊时使用）
-	GroupID    int64  `json:"group_id"`     // 群组 ID（群聊时使用，单聊时为 0）
-	Content    string `json:"content"`      // 消息内容
-	MsgType    int    `json:"msg_type"`     // 消息类型（1:文本 2:图片 3:语音 4:视频 5:文件）
-	FileID     string `json:"file_id"`      // 文件ID（多媒体消息时使用）
+	FromUserID int64   `json:"from_user_id"`          // 发送者用户 ID（0 表示系统消息）
+	ToUserID   int64   `json:"to_user_id"`            // 接收者用户 ID（单聊时使用）
+	GroupID    int64   `json:"group_id"`              // 群组 ID（群聊时使用，单聊时为 0）
+	Content    string  `json:"content"`               // 消息内容
+	MsgType    int     `json:"msg_type"`              // 消息类型（1:文本 2:图片 3:语音 4:视频 5:文件）
+	FileID     string  `json:"file_id"`               // 文件ID（多媒体消息时使用）
+	AtUserIDs  []int64 `json:"at_user_ids,omitempty"` // @ 的用户 ID 列表
 }
 
 // Message 消息
 type Message struct {
-	MsgID         string                 `json:"msg_id"`                   // 消息唯一 ID
-	FromUserID    int64                  `json:"from_user_id"`             // 发送者用户 ID
-	ToUserID      int64                  `json:"to_user_id"`               // 接收者用户 ID
-	GroupID       int64                  `json:"group_id"`                 // 群组 ID（0 表示单聊）
-	Content       string                 `json:"content"`                  // 消息内容
-	MsgType       int                    `json:"msg_type"`                 // 消息类型
-	Status        int                    `json:"status"`                   // 消息状态
-	FileID        string                 `json:"file_id,omitempty"`        // 文件ID（多媒体消息）
-	FileInfo      *FileInfo              `json:"file_info,omitempty"`      // 文件信息（多媒体消息）
-	ClientTime    int64                  `json:"client_time"`              // 客户端时间戳（毫秒）
-	ServerTime    int64                  `json:"server_time"`              // 服务端时间戳（毫秒）
-	DeliveredTime int64                  `json:"delivered_time"`           // 送达时间戳（毫秒）
-	ReadTime      int64                  `json:"read_time"`                // 已读时间戳（毫秒）
+	MsgID         string    `json:"msg_id"`                // 消息唯一 ID
+	FromUserID    int64     `json:"from_user_id"`          // 发送者用户 ID
+	ToUserID      int64     `json:"to_user_id"`            // 接收者用户 ID
+	GroupID       int64     `json:"group_id"`              // 群组 ID（0 表示单聊）
+	Content       string    `json:"content"`               // 消息内容
+	MsgType       int       `json:"msg_type"`              // 消息类型
+	Status        int       `json:"status"`                // 消息状态
+	FileID        string    `json:"file_id,omitempty"`     // 文件ID（多媒体消息）
+	FileInfo      *FileInfo `json:"file_info,omitempty"`   // 文件信息（多媒体消息）
+	ClientTime    int64     `json:"client_time"`           // 客户端时间戳（毫秒）
+	ServerTime    int64     `json:"server_time"`           // 服务端时间戳（毫秒）
+	DeliveredTime int64     `json:"delivered_time"`        // 送达时间戳（毫秒）
+	ReadTime      int64     `json:"read_time"`             // 已读时间戳（毫秒）
+	AtUserIDs     []int64   `json:"at_user_ids,omitempty"` // @ 的用户 ID 列表（仅用于通知判定，不持久化）
 }
 
 // FileInfo 文件信息
@@ -64,6 +133,35 @@ type FileInfo struct {
 	Duration int    `json:"duration,omitempty"`  // 时长（音频/视频）
 }
 
+// NodeInfo 集群节点信息
+type NodeInfo struct {
+	ServerID        string `json:"server_id"`        // 节点 ID
+	GRPCAddr        string `json:"grpc_addr"`        // gRPC 地址
+	LastHeartbeat   int64  `json:"last_heartbeat"`   // 最近心跳时间戳（秒）
+	Reachable       bool   `json:"reachable"`        // 是否获取到节点统计，为 false 时以下字段为空
+	Connections     int    `json:"connections"`      // 当前 WebSocket 连接数
+	Version         string `json:"version"`          // 节点版本
+	ProtocolVersion int    `json:"protocol_version"` // WebSocket 协议版本
+	StartedAt       int64  `json:"started_at"`       // 启动时间戳（毫秒）
+	Draining        bool   `json:"draining"`         // 是否正在优雅关闭
+	Error           string `json:"error,omitempty"`  // 获取节点统计失败的原因
+}
+
+// ClusterInfo 集群拓扑
+type ClusterInfo struct {
+	ServerID    string      `json:"server_id"`   // 当前节点 ID
+	Nodes       []*NodeInfo `json:"nodes"`       // 活跃节点，按节点 ID 排序
+	Connections int         `json:"connections"` // 可达节点的连接数合计
+}
+
+// TrafficStats 连接流量统计（WebSocket 线上字节数，含帧头，开启压缩时为压缩后大小）
+type TrafficStats struct {
+	UserID      int64 `json:"user_id"`      // 用户 ID
+	BytesIn     int64 `json:"bytes_in"`     // 收到的字节数
+	BytesOut    int64 `json:"bytes_out"`    // 发送的字节数
+	ConnectedAt int64 `json:"connected_at"` // 连接建立时间戳（毫秒）
+}
+
 // Session 会话
 type Session struct {
 	UserID         int64  `json:"user_id"`          // 用户 ID
@@ -71,16 +169,39 @@ type Session struct {
 	SessionType    int    `json:"session_type"`     // 会话类型（1:单聊 2:群聊）
 	LastMsgContent string `json:"last_msg_content"` // 最后一条消息内容
 	LastMsgTime    int64  `json:"last_msg_time"`    // 最后消息时间戳（毫秒）
-	UnreadCount    int    `json:"unread_count"`     // 未读消息数
+	UnreadCount    int    `json:"unread_count"`     // 未读消息数（对方发来且晚于已读水位的消息数）
+	LastReadTime   int64  `json:"last_read_time"`   // 已读水位：已读到的最新消息服务端时间戳（毫秒）
 }
 
 // GetMessagesRequest 获取历史消息请求
 type GetMessagesRequest struct {
-	UserID      int64 `json:"user_id"`       // 当前用户 ID
-	TargetID    int64 `json:"target_id"`     // 对方用户 ID 或群组 ID
-	SessionType int   `json:"session_type"`  // 会话类型（1:单聊 2:群聊）
-	BeforeTime  int64 `json:"before_time"`   // 获取此时间之前的消息（分页），0 表示最新
-	Limit       int   `json:"limit"`         // 每页条数
+	UserID      int64  `json:"user_id"`      // 当前用户 ID
+	TargetID    int64  `json:"target_id"`    // 对方用户 ID 或群组 ID
+	SessionType int    `json:"session_type"` // 会话类型（1:单聊 2:群聊）
+	BeforeTime  int64  `json:"before_time"`  // 获取此时间之前的消息（分页），0 表示最新
+	Cursor      string `json:"cursor"`       // 分页游标（上一页的 next_cursor），设置后忽略 BeforeTime
+	Limit       int    `json:"limit"`        // 每页条数
+}
+
+// MessagePage 历史消息分页结果，按服务端时间倒序
+type MessagePage struct {
+	Messages   []*Message `json:"messages"`              // 本页消息
+	NextCursor string     `json:"next_cursor,omitempty"` // 下一页游标，没有更多时为空
+	HasMore    bool       `json:"has_more"`              // 是否还有更早的消息
+}
+
+// GetSessionsRequest 分页获取会话列表请求
+type GetSessionsRequest struct {
+	UserID int64  `json:"user_id"` // 当前用户 ID
+	Cursor string `json:"cursor"`  // 分页游标（上一页的 next_cursor），为空表示第一页
+	Limit  int    `json:"limit"`   // 每页条数
+}
+
+// SessionPage 会话列表分页结果，按最后消息时间倒序
+type SessionPage struct {
+	Sessions   []*Session `json:"sessions"`              // 本页会话
+	NextCursor string     `json:"next_cursor,omitempty"` // 下一页游标，没有更多时为空
+	HasMore    bool       `json:"has_more"`              // 是否还有更多会话
 }
 
 // Group 群组
@@ -92,6 +213,15 @@ type Group struct {
 	CreatedAt int64  `json:"created_at"` // 创建时间戳（毫秒）
 }
 
+// GroupPolicy 群组防刷屏策略，群主和管理员不受限制
+type GroupPolicy struct {
+	GroupID      int64 `json:"group_id"`      // 群组 ID
+	SlowMode     int   `json:"slow_mode"`     // 慢速模式：成员两条消息的最小间隔（秒），0 表示不限制
+	BlockLinks   bool  `json:"block_links"`   // 禁止普通成员发送链接
+	BlockImages  bool  `json:"block_images"`  // 禁止普通成员发送图片
+	NewcomerMute int   `json:"newcomer_mute"` // 新成员入群后的禁言时长（秒），0 表示不禁言
+}
+
 // GroupMember 群成员
 type GroupMember struct {
 	GroupID  int64 `json:"group_id"`  // 群组 ID
@@ -99,3 +229,176 @@ type GroupMember struct {
 	Role     int   `json:"role"`      // 角色（0:普通成员 1:管理员 2:群主）
 	JoinedAt int64 `json:"joined_at"` // 加入时间戳（毫秒）
 }
+
+// MessageReport 消息举报，保存举报时的消息快照，消息删除后仍可审核
+type MessageReport struct {
+	ID         int64  `json:"id"`           // 举报 ID
+	MsgID      string `json:"msg_id"`       // 被举报的消息 ID
+	ReporterID int64  `json:"reporter_id"`  // 举报人用户 ID
+	Reason     string `json:"reason"`       // 举报原因
+	FromUserID int64  `json:"from_user_id"` // 消息发送者（被举报人）
+	GroupID    int64  `json:"group_id"`     // 群组 ID（单聊为 0）
+	Content    string `json:"content"`      // 消息内容快照
+	MsgType    int    `json:"msg_type"`     // 消息类型
+	FileID     string `json:"file_id"`      // 文件 ID 快照
+	Status     int    `json:"status"`       // 处理状态（1:待处理 2:已处理 3:已驳回）
+	ResolverID int64  `json:"resolver_id"`  // 处理人用户 ID
+	Note       string `json:"note"`         // 处理备注
+	CreatedAt  int64  `json:"created_at"`   // 举报时间戳（毫秒）
+	ResolvedAt int64  `json:"resolved_at"`  // 处理时间戳（毫秒）
+}
+
+// ListReportsRequest 分页获取举报请求
+type ListReportsRequest struct {
+	Status int    `json:"status"` // 处理状态，0 表示全部
+	Cursor string `json:"cursor"` // 分页游标（上一页的 next_cursor）
+	Limit  int    `json:"limit"`  // 每页条数
+}
+
+// ReportPage 举报分页结果，按举报时间倒序
+type ReportPage struct {
+	Reports    []*MessageReport `json:"reports"`               // 本页举报
+	NextCursor string           `json:"next_cursor,omitempty"` // 下一页游标，没有更多时为空
+	HasMore    bool             `json:"has_more"`              // 是否还有更早的举报
+}
+
+// SupportAgent 客服
+type SupportAgent struct {
+	UserID              int64 `json:"user_id"`              // 客服用户 ID
+	MaxConversations    int   `json:"max_conversations"`    // 同时接待的会话数上限，0 表示不限制
+	ActiveConversations int   `json:"active_conversations"` // 进行中的会话数
+	Online              bool  `json:"online"`               // 是否在线，只有在线且未满的客服参与分配
+	CreatedAt           int64 `json:"created_at"`           // 加入时间戳（毫秒）
+}
+
+// SupportConversation 客服会话，同一客户同时只有一个未结束的会话
+type SupportConversation struct {
+	ID         int64 `json:"id"`          // 会话 ID
+	CustomerID int64 `json:"customer_id"` // 客户用户 ID（可为访客的负数 ID）
+	AgentID    int64 `json:"agent_id"`    // 接待客服用户 ID，待分配时为 0
+	Status     int   `json:"status"`      // 状态（1:待分配 2:进行中 3:已结束）
+	CreatedAt  int64 `json:"created_at"`  // 创建时间戳（毫秒）
+	UpdatedAt  int64 `json:"updated_at"`  // 最后分配或转接时间戳（毫秒）
+	ClosedAt   int64 `json:"closed_at"`   // 结束时间戳（毫秒）
+}
+
+// ListSupportConversationsRequest 分页获取客服会话请求
+type ListSupportConversationsRequest struct {
+	AgentID int64  `json:"agent_id"` // 接待客服，0 表示全部
+	Status  int    `json:"status"`   // 状态，0 表示全部
+	Cursor  string `json:"cursor"`   // 分页游标（上一页的 next_cursor）
+	Limit   int    `json:"limit"`    // 每页条数
+}
+
+// SupportConversationPage 客服会话分页结果，按创建时间倒序
+type SupportConversationPage struct {
+	Conversations []*SupportConversation `json:"conversations"`         // 本页会话
+	NextCursor    string                 `json:"next_cursor,omitempty"` // 下一页游标，没有更多时为空
+	HasMore       bool                   `json:"has_more"`              // 是否还有更早的会话
+}
+
+// ConnMeta WebSocket 连接信息，认证时传给 AuthFunc
+type ConnMeta struct {
+	RemoteIP  string // 客户端 IP，开启 TrustProxy 时优先使用代理转发的地址
+	UserAgent string // 升级请求的 User-Agent
+	DeviceID  string // 客户端设备 ID（可选），取自 X-Device-ID 请求头或 device_id 查询参数
+}
+
+// CallbackOptions 回调选项
+type CallbackOptions struct {
+	Priority int  // 优先级，数值大的先调用，相同优先级按注册顺序
+	Sync     bool // 同步调用：在触发事件的协程中执行，执行完才继续后续处理和回调；默认在后台协程中异步执行
+}
+
+// EventType 事件类型
+type EventType string
+
+// 事件类型
+const (
+	EventMessageReceived  EventType = "message_received"  // 收到客户端单聊或群聊消息（已持久化），UserID 为发送者，与 OnMessage 相同
+	EventMessageSaved     EventType = "message_saved"     // 消息已持久化（含 SendMessage 发送的消息），只有 Message
+	EventMessageDelivered EventType = "message_delivered" // 消息已推送到接收方在本节点的连接，UserID 为接收方
+	EventUserOnline       EventType = "user_online"       // 用户上线，只有 UserID，与 OnUserOnline 相同
+	EventUserOffline      EventType = "user_offline"      // 用户下线，只有 UserID，与 OnUserOffline 相同
+	EventSessionUpdated   EventType = "session_updated"   // 单聊会话的最后消息已更新，发送方和接收方各触发一次，UserID 为会话所属用户，Session 不含未读数
+	EventGroupCreated     EventType = "group_created"     // 群组已创建，UserID 为群主，Group 有值
+	EventMessageReported  EventType = "message_reported"  // 新的消息举报，UserID 为举报人，Report 有值，与 OnMessageReported 相同
+	EventSupportAssigned  EventType = "support_assigned"  // 客服会话分配或转接给客服（含转为待分配），UserID 为客户，Support 有值
+	EventSupportClosed    EventType = "support_closed"    // 客服会话已结束，UserID 为客户，Support 有值
+)
+
+// EventTypes 所有事件类型
+var EventTypes = []EventType{
+	EventMessageReceived, EventMessageSaved, EventMessageDelivered, EventUserOnline,
+	EventUserOffline, EventSessionUpdated, EventGroupCreated, EventMessageReported,
+	EventSupportAssigned, EventSupportClosed,
+}
+
+// Event 事件，按 Type 填充对应字段，同一事件的所有回调共用，回调中不能修改
+type Event struct {
+	Type    EventType            `json:"type"`              // 事件类型
+	Time    int64                `json:"time"`              // 事件时间戳（毫秒）
+	UserID  int64                `json:"user_id,omitempty"` // 相关用户 ID
+	Message *Message             `json:"message,omitempty"` // 相关消息
+	Session *Session             `json:"session,omitempty"` // 更新的会话
+	Group   *Group               `json:"group,omitempty"`   // 相关群组
+	Report  *MessageReport       `json:"report,omitempty"`  // 消息举报
+	Support *SupportConversation `json:"support,omitempty"` // 客服会话
+}
+
+// Subscription 消息订阅，Keywords 和 Match 同时设置时需都满足
+type Subscription struct {
+	Name     string                  // 订阅名称，原样返回到 SubscriptionMatch
+	Keywords []string                // 关键词（不区分大小写的子串），命中任一即满足，为空时不按关键词过滤
+	Match    func(msg *Message) bool // 自定义条件，为 nil 时不过滤，关键词命中后才调用
+}
+
+// SubscriptionMatch 订阅命中信息
+type SubscriptionMatch struct {
+	Subscription string   `json:"subscription"`       // 订阅名称
+	Message      *Message `json:"message"`            // 命中的消息
+	Keywords     []string `json:"keywords,omitempty"` // 命中的关键词，按在消息中首次出现的顺序
+}
+
+// Draft 会话草稿（多端同步）
+type Draft struct {
+	UserID      int64  `json:"user_id"`      // 用户 ID
+	TargetID    int64  `json:"target_id"`    // 对方用户 ID 或群组 ID
+	SessionType int    `json:"session_type"` // 会话类型（1:单聊 2:群聊）
+	Content     string `json:"content"`      // 草稿内容（为空表示清除草稿）
+	UpdatedAt   int64  `json:"updated_at"`   // 更新时间戳（毫秒）
+}
+
+// Presence 用户在线状态
+type Presence struct {
+	UserID    int64  `json:"user_id"`        // 用户 ID
+	Status    string `json:"status"`         // 在线状态（online、busy、away、invisible、offline）
+	Text      string `json:"text,omitempty"` // 状态文字，如 "开会中"
+	UpdatedAt int64  `json:"updated_at"`     // 设置时间戳（毫秒），未设置过为 0
+}
+
+// AwayStatus 离开状态，离开期间收到单聊消息时自动回复发送者
+type AwayStatus struct {
+	UserID    int64  `json:"user_id"`    // 用户 ID
+	Message   string `json:"message"`    // 自动回复内容
+	UpdatedAt int64  `json:"updated_at"` // 设置时间戳（毫秒）
+}
+
+// DNDSchedule 免打扰时段
+// 免打扰期间消息正常投递，仅抑制离线推送
+type DNDSchedule struct {
+	UserID    int64  `json:"user_id"`    // 用户 ID
+	Enabled   bool   `json:"enabled"`    // 是否启用
+	StartTime string `json:"start_time"` // 开始时间（HH:MM）
+	EndTime   string `json:"end_time"`   // 结束时间（HH:MM），早于开始时间表示跨天
+	Timezone  string `json:"timezone"`   // 时区（IANA 名称，如 Asia/Shanghai），为空使用服务器时区
+}
+
+// NotificationPreference 会话通知偏好
+type NotificationPreference struct {
+	UserID      int64 `json:"user_id"`      // 用户 ID
+	TargetID    int64 `json:"target_id"`    // 对方用户 ID 或群组 ID
+	SessionType int   `json:"session_type"` // 会话类型（1:单聊 2:群聊）
+	Level       int   `json:"level"`        // 通知级别（0:全部 1:仅@我 2:不通知）
+	MuteUntil   int64 `json:"mute_until"`   // 通知级别生效截止时间戳（毫秒），0 表示永久；到期后恢复全部通知
+}
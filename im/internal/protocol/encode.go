@@ -0,0 +1,171 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strconv"
+	"unicode/utf8"
+)
+
+// 热点推送帧（聊天消息、ACK、状态更新）的手写编码，输出与 Encode 一致
+// 按内容长度预先分配缓冲，每帧只分配一次，避免反射和中间结构体
+
+// EncodePush 编码推送给接收方的聊天消息帧
+func EncodePush(push *WSPushMessage) []byte {
+	buf := make([]byte, 0, 256+2*len(push.MsgID)+len(push.Content)+len(push.FileID))
+	buf = appendHeader(buf, WSMsgTypeChatMsg, push.MsgID)
+	buf = appendStringField(buf, `{"msg_id":`, push.MsgID)
+	buf = appendIntField(buf, `,"from_user_id":`, push.FromUserID)
+	buf = appendStringField(buf, `,"content":`, push.Content)
+	buf = appendIntField(buf, `,"msg_type":`, int64(push.MsgType))
+	buf = appendStringField(buf, `,"file_id":`, push.FileID)
+	buf = appendIntField(buf, `,"status":`, int64(push.Status))
+	buf = appendIntField(buf, `,"client_time":`, push.ClientTime)
+	buf = appendIntField(buf, `,"server_time":`, push.ServerTime)
+	if push.GroupID != 0 {
+		buf = appendIntField(buf, `,"group_id":`, push.GroupID)
+	}
+	return appendTrailer(buf, push.ServerTime)
+}
+
+// EncodeAck 编码回复发送方的 ACK 帧
+func EncodeAck(ack *WSAckMessage, timestamp int64) []byte {
+	buf := make([]byte, 0, 160+2*len(ack.MsgID)+len(ack.Error)+len(ack.Code))
+	buf = appendHeader(buf, WSMsgTypeAck, ack.MsgID)
+	buf = appendStringField(buf, `{"msg_id":`, ack.MsgID)
+	buf = appendIntField(buf, `,"status":`, int64(ack.Status))
+	buf = appendIntField(buf, `,"server_time":`, ack.ServerTime)
+	if ack.Error != "" {
+		buf = appendStringField(buf, `,"error":`, ack.Error)
+	}
+	if ack.Code != "" {
+		buf = appendStringField(buf, `,"code":`, ack.Code)
+	}
+	if ack.RetryAfter != 0 {
+		buf = appendIntField(buf, `,"retry_after":`, ack.RetryAfter)
+	}
+	return appendTrailer(buf, timestamp)
+}
+
+// EncodeStatusUpdate 编码通知发送方的状态更新帧
+func EncodeStatusUpdate(update *WSStatusUpdate) []byte {
+	size := 128 + 2*len(update.MsgID)
+	for _, msgID := range update.MsgIDs {
+		size += len(msgID) + 3
+	}
+	buf := make([]byte, 0, size)
+	buf = appendHeader(buf, WSMsgTypeStatusUpdate, update.MsgID)
+	buf = appendStringField(buf, `{"msg_id":`, update.MsgID)
+	if len(update.MsgIDs) > 0 {
+		buf = append(buf, `,"msg_ids":[`...)
+		for i, msgID := range update.MsgIDs {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendString(buf, msgID)
+		}
+		buf = append(buf, ']')
+	}
+	buf = appendIntField(buf, `,"status":`, int64(update.Status))
+	buf = appendIntField(buf, `,"update_time":`, update.UpdateTime)
+	return appendTrailer(buf, update.UpdateTime)
+}
+
+// Encode 编码消息帧，data 为 nil 时 data 字段为 null
+// 热点帧使用 EncodePush、EncodeAck、EncodeStatusUpdate
+func Encode(msgType, msgID string, timestamp int64, data interface{}) ([]byte, error) {
+	msg := &WSMessage{Type: msgType, MsgID: msgID, Timestamp: timestamp}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = raw
+	}
+	return json.Marshal(msg)
+}
+
+// DecodeData 将消息数据解码到 v，没有数据时 v 保持不变
+func (m *WSMessage) DecodeData(v interface{}) error {
+	if len(m.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.Data, v)
+}
+
+// appendHeader 写入帧头 {"type":...,"msg_id":...,"data":
+func appendHeader(buf []byte, msgType, msgID string) []byte {
+	buf = appendStringField(buf, `{"type":`, msgType)
+	buf = appendStringField(buf, `,"msg_id":`, msgID)
+	return append(buf, `,"data":`...)
+}
+
+// appendTrailer 写入帧尾 },"timestamp":...}
+func appendTrailer(buf []byte, timestamp int64) []byte {
+	buf = appendIntField(buf, `},"timestamp":`, timestamp)
+	return append(buf, '}')
+}
+
+// appendStringField 写入字段名和字符串值
+func appendStringField(buf []byte, name, value string) []byte {
+	return appendString(append(buf, name...), value)
+}
+
+// appendIntField 写入字段名和整数值
+func appendIntField(buf []byte, name string, value int64) []byte {
+	return strconv.AppendInt(append(buf, name...), value, 10)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendString 写入 JSON 字符串，转义规则与 encoding/json 一致（含 HTML 字符、U+2028/U+2029，非法 UTF-8 替换为 U+FFFD）
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				buf = append(buf, '\\', b)
+			case '\b':
+				buf = append(buf, '\\', 'b')
+			case '\f':
+				buf = append(buf, '\\', 'f')
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
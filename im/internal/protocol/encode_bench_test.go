@@ -0,0 +1,118 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// 热点路径编解码基准测试：对比 json.Marshal 与手写编码、Data 为 interface{}（先编码再解码）与 RawMessage（直接解码）
+//
+//	go test ./internal/protocol -run '^$' -bench . -benchmem
+
+// benchPush 基准测试用的推送消息，内容 64 字节
+func benchPush() *WSPushMessage {
+	return &WSPushMessage{
+		MsgID:      "8f0c2a3e-6b1d-4c5e-9f7a-1b2c3d4e5f60",
+		FromUserID: 10001,
+		Content:    strings.Repeat("x", 64),
+		MsgType:    1,
+		Status:     2,
+		ClientTime: 1700000000000,
+		ServerTime: 1700000000123,
+	}
+}
+
+// TestEncodePush 手写编码与 Encode 输出一致，且每帧只分配一次
+func TestEncodePush(t *testing.T) {
+	push := benchPush()
+	for _, groupID := range []int64{0, 42} {
+		push.GroupID = groupID
+		want, err := Encode(WSMsgTypeChatMsg, push.MsgID, push.ServerTime, push)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := EncodePush(push); !bytes.Equal(got, want) {
+			t.Errorf("EncodePush(group_id=%d) = %s, want %s", groupID, got, want)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { EncodePush(push) }); allocs != 1 {
+		t.Errorf("EncodePush allocs = %v, want 1", allocs)
+	}
+}
+
+// TestEncodeAck 手写编码与 Encode 输出一致，且每帧只分配一次
+func TestEncodeAck(t *testing.T) {
+	ack := &WSAckMessage{MsgID: "m1", Status: 5, ServerTime: 1700000000123, Error: "rate limited", Code: "rate_limited", RetryAfter: 1000}
+	want, err := Encode(WSMsgTypeAck, ack.MsgID, ack.ServerTime, ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := EncodeAck(ack, ack.ServerTime); !bytes.Equal(got, want) {
+		t.Errorf("EncodeAck = %s, want %s", got, want)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { EncodeAck(ack, ack.ServerTime) }); allocs != 1 {
+		t.Errorf("EncodeAck allocs = %v, want 1", allocs)
+	}
+}
+
+func BenchmarkEncodePush(b *testing.B) {
+	push := benchPush()
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Encode(WSMsgTypeChatMsg, push.MsgID, push.ServerTime, push)
+		}
+	})
+	b.Run("append", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			EncodePush(push)
+		}
+	})
+}
+
+func BenchmarkEncodeAck(b *testing.B) {
+	ack := &WSAckMessage{MsgID: benchPush().MsgID, Status: 2, ServerTime: 1700000000123}
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Encode(WSMsgTypeAck, ack.MsgID, ack.ServerTime, ack)
+		}
+	})
+	b.Run("append", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			EncodeAck(ack, ack.ServerTime)
+		}
+	})
+}
+
+func BenchmarkDecodeChat(b *testing.B) {
+	push := benchPush()
+	inbound, _ := Encode(WSMsgTypeChatMsg, "", 0, &WSChatMessage{MsgID: push.MsgID, ToUserID: 10002, Content: push.Content, MsgType: 1})
+	b.Run("interface", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			// Data 为 interface{} 时需先编码再按类型解码
+			var wsMsg struct {
+				Type string      `json:"type"`
+				Data interface{} `json:"data"`
+			}
+			json.Unmarshal(inbound, &wsMsg)
+			data, _ := json.Marshal(wsMsg.Data)
+			var chatMsg WSChatMessage
+			json.Unmarshal(data, &chatMsg)
+		}
+	})
+	b.Run("raw", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var wsMsg WSMessage
+			json.Unmarshal(inbound, &wsMsg)
+			var chatMsg WSChatMessage
+			wsMsg.DecodeData(&chatMsg)
+		}
+	})
+}
@@ -1,5 +1,25 @@
 package protocol
 
+import "encoding/json"
+
+// 协议版本
+const (
+	ProtocolVersion    = 1 // 服务端当前协议版本
+	MinProtocolVersion = 1 // 服务端支持的最低协议版本
+)
+
+// 客户端能力
+const (
+	CapBinaryFraming = "binary_framing" // 二进制帧
+	CapBatchReceipts = "batch_receipts" // 批量回执（WSReceipt.MsgIDs）和批量状态更新（WSStatusUpdate.MsgIDs）
+	CapReactions     = "reactions"      // 消息表情回应
+)
+
+// SupportedCapabilities 服务端已支持的能力
+var SupportedCapabilities = []string{
+	CapBatchReceipts,
+}
+
 // WebSocket 消息类型
 const (
 	WSMsgTypePing             = "ping"              // 心跳请求
@@ -10,66 +30,151 @@ const (
 	WSMsgTypeStatusUpdate     = "status_update"     // 消息状态更新
 	WSMsgTypeDeliveredReceipt = "delivered_receipt" // 送达回执
 	WSMsgTypeReadReceipt      = "read_receipt"      // 已读回执
+	WSMsgTypeDraft            = "draft"             // 保存草稿
+	WSMsgTypeDraftSync        = "draft_sync"        // 草稿同步（服务端推送）
+	WSMsgTypeHello            = "hello"             // 握手（客户端声明协议版本和能力）
+	WSMsgTypeHelloAck         = "hello_ack"         // 握手响应（服务端协商结果）
+	WSMsgTypeShutdown         = "shutdown"          // 节点即将关闭（服务端推送），客户端应重新连接
+	WSMsgTypeConversationRead = "conversation_read" // 会话已读（客户端上报已读水位 / 服务端通知对方）
+	WSMsgTypeConnectInfo      = "connect_info"      // 连接信息（服务端推送），含所在节点和亲和标识
+	WSMsgTypeKicked           = "kicked"            // 被踢下线（服务端推送），客户端不应自动重连
+	WSMsgTypePresence         = "presence"          // 设置在线状态（online、busy、away、invisible）
 )
 
 // WSMessage WebSocket 消息包装
+// Data 保留原始 JSON，按 Type 通过 DecodeData 解码一次；发送时通过 Encode 构造
 type WSMessage struct {
-	Type      string      `json:"type"`       // 消息类型
-	MsgID     string      `json:"msg_id"`     // 消息 ID
-	Data      interface{} `json:"data"`       // 消息数据
-	Timestamp int64       `json:"timestamp"`  // 时间戳
+	Type      string          `json:"type"`      // 消息类型
+	MsgID     string          `json:"msg_id"`    // 消息 ID
+	Data      json.RawMessage `json:"data"`      // 消息数据（原始 JSON）
+	Timestamp int64           `json:"timestamp"` // 时间戳
 }
 
 // WSChatMessage 客户端发送的聊天消息
 type WSChatMessage struct {
-	MsgID      string `json:"msg_id"`       // 消息 ID（客户端生成 UUID）
-	ToUserID   int64  `json:"to_user_id"`   // 接收者用户 ID
-	Content    string `json:"content"`      // 消息内容
-	MsgType    int    `json:"msg_type"`     // 消息类型
-	FileID     string `json:"file_id"`      // 文件ID（多媒体消息）
-	ClientTime int64  `json:"client_time"`  // 客户端时间戳
+	MsgID      string  `json:"msg_id"`                // 消息 ID（客户端生成 UUID）
+	ToUserID   int64   `json:"to_user_id"`            // 接收者用户 ID
+	Content    string  `json:"content"`               // 消息内容
+	MsgType    int     `json:"msg_type"`              // 消息类型
+	FileID     string  `json:"file_id"`               // 文件ID（多媒体消息）
+	ClientTime int64   `json:"client_time"`           // 客户端时间戳
+	AtUserIDs  []int64 `json:"at_user_ids,omitempty"` // @ 的用户 ID 列表
 }
 
 // WSGroupMessage 客户端发送的群聊消息
 type WSGroupMessage struct {
-	MsgID      string `json:"msg_id"`       // 消息 ID
-	GroupID    int64  `json:"group_id"`     // 群组 ID
-	Content    string `json:"content"`      // 消息内容
-	MsgType    int    `json:"msg_type"`     // 消息类型
-	FileID     string `json:"file_id"`      // 文件ID（多媒体消息）
-	ClientTime int64  `json:"client_time"`  // 客户端时间戳
+	MsgID      string  `json:"msg_id"`                // 消息 ID
+	GroupID    int64   `json:"group_id"`              // 群组 ID
+	Content    string  `json:"content"`               // 消息内容
+	MsgType    int     `json:"msg_type"`              // 消息类型
+	FileID     string  `json:"file_id"`               // 文件ID（多媒体消息）
+	ClientTime int64   `json:"client_time"`           // 客户端时间戳
+	AtUserIDs  []int64 `json:"at_user_ids,omitempty"` // @ 的用户 ID 列表
 }
 
 // WSAckMessage 服务端发送的 ACK 确认
 type WSAckMessage struct {
-	MsgID      string `json:"msg_id"`       // 消息 ID
-	Status     int    `json:"status"`       // 消息状态
-	ServerTime int64  `json:"server_time"`  // 服务端时间戳
-	Error      string `json:"error,omitempty"` // 错误信息
+	MsgID      string `json:"msg_id"`                // 消息 ID
+	Status     int    `json:"status"`                // 消息状态
+	ServerTime int64  `json:"server_time"`           // 服务端时间戳
+	Error      string `json:"error,omitempty"`       // 错误信息
+	Code       string `json:"code,omitempty"`        // 拒绝原因（如群组策略的 slow_mode）
+	RetryAfter int64  `json:"retry_after,omitempty"` // 多久后可以重试（毫秒）
 }
 
 // WSPushMessage 服务端推送的消息
 type WSPushMessage struct {
-	MsgID      string `json:"msg_id"`       // 消息 ID
-	FromUserID int64  `json:"from_user_id"` // 发送者用户 ID
-	Content    string `json:"content"`      // 消息内容
-	MsgType    int    `json:"msg_type"`     // 消息类型
-	FileID     string `json:"file_id"`      // 文件ID（多媒体消息）
-	Status     int    `json:"status"`       // 消息状态
-	ClientTime int64  `json:"client_time"`  // 发送方的时间戳
-	ServerTime int64  `json:"server_time"`  // 服务端时间戳
+	MsgID      string `json:"msg_id"`             // 消息 ID
+	FromUserID int64  `json:"from_user_id"`       // 发送者用户 ID
+	Content    string `json:"content"`            // 消息内容
+	MsgType    int    `json:"msg_type"`           // 消息类型
+	FileID     string `json:"file_id"`            // 文件ID（多媒体消息）
+	Status     int    `json:"status"`             // 消息状态
+	ClientTime int64  `json:"client_time"`        // 发送方的时间戳
+	ServerTime int64  `json:"server_time"`        // 服务端时间戳，同一会话内单调不减，可用于排序
+	GroupID    int64  `json:"group_id,omitempty"` // 群组 ID，单聊消息为空
 }
 
 // WSStatusUpdate 消息状态更新
 type WSStatusUpdate struct {
-	MsgID      string `json:"msg_id"`       // 消息 ID
-	Status     int    `json:"status"`       // 新状态
-	UpdateTime int64  `json:"update_time"`  // 更新时间戳
+	MsgID      string   `json:"msg_id"`            // 消息 ID
+	MsgIDs     []string `json:"msg_ids,omitempty"` // 批量消息 ID，状态和时间相同（需协商 batch_receipts 能力）
+	Status     int      `json:"status"`            // 新状态
+	UpdateTime int64    `json:"update_time"`       // 更新时间戳
 }
 
 // WSReceipt 回执（送达/已读）
 type WSReceipt struct {
-	MsgID string `json:"msg_id"` // 消息 ID
-	Type  string `json:"type"`   // 回执类型（"delivered" 或 "read"）
-	Time  int64  `json:"time"`   // 时间戳
+	MsgID  string   `json:"msg_id"`            // 消息 ID
+	MsgIDs []string `json:"msg_ids,omitempty"` // 批量消息 ID（需协商 batch_receipts 能力）
+	Type   string   `json:"type"`              // 回执类型（"delivered" 或 "read"）
+	Time   int64    `json:"time"`              // 时间戳
+}
+
+// MaxReceiptMsgIDs 一条回执最多包含的消息 ID 数，超出时整条回执被拒绝
+const MaxReceiptMsgIDs = 100
+
+// AllMsgIDs 返回回执涉及的所有消息 ID
+func (r *WSReceipt) AllMsgIDs() []string {
+	if r.MsgID == "" {
+		return r.MsgIDs
+	}
+	return append([]string{r.MsgID}, r.MsgIDs...)
+}
+
+// WSConversationRead 会话已读
+// 客户端上报时 TargetID 为对方，ReadTime 之前（含）对方发来的消息全部已读，为 0 表示全部；
+// 服务端通知对方时 TargetID 为已读方，ReadTime 为其已读水位
+type WSConversationRead struct {
+	TargetID int64 `json:"target_id"` // 会话对方用户 ID
+	ReadTime int64 `json:"read_time"` // 已读水位（服务端时间戳，毫秒）
+}
+
+// WSConnectInfo 连接建立后服务端推送的连接信息
+// 客户端重连时通过 AffinityCookie 指定的 Cookie 或 affinity 查询参数带回 AffinityToken，
+// 由负载均衡 / Ingress 按其路由回同一节点
+type WSConnectInfo struct {
+	UserID         int64  `json:"user_id"`                   // 当前连接的用户 ID，访客连接为临时分配的负数 ID
+	ServerID       string `json:"server_id"`                 // 所在节点 ID
+	AffinityToken  string `json:"affinity_token"`            // 节点亲和标识
+	AffinityCookie string `json:"affinity_cookie,omitempty"` // 亲和 Cookie 名（已在升级响应中写入）
+}
+
+// WSHello 客户端握手
+type WSHello struct {
+	Version      int      `json:"version"`      // 客户端协议版本
+	Capabilities []string `json:"capabilities"` // 客户端支持的能力
+}
+
+// WSHelloAck 服务端握手响应
+type WSHelloAck struct {
+	Version      int      `json:"version"`         // 协商后的协议版本
+	MinVersion   int      `json:"min_version"`     // 服务端支持的最低协议版本
+	MaxVersion   int      `json:"max_version"`     // 服务端支持的最高协议版本
+	Capabilities []string `json:"capabilities"`    // 协商后双方均支持的能力
+	Error        string   `json:"error,omitempty"` // 错误信息（版本不兼容时）
+}
+
+// WSDraft 草稿（客户端保存 / 服务端同步）
+type WSDraft struct {
+	TargetID    int64  `json:"target_id"`    // 对方用户 ID 或群组 ID
+	SessionType int    `json:"session_type"` // 会话类型（1:单聊 2:群聊）
+	Content     string `json:"content"`      // 草稿内容（为空表示清除）
+	UpdatedAt   int64  `json:"updated_at"`   // 更新时间戳
+}
+
+// WSPresence 设置在线状态
+type WSPresence struct {
+	Status string `json:"status"` // 在线状态（online、busy、away、invisible）
+	Text   string `json:"text"`   // 状态文字（可选）
+}
+
+// WSShutdown 节点关闭通知，之后服务端发送完缓冲的消息并关闭连接
+type WSShutdown struct {
+	Reason string `json:"reason"` // 关闭原因
+}
+
+// WSKicked 踢下线通知，之后服务端发送完缓冲的消息并关闭连接（关闭码 1008）
+type WSKicked struct {
+	Reason string `json:"reason"` // 原因（admin、banned、logged_in_elsewhere）
 }
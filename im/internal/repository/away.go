@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// DBAwayStatus 离开状态数据库模型
+type DBAwayStatus struct {
+	UserID    int64  `gorm:"primaryKey;autoIncrement:false"`
+	Message   string `gorm:"type:varchar(500);not null"`
+	UpdatedAt int64  `gorm:"autoUpdateTime:milli"`
+}
+
+func (DBAwayStatus) TableName() string {
+	return "im_away_statuses"
+}
+
+// AwayRepository 离开状态仓库
+type AwayRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewAwayRepository 创建离开状态仓库
+func NewAwayRepository(db *gorm.DB, namer *TableNamer) *AwayRepository {
+	return &AwayRepository{db: db, namer: namer}
+}
+
+// statuses 离开状态表
+func (r *AwayRepository) statuses(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBAwayStatus{}.TableName()))
+}
+
+// InitTables 初始化数据库表（v10 迁移）
+func (r *AwayRepository) InitTables(ctx context.Context) error {
+	return r.statuses(ctx).AutoMigrate(&DBAwayStatus{})
+}
+
+// Save 保存离开状态（如果不存在则创建）
+func (r *AwayRepository) Save(ctx context.Context, status *model.AwayStatus) error {
+	dbStatus := &DBAwayStatus{
+		UserID:  status.UserID,
+		Message: status.Message,
+	}
+	if err := r.statuses(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"message", "updated_at"}),
+	}).Create(dbStatus).Error; err != nil {
+		return err
+	}
+	status.UpdatedAt = dbStatus.UpdatedAt
+	return nil
+}
+
+// Get 获取用户的离开状态
+func (r *AwayRepository) Get(ctx context.Context, userID int64) (*model.AwayStatus, error) {
+	var dbStatus DBAwayStatus
+	if err := r.statuses(ctx).Where("user_id = ?", userID).First(&dbStatus).Error; err != nil {
+		return nil, err
+	}
+
+	return &model.AwayStatus{
+		UserID:    dbStatus.UserID,
+		Message:   dbStatus.Message,
+		UpdatedAt: dbStatus.UpdatedAt,
+	}, nil
+}
+
+// Delete 删除用户的离开状态
+func (r *AwayRepository) Delete(ctx context.Context, userID int64) error {
+	return r.statuses(ctx).Where("user_id = ?", userID).Delete(&DBAwayStatus{}).Error
+}
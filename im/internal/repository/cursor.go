@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// encodeCursor 将最后一条记录的排序键编码为不透明的分页游标
+func encodeCursor(keys ...int64) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = strconv.FormatInt(key, 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, ":")))
+}
+
+// decodeCursor 解析分页游标，n 为排序键个数，格式错误返回参数错误
+func decodeCursor(cursor string, n int) ([]int64, error) {
+	invalid := model.NewParamError("cursor", "invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != n {
+		return nil, invalid
+	}
+	keys := make([]int64, n)
+	for i, part := range parts {
+		if keys[i], err = strconv.ParseInt(part, 10, 64); err != nil {
+			return nil, invalid
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// DBDNDSchedule 免打扰时段数据库模型
+type DBDNDSchedule struct {
+	UserID    int64  `gorm:"primaryKey;autoIncrement:false"`
+	Enabled   bool   `gorm:"not null;default:false"`
+	StartTime string `gorm:"type:varchar(5);not null"`
+	EndTime   string `gorm:"type:varchar(5);not null"`
+	Timezone  string `gorm:"type:varchar(64)"`
+	UpdatedAt int64  `gorm:"autoUpdateTime:milli"`
+}
+
+func (DBDNDSchedule) TableName() string {
+	return "im_dnd_schedules"
+}
+
+// DNDRepository 免打扰仓库
+type DNDRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewDNDRepository 创建免打扰仓库
+func NewDNDRepository(db *gorm.DB, namer *TableNamer) *DNDRepository {
+	return &DNDRepository{db: db, namer: namer}
+}
+
+// schedules 免打扰时段表
+func (r *DNDRepository) schedules(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBDNDSchedule{}.TableName()))
+}
+
+// InitTables 初始化数据库表
+func (r *DNDRepository) InitTables(ctx context.Context) error {
+	return r.schedules(ctx).AutoMigrate(&DBDNDSchedule{})
+}
+
+// Save 保存免打扰时段（如果不存在则创建）
+func (r *DNDRepository) Save(ctx context.Context, schedule *model.DNDSchedule) error {
+	dbSchedule := &DBDNDSchedule{
+		UserID:    schedule.UserID,
+		Enabled:   schedule.Enabled,
+		StartTime: schedule.StartTime,
+		EndTime:   schedule.EndTime,
+		Timezone:  schedule.Timezone,
+	}
+
+	return r.schedules(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "start_time", "end_time", "timezone", "updated_at"}),
+	}).Create(dbSchedule).Error
+}
+
+// Get 获取用户的免打扰时段
+func (r *DNDRepository) Get(ctx context.Context, userID int64) (*model.DNDSchedule, error) {
+	var dbSchedule DBDNDSchedule
+	if err := r.schedules(ctx).Where("user_id = ?", userID).First(&dbSchedule).Error; err != nil {
+		return nil, err
+	}
+
+	return &model.DNDSchedule{
+		UserID:    dbSchedule.UserID,
+		Enabled:   dbSchedule.Enabled,
+		StartTime: dbSchedule.StartTime,
+		EndTime:   dbSchedule.EndTime,
+		Timezone:  dbSchedule.Timezone,
+	}, nil
+}
+
+// Delete 删除用户的免打扰时段
+func (r *DNDRepository) Delete(ctx context.Context, userID int64) error {
+	return r.schedules(ctx).Where("user_id = ?", userID).Delete(&DBDNDSchedule{}).Error
+}
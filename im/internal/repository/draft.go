@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// DBDraft 草稿数据库模型
+type DBDraft struct {
+	UserID      int64  `gorm:"primaryKey"`
+	TargetID    int64  `gorm:"primaryKey"`
+	SessionType int    `gorm:"primaryKey;type:tinyint;default:1"`
+	Content     string `gorm:"type:text"`
+	UpdatedAt   int64  `gorm:"type:bigint;not null"`
+}
+
+func (DBDraft) TableName() string {
+	return "im_drafts"
+}
+
+// DraftRepository 草稿仓库
+type DraftRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewDraftRepository 创建草稿仓库
+func NewDraftRepository(db *gorm.DB, namer *TableNamer) *DraftRepository {
+	return &DraftRepository{db: db, namer: namer}
+}
+
+// drafts 草稿表
+func (r *DraftRepository) drafts(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBDraft{}.TableName()))
+}
+
+// InitTables 初始化数据库表
+func (r *DraftRepository) InitTables(ctx context.Context) error {
+	return r.drafts(ctx).AutoMigrate(&DBDraft{})
+}
+
+// Save 保存草稿（如果不存在则创建）
+func (r *DraftRepository) Save(ctx context.Context, draft *model.Draft) error {
+	dbDraft := &DBDraft{
+		UserID:      draft.UserID,
+		TargetID:    draft.TargetID,
+		SessionType: draft.SessionType,
+		Content:     draft.Content,
+		UpdatedAt:   draft.UpdatedAt,
+	}
+
+	return r.drafts(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "user_id"},
+			{Name: "target_id"},
+			{Name: "session_type"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "updated_at"}),
+	}).Create(dbDraft).Error
+}
+
+// Delete 删除草稿，返回是否删除
+func (r *DraftRepository) Delete(ctx context.Context, userID, targetID int64, sessionType int) (bool, error) {
+	result := r.drafts(ctx).Where("user_id = ? AND target_id = ? AND session_type = ?", userID, targetID, sessionType).
+		Delete(&DBDraft{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteByUser 删除用户的全部草稿
+func (r *DraftRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	return r.drafts(ctx).Where("user_id = ?", userID).Delete(&DBDraft{}).Error
+}
+
+// GetUserDrafts 获取用户的所有草稿
+func (r *DraftRepository) GetUserDrafts(ctx context.Context, userID int64) ([]*model.Draft, error) {
+	var dbDrafts []DBDraft
+
+	if err := r.drafts(ctx).Where("user_id = ?", userID).
+		Order("updated_at DESC").
+		Find(&dbDrafts).Error; err != nil {
+		return nil, err
+	}
+
+	drafts := make([]*model.Draft, len(dbDrafts))
+	for i, d := range dbDrafts {
+		drafts[i] = &model.Draft{
+			UserID:      d.UserID,
+			TargetID:    d.TargetID,
+			SessionType: d.SessionType,
+			Content:     d.Content,
+			UpdatedAt:   d.UpdatedAt,
+		}
+	}
+
+	return drafts, nil
+}
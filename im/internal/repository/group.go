@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"gorm.io/gorm"
 
 	"github.com/bbadbeef/go-base/im/internal/model"
@@ -14,6 +16,12 @@ type DBGroup struct {
 	AvatarURL string `gorm:"type:varchar(255)"`
 	CreatedAt int64  `gorm:"autoCreateTime:milli"`
 	UpdatedAt int64  `gorm:"autoUpdateTime:milli"`
+
+	// 防刷屏策略
+	SlowMode     int  `gorm:"not null;default:0"`
+	BlockLinks   bool `gorm:"not null;default:false"`
+	BlockImages  bool `gorm:"not null;default:false"`
+	NewcomerMute int  `gorm:"not null;default:0"`
 }
 
 func (DBGroup) TableName() string {
@@ -23,8 +31,8 @@ func (DBGroup) TableName() string {
 // DBGroupMember 群成员数据库模型
 type DBGroupMember struct {
 	ID       int64 `gorm:"primaryKey;autoIncrement"`
-	GroupID  int64 `gorm:"uniqueIndex:uk_group_user;index:idx_group;not null"`
-	UserID   int64 `gorm:"uniqueIndex:uk_group_user;index:idx_user;not null"`
+	GroupID  int64 `gorm:"uniqueIndex:uk_group_user;index:idx_member_group;not null"`
+	UserID   int64 `gorm:"uniqueIndex:uk_group_user;index:idx_member_user;not null"`
 	Role     int   `gorm:"type:tinyint;default:0"`
 	JoinedAt int64 `gorm:"autoCreateTime:milli"`
 }
@@ -35,31 +43,45 @@ func (DBGroupMember) TableName() string {
 
 // GroupRepository 群组仓库
 type GroupRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	namer *TableNamer
 }
 
 // NewGroupRepository 创建群组仓库
-func NewGroupRepository(db *gorm.DB) *GroupRepository {
-	return &GroupRepository{db: db}
+func NewGroupRepository(db *gorm.DB, namer *TableNamer) *GroupRepository {
+	return &GroupRepository{db: db, namer: namer}
+}
+
+// groups 群组表
+func (r *GroupRepository) groups(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBGroup{}.TableName()))
+}
+
+// members 群成员表
+func (r *GroupRepository) members(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBGroupMember{}.TableName()))
 }
 
 // InitTables 初始化数据库表
-func (r *GroupRepository) InitTables() error {
-	if err := r.db.AutoMigrate(&DBGroup{}, &DBGroupMember{}); err != nil {
+func (r *GroupRepository) InitTables(ctx context.Context) error {
+	if err := r.groups(ctx).AutoMigrate(&DBGroup{}); err != nil {
+		return err
+	}
+	if err := r.members(ctx).AutoMigrate(&DBGroupMember{}); err != nil {
 		return err
 	}
 	return nil
 }
 
 // CreateGroup 创建群组
-func (r *GroupRepository) CreateGroup(group *model.Group) error {
+func (r *GroupRepository) CreateGroup(ctx context.Context, group *model.Group) error {
 	dbGroup := &DBGroup{
 		GroupName: group.GroupName,
 		OwnerID:   group.OwnerID,
 		AvatarURL: group.AvatarURL,
 	}
 
-	if err := r.db.Create(dbGroup).Error; err != nil {
+	if err := r.groups(ctx).Create(dbGroup).Error; err != nil {
 		return err
 	}
 
@@ -68,42 +90,153 @@ func (r *GroupRepository) CreateGroup(group *model.Group) error {
 	return nil
 }
 
+// CreateGroupWithMembers 在事务中创建群组并添加成员，成员的 GroupID 取创建的群组
+func (r *GroupRepository) CreateGroupWithMembers(ctx context.Context, group *model.Group, members []*model.GroupMember) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := NewGroupRepository(tx, r.namer)
+		if err := txRepo.CreateGroup(ctx, group); err != nil {
+			return err
+		}
+		for _, m := range members {
+			m.GroupID = group.GroupID
+			if err := txRepo.AddMember(ctx, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // GetGroup 获取群组信息
-func (r *GroupRepository) GetGroup(groupID int64) (*model.Group, error) {
+func (r *GroupRepository) GetGroup(ctx context.Context, groupID int64) (*model.Group, error) {
 	var dbGroup DBGroup
-	if err := r.db.First(&dbGroup, groupID).Error; err != nil {
+	if err := r.groups(ctx).First(&dbGroup, groupID).Error; err != nil {
 		return nil, err
 	}
 
-	return &model.Group{
-		GroupID:   dbGroup.GroupID,
-		GroupName: dbGroup.GroupName,
-		OwnerID:   dbGroup.OwnerID,
-		AvatarURL: dbGroup.AvatarURL,
-		CreatedAt: dbGroup.CreatedAt,
+	return dbGroup.toModel(), nil
+}
+
+// UpdateGroup 更新群组名称和头像
+func (r *GroupRepository) UpdateGroup(ctx context.Context, group *model.Group) error {
+	return r.groups(ctx).Where("group_id = ?", group.GroupID).Updates(map[string]interface{}{
+		"group_name": group.GroupName,
+		"avatar_url": group.AvatarURL,
+	}).Error
+}
+
+// AddPolicyColumns 添加群组防刷屏策略字段（v8 迁移）
+func (r *GroupRepository) AddPolicyColumns(ctx context.Context) error {
+	migrator := r.groups(ctx).Migrator()
+	for _, field := range []string{"SlowMode", "BlockLinks", "BlockImages", "NewcomerMute"} {
+		if migrator.HasColumn(&DBGroup{}, field) {
+			continue
+		}
+		if err := migrator.AddColumn(&DBGroup{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPolicy 获取群组防刷屏策略
+func (r *GroupRepository) GetPolicy(ctx context.Context, groupID int64) (*model.GroupPolicy, error) {
+	var dbGroup DBGroup
+	if err := r.groups(ctx).Select("group_id", "slow_mode", "block_links", "block_images", "newcomer_mute").
+		First(&dbGroup, groupID).Error; err != nil {
+		return nil, err
+	}
+	return &model.GroupPolicy{
+		GroupID:      dbGroup.GroupID,
+		SlowMode:     dbGroup.SlowMode,
+		BlockLinks:   dbGroup.BlockLinks,
+		BlockImages:  dbGroup.BlockImages,
+		NewcomerMute: dbGroup.NewcomerMute,
 	}, nil
 }
 
+// UpdatePolicy 更新群组防刷屏策略
+func (r *GroupRepository) UpdatePolicy(ctx context.Context, policy *model.GroupPolicy) error {
+	return r.groups(ctx).Where("group_id = ?", policy.GroupID).Updates(map[string]interface{}{
+		"slow_mode":     policy.SlowMode,
+		"block_links":   policy.BlockLinks,
+		"block_images":  policy.BlockImages,
+		"newcomer_mute": policy.NewcomerMute,
+	}).Error
+}
+
+// DeleteGroup 删除群组及其全部成员
+func (r *GroupRepository) DeleteGroup(ctx context.Context, groupID int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := NewGroupRepository(tx, r.namer)
+		if err := txRepo.members(ctx).Where("group_id = ?", groupID).Delete(&DBGroupMember{}).Error; err != nil {
+			return err
+		}
+		return txRepo.groups(ctx).Where("group_id = ?", groupID).Delete(&DBGroup{}).Error
+	})
+}
+
+// GetUserGroups 获取用户加入的群组，按加入时间倒序
+func (r *GroupRepository) GetUserGroups(ctx context.Context, userID int64) ([]*model.Group, error) {
+	var groupIDs []int64
+	if err := r.members(ctx).Where("user_id = ?", userID).
+		Order("joined_at DESC").
+		Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(groupIDs) == 0 {
+		return []*model.Group{}, nil
+	}
+
+	var dbGroups []DBGroup
+	if err := r.groups(ctx).Where("group_id IN ?", groupIDs).Find(&dbGroups).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*model.Group, len(dbGroups))
+	for i := range dbGroups {
+		byID[dbGroups[i].GroupID] = dbGroups[i].toModel()
+	}
+
+	groups := make([]*model.Group, 0, len(dbGroups))
+	for _, id := range groupIDs {
+		if g, ok := byID[id]; ok {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// toModel 转换为群组模型
+func (g *DBGroup) toModel() *model.Group {
+	return &model.Group{
+		GroupID:   g.GroupID,
+		GroupName: g.GroupName,
+		OwnerID:   g.OwnerID,
+		AvatarURL: g.AvatarURL,
+		CreatedAt: g.CreatedAt,
+	}
+}
+
 // AddMember 添加群成员
-func (r *GroupRepository) AddMember(member *model.GroupMember) error {
+func (r *GroupRepository) AddMember(ctx context.Context, member *model.GroupMember) error {
 	dbMember := &DBGroupMember{
 		GroupID: member.GroupID,
 		UserID:  member.UserID,
 		Role:    member.Role,
 	}
-	return r.db.Create(dbMember).Error
+	return r.members(ctx).Create(dbMember).Error
 }
 
 // RemoveMember 移除群成员
-func (r *GroupRepository) RemoveMember(groupID, userID int64) error {
-	return r.db.Where("group_id = ? AND user_id = ?", groupID, userID).
+func (r *GroupRepository) RemoveMember(ctx context.Context, groupID, userID int64) error {
+	return r.members(ctx).Where("group_id = ? AND user_id = ?", groupID, userID).
 		Delete(&DBGroupMember{}).Error
 }
 
 // GetMembers 获取群成员列表
-func (r *GroupRepository) GetMembers(groupID int64) ([]*model.GroupMember, error) {
+func (r *GroupRepository) GetMembers(ctx context.Context, groupID int64) ([]*model.GroupMember, error) {
 	var dbMembers []DBGroupMember
-	if err := r.db.Where("group_id = ?", groupID).Find(&dbMembers).Error; err != nil {
+	if err := r.members(ctx).Where("group_id = ?", groupID).Find(&dbMembers).Error; err != nil {
 		return nil, err
 	}
 
@@ -120,10 +253,24 @@ func (r *GroupRepository) GetMembers(groupID int64) ([]*model.GroupMember, error
 	return members, nil
 }
 
+// GetMember 获取群成员
+func (r *GroupRepository) GetMember(ctx context.Context, groupID, userID int64) (*model.GroupMember, error) {
+	var m DBGroupMember
+	if err := r.members(ctx).Where("group_id = ? AND user_id = ?", groupID, userID).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return &model.GroupMember{
+		GroupID:  m.GroupID,
+		UserID:   m.UserID,
+		Role:     m.Role,
+		JoinedAt: m.JoinedAt,
+	}, nil
+}
+
 // IsMember 检查用户是否是群成员
-func (r *GroupRepository) IsMember(groupID, userID int64) (bool, error) {
+func (r *GroupRepository) IsMember(ctx context.Context, groupID, userID int64) (bool, error) {
 	var count int64
-	if err := r.db.Model(&DBGroupMember{}).
+	if err := r.members(ctx).Model(&DBGroupMember{}).
 		Where("group_id = ? AND user_id = ?", groupID, userID).
 		Count(&count).Error; err != nil {
 		return false, err
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"strconv"
 	"strings"
 	
 	"gorm.io/gorm"
@@ -11,10 +13,12 @@ import (
 // DBMessage 消息数据库模型
 type DBMessage struct {
 	ID            int64  `gorm:"primaryKey;autoIncrement"`
+	TenantID      string `gorm:"type:varchar(64);not null;default:''"`
 	MsgID         string `gorm:"type:varchar(64);uniqueIndex:uk_msg_id;not null"`
 	FromUserID    int64  `gorm:"index:idx_from;not null"`
 	ToUserID      int64  `gorm:"index:idx_to;not null"`
 	GroupID       int64  `gorm:"index:idx_group;default:0"`
+	ConvID        string `gorm:"column:conversation_id;type:varchar(64);not null;default:''"` // 单聊会话 ID（有序用户对），群聊为空
 	Content       string `gorm:"type:text;not null"`
 	MsgType       int    `gorm:"type:tinyint;default:1"`
 	Status        int    `gorm:"type:tinyint;default:1"`
@@ -32,18 +36,47 @@ func (DBMessage) TableName() string {
 
 // MessageRepository 消息仓库
 type MessageRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	readDB *gorm.DB
+	namer  *TableNamer
+	tenant string
 }
 
 // NewMessageRepository 创建消息仓库
-func NewMessageRepository(db *gorm.DB) *MessageRepository {
-	return &MessageRepository{db: db}
+func NewMessageRepository(db *gorm.DB, namer *TableNamer) *MessageRepository {
+	return &MessageRepository{db: db, namer: namer}
+}
+
+// SetReadDB 设置只读库（从库），历史消息查询走只读库
+func (r *MessageRepository) SetReadDB(readDB *gorm.DB) {
+	r.readDB = readDB
+}
+
+// SetTenant 设置租户 ID，保存的消息归属该租户，查询和修改仅限该租户的消息
+func (r *MessageRepository) SetTenant(tenant string) {
+	r.tenant = tenant
+}
+
+// table 消息表，不限定租户，用于表结构迁移
+func (r *MessageRepository) table(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBMessage{}.TableName()))
+}
+
+// messages 消息表，限定当前租户
+func (r *MessageRepository) messages(ctx context.Context) *gorm.DB {
+	return r.table(ctx).Where("tenant_id = ?", r.tenant)
+}
+
+// readMessages 消息表（只读库，未设置时使用主库），限定当前租户
+func (r *MessageRepository) readMessages(ctx context.Context) *gorm.DB {
+	return readerOf(r.db, r.readDB).WithContext(ctx).Table(r.namer.Table(DBMessage{}.TableName())).
+		Where("tenant_id = ?", r.tenant)
 }
 
 // InitTables 初始化数据库表
-func (r *MessageRepository) InitTables() error {
+func (r *MessageRepository) InitTables(ctx context.Context) error {
 	// 自动迁移消息表
-	err := r.db.AutoMigrate(&DBMessage{})
+	err := r.table(ctx).AutoMigrate(&DBMessage{})
 	// 忽略DROP不存在的索引/外键错误（GORM迁移的已知问题）
 	if err != nil && (strings.Contains(err.Error(), "Can't DROP") || 
 		strings.Contains(err.Error(), "check that column/key exists")) {
@@ -53,40 +86,102 @@ func (r *MessageRepository) InitTables() error {
 		return err
 	}
 
-	// 创建复合索引（MySQL 需要先检查是否存在）
-	// 检查并创建 idx_to_status_time 索引
-	var count int64
-	r.db.Raw(`
-		SELECT COUNT(1) 
-		FROM information_schema.statistics 
-		WHERE table_schema = DATABASE() 
-		AND table_name = 'im_messages' 
-		AND index_name = 'idx_to_status_time'
-	`).Scan(&count)
-	
-	if count == 0 {
-		r.db.Exec(`CREATE INDEX idx_to_status_time ON im_messages(to_user_id, status, server_time DESC)`)
-	}
-
-	// 检查并创建 idx_server_time_id 索引
-	r.db.Raw(`
-		SELECT COUNT(1) 
-		FROM information_schema.statistics 
-		WHERE table_schema = DATABASE() 
-		AND table_name = 'im_messages' 
-		AND index_name = 'idx_server_time_id'
-	`).Scan(&count)
-	
-	if count == 0 {
-		r.db.Exec(`CREATE INDEX idx_server_time_id ON im_messages(server_time DESC, id DESC)`)
+	// 创建复合索引（通过 Migrator 检查是否存在，兼容 MySQL / SQLite）
+	if err := r.ensureIndex(ctx, "idx_to_status_time", "to_user_id, status, server_time DESC"); err != nil {
+		return err
+	}
+	if err := r.ensureIndex(ctx, "idx_server_time_id", "server_time DESC, id DESC"); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// AddTenantColumn 添加租户字段（v2 迁移）
+func (r *MessageRepository) AddTenantColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if migrator.HasColumn(&DBMessage{}, "TenantID") {
+		return nil
+	}
+	return migrator.AddColumn(&DBMessage{}, "TenantID")
+}
+
+// AddConversationColumn 添加单聊会话 ID 字段及索引，并回填存量消息（v4 迁移）
+func (r *MessageRepository) AddConversationColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if !migrator.HasColumn(&DBMessage{}, "ConvID") {
+		if err := migrator.AddColumn(&DBMessage{}, "ConvID"); err != nil {
+			return err
+		}
+	}
+	if err := r.ensureIndex(ctx, "idx_conversation_time", "tenant_id, conversation_id, server_time DESC"); err != nil {
+		return err
+	}
+	return r.backfillConversationID(ctx)
+}
+
+// backfillConversationID 按用户对分批回填存量单聊消息的会话 ID
+func (r *MessageRepository) backfillConversationID(ctx context.Context) error {
+	const batchSize = 500
+
+	type pair struct {
+		FromUserID int64
+		ToUserID   int64
+	}
+	for {
+		var pairs []pair
+		err := r.table(ctx).Distinct("from_user_id", "to_user_id").
+			Where("conversation_id = '' AND group_id = 0").
+			Limit(batchSize).Find(&pairs).Error
+		if err != nil {
+			return err
+		}
+		if len(pairs) == 0 {
+			return nil
+		}
+		for _, p := range pairs {
+			err := r.table(ctx).
+				Where("from_user_id = ? AND to_user_id = ? AND group_id = 0", p.FromUserID, p.ToUserID).
+				Update("conversation_id", ConversationID(p.FromUserID, p.ToUserID)).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ConversationID 单聊会话 ID，由两个用户 ID 按从小到大拼接，与收发方向无关
+func ConversationID(userA, userB int64) string {
+	if userA > userB {
+		userA, userB = userB, userA
+	}
+	return strconv.FormatInt(userA, 10) + ":" + strconv.FormatInt(userB, 10)
+}
+
+// ensureIndex 索引不存在时创建
+func (r *MessageRepository) ensureIndex(ctx context.Context, name, columns string) error {
+	return ensureIndex(r.table(ctx), &DBMessage{}, name, columns)
+}
+
 // Save 保存消息
-func (r *MessageRepository) Save(msg *model.Message) error {
+func (r *MessageRepository) Save(ctx context.Context, msg *model.Message) error {
+	return r.messages(ctx).Create(r.toDB(msg)).Error
+}
+
+// SaveBatch 批量保存消息，合并为一条多行 INSERT
+// 任一消息写入失败（如 msg_id 重复）时整批失败
+func (r *MessageRepository) SaveBatch(ctx context.Context, msgs []*model.Message) error {
+	dbMsgs := make([]*DBMessage, len(msgs))
+	for i, msg := range msgs {
+		dbMsgs[i] = r.toDB(msg)
+	}
+	return r.messages(ctx).Create(dbMsgs).Error
+}
+
+// toDB 转换为数据库模型
+func (r *MessageRepository) toDB(msg *model.Message) *DBMessage {
 	dbMsg := &DBMessage{
+		TenantID:      r.tenant,
 		MsgID:         msg.MsgID,
 		FromUserID:    msg.FromUserID,
 		ToUserID:      msg.ToUserID,
@@ -100,20 +195,97 @@ func (r *MessageRepository) Save(msg *model.Message) error {
 		DeliveredTime: msg.DeliveredTime,
 		ReadTime:      msg.ReadTime,
 	}
-	return r.db.Create(dbMsg).Error
+	if msg.GroupID == 0 {
+		dbMsg.ConvID = ConversationID(msg.FromUserID, msg.ToUserID)
+	}
+	return dbMsg
 }
 
 // GetByMsgID 根据消息 ID 查询
-func (r *MessageRepository) GetByMsgID(msgID string) (*model.Message, error) {
+func (r *MessageRepository) GetByMsgID(ctx context.Context, msgID string) (*model.Message, error) {
 	var dbMsg DBMessage
-	if err := r.db.Where("msg_id = ?", msgID).First(&dbMsg).Error; err != nil {
+	if err := r.messages(ctx).Where("msg_id = ?", msgID).First(&dbMsg).Error; err != nil {
 		return nil, err
 	}
 	return r.toModel(&dbMsg), nil
 }
 
-// UpdateStatus 更新消息状态
-func (r *MessageRepository) UpdateStatus(msgID string, status int, updateTime int64) error {
+// GetByMsgIDs 根据消息 ID 批量查询，不存在的消息 ID 忽略
+func (r *MessageRepository) GetByMsgIDs(ctx context.Context, msgIDs []string) ([]*model.Message, error) {
+	if len(msgIDs) == 0 {
+		return nil, nil
+	}
+
+	var dbMessages []DBMessage
+	if err := r.messages(ctx).Where("msg_id IN ?", msgIDs).Find(&dbMessages).Error; err != nil {
+		return nil, err
+	}
+
+	messages := make([]*model.Message, len(dbMessages))
+	for i, dbMsg := range dbMessages {
+		messages[i] = r.toModel(&dbMsg)
+	}
+	return messages, nil
+}
+
+// UpdateStatus 更新消息状态，只前进不回退（如已读的消息不会被迟到的送达回执改回已送达）
+func (r *MessageRepository) UpdateStatus(ctx context.Context, msgID string, status int, updateTime int64) error {
+	return r.messages(ctx).Model(&DBMessage{}).Where("msg_id = ? AND status < ?", msgID, status).Updates(statusUpdates(status, updateTime)).Error
+}
+
+// UpdateStatusBatch 批量更新消息状态，合并为一条 UPDATE ... WHERE msg_id IN (...)，只前进不回退
+func (r *MessageRepository) UpdateStatusBatch(ctx context.Context, msgIDs []string, status int, updateTime int64) error {
+	if len(msgIDs) == 0 {
+		return nil
+	}
+	return r.messages(ctx).Model(&DBMessage{}).Where("msg_id IN ? AND status < ?", msgIDs, status).Updates(statusUpdates(status, updateTime)).Error
+}
+
+// UpdateRecipientStatus 更新发给 userID 的消息状态（送达和已读回执），其他用户的消息和状态不低于 status 的消息不更新
+// 返回实际更新的消息（更新前的状态），没有需要更新的消息时返回空
+func (r *MessageRepository) UpdateRecipientStatus(ctx context.Context, userID int64, msgIDs []string, status int, updateTime int64) ([]*model.Message, error) {
+	if len(msgIDs) == 0 {
+		return nil, nil
+	}
+
+	var dbMessages []DBMessage
+	if err := r.messages(ctx).Where("msg_id IN ? AND to_user_id = ? AND status < ?", msgIDs, userID, status).
+		Find(&dbMessages).Error; err != nil {
+		return nil, err
+	}
+	if len(dbMessages) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(dbMessages))
+	for i := range dbMessages {
+		ids[i] = dbMessages[i].MsgID
+	}
+	result := r.messages(ctx).Model(&DBMessage{}).Where("msg_id IN ? AND to_user_id = ? AND status < ?", ids, userID, status).
+		Updates(statusUpdates(status, updateTime))
+	if result.Error != nil || result.RowsAffected == 0 {
+		// 并发的回执已先完成更新
+		return nil, result.Error
+	}
+
+	messages := make([]*model.Message, len(dbMessages))
+	for i := range dbMessages {
+		messages[i] = r.toModel(&dbMessages[i])
+	}
+	return messages, nil
+}
+
+// MarkConversationRead 将单聊会话中 upToTime（含）之前发给 userID 且未读的消息标记为已读（一条 UPDATE），返回更新条数
+func (r *MessageRepository) MarkConversationRead(ctx context.Context, userID, targetID, upToTime, readTime int64) (int64, error) {
+	result := r.messages(ctx).Model(&DBMessage{}).
+		Where("conversation_id = ? AND to_user_id = ? AND server_time <= ? AND status < ?",
+			ConversationID(userID, targetID), userID, upToTime, model.MsgStatusRead).
+		Updates(statusUpdates(model.MsgStatusRead, readTime))
+	return result.RowsAffected, result.Error
+}
+
+// statusUpdates 状态更新的字段，送达和已读同时记录对应时间
+func statusUpdates(status int, updateTime int64) map[string]interface{} {
 	updates := map[string]interface{}{
 		"status": status,
 	}
@@ -123,29 +295,42 @@ func (r *MessageRepository) UpdateStatus(msgID string, status int, updateTime in
 	} else if status == model.MsgStatusRead {
 		updates["read_time"] = updateTime
 	}
-
-	return r.db.Model(&DBMessage{}).Where("msg_id = ?", msgID).Updates(updates).Error
+	return updates
 }
 
 // GetMessages 获取历史消息
-func (r *MessageRepository) GetMessages(req *model.GetMessagesRequest) ([]*model.Message, error) {
+func (r *MessageRepository) GetMessages(ctx context.Context, req *model.GetMessagesRequest) ([]*model.Message, error) {
+	page, err := r.GetMessagePage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return page.Messages, nil
+}
+
+// GetMessagePage 分页获取历史消息，按 server_time、id 倒序
+// 游标记录上一页最后一条消息的 server_time 和 id，同一毫秒内的消息也不会重复或遗漏
+func (r *MessageRepository) GetMessagePage(ctx context.Context, req *model.GetMessagesRequest) (*model.MessagePage, error) {
 	var dbMessages []DBMessage
 
-	query := r.db.Model(&DBMessage{})
+	query := r.readMessages(ctx).Model(&DBMessage{})
 
 	// 单聊消息查询
 	if req.SessionType == model.SessionTypeSingle {
-		query = query.Where(
-			"(from_user_id = ? AND to_user_id = ?) OR (from_user_id = ? AND to_user_id = ?)",
-			req.UserID, req.TargetID, req.TargetID, req.UserID,
-		)
+		// 按有序用户对的会话 ID 查询，可命中 idx_conversation_time 索引
+		query = query.Where("conversation_id = ?", ConversationID(req.UserID, req.TargetID))
 	} else {
 		// 群聊消息查询
 		query = query.Where("group_id = ?", req.TargetID)
 	}
 
 	// 分页查询
-	if req.BeforeTime > 0 {
+	if req.Cursor != "" {
+		keys, err := decodeCursor(req.Cursor, 2)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("(server_time < ? OR (server_time = ? AND id < ?))", keys[0], keys[0], keys[1])
+	} else if req.BeforeTime > 0 {
 		query = query.Where("server_time < ?", req.BeforeTime)
 	}
 
@@ -153,25 +338,34 @@ func (r *MessageRepository) GetMessages(req *model.GetMessagesRequest) ([]*model
 		req.Limit = 20
 	}
 
-	if err := query.Order("server_time DESC").Limit(req.Limit).Find(&dbMessages).Error; err != nil {
+	// 多取一条判断是否还有更多
+	if err := query.Order("server_time DESC").Order("id DESC").Limit(req.Limit + 1).Find(&dbMessages).Error; err != nil {
 		return nil, err
 	}
 
+	page := &model.MessagePage{}
+	if len(dbMessages) > req.Limit {
+		dbMessages = dbMessages[:req.Limit]
+		last := dbMessages[len(dbMessages)-1]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(last.ServerTime, last.ID)
+	}
+
 	// 转换为模型
-	messages := make([]*model.Message, len(dbMessages))
+	page.Messages = make([]*model.Message, len(dbMessages))
 	for i, dbMsg := range dbMessages {
-		messages[i] = r.toModel(&dbMsg)
+		page.Messages[i] = r.toModel(&dbMsg)
 	}
 
-	return messages, nil
+	return page, nil
 }
 
 // GetUndeliveredMessages 获取未送达消息
-func (r *MessageRepository) GetUndeliveredMessages(userID int64, limit int) ([]*model.Message, error) {
+func (r *MessageRepository) GetUndeliveredMessages(ctx context.Context, userID int64, limit int) ([]*model.Message, error) {
 	var dbMessages []DBMessage
 
-	if err := r.db.Where("to_user_id = ? AND status = ?", userID, model.MsgStatusSent).
-		Order("server_time ASC").
+	if err := r.messages(ctx).Where("to_user_id = ? AND status = ?", userID, model.MsgStatusSent).
+		Order("server_time ASC").Order("id ASC").
 		Limit(limit).
 		Find(&dbMessages).Error; err != nil {
 		return nil, err
@@ -185,6 +379,47 @@ func (r *MessageRepository) GetUndeliveredMessages(userID int64, limit int) ([]*
 	return messages, nil
 }
 
+// DeleteByFromUser 删除用户发送的全部消息
+func (r *MessageRepository) DeleteByFromUser(ctx context.Context, userID int64) error {
+	return r.messages(ctx).Where("from_user_id = ?", userID).Delete(&DBMessage{}).Error
+}
+
+// HasFileMessage 检查用户是否发送或接收过引用 fileID 的消息
+func (r *MessageRepository) HasFileMessage(ctx context.Context, fileID string, userID int64) (bool, error) {
+	var count int64
+	if err := r.messages(ctx).Model(&DBMessage{}).
+		Where("file_id = ? AND (from_user_id = ? OR to_user_id = ?)", fileID, userID, userID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetFileGroupIDs 获取发送过引用 fileID 的消息的群组
+func (r *MessageRepository) GetFileGroupIDs(ctx context.Context, fileID string) ([]int64, error) {
+	var groupIDs []int64
+	if err := r.messages(ctx).Model(&DBMessage{}).
+		Where("file_id = ? AND group_id > 0", fileID).
+		Distinct().Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	return groupIDs, nil
+}
+
+// GetReferencedFileIDs 获取 fileIDs 中被消息引用的文件ID
+func (r *MessageRepository) GetReferencedFileIDs(ctx context.Context, fileIDs []string) ([]string, error) {
+	var referenced []string
+	if len(fileIDs) == 0 {
+		return referenced, nil
+	}
+	if err := r.messages(ctx).Model(&DBMessage{}).
+		Where("file_id IN ?", fileIDs).
+		Distinct().Pluck("file_id", &referenced).Error; err != nil {
+		return nil, err
+	}
+	return referenced, nil
+}
+
 // toModel 转换为业务模型
 func (r *MessageRepository) toModel(dbMsg *DBMessage) *model.Message {
 	return &model.Message{
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/migrate"
+)
+
+// MigrationTable 迁移记录默认表名
+const MigrationTable = "im_schema_migrations"
+
+// Migrations IM 模块的全部数据库迁移
+// 已发布的迁移不可修改，表结构变更需追加新版本
+func Migrations(namer *TableNamer) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version: 1,
+			Name:    "init",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return initTables(ctx, db, namer)
+			},
+		},
+		{
+			Version: 2,
+			Name:    "tenant_id",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewMessageRepository(db, namer).AddTenantColumn(ctx); err != nil {
+					return fmt.Errorf("add message tenant column failed: %w", err)
+				}
+				if err := NewSessionRepository(db, namer).AddTenantColumn(ctx); err != nil {
+					return fmt.Errorf("add session tenant column failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 3,
+			Name:    "groups",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewGroupRepository(db, namer).InitTables(ctx)
+			},
+		},
+		{
+			Version: 4,
+			Name:    "message_conversation_id",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewMessageRepository(db, namer).AddConversationColumn(ctx)
+			},
+		},
+		{
+			Version: 5,
+			Name:    "session_user_time",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewSessionRepository(db, namer).AddUserTimeIndex(ctx)
+			},
+		},
+		{
+			Version: 6,
+			Name:    "session_last_read_time",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewSessionRepository(db, namer).AddLastReadColumn(ctx)
+			},
+		},
+		{
+			Version: 7,
+			Name:    "notification_mute_until",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewNotificationRepository(db, namer).AddMuteUntilColumn(ctx)
+			},
+		},
+		{
+			Version: 8,
+			Name:    "group_policy",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewGroupRepository(db, namer).AddPolicyColumns(ctx)
+			},
+		},
+		{
+			Version: 9,
+			Name:    "message_reports",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewReportRepository(db, namer).InitTables(ctx)
+			},
+		},
+		{
+			Version: 10,
+			Name:    "away_status",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewAwayRepository(db, namer).InitTables(ctx)
+			},
+		},
+		{
+			Version: 11,
+			Name:    "user_presence",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewPresenceRepository(db, namer).InitTables(ctx)
+			},
+		},
+		{
+			Version: 12,
+			Name:    "support_desk",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				return NewSupportRepository(db, namer).InitTables(ctx)
+			},
+		},
+	}
+}
+
+// NewMigrator 创建 IM 模块的迁移执行器
+func NewMigrator(db *gorm.DB, namer *TableNamer) *migrate.Migrator {
+	return migrate.New(db, namer.Table(MigrationTable), Migrations(namer))
+}
+
+// initTables 创建 IM 模块的所有表
+func initTables(ctx context.Context, db *gorm.DB, namer *TableNamer) error {
+	if err := NewMessageRepository(db, namer).InitTables(ctx); err != nil {
+		return err
+	}
+	if err := NewRouteRepository(db, namer).InitTables(ctx); err != nil {
+		return err
+	}
+	if err := NewSessionRepository(db, namer).InitTables(ctx); err != nil {
+		return err
+	}
+	if err := NewDraftRepository(db, namer).InitTables(ctx); err != nil {
+		return err
+	}
+	if err := NewDNDRepository(db, namer).InitTables(ctx); err != nil {
+		return err
+	}
+	return NewNotificationRepository(db, namer).InitTables(ctx)
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// DBNotificationPreference 会话通知偏好数据库模型
+type DBNotificationPreference struct {
+	UserID      int64 `gorm:"primaryKey"`
+	TargetID    int64 `gorm:"primaryKey"`
+	SessionType int   `gorm:"primaryKey;type:tinyint;default:1"`
+	Level       int   `gorm:"type:tinyint;default:0"`
+	MuteUntil   int64 `gorm:"type:bigint;not null;default:0"`
+	UpdatedAt   int64 `gorm:"autoUpdateTime:milli"`
+}
+
+func (DBNotificationPreference) TableName() string {
+	return "im_notification_prefs"
+}
+
+// NotificationRepository 通知偏好仓库
+type NotificationRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewNotificationRepository 创建通知偏好仓库
+func NewNotificationRepository(db *gorm.DB, namer *TableNamer) *NotificationRepository {
+	return &NotificationRepository{db: db, namer: namer}
+}
+
+// prefs 通知偏好表
+func (r *NotificationRepository) prefs(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBNotificationPreference{}.TableName()))
+}
+
+// InitTables 初始化数据库表
+func (r *NotificationRepository) InitTables(ctx context.Context) error {
+	return r.prefs(ctx).AutoMigrate(&DBNotificationPreference{})
+}
+
+// AddMuteUntilColumn 添加通知级别截止时间字段（v7 迁移），存量偏好为永久生效
+func (r *NotificationRepository) AddMuteUntilColumn(ctx context.Context) error {
+	migrator := r.prefs(ctx).Migrator()
+	if migrator.HasColumn(&DBNotificationPreference{}, "MuteUntil") {
+		return nil
+	}
+	return migrator.AddColumn(&DBNotificationPreference{}, "MuteUntil")
+}
+
+// active 未过期的通知偏好
+func (r *NotificationRepository) active(ctx context.Context) *gorm.DB {
+	return r.prefs(ctx).Where("(mute_until = 0 OR mute_until > ?)", time.Now().UnixMilli())
+}
+
+// Save 保存通知偏好（如果不存在则创建）
+func (r *NotificationRepository) Save(ctx context.Context, pref *model.NotificationPreference) error {
+	dbPref := &DBNotificationPreference{
+		UserID:      pref.UserID,
+		TargetID:    pref.TargetID,
+		SessionType: pref.SessionType,
+		Level:       pref.Level,
+		MuteUntil:   pref.MuteUntil,
+	}
+
+	return r.prefs(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "user_id"},
+			{Name: "target_id"},
+			{Name: "session_type"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"level", "mute_until", "updated_at"}),
+	}).Create(dbPref).Error
+}
+
+// Get 获取单个会话的通知偏好，已过 MuteUntil 的偏好视为不存在
+func (r *NotificationRepository) Get(ctx context.Context, userID, targetID int64, sessionType int) (*model.NotificationPreference, error) {
+	var dbPref DBNotificationPreference
+	if err := r.active(ctx).Where("user_id = ? AND target_id = ? AND session_type = ?", userID, targetID, sessionType).
+		First(&dbPref).Error; err != nil {
+		return nil, err
+	}
+	return r.toModel(&dbPref), nil
+}
+
+// GetUserPreferences 获取用户未过期的所有通知偏好
+func (r *NotificationRepository) GetUserPreferences(ctx context.Context, userID int64) ([]*model.NotificationPreference, error) {
+	var dbPrefs []DBNotificationPreference
+	if err := r.active(ctx).Where("user_id = ?", userID).Find(&dbPrefs).Error; err != nil {
+		return nil, err
+	}
+
+	prefs := make([]*model.NotificationPreference, len(dbPrefs))
+	for i := range dbPrefs {
+		prefs[i] = r.toModel(&dbPrefs[i])
+	}
+	return prefs, nil
+}
+
+// Delete 删除通知偏好（恢复默认）
+func (r *NotificationRepository) Delete(ctx context.Context, userID, targetID int64, sessionType int) error {
+	return r.prefs(ctx).Where("user_id = ? AND target_id = ? AND session_type = ?", userID, targetID, sessionType).
+		Delete(&DBNotificationPreference{}).Error
+}
+
+// DeleteByUser 删除用户的全部通知偏好
+func (r *NotificationRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	return r.prefs(ctx).Where("user_id = ?", userID).Delete(&DBNotificationPreference{}).Error
+}
+
+// toModel 转换为业务模型
+func (r *NotificationRepository) toModel(dbPref *DBNotificationPreference) *model.NotificationPreference {
+	return &model.NotificationPreference{
+		UserID:      dbPref.UserID,
+		TargetID:    dbPref.TargetID,
+		SessionType: dbPref.SessionType,
+		Level:       dbPref.Level,
+		MuteUntil:   dbPref.MuteUntil,
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// DBPresence 用户在线状态数据库模型，未设置时视为 online
+type DBPresence struct {
+	UserID    int64  `gorm:"primaryKey;autoIncrement:false"`
+	Status    string `gorm:"type:varchar(16);not null"`
+	Text      string `gorm:"type:varchar(100)"`
+	UpdatedAt int64  `gorm:"autoUpdateTime:milli"`
+}
+
+func (DBPresence) TableName() string {
+	return "im_user_presence"
+}
+
+// PresenceRepository 用户在线状态仓库
+type PresenceRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewPresenceRepository 创建用户在线状态仓库
+func NewPresenceRepository(db *gorm.DB, namer *TableNamer) *PresenceRepository {
+	return &PresenceRepository{db: db, namer: namer}
+}
+
+// presences 用户在线状态表
+func (r *PresenceRepository) presences(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBPresence{}.TableName()))
+}
+
+// InitTables 初始化数据库表（v11 迁移）
+func (r *PresenceRepository) InitTables(ctx context.Context) error {
+	return r.presences(ctx).AutoMigrate(&DBPresence{})
+}
+
+// Save 保存用户在线状态（如果不存在则创建）
+func (r *PresenceRepository) Save(ctx context.Context, presence *model.Presence) error {
+	dbPresence := &DBPresence{
+		UserID: presence.UserID,
+		Status: presence.Status,
+		Text:   presence.Text,
+	}
+	return r.presences(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "text", "updated_at"}),
+	}).Create(dbPresence).Error
+}
+
+// GetBatch 批量获取用户在线状态，未设置的用户不在结果中
+func (r *PresenceRepository) GetBatch(ctx context.Context, userIDs []int64) (map[int64]*model.Presence, error) {
+	result := make(map[int64]*model.Presence, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	var dbPresences []DBPresence
+	if err := r.presences(ctx).Where("user_id IN ?", userIDs).Find(&dbPresences).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range dbPresences {
+		result[p.UserID] = &model.Presence{
+			UserID:    p.UserID,
+			Status:    p.Status,
+			Text:      p.Text,
+			UpdatedAt: p.UpdatedAt,
+		}
+	}
+	return result, nil
+}
+
+// Delete 删除用户在线状态（恢复为 online）
+func (r *PresenceRepository) Delete(ctx context.Context, userID int64) error {
+	return r.presences(ctx).Where("user_id = ?", userID).Delete(&DBPresence{}).Error
+}
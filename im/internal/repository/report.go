@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// DBMessageReport 消息举报数据库模型
+type DBMessageReport struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement"`
+	MsgID      string `gorm:"type:varchar(64);uniqueIndex:uk_report_msg_reporter;not null"`
+	ReporterID int64  `gorm:"uniqueIndex:uk_report_msg_reporter;index:idx_report_reporter;not null"`
+	Reason     string `gorm:"type:varchar(500);not null"`
+	FromUserID int64  `gorm:"not null"`
+	GroupID    int64  `gorm:"default:0"`
+	Content    string `gorm:"type:text"`
+	MsgType    int    `gorm:"type:tinyint;default:1"`
+	FileID     string `gorm:"type:varchar(64)"`
+	Status     int    `gorm:"type:tinyint;index:idx_report_status;default:1"`
+	ResolverID int64  `gorm:"default:0"`
+	Note       string `gorm:"type:varchar(500)"`
+	CreatedAt  int64  `gorm:"autoCreateTime:milli"`
+	ResolvedAt int64  `gorm:"default:0"`
+}
+
+func (DBMessageReport) TableName() string {
+	return "im_message_reports"
+}
+
+// ReportRepository 消息举报仓库
+type ReportRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewReportRepository 创建消息举报仓库
+func NewReportRepository(db *gorm.DB, namer *TableNamer) *ReportRepository {
+	return &ReportRepository{db: db, namer: namer}
+}
+
+// reports 举报表
+func (r *ReportRepository) reports(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBMessageReport{}.TableName()))
+}
+
+// InitTables 初始化数据库表（v9 迁移）
+func (r *ReportRepository) InitTables(ctx context.Context) error {
+	return r.reports(ctx).AutoMigrate(&DBMessageReport{})
+}
+
+// Create 保存举报，同一用户重复举报同一消息时不重复创建，返回是否新建
+func (r *ReportRepository) Create(ctx context.Context, report *model.MessageReport) (bool, error) {
+	dbReport := &DBMessageReport{
+		MsgID:      report.MsgID,
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		FromUserID: report.FromUserID,
+		GroupID:    report.GroupID,
+		Content:    report.Content,
+		MsgType:    report.MsgType,
+		FileID:     report.FileID,
+		Status:     model.ReportStatusPending,
+	}
+	result := r.reports(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(dbReport)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	*report = *toReport(dbReport)
+	return true, nil
+}
+
+// Get 获取举报
+func (r *ReportRepository) Get(ctx context.Context, id int64) (*model.MessageReport, error) {
+	var dbReport DBMessageReport
+	if err := r.reports(ctx).First(&dbReport, id).Error; err != nil {
+		return nil, err
+	}
+	return toReport(&dbReport), nil
+}
+
+// GetByReporter 获取用户对消息的举报
+func (r *ReportRepository) GetByReporter(ctx context.Context, msgID string, reporterID int64) (*model.MessageReport, error) {
+	var dbReport DBMessageReport
+	if err := r.reports(ctx).Where("msg_id = ? AND reporter_id = ?", msgID, reporterID).First(&dbReport).Error; err != nil {
+		return nil, err
+	}
+	return toReport(&dbReport), nil
+}
+
+// List 分页获取举报，按 ID 倒序，status 为 0 时不过滤
+func (r *ReportRepository) List(ctx context.Context, req *model.ListReportsRequest) (*model.ReportPage, error) {
+	query := r.reports(ctx)
+	if req.Status != 0 {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.Cursor != "" {
+		keys, err := decodeCursor(req.Cursor, 1)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", keys[0])
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	// 多取一条判断是否还有更多
+	var dbReports []DBMessageReport
+	if err := query.Order("id DESC").Limit(req.Limit + 1).Find(&dbReports).Error; err != nil {
+		return nil, err
+	}
+
+	page := &model.ReportPage{}
+	if len(dbReports) > req.Limit {
+		dbReports = dbReports[:req.Limit]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(dbReports[len(dbReports)-1].ID)
+	}
+	page.Reports = make([]*model.MessageReport, len(dbReports))
+	for i := range dbReports {
+		page.Reports[i] = toReport(&dbReports[i])
+	}
+	return page, nil
+}
+
+// Resolve 处理待处理的举报，返回是否更新（已处理过的举报不再更新）
+func (r *ReportRepository) Resolve(ctx context.Context, id int64, status int, resolverID int64, note string, resolvedAt int64) (bool, error) {
+	result := r.reports(ctx).Where("id = ? AND status = ?", id, model.ReportStatusPending).Updates(map[string]interface{}{
+		"status":      status,
+		"resolver_id": resolverID,
+		"note":        note,
+		"resolved_at": resolvedAt,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteByReporter 删除用户提交的全部举报
+func (r *ReportRepository) DeleteByReporter(ctx context.Context, userID int64) error {
+	return r.reports(ctx).Where("reporter_id = ?", userID).Delete(&DBMessageReport{}).Error
+}
+
+// toReport 转换为举报模型
+func toReport(dbReport *DBMessageReport) *model.MessageReport {
+	return &model.MessageReport{
+		ID:         dbReport.ID,
+		MsgID:      dbReport.MsgID,
+		ReporterID: dbReport.ReporterID,
+		Reason:     dbReport.Reason,
+		FromUserID: dbReport.FromUserID,
+		GroupID:    dbReport.GroupID,
+		Content:    dbReport.Content,
+		MsgType:    dbReport.MsgType,
+		FileID:     dbReport.FileID,
+		Status:     dbReport.Status,
+		ResolverID: dbReport.ResolverID,
+		Note:       dbReport.Note,
+		CreatedAt:  dbReport.CreatedAt,
+		ResolvedAt: dbReport.ResolvedAt,
+	}
+}
@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DBServer 服务器节点数据库模型
@@ -12,7 +14,7 @@ type DBServer struct {
 	GRPCAddr      string    `gorm:"column:grpc_addr;type:varchar(128);not null"`
 	LastHeartbeat int64     `gorm:"index:idx_heartbeat;not null"`
 	CreatedAt     time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
-	UpdatedAt     time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP"` // 由 GORM 自动维护更新时间
 }
 
 func (DBServer) TableName() string {
@@ -22,10 +24,10 @@ func (DBServer) TableName() string {
 // DBUserRoute 用户路由数据库模型
 type DBUserRoute struct {
 	UserID        int64     `gorm:"primaryKey;autoIncrement:false"`
-	ServerID      string    `gorm:"type:varchar(64);index:idx_server;not null"`
-	LastHeartbeat int64     `gorm:"index:idx_heartbeat;not null"`
+	ServerID      string    `gorm:"type:varchar(64);index:idx_route_server;not null"`
+	LastHeartbeat int64     `gorm:"index:idx_route_heartbeat;not null"`
 	CreatedAt     time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP"`
-	UpdatedAt     time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP"` // 由 GORM 自动维护更新时间
 }
 
 func (DBUserRoute) TableName() string {
@@ -41,25 +43,63 @@ type Server struct {
 
 // RouteRepository 路由仓库
 type RouteRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	readDB *gorm.DB
+	namer  *TableNamer
+
+	// presenceTimeout 用户路由心跳超时（秒），超时的路由视为离线，0 表示不判断
+	presenceTimeout int64
 }
 
 // NewRouteRepository 创建路由仓库
-func NewRouteRepository(db *gorm.DB) *RouteRepository {
-	return &RouteRepository{db: db}
+func NewRouteRepository(db *gorm.DB, namer *TableNamer) *RouteRepository {
+	return &RouteRepository{db: db, namer: namer}
+}
+
+// servers 服务器节点表
+func (r *RouteRepository) servers(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBServer{}.TableName()))
+}
+
+// routes 用户路由表
+func (r *RouteRepository) routes(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBUserRoute{}.TableName()))
+}
+
+// SetReadDB 设置只读库（从库），节点和用户路由查询走只读库
+func (r *RouteRepository) SetReadDB(readDB *gorm.DB) {
+	r.readDB = readDB
+}
+
+// SetPresenceTimeout 设置用户路由心跳超时（秒），查询路由时忽略心跳超时的用户
+func (r *RouteRepository) SetPresenceTimeout(seconds int) {
+	r.presenceTimeout = int64(seconds)
+}
+
+// readServers 服务器节点表（只读库，未设置时使用主库）
+func (r *RouteRepository) readServers(ctx context.Context) *gorm.DB {
+	return readerOf(r.db, r.readDB).WithContext(ctx).Table(r.namer.Table(DBServer{}.TableName()))
+}
+
+// readRoutes 用户路由表（只读库，未设置时使用主库）
+func (r *RouteRepository) readRoutes(ctx context.Context) *gorm.DB {
+	return readerOf(r.db, r.readDB).WithContext(ctx).Table(r.namer.Table(DBUserRoute{}.TableName()))
 }
 
 // InitTables 初始化数据库表
-func (r *RouteRepository) InitTables() error {
-	return r.db.AutoMigrate(&DBServer{}, &DBUserRoute{})
+func (r *RouteRepository) InitTables(ctx context.Context) error {
+	if err := r.servers(ctx).AutoMigrate(&DBServer{}); err != nil {
+		return err
+	}
+	return r.routes(ctx).AutoMigrate(&DBUserRoute{})
 }
 
 // RegisterServer 注册服务器节点
-func (r *RouteRepository) RegisterServer(serverID, grpcAddr string) error {
+func (r *RouteRepository) RegisterServer(ctx context.Context, serverID, grpcAddr string) error {
 	now := time.Now().Unix()
 	
 	// 先尝试更新
-	result := r.db.Model(&DBServer{}).
+	result := r.servers(ctx).Model(&DBServer{}).
 		Where("server_id = ?", serverID).
 		Updates(map[string]interface{}{
 			"grpc_addr":      grpcAddr,
@@ -77,31 +117,36 @@ func (r *RouteRepository) RegisterServer(serverID, grpcAddr string) error {
 			GRPCAddr:      grpcAddr,
 			LastHeartbeat: now,
 		}
-		return r.db.Create(server).Error
+		return r.servers(ctx).Create(server).Error
 	}
 	
 	return nil
 }
 
 // UnregisterServer 注销服务器节点
-func (r *RouteRepository) UnregisterServer(serverID string) error {
-	return r.db.Delete(&DBServer{}, "server_id = ?", serverID).Error
+func (r *RouteRepository) UnregisterServer(ctx context.Context, serverID string) error {
+	return r.servers(ctx).Delete(&DBServer{}, "server_id = ?", serverID).Error
 }
 
 // UpdateServerHeartbeat 更新服务器心跳
-func (r *RouteRepository) UpdateServerHeartbeat(serverID string) error {
+func (r *RouteRepository) UpdateServerHeartbeat(ctx context.Context, serverID string) error {
 	now := time.Now().Unix()
-	return r.db.Model(&DBServer{}).
+	return r.servers(ctx).Model(&DBServer{}).
 		Where("server_id = ?", serverID).
 		Update("last_heartbeat", now).Error
 }
 
+// HasServerTable 服务器节点表是否已创建
+func (r *RouteRepository) HasServerTable(ctx context.Context) bool {
+	return r.db.WithContext(ctx).Migrator().HasTable(r.namer.Table(DBServer{}.TableName()))
+}
+
 // GetActiveServers 获取活跃的服务器列表
-func (r *RouteRepository) GetActiveServers() ([]*Server, error) {
+func (r *RouteRepository) GetActiveServers(ctx context.Context) ([]*Server, error) {
 	var dbServers []DBServer
 	timeout := time.Now().Unix() - 60 // 60秒内有心跳的认为在线
 
-	if err := r.db.Where("last_heartbeat > ?", timeout).Find(&dbServers).Error; err != nil {
+	if err := r.readServers(ctx).Where("last_heartbeat > ?", timeout).Find(&dbServers).Error; err != nil {
 		return nil, err
 	}
 
@@ -118,11 +163,11 @@ func (r *RouteRepository) GetActiveServers() ([]*Server, error) {
 }
 
 // RegisterUserRoute 注册用户路由
-func (r *RouteRepository) RegisterUserRoute(userID int64, serverID string) error {
+func (r *RouteRepository) RegisterUserRoute(ctx context.Context, userID int64, serverID string) error {
 	now := time.Now().Unix()
 	
 	// 先尝试更新
-	result := r.db.Model(&DBUserRoute{}).
+	result := r.routes(ctx).Model(&DBUserRoute{}).
 		Where("user_id = ?", userID).
 		Updates(map[string]interface{}{
 			"server_id":      serverID,
@@ -140,15 +185,31 @@ func (r *RouteRepository) RegisterUserRoute(userID int64, serverID string) error
 			ServerID:      serverID,
 			LastHeartbeat: now,
 		}
-		return r.db.Create(route).Error
+		return r.routes(ctx).Create(route).Error
 	}
 	
 	return nil
 }
 
 // UnregisterUserRoute 注销用户路由
-func (r *RouteRepository) UnregisterUserRoute(userID int64) error {
-	return r.db.Delete(&DBUserRoute{}, "user_id = ?", userID).Error
+func (r *RouteRepository) UnregisterUserRoute(ctx context.Context, userID int64) error {
+	return r.routes(ctx).Delete(&DBUserRoute{}, "user_id = ?", userID).Error
+}
+
+// ReleaseUserRoute 注销 serverID 持有的用户路由，返回是否删除
+// 用户已重新连接到其他节点或路由已过期删除时返回 false
+func (r *RouteRepository) ReleaseUserRoute(ctx context.Context, userID int64, serverID string) (bool, error) {
+	result := r.routes(ctx).Delete(&DBUserRoute{}, "user_id = ? AND server_id = ?", userID, serverID)
+	return result.RowsAffected > 0, result.Error
+}
+
+// EnsureUserRoute 用户路由不存在时创建，已存在（包括指向其他节点）时不修改
+func (r *RouteRepository) EnsureUserRoute(ctx context.Context, userID int64, serverID string) error {
+	return r.routes(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&DBUserRoute{
+		UserID:        userID,
+		ServerID:      serverID,
+		LastHeartbeat: time.Now().Unix(),
+	}).Error
 }
 
 // UserRoute 用户路由结果
@@ -158,15 +219,19 @@ type UserRoute struct {
 }
 
 // GetUserRoute 获取用户路由
-func (r *RouteRepository) GetUserRoute(userID int64) (*UserRoute, error) {
+func (r *RouteRepository) GetUserRoute(ctx context.Context, userID int64) (*UserRoute, error) {
 	var route DBUserRoute
-	if err := r.db.Where("user_id = ?", userID).First(&route).Error; err != nil {
+	query := r.readRoutes(ctx).Where("user_id = ?", userID)
+	if r.presenceTimeout > 0 {
+		query = query.Where("last_heartbeat >= ?", time.Now().Unix()-r.presenceTimeout)
+	}
+	if err := query.First(&route).Error; err != nil {
 		return nil, err
 	}
 
 	// 查询服务器信息
 	var server DBServer
-	if err := r.db.Where("server_id = ?", route.ServerID).First(&server).Error; err != nil {
+	if err := r.readServers(ctx).Where("server_id = ?", route.ServerID).First(&server).Error; err != nil {
 		return nil, err
 	}
 
@@ -176,14 +241,42 @@ func (r *RouteRepository) GetUserRoute(userID int64) (*UserRoute, error) {
 	}, nil
 }
 
-// BatchUpdateHeartbeat 批量更新用户心跳
-func (r *RouteRepository) BatchUpdateHeartbeat(userIDs []int64) error {
+// BatchUpdateHeartbeat 批量更新 serverID 持有的用户路由心跳，返回更新的路由数
+// 路由已被删除或已指向其他节点的用户不计入
+func (r *RouteRepository) BatchUpdateHeartbeat(ctx context.Context, userIDs []int64, serverID string) (int64, error) {
 	if len(userIDs) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	now := time.Now().Unix()
-	return r.db.Model(&DBUserRoute{}).
-		Where("user_id IN ?", userIDs).
-		Update("last_heartbeat", now).Error
+	result := r.routes(ctx).Model(&DBUserRoute{}).
+		Where("user_id IN ? AND server_id = ?", userIDs, serverID).
+		Update("last_heartbeat", now)
+	return result.RowsAffected, result.Error
+}
+
+// FilterServerUsers 返回 userIDs 中路由指向 serverID 的用户
+func (r *RouteRepository) FilterServerUsers(ctx context.Context, userIDs []int64, serverID string) ([]int64, error) {
+	var held []int64
+	if len(userIDs) == 0 {
+		return held, nil
+	}
+	err := r.routes(ctx).Where("user_id IN ? AND server_id = ?", userIDs, serverID).Pluck("user_id", &held).Error
+	return held, err
+}
+
+// GetStaleUserRoutes 获取心跳早于 before（秒）的用户，最多 limit 个
+func (r *RouteRepository) GetStaleUserRoutes(ctx context.Context, before int64, limit int) ([]int64, error) {
+	var userIDs []int64
+	err := r.routes(ctx).Where("last_heartbeat < ?", before).
+		Order("last_heartbeat ASC").Limit(limit).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// ExpireUserRoute 删除心跳早于 before（秒）的用户路由，返回是否删除
+// 多个节点同时清理同一用户时只有一个节点删除成功
+func (r *RouteRepository) ExpireUserRoute(ctx context.Context, userID, before int64) (bool, error) {
+	result := r.routes(ctx).Where("user_id = ? AND last_heartbeat < ?", userID, before).Delete(&DBUserRoute{})
+	return result.RowsAffected > 0, result.Error
 }
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
@@ -10,11 +13,13 @@ import (
 // DBSession 会话数据库模型
 type DBSession struct {
 	UserID         int64  `gorm:"primaryKey"`
+	TenantID       string `gorm:"type:varchar(64);not null;default:''"`
 	TargetID       int64  `gorm:"primaryKey"`
 	SessionType    int    `gorm:"primaryKey;type:tinyint;default:1"`
 	LastMsgContent string `gorm:"type:text"`
 	LastMsgTime    int64  `gorm:"type:bigint;index:idx_user_time"`
 	UnreadCount    int    `gorm:"type:int;default:0"`
+	LastReadTime   int64  `gorm:"type:bigint;not null;default:0"`
 	CreatedAt      int64  `gorm:"autoCreateTime:milli"`
 	UpdatedAt      int64  `gorm:"autoUpdateTime:milli"`
 }
@@ -25,22 +30,86 @@ func (DBSession) TableName() string {
 
 // SessionRepository 会话仓库
 type SessionRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	readDB *gorm.DB
+	namer  *TableNamer
+	tenant string
 }
 
 // NewSessionRepository 创建会话仓库
-func NewSessionRepository(db *gorm.DB) *SessionRepository {
-	return &SessionRepository{db: db}
+func NewSessionRepository(db *gorm.DB, namer *TableNamer) *SessionRepository {
+	return &SessionRepository{db: db, namer: namer}
+}
+
+// SetReadDB 设置只读库（从库），会话列表查询走只读库
+func (r *SessionRepository) SetReadDB(readDB *gorm.DB) {
+	r.readDB = readDB
+}
+
+// SetTenant 设置租户 ID，创建的会话归属该租户，查询和修改仅限该租户的会话
+func (r *SessionRepository) SetTenant(tenant string) {
+	r.tenant = tenant
+}
+
+// table 会话表，不限定租户，用于表结构迁移
+func (r *SessionRepository) table(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBSession{}.TableName()))
+}
+
+// sessions 会话表，限定当前租户
+func (r *SessionRepository) sessions(ctx context.Context) *gorm.DB {
+	return r.table(ctx).Where("tenant_id = ?", r.tenant)
+}
+
+// readSessions 会话表（只读库，未设置时使用主库），限定当前租户
+func (r *SessionRepository) readSessions(ctx context.Context) *gorm.DB {
+	return readerOf(r.db, r.readDB).WithContext(ctx).Table(r.namer.Table(DBSession{}.TableName())).
+		Where("tenant_id = ?", r.tenant)
 }
 
 // InitTables 初始化数据库表
-func (r *SessionRepository) InitTables() error {
-	return r.db.AutoMigrate(&DBSession{})
+func (r *SessionRepository) InitTables(ctx context.Context) error {
+	return r.table(ctx).AutoMigrate(&DBSession{})
+}
+
+// AddTenantColumn 添加租户字段（v2 迁移）
+func (r *SessionRepository) AddTenantColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if migrator.HasColumn(&DBSession{}, "TenantID") {
+		return nil
+	}
+	return migrator.AddColumn(&DBSession{}, "TenantID")
+}
+
+// AddUserTimeIndex 添加按用户分页查询会话的复合索引（v5 迁移）
+func (r *SessionRepository) AddUserTimeIndex(ctx context.Context) error {
+	return ensureIndex(r.table(ctx), &DBSession{}, "idx_user_last_msg", "tenant_id, user_id, last_msg_time DESC")
+}
+
+// AddLastReadColumn 添加已读水位字段，并按存量未读数回填（v6 迁移）
+// 没有未读的会话水位取最后消息时间，有未读的取对方已读消息中最新的服务端时间
+func (r *SessionRepository) AddLastReadColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if migrator.HasColumn(&DBSession{}, "LastReadTime") {
+		return nil
+	}
+	if err := migrator.AddColumn(&DBSession{}, "LastReadTime"); err != nil {
+		return err
+	}
+
+	sessions := r.namer.Table(DBSession{}.TableName())
+	lastRead := fmt.Sprintf(
+		"CASE WHEN unread_count = 0 THEN COALESCE(last_msg_time, 0) ELSE COALESCE((SELECT MAX(m.server_time) FROM %s m "+
+			"WHERE m.tenant_id = %s.tenant_id AND m.to_user_id = %s.user_id AND m.from_user_id = %s.target_id AND m.status = ?), 0) END",
+		r.namer.Table(DBMessage{}.TableName()), sessions, sessions, sessions)
+	return r.table(ctx).Where("1 = 1").Update("last_read_time", gorm.Expr(lastRead, model.MsgStatusRead)).Error
 }
 
 // UpdateSession 更新会话（如果不存在则创建）
-func (r *SessionRepository) UpdateSession(session *model.Session) error {
+// 未读数不在此累加，由 RefreshUnread 按已读水位计算
+func (r *SessionRepository) UpdateSession(ctx context.Context, session *model.Session) error {
 	dbSession := &DBSession{
+		TenantID:       r.tenant,
 		UserID:         session.UserID,
 		TargetID:       session.TargetID,
 		SessionType:    session.SessionType,
@@ -49,7 +118,7 @@ func (r *SessionRepository) UpdateSession(session *model.Session) error {
 	}
 
 	// 使用 upsert 模式
-	return r.db.Clauses(clause.OnConflict{
+	return r.sessions(ctx).Clauses(clause.OnConflict{
 		Columns: []clause.Column{
 			{Name: "user_id"},
 			{Name: "target_id"},
@@ -58,21 +127,64 @@ func (r *SessionRepository) UpdateSession(session *model.Session) error {
 		DoUpdates: clause.Assignments(map[string]interface{}{
 			"last_msg_content": session.LastMsgContent,
 			"last_msg_time":    session.LastMsgTime,
-			"unread_count":     gorm.Expr("unread_count + ?", session.UnreadCount),
 		}),
 	}).Create(dbSession).Error
 }
 
 // GetUserSessions 获取用户的会话列表
-func (r *SessionRepository) GetUserSessions(userID int64) ([]*model.Session, error) {
+func (r *SessionRepository) GetUserSessions(ctx context.Context, userID int64) ([]*model.Session, error) {
 	var dbSessions []DBSession
 
-	if err := r.db.Where("user_id = ?", userID).
+	if err := r.readSessions(ctx).Where("user_id = ?", userID).
 		Order("last_msg_time DESC").
 		Find(&dbSessions).Error; err != nil {
 		return nil, err
 	}
 
+	return toSessions(dbSessions), nil
+}
+
+// GetSessionPage 分页获取用户的会话列表，按 last_msg_time、session_type、target_id 倒序
+func (r *SessionRepository) GetSessionPage(ctx context.Context, req *model.GetSessionsRequest) (*model.SessionPage, error) {
+	var dbSessions []DBSession
+
+	query := r.readSessions(ctx).Where("user_id = ?", req.UserID)
+	if req.Cursor != "" {
+		keys, err := decodeCursor(req.Cursor, 3)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(
+			"(last_msg_time < ? OR (last_msg_time = ? AND (session_type < ? OR (session_type = ? AND target_id < ?))))",
+			keys[0], keys[0], keys[1], keys[1], keys[2],
+		)
+	}
+
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	// 多取一条判断是否还有更多
+	if err := query.Order("last_msg_time DESC").Order("session_type DESC").Order("target_id DESC").
+		Limit(req.Limit + 1).
+		Find(&dbSessions).Error; err != nil {
+		return nil, err
+	}
+
+	page := &model.SessionPage{}
+	if len(dbSessions) > req.Limit {
+		dbSessions = dbSessions[:req.Limit]
+		last := dbSessions[len(dbSessions)-1]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(last.LastMsgTime, int64(last.SessionType), last.TargetID)
+	}
+	page.Sessions = toSessions(dbSessions)
+
+	return page, nil
+}
+
+// toSessions 转换为会话模型
+func toSessions(dbSessions []DBSession) []*model.Session {
 	sessions := make([]*model.Session, len(dbSessions))
 	for i, s := range dbSessions {
 		sessions[i] = &model.Session{
@@ -82,15 +194,53 @@ func (r *SessionRepository) GetUserSessions(userID int64) ([]*model.Session, err
 			LastMsgContent: s.LastMsgContent,
 			LastMsgTime:    s.LastMsgTime,
 			UnreadCount:    s.UnreadCount,
+			LastReadTime:   s.LastReadTime,
 		}
 	}
+	return sessions
+}
+
+// DeleteByUser 删除用户的全部会话
+func (r *SessionRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	return r.sessions(ctx).Where("user_id = ?", userID).Delete(&DBSession{}).Error
+}
+
+// session 指定会话，限定当前租户
+func (r *SessionRepository) session(ctx context.Context, userID, targetID int64, sessionType int) *gorm.DB {
+	return r.sessions(ctx).Model(&DBSession{}).
+		Where("user_id = ? AND target_id = ? AND session_type = ?", userID, targetID, sessionType)
+}
+
+// ClearUnread 清除未读数，已读水位推进到最后一条消息
+func (r *SessionRepository) ClearUnread(ctx context.Context, userID, targetID int64, sessionType int) error {
+	return r.session(ctx, userID, targetID, sessionType).Updates(map[string]interface{}{
+		"last_read_time": gorm.Expr("COALESCE(last_msg_time, 0)"),
+		"unread_count":   0,
+	}).Error
+}
 
-	return sessions, nil
+// MarkRead 推进已读水位到 readTime（只前进不后退），并重新计算未读数
+// 重复调用结果相同
+func (r *SessionRepository) MarkRead(ctx context.Context, userID, targetID int64, sessionType int, readTime int64) error {
+	err := r.session(ctx, userID, targetID, sessionType).
+		Where("last_read_time < ?", readTime).
+		Update("last_read_time", readTime).Error
+	if err != nil {
+		return err
+	}
+	return r.RefreshUnread(ctx, userID, targetID, sessionType)
 }
 
-// ClearUnread 清除未读数
-func (r *SessionRepository) ClearUnread(userID, targetID int64, sessionType int) error {
-	return r.db.Model(&DBSession{}).
-		Where("user_id = ? AND target_id = ? AND session_type = ?", userID, targetID, sessionType).
-		Update("unread_count", 0).Error
+// RefreshUnread 按已读水位重新计算未读数：对方发来且服务端时间晚于 last_read_time 的消息数
+// 由已持久化的消息推导，重复调用结果相同，不会因重试或重复投递多计
+func (r *SessionRepository) RefreshUnread(ctx context.Context, userID, targetID int64, sessionType int) error {
+	cond, args := "m.conversation_id = ? AND m.to_user_id = ?", []interface{}{ConversationID(userID, targetID), userID}
+	if sessionType == model.SessionTypeGroup {
+		cond, args = "m.group_id = ? AND m.from_user_id <> ?", []interface{}{targetID, userID}
+	}
+
+	count := fmt.Sprintf("(SELECT COUNT(*) FROM %s m WHERE m.tenant_id = ? AND %s AND m.server_time > %s.last_read_time)",
+		r.namer.Table(DBMessage{}.TableName()), cond, r.namer.Table(DBSession{}.TableName()))
+	return r.session(ctx, userID, targetID, sessionType).
+		Update("unread_count", gorm.Expr(count, append([]interface{}{r.tenant}, args...)...)).Error
 }
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/im/internal/model"
+)
+
+// DBSupportAgent 客服数据库模型
+type DBSupportAgent struct {
+	UserID           int64 `gorm:"primaryKey;autoIncrement:false"`
+	MaxConversations int   `gorm:"default:0"`
+	CreatedAt        int64 `gorm:"autoCreateTime:milli"`
+}
+
+func (DBSupportAgent) TableName() string {
+	return "im_support_agents"
+}
+
+// DBSupportConversation 客服会话数据库模型
+// OpenCustomerID 在会话未结束时等于 CustomerID，结束后置空，用唯一索引保证同一客户只有一个未结束的会话
+type DBSupportConversation struct {
+	ID             int64  `gorm:"primaryKey;autoIncrement"`
+	CustomerID     int64  `gorm:"index:idx_support_customer;not null"`
+	OpenCustomerID *int64 `gorm:"uniqueIndex:uk_support_open_customer"`
+	AgentID        int64  `gorm:"index:idx_support_agent_status,priority:1;default:0"`
+	Status         int    `gorm:"type:tinyint;index:idx_support_agent_status,priority:2;default:1"`
+	CreatedAt      int64  `gorm:"autoCreateTime:milli"`
+	UpdatedAt      int64  `gorm:"autoUpdateTime:milli"`
+	ClosedAt       int64  `gorm:"default:0"`
+}
+
+func (DBSupportConversation) TableName() string {
+	return "im_support_conversations"
+}
+
+// SupportRepository 客服仓库
+type SupportRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewSupportRepository 创建客服仓库
+func NewSupportRepository(db *gorm.DB, namer *TableNamer) *SupportRepository {
+	return &SupportRepository{db: db, namer: namer}
+}
+
+// agents 客服表
+func (r *SupportRepository) agents(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBSupportAgent{}.TableName()))
+}
+
+// conversations 客服会话表
+func (r *SupportRepository) conversations(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBSupportConversation{}.TableName()))
+}
+
+// InitTables 初始化数据库表（v12 迁移）
+func (r *SupportRepository) InitTables(ctx context.Context) error {
+	if err := r.agents(ctx).AutoMigrate(&DBSupportAgent{}); err != nil {
+		return err
+	}
+	return r.conversations(ctx).AutoMigrate(&DBSupportConversation{})
+}
+
+// SaveAgent 保存客服（如果已存在则更新会话数上限）
+func (r *SupportRepository) SaveAgent(ctx context.Context, userID int64, maxConversations int) error {
+	dbAgent := &DBSupportAgent{
+		UserID:           userID,
+		MaxConversations: maxConversations,
+	}
+	return r.agents(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_conversations"}),
+	}).Create(dbAgent).Error
+}
+
+// DeleteAgent 删除客服，返回是否删除
+func (r *SupportRepository) DeleteAgent(ctx context.Context, userID int64) (bool, error) {
+	result := r.agents(ctx).Where("user_id = ?", userID).Delete(&DBSupportAgent{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetAgent 获取客服，不含会话数和在线状态
+func (r *SupportRepository) GetAgent(ctx context.Context, userID int64) (*model.SupportAgent, error) {
+	var dbAgent DBSupportAgent
+	if err := r.agents(ctx).Where("user_id = ?", userID).First(&dbAgent).Error; err != nil {
+		return nil, err
+	}
+	return toSupportAgent(&dbAgent), nil
+}
+
+// ListAgents 获取全部客服，按用户 ID 升序，不含会话数和在线状态
+func (r *SupportRepository) ListAgents(ctx context.Context) ([]*model.SupportAgent, error) {
+	var dbAgents []DBSupportAgent
+	if err := r.agents(ctx).Order("user_id").Find(&dbAgents).Error; err != nil {
+		return nil, err
+	}
+	agents := make([]*model.SupportAgent, len(dbAgents))
+	for i := range dbAgents {
+		agents[i] = toSupportAgent(&dbAgents[i])
+	}
+	return agents, nil
+}
+
+// CountOpenByAgent 统计各客服进行中的会话数，没有会话的客服不在结果中
+func (r *SupportRepository) CountOpenByAgent(ctx context.Context) (map[int64]int, error) {
+	var rows []struct {
+		AgentID int64
+		Count   int
+	}
+	if err := r.conversations(ctx).Select("agent_id, COUNT(*) AS count").
+		Where("status = ?", model.SupportStatusOpen).Group("agent_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		counts[row.AgentID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetOpenByCustomer 获取客户未结束的会话
+func (r *SupportRepository) GetOpenByCustomer(ctx context.Context, customerID int64) (*model.SupportConversation, error) {
+	var dbConv DBSupportConversation
+	if err := r.conversations(ctx).Where("open_customer_id = ?", customerID).First(&dbConv).Error; err != nil {
+		return nil, err
+	}
+	return toSupportConversation(&dbConv), nil
+}
+
+// Create 为客户创建待分配的会话，客户已有未结束的会话时不重复创建，返回是否新建
+func (r *SupportRepository) Create(ctx context.Context, customerID int64) (bool, error) {
+	dbConv := &DBSupportConversation{
+		CustomerID:     customerID,
+		OpenCustomerID: &customerID,
+		Status:         model.SupportStatusPending,
+	}
+	result := r.conversations(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(dbConv)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Get 获取会话
+func (r *SupportRepository) Get(ctx context.Context, id int64) (*model.SupportConversation, error) {
+	var dbConv DBSupportConversation
+	if err := r.conversations(ctx).First(&dbConv, id).Error; err != nil {
+		return nil, err
+	}
+	return toSupportConversation(&dbConv), nil
+}
+
+// Assign 将未结束的会话分配给客服，agentID 为 0 时转为待分配，fromAgentID 为会话当前的客服，
+// 会话已结束或客服已变化时不更新，返回是否更新
+func (r *SupportRepository) Assign(ctx context.Context, id, fromAgentID, agentID int64, updatedAt int64) (bool, error) {
+	status := model.SupportStatusOpen
+	if agentID == 0 {
+		status = model.SupportStatusPending
+	}
+	result := r.conversations(ctx).
+		Where("id = ? AND agent_id = ? AND status <> ?", id, fromAgentID, model.SupportStatusClosed).
+		Updates(map[string]interface{}{
+			"agent_id":   agentID,
+			"status":     status,
+			"updated_at": updatedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Close 结束未结束的会话，返回是否更新
+func (r *SupportRepository) Close(ctx context.Context, id int64, closedAt int64) (bool, error) {
+	result := r.conversations(ctx).Where("id = ? AND status <> ?", id, model.SupportStatusClosed).Updates(map[string]interface{}{
+		"status":           model.SupportStatusClosed,
+		"open_customer_id": nil,
+		"closed_at":        closedAt,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// List 分页获取会话，按 ID 倒序，agentID 和 status 为 0 时不过滤
+func (r *SupportRepository) List(ctx context.Context, req *model.ListSupportConversationsRequest) (*model.SupportConversationPage, error) {
+	query := r.conversations(ctx)
+	if req.AgentID != 0 {
+		query = query.Where("agent_id = ?", req.AgentID)
+	}
+	if req.Status != 0 {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.Cursor != "" {
+		keys, err := decodeCursor(req.Cursor, 1)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", keys[0])
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	// 多取一条判断是否还有更多
+	var dbConvs []DBSupportConversation
+	if err := query.Order("id DESC").Limit(req.Limit + 1).Find(&dbConvs).Error; err != nil {
+		return nil, err
+	}
+
+	page := &model.SupportConversationPage{}
+	if len(dbConvs) > req.Limit {
+		dbConvs = dbConvs[:req.Limit]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(dbConvs[len(dbConvs)-1].ID)
+	}
+	page.Conversations = make([]*model.SupportConversation, len(dbConvs))
+	for i := range dbConvs {
+		page.Conversations[i] = toSupportConversation(&dbConvs[i])
+	}
+	return page, nil
+}
+
+// DeleteByUser 删除用户作为客户的全部会话和客服身份，用户接待中的会话转为待分配
+func (r *SupportRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	if err := r.conversations(ctx).Where("customer_id = ?", userID).Delete(&DBSupportConversation{}).Error; err != nil {
+		return err
+	}
+	if err := r.conversations(ctx).Where("agent_id = ? AND status = ?", userID, model.SupportStatusOpen).Updates(map[string]interface{}{
+		"agent_id": 0,
+		"status":   model.SupportStatusPending,
+	}).Error; err != nil {
+		return err
+	}
+	return r.agents(ctx).Where("user_id = ?", userID).Delete(&DBSupportAgent{}).Error
+}
+
+// toSupportAgent 转换为客服模型
+func toSupportAgent(dbAgent *DBSupportAgent) *model.SupportAgent {
+	return &model.SupportAgent{
+		UserID:           dbAgent.UserID,
+		MaxConversations: dbAgent.MaxConversations,
+		CreatedAt:        dbAgent.CreatedAt,
+	}
+}
+
+// toSupportConversation 转换为客服会话模型
+func toSupportConversation(dbConv *DBSupportConversation) *model.SupportConversation {
+	return &model.SupportConversation{
+		ID:         dbConv.ID,
+		CustomerID: dbConv.CustomerID,
+		AgentID:    dbConv.AgentID,
+		Status:     dbConv.Status,
+		CreatedAt:  dbConv.CreatedAt,
+		UpdatedAt:  dbConv.UpdatedAt,
+		ClosedAt:   dbConv.ClosedAt,
+	}
+}
@@ -0,0 +1,40 @@
+package repository
+
+import "gorm.io/gorm"
+
+// TableNamer 表名解析器，支持统一前缀和按表覆盖
+type TableNamer struct {
+	// Prefix 表名前缀，拼接在默认表名之前（如 "app_" -> "app_im_messages"）
+	Prefix string
+
+	// Overrides 按默认表名覆盖实际表名（如 {"im_messages": "chat_messages"}），优先于 Prefix
+	Overrides map[string]string
+}
+
+// Table 返回默认表名对应的实际表名
+func (n *TableNamer) Table(defaultName string) string {
+	if n == nil {
+		return defaultName
+	}
+	if name, ok := n.Overrides[defaultName]; ok && name != "" {
+		return name
+	}
+	return n.Prefix + defaultName
+}
+
+// readerOf 返回读查询使用的连接，未配置只读库时使用主库
+func readerOf(db, readDB *gorm.DB) *gorm.DB {
+	if readDB != nil {
+		return readDB
+	}
+	return db
+}
+
+// ensureIndex 索引不存在时创建（通过 Migrator 检查是否存在，兼容 MySQL / SQLite）
+// table 为已指定表名的查询，value 为对应的数据库模型
+func ensureIndex(table *gorm.DB, value interface{}, name, columns string) error {
+	if table.Migrator().HasIndex(value, name) {
+		return nil
+	}
+	return table.Exec("CREATE INDEX " + name + " ON " + table.Statement.Table + "(" + columns + ")").Error
+}
@@ -0,0 +1,101 @@
+// loadtest 命令：对 IM WebSocket 服务进行压测 / 浸泡测试
+//
+// 示例:
+//
+//	go run ./loadtest/cmd/loadtest -url ws://localhost:8080/ws -clients 1000 -rate 2 -duration 5m \
+//	    -token-format "test-token-%d"
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bbadbeef/go-base/im/loadtest"
+)
+
+var (
+	wsURL        = flag.String("url", "ws://localhost:8080/ws", "WebSocket 地址")
+	clients      = flag.Int("clients", 100, "模拟客户端数量")
+	startUserID  = flag.Int64("start-user", 1, "起始用户 ID")
+	rate         = flag.Float64("rate", 1, "每个客户端每秒发送消息数")
+	duration     = flag.Duration("duration", time.Minute, "发送持续时间")
+	drain        = flag.Duration("drain", 5*time.Second, "停止发送后等待 ACK 的时间")
+	connInterval = flag.Duration("connect-interval", 0, "建立连接的间隔")
+	tokenFormat  = flag.String("token-format", "%d", "Token 格式（printf 格式，参数为用户 ID）")
+	tokenFile    = flag.String("tokens", "", "Token 文件，每行 \"user_id token\"，优先于 -token-format")
+	jsonOutput   = flag.Bool("json", false, "以 JSON 格式输出报告")
+)
+
+func main() {
+	flag.Parse()
+
+	tokenFunc := func(userID int64) string {
+		return fmt.Sprintf(*tokenFormat, userID)
+	}
+	if *tokenFile != "" {
+		tokens, err := loadTokens(*tokenFile)
+		if err != nil {
+			log.Fatalf("Failed to load tokens: %v", err)
+		}
+		tokenFunc = func(userID int64) string {
+			return tokens[userID]
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	report, err := loadtest.Run(ctx, &loadtest.Config{
+		URL:             *wsURL,
+		Clients:         *clients,
+		StartUserID:     *startUserID,
+		TokenFunc:       tokenFunc,
+		Rate:            *rate,
+		Duration:        *duration,
+		DrainTimeout:    *drain,
+		ConnectInterval: *connInterval,
+	})
+	if err != nil {
+		log.Fatalf("Load test failed: %v", err)
+	}
+
+	if *jsonOutput {
+		json.NewEncoder(os.Stdout).Encode(report)
+		return
+	}
+	fmt.Println(report)
+}
+
+// loadTokens 读取 Token 文件
+func loadTokens(path string) (map[int64]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tokens := make(map[int64]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		userID, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		tokens[userID] = fields[1]
+	}
+	return tokens, scanner.Err()
+}
@@ -0,0 +1,356 @@
+// Package loadtest 提供 IM Hub 的压测 / 浸泡测试工具
+// 模拟 K 个 WebSocket 客户端按配置速率互相发送消息，统计投递延迟分位数和 ACK 丢失
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/bbadbeef/go-base/im/internal/protocol"
+)
+
+// Config 压测配置
+type Config struct {
+	// URL WebSocket 地址，如 "ws://localhost:8080/ws"
+	URL string
+
+	// Clients 模拟客户端数量
+	Clients int
+
+	// StartUserID 第一个模拟客户端的用户 ID，后续客户端依次递增
+	StartUserID int64
+
+	// TokenFunc 根据用户 ID 生成连接 Token（由被测应用的认证方式决定）
+	TokenFunc func(userID int64) string
+
+	// Rate 每个客户端每秒发送的消息数
+	Rate float64
+
+	// Duration 发送持续时间
+	Duration time.Duration
+
+	// DrainTimeout 停止发送后等待 ACK 和投递的时间，默认 5 秒
+	DrainTimeout time.Duration
+
+	// ConnectInterval 建立连接的间隔，避免瞬时建连风暴，默认不间隔
+	ConnectInterval time.Duration
+
+	// Content 消息内容，默认 "loadtest"
+	Content string
+}
+
+// Latency 延迟分位数统计
+type Latency struct {
+	Count int           `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// Report 压测报告
+type Report struct {
+	Clients        int           `json:"clients"`         // 成功连接的客户端数
+	ConnectErrors  int64         `json:"connect_errors"`  // 连接失败数
+	Sent           int64         `json:"sent"`            // 发送消息数
+	Acked          int64         `json:"acked"`           // 收到成功 ACK 数
+	AckFailed      int64         `json:"ack_failed"`      // 收到失败 ACK 数
+	AckLost        int64         `json:"ack_lost"`        // 未收到 ACK 数
+	Delivered      int64         `json:"delivered"`       // 接收方收到的消息数
+	DeliveryLost   int64         `json:"delivery_lost"`   // 未投递的消息数
+	AckLatency     Latency       `json:"ack_latency"`     // 发送 -> ACK 延迟
+	DeliverLatency Latency       `json:"deliver_latency"` // 发送 -> 接收方收到 延迟
+	Elapsed        time.Duration `json:"elapsed"`         // 总耗时
+}
+
+// String 格式化输出报告
+func (r *Report) String() string {
+	return fmt.Sprintf(
+		"clients=%d connect_errors=%d sent=%d acked=%d ack_failed=%d ack_lost=%d delivered=%d delivery_lost=%d elapsed=%s\n"+
+			"ack latency:     n=%d p50=%s p90=%s p99=%s max=%s\n"+
+			"deliver latency: n=%d p50=%s p90=%s p99=%s max=%s",
+		r.Clients, r.ConnectErrors, r.Sent, r.Acked, r.AckFailed, r.AckLost, r.Delivered, r.DeliveryLost, r.Elapsed,
+		r.AckLatency.Count, r.AckLatency.P50, r.AckLatency.P90, r.AckLatency.P99, r.AckLatency.Max,
+		r.DeliverLatency.Count, r.DeliverLatency.P50, r.DeliverLatency.P90, r.DeliverLatency.P99, r.DeliverLatency.Max,
+	)
+}
+
+// runner 一次压测的运行状态
+type runner struct {
+	config *Config
+
+	sendTimes sync.Map // msgID -> time.Time
+
+	sent          int64
+	acked         int64
+	ackFailed     int64
+	delivered     int64
+	connectErrors int64
+
+	latencyMutex   sync.Mutex
+	ackLatency     []time.Duration
+	deliverLatency []time.Duration
+}
+
+// simClient 模拟客户端
+type simClient struct {
+	userID int64
+	conn   *websocket.Conn
+	mutex  sync.Mutex // websocket 写操作不支持并发
+}
+
+// Run 执行压测，返回报告
+func Run(ctx context.Context, config *Config) (*Report, error) {
+	if config == nil || config.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if config.Clients < 2 {
+		return nil, fmt.Errorf("at least 2 clients are required")
+	}
+	if config.TokenFunc == nil {
+		return nil, fmt.Errorf("token func is required")
+	}
+	if config.Rate <= 0 {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+	if config.DrainTimeout == 0 {
+		config.DrainTimeout = 5 * time.Second
+	}
+	if config.Content == "" {
+		config.Content = "loadtest"
+	}
+
+	r := &runner{config: config}
+	start := time.Now()
+
+	// 1. 建立连接
+	clients := r.connectAll(ctx)
+	if len(clients) < 2 {
+		return nil, fmt.Errorf("only %d clients connected", len(clients))
+	}
+	defer func() {
+		for _, c := range clients {
+			c.conn.Close()
+		}
+	}()
+
+	var readers sync.WaitGroup
+	for _, c := range clients {
+		readers.Add(1)
+		go func(c *simClient) {
+			defer readers.Done()
+			r.readLoop(c)
+		}(c)
+	}
+
+	// 2. 按速率发送，客户端 i 发给客户端 i+1（环形）
+	sendCtx, cancel := context.WithTimeout(ctx, config.Duration)
+	defer cancel()
+
+	var senders sync.WaitGroup
+	for i, c := range clients {
+		peer := clients[(i+1)%len(clients)]
+		senders.Add(1)
+		go func(c, peer *simClient) {
+			defer senders.Done()
+			r.sendLoop(sendCtx, c, peer.userID)
+		}(c, peer)
+	}
+	senders.Wait()
+
+	// 3. 等待 ACK 和投递
+	select {
+	case <-ctx.Done():
+	case <-time.After(config.DrainTimeout):
+	}
+
+	for _, c := range clients {
+		c.conn.Close()
+	}
+	readers.Wait()
+
+	return r.report(len(clients), time.Since(start)), nil
+}
+
+// connectAll 建立所有客户端连接
+func (r *runner) connectAll(ctx context.Context) []*simClient {
+	clients := make([]*simClient, 0, r.config.Clients)
+	for i := 0; i < r.config.Clients; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		userID := r.config.StartUserID + int64(i)
+		conn, err := r.dial(ctx, userID)
+		if err != nil {
+			atomic.AddInt64(&r.connectErrors, 1)
+			continue
+		}
+		clients = append(clients, &simClient{userID: userID, conn: conn})
+
+		if r.config.ConnectInterval > 0 {
+			time.Sleep(r.config.ConnectInterval)
+		}
+	}
+	return clients
+}
+
+// dial 建立单个 WebSocket 连接
+func (r *runner) dial(ctx context.Context, userID int64) (*websocket.Conn, error) {
+	u, err := url.Parse(r.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("token", r.config.TokenFunc(userID))
+	u.RawQuery = query.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	return conn, err
+}
+
+// sendLoop 按速率发送消息
+func (r *runner) sendLoop(ctx context.Context, c *simClient, toUserID int64) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / r.config.Rate))
+	defer ticker.Stop()
+
+	var seq int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			seq++
+			msgID := "lt-" + strconv.FormatInt(c.userID, 10) + "-" + strconv.FormatInt(seq, 10)
+			now := time.Now()
+
+			msg, _ := protocol.Encode(protocol.WSMsgTypeChatMsg, msgID, now.UnixMilli(), &protocol.WSChatMessage{
+				MsgID:      msgID,
+				ToUserID:   toUserID,
+				Content:    r.config.Content,
+				MsgType:    1,
+				ClientTime: now.UnixMilli(),
+			})
+
+			r.sendTimes.Store(msgID, now)
+			c.mutex.Lock()
+			err := c.conn.WriteMessage(websocket.TextMessage, msg)
+			c.mutex.Unlock()
+			if err != nil {
+				r.sendTimes.Delete(msgID)
+				return
+			}
+			atomic.AddInt64(&r.sent, 1)
+		}
+	}
+}
+
+// readLoop 读取 ACK 和推送消息
+func (r *runner) readLoop(c *simClient) {
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		now := time.Now()
+
+		var wsMsg protocol.WSMessage
+		if err := json.Unmarshal(data, &wsMsg); err != nil {
+			continue
+		}
+
+		switch wsMsg.Type {
+		case protocol.WSMsgTypeAck:
+			var ack protocol.WSAckMessage
+			if wsMsg.DecodeData(&ack) != nil {
+				continue
+			}
+			if ack.Error != "" {
+				atomic.AddInt64(&r.ackFailed, 1)
+				continue
+			}
+			atomic.AddInt64(&r.acked, 1)
+			if sentAt, ok := r.sendTimes.Load(ack.MsgID); ok {
+				r.recordLatency(&r.ackLatency, now.Sub(sentAt.(time.Time)))
+			}
+		case protocol.WSMsgTypeChatMsg:
+			var push protocol.WSPushMessage
+			if wsMsg.DecodeData(&push) != nil {
+				continue
+			}
+			atomic.AddInt64(&r.delivered, 1)
+			if sentAt, ok := r.sendTimes.Load(push.MsgID); ok {
+				r.recordLatency(&r.deliverLatency, now.Sub(sentAt.(time.Time)))
+			}
+		}
+	}
+}
+
+// recordLatency 记录一次延迟样本
+func (r *runner) recordLatency(samples *[]time.Duration, d time.Duration) {
+	r.latencyMutex.Lock()
+	*samples = append(*samples, d)
+	r.latencyMutex.Unlock()
+}
+
+// report 汇总报告
+func (r *runner) report(clients int, elapsed time.Duration) *Report {
+	report := &Report{
+		Clients:       clients,
+		ConnectErrors: atomic.LoadInt64(&r.connectErrors),
+		Sent:          atomic.LoadInt64(&r.sent),
+		Acked:         atomic.LoadInt64(&r.acked),
+		AckFailed:     atomic.LoadInt64(&r.ackFailed),
+		Delivered:     atomic.LoadInt64(&r.delivered),
+		Elapsed:       elapsed,
+	}
+
+	report.AckLost = report.Sent - report.Acked - report.AckFailed
+	if report.AckLost < 0 {
+		report.AckLost = 0
+	}
+	report.DeliveryLost = report.Acked - report.Delivered
+	if report.DeliveryLost < 0 {
+		report.DeliveryLost = 0
+	}
+
+	r.latencyMutex.Lock()
+	report.AckLatency = percentiles(r.ackLatency)
+	report.DeliverLatency = percentiles(r.deliverLatency)
+	r.latencyMutex.Unlock()
+
+	return report
+}
+
+// percentiles 计算延迟分位数
+func percentiles(samples []time.Duration) Latency {
+	if len(samples) == 0 {
+		return Latency{}
+	}
+
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	at := func(p float64) time.Duration {
+		idx := int(float64(len(sorted)-1) * p)
+		return sorted[idx]
+	}
+
+	return Latency{
+		Count: len(sorted),
+		P50:   at(0.50),
+		P90:   at(0.90),
+		P99:   at(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
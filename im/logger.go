@@ -4,8 +4,8 @@ import (
 	"github.com/bbadbeef/go-base/im/internal/log"
 )
 
-// Logger 日志接口
-// 任何实现了这个接口的日志实例都可以传入 IM 模块使用
+// Logger 日志接口，即根目录 log 模块的 log.Logger，与 user、storage 模块共用
+// 任何实现了这个接口的日志实例都可以传入 IM 模块使用，同一实例也可以设置到 user.Config.Logger 和 storage.Config.Logger
 type Logger = log.Logger
 
 // LogConfig 日志配置（用于 logrus）
@@ -17,17 +17,19 @@ CREATE TABLE IF NOT EXISTS im_user_routes (
     last_heartbeat BIGINT NOT NULL COMMENT '最后心跳时间戳（秒）',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '连接建立时间',
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
-    INDEX idx_server (server_id),
-    INDEX idx_heartbeat (last_heartbeat)
+    INDEX idx_route_server (server_id),
+    INDEX idx_route_heartbeat (last_heartbeat)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户路由表';
 
 -- 消息表
 CREATE TABLE IF NOT EXISTS im_messages (
     id BIGINT PRIMARY KEY AUTO_INCREMENT COMMENT '自增 ID',
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT '租户 ID（未启用多租户时为空）',
     msg_id VARCHAR(64) UNIQUE NOT NULL COMMENT '消息唯一 ID',
     from_user_id BIGINT NOT NULL COMMENT '发送者用户 ID',
     to_user_id BIGINT NOT NULL COMMENT '接收者用户 ID',
     group_id BIGINT DEFAULT 0 COMMENT '群组 ID（0 表示单聊）',
+    conversation_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT '单聊会话 ID（有序用户对 "小ID:大ID"，群聊为空）',
     content TEXT NOT NULL COMMENT '消息内容',
     msg_type TINYINT DEFAULT 1 COMMENT '消息类型（1:文本 2:图片 3:语音 4:视频 5:文件）',
     status TINYINT DEFAULT 1 COMMENT '消息状态（1:发送中 2:已发送 3:已送达 4:已读 5:失败）',
@@ -40,21 +42,25 @@ CREATE TABLE IF NOT EXISTS im_messages (
     INDEX idx_from (from_user_id, server_time DESC),
     INDEX idx_to (to_user_id, status, server_time DESC),
     INDEX idx_group (group_id, server_time DESC),
-    INDEX idx_server_time (server_time DESC, id DESC)
+    INDEX idx_server_time (server_time DESC, id DESC),
+    INDEX idx_conversation_time (tenant_id, conversation_id, server_time DESC)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='消息表';
 
 -- 会话表
 CREATE TABLE IF NOT EXISTS im_sessions (
     user_id BIGINT NOT NULL COMMENT '用户 ID',
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT '租户 ID（未启用多租户时为空）',
     target_id BIGINT NOT NULL COMMENT '对方用户 ID 或群组 ID',
     session_type TINYINT DEFAULT 1 COMMENT '会话类型（1:单聊 2:群聊）',
     last_msg_content TEXT COMMENT '最后一条消息内容',
     last_msg_time BIGINT COMMENT '最后消息时间戳（毫秒）',
-    unread_count INT DEFAULT 0 COMMENT '未读消息数',
+    unread_count INT DEFAULT 0 COMMENT '未读消息数（由已读水位计算）',
+    last_read_time BIGINT NOT NULL DEFAULT 0 COMMENT '已读水位：已读到的最新消息服务端时间戳（毫秒）',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     PRIMARY KEY (user_id, target_id, session_type),
-    INDEX idx_user_time (user_id, last_msg_time DESC)
+    INDEX idx_user_time (user_id, last_msg_time DESC),
+    INDEX idx_user_last_msg (tenant_id, user_id, last_msg_time DESC)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='会话表';
 
 -- 群组表
@@ -63,6 +69,10 @@ CREATE TABLE IF NOT EXISTS im_groups (
     group_name VARCHAR(100) NOT NULL COMMENT '群组名称',
     owner_id BIGINT NOT NULL COMMENT '群主用户 ID',
     avatar_url VARCHAR(255) COMMENT '群头像 URL',
+    slow_mode INT NOT NULL DEFAULT 0 COMMENT '慢速模式：成员两条消息的最小间隔（秒），0 表示不限制',
+    block_links BOOLEAN NOT NULL DEFAULT FALSE COMMENT '禁止普通成员发送链接',
+    block_images BOOLEAN NOT NULL DEFAULT FALSE COMMENT '禁止普通成员发送图片',
+    newcomer_mute INT NOT NULL DEFAULT 0 COMMENT '新成员入群后的禁言时长（秒），0 表示不禁言',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='群组表';
@@ -75,6 +85,104 @@ CREATE TABLE IF NOT EXISTS im_group_members (
     role TINYINT DEFAULT 0 COMMENT '角色（0:普通成员 1:管理员 2:群主）',
     joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '加入时间',
     UNIQUE KEY uk_group_user (group_id, user_id),
-    INDEX idx_user (user_id),
-    INDEX idx_group (group_id)
+    INDEX idx_member_user (user_id),
+    INDEX idx_member_group (group_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='群成员表';
+
+-- 草稿表
+CREATE TABLE IF NOT EXISTS im_drafts (
+    user_id BIGINT NOT NULL COMMENT '用户 ID',
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT '租户 ID（未启用多租户时为空）',
+    target_id BIGINT NOT NULL COMMENT '对方用户 ID 或群组 ID',
+    session_type TINYINT DEFAULT 1 COMMENT '会话类型（1:单聊 2:群聊）',
+    content TEXT COMMENT '草稿内容',
+    updated_at BIGINT NOT NULL COMMENT '更新时间戳（毫秒）',
+    PRIMARY KEY (user_id, target_id, session_type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='会话草稿表';
+
+-- 免打扰时段表
+CREATE TABLE IF NOT EXISTS im_dnd_schedules (
+    user_id BIGINT PRIMARY KEY COMMENT '用户 ID',
+    enabled TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否启用',
+    start_time VARCHAR(5) NOT NULL COMMENT '开始时间（HH:MM）',
+    end_time VARCHAR(5) NOT NULL COMMENT '结束时间（HH:MM）',
+    timezone VARCHAR(64) COMMENT '时区',
+    updated_at BIGINT COMMENT '更新时间戳（毫秒）'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='免打扰时段表';
+
+-- 会话通知偏好表
+CREATE TABLE IF NOT EXISTS im_notification_prefs (
+    user_id BIGINT NOT NULL COMMENT '用户 ID',
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT '租户 ID（未启用多租户时为空）',
+    target_id BIGINT NOT NULL COMMENT '对方用户 ID 或群组 ID',
+    session_type TINYINT DEFAULT 1 COMMENT '会话类型（1:单聊 2:群聊）',
+    level TINYINT DEFAULT 0 COMMENT '通知级别（0:全部 1:仅@我 2:不通知）',
+    mute_until BIGINT NOT NULL DEFAULT 0 COMMENT '通知级别生效截止时间戳（毫秒），0 表示永久',
+    updated_at BIGINT COMMENT '更新时间戳（毫秒）',
+    PRIMARY KEY (user_id, target_id, session_type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='会话通知偏好表';
+
+-- 消息举报表
+CREATE TABLE IF NOT EXISTS im_message_reports (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    msg_id VARCHAR(64) NOT NULL COMMENT '被举报的消息 ID',
+    reporter_id BIGINT NOT NULL COMMENT '举报人用户 ID',
+    reason VARCHAR(500) NOT NULL COMMENT '举报原因',
+    from_user_id BIGINT NOT NULL COMMENT '消息发送者 ID',
+    group_id BIGINT DEFAULT 0 COMMENT '群组 ID（单聊为 0）',
+    content TEXT COMMENT '举报时的消息内容快照',
+    msg_type TINYINT DEFAULT 1 COMMENT '消息类型',
+    file_id VARCHAR(64) COMMENT '文件 ID',
+    status TINYINT DEFAULT 1 COMMENT '处理状态（1:待处理 2:已处理 3:已驳回）',
+    resolver_id BIGINT DEFAULT 0 COMMENT '处理人用户 ID',
+    note VARCHAR(500) COMMENT '处理备注',
+    created_at BIGINT COMMENT '举报时间戳（毫秒）',
+    resolved_at BIGINT DEFAULT 0 COMMENT '处理时间戳（毫秒）',
+    UNIQUE KEY uk_report_msg_reporter (msg_id, reporter_id),
+    INDEX idx_report_reporter (reporter_id),
+    INDEX idx_report_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='消息举报表';
+
+-- 离开状态表
+CREATE TABLE IF NOT EXISTS im_away_statuses (
+    user_id BIGINT PRIMARY KEY COMMENT '用户 ID',
+    message VARCHAR(500) NOT NULL COMMENT '自动回复内容',
+    updated_at BIGINT COMMENT '设置时间戳（毫秒）'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='离开状态表';
+
+-- 用户在线状态表（未设置时为 online）
+CREATE TABLE IF NOT EXISTS im_user_presence (
+    user_id BIGINT PRIMARY KEY COMMENT '用户 ID',
+    status VARCHAR(16) NOT NULL COMMENT '在线状态（online、busy、away、invisible）',
+    text VARCHAR(100) COMMENT '状态文字',
+    updated_at BIGINT COMMENT '设置时间戳（毫秒）'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户在线状态表';
+
+-- 客服表
+CREATE TABLE IF NOT EXISTS im_support_agents (
+    user_id BIGINT PRIMARY KEY COMMENT '客服用户 ID',
+    max_conversations INT DEFAULT 0 COMMENT '同时接待的会话数上限（0 表示不限制）',
+    created_at BIGINT COMMENT '加入时间戳（毫秒）'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='客服表';
+
+-- 客服会话表
+CREATE TABLE IF NOT EXISTS im_support_conversations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    customer_id BIGINT NOT NULL COMMENT '客户用户 ID（访客为负数）',
+    open_customer_id BIGINT NULL COMMENT '未结束时等于 customer_id，结束后为 NULL，保证每个客户只有一个未结束的会话',
+    agent_id BIGINT DEFAULT 0 COMMENT '接待客服用户 ID（待分配时为 0）',
+    status TINYINT DEFAULT 1 COMMENT '状态（1:待分配 2:进行中 3:已结束）',
+    created_at BIGINT COMMENT '创建时间戳（毫秒）',
+    updated_at BIGINT COMMENT '最后分配或转接时间戳（毫秒）',
+    closed_at BIGINT DEFAULT 0 COMMENT '结束时间戳（毫秒）',
+    UNIQUE KEY uk_support_open_customer (open_customer_id),
+    INDEX idx_support_customer (customer_id),
+    INDEX idx_support_agent_status (agent_id, status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='客服会话表';
+
+-- 数据库迁移记录表
+CREATE TABLE IF NOT EXISTS im_schema_migrations (
+    version BIGINT PRIMARY KEY COMMENT '迁移版本号',
+    name VARCHAR(128) NOT NULL COMMENT '迁移名称',
+    applied_at BIGINT NOT NULL COMMENT '执行时间戳（毫秒）'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='IM 数据库迁移记录表';
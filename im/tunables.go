@@ -0,0 +1,77 @@
+package im
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bbadbeef/go-base/config"
+	"github.com/bbadbeef/go-base/im/internal/log"
+)
+
+// WatchTunables 监听配置文件，文件变更后热更新服务的运行期配置，ctx 取消时停止监听
+// 读取 YAML / JSON 文件的 tunables 段（没有时读取整个文档），文件中未出现的字段保持调用时的值：
+//
+//	tunables:
+//	  log_level: debug
+//	  cache_ttl: 10
+//	  max_message_size: 4096
+//	  rate_limit: 10
+//	  bandwidth_limit: 65536
+//
+// 首次加载失败时返回错误；之后按 interval（<= 0 时为 5 秒）检查文件修改时间，加载或校验失败时记录日志并保持原配置
+// 配置中心等其他来源可直接在变更回调中调用 UpdateTunables
+func WatchTunables(ctx context.Context, svc IMService, path string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	base := svc.Tunables()
+	load := func() error {
+		t := base
+		if err := config.Load(path, "tunables", &t); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+		}
+		return svc.UpdateTunables(t)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := load(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		modTime, size := info.ModTime(), info.Size()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Warnf("Failed to stat tunables file %s: %v", path, err)
+				continue
+			}
+			if info.ModTime().Equal(modTime) && info.Size() == size {
+				continue
+			}
+			modTime, size = info.ModTime(), info.Size()
+
+			if err := load(); err != nil {
+				log.Warnf("Failed to reload tunables from %s: %v", path, err)
+				continue
+			}
+			log.Infof("Tunables reloaded from %s", path)
+		}
+	}()
+	return nil
+}
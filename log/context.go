@@ -0,0 +1,86 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader 传递请求 ID 的 HTTP 头
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDField 日志中请求 ID 的字段名
+const RequestIDField = "request_id"
+
+// maxRequestIDLength 接受的上游请求 ID 最大长度，超过时重新生成
+const maxRequestIDLength = 128
+
+// requestIDKey context 中请求 ID 的键
+type requestIDKey struct{}
+
+// WithRequestID 返回携带请求 ID 的 context，各模块以该 context 记录的日志附带 request_id 字段
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 获取 context 中的请求 ID，未设置时返回空字符串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// EnsureRequestID 返回携带请求 ID 的 context，已有请求 ID 时原样返回，否则生成新的请求 ID
+func EnsureRequestID(ctx context.Context) context.Context {
+	if RequestID(ctx) != "" {
+		return ctx
+	}
+	return WithRequestID(ctx, NewRequestID())
+}
+
+// NewRequestID 生成 32 位十六进制随机请求 ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// FromContext 返回附带 ctx 中请求 ID 的 Logger，未设置请求 ID 或 logger 不支持结构化日志时原样返回
+func FromContext(logger Logger, ctx context.Context) Logger {
+	if id := RequestID(ctx); id != "" {
+		return WithField(logger, RequestIDField, id)
+	}
+	return logger
+}
+
+// RequestIDMiddleware 为每个请求设置请求 ID：context 中已有请求 ID 时沿用（中间件重复挂载），
+// 否则优先使用请求头 X-Request-ID（便于与网关、上游服务的日志关联），没有或不合法时生成新的请求 ID，并写入响应头 X-Request-ID
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := RequestID(r.Context())
+		if id == "" {
+			id = r.Header.Get(RequestIDHeader)
+		}
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID 请求 ID 非空、长度不超过 128 且只含可见 ASCII 字符，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+// Package gormlog 将 GORM 的 SQL 日志写入 log.Logger，日志附带 context 中的请求 ID
+// 各模块的数据库操作均通过 db.WithContext(ctx) 执行，设置后可按 request_id 关联一次请求的 HTTP、IM 和 SQL 日志
+package gormlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	golog "github.com/bbadbeef/go-base/log"
+)
+
+// Config GORM 日志配置
+type Config struct {
+	SlowThreshold             time.Duration       // 慢查询阈值，默认 200ms，小于 0 时不记录慢查询
+	LogLevel                  gormlogger.LogLevel // 日志级别，默认 gormlogger.Warn（只记录慢查询和错误），gormlogger.Info 记录全部 SQL
+	IgnoreRecordNotFoundError bool                // 不记录 gorm.ErrRecordNotFound 错误
+}
+
+// Logger 实现 gormlogger.Interface，SQL 错误记录为错误级别，慢查询为警告级别，LogLevel 为 gormlogger.Info 时其余 SQL 为信息级别
+type Logger struct {
+	logger golog.Logger
+	config Config
+}
+
+// New 创建 GORM 日志，通过 gorm.Config{Logger: gormlog.New(logger, gormlog.Config{})} 设置
+func New(logger golog.Logger, config Config) *Logger {
+	if config.SlowThreshold == 0 {
+		config.SlowThreshold = 200 * time.Millisecond
+	}
+	if config.LogLevel == 0 {
+		config.LogLevel = gormlogger.Warn
+	}
+	return &Logger{logger: golog.OrStd(logger), config: config}
+}
+
+var _ gormlogger.Interface = (*Logger)(nil)
+
+// LogMode 返回指定日志级别的副本，用于 db.Debug() 等
+func (l *Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.config.LogLevel = level
+	return &clone
+}
+
+// Info 信息日志
+func (l *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Info {
+		golog.FromContext(l.logger, ctx).Infof("gorm: "+msg, data...)
+	}
+}
+
+// Warn 警告日志
+func (l *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Warn {
+		golog.FromContext(l.logger, ctx).Warnf("gorm: "+msg, data...)
+	}
+}
+
+// Error 错误日志
+func (l *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= gormlogger.Error {
+		golog.FromContext(l.logger, ctx).Errorf("gorm: "+msg, data...)
+	}
+}
+
+// Trace 记录一条 SQL 的执行结果
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.config.LogLevel <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	fields := func(sql string, rows int64) golog.Logger {
+		return golog.WithFields(golog.FromContext(l.logger, ctx), golog.Fields{
+			"sql":      sql,
+			"rows":     rows,
+			"duration": elapsed,
+		})
+	}
+
+	switch {
+	case err != nil && l.config.LogLevel >= gormlogger.Error &&
+		!(l.config.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)):
+		sql, rows := fc()
+		golog.WithField(fields(sql, rows), "err", err).Error("gorm: query failed")
+	case l.config.SlowThreshold > 0 && elapsed > l.config.SlowThreshold && l.config.LogLevel >= gormlogger.Warn:
+		sql, rows := fc()
+		fields(sql, rows).Warn(fmt.Sprintf("gorm: slow query >= %s", l.config.SlowThreshold))
+	case l.config.LogLevel >= gormlogger.Info:
+		sql, rows := fc()
+		fields(sql, rows).Info("gorm: query")
+	}
+}
@@ -0,0 +1,72 @@
+// Package log user / im / storage 共用的日志接口
+// 各模块通过 Config.Logger（im 为 SetLogger）接收 Logger，任何实现了 Logger 的日志实例（logrus、zap、zerolog 的适配器等）都可以传入
+// 未设置时 user、storage 使用 NewStd 写入标准库 log，im 使用内置的 logrus
+package log
+
+// Logger 日志接口
+type Logger interface {
+	Debug(args ...interface{})
+	Debugf(format string, args ...interface{})
+
+	Info(args ...interface{})
+	Infof(format string, args ...interface{})
+
+	Warn(args ...interface{})
+	Warnf(format string, args ...interface{})
+
+	Error(args ...interface{})
+	Errorf(format string, args ...interface{})
+
+	Fatal(args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// WithFielder 支持结构化日志的接口（可选）
+type WithFielder interface {
+	WithField(key string, value interface{}) Logger
+	WithFields(fields map[string]interface{}) Logger
+}
+
+// Fields 结构化日志字段
+type Fields = map[string]interface{}
+
+// WithField 添加单个字段，logger 不支持结构化日志时原样返回
+func WithField(logger Logger, key string, value interface{}) Logger {
+	if wf, ok := logger.(WithFielder); ok {
+		return wf.WithField(key, value)
+	}
+	return logger
+}
+
+// WithFields 添加多个字段，logger 不支持结构化日志时原样返回
+func WithFields(logger Logger, fields Fields) Logger {
+	if wf, ok := logger.(WithFielder); ok {
+		return wf.WithFields(fields)
+	}
+	return logger
+}
+
+// Nop 丢弃所有日志（Fatal 同样不退出进程），用于关闭模块日志
+var Nop Logger = nopLogger{}
+
+// nopLogger 丢弃所有日志
+type nopLogger struct{}
+
+func (nopLogger) Debug(...interface{})          {}
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Info(...interface{})           {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warn(...interface{})           {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Error(...interface{})          {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+func (nopLogger) Fatal(...interface{})          {}
+func (nopLogger) Fatalf(string, ...interface{}) {}
+
+// OrStd logger 为 nil 时返回 NewStd(nil, LevelInfo)
+func OrStd(logger Logger) Logger {
+	if logger == nil {
+		return NewStd(nil, LevelInfo)
+	}
+	return logger
+}
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Slog 标准库 log/slog 适配器，实现 Logger 和 WithFielder，字段写入 slog 属性
+type Slog struct {
+	logger *slog.Logger
+}
+
+// NewSlog 创建 slog 适配器，logger 为 nil 时使用 slog.Default()
+func NewSlog(logger *slog.Logger) *Slog {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Slog{logger: logger}
+}
+
+// Debug 调试日志
+func (l *Slog) Debug(args ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprint(args...))
+}
+
+// Debugf 调试日志（格式化）
+func (l *Slog) Debugf(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Info 信息日志
+func (l *Slog) Info(args ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprint(args...))
+}
+
+// Infof 信息日志（格式化）
+func (l *Slog) Infof(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Warn 警告日志
+func (l *Slog) Warn(args ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprint(args...))
+}
+
+// Warnf 警告日志（格式化）
+func (l *Slog) Warnf(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Error 错误日志
+func (l *Slog) Error(args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprint(args...))
+}
+
+// Errorf 错误日志（格式化）
+func (l *Slog) Errorf(format string, args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, args...))
+}
+
+// Fatal 记录错误日志后退出进程（slog 没有 Fatal 级别）
+func (l *Slog) Fatal(args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+// Fatalf 记录错误日志后退出进程（slog 没有 Fatal 级别）
+func (l *Slog) Fatalf(format string, args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// WithField 返回附加单个属性的 Logger
+func (l *Slog) WithField(key string, value interface{}) Logger {
+	return &Slog{logger: l.logger.With(key, value)}
+}
+
+// WithFields 返回附加多个属性的 Logger
+func (l *Slog) WithFields(fields Fields) Logger {
+	args := make([]interface{}, 0, len(fields)*2)
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	return &Slog{logger: l.logger.With(args...)}
+}
+
+// Unwrap 返回底层的 *slog.Logger
+func (l *Slog) Unwrap() *slog.Logger {
+	return l.logger
+}
+
+// log 输出一条日志
+func (l *Slog) log(level slog.Level, msg string) {
+	l.logger.Log(context.Background(), level, msg)
+}
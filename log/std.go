@@ -0,0 +1,125 @@
+package log
+
+import (
+	"fmt"
+	stdlog "log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 日志级别
+const (
+	LevelDebug = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelNames 各级别在日志中的名称
+var levelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+// Std 写入标准库 log 的 Logger，支持结构化字段，以 "LEVEL 消息 key=value ..." 格式输出，并发安全
+type Std struct {
+	out    *stdlog.Logger
+	level  int
+	fields string // 已格式化的字段，以空格开头
+}
+
+// NewStd 创建写入 out 的 Logger，低于 level 的日志被丢弃，out 为 nil 时使用标准库 log 的默认 Logger
+func NewStd(out *stdlog.Logger, level int) *Std {
+	if out == nil {
+		out = stdlog.Default()
+	}
+	return &Std{out: out, level: level}
+}
+
+// Debug 调试日志
+func (l *Std) Debug(args ...interface{}) {
+	l.print(LevelDebug, fmt.Sprint(args...))
+}
+
+// Debugf 调试日志（格式化）
+func (l *Std) Debugf(format string, args ...interface{}) {
+	l.print(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Info 信息日志
+func (l *Std) Info(args ...interface{}) {
+	l.print(LevelInfo, fmt.Sprint(args...))
+}
+
+// Infof 信息日志（格式化）
+func (l *Std) Infof(format string, args ...interface{}) {
+	l.print(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Warn 警告日志
+func (l *Std) Warn(args ...interface{}) {
+	l.print(LevelWarn, fmt.Sprint(args...))
+}
+
+// Warnf 警告日志（格式化）
+func (l *Std) Warnf(format string, args ...interface{}) {
+	l.print(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Error 错误日志
+func (l *Std) Error(args ...interface{}) {
+	l.print(LevelError, fmt.Sprint(args...))
+}
+
+// Errorf 错误日志（格式化）
+func (l *Std) Errorf(format string, args ...interface{}) {
+	l.print(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Fatal 记录错误日志后退出进程
+func (l *Std) Fatal(args ...interface{}) {
+	l.print(LevelError, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+// Fatalf 记录错误日志后退出进程
+func (l *Std) Fatalf(format string, args ...interface{}) {
+	l.print(LevelError, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// WithField 返回附加单个字段的 Logger
+func (l *Std) WithField(key string, value interface{}) Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+// WithFields 返回附加多个字段的 Logger，字段按键排序输出
+func (l *Std) WithFields(fields Fields) Logger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(l.fields)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%s", k, formatValue(fields[k]))
+	}
+	return &Std{out: l.out, level: l.level, fields: b.String()}
+}
+
+// print 输出一条日志
+func (l *Std) print(level int, msg string) {
+	if level < l.level {
+		return
+	}
+	l.out.Print(levelNames[level] + " " + msg + l.fields)
+}
+
+// formatValue 格式化字段值，含空格、引号或为空时加引号
+func formatValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
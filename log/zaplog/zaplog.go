@@ -0,0 +1,99 @@
+// Package zaplog 将 zap 接入 log.Logger，字段写入 zap 的结构化字段
+//
+//	logger, _ := zap.NewProduction()
+//	svc, err := user.NewService(&user.Config{DB: db, JWTSecret: "secret", Logger: zaplog.New(logger)})
+package zaplog
+
+import (
+	"go.uber.org/zap"
+
+	golog "github.com/bbadbeef/go-base/log"
+)
+
+// Logger zap 适配器，实现 log.Logger 和 log.WithFielder
+type Logger struct {
+	sugar *zap.SugaredLogger
+}
+
+// New 创建 zap 适配器，logger 为 nil 时使用 zap.L()（zap.ReplaceGlobals 设置的全局 logger）
+// 调用位置（caller）跳过适配器一层，指向调用日志方法的代码
+func New(logger *zap.Logger) *Logger {
+	if logger == nil {
+		logger = zap.L()
+	}
+	return &Logger{sugar: logger.WithOptions(zap.AddCallerSkip(1)).Sugar()}
+}
+
+var (
+	_ golog.Logger      = (*Logger)(nil)
+	_ golog.WithFielder = (*Logger)(nil)
+)
+
+// Debug 调试日志
+func (l *Logger) Debug(args ...interface{}) {
+	l.sugar.Debug(args...)
+}
+
+// Debugf 调试日志（格式化）
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.sugar.Debugf(format, args...)
+}
+
+// Info 信息日志
+func (l *Logger) Info(args ...interface{}) {
+	l.sugar.Info(args...)
+}
+
+// Infof 信息日志（格式化）
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.sugar.Infof(format, args...)
+}
+
+// Warn 警告日志
+func (l *Logger) Warn(args ...interface{}) {
+	l.sugar.Warn(args...)
+}
+
+// Warnf 警告日志（格式化）
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.sugar.Warnf(format, args...)
+}
+
+// Error 错误日志
+func (l *Logger) Error(args ...interface{}) {
+	l.sugar.Error(args...)
+}
+
+// Errorf 错误日志（格式化）
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.sugar.Errorf(format, args...)
+}
+
+// Fatal 致命错误日志，记录后退出进程
+func (l *Logger) Fatal(args ...interface{}) {
+	l.sugar.Fatal(args...)
+}
+
+// Fatalf 致命错误日志（格式化），记录后退出进程
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.sugar.Fatalf(format, args...)
+}
+
+// WithField 返回附加单个字段的 Logger
+func (l *Logger) WithField(key string, value interface{}) golog.Logger {
+	return &Logger{sugar: l.sugar.With(zap.Any(key, value))}
+}
+
+// WithFields 返回附加多个字段的 Logger
+func (l *Logger) WithFields(fields golog.Fields) golog.Logger {
+	args := make([]interface{}, 0, len(fields))
+	for k, v := range fields {
+		args = append(args, zap.Any(k, v))
+	}
+	return &Logger{sugar: l.sugar.With(args...)}
+}
+
+// Sync 刷新缓冲的日志，进程退出前调用
+func (l *Logger) Sync() error {
+	return l.sugar.Sync()
+}
@@ -0,0 +1,88 @@
+// Package zerologlog 将 zerolog 接入 log.Logger，字段写入 zerolog 的上下文字段
+//
+//	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+//	svc, err := user.NewService(&user.Config{DB: db, JWTSecret: "secret", Logger: zerologlog.New(logger)})
+package zerologlog
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	golog "github.com/bbadbeef/go-base/log"
+)
+
+// Logger zerolog 适配器，实现 log.Logger 和 log.WithFielder
+type Logger struct {
+	logger zerolog.Logger
+}
+
+// New 创建 zerolog 适配器
+func New(logger zerolog.Logger) *Logger {
+	return &Logger{logger: logger}
+}
+
+var (
+	_ golog.Logger      = (*Logger)(nil)
+	_ golog.WithFielder = (*Logger)(nil)
+)
+
+// Debug 调试日志
+func (l *Logger) Debug(args ...interface{}) {
+	l.logger.Debug().Msg(fmt.Sprint(args...))
+}
+
+// Debugf 调试日志（格式化）
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logger.Debug().Msgf(format, args...)
+}
+
+// Info 信息日志
+func (l *Logger) Info(args ...interface{}) {
+	l.logger.Info().Msg(fmt.Sprint(args...))
+}
+
+// Infof 信息日志（格式化）
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logger.Info().Msgf(format, args...)
+}
+
+// Warn 警告日志
+func (l *Logger) Warn(args ...interface{}) {
+	l.logger.Warn().Msg(fmt.Sprint(args...))
+}
+
+// Warnf 警告日志（格式化）
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logger.Warn().Msgf(format, args...)
+}
+
+// Error 错误日志
+func (l *Logger) Error(args ...interface{}) {
+	l.logger.Error().Msg(fmt.Sprint(args...))
+}
+
+// Errorf 错误日志（格式化）
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logger.Error().Msgf(format, args...)
+}
+
+// Fatal 致命错误日志，记录后退出进程
+func (l *Logger) Fatal(args ...interface{}) {
+	l.logger.Fatal().Msg(fmt.Sprint(args...))
+}
+
+// Fatalf 致命错误日志（格式化），记录后退出进程
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.logger.Fatal().Msgf(format, args...)
+}
+
+// WithField 返回附加单个字段的 Logger
+func (l *Logger) WithField(key string, value interface{}) golog.Logger {
+	return l.WithFields(golog.Fields{key: value})
+}
+
+// WithFields 返回附加多个字段的 Logger，error、time.Duration 等类型按 zerolog 的规则编码
+func (l *Logger) WithFields(fields golog.Fields) golog.Logger {
+	return &Logger{logger: l.logger.With().Fields(map[string]interface{}(fields)).Logger()}
+}
@@ -0,0 +1,10 @@
+module github.com/bbadbeef/go-base/migrate
+
+go 1.21
+
+require gorm.io/gorm v1.25.5
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+)
@@ -0,0 +1,154 @@
+// Package migrate 数据库迁移版本管理，user / im / storage 共用
+// 每个迁移有唯一递增的版本号，执行记录保存在迁移表中，只执行尚未执行的迁移
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultTable 默认迁移记录表名
+const DefaultTable = "schema_migrations"
+
+// Migration 单个迁移
+// 已发布的迁移不可修改，需要变更表结构时追加新版本
+type Migration struct {
+	Version int64                                        // 版本号，递增且唯一
+	Name    string                                       // 迁移名称
+	Up      func(ctx context.Context, db *gorm.DB) error // 迁移操作（应保证可重复执行）
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   int64  `json:"version"`    // 版本号
+	Name      string `json:"name"`       // 迁移名称
+	Applied   bool   `json:"applied"`    // 是否已执行
+	AppliedAt int64  `json:"applied_at"` // 执行时间戳（毫秒），未执行为 0
+}
+
+// DBSchemaMigration 迁移记录数据库模型
+type DBSchemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"type:varchar(128);not null"`
+	AppliedAt int64  `gorm:"type:bigint;not null"`
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	table      string
+	migrations []Migration
+}
+
+// New 创建迁移执行器
+// table 为迁移记录表名，migrations 为全部迁移（无需排序）
+func New(db *gorm.DB, table string, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:         db,
+		table:      table,
+		migrations: sorted,
+	}
+}
+
+// records 迁移记录表
+func (m *Migrator) records(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(ctx).Table(m.table)
+}
+
+// Migrate 按版本顺序执行所有未执行的迁移
+func (m *Migrator) Migrate(ctx context.Context) error {
+	if err := m.records(ctx).AutoMigrate(&DBSchemaMigration{}); err != nil {
+		return fmt.Errorf("init migration table failed: %w", err)
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := migration.Up(ctx, m.db.WithContext(ctx)); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+
+		// 多节点同时启动时可能重复执行同一迁移，迁移本身可重复执行，记录冲突时忽略
+		record := &DBSchemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UnixMilli(),
+		}
+		if err := m.records(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error; err != nil {
+			return fmt.Errorf("record migration %d failed: %w", migration.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// Status 获取所有迁移的执行状态
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	applied := make(map[int64]*DBSchemaMigration)
+	if m.records(ctx).Migrator().HasTable(&DBSchemaMigration{}) {
+		var err error
+		if applied, err = m.applied(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]*Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		status := &Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+		}
+		statuses[i] = status
+	}
+	return statuses, nil
+}
+
+// Pending 获取未执行的迁移数量
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// applied 查询已执行的迁移记录
+func (m *Migrator) applied(ctx context.Context) (map[int64]*DBSchemaMigration, error) {
+	var records []DBSchemaMigration
+	if err := m.records(ctx).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("query migration records failed: %w", err)
+	}
+
+	applied := make(map[int64]*DBSchemaMigration, len(records))
+	for i := range records {
+		applied[records[i].Version] = &records[i]
+	}
+	return applied, nil
+}
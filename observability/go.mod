@@ -0,0 +1,17 @@
+module github.com/bbadbeef/go-base/observability
+
+go 1.21
+
+require (
+	go.opentelemetry.io/otel v1.29.0
+	go.opentelemetry.io/otel/metric v1.29.0
+	gorm.io/gorm v1.25.5
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	go.opentelemetry.io/otel/trace v1.29.0 // indirect
+)
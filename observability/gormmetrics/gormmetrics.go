@@ -0,0 +1,184 @@
+// Package gormmetrics GORM 插件，按仓库方法记录 SQL 耗时并在慢查询时回调
+// 各模块在设置 Metrics 或 SlowQueryFunc 时自动在自己的数据库连接上注册，多个模块共用同一个 *gorm.DB 时
+// 按调用栈只统计本模块发起的查询，定位如 MessageRepository.GetMessages 这样的热点查询
+package gormmetrics
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// SlowQuery 慢查询信息，SQL 不含参数值
+type SlowQuery struct {
+	Module   string        // 发起查询的模块，如 "im"
+	Method   string        // 发起查询的仓库方法，如 "MessageRepository.GetMessages"
+	Table    string        // 表名
+	SQL      string        // SQL 语句（参数为占位符）
+	Rows     int64         // 影响或返回的行数
+	Duration time.Duration // 耗时
+	Err      error         // 查询错误
+}
+
+// SlowQueryFunc 慢查询回调，在查询所在的 goroutine 中同步调用，ctx 为查询的 context（含请求 ID）
+type SlowQueryFunc func(ctx context.Context, query *SlowQuery)
+
+// Config 插件配置
+type Config struct {
+	Module        string                // 模块名，插件名和指标名前缀，如 "im" -> im_db_query_duration_seconds
+	Package       string                // 只统计调用栈中该包路径前缀的代码发起的查询，如 "github.com/bbadbeef/go-base/im/"，为空时统计全部查询
+	Metrics       observability.Metrics // 记录 {Module}_db_query_duration_seconds（标签 method、operation、result），nil 时不记录
+	SlowThreshold time.Duration         // 慢查询阈值，默认 200ms，小于 0 时不回调
+	OnSlowQuery   SlowQueryFunc         // 慢查询回调（可选）
+}
+
+// Plugin GORM 插件，通过 db.Use(gormmetrics.New(config)) 注册
+type Plugin struct {
+	config Config
+	metric string
+	key    string // 查询开始时间在 Statement 中的键
+}
+
+// New 创建插件
+func New(config Config) *Plugin {
+	if config.SlowThreshold == 0 {
+		config.SlowThreshold = 200 * time.Millisecond
+	}
+	config.Metrics = observability.OrNop(config.Metrics)
+	return &Plugin{
+		config: config,
+		metric: config.Module + "_db_query_duration_seconds",
+		key:    "gormmetrics:" + config.Module + ":start",
+	}
+}
+
+// Register 在 db 上注册插件，db 为 nil 或插件已注册时不做任何事
+func Register(db *gorm.DB, config Config) error {
+	if db == nil {
+		return nil
+	}
+	if err := db.Use(New(config)); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		return err
+	}
+	return nil
+}
+
+// Name 插件名，同一个 *gorm.DB 上每个模块注册一次
+func (p *Plugin) Name() string {
+	return "gormmetrics:" + p.config.Module
+}
+
+// Initialize 注册各类操作的前后回调
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	name := p.Name()
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register(name+":before_create", p.before),
+		cb.Create().After("gorm:create").Register(name+":after_create", p.after("create")),
+		cb.Query().Before("gorm:query").Register(name+":before_query", p.before),
+		cb.Query().After("gorm:query").Register(name+":after_query", p.after("query")),
+		cb.Update().Before("gorm:update").Register(name+":before_update", p.before),
+		cb.Update().After("gorm:update").Register(name+":after_update", p.after("update")),
+		cb.Delete().Before("gorm:delete").Register(name+":before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register(name+":after_delete", p.after("delete")),
+		cb.Row().Before("gorm:row").Register(name+":before_row", p.before),
+		cb.Row().After("gorm:row").Register(name+":after_row", p.after("row")),
+		cb.Raw().Before("gorm:raw").Register(name+":before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register(name+":after_raw", p.after("raw")),
+	)
+}
+
+// before 记录查询开始时间
+func (p *Plugin) before(db *gorm.DB) {
+	db.InstanceSet(p.key, time.Now())
+}
+
+// after 按仓库方法记录耗时，超过阈值时回调
+func (p *Plugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(p.key)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		duration := time.Since(start)
+
+		method, ok := p.caller()
+		if !ok {
+			return
+		}
+
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		p.config.Metrics.Observe(p.metric, duration.Seconds(),
+			observability.L("method", method),
+			observability.L("operation", operation),
+			observability.L("result", observability.Result(err)),
+		)
+
+		if p.config.OnSlowQuery != nil && p.config.SlowThreshold >= 0 && duration >= p.config.SlowThreshold {
+			p.config.OnSlowQuery(db.Statement.Context, &SlowQuery{
+				Module:   p.config.Module,
+				Method:   method,
+				Table:    db.Statement.Table,
+				SQL:      db.Statement.SQL.String(),
+				Rows:     db.RowsAffected,
+				Duration: duration,
+				Err:      err,
+			})
+		}
+	}
+}
+
+// caller 从调用栈中找到发起查询的函数（跳过 GORM 和本包），不属于 Package 时返回 false
+func (p *Plugin) caller() (string, bool) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		fn := frame.Function
+		if fn != "" && !strings.HasPrefix(fn, "gorm.io/") && !strings.HasPrefix(fn, thisPackage) {
+			if p.config.Package != "" && !strings.HasPrefix(fn, p.config.Package) {
+				return "", false
+			}
+			return methodName(fn), true
+		}
+		if !more {
+			return "", false
+		}
+	}
+}
+
+// thisPackage 本包的函数名前缀
+const thisPackage = "github.com/bbadbeef/go-base/observability/gormmetrics."
+
+// methodName 将完整函数名简化为 类型.方法，如
+// "github.com/bbadbeef/go-base/im/internal/repository.(*MessageRepository).GetMessages.func1" -> "MessageRepository.GetMessages"
+func methodName(fn string) string {
+	if i := strings.LastIndex(fn, "/"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	if i := strings.Index(fn, "."); i >= 0 {
+		fn = fn[i+1:]
+	}
+	fn = strings.NewReplacer("(*", "", ")", "").Replace(fn)
+
+	// 去掉闭包后缀 .func1、.func1.2 等
+	parts := strings.Split(fn, ".")
+	for len(parts) > 1 && strings.Trim(strings.TrimPrefix(parts[len(parts)-1], "func"), "0123456789") == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,52 @@
+// Package observability user / im / storage 共用的监控指标接口
+// 各模块通过 Metrics 上报计数、耗时和当前值，应用选择导出方式：NewPrometheus 以 Prometheus 文本格式导出，
+// otelmetrics.New 接入 OpenTelemetry，也可实现 Metrics 接入其他监控系统
+package observability
+
+// Metrics 指标接口，实现需并发安全
+// 指标名按模块加前缀（如 storage_operations_total、im_connections、user_logins_total），同一指标名的标签键需一致
+type Metrics interface {
+	// Add 计数器增加 value，如操作次数、字节数
+	Add(name string, value float64, labels ...Label)
+	// Observe 记录一次分布值，如耗时（秒），导出为直方图
+	Observe(name string, value float64, labels ...Label)
+	// Set 设置当前值，如在线连接数
+	Set(name string, value float64, labels ...Label)
+}
+
+// Label 指标标签
+type Label struct {
+	Key   string
+	Value string
+}
+
+// L 创建指标标签
+func L(key, value string) Label {
+	return Label{Key: key, Value: value}
+}
+
+// Result 按错误返回结果标签值，err 为 nil 时为 "ok"，否则为 "error"
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// Nop 不记录任何指标，未配置指标时使用
+var Nop Metrics = nopMetrics{}
+
+// nopMetrics 不记录任何指标
+type nopMetrics struct{}
+
+func (nopMetrics) Add(string, float64, ...Label)     {}
+func (nopMetrics) Observe(string, float64, ...Label) {}
+func (nopMetrics) Set(string, float64, ...Label)     {}
+
+// OrNop m 为 nil 时返回 Nop
+func OrNop(m Metrics) Metrics {
+	if m == nil {
+		return Nop
+	}
+	return m
+}
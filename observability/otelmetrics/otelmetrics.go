@@ -0,0 +1,97 @@
+// Package otelmetrics 将 observability.Metrics 接入 OpenTelemetry
+// 指标通过应用配置的 MeterProvider 导出（如 OTLP、Prometheus exporter），本包不引入任何 exporter
+package otelmetrics
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// instrumentationName 默认 Meter 的名称
+const instrumentationName = "github.com/bbadbeef/go-base"
+
+// Metrics OpenTelemetry 指标，Add、Observe、Set 分别对应 Counter、Histogram、Gauge，按指标名首次使用时创建
+type Metrics struct {
+	meter      metric.Meter
+	mu         sync.RWMutex
+	counters   map[string]metric.Float64Counter
+	histograms map[string]metric.Float64Histogram
+	gauges     map[string]metric.Float64Gauge
+}
+
+// New 使用 meter 创建指标，meter 为 nil 时使用全局 MeterProvider（otel.SetMeterProvider 设置）
+func New(meter metric.Meter) *Metrics {
+	if meter == nil {
+		meter = otel.Meter(instrumentationName)
+	}
+	return &Metrics{
+		meter:      meter,
+		counters:   make(map[string]metric.Float64Counter),
+		histograms: make(map[string]metric.Float64Histogram),
+		gauges:     make(map[string]metric.Float64Gauge),
+	}
+}
+
+var _ observability.Metrics = (*Metrics)(nil)
+
+// Add 计数器增加 value
+func (m *Metrics) Add(name string, value float64, labels ...observability.Label) {
+	counter, ok := instrument(m, m.counters, name, m.meter.Float64Counter)
+	if ok {
+		counter.Add(context.Background(), value, metric.WithAttributes(attributes(labels)...))
+	}
+}
+
+// Observe 记录一次分布值
+func (m *Metrics) Observe(name string, value float64, labels ...observability.Label) {
+	histogram, ok := instrument(m, m.histograms, name, m.meter.Float64Histogram)
+	if ok {
+		histogram.Record(context.Background(), value, metric.WithAttributes(attributes(labels)...))
+	}
+}
+
+// Set 设置当前值
+func (m *Metrics) Set(name string, value float64, labels ...observability.Label) {
+	gauge, ok := instrument(m, m.gauges, name, m.meter.Float64Gauge)
+	if ok {
+		gauge.Record(context.Background(), value, metric.WithAttributes(attributes(labels)...))
+	}
+}
+
+// instrument 获取或创建指标名对应的 instrument，创建失败（如指标名不合法）时返回 false，该指标被忽略
+func instrument[T any, O any](m *Metrics, cache map[string]T, name string, create func(string, ...O) (T, error)) (T, bool) {
+	m.mu.RLock()
+	inst, ok := cache[name]
+	m.mu.RUnlock()
+	if ok {
+		return inst, true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if inst, ok := cache[name]; ok {
+		return inst, true
+	}
+	inst, err := create(name)
+	if err != nil {
+		otel.Handle(err)
+		return inst, false
+	}
+	cache[name] = inst
+	return inst, true
+}
+
+// attributes 将标签转换为 OpenTelemetry 属性
+func attributes(labels []observability.Label) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, len(labels))
+	for i, l := range labels {
+		attrs[i] = attribute.String(l.Key, l.Value)
+	}
+	return attrs
+}
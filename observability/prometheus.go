@@ -0,0 +1,172 @@
+package observability
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets 直方图默认的桶上限，适用于以秒为单位的耗时
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// 指标类型
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// Prometheus 以 Prometheus 文本格式导出的指标，不依赖 Prometheus 客户端库，并发安全
+// 挂载为 HTTP 接口供 Prometheus 抓取，如 mux.Handle("/metrics", metrics)
+type Prometheus struct {
+	mu      sync.Mutex
+	buckets []float64
+	metrics map[string]*promMetric
+}
+
+// promMetric 同名指标的所有序列
+type promMetric struct {
+	typ    string
+	series map[string]*promSeries // 标签文本 -> 序列
+}
+
+// promSeries 一个标签组合的值，直方图的 buckets 为各桶的非累计次数
+type promSeries struct {
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+// NewPrometheus 创建 Prometheus 指标，buckets 为直方图的桶上限（升序），为空时使用 DefaultBuckets
+func NewPrometheus(buckets ...float64) *Prometheus {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Prometheus{buckets: buckets, metrics: make(map[string]*promMetric)}
+}
+
+// Add 计数器增加 value
+func (p *Prometheus) Add(name string, value float64, labels ...Label) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.series(name, typeCounter, labels).value += value
+}
+
+// Observe 记录一次分布值
+func (p *Prometheus) Observe(name string, value float64, labels ...Label) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.series(name, typeHistogram, labels)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(p.buckets))
+	}
+	if i := sort.SearchFloat64s(p.buckets, value); i < len(p.buckets) {
+		s.buckets[i]++
+	}
+	s.count++
+	s.value += value
+}
+
+// Set 设置当前值
+func (p *Prometheus) Set(name string, value float64, labels ...Label) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.series(name, typeGauge, labels).value = value
+}
+
+// series 获取或创建序列，调用方需持有锁；同名指标按首次使用的类型导出
+func (p *Prometheus) series(name, typ string, labels []Label) *promSeries {
+	m := p.metrics[name]
+	if m == nil {
+		m = &promMetric{typ: typ, series: make(map[string]*promSeries)}
+		p.metrics[name] = m
+	}
+	key := formatLabels(labels)
+	s := m.series[key]
+	if s == nil {
+		s = &promSeries{}
+		m.series[key] = s
+	}
+	return s
+}
+
+// ServeHTTP 以 Prometheus 文本格式输出指标
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(p.String()))
+}
+
+// String 以 Prometheus 文本格式返回指标，按指标名和标签排序
+func (p *Prometheus) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.metrics))
+	for name := range p.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		m := p.metrics[name]
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, m.typ)
+		keys := make([]string, 0, len(m.series))
+		for key := range m.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := m.series[key]
+			if m.typ != typeHistogram {
+				fmt.Fprintf(&b, "%s%s %s\n", name, braces(key), formatFloat(s.value))
+				continue
+			}
+			var cumulative uint64
+			for i, le := range p.buckets {
+				cumulative += s.buckets[i]
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(joinLabels(key, `le="`+formatFloat(le)+`"`)), cumulative)
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(joinLabels(key, `le="+Inf"`)), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, braces(key), formatFloat(s.value))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, braces(key), s.count)
+		}
+	}
+	return b.String()
+}
+
+// formatLabels 按 Prometheus 文本格式输出标签，不含花括号
+func formatLabels(labels []Label) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		v := strings.ReplaceAll(l.Value, `\`, `\\`)
+		v = strings.ReplaceAll(v, "\n", `\n`)
+		v = strings.ReplaceAll(v, `"`, `\"`)
+		parts[i] = l.Key + `="` + v + `"`
+	}
+	return strings.Join(parts, ",")
+}
+
+// joinLabels 在标签文本后追加一个标签
+func joinLabels(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+// braces 为非空的标签文本加上花括号
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+// formatFloat 按 Prometheus 文本格式输出数值
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,57 @@
+module github.com/bbadbeef/go-base/server
+
+go 1.21
+
+require (
+	github.com/bbadbeef/go-base/im v0.0.0
+	github.com/bbadbeef/go-base/log v0.0.0
+	github.com/bbadbeef/go-base/observability v0.0.0
+	github.com/bbadbeef/go-base/storage v0.0.0
+	github.com/bbadbeef/go-base/user v0.0.0
+	gorm.io/gorm v1.25.12
+)
+
+require (
+	github.com/bbadbeef/go-base/config v0.0.0 // indirect
+	github.com/bbadbeef/go-base/errcode v0.0.0 // indirect
+	github.com/bbadbeef/go-base/idgen v0.0.0
+	github.com/bbadbeef/go-base/migrate v0.0.0 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.0 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/gorilla/websocket v1.5.1 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/redis/go-redis/v9 v9.7.0 // indirect
+	github.com/sirupsen/logrus v1.9.3 // indirect
+	golang.org/x/crypto v0.18.0 // indirect
+	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231212172506-995d672761c0 // indirect
+	google.golang.org/grpc v1.60.1 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/bbadbeef/go-base/im => ../im
+	github.com/bbadbeef/go-base/user => ../user
+)
+
+replace github.com/bbadbeef/go-base/storage => ../storage
+
+replace github.com/bbadbeef/go-base/errcode => ../errcode
+
+replace github.com/bbadbeef/go-base/observability => ../observability
+
+replace github.com/bbadbeef/go-base/log => ../log
+
+replace github.com/bbadbeef/go-base/config => ../config
+
+replace github.com/bbadbeef/go-base/idgen => ../idgen
+
+replace github.com/bbadbeef/go-base/migrate => ../migrate
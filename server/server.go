@@ -0,0 +1,298 @@
+// Package server 组合 user、storage、im 三个模块：共用数据库、日志和监控指标，
+// IM 和存储接口的认证绑定到用户模块的访问令牌，挂载全部 REST 接口和 WebSocket，并负责启动和优雅关闭
+// 自行组合模块时可使用 Shutdown 按顺序优雅关闭
+//
+//	app, err := server.New(&server.Config{
+//		DB:   db,
+//		Addr: ":8080",
+//		User: &user.Config{JWTSecret: secret},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	log.Fatal(app.Run(ctx))
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/idgen"
+	"github.com/bbadbeef/go-base/im"
+	imhttp "github.com/bbadbeef/go-base/im/httpapi"
+	golog "github.com/bbadbeef/go-base/log"
+	"github.com/bbadbeef/go-base/observability"
+	"github.com/bbadbeef/go-base/storage"
+	storagehttp "github.com/bbadbeef/go-base/storage/httpapi"
+	"github.com/bbadbeef/go-base/user"
+	userhttp "github.com/bbadbeef/go-base/user/httpapi"
+)
+
+// ErrInvalidConfig 组合服务配置不合法
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Config 组合服务配置，模块配置中未设置的 DB、TenantID、Metrics、Logger、IDGenerator 使用这里的值
+type Config struct {
+	DB              *gorm.DB              // 数据库连接，三个模块共用
+	Addr            string                // HTTP 监听地址，默认 ":8080"
+	TenantID        string                // 租户 ID（可选），三个模块使用相同租户
+	Metrics         observability.Metrics // 监控指标（可选），实现 http.Handler 时（如 observability.NewPrometheus()）挂载到 /metrics
+	Logger          golog.Logger          // 日志（可选），默认使用 IM 的日志
+	IDGenerator     idgen.Generator       // 消息和文件 ID 生成器（可选），如 idgen.NewSnowflake(node)；用户 ID 需单独设置 User.IDGenerator
+	ShutdownTimeout time.Duration         // 优雅关闭（等待进行中的请求、WebSocket 连接发送完缓冲的消息等）的最长时间，默认 10 秒
+
+	User    *user.Config    // 用户模块配置，JWTSecret 等必填项见 user.Config
+	Storage *storage.Config // 存储模块配置（可选），BaseURL 默认 "http://localhost" + Addr
+	IM      *im.Config      // IM 模块配置（可选），ServerID 默认主机名，AuthFunc 默认校验用户模块的访问令牌
+
+	UserHTTP    *userhttp.Config    // 用户 REST 接口配置（可选）
+	StorageHTTP *storagehttp.Config // 存储 REST 接口配置（可选），AccessCheck 默认为 IM 会话参与者校验
+	IMHTTP      *imhttp.Config      // IM REST 接口配置（可选）
+}
+
+// App 组合后的服务，各模块可直接访问，Mux 可继续注册主应用自己的路由
+type App struct {
+	User    user.Service
+	Storage storage.Storage
+	IM      im.IMService
+	Mux     *http.ServeMux
+
+	config Config
+}
+
+// New 创建三个模块并挂载路由（路由均相对于根路径）：
+//
+//	/api/user/...                            用户接口，见 user/httpapi
+//	/api/upload/、/api/uploads/、/api/files、/api/usage  存储接口，见 storage/httpapi
+//	/api/im/...                              IM 接口，见 im/httpapi
+//	/api/im/admin/cluster                    集群节点、连接数和版本（admin 角色）
+//	/api/im/admin/traffic                    当前节点 WebSocket 流量（admin 角色）
+//	/api/im/admin/kick                       强制用户下线（admin 角色）
+//	/api/im/admin/reports                    消息举报列表和处理（admin 角色）
+//	/api/im/admin/support/agents             客服列表和设置（admin 角色）
+//	/api/im/admin/support/conversations      客服会话列表、转接和结束（admin 角色）
+//	/ws?token=                               IM WebSocket
+//	/metrics                                 监控指标（Metrics 实现 http.Handler 时）
+//
+// 注销账号时自动清理 IM 和存储中的用户数据；模块创建失败时返回的错误满足该模块的 ErrInvalidConfig 等错误判断
+func New(config *Config) (*App, error) {
+	if config == nil {
+		return nil, fmt.Errorf("%w: config is required", ErrInvalidConfig)
+	}
+	if config.DB == nil {
+		return nil, fmt.Errorf("%w: database connection is required", ErrInvalidConfig)
+	}
+	if config.User == nil {
+		return nil, fmt.Errorf("%w: user config is required", ErrInvalidConfig)
+	}
+
+	cfg := *config
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 10 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = im.GetLogger()
+	}
+
+	a := &App{Mux: http.NewServeMux(), config: cfg}
+
+	userConfig := *cfg.User
+	if userConfig.DB == nil {
+		userConfig.DB = cfg.DB
+	}
+	if userConfig.TenantID == "" {
+		userConfig.TenantID = cfg.TenantID
+	}
+	if userConfig.Metrics == nil {
+		userConfig.Metrics = cfg.Metrics
+	}
+	if userConfig.Logger == nil {
+		userConfig.Logger = cfg.Logger
+	}
+	userConfig.AccountDeletionHooks = append(append([]user.AccountDeletionHook(nil), userConfig.AccountDeletionHooks...),
+		func(ctx context.Context, userID int64) error {
+			return a.IM.PurgeUserData(ctx, userID)
+		},
+		func(ctx context.Context, userID int64) error {
+			return a.Storage.WithContext(ctx).DeleteByUser(userID)
+		},
+	)
+	var err error
+	if a.User, err = user.NewService(&userConfig); err != nil {
+		return nil, fmt.Errorf("create user service: %w", err)
+	}
+
+	var storageConfig storage.Config
+	if cfg.Storage != nil {
+		storageConfig = *cfg.Storage
+	}
+	if storageConfig.DB == nil {
+		storageConfig.DB = cfg.DB
+	}
+	if storageConfig.TenantID == "" {
+		storageConfig.TenantID = cfg.TenantID
+	}
+	if storageConfig.BaseURL == "" {
+		storageConfig.BaseURL = defaultBaseURL(cfg.Addr)
+	}
+	if storageConfig.Metrics == nil {
+		storageConfig.Metrics = cfg.Metrics
+	}
+	if storageConfig.Logger == nil {
+		storageConfig.Logger = cfg.Logger
+	}
+	if storageConfig.IDGenerator == nil {
+		storageConfig.IDGenerator = cfg.IDGenerator
+	}
+	if a.Storage, err = storage.NewStorage(&storageConfig); err != nil {
+		return nil, fmt.Errorf("create storage: %w", err)
+	}
+
+	var imConfig im.Config
+	if cfg.IM != nil {
+		imConfig = *cfg.IM
+	}
+	if imConfig.DB == nil {
+		imConfig.DB = cfg.DB
+	}
+	if imConfig.TenantID == "" {
+		imConfig.TenantID = cfg.TenantID
+	}
+	if imConfig.ServerID == "" {
+		imConfig.ServerID, _ = os.Hostname()
+	}
+	if imConfig.AuthFunc == nil {
+		imConfig.AuthFunc = im.TokenAuth(a.ValidateToken)
+	}
+	if imConfig.FileCheckFunc == nil {
+		imConfig.FileCheckFunc = a.canSendFile
+	}
+	if imConfig.Metrics == nil {
+		imConfig.Metrics = cfg.Metrics
+	}
+	if imConfig.IDGenerator == nil {
+		imConfig.IDGenerator = cfg.IDGenerator
+	}
+	if a.IM, err = im.New(&imConfig); err != nil {
+		return nil, fmt.Errorf("create im service: %w", err)
+	}
+
+	a.mount()
+	return a, nil
+}
+
+// ValidateToken 校验用户模块的访问令牌并返回用户 ID，可作为其他需要认证的接口的校验函数
+func (a *App) ValidateToken(token string) (int64, error) {
+	claims, err := a.User.ValidateToken(token)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// canSendFile 用户可以在消息中引用的文件：本人上传的文件，或可以访问的会话中的文件（如转发收到的图片）
+func (a *App) canSendFile(ctx context.Context, userID int64, fileID string) (bool, error) {
+	info, err := a.Storage.GetFileInfo(fileID)
+	if errors.Is(err, storage.ErrFileNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.UserID == userID {
+		return true, nil
+	}
+	return a.IM.CanAccessFile(ctx, userID, fileID)
+}
+
+// mount 挂载各模块的接口
+func (a *App) mount() {
+	a.Mux.Handle("/api/user/", http.StripPrefix("/api/user", userhttp.NewHandler(a.User, a.config.UserHTTP)))
+
+	var storageHTTP storagehttp.Config
+	if a.config.StorageHTTP != nil {
+		storageHTTP = *a.config.StorageHTTP
+	}
+	if storageHTTP.AccessCheck == nil {
+		storageHTTP.AccessCheck = func(ctx context.Context, userID int64, file *storage.FileInfo) (bool, error) {
+			return a.IM.CanAccessFile(ctx, userID, file.FileID)
+		}
+	}
+	files := http.StripPrefix("/api", storagehttp.NewHandler(a.Storage, storagehttp.BearerAuth(a.ValidateToken), &storageHTTP))
+	for _, path := range []string{"/api/upload/", "/api/uploads/", "/api/files", "/api/files/", "/api/usage"} {
+		a.Mux.Handle(path, files)
+	}
+
+	a.Mux.Handle("/api/im/", http.StripPrefix("/api/im", imhttp.NewHandler(a.IM, imhttp.BearerAuth(a.ValidateToken), a.config.IMHTTP)))
+	a.Mux.Handle("/api/im/admin/cluster", user.RequireRole(a.User, "admin")(imhttp.NewClusterHandler(a.IM)))
+	a.Mux.Handle("/api/im/admin/traffic", user.RequireRole(a.User, "admin")(imhttp.NewTrafficHandler(a.IM)))
+	a.Mux.Handle("/api/im/admin/kick", user.RequireRole(a.User, "admin")(imhttp.NewKickHandler(a.IM)))
+	a.Mux.Handle("/api/im/admin/reports", user.RequireRole(a.User, "admin")(imhttp.NewReportHandler(a.IM, imhttp.BearerAuth(a.ValidateToken))))
+	a.Mux.Handle("/api/im/admin/support/agents", user.RequireRole(a.User, "admin")(imhttp.NewSupportAgentHandler(a.IM)))
+	a.Mux.Handle("/api/im/admin/support/conversations", user.RequireRole(a.User, "admin")(imhttp.NewSupportConversationHandler(a.IM)))
+	a.Mux.HandleFunc("/ws", a.IM.WebSocketHandler())
+
+	if metrics, ok := a.config.Metrics.(http.Handler); ok {
+		a.Mux.Handle("/metrics", metrics)
+	}
+}
+
+// Handler 返回处理全部请求的 Handler，请求 ID 写入 context 和响应头
+func (a *App) Handler() http.Handler {
+	return golog.RequestIDMiddleware(a.Mux)
+}
+
+// Run 启动 IM 服务和 HTTP 服务，阻塞到 ctx 取消或 HTTP 服务出错，返回前按顺序优雅关闭（最长 ShutdownTimeout）：
+// 停止接受 HTTP 请求并等待进行中的请求，通知并断开 WebSocket 连接，等待消息处理和离线推送，关闭 gRPC，最后关闭 DB
+func (a *App) Run(ctx context.Context) error {
+	go func() {
+		if err := a.IM.Start(context.Background()); err != nil {
+			a.config.Logger.Errorf("IM service stopped: %v", err)
+		}
+	}()
+
+	server := &http.Server{Addr: a.config.Addr, Handler: a.Handler()}
+	errCh := make(chan error, 1)
+	go func() {
+		a.config.Logger.Infof("HTTP server listening on %s", a.config.Addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+	defer cancel()
+	shutdownErr := NewShutdown(a.config.Logger).HTTP(server).IM(a.IM).DB(a.config.DB).Run(shutdownCtx)
+	if err == nil || errors.Is(err, http.ErrServerClosed) {
+		err = shutdownErr
+	}
+	return err
+}
+
+// defaultBaseURL 按监听地址生成本机访问地址，如 ":8080" -> "http://localhost:8080"
+func defaultBaseURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/im"
+	golog "github.com/bbadbeef/go-base/log"
+)
+
+// Shutdown 优雅关闭协调器，按添加顺序依次执行关闭步骤，推荐顺序：
+//
+//	shutdown := server.NewShutdown(logger).
+//		HTTP(httpServer). // 停止接受 HTTP 请求，等待进行中的请求
+//		IM(imService).    // 通知并断开 WebSocket 连接，等待消息处理和离线推送，关闭 gRPC
+//		DB(db)            // 关闭数据库连接
+//	shutdown.Wait(10 * time.Second)
+//
+// 某一步失败时记录日志并继续执行后续步骤
+type Shutdown struct {
+	steps  []shutdownStep
+	logger golog.Logger
+}
+
+// shutdownStep 关闭步骤
+type shutdownStep struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewShutdown 创建关闭协调器，logger 为 nil 时写入标准库 log
+func NewShutdown(logger golog.Logger) *Shutdown {
+	return &Shutdown{logger: golog.OrStd(logger)}
+}
+
+// Add 添加关闭步骤，fn 需在 ctx 到期时尽快返回
+func (s *Shutdown) Add(name string, fn func(ctx context.Context) error) *Shutdown {
+	s.steps = append(s.steps, shutdownStep{name: name, fn: fn})
+	return s
+}
+
+// HTTP 添加步骤：停止接受新请求，等待进行中的请求完成（不包括已升级的 WebSocket 连接）
+func (s *Shutdown) HTTP(server *http.Server) *Shutdown {
+	return s.Add("http", server.Shutdown)
+}
+
+// IM 添加步骤：调用 IMService.Shutdown，通知并断开 WebSocket 连接，等待进行中的消息处理和离线推送，关闭 gRPC
+func (s *Shutdown) IM(svc im.IMService) *Shutdown {
+	return s.Add("im", svc.Shutdown)
+}
+
+// DB 添加步骤：关闭数据库连接池
+func (s *Shutdown) DB(db *gorm.DB) *Shutdown {
+	return s.Add("db", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+}
+
+// Run 依次执行全部关闭步骤，所有步骤共用 ctx 的截止时间，返回全部失败的步骤（errors.Join）
+func (s *Shutdown) Run(ctx context.Context) error {
+	var errs []error
+	for _, step := range s.steps {
+		start := time.Now()
+		if err := step.fn(ctx); err != nil {
+			s.logger.Errorf("Shutdown step %s failed: %v", step.name, err)
+			errs = append(errs, fmt.Errorf("shutdown %s: %w", step.name, err))
+			continue
+		}
+		s.logger.Infof("Shutdown step %s done in %s", step.name, time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// Wait 阻塞到收到 SIGINT 或 SIGTERM，然后在 timeout 内执行全部关闭步骤
+func (s *Shutdown) Wait(timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+
+	s.logger.Infof("Shutting down, timeout %s", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Run(ctx)
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// 头像各尺寸的边长（像素），原图小于该尺寸时不放大
+const (
+	AvatarSizeSmall  = 64  // 会话列表、消息
+	AvatarSizeMedium = 256 // 资料页
+	AvatarSizeLarge  = 640 // 查看大图
+)
+
+// avatarQuality 头像 JPEG 质量
+const avatarQuality = 85
+
+// avatarMimeTypes 可生成头像的图片类型
+var avatarMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// AvatarInfo 头像上传结果，各尺寸为原图的派生文件，删除原图时随之删除
+type AvatarInfo struct {
+	FileID string `json:"file_id"` // 原图文件ID
+	URL    string `json:"url"`     // 原图URL
+	Small  string `json:"small"`   // 小尺寸头像URL（AvatarSizeSmall）
+	Medium string `json:"medium"`  // 中尺寸头像URL（AvatarSizeMedium）
+	Large  string `json:"large"`   // 大尺寸头像URL（AvatarSizeLarge）
+}
+
+// UploadAvatar 上传头像：校验为 JPEG、PNG、GIF 图片，保存原图后居中裁剪为正方形，生成小、中、大三种尺寸的 JPEG
+// meta.FileType 可为空或 FileTypeImage，不支持客户端加密；生成失败时原图一并删除
+func (s *dbStorage) UploadAvatar(r io.Reader, meta *UploadMeta) (*AvatarInfo, error) {
+	if r == nil || meta == nil || meta.Encrypted || meta.FileType != "" && meta.FileType != FileTypeImage {
+		return nil, fmt.Errorf("%w: avatar must be an unencrypted image", ErrInvalidUploadRequest)
+	}
+
+	// 先按文件头校验类型，HEIC、WebP 等无法解码的图片不保存
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	if detected := sniffMimeType(head); !avatarMimeTypes[mimeFamily(detected)] {
+		return nil, fmt.Errorf("%w: avatar must be JPEG, PNG or GIF, got %s", ErrUnsupportedMimeType, detected)
+	}
+
+	m := *meta
+	m.FileType = FileTypeImage
+	info, err := s.UploadStream(br, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	avatar, err := s.avatarVariants(info)
+	if err != nil {
+		s.Delete(info.FileID)
+		return nil, err
+	}
+	return avatar, nil
+}
+
+// avatarVariants 为已上传的头像原图生成各尺寸的派生文件
+func (s *dbStorage) avatarVariants(info *FileInfo) (*AvatarInfo, error) {
+	source := &DBFile{}
+	if err := s.files().Omit("file_data").Where("file_id = ?", info.FileID).First(source).Error; err != nil {
+		return nil, err
+	}
+	src, err := s.decodeImage(source)
+	if err != nil {
+		return nil, err
+	}
+	r := cropRect(src.Bounds(), 1, 1)
+
+	base := strings.TrimSuffix(source.FileName, filepath.Ext(source.FileName))
+	avatar := &AvatarInfo{FileID: info.FileID, URL: info.URL}
+	for _, v := range []struct {
+		name string
+		size int
+		url  *string
+	}{
+		{"small", AvatarSizeSmall, &avatar.Small},
+		{"medium", AvatarSizeMedium, &avatar.Medium},
+		{"large", AvatarSizeLarge, &avatar.Large},
+	} {
+		side := min(r.Dx(), v.size)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scaleImage(src, r, side, side, true), &jpeg.Options{Quality: avatarQuality}); err != nil {
+			return nil, fmt.Errorf("encode avatar failed: %w", err)
+		}
+		variant := &DBFile{
+			FileName: "avatar_" + v.name + "_" + base + ".jpg",
+			MimeType: "image/jpeg",
+			Width:    side,
+			Height:   side,
+		}
+		if err := s.saveDerived(source, variant, buf.Bytes()); err != nil {
+			return nil, err
+		}
+		*v.url = s.fileURL(variant.FileID)
+	}
+	return avatar, nil
+}
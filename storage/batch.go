@@ -0,0 +1,117 @@
+package storage
+
+// fileInfoColumns 查询文件信息的字段，不含文件内容
+const fileInfoColumns = "id, file_id, user_id, file_name, file_type, mime_type, file_size, width, height, duration, thumbnail_id, source_id, encrypted, status, created_at, expires_at"
+
+// FileFilter 文件列表查询条件
+type FileFilter struct {
+	FileType string `json:"file_type,omitempty"` // 按文件类型过滤，为空表示不过滤
+	Page     int    `json:"page"`                // 页码，从 1 开始，默认 1
+	PageSize int    `json:"page_size"`           // 每页数量，默认 20，最大 100
+}
+
+// FileList 文件列表
+type FileList struct {
+	Files    []*FileInfo `json:"files"`
+	Total    int64       `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+}
+
+// GetFileInfos 批量获取文件信息，不存在或已删除的文件不包含在结果中
+func (s *dbStorage) GetFileInfos(fileIDs []string) (map[string]*FileInfo, error) {
+	infos := make(map[string]*FileInfo, len(fileIDs))
+	if len(fileIDs) == 0 {
+		return infos, nil
+	}
+
+	var dbFiles []DBFile
+	if err := s.files().Select(fileInfoColumns).
+		Where("file_id IN ? AND status IN (1, 3)", fileIDs).Find(&dbFiles).Error; err != nil {
+		return nil, err
+	}
+	for i := range dbFiles {
+		infos[dbFiles[i].FileID] = s.toFileInfo(&dbFiles[i])
+	}
+	return infos, nil
+}
+
+// DeleteFiles 批量删除文件，缩略图等派生文件随之删除，返回删除的文件数，不存在或已删除的文件跳过
+func (s *dbStorage) DeleteFiles(fileIDs []string) (int64, error) {
+	if len(fileIDs) == 0 {
+		return 0, nil
+	}
+	var dbFiles []DBFile
+	if err := s.files().Select("id, file_id, content_hash").
+		Where("file_id IN ? AND status IN (1, 3)", fileIDs).Find(&dbFiles).Error; err != nil {
+		return 0, err
+	}
+
+	// 去重的文件逐个删除以释放内容引用，其余的一次更新
+	var count int64
+	var plain []int64
+	sources := make([]string, len(dbFiles))
+	for i := range dbFiles {
+		dbFile := &dbFiles[i]
+		sources[i] = dbFile.FileID
+		if dbFile.ContentHash == "" {
+			plain = append(plain, dbFile.ID)
+			continue
+		}
+		deleted, err := s.markDeleted(dbFile)
+		if err != nil {
+			return count, err
+		}
+		if deleted {
+			count++
+		}
+	}
+	if len(plain) > 0 {
+		result := s.files().Model(&DBFile{}).
+			Where("id IN ? AND status IN (1, 3)", plain).
+			Updates(statusUpdate(2))
+		if result.Error != nil {
+			return count, result.Error
+		}
+		count += result.RowsAffected
+	}
+	s.deleteDerived(sources...)
+	return count, nil
+}
+
+// ListFiles 分页获取用户上传的文件，按上传时间倒序，包括待扫描的文件，不含缩略图等派生文件
+func (s *dbStorage) ListFiles(userID int64, filter *FileFilter) (*FileList, error) {
+	var f FileFilter
+	if filter != nil {
+		f = *filter
+	}
+	if f.Page <= 0 {
+		f.Page = 1
+	}
+	if f.PageSize <= 0 {
+		f.PageSize = 20
+	}
+	if f.PageSize > 100 {
+		f.PageSize = 100
+	}
+
+	query := s.files().Model(&DBFile{}).Where("user_id = ? AND status IN (1, 3) AND source_id = ''", userID)
+	if f.FileType != "" {
+		query = query.Where("file_type = ?", f.FileType)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var dbFiles []DBFile
+	if err := query.Select(fileInfoColumns).Order("created_at DESC, id DESC").
+		Offset((f.Page - 1) * f.PageSize).Limit(f.PageSize).Find(&dbFiles).Error; err != nil {
+		return nil, err
+	}
+	list := &FileList{Files: make([]*FileInfo, len(dbFiles)), Total: total, Page: f.Page, PageSize: f.PageSize}
+	for i := range dbFiles {
+		list.Files[i] = s.toFileInfo(&dbFiles[i])
+	}
+	return list, nil
+}
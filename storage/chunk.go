@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fileChunkSize 数据库分块大小，每块一行，需小于 MEDIUMBLOB 上限和 MySQL max_allowed_packet
+// 分块大小随文件记录保存，修改后只影响新上传的文件
+const fileChunkSize = 1 << 20 // 1MB
+
+// chunks 分块表
+func (s *dbStorage) chunks() *gorm.DB {
+	return s.db.WithContext(s.ctx).Table(s.chunkTable)
+}
+
+// writeChunks 按 fileChunkSize 读取 r 并逐块写入分块表，返回读取的总字节数
+// 出错时已写入的分块由调用方通过 deleteChunks 清理
+func (s *dbStorage) writeChunks(fileID string, r io.Reader) (int64, error) {
+	return s.appendChunks(fileID, fileChunkSize, 0, r)
+}
+
+// appendChunks 从 offset 处开始将 r 追加到分块表，offset 需为已写入内容的长度
+// offset 不在分块边界时先补齐最后一个不完整的分块，返回本次读取的字节数
+func (s *dbStorage) appendChunks(fileID string, chunkSize int, offset int64, r io.Reader) (int64, error) {
+	var total int64
+	seq := int(offset / int64(chunkSize))
+
+	// 补齐最后一个不完整的分块
+	if fill := int(offset % int64(chunkSize)); fill > 0 {
+		var last DBFileChunk
+		if err := s.chunks().Where("file_id = ? AND seq = ?", fileID, seq).First(&last).Error; err != nil {
+			return 0, fmt.Errorf("load file chunk %d failed: %w", seq, err)
+		}
+		buf := make([]byte, chunkSize)
+		copy(buf, last.Data)
+		n, err := io.ReadFull(r, buf[fill:])
+		if n > 0 {
+			if err := s.chunks().Where("id = ?", last.ID).Update("data", buf[:fill+n]).Error; err != nil {
+				return 0, fmt.Errorf("save file chunk failed: %w", err)
+			}
+			total += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return total, nil
+		}
+		if err != nil {
+			return total, fmt.Errorf("read file failed: %w", err)
+		}
+		seq++
+	}
+
+	buf := make([]byte, chunkSize)
+	for ; ; seq++ {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			chunk := &DBFileChunk{FileID: fileID, Seq: seq, Data: buf[:n]}
+			if err := s.chunks().Create(chunk).Error; err != nil {
+				return total, fmt.Errorf("save file chunk failed: %w", err)
+			}
+			total += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return total, nil
+		}
+		if err != nil {
+			return total, fmt.Errorf("read file failed: %w", err)
+		}
+	}
+}
+
+// deleteChunks 删除文件的全部分块
+func (s *dbStorage) deleteChunks(fileID string) error {
+	return s.chunks().Where("file_id = ?", fileID).Delete(&DBFileChunk{}).Error
+}
+
+// chunkReader 分块读取器，按读取位置逐块查询，同一时间只在内存中保留一块
+type chunkReader struct {
+	s         *dbStorage
+	fileID    string
+	size      int64
+	chunkSize int64
+	offset    int64
+	seq       int64 // 当前已加载的分块序号，-1 表示未加载
+	data      []byte
+}
+
+// newChunkReader 创建分块读取器
+func newChunkReader(s *dbStorage, fileID string, size int64, chunkSize int) *chunkReader {
+	return &chunkReader{s: s, fileID: fileID, size: size, chunkSize: int64(chunkSize), seq: -1}
+}
+
+// Read 读取文件内容
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if c.offset >= c.size {
+		return 0, io.EOF
+	}
+	seq := c.offset / c.chunkSize
+	if seq != c.seq {
+		var chunk DBFileChunk
+		start := time.Now()
+		err := c.s.chunks().Where("file_id = ? AND seq = ?", c.fileID, seq).First(&chunk).Error
+		c.s.observe(OpBlobRead, c.fileID, "", int64(len(chunk.Data)), start, err)
+		if err != nil {
+			return 0, fmt.Errorf("load file chunk %d failed: %w", seq, err)
+		}
+		c.seq, c.data = seq, chunk.Data
+	}
+
+	start := c.offset - seq*c.chunkSize
+	if start >= int64(len(c.data)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, c.data[start:])
+	c.offset += int64(n)
+	return n, nil
+}
+
+// Seek 设置读取位置
+func (c *chunkReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += c.offset
+	case io.SeekEnd:
+		offset += c.size
+	}
+	if offset < 0 {
+		return 0, errors.New("chunk reader: negative position")
+	}
+	c.offset = offset
+	return offset, nil
+}
+
+// Close 释放已加载的分块
+func (c *chunkReader) Close() error {
+	c.data = nil
+	c.seq = -1
+	return nil
+}
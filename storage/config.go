@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bbadbeef/go-base/config"
+)
+
+// fileConfig 配置文件中 storage 段的字段，env 标签为覆盖该字段的环境变量
+type fileConfig struct {
+	TenantID        string                     `json:"tenant_id" env:"STORAGE_TENANT_ID"`
+	BaseURL         string                     `json:"base_url" env:"STORAGE_BASE_URL"`
+	TablePrefix     string                     `json:"table_prefix" env:"STORAGE_TABLE_PREFIX"`
+	TableNames      map[string]string          `json:"table_names"`
+	AutoMigrate     *bool                      `json:"auto_migrate" env:"STORAGE_AUTO_MIGRATE"`
+	Backend         string                     `json:"backend" env:"STORAGE_BACKEND"`
+	S3              *fileS3Config              `json:"s3"`
+	UserQuota       int64                      `json:"user_quota" env:"STORAGE_USER_QUOTA"`
+	StripMetadata   bool                       `json:"strip_metadata" env:"STORAGE_STRIP_METADATA"`
+	ScanAsync       bool                       `json:"scan_async" env:"STORAGE_SCAN_ASYNC"`
+	ImageCacheBytes int64                      `json:"image_cache_bytes" env:"STORAGE_IMAGE_CACHE_BYTES"`
+	RetentionPolicy map[string]config.Duration `json:"retention_policy"`
+	SlowThreshold   config.Duration            `json:"slow_threshold" env:"STORAGE_SLOW_THRESHOLD"`
+}
+
+// fileS3Config 配置文件中的 S3 配置
+type fileS3Config struct {
+	Endpoint        string `json:"endpoint" env:"STORAGE_S3_ENDPOINT"`
+	Region          string `json:"region" env:"STORAGE_S3_REGION"`
+	Bucket          string `json:"bucket" env:"STORAGE_S3_BUCKET"`
+	AccessKeyID     string `json:"access_key_id" env:"STORAGE_S3_ACCESS_KEY_ID"`
+	SecretAccessKey string `json:"secret_access_key" env:"STORAGE_S3_SECRET_ACCESS_KEY"`
+	SessionToken    string `json:"session_token" env:"STORAGE_S3_SESSION_TOKEN"`
+	PathStyle       bool   `json:"path_style" env:"STORAGE_S3_PATH_STYLE"`
+	KeyPrefix       string `json:"key_prefix" env:"STORAGE_S3_KEY_PREFIX"`
+}
+
+// LoadConfig 从 YAML / JSON 配置文件加载配置，读取文件的 storage 段（没有时读取整个文档），可与 im、user 共用一个文档：
+//
+//	storage:
+//	  base_url: https://files.example.com
+//	  backend: s3
+//	  s3:
+//	    endpoint: https://s3.us-east-1.amazonaws.com
+//	    bucket: my-bucket
+//	    access_key_id: AKIA...     # 建议通过环境变量 STORAGE_S3_ACCESS_KEY_ID 设置
+//	  user_quota: 1073741824
+//	  retention_policy: {voice: 720h}
+//	  slow_threshold: 200ms
+//
+// 环境变量覆盖文件中的值，变量名为 STORAGE_ 加大写的字段名，S3 配置为 STORAGE_S3_ 前缀，如 STORAGE_S3_SECRET_ACCESS_KEY
+// 数据库连接、缩略图、扫描器等无法序列化的配置需在返回的 Config 上设置后再调用 NewStorage
+func LoadConfig(path string) (*Config, error) {
+	var fc fileConfig
+	if err := config.Load(path, "storage", &fc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	cfg := &Config{
+		TenantID:           fc.TenantID,
+		BaseURL:            fc.BaseURL,
+		TablePrefix:        fc.TablePrefix,
+		TableNames:         fc.TableNames,
+		DisableAutoMigrate: fc.AutoMigrate != nil && !*fc.AutoMigrate,
+		Backend:            fc.Backend,
+		UserQuota:          fc.UserQuota,
+		StripMetadata:      fc.StripMetadata,
+		ScanAsync:          fc.ScanAsync,
+		ImageCacheBytes:    fc.ImageCacheBytes,
+		SlowThreshold:      time.Duration(fc.SlowThreshold),
+	}
+	if fc.S3 != nil {
+		cfg.S3 = &S3Config{
+			Endpoint:        fc.S3.Endpoint,
+			Region:          fc.S3.Region,
+			Bucket:          fc.S3.Bucket,
+			AccessKeyID:     fc.S3.AccessKeyID,
+			SecretAccessKey: fc.S3.SecretAccessKey,
+			SessionToken:    fc.S3.SessionToken,
+			PathStyle:       fc.S3.PathStyle,
+			KeyPrefix:       fc.S3.KeyPrefix,
+		}
+	}
+	if len(fc.RetentionPolicy) > 0 {
+		cfg.RetentionPolicy = make(map[string]time.Duration, len(fc.RetentionPolicy))
+		for fileType, d := range fc.RetentionPolicy {
+			cfg.RetentionPolicy[fileType] = time.Duration(d)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// blobTableDB 内容表，不限定租户，用于表结构迁移
+func (s *dbStorage) blobTableDB() *gorm.DB {
+	return s.db.WithContext(s.ctx).Table(s.blobTable)
+}
+
+// blobs 内容表，限定当前租户，相同内容只在同一租户内去重
+func (s *dbStorage) blobs() *gorm.DB {
+	return s.blobTableDB().Where("tenant_id = ?", s.tenant)
+}
+
+// dedupe 按内容哈希登记内容，需在内容写入后与保存文件记录在同一事务中调用
+// 已有相同内容时 dbFile 改为引用已有内容并返回 true，调用方在事务提交后删除刚写入的内容
+// 登记后设置 dbFile.ContentHash；并发上传相同内容时后登记的一方不去重
+func (s *dbStorage) dedupe(dbFile *DBFile, hash string) (bool, error) {
+	var blob DBBlob
+	err := s.blobs().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("hash = ? AND ref_count > 0", hash).Take(&blob).Error
+	if err == nil {
+		if err := s.blobs().Where("id = ?", blob.ID).Update("ref_count", gorm.Expr("ref_count + 1")).Error; err != nil {
+			return false, err
+		}
+		dbFile.ContentHash = hash
+		dbFile.ContentID = blob.ContentID
+		dbFile.ObjectKey = blob.ObjectKey
+		dbFile.ChunkSize = blob.ChunkSize
+		return true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	blob = DBBlob{
+		TenantID:  s.tenant,
+		Hash:      hash,
+		Size:      dbFile.FileSize,
+		ContentID: dbFile.contentFileID(),
+		ObjectKey: dbFile.ObjectKey,
+		ChunkSize: dbFile.ChunkSize,
+		RefCount:  1,
+	}
+	result := s.blobs().Clauses(clause.OnConflict{DoNothing: true}).Create(&blob)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		dbFile.ContentHash = hash
+	}
+	return false, nil
+}
+
+// releaseBlob 释放一个内容引用，最后一个引用释放后删除内容
+func (s *dbStorage) releaseBlob(hash string) error {
+	if err := s.blobs().Where("hash = ? AND ref_count > 0", hash).
+		Update("ref_count", gorm.Expr("ref_count - 1")).Error; err != nil {
+		return err
+	}
+
+	var blob DBBlob
+	if err := s.blobs().Where("hash = ? AND ref_count = 0", hash).Take(&blob).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	// 先删除登记，删除成功的一方负责删除内容
+	result := s.blobs().Where("id = ? AND ref_count = 0", blob.ID).Delete(&DBBlob{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	if blob.ObjectKey != "" {
+		if s.objects == nil {
+			return nil
+		}
+		return s.objects.Delete(s.ctx, blob.ObjectKey)
+	}
+	return s.deleteChunks(blob.ContentID)
+}
+
+// hashContent 计算已写入内容的 SHA-256
+func (s *dbStorage) hashContent(dbFile *DBFile) (string, error) {
+	rc, err := s.openContent(dbFile)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contentFileID 内容所在分块的文件ID，引用其他文件的内容时为被引用文件的ID
+func (f *DBFile) contentFileID() string {
+	if f.ContentID != "" {
+		return f.ContentID
+	}
+	return f.FileID
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// maxWrappedKeySize 密钥信封中包装后密钥的最大长度
+const maxWrappedKeySize = 4096
+
+// KeyEnvelope 文件密钥信封：客户端用接收者的公钥包装文件密钥，服务端只保存包装后的密钥，接触不到文件明文
+type KeyEnvelope struct {
+	UserID     int64  `json:"user_id"`             // 接收者用户ID
+	DeviceID   string `json:"device_id,omitempty"` // 接收者设备ID（可选），最长 64 个字符，按设备分发密钥时使用
+	KeyID      string `json:"key_id,omitempty"`    // 包装所用的接收者公钥ID（可选），最长 128 个字符，便于客户端选择私钥
+	Algorithm  string `json:"algorithm,omitempty"` // 包装算法（可选），最长 64 个字符，如 "X25519-HKDF-AES256GCM"，由客户端约定
+	WrappedKey []byte `json:"wrapped_key"`         // 包装后的文件密钥，JSON 中为 base64，最长 4096 字节
+}
+
+// KeyProvider 包装、解包文件密钥，由客户端基于接收者的公钥和本机私钥实现，服务端不需要
+// 配合 SealFile、OpenFile 在 Go 客户端（如机器人）中加密、解密文件
+type KeyProvider interface {
+	// WrapKey 用接收者（各设备）的公钥包装文件密钥，返回其密钥信封
+	WrapKey(ctx context.Context, userID int64, fileKey []byte) ([]*KeyEnvelope, error)
+	// UnwrapKey 用本机私钥解包密钥信封，返回文件密钥，信封不属于本机时返回错误
+	UnwrapKey(ctx context.Context, envelope *KeyEnvelope) ([]byte, error)
+}
+
+// SealFile 用随机生成的 AES-256-GCM 密钥加密文件内容，并为每个接收者包装密钥
+// 密文格式为 [12 字节 nonce][密文和认证标签]，以 UploadMeta.Encrypted 上传后通过 AddKeyEnvelopes 保存信封
+// recipients 需包含上传者本人，以便在其他设备上解密
+func SealFile(ctx context.Context, kp KeyProvider, plaintext []byte, recipients []int64) ([]byte, []*KeyEnvelope, error) {
+	fileKey := make([]byte, 32)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, nil, err
+	}
+	gcm, err := newFileCipher(fileKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
+
+	var envelopes []*KeyEnvelope
+	for _, userID := range recipients {
+		wrapped, err := kp.WrapKey(ctx, userID, fileKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("wrap file key for user %d failed: %w", userID, err)
+		}
+		envelopes = append(envelopes, wrapped...)
+	}
+	return ciphertext, envelopes, nil
+}
+
+// OpenFile 解密 SealFile 生成的密文，envelopes 为 GetKeyEnvelopes 返回的信封，使用第一个能解包的
+func OpenFile(ctx context.Context, kp KeyProvider, ciphertext []byte, envelopes []*KeyEnvelope) ([]byte, error) {
+	err := ErrKeyEnvelopeNotFound
+	for _, envelope := range envelopes {
+		var fileKey []byte
+		if fileKey, err = kp.UnwrapKey(ctx, envelope); err != nil {
+			continue
+		}
+		gcm, err := newFileCipher(fileKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) < gcm.NonceSize() {
+			return nil, errors.New("decrypt file failed: ciphertext too short")
+		}
+		nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+		plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt file failed: %w", err)
+		}
+		return plaintext, nil
+	}
+	return nil, fmt.Errorf("unwrap file key failed: %w", err)
+}
+
+// newFileCipher 创建文件内容的 AES-GCM 加密器
+func newFileCipher(fileKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// fileKeyTableDB 密钥信封表，不限定租户，用于表结构迁移
+func (s *dbStorage) fileKeyTableDB() *gorm.DB {
+	return s.db.WithContext(s.ctx).Table(s.fileKeyTable)
+}
+
+// fileKeys 密钥信封表，限定当前租户
+func (s *dbStorage) fileKeys() *gorm.DB {
+	return s.fileKeyTableDB().Where("tenant_id = ?", s.tenant)
+}
+
+// AddKeyEnvelopes 保存加密文件的密钥信封，同一接收者（设备）已有信封时替换
+// 用于上传后分发密钥，或会话加入新成员后为其补充密钥
+func (s *dbStorage) AddKeyEnvelopes(fileID string, envelopes []*KeyEnvelope) error {
+	for _, e := range envelopes {
+		if e == nil || e.UserID <= 0 || len(e.WrappedKey) == 0 || len(e.WrappedKey) > maxWrappedKeySize ||
+			len(e.DeviceID) > 64 || len(e.KeyID) > 128 || len(e.Algorithm) > 64 {
+			return fmt.Errorf("%w: user id and wrapped key are required, fields must not exceed their length limits", ErrInvalidKeyEnvelope)
+		}
+	}
+
+	var dbFile DBFile
+	if err := s.files().Select("id, encrypted").Where("file_id = ? AND status IN (1, 3)", fileID).First(&dbFile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFileNotFound
+		}
+		return err
+	}
+	if !dbFile.Encrypted {
+		return fmt.Errorf("%w: file %s is not encrypted", ErrInvalidKeyEnvelope, fileID)
+	}
+
+	return s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		ts := s.withDB(tx)
+		for _, e := range envelopes {
+			if err := ts.fileKeys().Where("file_id = ? AND user_id = ? AND device_id = ?", fileID, e.UserID, e.DeviceID).
+				Delete(&DBFileKey{}).Error; err != nil {
+				return err
+			}
+			if err := ts.fileKeys().Create(&DBFileKey{
+				TenantID:   s.tenant,
+				FileID:     fileID,
+				UserID:     e.UserID,
+				DeviceID:   e.DeviceID,
+				KeyID:      e.KeyID,
+				Algorithm:  e.Algorithm,
+				WrappedKey: e.WrappedKey,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetKeyEnvelopes 获取用户在加密文件上的密钥信封（每个设备一个），没有信封时返回 ErrKeyEnvelopeNotFound
+func (s *dbStorage) GetKeyEnvelopes(fileID string, userID int64) ([]*KeyEnvelope, error) {
+	var count int64
+	if err := s.files().Model(&DBFile{}).Where("file_id = ? AND status = 1", fileID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrFileNotFound
+	}
+
+	var keys []DBFileKey
+	if err := s.fileKeys().Where("file_id = ? AND user_id = ?", fileID, userID).Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrKeyEnvelopeNotFound
+	}
+	envelopes := make([]*KeyEnvelope, len(keys))
+	for i, key := range keys {
+		envelopes[i] = &KeyEnvelope{
+			UserID:     key.UserID,
+			DeviceID:   key.DeviceID,
+			KeyID:      key.KeyID,
+			Algorithm:  key.Algorithm,
+			WrappedKey: key.WrappedKey,
+		}
+	}
+	return envelopes, nil
+}
+
+// deleteKeyEnvelopes 删除文件的所有密钥信封
+func (s *dbStorage) deleteKeyEnvelopes(fileID string) error {
+	return s.fileKeys().Where("file_id = ?", fileID).Delete(&DBFileKey{}).Error
+}
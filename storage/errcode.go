@@ -0,0 +1,22 @@
+package storage
+
+import "github.com/bbadbeef/go-base/errcode"
+
+// 将存储模块的错误绑定到统一错误码目录
+func init() {
+	errcode.Bind(ErrInvalidConfig, errcode.InvalidConfig)
+	errcode.Bind(ErrInvalidUploadRequest, errcode.InvalidUpload)
+	errcode.Bind(ErrFileNotFound, errcode.FileNotFound)
+	errcode.Bind(ErrFileTooLarge, errcode.FileTooLarge)
+	errcode.Bind(ErrUnsupportedMimeType, errcode.UnsupportedMimeType)
+	errcode.Bind(ErrUnknownFileType, errcode.UnknownFileType)
+	errcode.Bind(ErrUploadNotFound, errcode.UploadNotFound)
+	errcode.Bind(ErrUploadOffsetMismatch, errcode.UploadOffsetMismatch)
+	errcode.Bind(ErrUploadIncomplete, errcode.UploadIncomplete)
+	errcode.Bind(ErrQuotaExceeded, errcode.QuotaExceeded)
+	errcode.Bind(ErrMalwareDetected, errcode.MalwareDetected)
+	errcode.Bind(ErrMimeTypeMismatch, errcode.MimeTypeMismatch)
+	errcode.Bind(ErrInvalidImageOptions, errcode.InvalidParam)
+	errcode.Bind(ErrInvalidKeyEnvelope, errcode.InvalidParam)
+	errcode.Bind(ErrKeyEnvelopeNotFound, errcode.KeyEnvelopeNotFound)
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 错误定义，可通过 errors.Is 判断
+var (
+	ErrInvalidConfig        = errors.New("invalid config")
+	ErrInvalidUploadRequest = errors.New("invalid upload request")
+	ErrFileNotFound         = errors.New("file not found")
+	ErrFileTooLarge         = errors.New("file too large")
+	ErrUnsupportedMimeType  = errors.New("unsupported mime type")
+	ErrUnknownFileType      = errors.New("unknown file type")
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadIncomplete     = errors.New("upload incomplete")
+	ErrQuotaExceeded        = errors.New("storage quota exceeded")
+	ErrMalwareDetected      = errors.New("malware detected")
+	ErrMimeTypeMismatch     = errors.New("mime type mismatch")
+	ErrInvalidImageOptions  = errors.New("invalid image options")
+	ErrInvalidKeyEnvelope   = errors.New("invalid key envelope")
+	ErrKeyEnvelopeNotFound  = errors.New("key envelope not found")
+)
+
+// FileTooLargeError 文件大小超过限制
+// errors.Is(err, ErrFileTooLarge) 为 true，可通过 errors.As 获取限制大小
+type FileTooLargeError struct {
+	Size    int64 // 实际大小（字节）
+	MaxSize int64 // 最大允许大小（字节）
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("file too large: %d bytes, max %.1fMB", e.Size, float64(e.MaxSize)/(1024*1024))
+}
+
+// Is 支持 errors.Is(err, ErrFileTooLarge)
+func (e *FileTooLargeError) Is(target error) bool {
+	return target == ErrFileTooLarge
+}
+
+// QuotaExceededError 用户存储用量超过配额
+// errors.Is(err, ErrQuotaExceeded) 为 true，可通过 errors.As 获取用量和配额
+type QuotaExceededError struct {
+	Used  int64 // 已用字节数
+	Size  int64 // 本次上传的字节数
+	Quota int64 // 配额（字节）
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("storage quota exceeded: used %d + %d bytes, quota %d bytes", e.Used, e.Size, e.Quota)
+}
+
+// Is 支持 errors.Is(err, ErrQuotaExceeded)
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// MalwareError 文件被病毒扫描检出恶意内容
+// errors.Is(err, ErrMalwareDetected) 为 true，可通过 errors.As 获取威胁名称
+type MalwareError struct {
+	Threat string // 扫描器报告的威胁名称
+}
+
+func (e *MalwareError) Error() string {
+	return fmt.Sprintf("malware detected: %s", e.Threat)
+}
+
+// Is 支持 errors.Is(err, ErrMalwareDetected)
+func (e *MalwareError) Is(target error) bool {
+	return target == ErrMalwareDetected
+}
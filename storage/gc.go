@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ReferenceFunc 返回 fileIDs 中仍被引用（如被 IM 消息引用）的文件，未包含的视为未引用
+type ReferenceFunc func(ctx context.Context, fileIDs []string) (map[string]bool, error)
+
+// GCOptions 垃圾回收选项，零值字段使用默认值
+type GCOptions struct {
+	DeletedRetention time.Duration // 已删除、已隔离文件的保留时长，超过后删除记录和内容，默认 30 天
+	OrphanAge        time.Duration // 上传超过该时长仍未被引用的文件标记为已删除（可选），需设置 IsReferenced，0 表示不清理
+	IsReferenced     ReferenceFunc // 判断文件是否被引用，可使用 IM 模块的 FilesReferenced；头像等不经消息引用的文件需一并判断
+	BatchSize        int           // 每批处理的文件数，默认 500
+	DryRun           bool          // 只统计不删除，用于核对清理范围
+}
+
+// withDefaults 填充默认值
+func (o GCOptions) withDefaults() GCOptions {
+	if o.DeletedRetention <= 0 {
+		o.DeletedRetention = 30 * 24 * time.Hour
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 500
+	}
+	return o
+}
+
+// GCReport 垃圾回收结果，DryRun 时为将要清理的数量
+type GCReport struct {
+	DryRun         bool     `json:"dry_run"`
+	Purged         int64    `json:"purged"`          // 删除记录和内容的已删除文件数
+	PurgedBytes    int64    `json:"purged_bytes"`    // 删除的已删除文件大小
+	Expired        int64    `json:"expired"`         // 标记为已删除的过期文件数
+	ExpiredBytes   int64    `json:"expired_bytes"`   // 过期文件大小
+	Orphaned       int64    `json:"orphaned"`        // 标记为已删除的未引用文件数
+	OrphanedBytes  int64    `json:"orphaned_bytes"`  // 未引用文件大小
+	OrphanedIDs    []string `json:"orphaned_ids"`    // 未引用文件ID
+	ExpiredUploads int64    `json:"expired_uploads"` // 删除的过期上传任务数，DryRun 时不统计
+}
+
+// GC 执行一次垃圾回收：
+//  1. 超过过期时间（上传时按保留策略或 UploadMeta.TTL 设置）的文件标记为已删除
+//  2. 上传超过 OrphanAge 仍未被引用的文件标记为已删除（缩略图等派生文件随源文件处理）
+//  3. 删除或隔离超过 DeletedRetention 的文件删除记录，未参与去重的内容一并删除，去重的内容删除文件时已释放
+//  4. 删除过期的断点续传上传任务
+func (s *dbStorage) GC(opts *GCOptions) (*GCReport, error) {
+	var o GCOptions
+	if opts != nil {
+		o = *opts
+	}
+	o = o.withDefaults()
+	if o.OrphanAge > 0 && o.IsReferenced == nil {
+		return nil, fmt.Errorf("%w: IsReferenced is required when OrphanAge is set", ErrInvalidConfig)
+	}
+
+	report := &GCReport{DryRun: o.DryRun}
+	if err := s.expireFiles(&o, report); err != nil {
+		return report, fmt.Errorf("expire files failed: %w", err)
+	}
+	if o.OrphanAge > 0 {
+		if err := s.collectOrphans(&o, report); err != nil {
+			return report, fmt.Errorf("collect orphaned files failed: %w", err)
+		}
+	}
+	if err := s.purgeDeleted(&o, report); err != nil {
+		return report, fmt.Errorf("purge deleted files failed: %w", err)
+	}
+	if !o.DryRun {
+		n, err := s.CleanupExpiredUploads()
+		report.ExpiredUploads = n
+		if err != nil {
+			return report, fmt.Errorf("cleanup expired uploads failed: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// RunGC 立即执行一次垃圾回收，之后每隔 interval 执行，直到 ctx 取消
+// onReport 接收每次的结果（可选），可用于记录日志
+//
+//	go storage.RunGC(ctx, st, 24*time.Hour, &storage.GCOptions{...}, nil)
+func RunGC(ctx context.Context, st Storage, interval time.Duration, opts *GCOptions, onReport func(*GCReport, error)) {
+	st = st.WithContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := st.GC(opts)
+		if onReport != nil {
+			onReport(report, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectOrphans 按批查询上传超过 OrphanAge 的正常文件，未被引用的标记为已删除
+func (s *dbStorage) collectOrphans(o *GCOptions, report *GCReport) error {
+	cutoff := time.Now().Add(-o.OrphanAge)
+	var lastID int64
+	for {
+		var batch []DBFile
+		if err := s.files().Select("id, file_id, file_size, content_hash").
+			Where("id > ? AND status = 1 AND source_id = '' AND created_at < ?", lastID, cutoff).
+			Order("id").Limit(o.BatchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+
+		fileIDs := make([]string, len(batch))
+		for i := range batch {
+			fileIDs[i] = batch[i].FileID
+		}
+		referenced, err := o.IsReferenced(s.ctx, fileIDs)
+		if err != nil {
+			return err
+		}
+
+		for i := range batch {
+			dbFile := &batch[i]
+			if referenced[dbFile.FileID] {
+				continue
+			}
+			if !o.DryRun {
+				deleted, err := s.markDeleted(dbFile)
+				if err != nil {
+					return err
+				}
+				if !deleted {
+					continue
+				}
+				s.deleteDerived(dbFile.FileID)
+			}
+			report.Orphaned++
+			report.OrphanedBytes += dbFile.FileSize
+			report.OrphanedIDs = append(report.OrphanedIDs, dbFile.FileID)
+		}
+	}
+}
+
+// purgeDeleted 按批删除超过保留期的已删除、已隔离文件
+// 迁移前删除的文件没有删除时间，按上传时间判断
+func (s *dbStorage) purgeDeleted(o *GCOptions, report *GCReport) error {
+	cutoff := time.Now().Add(-o.DeletedRetention)
+	var lastID int64
+	for {
+		var batch []DBFile
+		if err := s.files().Select("id, file_id, file_size, object_key, chunk_size, content_hash").
+			Where("id > ? AND status IN (2, 4) AND COALESCE(deleted_at, created_at) < ?", lastID, cutoff).
+			Order("id").Limit(o.BatchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+
+		for i := range batch {
+			dbFile := &batch[i]
+			if !o.DryRun {
+				if err := s.purgeFile(dbFile); err != nil {
+					return err
+				}
+			}
+			report.Purged++
+			report.PurgedBytes += dbFile.FileSize
+		}
+	}
+}
+
+// purgeFile 删除文件记录和密钥信封，未参与去重的内容一并删除
+func (s *dbStorage) purgeFile(dbFile *DBFile) error {
+	if dbFile.ContentHash == "" {
+		switch {
+		case dbFile.ObjectKey != "":
+			if s.objects != nil {
+				if err := s.objects.Delete(s.ctx, dbFile.ObjectKey); err != nil {
+					return err
+				}
+			}
+		case dbFile.ChunkSize > 0:
+			if err := s.deleteChunks(dbFile.FileID); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.deleteKeyEnvelopes(dbFile.FileID); err != nil {
+		return err
+	}
+	return s.files().Where("id = ?", dbFile.ID).Delete(&DBFile{}).Error
+}
@@ -3,12 +3,31 @@ module github.com/bbadbeef/go-base/storage
 go 1.21
 
 require (
+	github.com/bbadbeef/go-base/config v0.0.0
+	github.com/bbadbeef/go-base/errcode v0.0.0
+	github.com/bbadbeef/go-base/log v0.0.0
+	github.com/bbadbeef/go-base/observability v0.0.0
 	github.com/google/uuid v1.6.0
 	gorm.io/gorm v1.25.12
 )
 
 require (
+	github.com/bbadbeef/go-base/idgen v0.0.0
+	github.com/bbadbeef/go-base/migrate v0.0.0
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
 	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/bbadbeef/go-base/errcode => ../errcode
+
+replace github.com/bbadbeef/go-base/observability => ../observability
+
+replace github.com/bbadbeef/go-base/log => ../log
+
+replace github.com/bbadbeef/go-base/config => ../config
+
+replace github.com/bbadbeef/go-base/idgen => ../idgen
+
+replace github.com/bbadbeef/go-base/migrate => ../migrate
@@ -0,0 +1,199 @@
+// Package httpapi 提供可直接挂载的文件存储 REST 接口（上传、断点续传、下载、图片处理、文件信息、文件列表、删除、批量操作、用量）
+//
+//	h := httpapi.NewHandler(st, httpapi.BearerAuth(verifyToken), nil)
+//	mux.Handle("/api/upload/", http.StripPrefix("/api", h))
+//	mux.Handle("/api/uploads/", http.StripPrefix("/api", h))
+//	mux.Handle("/api/files", http.StripPrefix("/api", h))
+//	mux.Handle("/api/files/", http.StripPrefix("/api", h))
+//	mux.Handle("/api/usage", http.StripPrefix("/api", h))
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/storage"
+)
+
+// AuthFunc 从请求中解析当前用户 ID，失败时返回错误
+// 返回的错误未绑定错误码时按 errcode.Unauthorized 响应
+type AuthFunc func(r *http.Request) (userID int64, err error)
+
+// BearerAuth 从 Authorization: Bearer <token> 请求头取令牌并调用 verify 校验
+func BearerAuth(verify func(token string) (userID int64, err error)) AuthFunc {
+	return func(r *http.Request) (int64, error) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			return 0, errcode.New(errcode.Unauthorized, requestLang(r))
+		}
+		return verify(strings.TrimSpace(token))
+	}
+}
+
+// AccessFunc 校验用户能否下载他人上传的文件，上传者本人无需校验
+// 派生文件（如缩略图）传入其源文件，返回 false 时按 errcode.Forbidden 响应
+// 与 IM 模块集成时可校验用户是否为文件所在会话的参与者：
+//
+//	AccessCheck: func(ctx context.Context, userID int64, file *storage.FileInfo) (bool, error) {
+//		return imService.CanAccessFile(ctx, userID, file.FileID)
+//	}
+type AccessFunc func(ctx context.Context, userID int64, file *storage.FileInfo) (bool, error)
+
+// Config HTTP 接口配置，零值字段使用默认值
+type Config struct {
+	MaxUploadBytes  int64      // 上传请求体（含断点续传的单个分片）大小上限，默认 storage.MaxFileSize + 1 MiB（留给 multipart 表单开销），上传时同时受文件类型上限限制
+	PrivateDownload bool       // 下载需要登录，默认公开，便于在 <img> 等无法携带令牌的场景直接使用文件 URL
+	AccessCheck     AccessFunc // 下载权限校验（可选），设置后下载需要登录，仅上传者和校验通过的用户可下载
+	CacheMaxAge     int        // 下载响应的 Cache-Control max-age（秒），默认 86400，文件内容不可变
+}
+
+// withDefaults 填充默认值
+func (c Config) withDefaults() Config {
+	if c.MaxUploadBytes <= 0 {
+		c.MaxUploadBytes = storage.MaxFileSize + 1<<20
+	}
+	if c.CacheMaxAge <= 0 {
+		c.CacheMaxAge = 86400
+	}
+	if c.AccessCheck != nil {
+		c.PrivateDownload = true
+	}
+	return c
+}
+
+// route 路由
+type route struct {
+	handler authHandler
+	auth    bool // 需要登录
+}
+
+// authHandler 处理函数，userID 为当前用户，无需登录的路由为 0
+type authHandler func(w http.ResponseWriter, r *http.Request, userID int64)
+
+// Handler 文件存储 REST 接口
+type Handler struct {
+	st     storage.Storage
+	auth   AuthFunc
+	config Config
+	routes map[string]map[string]route // path -> method -> route
+}
+
+// NewHandler 创建文件存储 REST 接口，config 为 nil 时使用默认值
+// 路由相对于挂载点，storage 生成的文件 URL 为 {BaseURL}/api/files/{id}，通常挂载在 /api 下：
+//
+//	POST   /upload/{type}           上传文件（需登录），type 为 image、video、voice、file
+//	                                multipart/form-data 取 file 字段，其他 Content-Type 按原始内容上传，文件名取 ?name=
+//	                                客户端加密的文件加 ?encrypted=1，临时文件加 ?ttl=秒数
+//	POST   /upload/avatar           上传头像（需登录），请求体同上，返回原图和小、中、大三种尺寸的 URL
+//	POST   /uploads/{type}          创建断点续传上传任务（需登录），请求体为 {"file_name","mime_type","size","encrypted","ttl"}
+//	GET    /uploads/{id}            获取上传任务，received 为已接收的字节数（需登录，仅创建者，下同）
+//	PUT    /uploads/{id}?offset=N   上传分片，请求体为原始内容
+//	POST   /uploads/{id}/complete   完成上传，返回文件信息
+//	DELETE /uploads/{id}            取消上传任务
+//	GET    /files/{id}              下载文件，支持 Range 和条件请求（也支持 HEAD），配置 AccessCheck 时校验下载权限
+//	GET    /files/{id}/image        按需缩放、裁剪、转换格式后返回图片，查询参数 w、h（最大宽高）、crop=1（居中裁剪）、format（jpeg、png）、q（JPEG 质量），权限同下载
+//	GET    /files/{id}/info         获取文件信息（需登录，仅上传者）
+//	POST   /files/{id}/keys         保存加密文件的密钥信封，请求体为 {"envelopes":[...]}（需登录，仅上传者）
+//	GET    /files/{id}/keys         获取当前用户在加密文件上的密钥信封（需登录）
+//	DELETE /files/{id}              删除文件（需登录，仅上传者）
+//	GET    /files                   分页获取当前用户上传的文件，查询参数 type、page、page_size（需登录）
+//	POST   /files/batch/info        批量获取文件信息，请求体为 {"file_ids":[...]}，最多 100 个（需登录，仅返回自己上传的文件）
+//	POST   /files/batch/delete      批量删除文件，请求体同上，返回删除的文件数（需登录，仅删除自己上传的文件）
+//	GET    /usage                   获取当前用户的存储用量（需登录）
+func NewHandler(st storage.Storage, auth AuthFunc, config *Config) *Handler {
+	var cfg Config
+	if config != nil {
+		cfg = *config
+	}
+	h := &Handler{
+		st:     st,
+		auth:   auth,
+		config: cfg.withDefaults(),
+		routes: make(map[string]map[string]route),
+	}
+
+	h.handle(http.MethodPost, "/upload/", true, h.upload)
+	h.handle(http.MethodPost, "/uploads/", true, h.postUploadResource)
+	h.handle(http.MethodGet, "/uploads/", true, h.getUpload)
+	h.handle(http.MethodPut, "/uploads/", true, h.uploadPart)
+	h.handle(http.MethodDelete, "/uploads/", true, h.abortUpload)
+	h.handle(http.MethodGet, "/files/", h.config.PrivateDownload, h.getFileResource)
+	h.handle(http.MethodHead, "/files/", h.config.PrivateDownload, h.download)
+	h.handle(http.MethodPost, "/files/", true, h.postFileResource)
+	h.handle(http.MethodDelete, "/files/", true, h.deleteFile)
+	h.handle(http.MethodGet, "/files", true, h.listFiles)
+	h.handle(http.MethodPost, "/files/batch/info", true, h.batchFileInfo)
+	h.handle(http.MethodPost, "/files/batch/delete", true, h.batchDelete)
+	h.handle(http.MethodGet, "/usage", true, h.getUsage)
+	return h
+}
+
+// handle 注册路由，以 / 结尾的路径按前缀匹配
+func (h *Handler) handle(method, path string, auth bool, handler authHandler) {
+	if h.routes[path] == nil {
+		h.routes[path] = make(map[string]route)
+	}
+	h.routes[path][method] = route{handler: handler, auth: auth}
+}
+
+// ServeHTTP 分发请求，未知路径返回 404，方法不支持返回 405，认证失败返回 401
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	methods, ok := h.match(r.URL.Path)
+	if !ok {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	rt, ok := methods[r.Method]
+	if !ok {
+		allowed := make([]string, 0, len(methods))
+		for m := range methods {
+			allowed = append(allowed, m)
+		}
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var userID int64
+	if rt.auth {
+		if userID, ok = h.authenticate(w, r); !ok {
+			return
+		}
+	}
+	rt.handler(w, r, userID)
+}
+
+// authenticate 解析当前用户，失败时写入 401 响应并返回 false
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := h.auth(r)
+	if err == nil && userID <= 0 {
+		err = errcode.New(errcode.Unauthorized, requestLang(r))
+	}
+	if err != nil {
+		writeUnauthorized(w, r, err)
+		return 0, false
+	}
+	return userID, true
+}
+
+// match 按路径查找路由，先精确匹配再按前缀匹配
+func (h *Handler) match(path string) (map[string]route, bool) {
+	if path == "" {
+		path = "/"
+	} else if path[0] != '/' {
+		path = "/" + path
+	}
+	if methods, ok := h.routes[path]; ok {
+		return methods, true
+	}
+	for p, methods := range h.routes {
+		if strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) && len(path) > len(p) {
+			return methods, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,449 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/storage"
+)
+
+// filePath 解析 /files/{id}[/info]，返回文件 ID 和其后的路径段
+func filePath(p string) (fileID, sub string, ok bool) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(p, "/"), "files/")
+	fileID, sub, _ = strings.Cut(rest, "/")
+	if fileID == "" || strings.Contains(sub, "/") {
+		return "", "", false
+	}
+	return fileID, sub, true
+}
+
+// multipartOverhead multipart 表单中文件内容以外部分（其他字段、分隔符、头部）的大小上限
+const multipartOverhead = 1 << 20
+
+// uploadError 转换读取上传内容时的错误，超过请求体上限视为文件过大
+func (h *Handler) uploadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &storage.FileTooLargeError{Size: tooLarge.Limit + 1, MaxSize: tooLarge.Limit}
+	}
+	return fmt.Errorf("%w: %v", storage.ErrInvalidUploadRequest, err)
+}
+
+// uploadLimit 上传请求体的大小上限：文件类型的上限加表单开销，且不超过 MaxUploadBytes
+func (h *Handler) uploadLimit(fileType string) int64 {
+	limit := h.config.MaxUploadBytes
+	if maxSize, err := storage.MaxSizeOf(fileType); err == nil {
+		limit = min(limit, maxSize+multipartOverhead)
+	}
+	return limit
+}
+
+// limitBody 限制请求体大小，超过 limit 时读取出错；Content-Length 已超过时不读取请求体，直接返回文件过大
+// 客户端使用 Expect: 100-continue 时，被拒绝的请求体不会发送
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) error {
+	if r.ContentLength > limit {
+		return &storage.FileTooLargeError{Size: r.ContentLength, MaxSize: limit}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return nil
+}
+
+// upload 上传文件，multipart/form-data 取 file 字段，其他 Content-Type 按原始内容上传
+// 请求体直接流式写入存储，不整体读入内存，按文件类型的大小上限提前拒绝过大的请求
+// /upload/avatar 按头像上传，返回各尺寸的 URL
+func (h *Handler) upload(w http.ResponseWriter, r *http.Request, userID int64) {
+	fileType := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/"), "upload/")
+	if fileType == "" || strings.Contains(fileType, "/") {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	avatar := fileType == "avatar"
+	if avatar {
+		fileType = storage.FileTypeImage
+	}
+	if err := limitBody(w, r, h.uploadLimit(fileType)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	meta := &storage.UploadMeta{UserID: userID, FileType: fileType}
+	meta.Encrypted, _ = strconv.ParseBool(r.URL.Query().Get("encrypted"))
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		ttl, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ttl < 0 {
+			writeError(w, r, fmt.Errorf("%w: invalid ttl", storage.ErrInvalidUploadRequest))
+			return
+		}
+		meta.TTL = time.Duration(ttl) * time.Second
+	}
+	contentType := r.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "multipart/form-data" {
+		part, err := filePart(r)
+		if err != nil {
+			writeError(w, r, h.uploadError(err))
+			return
+		}
+		defer part.Close()
+		body = part
+		meta.FileName = part.FileName()
+		meta.MimeType = clientMimeType(part.Header.Get("Content-Type"))
+	} else {
+		meta.FileName = path.Base(r.URL.Query().Get("name"))
+		if meta.FileName == "." || meta.FileName == "/" {
+			meta.FileName = fileType
+		}
+		meta.MimeType = clientMimeType(contentType)
+	}
+
+	var data interface{}
+	var err error
+	if avatar {
+		data, err = h.st.WithContext(r.Context()).UploadAvatar(body, meta)
+	} else {
+		data, err = h.st.WithContext(r.Context()).UploadStream(body, meta)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = h.uploadError(err)
+		}
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, data)
+}
+
+// filePart 查找 multipart 表单中的 file 字段，其前面的字段被跳过
+func filePart(r *http.Request) (*multipart.Part, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("missing file field")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// clientMimeType 客户端声明的 MIME 类型，application/octet-stream 交由 storage 按文件名和内容识别
+func clientMimeType(contentType string) string {
+	if contentType == "application/octet-stream" {
+		return ""
+	}
+	return contentType
+}
+
+// getFileResource 分发 GET /files/{id}、GET /files/{id}/image、GET /files/{id}/info 和 GET /files/{id}/keys
+func (h *Handler) getFileResource(w http.ResponseWriter, r *http.Request, userID int64) {
+	_, sub, ok := filePath(r.URL.Path)
+	switch {
+	case ok && sub == "":
+		h.download(w, r, userID)
+	case ok && sub == "info":
+		if userID == 0 {
+			if userID, ok = h.authenticate(w, r); !ok {
+				return
+			}
+		}
+		h.getFileInfo(w, r, userID)
+	case ok && sub == "image":
+		h.getImage(w, r, userID)
+	case ok && sub == "keys":
+		if userID == 0 {
+			if userID, ok = h.authenticate(w, r); !ok {
+				return
+			}
+		}
+		h.getKeyEnvelopes(w, r, userID)
+	default:
+		writeStatus(w, r, http.StatusNotFound, "not found")
+	}
+}
+
+// download 下载文件，通过 http.ServeContent 支持 Range、If-None-Match、If-Modified-Since
+func (h *Handler) download(w http.ResponseWriter, r *http.Request, userID int64) {
+	fileID, sub, ok := filePath(r.URL.Path)
+	if !ok || sub != "" {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	rc, info, err := h.st.WithContext(r.Context()).DownloadStream(fileID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer rc.Close()
+	if err := h.checkAccess(r, userID, info); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// 密文不按声明的明文类型返回，避免浏览器按图片等渲染
+	contentType, disposition := info.MimeType, "inline"
+	if info.FileType == storage.FileTypeFile || info.Encrypted {
+		disposition = "attachment"
+	}
+	if info.Encrypted {
+		contentType = "application/octet-stream"
+	}
+	cacheScope := "public"
+	if h.config.PrivateDownload {
+		cacheScope = "private"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": info.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", strconv.Quote(info.FileID))
+	w.Header().Set("Cache-Control", cacheScope+", max-age="+strconv.Itoa(h.config.CacheMaxAge))
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, info.FileName, info.UploadTime, rs)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.FileSize, 10))
+	if r.Method != http.MethodHead {
+		io.Copy(w, rc)
+	}
+}
+
+// getImage 按查询参数 w、h、crop、format、q 处理图片后返回，权限和缓存策略与下载相同
+func (h *Handler) getImage(w http.ResponseWriter, r *http.Request, userID int64) {
+	fileID, _, _ := filePath(r.URL.Path)
+	query := r.URL.Query()
+	opts := &storage.ImageOptions{Format: query.Get("format")}
+	opts.Width, _ = strconv.Atoi(query.Get("w"))
+	opts.Height, _ = strconv.Atoi(query.Get("h"))
+	opts.Quality, _ = strconv.Atoi(query.Get("q"))
+	opts.Crop, _ = strconv.ParseBool(query.Get("crop"))
+	variant, info, err := h.st.WithContext(r.Context()).GetImage(fileID, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.checkAccess(r, userID, info); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	cacheScope := "public"
+	if h.config.PrivateDownload {
+		cacheScope = "private"
+	}
+	etag := fmt.Sprintf("%s-%dx%d-%t-%s-%d", info.FileID, opts.Width, opts.Height, opts.Crop, opts.Format, opts.Quality)
+	w.Header().Set("Content-Type", variant.MimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", strconv.Quote(etag))
+	w.Header().Set("Cache-Control", cacheScope+", max-age="+strconv.Itoa(h.config.CacheMaxAge))
+	http.ServeContent(w, r, "", info.UploadTime, bytes.NewReader(variant.Data))
+}
+
+// checkAccess 按 AccessCheck 校验当前用户能否下载文件，未配置时不校验
+// 上传者本人直接通过，派生文件（如缩略图）按源文件校验
+func (h *Handler) checkAccess(r *http.Request, userID int64, info *storage.FileInfo) error {
+	if h.config.AccessCheck == nil || info.UserID == userID {
+		return nil
+	}
+	if info.SourceID != "" {
+		source, err := h.st.WithContext(r.Context()).GetFileInfo(info.SourceID)
+		if err != nil {
+			return err
+		}
+		info = source
+	}
+	ok, err := h.config.AccessCheck(r.Context(), userID, info)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errcode.New(errcode.Forbidden, requestLang(r))
+	}
+	return nil
+}
+
+// ownedFile 获取文件信息并校验上传者为当前用户
+func (h *Handler) ownedFile(r *http.Request, userID int64) (*storage.FileInfo, error) {
+	fileID, _, _ := filePath(r.URL.Path)
+	info, err := h.st.WithContext(r.Context()).GetFileInfo(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if info.UserID != userID {
+		return nil, errcode.New(errcode.Forbidden, requestLang(r))
+	}
+	return info, nil
+}
+
+// getFileInfo 获取文件信息，仅上传者
+func (h *Handler) getFileInfo(w http.ResponseWriter, r *http.Request, userID int64) {
+	info, err := h.ownedFile(r, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, info)
+}
+
+// keyEnvelopesRequest 保存密钥信封请求
+type keyEnvelopesRequest struct {
+	Envelopes []*storage.KeyEnvelope `json:"envelopes"`
+}
+
+// postFileResource 分发 POST /files/{id}/keys
+func (h *Handler) postFileResource(w http.ResponseWriter, r *http.Request, userID int64) {
+	if _, sub, ok := filePath(r.URL.Path); !ok || sub != "keys" {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	h.addKeyEnvelopes(w, r, userID)
+}
+
+// addKeyEnvelopes 保存加密文件的密钥信封，请求体为 {"envelopes":[...]}，仅上传者
+func (h *Handler) addKeyEnvelopes(w http.ResponseWriter, r *http.Request, userID int64) {
+	info, err := h.ownedFile(r, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req keyEnvelopesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, fmt.Errorf("%w: invalid JSON body", storage.ErrInvalidKeyEnvelope))
+		return
+	}
+	if err := h.st.WithContext(r.Context()).AddKeyEnvelopes(info.FileID, req.Envelopes); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// getKeyEnvelopes 获取当前用户在加密文件上的密钥信封
+func (h *Handler) getKeyEnvelopes(w http.ResponseWriter, r *http.Request, userID int64) {
+	fileID, _, _ := filePath(r.URL.Path)
+	envelopes, err := h.st.WithContext(r.Context()).GetKeyEnvelopes(fileID, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, envelopes)
+}
+
+// deleteFile 删除文件，仅上传者
+func (h *Handler) deleteFile(w http.ResponseWriter, r *http.Request, userID int64) {
+	fileID, sub, ok := filePath(r.URL.Path)
+	if !ok || sub != "" {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	if _, err := h.ownedFile(r, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.st.WithContext(r.Context()).Delete(fileID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// maxBatchFiles 批量接口单次请求的最大文件数
+const maxBatchFiles = 100
+
+// batchRequest 批量操作请求
+type batchRequest struct {
+	FileIDs []string `json:"file_ids"`
+}
+
+// listFiles 分页获取当前用户上传的文件，查询参数 type、page、page_size
+func (h *Handler) listFiles(w http.ResponseWriter, r *http.Request, userID int64) {
+	query := r.URL.Query()
+	filter := &storage.FileFilter{FileType: query.Get("type")}
+	filter.Page, _ = strconv.Atoi(query.Get("page"))
+	filter.PageSize, _ = strconv.Atoi(query.Get("page_size"))
+	list, err := h.st.WithContext(r.Context()).ListFiles(userID, filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, list)
+}
+
+// ownedFiles 解析批量请求并获取其中当前用户上传的文件，按请求顺序返回，不存在或他人的文件跳过，重复的只返回一次
+func (h *Handler) ownedFiles(w http.ResponseWriter, r *http.Request, userID int64) ([]*storage.FileInfo, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.FileIDs) > maxBatchFiles {
+		return nil, errcode.New(errcode.InvalidParam, requestLang(r))
+	}
+	infos, err := h.st.WithContext(r.Context()).GetFileInfos(req.FileIDs)
+	if err != nil {
+		return nil, err
+	}
+	owned := make([]*storage.FileInfo, 0, len(infos))
+	for _, fileID := range req.FileIDs {
+		if info, ok := infos[fileID]; ok && info.UserID == userID {
+			owned = append(owned, info)
+			delete(infos, fileID)
+		}
+	}
+	return owned, nil
+}
+
+// batchFileInfo 批量获取文件信息，请求体为 {"file_ids":[...]}，仅返回当前用户上传的文件
+func (h *Handler) batchFileInfo(w http.ResponseWriter, r *http.Request, userID int64) {
+	owned, err := h.ownedFiles(w, r, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, owned)
+}
+
+// batchDelete 批量删除文件，请求体为 {"file_ids":[...]}，仅删除当前用户上传的文件，返回删除的文件数
+func (h *Handler) batchDelete(w http.ResponseWriter, r *http.Request, userID int64) {
+	owned, err := h.ownedFiles(w, r, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	fileIDs := make([]string, len(owned))
+	for i, info := range owned {
+		fileIDs[i] = info.FileID
+	}
+	deleted, err := h.st.WithContext(r.Context()).DeleteFiles(fileIDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, map[string]int64{"deleted": deleted})
+}
+
+// getUsage 获取当前用户的存储用量
+func (h *Handler) getUsage(w http.ResponseWriter, r *http.Request, userID int64) {
+	usage, err := h.st.WithContext(r.Context()).GetUsage(userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, usage)
+}
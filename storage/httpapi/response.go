@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bbadbeef/go-base/errcode"
+)
+
+// Response 统一的 JSON 响应，成功时 code 为 0
+type Response struct {
+	Code    errcode.Code `json:"code"`
+	Message string       `json:"message"`
+	Data    interface{}  `json:"data,omitempty"`
+}
+
+// requestLang 请求的语言
+func requestLang(r *http.Request) string {
+	return errcode.LangFromHeader(r.Header.Get("Accept-Language"))
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeData 写入成功响应
+func writeData(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJSON(w, http.StatusOK, &Response{
+		Code:    errcode.OK,
+		Message: errcode.Message(errcode.OK, requestLang(r)),
+		Data:    data,
+	})
+}
+
+// writeError 按 errcode 写入错误响应
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := errcode.Resolve(err, requestLang(r))
+	writeJSON(w, apiErr.HTTPStatus, &Response{Code: apiErr.Code, Message: apiErr.Message})
+}
+
+// writeUnauthorized 写入认证失败响应，AuthFunc 返回的错误未绑定错误码时使用 errcode.Unauthorized
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	if errcode.CodeOf(err) == errcode.Internal {
+		apiErr := errcode.New(errcode.Unauthorized, requestLang(r))
+		apiErr.Err = err
+		err = apiErr
+	}
+	writeError(w, r, err)
+}
+
+// writeStatus 写入路由错误（404、405），错误码为对应的通用错误码
+func writeStatus(w http.ResponseWriter, r *http.Request, status int, message string) {
+	code := errcode.NotFound
+	if status != http.StatusNotFound {
+		code = errcode.InvalidParam
+	}
+	writeJSON(w, status, &Response{Code: code, Message: message})
+}
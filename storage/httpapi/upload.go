@@ -0,0 +1,171 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/storage"
+)
+
+// initiateUploadRequest 创建上传任务请求
+type initiateUploadRequest struct {
+	FileName  string `json:"file_name"`
+	MimeType  string `json:"mime_type"` // 可选，为空时完成上传时按文件名和文件头识别
+	Size      int64  `json:"size"`      // 文件总大小（字节）
+	Encrypted bool   `json:"encrypted"` // 内容为客户端加密的密文
+	TTL       int64  `json:"ttl"`       // 保留时长（秒，可选），超过后文件被删除
+}
+
+// uploadPath 解析 /uploads/{id}[/complete]，返回第一个路径段和其后的路径段
+func uploadPath(p string) (id, sub string, ok bool) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(p, "/"), "uploads/")
+	id, sub, _ = strings.Cut(rest, "/")
+	if id == "" || strings.Contains(sub, "/") {
+		return "", "", false
+	}
+	return id, sub, true
+}
+
+// postUploadResource 分发 POST /uploads/{type} 和 POST /uploads/{id}/complete
+func (h *Handler) postUploadResource(w http.ResponseWriter, r *http.Request, userID int64) {
+	_, sub, ok := uploadPath(r.URL.Path)
+	switch {
+	case ok && sub == "":
+		h.initiateUpload(w, r, userID)
+	case ok && sub == "complete":
+		h.completeUpload(w, r, userID)
+	default:
+		writeStatus(w, r, http.StatusNotFound, "not found")
+	}
+}
+
+// initiateUpload 创建上传任务，请求体为 {"file_name":"movie.mp4","mime_type":"video/mp4","size":104857600}
+func (h *Handler) initiateUpload(w http.ResponseWriter, r *http.Request, userID int64) {
+	fileType, _, _ := uploadPath(r.URL.Path)
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+
+	var req initiateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, fmt.Errorf("%w: invalid JSON body", storage.ErrInvalidUploadRequest))
+		return
+	}
+	fileName := path.Base(req.FileName)
+	if fileName == "." || fileName == "/" {
+		fileName = fileType
+	}
+
+	session, err := h.st.WithContext(r.Context()).InitiateUpload(&storage.UploadMeta{
+		FileName:  fileName,
+		MimeType:  clientMimeType(req.MimeType),
+		UserID:    userID,
+		FileType:  fileType,
+		Encrypted: req.Encrypted,
+		TTL:       time.Duration(req.TTL) * time.Second,
+	}, req.Size)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, session)
+}
+
+// ownedUpload 获取上传任务并校验创建者为当前用户
+func (h *Handler) ownedUpload(r *http.Request, userID int64) (*storage.UploadSession, error) {
+	uploadID, _, _ := uploadPath(r.URL.Path)
+	session, err := h.st.WithContext(r.Context()).GetUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, errcode.New(errcode.Forbidden, requestLang(r))
+	}
+	return session, nil
+}
+
+// getUpload 获取上传任务，客户端中断后据 received 续传
+func (h *Handler) getUpload(w http.ResponseWriter, r *http.Request, userID int64) {
+	if _, sub, ok := uploadPath(r.URL.Path); !ok || sub != "" {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	session, err := h.ownedUpload(r, userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, session)
+}
+
+// uploadPart 上传分片，请求体为原始内容，?offset= 为分片在文件中的起始位置
+// 分片大小受 MaxUploadBytes 限制，未完整接收的分片整体丢弃，需从 received 处重传
+func (h *Handler) uploadPart(w http.ResponseWriter, r *http.Request, userID int64) {
+	uploadID, sub, ok := uploadPath(r.URL.Path)
+	if !ok || sub != "" {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, r, fmt.Errorf("%w: invalid offset", storage.ErrInvalidUploadRequest))
+		return
+	}
+	if _, err := h.ownedUpload(r, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := limitBody(w, r, h.config.MaxUploadBytes); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	session, err := h.st.WithContext(r.Context()).UploadPart(uploadID, offset, r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = h.uploadError(err)
+		}
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, session)
+}
+
+// completeUpload 完成上传，返回文件信息
+func (h *Handler) completeUpload(w http.ResponseWriter, r *http.Request, userID int64) {
+	uploadID, _, _ := uploadPath(r.URL.Path)
+	if _, err := h.ownedUpload(r, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	info, err := h.st.WithContext(r.Context()).CompleteUpload(uploadID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, info)
+}
+
+// abortUpload 取消上传任务
+func (h *Handler) abortUpload(w http.ResponseWriter, r *http.Request, userID int64) {
+	uploadID, sub, ok := uploadPath(r.URL.Path)
+	if !ok || sub != "" {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	if _, err := h.ownedUpload(r, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.st.WithContext(r.Context()).AbortUpload(uploadID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// MaxImageDimension 动态处理图片时输出的最大宽度、高度（像素）
+const MaxImageDimension = 4096
+
+// defaultImageCacheBytes 图片变体缓存的默认容量
+const defaultImageCacheBytes = 64 << 20
+
+// 动态处理图片的输出格式
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+)
+
+// ImageOptions 动态处理图片的选项
+// 只缩小不放大；Width、Height 均为 0 时保持原尺寸，只转换格式
+type ImageOptions struct {
+	Width   int    // 最大宽度（像素），0 表示按高度等比缩放
+	Height  int    // 最大高度（像素），0 表示按宽度等比缩放
+	Crop    bool   // 居中裁剪为 Width:Height 的比例后缩放，需同时设置 Width、Height，如方形头像
+	Format  string // 输出格式，ImageFormatJPEG 或 ImageFormatPNG，为空时 PNG、GIF 输出 PNG，其他输出 JPEG
+	Quality int    // JPEG 质量（1-100），默认 80
+}
+
+// ImageVariant 处理后的图片，Data 可能来自缓存并被多个调用方共享，不可修改
+type ImageVariant struct {
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// normalize 校验选项并填充默认值，sourceMime 为原图的 MIME 类型
+func (o ImageOptions) normalize(sourceMime string) (ImageOptions, error) {
+	if o.Width < 0 || o.Height < 0 || o.Width > MaxImageDimension || o.Height > MaxImageDimension {
+		return o, fmt.Errorf("%w: size must be within %dx%d", ErrInvalidImageOptions, MaxImageDimension, MaxImageDimension)
+	}
+	if o.Crop && (o.Width == 0 || o.Height == 0) {
+		return o, fmt.Errorf("%w: crop requires both width and height", ErrInvalidImageOptions)
+	}
+	switch strings.ToLower(o.Format) {
+	case "":
+		o.Format = ImageFormatJPEG
+		if family := mimeFamily(sourceMime); family == "image/png" || family == "image/gif" {
+			o.Format = ImageFormatPNG
+		}
+	case "jpeg", "jpg":
+		o.Format = ImageFormatJPEG
+	case "png":
+		o.Format = ImageFormatPNG
+	default:
+		return o, fmt.Errorf("%w: unsupported format %q", ErrInvalidImageOptions, o.Format)
+	}
+	if o.Format != ImageFormatJPEG || o.Quality == 0 {
+		o.Quality = 80
+	}
+	if o.Quality < 1 || o.Quality > 100 {
+		return o, fmt.Errorf("%w: quality must be between 1 and 100", ErrInvalidImageOptions)
+	}
+	return o, nil
+}
+
+// cacheKey 图片变体的缓存键
+func (o ImageOptions) cacheKey(fileID string) string {
+	return fmt.Sprintf("%s/%dx%d/%t/%s/%d", fileID, o.Width, o.Height, o.Crop, o.Format, o.Quality)
+}
+
+// GetImage 按需缩放、裁剪、转换格式后返回图片，结果缓存在内存中
+// 文件需为正常状态的图片，HEIC 等无法解码的格式返回 ErrUnsupportedMimeType
+func (s *dbStorage) GetImage(fileID string, opts *ImageOptions) (*ImageVariant, *FileInfo, error) {
+	var dbFile DBFile
+	if err := s.files().Omit("file_data").Where("file_id = ? AND status = 1", fileID).First(&dbFile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrFileNotFound
+		}
+		return nil, nil, err
+	}
+	if dbFile.FileType != FileTypeImage || dbFile.Encrypted {
+		return nil, nil, fmt.Errorf("%w: %s is not a decodable image", ErrUnsupportedMimeType, dbFile.MimeType)
+	}
+
+	var o ImageOptions
+	if opts != nil {
+		o = *opts
+	}
+	o, err := o.normalize(dbFile.MimeType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 文件内容不会变化，删除后查询不到记录，缓存无需失效
+	key := o.cacheKey(dbFile.FileID)
+	if variant, ok := s.images.get(key); ok {
+		return variant, s.toFileInfo(&dbFile), nil
+	}
+
+	src, err := s.decodeImage(&dbFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	variant, err := processImage(src, &o)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.images.add(key, variant)
+	return variant, s.toFileInfo(&dbFile), nil
+}
+
+// processImage 按选项裁剪、缩放并编码图片
+func processImage(src image.Image, o *ImageOptions) (*ImageVariant, error) {
+	r := src.Bounds()
+	if o.Crop {
+		r = cropRect(r, o.Width, o.Height)
+	}
+	tw, th := fitSize(r.Dx(), r.Dy(), o.Width, o.Height)
+
+	var buf bytes.Buffer
+	variant := &ImageVariant{Width: tw, Height: th}
+	switch o.Format {
+	case ImageFormatPNG:
+		if err := png.Encode(&buf, scaleImage(src, r, tw, th, false)); err != nil {
+			return nil, fmt.Errorf("encode image failed: %w", err)
+		}
+		variant.MimeType = "image/png"
+	default:
+		if err := jpeg.Encode(&buf, scaleImage(src, r, tw, th, true), &jpeg.Options{Quality: o.Quality}); err != nil {
+			return nil, fmt.Errorf("encode image failed: %w", err)
+		}
+		variant.MimeType = "image/jpeg"
+	}
+	variant.Data = buf.Bytes()
+	return variant, nil
+}
+
+// cropRect 返回 r 中居中、宽高比为 width:height 的最大区域
+func cropRect(r image.Rectangle, width, height int) image.Rectangle {
+	w, h := r.Dx(), r.Dy()
+	cw, ch := w, h
+	if w*height > h*width {
+		cw = max(h*width/height, 1)
+	} else {
+		ch = max(w*height/width, 1)
+	}
+	x0, y0 := r.Min.X+(w-cw)/2, r.Min.Y+(h-ch)/2
+	return image.Rect(x0, y0, x0+cw, y0+ch)
+}
+
+// fitSize 等比缩小到 maxWidth x maxHeight 以内，不放大，0 表示该方向不限制
+func fitSize(w, h, maxWidth, maxHeight int) (int, int) {
+	tw, th := w, h
+	if maxWidth > 0 && tw > maxWidth {
+		tw, th = maxWidth, h*maxWidth/w
+	}
+	if maxHeight > 0 && th > maxHeight {
+		tw, th = w*maxHeight/h, maxHeight
+	}
+	return max(tw, 1), max(th, 1)
+}
+
+// scaleImage 将 src 的 r 区域按区域平均缩放为 tw x th
+// opaque 为 true 时透明像素合成到白色背景上，便于编码为 JPEG，否则保留透明度
+func scaleImage(src image.Image, r image.Rectangle, tw, th int, opaque bool) *image.RGBA {
+	w, h := r.Dx(), r.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		sy0, sy1 := r.Min.Y+y*h/th, r.Min.Y+(y+1)*h/th
+		sy1 = max(sy1, sy0+1)
+		for x := 0; x < tw; x++ {
+			sx0, sx1 := r.Min.X+x*w/tw, r.Min.X+(x+1)*w/tw
+			sx1 = max(sx1, sx0+1)
+
+			var cr, cg, cb, ca, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					cr, cg, cb, ca = cr+uint64(pr), cg+uint64(pg), cb+uint64(pb), ca+uint64(pa)
+					n++
+				}
+			}
+			// RGBA() 返回预乘 alpha 的 16 位分量，与 image.RGBA 的存储方式一致
+			// 合成白色背景：c + (1 - alpha)
+			var bg uint64
+			if opaque {
+				bg, ca = 0xffff*n-ca, 0xffff*n
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8((cr + bg) / n >> 8)
+			dst.Pix[i+1] = uint8((cg + bg) / n >> 8)
+			dst.Pix[i+2] = uint8((cb + bg) / n >> 8)
+			dst.Pix[i+3] = uint8(ca / n >> 8)
+		}
+	}
+	return dst
+}
+
+// imageCache 图片变体的 LRU 缓存，按数据字节数限制容量，并发安全
+// nil 表示不缓存
+type imageCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	ll       *list.List // 最近使用的在前
+	items    map[string]*list.Element
+}
+
+// imageCacheEntry 缓存项
+type imageCacheEntry struct {
+	key     string
+	variant *ImageVariant
+}
+
+// newImageCache 创建图片变体缓存，maxBytes 为 0 时使用默认容量，小于 0 时不缓存
+func newImageCache(maxBytes int64) *imageCache {
+	if maxBytes < 0 {
+		return nil
+	}
+	if maxBytes == 0 {
+		maxBytes = defaultImageCacheBytes
+	}
+	return &imageCache{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get 获取缓存的图片变体
+func (c *imageCache) get(key string) (*ImageVariant, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*imageCacheEntry).variant, true
+}
+
+// add 缓存图片变体，超过容量时淘汰最久未使用的，大于总容量的不缓存
+func (c *imageCache) add(key string, variant *ImageVariant) {
+	size := int64(len(variant.Data))
+	if c == nil || size > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&imageCacheEntry{key: key, variant: variant})
+	c.bytes += size
+	for c.bytes > c.maxBytes {
+		e := c.ll.Back()
+		entry := e.Value.(*imageCacheEntry)
+		c.ll.Remove(e)
+		delete(c.items, entry.key)
+		c.bytes -= int64(len(entry.variant.Data))
+	}
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+)
+
+// fillMediaInfo 按已写入的内容填充宽高、时长：图片解码文件头，语音和视频使用 MediaProber
+// 识别失败不影响上传，相应字段保持为 0
+func (s *dbStorage) fillMediaInfo(dbFile *DBFile) {
+	if dbFile.Encrypted {
+		return
+	}
+	switch dbFile.FileType {
+	case FileTypeImage:
+		s.fillDimensions(dbFile)
+	case FileTypeVoice, FileTypeVideo:
+		s.probeMedia(dbFile)
+	}
+}
+
+// fillDimensions 按图片文件头填充宽高，无法识别的格式保持为 0
+func (s *dbStorage) fillDimensions(dbFile *DBFile) {
+	rc, err := s.openContent(dbFile)
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+	if cfg, _, err := image.DecodeConfig(rc); err == nil {
+		dbFile.Width, dbFile.Height = cfg.Width, cfg.Height
+	}
+}
+
+// stripImageMetadata 返回去除 EXIF、GPS 等元数据后的图片内容，支持 JPEG、PNG，其他格式原样返回
+// 调用方需关闭返回的 ReadCloser，提前停止读取时释放后台的处理协程
+func stripImageMetadata(mimeType string, r io.Reader) io.ReadCloser {
+	var strip func(w io.Writer, r *bufio.Reader) error
+	switch mimeType {
+	case "image/jpeg", "image/jpg":
+		strip = stripJPEG
+	case "image/png":
+		strip = stripPNG
+	default:
+		return io.NopCloser(r)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(strip(pw, bufio.NewReader(r)))
+	}()
+	return pr
+}
+
+// stripJPEG 去除 JPEG 的 APP1（EXIF、XMP）、APP13（IPTC）段和注释，EXIF 中的方向信息保留为最小的 EXIF 段
+// 不是合法 JPEG 时原样输出
+func stripJPEG(w io.Writer, r *bufio.Reader) error {
+	soi, err := r.Peek(2)
+	if err != nil || soi[0] != 0xFF || soi[1] != 0xD8 {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	r.Discard(2)
+	if _, err := w.Write([]byte{0xFF, 0xD8}); err != nil {
+		return err
+	}
+
+	for {
+		head, err := r.Peek(2)
+		if err != nil || head[0] != 0xFF {
+			// 结构异常，剩余内容原样输出
+			_, err := io.Copy(w, r)
+			return err
+		}
+		marker := head[1]
+		switch {
+		case marker == 0xFF:
+			// 填充字节
+			r.Discard(1)
+			continue
+		case marker == 0x01 || marker >= 0xD0 && marker <= 0xD8:
+			// 无长度的独立标记
+			r.Discard(2)
+			if _, err := w.Write([]byte{0xFF, marker}); err != nil {
+				return err
+			}
+			continue
+		case marker == 0xD9 || marker == 0xDA:
+			// EOI 或 SOS，之后为图像数据
+			_, err := io.Copy(w, r)
+			return err
+		}
+
+		var seg [4]byte
+		if _, err := io.ReadFull(r, seg[:]); err != nil {
+			return err
+		}
+		length := int64(binary.BigEndian.Uint16(seg[2:]))
+		if length < 2 {
+			w.Write(seg[:])
+			_, err := io.Copy(w, r)
+			return err
+		}
+
+		switch marker {
+		case 0xE1: // APP1：EXIF、XMP
+			data := make([]byte, length-2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+			if exif, ok := bytes.CutPrefix(data, []byte("Exif\x00\x00")); ok {
+				if o := exifOrientation(exif); o > 1 {
+					if _, err := w.Write(orientationEXIF(o)); err != nil {
+						return err
+					}
+				}
+			}
+		case 0xED, 0xFE: // APP13：IPTC；COM：注释
+			if _, err := r.Discard(int(length - 2)); err != nil {
+				return err
+			}
+		default:
+			if _, err := w.Write(seg[:]); err != nil {
+				return err
+			}
+			if _, err := io.CopyN(w, r, length-2); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// exifOrientation 读取 EXIF（TIFF 结构）IFD0 中的方向标签，不存在时返回 0
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int64(order.Uint32(tiff[4:]))
+	if ifd+2 > int64(len(tiff)) {
+		return 0
+	}
+	count := int64(order.Uint16(tiff[ifd:]))
+	for i := int64(0); i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > int64(len(tiff)) {
+			return 0
+		}
+		// 方向标签 0x0112，类型 SHORT
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// orientationEXIF 只包含方向标签的 APP1 段
+func orientationEXIF(orientation int) []byte {
+	seg := []byte{
+		0xFF, 0xE1, 0x00, 0x22, // APP1，长度 34
+		'E', 'x', 'i', 'f', 0x00, 0x00,
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, // 大端 TIFF 头，IFD0 偏移 8
+		0x00, 0x01, // 1 个标签
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, // 方向，SHORT，1 个值
+		0x00, 0x00, 0x00, 0x00, // 无下一个 IFD
+	}
+	binary.BigEndian.PutUint16(seg[28:], uint16(orientation))
+	return seg
+}
+
+// pngMetadataChunks 去除的 PNG 元数据块
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNG 去除 PNG 的 EXIF、文本（含 XMP）和时间块，不是合法 PNG 时原样输出
+func stripPNG(w io.Writer, r *bufio.Reader) error {
+	sig, err := r.Peek(8)
+	if err != nil || string(sig) != "\x89PNG\r\n\x1a\n" {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	if _, err := io.CopyN(w, r, 8); err != nil {
+		return err
+	}
+
+	for {
+		var head [8]byte // 长度、类型
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		length := int64(binary.BigEndian.Uint32(head[:4])) + 4 // 含 CRC
+		typ := string(head[4:])
+		if pngMetadataChunks[typ] {
+			if _, err := io.CopyN(io.Discard, r, length); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := w.Write(head[:]); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(w, r, length); err != nil {
+			return err
+		}
+		if typ == "IEND" {
+			_, err := io.Copy(w, r)
+			return err
+		}
+	}
+}
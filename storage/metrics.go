@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"time"
+
+	golog "github.com/bbadbeef/go-base/log"
+	"github.com/bbadbeef/go-base/observability"
+)
+
+// defaultSlowThreshold 默认的慢操作阈值
+const defaultSlowThreshold = 500 * time.Millisecond
+
+// 监控的操作
+const (
+	OpUpload   = "upload"    // 上传（Upload、UploadStream、CompleteUpload），断点续传只计完成上传的处理
+	OpDownload = "download"  // 下载（Download、DownloadStream），耗时为查询记录并打开内容，不含传输
+	OpBlobRead = "blob_read" // 从数据库读取一块内容（分块表的一行或 file_data），对象存储的读取不计入
+)
+
+// SlowOperation 耗时超过阈值的操作
+type SlowOperation struct {
+	Op       string
+	FileID   string
+	FileType string // blob_read 为空
+	Size     int64
+	Duration time.Duration
+	Err      error
+}
+
+// SlowLogFunc 记录慢操作
+type SlowLogFunc func(op *SlowOperation)
+
+// logSlowOperation 默认的慢操作日志，以警告级别写入 Logger
+func (s *dbStorage) logSlowOperation(op *SlowOperation) {
+	golog.WithFields(golog.FromContext(s.logger, s.ctx), golog.Fields{
+		"op":        op.Op,
+		"file_id":   op.FileID,
+		"file_type": op.FileType,
+		"size":      op.Size,
+		"duration":  op.Duration,
+		"err":       op.Err,
+	}).Warn("storage: slow operation")
+}
+
+// observe 记录操作的指标，耗时超过阈值时记录慢操作日志：
+//
+//	storage_operations_total{op, file_type, result}  操作次数，result 为 ok 或 error
+//	storage_operation_bytes_total{op, file_type}     成功操作的内容字节数
+//	storage_operation_duration_seconds{op}           操作耗时
+func (s *dbStorage) observe(op, fileID, fileType string, size int64, start time.Time, err error) {
+	d := time.Since(start)
+	if err != nil {
+		size = 0
+	}
+	s.metrics.Add("storage_operations_total", 1,
+		observability.L("op", op), observability.L("file_type", fileType), observability.L("result", observability.Result(err)))
+	if size > 0 {
+		s.metrics.Add("storage_operation_bytes_total", float64(size), observability.L("op", op), observability.L("file_type", fileType))
+	}
+	s.metrics.Observe("storage_operation_duration_seconds", d.Seconds(), observability.L("op", op))
+	if s.slowThreshold > 0 && d >= s.slowThreshold {
+		s.slowLog(&SlowOperation{Op: op, FileID: fileID, FileType: fileType, Size: size, Duration: d, Err: err})
+	}
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/migrate"
+)
+
+// migrationTable 迁移记录默认表名
+const migrationTable = "storage_schema_migrations"
+
+// MigrationStatus 数据库迁移状态
+type MigrationStatus = migrate.Status
+
+// migration 单个迁移
+// 已发布的迁移不可修改，表结构变更需追加新版本
+type migration struct {
+	version int64
+	name    string
+	up      func(s *dbStorage) error
+}
+
+// migrations 存储模块的全部数据库迁移（按版本号递增）
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "init",
+		up: func(s *dbStorage) error {
+			err := s.fileTableDB().AutoMigrate(&DBFile{})
+			// 忽略DROP不存在的索引/外键错误（GORM迁移的已知问题）
+			if err != nil && (strings.Contains(err.Error(), "Can't DROP") ||
+				strings.Contains(err.Error(), "check that column/key exists")) {
+				return nil
+			}
+			return err
+		},
+	},
+	{
+		version: 2,
+		name:    "tenant_id",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			if migrator.HasColumn(&DBFile{}, "TenantID") {
+				return nil
+			}
+			return migrator.AddColumn(&DBFile{}, "TenantID")
+		},
+	},
+	{
+		version: 3,
+		name:    "object_key",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			if migrator.HasColumn(&DBFile{}, "ObjectKey") {
+				return nil
+			}
+			return migrator.AddColumn(&DBFile{}, "ObjectKey")
+		},
+	},
+	{
+		version: 4,
+		name:    "file_chunks",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			if !migrator.HasColumn(&DBFile{}, "ChunkSize") {
+				if err := migrator.AddColumn(&DBFile{}, "ChunkSize"); err != nil {
+					return err
+				}
+			}
+			return s.chunks().AutoMigrate(&DBFileChunk{})
+		},
+	},
+	{
+		version: 5,
+		name:    "uploads",
+		up: func(s *dbStorage) error {
+			return s.uploadTableDB().AutoMigrate(&DBUpload{})
+		},
+	},
+	{
+		version: 6,
+		name:    "thumbnail_id",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			if migrator.HasColumn(&DBFile{}, "ThumbnailID") {
+				return nil
+			}
+			return migrator.AddColumn(&DBFile{}, "ThumbnailID")
+		},
+	},
+	{
+		version: 7,
+		name:    "blobs",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			for _, field := range []string{"ContentHash", "ContentID"} {
+				if !migrator.HasColumn(&DBFile{}, field) {
+					if err := migrator.AddColumn(&DBFile{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return s.blobTableDB().AutoMigrate(&DBBlob{})
+		},
+	},
+	{
+		version: 8,
+		name:    "source_id",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			if !migrator.HasColumn(&DBFile{}, "SourceID") {
+				if err := migrator.AddColumn(&DBFile{}, "SourceID"); err != nil {
+					return err
+				}
+			}
+			// 已有缩略图关联到源文件
+			var sources []DBFile
+			return s.fileTableDB().Select("id, file_id, thumbnail_id").Where("thumbnail_id <> ''").
+				FindInBatches(&sources, 500, func(tx *gorm.DB, batch int) error {
+					for _, source := range sources {
+						if err := s.fileTableDB().Where("file_id = ?", source.ThumbnailID).
+							Update("source_id", source.FileID).Error; err != nil {
+							return err
+						}
+					}
+					return nil
+				}).Error
+		},
+	},
+	{
+		version: 9,
+		name:    "deleted_at",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			if migrator.HasColumn(&DBFile{}, "DeletedAt") {
+				return nil
+			}
+			return migrator.AddColumn(&DBFile{}, "DeletedAt")
+		},
+	},
+	{
+		version: 10,
+		name:    "encryption",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			if !migrator.HasColumn(&DBFile{}, "Encrypted") {
+				if err := migrator.AddColumn(&DBFile{}, "Encrypted"); err != nil {
+					return err
+				}
+			}
+			migrator = s.uploadTableDB().Migrator()
+			if !migrator.HasColumn(&DBUpload{}, "Encrypted") {
+				if err := migrator.AddColumn(&DBUpload{}, "Encrypted"); err != nil {
+					return err
+				}
+			}
+			return s.fileKeyTableDB().AutoMigrate(&DBFileKey{})
+		},
+	},
+	{
+		version: 11,
+		name:    "expires_at",
+		up: func(s *dbStorage) error {
+			migrator := s.fileTableDB().Migrator()
+			if !migrator.HasColumn(&DBFile{}, "ExpiresAt") {
+				if err := migrator.AddColumn(&DBFile{}, "ExpiresAt"); err != nil {
+					return err
+				}
+			}
+			if !migrator.HasIndex(&DBFile{}, "idx_file_expires") {
+				if err := migrator.CreateIndex(&DBFile{}, "idx_file_expires"); err != nil {
+					return err
+				}
+			}
+			migrator = s.uploadTableDB().Migrator()
+			if migrator.HasColumn(&DBUpload{}, "FileTTL") {
+				return nil
+			}
+			return migrator.AddColumn(&DBUpload{}, "FileTTL")
+		},
+	},
+}
+
+// newMigrator 创建存储模块的迁移执行器，迁移在 s 的表上执行
+func newMigrator(s *dbStorage, table string) *migrate.Migrator {
+	list := make([]migrate.Migration, len(migrations))
+	for i, m := range migrations {
+		up := m.up
+		list[i] = migrate.Migration{
+			Version: m.version,
+			Name:    m.name,
+			Up:      func(context.Context, *gorm.DB) error { return up(s) },
+		}
+	}
+	return migrate.New(s.db, table, list)
+}
+
+// Migrate 按版本顺序执行所有未执行的数据库迁移
+func (s *dbStorage) Migrate() error {
+	return s.migrator.Migrate(s.ctx)
+}
+
+// MigrationStatus 获取所有数据库迁移的执行状态
+func (s *dbStorage) MigrationStatus() ([]*MigrationStatus, error) {
+	return s.migrator.Status(s.ctx)
+}
@@ -6,21 +6,101 @@ import (
 
 // DBFile 文件数据库模型
 type DBFile struct {
-	ID        int64     `gorm:"primaryKey;autoIncrement"`
-	FileID    string    `gorm:"type:varchar(64);uniqueIndex:uk_file_id;not null"`
-	UserID    int64     `gorm:"index:idx_user;not null"`
-	FileName  string    `gorm:"type:varchar(255);not null"`
-	FileType  string    `gorm:"type:varchar(50);not null;index:idx_type"`
-	MimeType  string    `gorm:"type:varchar(100);not null"`
-	FileSize  int64     `gorm:"not null"`
-	FileData  []byte    `gorm:"type:mediumblob;not null"` // 最大 16MB
-	Width     int       `gorm:"type:int;default:0"`
-	Height    int       `gorm:"type:int;default:0"`
-	Duration  int       `gorm:"type:int;default:0"`
-	Status    int       `gorm:"type:tinyint;default:1;index:idx_status"` // 1:正常 2:已删除
-	CreatedAt time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP;index:idx_created"`
+	ID          int64      `gorm:"primaryKey;autoIncrement"`
+	TenantID    string     `gorm:"type:varchar(64);not null;default:''"`
+	FileID      string     `gorm:"type:varchar(64);uniqueIndex:uk_file_id;not null"`
+	UserID      int64      `gorm:"index:idx_user;not null"`
+	FileName    string     `gorm:"type:varchar(255);not null"`
+	FileType    string     `gorm:"type:varchar(50);not null;index:idx_type"`
+	MimeType    string     `gorm:"type:varchar(100);not null"`
+	FileSize    int64      `gorm:"not null"`
+	FileData    []byte     `gorm:"type:mediumblob;not null"`              // 最大 16MB，分块存储或使用对象存储时为空
+	ObjectKey   string     `gorm:"type:varchar(255);not null;default:''"` // 对象存储中的键，为空表示内容存于数据库
+	ChunkSize   int        `gorm:"type:int;not null;default:0"`           // 分块大小，大于 0 表示内容分块存于分块表
+	ThumbnailID string     `gorm:"type:varchar(64);not null;default:''"`  // 缩略图文件ID，为空表示没有缩略图
+	ContentHash string     `gorm:"type:varchar(64);not null;default:''"`  // 内容 SHA-256，为空表示未参与去重
+	ContentID   string     `gorm:"type:varchar(64);not null;default:''"`  // 内容所在分块的文件ID，为空表示内容属于本文件
+	SourceID    string     `gorm:"type:varchar(64);not null;default:''"`  // 派生文件（如缩略图）的源文件ID
+	Encrypted   bool       `gorm:"not null;default:false"`                // 客户端加密，内容为密文
+	Width       int        `gorm:"type:int;default:0"`
+	Height      int        `gorm:"type:int;default:0"`
+	Duration    int        `gorm:"type:int;default:0"`
+	Status      int        `gorm:"type:tinyint;default:1;index:idx_status"` // 1:正常 2:已删除 3:待扫描 4:已隔离
+	CreatedAt   time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP;index:idx_created"`
+	DeletedAt   *time.Time `gorm:"type:timestamp NULL"`                        // 删除或隔离时间，垃圾回收按此判断保留期
+	ExpiresAt   *time.Time `gorm:"type:timestamp NULL;index:idx_file_expires"` // 过期时间，为空表示永久保留，过期后由垃圾回收删除
 }
 
 func (DBFile) TableName() string {
 	return "storage_files"
 }
+
+// DBFileChunk 文件内容分块数据库模型
+type DBFileChunk struct {
+	ID     int64  `gorm:"primaryKey;autoIncrement"`
+	FileID string `gorm:"type:varchar(64);uniqueIndex:uk_file_seq;not null"`
+	Seq    int    `gorm:"uniqueIndex:uk_file_seq;not null"` // 分块序号，从 0 开始
+	Data   []byte `gorm:"type:mediumblob;not null"`
+}
+
+func (DBFileChunk) TableName() string {
+	return "storage_file_chunks"
+}
+
+// DBBlob 去重的文件内容，相同 SHA-256 的文件共用一份内容，按引用数释放
+type DBBlob struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	TenantID  string `gorm:"type:varchar(64);uniqueIndex:uk_blob_hash;not null;default:''"`
+	Hash      string `gorm:"type:varchar(64);uniqueIndex:uk_blob_hash;not null"` // SHA-256
+	Size      int64  `gorm:"not null"`
+	ContentID string `gorm:"type:varchar(64);not null;default:''"`  // 内容所在分块的文件ID
+	ObjectKey string `gorm:"type:varchar(255);not null;default:''"` // 对象存储中的键，为空表示内容存于分块表
+	ChunkSize int    `gorm:"type:int;not null;default:0"`
+	RefCount  int64  `gorm:"not null;default:0"` // 引用数，为 0 时内容被删除
+	CreatedAt time.Time
+}
+
+func (DBBlob) TableName() string {
+	return "storage_blobs"
+}
+
+// DBUpload 断点续传上传任务数据库模型
+// 已接收的内容按 ChunkSize 分块暂存于分块表，以 FileID 关联，完成后直接作为文件内容或转存到对象存储
+type DBUpload struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	TenantID  string    `gorm:"type:varchar(64);not null;default:''"`
+	UploadID  string    `gorm:"type:varchar(64);uniqueIndex:uk_upload_id;not null"`
+	FileID    string    `gorm:"type:varchar(64);not null"`
+	UserID    int64     `gorm:"not null"`
+	FileName  string    `gorm:"type:varchar(255);not null"`
+	FileType  string    `gorm:"type:varchar(50);not null"`
+	MimeType  string    `gorm:"type:varchar(100);not null;default:''"`
+	Encrypted bool      `gorm:"not null;default:false"`
+	FileTTL   int64     `gorm:"not null;default:0"` // 文件的保留时长（秒），0 表示按保留策略
+	TotalSize int64     `gorm:"not null"`
+	Received  int64     `gorm:"not null;default:0"`
+	ChunkSize int       `gorm:"type:int;not null"`
+	ExpiresAt time.Time `gorm:"index:idx_expires;not null"`
+	CreatedAt time.Time
+}
+
+func (DBUpload) TableName() string {
+	return "storage_uploads"
+}
+
+// DBFileKey 加密文件的密钥信封，每个接收者（设备）一条
+type DBFileKey struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement"`
+	TenantID   string `gorm:"type:varchar(64);not null;default:''"`
+	FileID     string `gorm:"type:varchar(64);uniqueIndex:uk_file_recipient;not null"`
+	UserID     int64  `gorm:"uniqueIndex:uk_file_recipient;not null"`
+	DeviceID   string `gorm:"type:varchar(64);uniqueIndex:uk_file_recipient;not null;default:''"`
+	KeyID      string `gorm:"type:varchar(128);not null;default:''"` // 包装所用的接收者公钥ID
+	Algorithm  string `gorm:"type:varchar(64);not null;default:''"`
+	WrappedKey []byte `gorm:"type:blob;not null"`
+	CreatedAt  time.Time
+}
+
+func (DBFileKey) TableName() string {
+	return "storage_file_keys"
+}
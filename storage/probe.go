@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaInfo 音视频信息
+type MediaInfo struct {
+	Duration time.Duration // 时长
+	Width    int           // 视频宽度（像素），语音为 0
+	Height   int           // 视频高度（像素），语音为 0
+}
+
+// MediaProber 音视频信息探测，path 为本地临时文件路径
+type MediaProber interface {
+	Probe(ctx context.Context, path string) (*MediaInfo, error)
+}
+
+// MediaProberFunc 函数形式的 MediaProber
+type MediaProberFunc func(ctx context.Context, path string) (*MediaInfo, error)
+
+// Probe 探测音视频信息
+func (f MediaProberFunc) Probe(ctx context.Context, path string) (*MediaInfo, error) {
+	return f(ctx, path)
+}
+
+// FFprobe 使用 ffprobe 探测时长和视频宽高
+// ffprobePath 为 ffprobe 可执行文件路径，为空时从 PATH 查找
+func FFprobe(ffprobePath string) MediaProber {
+	if ffprobePath == "" {
+		ffprobePath = "ffprobe"
+	}
+	return MediaProberFunc(func(ctx context.Context, path string) (*MediaInfo, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, ffprobePath, "-v", "error", "-of", "json",
+			"-show_entries", "format=duration:stream=codec_type,width,height", path)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("ffprobe failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+
+		var out struct {
+			Streams []struct {
+				CodecType string `json:"codec_type"`
+				Width     int    `json:"width"`
+				Height    int    `json:"height"`
+			} `json:"streams"`
+			Format struct {
+				Duration string `json:"duration"`
+			} `json:"format"`
+		}
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			return nil, fmt.Errorf("parse ffprobe output failed: %w", err)
+		}
+
+		info := &MediaInfo{}
+		if seconds, err := strconv.ParseFloat(out.Format.Duration, 64); err == nil {
+			info.Duration = time.Duration(seconds * float64(time.Second))
+		}
+		for _, stream := range out.Streams {
+			if stream.CodecType == "video" {
+				info.Width, info.Height = stream.Width, stream.Height
+				break
+			}
+		}
+		return info, nil
+	})
+}
+
+// probeMedia 探测语音和视频的时长、宽高，未配置 MediaProber 或探测失败时跳过
+func (s *dbStorage) probeMedia(dbFile *DBFile) {
+	if s.prober == nil {
+		return
+	}
+	var info *MediaInfo
+	err := s.withLocalFile(dbFile, func(path string) (err error) {
+		info, err = s.prober.Probe(s.ctx, path)
+		return err
+	})
+	if err != nil || info == nil {
+		return
+	}
+
+	// 按秒取整，不足 1 秒的记为 1 秒，便于客户端显示语音时长
+	if info.Duration > 0 {
+		dbFile.Duration = max(int(math.Round(info.Duration.Seconds())), 1)
+	}
+	if dbFile.Width == 0 && dbFile.Height == 0 {
+		dbFile.Width, dbFile.Height = info.Width, info.Height
+	}
+}
+
+// withLocalFile 将文件内容写入本地临时文件后调用 fn，用于需要文件路径的外部工具
+func (s *dbStorage) withLocalFile(dbFile *DBFile, fn func(path string) error) error {
+	rc, err := s.openContent(dbFile)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "storage-media-*"+filepath.Ext(dbFile.FileName))
+	if err != nil {
+		return fmt.Errorf("create temp file failed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, rc); err != nil {
+		return fmt.Errorf("read file failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return fn(tmp.Name())
+}
@@ -0,0 +1,57 @@
+package storage
+
+// QuotaFunc 返回用户的存储配额（字节），0 表示不限制
+type QuotaFunc func(userID int64) int64
+
+// Usage 用户存储用量，按文件大小统计，去重的文件同样计入
+type Usage struct {
+	UserID int64            `json:"user_id"`
+	Total  int64            `json:"total"`           // 已用字节数
+	ByType map[string]int64 `json:"by_type"`         // 按文件类型统计的已用字节数
+	Quota  int64            `json:"quota,omitempty"` // 配额（字节），0 表示不限制
+}
+
+// GetUsage 获取用户的存储用量
+func (s *dbStorage) GetUsage(userID int64) (*Usage, error) {
+	var rows []struct {
+		FileType string
+		Size     int64
+	}
+	if err := s.files().Model(&DBFile{}).Select("file_type, SUM(file_size) AS size").
+		Where("user_id = ? AND status IN (1, 3)", userID).Group("file_type").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{UserID: userID, ByType: make(map[string]int64), Quota: s.quotaOf(userID)}
+	for _, row := range rows {
+		usage.ByType[row.FileType] = row.Size
+		usage.Total += row.Size
+	}
+	return usage, nil
+}
+
+// quotaOf 用户的存储配额，0 表示不限制
+func (s *dbStorage) quotaOf(userID int64) int64 {
+	if s.quotaFunc != nil {
+		return s.quotaFunc(userID)
+	}
+	return s.quota
+}
+
+// checkQuota 校验用户新增 size 字节后不超过配额，未配置配额时不查询
+func (s *dbStorage) checkQuota(userID, size int64) error {
+	quota := s.quotaOf(userID)
+	if quota <= 0 {
+		return nil
+	}
+
+	var used int64
+	if err := s.files().Model(&DBFile{}).Select("COALESCE(SUM(file_size), 0)").
+		Where("user_id = ? AND status IN (1, 3)", userID).Scan(&used).Error; err != nil {
+		return err
+	}
+	if used+size > quota {
+		return &QuotaExceededError{Used: used, Size: size, Quota: quota}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// retentionPolicy 按文件类型的保留时长，运行时可修改，WithContext 返回的实例共享同一策略
+type retentionPolicy struct {
+	mu  sync.RWMutex
+	ttl map[string]time.Duration
+}
+
+// newRetentionPolicy 创建保留策略
+func newRetentionPolicy(policy map[string]time.Duration) (*retentionPolicy, error) {
+	r := &retentionPolicy{}
+	if err := r.set(policy); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// set 替换保留策略，文件类型需为已知类型，保留时长不能为负
+func (r *retentionPolicy) set(policy map[string]time.Duration) error {
+	ttl := make(map[string]time.Duration, len(policy))
+	for fileType, d := range policy {
+		if _, _, err := fileTypeLimit(fileType); err != nil {
+			return fmt.Errorf("%w: retention policy: %v", ErrInvalidConfig, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%w: retention of %s must not be negative", ErrInvalidConfig, fileType)
+		}
+		if d > 0 {
+			ttl[fileType] = d
+		}
+	}
+	r.mu.Lock()
+	r.ttl = ttl
+	r.mu.Unlock()
+	return nil
+}
+
+// get 返回保留策略的副本
+func (r *retentionPolicy) get() map[string]time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	policy := make(map[string]time.Duration, len(r.ttl))
+	for fileType, d := range r.ttl {
+		policy[fileType] = d
+	}
+	return policy
+}
+
+// SetRetentionPolicy 运行时替换按文件类型的保留策略，只影响之后上传的文件，已有文件的过期时间不变
+func (s *dbStorage) SetRetentionPolicy(policy map[string]time.Duration) error {
+	return s.retention.set(policy)
+}
+
+// RetentionPolicy 获取当前的保留策略
+func (s *dbStorage) RetentionPolicy() map[string]time.Duration {
+	return s.retention.get()
+}
+
+// expiresAt 按上传时指定的保留时长和文件类型的保留策略计算过期时间，两者都设置时取较短的，都未设置时不过期
+func (s *dbStorage) expiresAt(fileType string, ttl time.Duration) *time.Time {
+	s.retention.mu.RLock()
+	policy := s.retention.ttl[fileType]
+	s.retention.mu.RUnlock()
+	if ttl <= 0 || policy > 0 && policy < ttl {
+		ttl = policy
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	t := time.Now().Add(ttl)
+	return &t
+}
+
+// expireFiles 按批将已过期的文件标记为已删除，缩略图等派生文件随之删除
+func (s *dbStorage) expireFiles(o *GCOptions, report *GCReport) error {
+	now := time.Now()
+	var lastID int64
+	for {
+		var batch []DBFile
+		if err := s.files().Select("id, file_id, file_size, content_hash").
+			Where("id > ? AND status IN (1, 3) AND expires_at < ?", lastID, now).
+			Order("id").Limit(o.BatchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+
+		for i := range batch {
+			dbFile := &batch[i]
+			if !o.DryRun {
+				deleted, err := s.markDeleted(dbFile)
+				if err != nil {
+					return err
+				}
+				if !deleted {
+					continue
+				}
+				s.deleteDerived(dbFile.FileID)
+			}
+			report.Expired++
+			report.ExpiredBytes += dbFile.FileSize
+		}
+	}
+}
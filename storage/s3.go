@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 存储后端
+const (
+	BackendDB = "db" // 文件内容存入数据库（默认）
+	BackendS3 = "s3" // 文件内容存入 S3 兼容对象存储，数据库只保存元数据
+)
+
+// S3Config S3 兼容对象存储配置，适用于 AWS S3、腾讯云 COS、阿里云 OSS、MinIO 等
+// 请求使用 AWS Signature V4 签名
+type S3Config struct {
+	Endpoint        string       // 服务地址，如 "https://s3.us-east-1.amazonaws.com"、"https://cos.ap-guangzhou.myqcloud.com"、"https://oss-cn-hangzhou.aliyuncs.com"、"http://localhost:9000"
+	Region          string       // 区域，如 "us-east-1"、"ap-guangzhou"、"cn-hangzhou"，默认 "us-east-1"
+	Bucket          string       // 存储桶
+	AccessKeyID     string       // 访问密钥 ID
+	SecretAccessKey string       // 访问密钥
+	SessionToken    string       // 临时凭证令牌（可选）
+	PathStyle       bool         // 使用路径风格地址 {Endpoint}/{Bucket}/{Key}，MinIO 等自建服务通常需要开启；默认 {Bucket}.{Endpoint 主机}/{Key}
+	KeyPrefix       string       // 对象键前缀（可选），如 "uploads/"
+	HTTPClient      *http.Client // HTTP 客户端（可选），默认超时 60 秒
+}
+
+// errObjectNotFound 对象不存在
+var errObjectNotFound = errors.New("object not found")
+
+// objectStore 对象存储，保存文件内容
+type objectStore interface {
+	// Put 上传对象，body 需从头读取 size 字节
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error
+
+	// Open 打开对象，首次读取时才发起请求，不存在时读取返回 errObjectNotFound
+	Open(ctx context.Context, key string, size int64) io.ReadSeekCloser
+
+	// Delete 删除对象，不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// s3Store S3 兼容对象存储
+type s3Store struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// newS3Store 创建 S3 兼容对象存储
+func newS3Store(config *S3Config) (*s3Store, error) {
+	if config == nil {
+		return nil, fmt.Errorf("%w: s3 config is required", ErrInvalidConfig)
+	}
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("%w: s3 bucket and credentials are required", ErrInvalidConfig)
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("%w: invalid s3 endpoint %q", ErrInvalidConfig, config.Endpoint)
+	}
+
+	cfg := *config
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &s3Store{config: cfg, endpoint: endpoint, client: client, now: time.Now}, nil
+}
+
+// objectURL 对象地址
+func (s *s3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	key = strings.TrimPrefix(s.config.KeyPrefix+key, "/")
+	if s.config.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.config.Bucket + "/" + key
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	// 签名要求按 RFC 3986 编码路径，与 url.URL 默认的编码规则不同
+	u.RawPath = uriEncodePath(u.Path)
+	return &u
+}
+
+// uriEncodePath 按 S3 签名规则编码路径，除非保留字符和 / 外全部编码
+func uriEncodePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// Put 上传对象，先读取一遍 body 计算签名所需的 SHA-256，再回到开头上传
+func (s *s3Store) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return fmt.Errorf("hash s3 object failed: %w", err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind s3 object failed: %w", err)
+	}
+
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, header, body, size, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Open 打开对象，按读取位置发起 Range 请求
+func (s *s3Store) Open(ctx context.Context, key string, size int64) io.ReadSeekCloser {
+	return &s3Object{store: s, ctx: ctx, key: key, size: size}
+}
+
+// Delete 删除对象
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0, emptySHA256)
+	if errors.Is(err, errObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do 发送签名请求，非 2xx 响应转换为错误，404 返回 errObjectNotFound
+func (s *s3Store) do(ctx context.Context, method, key string, header http.Header, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), io.NopCloser(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.ContentLength = size
+	s.sign(req, payloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s failed: %w", method, key, err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errObjectNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3 %s %s failed: status %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+// sign 按 AWS Signature V4 签名请求，签名 Host、Content-Type、Range 和全部 x-amz-* 头
+func (s *s3Store) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	// 规范请求头
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "content-type" || lk == "range" || strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// s3Object 对象读取器，Seek 只记录位置，读取时按位置发起 Range 请求
+type s3Object struct {
+	store  *s3Store
+	ctx    context.Context
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+// Read 读取对象内容
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		header := http.Header{}
+		if o.offset > 0 {
+			header.Set("Range", fmt.Sprintf("bytes=%d-", o.offset))
+		}
+		resp, err := o.store.do(o.ctx, http.MethodGet, o.key, header, nil, 0, emptySHA256)
+		if err != nil {
+			return 0, err
+		}
+		o.body = resp.Body
+	}
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	if err == io.EOF && o.offset < o.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Seek 设置读取位置，位置变化时关闭当前响应，下次读取重新请求
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 {
+		return 0, errors.New("s3 object: negative position")
+	}
+	if offset != o.offset && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.offset = offset
+	return offset, nil
+}
+
+// Close 关闭当前响应
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
+
+// emptySHA256 空内容的 SHA-256
+var emptySHA256 = sha256Hex(nil)
+
+// sha256Hex SHA-256 十六进制摘要
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
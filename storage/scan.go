@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	golog "github.com/bbadbeef/go-base/log"
+)
+
+// ScanResult 病毒扫描结果
+type ScanResult struct {
+	Infected bool   // 是否检出恶意内容
+	Threat   string // 威胁名称，如 "Eicar-Test-Signature"
+}
+
+// Scanner 病毒扫描，r 为文件内容
+// 扫描出错时返回错误，同步扫描时上传失败，异步扫描时文件保持待扫描状态
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*ScanResult, error)
+}
+
+// ScannerFunc 函数形式的 Scanner
+type ScannerFunc func(ctx context.Context, r io.Reader) (*ScanResult, error)
+
+// Scan 扫描文件内容
+func (f ScannerFunc) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	return f(ctx, r)
+}
+
+// clamdChunkSize 向 clamd 发送内容的分块大小
+const clamdChunkSize = 32 << 10
+
+// ClamAV 使用 clamd 的 INSTREAM 命令扫描
+// network、address 为 clamd 监听地址，如 "tcp", "127.0.0.1:3310" 或 "unix", "/var/run/clamav/clamd.ctl"
+// 文件大小需不超过 clamd 的 StreamMaxLength（默认 25MB），超过时返回错误
+func ClamAV(network, address string) Scanner {
+	return ScannerFunc(func(ctx context.Context, r io.Reader) (*ScanResult, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, fmt.Errorf("connect clamd failed: %w", err)
+		}
+		defer conn.Close()
+		// ctx 取消时关闭连接，中止读写
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		defer stop()
+
+		if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+			return nil, fmt.Errorf("write clamd failed: %w", err)
+		}
+		// 内容按 [4 字节大端长度][数据] 分块发送，长度为 0 的块表示结束
+		buf := make([]byte, 4+clamdChunkSize)
+		for {
+			n, err := io.ReadFull(r, buf[4:])
+			if n > 0 {
+				binary.BigEndian.PutUint32(buf, uint32(n))
+				if _, err := conn.Write(buf[:4+n]); err != nil {
+					return nil, fmt.Errorf("write clamd failed: %w", err)
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("read file failed: %w", err)
+			}
+		}
+		if _, err := conn.Write(make([]byte, 4)); err != nil {
+			return nil, fmt.Errorf("write clamd failed: %w", err)
+		}
+
+		// 响应如 "stream: OK"、"stream: Eicar-Test-Signature FOUND"、"INSTREAM size limit exceeded. ERROR"
+		reply, err := bufio.NewReader(conn).ReadString(0)
+		if err != nil && reply == "" {
+			return nil, fmt.Errorf("read clamd reply failed: %w", err)
+		}
+		reply = strings.TrimPrefix(strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), "stream: ")
+		switch {
+		case reply == "OK":
+			return &ScanResult{}, nil
+		case strings.HasSuffix(reply, " FOUND"):
+			return &ScanResult{Infected: true, Threat: strings.TrimSuffix(reply, " FOUND")}, nil
+		default:
+			return nil, fmt.Errorf("clamd: %s", reply)
+		}
+	})
+}
+
+// ScanPendingFiles 扫描待扫描的文件，返回完成扫描（通过或隔离）的文件数
+// 用于异步扫描出错或进程退出后重试，可由主应用定时调用
+func (s *dbStorage) ScanPendingFiles() (int64, error) {
+	if s.scanner == nil {
+		return 0, fmt.Errorf("%w: scanner is not configured", ErrInvalidConfig)
+	}
+	var pending []DBFile
+	if err := s.files().Omit("file_data").Where("status = 3").Find(&pending).Error; err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for i := range pending {
+		if err := s.scanPending(&pending[i]); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// scanUpload 扫描已写入内容、尚未保存记录的文件，未配置 Scanner 或文件为密文时跳过
+// 异步扫描时只将文件标记为待扫描，保存记录后由 afterSave 扫描
+func (s *dbStorage) scanUpload(dbFile *DBFile) error {
+	switch {
+	case s.scanner == nil || dbFile.Encrypted:
+		return nil
+	case s.scanAsync:
+		dbFile.Status = 3 // 待扫描
+		return nil
+	default:
+		return s.scan(dbFile)
+	}
+}
+
+// afterSave 保存文件记录后的处理：待扫描的文件在后台扫描，扫描通过后再生成缩略图
+func (s *dbStorage) afterSave(dbFile *DBFile) {
+	if dbFile.Status != 3 {
+		s.autoThumbnail(dbFile)
+		return
+	}
+	// 扫描不随请求取消而中止
+	detached := *s
+	detached.ctx = context.WithoutCancel(s.ctx)
+	file := *dbFile
+	go func() {
+		if err := detached.scanPending(&file); err != nil {
+			golog.WithFields(golog.FromContext(s.logger, detached.ctx), golog.Fields{"file_id": file.FileID, "err": err}).
+				Error("storage: async scan failed, retry with ScanPendingFiles")
+		}
+	}()
+}
+
+// scanPending 扫描待扫描的文件，通过后恢复正常并生成缩略图，检出恶意内容时隔离并释放内容引用，派生文件（如头像尺寸）随之删除
+func (s *dbStorage) scanPending(dbFile *DBFile) error {
+	status := 1 // 正常
+	if err := s.scan(dbFile); errors.Is(err, ErrMalwareDetected) {
+		status = 4 // 已隔离
+	} else if err != nil {
+		return err
+	}
+
+	result := s.files().Model(&DBFile{}).
+		Where("id = ? AND status = 3", dbFile.ID).
+		Updates(statusUpdate(status))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	dbFile.Status = status
+	if status == 4 {
+		s.deleteDerived(dbFile.FileID)
+		if dbFile.ContentHash != "" {
+			return s.releaseBlob(dbFile.ContentHash)
+		}
+		return nil
+	}
+	s.autoThumbnail(dbFile)
+	return nil
+}
+
+// scan 扫描文件内容，检出恶意内容时返回 *MalwareError
+func (s *dbStorage) scan(dbFile *DBFile) error {
+	rc, err := s.openContent(dbFile)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	result, err := s.scanner.Scan(s.ctx, rc)
+	if err != nil {
+		return fmt.Errorf("scan file failed: %w", err)
+	}
+	if result != nil && result.Infected {
+		return &MalwareError{Threat: result.Threat}
+	}
+	return nil
+}
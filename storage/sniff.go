@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// sniffAliases 内容识别结果的别名，统一为允许列表中使用的名称
+var sniffAliases = map[string]string{
+	"application/ogg": "audio/ogg",
+	"audio/wave":      "audio/wav",
+	"video/avi":       "video/x-msvideo",
+}
+
+// mimeFamilies 比较声明类型与识别结果时视为一致的 MIME 类型
+// MP4、M4A、MOV 同为 ISO BMFF 容器，主品牌因录制设备而异，不据此区分
+var mimeFamilies = map[string]string{
+	"image/jpg":       "image/jpeg",
+	"image/pjpeg":     "image/jpeg",
+	"image/x-ms-bmp":  "image/bmp",
+	"image/heic":      "image/heif",
+	"audio/mp3":       "audio/mpeg",
+	"audio/wave":      "audio/wav",
+	"audio/x-wav":     "audio/wav",
+	"audio/x-aac":     "audio/aac",
+	"application/ogg": "audio/ogg",
+	"video/avi":       "video/x-msvideo",
+	"audio/mp4":       "video/mp4",
+	"audio/m4a":       "video/mp4",
+	"audio/x-m4a":     "video/mp4",
+	"video/quicktime": "video/mp4",
+}
+
+// resolveMimeType 按文件头识别 MIME 类型并与客户端声明的类型比对
+// declared 为空时使用识别结果，内容无法识别时按文件扩展名
+// 图片、视频、语音的声明类型与识别结果不一致时返回 ErrMimeTypeMismatch，防止以图片等名义上传其他内容
+func resolveMimeType(fileType, fileName, declared string, head []byte) (string, error) {
+	sniffed := sniffMimeType(head)
+	if declared == "" {
+		declared = sniffed
+		if declared == "application/octet-stream" {
+			declared = mimeTypeByExt(fileName)
+		}
+	}
+	switch fileType {
+	case FileTypeImage, FileTypeVideo, FileTypeVoice:
+		if mimeFamily(declared) != mimeFamily(sniffed) {
+			return "", fmt.Errorf("%w: declared %s, detected %s", ErrMimeTypeMismatch, declared, sniffed)
+		}
+	}
+	return declared, nil
+}
+
+// declaredMimeType 不识别内容时（如客户端加密的文件）使用声明的 MIME 类型，未声明时按文件扩展名
+func declaredMimeType(fileName, declared string) string {
+	if declared != "" {
+		return declared
+	}
+	return mimeTypeByExt(fileName)
+}
+
+// sniffMimeType 按文件头魔数识别 MIME 类型，无法识别时返回 application/octet-stream
+// 在 http.DetectContentType 的基础上补充 MP4 / MOV / M4A / HEIC 等 ISO BMFF 格式、无 ID3 标签的 MP3、ADTS AAC 和 MPEG 视频
+func sniffMimeType(data []byte) string {
+	if mimeType := sniffFtyp(data); mimeType != "" {
+		return mimeType
+	}
+	if len(data) >= 4 && string(data[:3]) == "\x00\x00\x01" && (data[3] == 0xBA || data[3] == 0xB3) {
+		return "video/mpeg"
+	}
+	if len(data) >= 3 && data[0] == 0xFF && data[1]&0xE0 == 0xE0 {
+		// 帧同步：layer 位为 0 的是 ADTS AAC，1、2 为 MP3、MP2
+		// Layer I（layer 位为 3）极少使用且与 UTF-16 BOM 冲突，不识别
+		switch layer := data[1] >> 1 & 0x03; {
+		case layer == 0 && data[1]&0xF0 == 0xF0:
+			return "audio/aac"
+		case layer == 1 || layer == 2:
+			if data[2]>>4 != 0x0F { // 比特率索引 15 无效
+				return "audio/mpeg"
+			}
+		}
+	}
+
+	mimeType := http.DetectContentType(data)
+	if alias, ok := sniffAliases[mimeType]; ok {
+		return alias
+	}
+	return mimeType
+}
+
+// sniffFtyp 按 ftyp 主品牌识别 ISO BMFF 文件，不是 ISO BMFF 时返回空
+func sniffFtyp(data []byte) string {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return ""
+	}
+	switch string(data[8:12]) {
+	case "heic", "heix", "heim", "heis", "hevc", "hevx":
+		return "image/heic"
+	case "mif1", "msf1":
+		return "image/heif"
+	case "avif", "avis":
+		return "image/avif"
+	case "qt  ":
+		return "video/quicktime"
+	case "M4A ", "M4B ", "M4P ":
+		return "audio/mp4"
+	default:
+		// isom、mp41、mp42、avc1、iso2、dash 等
+		return "video/mp4"
+	}
+}
+
+// mimeFamily 归一化 MIME 类型：去掉参数、转为小写并合并别名
+func mimeFamily(mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if family, ok := mimeFamilies[mimeType]; ok {
+		return family
+	}
+	return mimeType
+}
+
+// mimeTypeByExt 按文件扩展名判断 MIME 类型，未知扩展名返回 application/octet-stream
+func mimeTypeByExt(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	case ".bmp":
+		return "image/bmp"
+	case ".heic":
+		return "image/heic"
+	case ".heif":
+		return "image/heif"
+	case ".mp4":
+		return "video/mp4"
+	case ".mov":
+		return "video/quicktime"
+	case ".avi":
+		return "video/x-msvideo"
+	case ".mpg", ".mpeg":
+		return "video/mpeg"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".wav":
+		return "audio/wav"
+	case ".ogg":
+		return "audio/ogg"
+	case ".aac":
+		return "audio/aac"
+	case ".m4a":
+		return "audio/mp4"
+	}
+	return "application/octet-stream"
+}
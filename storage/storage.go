@@ -1,18 +1,29 @@
 // Package storage 提供文件存储功能
-// 支持将文件存储到数据库，适用于小文件（<10MB）的多节点部署场景
+// 支持将文件存储到数据库（适用于小文件（<10MB）的多节点部署场景）或 S3 兼容对象存储（元数据仍保存在数据库）
 package storage
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"path/filepath"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/idgen"
+	golog "github.com/bbadbeef/go-base/log"
+	"github.com/bbadbeef/go-base/migrate"
+	"github.com/bbadbeef/go-base/observability"
+	"github.com/bbadbeef/go-base/observability/gormmetrics"
 )
 
 // 文件类型常量
@@ -23,6 +34,9 @@ const (
 	FileTypeFile  = "file"  // 普通文件
 )
 
+// sniffLen 识别 MIME 类型时读取的文件头长度
+const sniffLen = 512
+
 // 文件大小限制（字节）
 const (
 	MaxImageSize = 10 * 1024 * 1024  // 10MB
@@ -34,7 +48,7 @@ const (
 // 允许的文件类型
 var (
 	AllowedImageTypes = []string{
-		"image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp", "image/bmp",
+		"image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp", "image/bmp", "image/heic", "image/heif",
 	}
 	AllowedVideoTypes = []string{
 		"video/mp4", "video/quicktime", "video/x-msvideo", "video/mpeg",
@@ -47,6 +61,7 @@ var (
 // FileInfo 文件信息
 type FileInfo struct {
 	FileID     string                 `json:"file_id"`              // 文件唯一ID
+	UserID     int64                  `json:"user_id"`              // 上传用户ID
 	FileName   string                 `json:"file_name"`            // 原始文件名
 	FileType   string                 `json:"file_type"`            // 文件类型（image/video/voice/file）
 	MimeType   string                 `json:"mime_type"`            // MIME类型
@@ -56,10 +71,24 @@ type FileInfo struct {
 	Duration   int                    `json:"duration,omitempty"`   // 时长（音频/视频，秒）
 	URL        string                 `json:"url"`                  // 访问URL
 	Thumbnail  string                 `json:"thumbnail,omitempty"`  // 缩略图URL（图片/视频）
+	Pending    bool                   `json:"pending,omitempty"`    // 等待病毒扫描，扫描通过前不可下载
+	SourceID   string                 `json:"source_id,omitempty"`  // 源文件ID，仅派生文件（如缩略图）
+	Encrypted  bool                   `json:"encrypted,omitempty"`  // 客户端加密的文件，内容为密文，密钥信封通过 GetKeyEnvelopes 获取
+	ExpiresAt  *time.Time             `json:"expires_at,omitempty"` // 过期时间，过期后由垃圾回收删除，为空表示永久保留
 	ExtraData  map[string]interface{} `json:"extra_data,omitempty"` // 扩展数据
 	UploadTime time.Time              `json:"upload_time"`          // 上传时间
 }
 
+// UploadMeta 流式上传的文件信息
+type UploadMeta struct {
+	FileName  string        // 原始文件名
+	MimeType  string        // MIME类型（可选），为空时按文件名和文件头识别
+	UserID    int64         // 上传用户ID
+	FileType  string        // 文件类型
+	Encrypted bool          // 内容为客户端加密的密文（可选），不识别内容、不生成缩略图、不扫描，MIME 类型使用声明的明文类型，未声明时按文件扩展名
+	TTL       time.Duration // 保留时长（可选），如临时文件 24 小时，超过后由垃圾回收删除；文件类型设置了保留策略时取较短的
+}
+
 // UploadRequest 上传请求
 type UploadRequest struct {
 	File     multipart.File   // 文件
@@ -70,232 +99,675 @@ type UploadRequest struct {
 
 // Storage 存储接口
 type Storage interface {
-	// Upload 上传文件
+	// Upload 上传文件，req.File 通常来自 r.FormFile，表单解析时已整体缓存，处理 HTTP 请求时建议使用 UploadStream
 	Upload(req *UploadRequest) (*FileInfo, error)
 
-	// Download 下载文件
+	// UploadStream 流式上传文件，内容边读边写入存储，不整体读入内存
+	UploadStream(r io.Reader, meta *UploadMeta) (*FileInfo, error)
+
+	// UploadAvatar 上传头像，居中裁剪为正方形并生成小、中、大三种尺寸，返回各尺寸的 URL
+	UploadAvatar(r io.Reader, meta *UploadMeta) (*AvatarInfo, error)
+
+	// Download 下载文件，整个文件读入内存
 	Download(fileID string) ([]byte, *FileInfo, error)
 
+	// DownloadStream 流式下载文件，调用方负责关闭返回的 ReadCloser
+	// 返回值同时实现 io.Seeker，可配合 http.ServeContent 支持 Range 请求
+	DownloadStream(fileID string) (io.ReadCloser, *FileInfo, error)
+
+	// InitiateUpload 创建断点续传上传任务，totalSize 为文件总大小
+	InitiateUpload(meta *UploadMeta, totalSize int64) (*UploadSession, error)
+
+	// GetUpload 获取上传任务，Received 为已接收的字节数
+	GetUpload(uploadID string) (*UploadSession, error)
+
+	// UploadPart 从 offset 处上传一个分片，offset 需不大于已接收的字节数
+	UploadPart(uploadID string, offset int64, r io.Reader) (*UploadSession, error)
+
+	// CompleteUpload 完成上传，校验文件类型和大小后生成文件
+	CompleteUpload(uploadID string) (*FileInfo, error)
+
+	// AbortUpload 取消上传任务
+	AbortUpload(uploadID string) error
+
+	// CleanupExpiredUploads 删除已过期的上传任务，返回删除的任务数
+	CleanupExpiredUploads() (int64, error)
+
 	// GetFileInfo 获取文件信息
 	GetFileInfo(fileID string) (*FileInfo, error)
 
+	// GetFileInfos 批量获取文件信息，按文件ID返回，不存在或已删除的文件不包含在结果中
+	GetFileInfos(fileIDs []string) (map[string]*FileInfo, error)
+
+	// ListFiles 分页获取用户上传的文件，按上传时间倒序，filter 为 nil 时使用默认值
+	ListFiles(userID int64, filter *FileFilter) (*FileList, error)
+
+	// AddKeyEnvelopes 保存客户端加密文件的密钥信封，同一接收者（设备）已有信封时替换
+	AddKeyEnvelopes(fileID string, envelopes []*KeyEnvelope) error
+
+	// GetKeyEnvelopes 获取用户在加密文件上的密钥信封，没有信封时返回 ErrKeyEnvelopeNotFound
+	GetKeyEnvelopes(fileID string, userID int64) ([]*KeyEnvelope, error)
+
+	// GetUsage 获取用户的存储用量，按文件类型统计
+	GetUsage(userID int64) (*Usage, error)
+
+	// GC 执行一次垃圾回收：删除已过期和未被引用的文件，删除超过保留期的已删除文件的记录和内容，opts 为 nil 时使用默认值
+	// 可使用 RunGC 定期执行
+	GC(opts *GCOptions) (*GCReport, error)
+
+	// SetRetentionPolicy 运行时替换按文件类型的保留策略，只影响之后上传的文件
+	SetRetentionPolicy(policy map[string]time.Duration) error
+
+	// RetentionPolicy 获取当前按文件类型的保留策略
+	RetentionPolicy() map[string]time.Duration
+
+	// ScanPendingFiles 扫描待扫描的文件，返回完成扫描的文件数，需配置 Config.Scanner
+	ScanPendingFiles() (int64, error)
+
+	// GetImage 按需缩放、裁剪、转换格式后返回图片，用于按显示尺寸获取头像、预览图，结果缓存在内存中
+	// opts 为 nil 时保持原尺寸
+	GetImage(fileID string, opts *ImageOptions) (*ImageVariant, *FileInfo, error)
+
+	// GenerateThumbnail 为已上传的文件（重新）生成缩略图，需配置 Config.Thumbnail
+	GenerateThumbnail(fileID string) (*FileInfo, error)
+
 	// Delete 删除文件
 	Delete(fileID string) error
 
+	// DeleteFiles 批量删除文件，返回删除的文件数，不存在或已删除的文件跳过
+	DeleteFiles(fileIDs []string) (int64, error)
+
 	// DeleteByUser 删除用户的所有文件
 	DeleteByUser(userID int64) error
+
+	// Migrate 执行所有未执行的数据库迁移
+	Migrate() error
+
+	// MigrationStatus 获取所有数据库迁移的执行状态
+	MigrationStatus() ([]*MigrationStatus, error)
+
+	// WithContext 返回绑定 ctx 的存储实例，数据库操作随 ctx 超时或取消而中止
+	WithContext(ctx context.Context) Storage
 }
 
+// SlowQuery 慢 SQL 信息，SlowQueryFunc 慢 SQL 回调，见 observability/gormmetrics
+type (
+	SlowQuery     = gormmetrics.SlowQuery
+	SlowQueryFunc = gormmetrics.SlowQueryFunc
+)
+
 // Config 存储配置
 type Config struct {
-	DB      *gorm.DB // 数据库连接
-	BaseURL string   // 文件访问基础URL，如 "http://localhost:8080"
+	DB                 *gorm.DB                 // 数据库连接
+	TenantID           string                   // 租户 ID（可选），最长 64 个字符，设置后文件仅在该租户内可见，多个租户可共用同一数据库
+	BaseURL            string                   // 文件访问基础URL，如 "http://localhost:8080"
+	TablePrefix        string                   // 表名前缀（可选），如 "app_" -> "app_storage_files"
+	TableNames         map[string]string        // 按默认表名覆盖表名（可选），如 {"storage_files": "files"}，优先于 TablePrefix
+	DisableAutoMigrate bool                     // 禁止创建实例时自动执行数据库迁移（可选），禁止后需显式调用 Migrate
+	Backend            string                   // 存储后端（可选），BackendDB（默认）或 BackendS3
+	S3                 *S3Config                // S3 兼容对象存储配置，Backend 为 BackendS3 时必填
+	Thumbnail          *ThumbnailConfig         // 缩略图配置（可选），设置后上传图片和视频时生成缩略图
+	MediaProber        MediaProber              // 音视频信息探测（可选），设置后上传语音和视频时填充时长，可使用 FFprobe
+	UserQuota          int64                    // 每个用户的存储配额（字节，可选），0 表示不限制
+	UserQuotaFunc      QuotaFunc                // 按用户返回存储配额（可选），优先于 UserQuota，返回 0 表示不限制
+	StripMetadata      bool                     // 上传图片时去除 EXIF（含 GPS 位置）、XMP 等元数据（可选），支持 JPEG、PNG，保留 EXIF 方向信息
+	Scanner            Scanner                  // 病毒扫描（可选），设置后检出恶意内容的文件不可下载，可使用 ClamAV
+	ScanAsync          bool                     // 异步扫描（可选），上传后立即返回，文件在扫描通过前处于待扫描状态，默认同步扫描，检出时上传失败
+	ImageCacheBytes    int64                    // GetImage 处理结果的内存缓存容量（字节，可选），默认 64MB，小于 0 时不缓存
+	RetentionPolicy    map[string]time.Duration // 按文件类型的保留时长（可选），如 {FileTypeVoice: 30 * 24 * time.Hour}，超过后由垃圾回收删除，可通过 SetRetentionPolicy 运行时修改
+	Metrics            observability.Metrics    // 上传、下载、读取内容的监控指标（可选），如 observability.NewPrometheus()
+	SlowThreshold      time.Duration            // 慢操作阈值（可选），默认 500ms，小于 0 时不记录慢操作
+	SlowLog            SlowLogFunc              // 慢操作日志（可选），默认以警告级别写入 Logger
+	Logger             golog.Logger             // 日志（可选），记录慢操作、异步扫描和缩略图生成失败，默认写入标准库 log，可与 user、im 模块共用
+	SlowQueryThreshold time.Duration            // 慢 SQL 阈值（可选），默认 200ms，小于 0 时不回调 SlowQueryFunc
+	SlowQueryFunc      SlowQueryFunc            // 慢 SQL 回调（可选），附带发起查询的方法；设置 Metrics 或 SlowQueryFunc 时按方法记录 storage_db_query_duration_seconds
+	IDGenerator        idgen.Generator          // 文件 ID 生成器（可选），如 idgen.NewSnowflake(node)，生成的 ID 以十进制字符串作为文件 ID，默认随机 UUID
 }
 
-// dbStorage 数据库存储实现
+// dbStorage 数据库存储实现，objects 不为空时文件内容存入对象存储，数据库只保存元数据
 type dbStorage struct {
-	db      *gorm.DB
-	baseURL string
+	db            *gorm.DB
+	objects       objectStore
+	baseURL       string
+	fileTable     string
+	chunkTable    string
+	uploadTable   string
+	blobTable     string
+	fileKeyTable  string
+	migrator      *migrate.Migrator
+	tenant        string
+	thumbnail     *ThumbnailConfig
+	stripMetadata bool
+	prober        MediaProber
+	quota         int64
+	quotaFunc     QuotaFunc
+	scanner       Scanner
+	scanAsync     bool
+	images        *imageCache
+	retention     *retentionPolicy
+	metrics       observability.Metrics
+	slowThreshold time.Duration
+	slowLog       SlowLogFunc
+	logger        golog.Logger
+	ids           idgen.Generator
+	ctx           context.Context
 }
 
 // NewStorage 创建存储实例
 func NewStorage(config *Config) (Storage, error) {
 	if config == nil {
-		return nil, fmt.Errorf("config is required")
+		return nil, fmt.Errorf("%w: config is required", ErrInvalidConfig)
 	}
 	if config.DB == nil {
-		return nil, fmt.Errorf("database connection is required")
+		return nil, fmt.Errorf("%w: database connection is required", ErrInvalidConfig)
 	}
 	if config.BaseURL == "" {
-		return nil, fmt.Errorf("base URL is required")
+		return nil, fmt.Errorf("%w: base URL is required", ErrInvalidConfig)
+	}
+	if len(config.TenantID) > 64 {
+		return nil, fmt.Errorf("%w: tenant id must be at most 64 characters", ErrInvalidConfig)
+	}
+
+	retention, err := newRetentionPolicy(config.RetentionPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQL 耗时指标和慢查询回调
+	if config.Metrics != nil || config.SlowQueryFunc != nil {
+		err := gormmetrics.Register(config.DB, gormmetrics.Config{
+			Module:        "storage",
+			Package:       "github.com/bbadbeef/go-base/storage.",
+			Metrics:       config.Metrics,
+			SlowThreshold: config.SlowQueryThreshold,
+			OnSlowQuery:   config.SlowQueryFunc,
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	storage := &dbStorage{
-		db:      config.DB,
-		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+		db:            config.DB,
+		baseURL:       strings.TrimSuffix(config.BaseURL, "/"),
+		fileTable:     tableName(config, DBFile{}.TableName()),
+		chunkTable:    tableName(config, DBFileChunk{}.TableName()),
+		uploadTable:   tableName(config, DBUpload{}.TableName()),
+		blobTable:     tableName(config, DBBlob{}.TableName()),
+		fileKeyTable:  tableName(config, DBFileKey{}.TableName()),
+		tenant:        config.TenantID,
+		stripMetadata: config.StripMetadata,
+		prober:        config.MediaProber,
+		quota:         config.UserQuota,
+		quotaFunc:     config.UserQuotaFunc,
+		scanner:       config.Scanner,
+		scanAsync:     config.ScanAsync,
+		images:        newImageCache(config.ImageCacheBytes),
+		retention:     retention,
+		metrics:       observability.OrNop(config.Metrics),
+		slowThreshold: config.SlowThreshold,
+		slowLog:       config.SlowLog,
+		logger:        golog.OrStd(config.Logger),
+		ids:           config.IDGenerator,
+		ctx:           context.Background(),
+	}
+	if storage.slowThreshold == 0 {
+		storage.slowThreshold = defaultSlowThreshold
+	}
+	if storage.slowLog == nil {
+		storage.slowLog = storage.logSlowOperation
+	}
+	storage.migrator = newMigrator(storage, tableName(config, migrationTable))
+	if config.Thumbnail != nil {
+		thumbnail := config.Thumbnail.withDefaults()
+		storage.thumbnail = &thumbnail
+	}
+
+	switch config.Backend {
+	case "", BackendDB:
+	case BackendS3:
+		objects, err := newS3Store(config.S3)
+		if err != nil {
+			return nil, err
+		}
+		storage.objects = objects
+	default:
+		return nil, fmt.Errorf("%w: unknown backend %q", ErrInvalidConfig, config.Backend)
 	}
 
-	// 初始化数据库表
-	if err := storage.initTable(); err != nil {
-		return nil, fmt.Errorf("init storage table failed: %w", err)
+	// 自动执行数据库迁移
+	if !config.DisableAutoMigrate {
+		if err := storage.Migrate(); err != nil {
+			return nil, fmt.Errorf("migrate storage tables failed: %w", err)
+		}
 	}
 
 	return storage, nil
 }
 
-// initTable 初始化数据库表
-func (s *dbStorage) initTable() error {
-	err := s.db.AutoMigrate(&DBFile{})
-	// 忽略DROP不存在的索引/外键错误（GORM迁移的已知问题）
-	if err != nil && (strings.Contains(err.Error(), "Can't DROP") || 
-		strings.Contains(err.Error(), "check that column/key exists")) {
-		return nil
+// tableName 根据配置解析实际表名
+func tableName(config *Config, defaultName string) string {
+	if name, ok := config.TableNames[defaultName]; ok && name != "" {
+		return name
 	}
-	return err
+	return config.TablePrefix + defaultName
+}
+
+// fileTableDB 文件表，不限定租户，用于表结构迁移
+func (s *dbStorage) fileTableDB() *gorm.DB {
+	return s.db.WithContext(s.ctx).Table(s.fileTable)
 }
 
-// Upload 上传文件
+// files 文件表，限定当前租户
+func (s *dbStorage) files() *gorm.DB {
+	return s.fileTableDB().Where("tenant_id = ?", s.tenant)
+}
+
+// WithContext 返回绑定 ctx 的存储实例
+func (s *dbStorage) WithContext(ctx context.Context) Storage {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
+}
+
+// Upload 上传文件，文件内容流式写入存储
 func (s *dbStorage) Upload(req *UploadRequest) (*FileInfo, error) {
 	if req == nil || req.File == nil || req.Header == nil {
-		return nil, fmt.Errorf("invalid upload request")
+		return nil, ErrInvalidUploadRequest
 	}
+	return s.UploadStream(req.File, &UploadMeta{
+		FileName: req.Header.Filename,
+		MimeType: req.Header.Header.Get("Content-Type"),
+		UserID:   req.UserID,
+		FileType: req.FileType,
+	})
+}
 
-	// 读取文件内容
-	data, err := io.ReadAll(req.File)
-	if err != nil {
-		return nil, fmt.Errorf("read file failed: %w", err)
+// UploadStream 流式上传文件
+// 数据库后端按 fileChunkSize 分块写入分块表；对象存储后端先写入临时文件计算签名，再上传
+func (s *dbStorage) UploadStream(r io.Reader, meta *UploadMeta) (*FileInfo, error) {
+	start := time.Now()
+	info, err := s.uploadStream(r, meta)
+	var fileID, fileType string
+	var size int64
+	if meta != nil {
+		fileType = meta.FileType
+	}
+	if info != nil {
+		fileID, size = info.FileID, info.FileSize
+	}
+	s.observe(OpUpload, fileID, fileType, size, start, err)
+	return info, err
+}
+
+// uploadStream 流式上传文件
+func (s *dbStorage) uploadStream(r io.Reader, meta *UploadMeta) (*FileInfo, error) {
+	if r == nil || meta == nil || meta.TTL < 0 {
+		return nil, ErrInvalidUploadRequest
 	}
 
-	// 获取文件信息
-	fileName := req.Header.Filename
-	fileSize := int64(len(data))
-	mimeType := req.Header.Header.Get("Content-Type")
-	if mimeType == "" {
-		mimeType = detectMimeType(fileName, data)
+	// 按文件头识别 MIME 类型并与声明的类型比对，密文无法识别，使用声明的类型
+	br := bufio.NewReaderSize(r, sniffLen)
+	var mimeType string
+	var err error
+	if meta.Encrypted {
+		mimeType = declaredMimeType(meta.FileName, meta.MimeType)
+	} else {
+		head, _ := br.Peek(sniffLen)
+		if mimeType, err = resolveMimeType(meta.FileType, meta.FileName, meta.MimeType, head); err != nil {
+			return nil, err
+		}
 	}
 
-	// 验证文件
-	if err := s.validateFile(req.FileType, mimeType, fileSize); err != nil {
+	// 验证文件类型，大小在读取过程中校验
+	maxSize, err := s.checkFileType(meta.FileType, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	// 已达到配额时不再读取内容，写入后按实际大小校验
+	if err := s.checkQuota(meta.UserID, 0); err != nil {
 		return nil, err
 	}
+	// 去除图片元数据，大小按去除后的内容校验
+	content := io.Reader(br)
+	if s.stripMetadata && meta.FileType == FileTypeImage && !meta.Encrypted {
+		stripped := stripImageMetadata(mimeType, br)
+		defer stripped.Close()
+		content = stripped
+	}
+	limited := &io.LimitedReader{R: content, N: maxSize + 1}
+	hash := sha256.New()
+	body := io.TeeReader(limited, hash)
 
 	// 生成文件ID
-	fileID := generateFileID()
+	fileID := s.newFileID()
 
-	// 创建数据库记录
 	dbFile := &DBFile{
-		FileID:   fileID,
-		UserID:   req.UserID,
-		FileName: fileName,
-		FileType: req.FileType,
-		MimeType: mimeType,
-		FileSize: fileSize,
-		FileData: data,
-		Status:   1, // 正常
+		TenantID:  s.tenant,
+		FileID:    fileID,
+		UserID:    meta.UserID,
+		FileName:  meta.FileName,
+		FileType:  meta.FileType,
+		MimeType:  mimeType,
+		FileData:  []byte{},
+		Encrypted: meta.Encrypted,
+		ExpiresAt: s.expiresAt(meta.FileType, meta.TTL),
+		Status:    1, // 正常
 	}
 
-	// 保存到数据库
-	if err := s.db.Create(dbFile).Error; err != nil {
+	// 写入文件内容，cleanup 在保存记录失败或内容重复时清理已写入的内容
+	var cleanup func()
+	if s.objects != nil {
+		key := s.objectKey(fileID)
+		dbFile.ObjectKey = key
+		dbFile.FileSize, err = s.putObject(key, body, maxSize, mimeType)
+		cleanup = func() { s.objects.Delete(s.ctx, key) }
+	} else {
+		dbFile.ChunkSize = fileChunkSize
+		dbFile.FileSize, err = s.writeChunks(fileID, body)
+		cleanup = func() { s.deleteChunks(fileID) }
+	}
+	if err == nil && dbFile.FileSize > maxSize {
+		err = &FileTooLargeError{Size: dbFile.FileSize, MaxSize: maxSize}
+	}
+	if err == nil {
+		err = s.checkQuota(meta.UserID, dbFile.FileSize)
+	}
+	if err == nil {
+		err = s.scanUpload(dbFile)
+	}
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	s.fillMediaInfo(dbFile)
+
+	// 保存到数据库，已有相同内容时引用已有内容
+	var duplicate bool
+	err = s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		ts := s.withDB(tx)
+		var err error
+		if duplicate, err = ts.dedupe(dbFile, hex.EncodeToString(hash.Sum(nil))); err != nil {
+			return err
+		}
+		return ts.files().Create(dbFile).Error
+	})
+	if err != nil {
+		cleanup()
 		return nil, fmt.Errorf("save file to database failed: %w", err)
 	}
+	if duplicate {
+		cleanup()
+	}
 
-	// 构建文件信息
-	fileInfo := &FileInfo{
-		FileID:     fileID,
-		FileName:   fileName,
-		FileType:   req.FileType,
-		MimeType:   mimeType,
-		FileSize:   fileSize,
-		URL:        fmt.Sprintf("%s/api/files/%s", s.baseURL, fileID),
-		UploadTime: dbFile.CreatedAt,
+	s.afterSave(dbFile)
+	return s.toFileInfo(dbFile), nil
+}
+
+// putObject 将内容写入临时文件后上传到对象存储，超过 maxSize 时不上传，返回读取的字节数
+func (s *dbStorage) putObject(key string, r io.Reader, maxSize int64, mimeType string) (int64, error) {
+	tmp, err := os.CreateTemp("", "storage-upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("create temp file failed: %w", err)
 	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
-	return fileInfo, nil
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return size, fmt.Errorf("read file failed: %w", err)
+	}
+	if size > maxSize {
+		return size, nil
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return size, err
+	}
+	if err := s.objects.Put(s.ctx, key, tmp, size, mimeType); err != nil {
+		return size, fmt.Errorf("save file to object storage failed: %w", err)
+	}
+	return size, nil
 }
 
-// Download 下载文件
+// Download 下载文件，整个文件读入内存，大文件使用 DownloadStream
 func (s *dbStorage) Download(fileID string) ([]byte, *FileInfo, error) {
+	rc, fileInfo, err := s.DownloadStream(fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if errors.Is(err, errObjectNotFound) {
+		return nil, nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, fileInfo, nil
+}
+
+// DownloadStream 流式下载文件，返回的 ReadCloser 同时实现 io.Seeker
+func (s *dbStorage) DownloadStream(fileID string) (io.ReadCloser, *FileInfo, error) {
+	start := time.Now()
+	rc, info, err := s.downloadStream(fileID)
+	var fileType string
+	var size int64
+	if info != nil {
+		fileType, size = info.FileType, info.FileSize
+	}
+	s.observe(OpDownload, fileID, fileType, size, start, err)
+	return rc, info, err
+}
+
+// downloadStream 查询文件并打开内容
+func (s *dbStorage) downloadStream(fileID string) (io.ReadCloser, *FileInfo, error) {
 	var dbFile DBFile
-	if err := s.db.Where("file_id = ? AND status = 1", fileID).First(&dbFile).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil, fmt.Errorf("file not found")
+	if err := s.files().Omit("file_data").Where("file_id = ? AND status = 1", fileID).First(&dbFile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrFileNotFound
 		}
 		return nil, nil, err
 	}
 
-	fileInfo := &FileInfo{
-		FileID:     dbFile.FileID,
-		FileName:   dbFile.FileName,
-		FileType:   dbFile.FileType,
-		MimeType:   dbFile.MimeType,
-		FileSize:   dbFile.FileSize,
-		URL:        fmt.Sprintf("%s/api/files/%s", s.baseURL, dbFile.FileID),
-		UploadTime: dbFile.CreatedAt,
+	rc, err := s.openContent(&dbFile)
+	if err != nil {
+		return nil, nil, err
 	}
+	return rc, s.toFileInfo(&dbFile), nil
+}
 
-	return dbFile.FileData, fileInfo, nil
+// openContent 打开文件内容，按存储方式从对象存储、分块表或 file_data 读取
+func (s *dbStorage) openContent(dbFile *DBFile) (io.ReadSeekCloser, error) {
+	switch {
+	case dbFile.ObjectKey != "":
+		if s.objects == nil {
+			return nil, fmt.Errorf("file %s is stored in object storage, but no object storage is configured", dbFile.FileID)
+		}
+		return s.objects.Open(s.ctx, dbFile.ObjectKey, dbFile.FileSize), nil
+	case dbFile.ChunkSize > 0:
+		return newChunkReader(s, dbFile.contentFileID(), dbFile.FileSize, dbFile.ChunkSize), nil
+	default:
+		// 分块存储之前上传的文件，内容在 file_data 中
+		var data DBFile
+		start := time.Now()
+		err := s.files().Select("file_data").Where("file_id = ?", dbFile.FileID).Take(&data).Error
+		s.observe(OpBlobRead, dbFile.FileID, "", int64(len(data.FileData)), start, err)
+		if err != nil {
+			return nil, err
+		}
+		return nopSeekCloser{bytes.NewReader(data.FileData)}, nil
+	}
 }
 
-// GetFileInfo 获取文件信息
+// GetFileInfo 获取文件信息，包括待扫描的文件
 func (s *dbStorage) GetFileInfo(fileID string) (*FileInfo, error) {
 	var dbFile DBFile
-	if err := s.db.Select("file_id, user_id, file_name, file_type, mime_type, file_size, created_at").
-		Where("file_id = ? AND status = 1", fileID).First(&dbFile).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("file not found")
+	if err := s.files().Select(fileInfoColumns).
+		Where("file_id = ? AND status IN (1, 3)", fileID).First(&dbFile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
 		}
 		return nil, err
 	}
 
-	return &FileInfo{
+	return s.toFileInfo(&dbFile), nil
+}
+
+// toFileInfo 转换为文件信息
+func (s *dbStorage) toFileInfo(dbFile *DBFile) *FileInfo {
+	info := &FileInfo{
 		FileID:     dbFile.FileID,
+		UserID:     dbFile.UserID,
 		FileName:   dbFile.FileName,
 		FileType:   dbFile.FileType,
 		MimeType:   dbFile.MimeType,
 		FileSize:   dbFile.FileSize,
-		URL:        fmt.Sprintf("%s/api/files/%s", s.baseURL, dbFile.FileID),
+		Width:      dbFile.Width,
+		Height:     dbFile.Height,
+		Duration:   dbFile.Duration,
+		URL:        s.fileURL(dbFile.FileID),
+		SourceID:   dbFile.SourceID,
+		Encrypted:  dbFile.Encrypted,
+		ExpiresAt:  dbFile.ExpiresAt,
+		Pending:    dbFile.Status == 3,
 		UploadTime: dbFile.CreatedAt,
-	}, nil
+	}
+	if dbFile.ThumbnailID != "" {
+		info.Thumbnail = s.fileURL(dbFile.ThumbnailID)
+	}
+	return info
+}
+
+// fileURL 文件访问URL
+func (s *dbStorage) fileURL(fileID string) string {
+	return fmt.Sprintf("%s/api/files/%s", s.baseURL, fileID)
+}
+
+// nopSeekCloser 为 io.ReadSeeker 添加空的 Close
+type nopSeekCloser struct {
+	io.ReadSeeker
 }
 
-// Delete 删除文件
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+// Delete 删除文件，缩略图等派生文件随之删除
 func (s *dbStorage) Delete(fileID string) error {
-	result := s.db.Model(&DBFile{}).
-		Where("file_id = ?", fileID).
-		Update("status", 2) // 标记为已删除
+	var dbFile DBFile
+	if err := s.files().Select("id, content_hash").
+		Where("file_id = ? AND status IN (1, 3)", fileID).Take(&dbFile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFileNotFound
+		}
+		return err
+	}
 
-	if result.Error != nil {
-		return result.Error
+	deleted, err := s.markDeleted(&dbFile)
+	if err != nil {
+		return err
 	}
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("file not found")
+	if !deleted {
+		return ErrFileNotFound
 	}
-
+	s.deleteDerived(fileID)
 	return nil
 }
 
 // DeleteByUser 删除用户的所有文件
 func (s *dbStorage) DeleteByUser(userID int64) error {
-	return s.db.Model(&DBFile{}).
-		Where("user_id = ?", userID).
-		Update("status", 2).Error
+	// 去重的文件逐个删除以释放内容引用
+	var deduped []DBFile
+	if err := s.files().Select("id, content_hash").
+		Where("user_id = ? AND status IN (1, 3) AND content_hash <> ''", userID).Find(&deduped).Error; err != nil {
+		return err
+	}
+	for i := range deduped {
+		if _, err := s.markDeleted(&deduped[i]); err != nil {
+			return err
+		}
+	}
+
+	return s.files().Model(&DBFile{}).
+		Where("user_id = ? AND status IN (1, 3)", userID).
+		Updates(statusUpdate(2)).Error
 }
 
-// validateFile 验证文件
-func (s *dbStorage) validateFile(fileType, mimeType string, fileSize int64) error {
-	// 检查文件大小
-	var maxSize int64
+// markDeleted 将文件标记为已删除并释放内容引用，文件已被删除时返回 false
+func (s *dbStorage) markDeleted(dbFile *DBFile) (bool, error) {
+	result := s.files().Model(&DBFile{}).
+		Where("id = ? AND status IN (1, 3)", dbFile.ID).
+		Updates(statusUpdate(2)) // 标记为已删除
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	if dbFile.ContentHash != "" {
+		s.releaseBlob(dbFile.ContentHash)
+	}
+	return true, nil
+}
+
+// statusUpdate 更新文件状态，标记删除或隔离时记录删除时间，垃圾回收据此判断保留期
+func statusUpdate(status int) map[string]interface{} {
+	updates := map[string]interface{}{"status": status}
+	if status == 2 || status == 4 {
+		updates["deleted_at"] = time.Now()
+	}
+	return updates
+}
+
+// objectKey 文件在对象存储中的键，设置租户时按租户分目录
+func (s *dbStorage) objectKey(fileID string) string {
+	if s.tenant == "" {
+		return fileID
+	}
+	return s.tenant + "/" + fileID
+}
+
+// MaxSizeOf 文件类型的大小上限（字节），可用于在读取请求体前按 Content-Length 拒绝过大的上传
+func MaxSizeOf(fileType string) (int64, error) {
+	maxSize, _, err := fileTypeLimit(fileType)
+	return maxSize, err
+}
+
+// fileTypeLimit 文件类型的大小上限和允许的 MIME 类型，allowed 为 nil 表示不限制
+func fileTypeLimit(fileType string) (maxSize int64, allowed []string, err error) {
 	switch fileType {
 	case FileTypeImage:
-		maxSize = MaxImageSize
-		if !isAllowedMimeType(mimeType, AllowedImageTypes) {
-			return fmt.Errorf("不支持的图片格式: %s", mimeType)
-		}
+		return MaxImageSize, AllowedImageTypes, nil
 	case FileTypeVideo:
-		maxSize = MaxVideoSize
-		if !isAllowedMimeType(mimeType, AllowedVideoTypes) {
-			return fmt.Errorf("不支持的视频格式: %s", mimeType)
-		}
+		return MaxVideoSize, AllowedVideoTypes, nil
 	case FileTypeVoice:
-		maxSize = MaxVoiceSize
-		if !isAllowedMimeType(mimeType, AllowedVoiceTypes) {
-			return fmt.Errorf("不支持的语音格式: %s", mimeType)
-		}
+		return MaxVoiceSize, AllowedVoiceTypes, nil
 	case FileTypeFile:
-		maxSize = MaxFileSize
+		return MaxFileSize, nil, nil
 	default:
-		return fmt.Errorf("未知的文件类型: %s", fileType)
+		return 0, nil, fmt.Errorf("%w: %s", ErrUnknownFileType, fileType)
 	}
+}
 
-	if fileSize > maxSize {
-		return fmt.Errorf("文件大小超过限制，最大 %.1fMB", float64(maxSize)/(1024*1024))
+// checkFileType 验证文件类型和 MIME 类型，返回该类型的大小上限
+func (s *dbStorage) checkFileType(fileType, mimeType string) (int64, error) {
+	maxSize, allowed, err := fileTypeLimit(fileType)
+	if err != nil {
+		return 0, err
 	}
-
-	return nil
+	if allowed != nil && !isAllowedMimeType(mimeType, allowed) {
+		return 0, fmt.Errorf("%w: %s for %s", ErrUnsupportedMimeType, mimeType, fileType)
+	}
+	return maxSize, nil
 }
 
-// generateFileID 生成文件ID
-func generateFileID() string {
+// newFileID 生成文件ID，配置了 IDGenerator 时使用其生成的 ID，否则为随机 UUID
+func (s *dbStorage) newFileID() string {
+	if s.ids != nil {
+		return idgen.String(s.ids)
+	}
 	return uuid.New().String()
 }
 
@@ -309,46 +781,3 @@ func isAllowedMimeType(mimeType string, allowedTypes []string) bool {
 	}
 	return false
 }
-
-// detectMimeType 检测MIME类型
-func detectMimeType(fileName string, data []byte) string {
-	// 根据文件扩展名判断
-	ext := strings.ToLower(filepath.Ext(fileName))
-	switch ext {
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".png":
-		return "image/png"
-	case ".gif":
-		return "image/gif"
-	case ".webp":
-		return "image/webp"
-	case ".mp4":
-		return "video/mp4"
-	case ".mov":
-		return "video/quicktime"
-	case ".mp3":
-		return "audio/mpeg"
-	case ".wav":
-		return "audio/wav"
-	case ".ogg":
-		return "audio/ogg"
-	case ".m4a":
-		return "audio/mp4"
-	}
-
-	// 根据文件头魔数判断
-	if len(data) >= 4 {
-		if bytes.Equal(data[0:2], []byte{0xFF, 0xD8}) {
-			return "image/jpeg"
-		}
-		if bytes.Equal(data[0:4], []byte{0x89, 0x50, 0x4E, 0x47}) {
-			return "image/png"
-		}
-		if bytes.Equal(data[0:4], []byte{0x47, 0x49, 0x46, 0x38}) {
-			return "image/gif"
-		}
-	}
-
-	return "application/octet-stream"
-}
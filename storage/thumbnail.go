@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+
+	golog "github.com/bbadbeef/go-base/log"
+
+	// 注册图片解码器
+	_ "image/gif"
+	_ "image/png"
+)
+
+// maxThumbnailSourcePixels 生成缩略图时原图的最大像素数，避免解码超大图片占用过多内存
+const maxThumbnailSourcePixels = 25_000_000
+
+// ThumbnailConfig 缩略图配置，零值字段使用默认值
+// 支持 JPEG、PNG、GIF（取第一帧）图片；视频需提供 VideoPoster 截取封面帧
+type ThumbnailConfig struct {
+	MaxWidth    int             // 最大宽度（像素），默认 320
+	MaxHeight   int             // 最大高度（像素），默认 320
+	Quality     int             // JPEG 质量（1-100），默认 80
+	VideoPoster VideoPosterFunc // 视频封面帧截取（可选），为空时视频不生成缩略图，可使用 FFmpegPoster
+}
+
+// VideoPosterFunc 从本地视频文件截取封面帧
+type VideoPosterFunc func(ctx context.Context, videoPath string) (image.Image, error)
+
+// withDefaults 填充默认值
+func (c ThumbnailConfig) withDefaults() ThumbnailConfig {
+	if c.MaxWidth <= 0 {
+		c.MaxWidth = 320
+	}
+	if c.MaxHeight <= 0 {
+		c.MaxHeight = 320
+	}
+	if c.Quality <= 0 || c.Quality > 100 {
+		c.Quality = 80
+	}
+	return c
+}
+
+// FFmpegPoster 使用 ffmpeg 截取视频第 1 秒的帧作为封面，视频不足 1 秒时取第一帧
+// ffmpegPath 为 ffmpeg 可执行文件路径，为空时从 PATH 查找
+func FFmpegPoster(ffmpegPath string) VideoPosterFunc {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+	return func(ctx context.Context, videoPath string) (image.Image, error) {
+		for _, seek := range []string{"1", "0"} {
+			var stdout, stderr bytes.Buffer
+			cmd := exec.CommandContext(ctx, ffmpegPath, "-v", "error", "-ss", seek, "-i", videoPath,
+				"-frames:v", "1", "-f", "image2pipe", "-vcodec", "png", "-")
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			if err := cmd.Run(); err != nil {
+				return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+			}
+			if stdout.Len() > 0 {
+				img, _, err := image.Decode(&stdout)
+				return img, err
+			}
+		}
+		return nil, errors.New("ffmpeg: no video frame")
+	}
+}
+
+// GenerateThumbnail 为已上传的文件（重新）生成缩略图，用于补齐配置缩略图之前上传的文件或重试失败的生成
+func (s *dbStorage) GenerateThumbnail(fileID string) (*FileInfo, error) {
+	var dbFile DBFile
+	if err := s.files().Omit("file_data").Where("file_id = ? AND status = 1", fileID).First(&dbFile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	if err := s.generateThumbnail(&dbFile); err != nil {
+		return nil, err
+	}
+	return s.toFileInfo(&dbFile), nil
+}
+
+// autoThumbnail 上传后生成缩略图，未配置、文件类型不支持或文件为密文时跳过
+// 生成失败不影响上传，可稍后通过 GenerateThumbnail 重试
+func (s *dbStorage) autoThumbnail(dbFile *DBFile) {
+	if s.thumbnail == nil || dbFile.Encrypted {
+		return
+	}
+	if dbFile.FileType == FileTypeImage || dbFile.FileType == FileTypeVideo && s.thumbnail.VideoPoster != nil {
+		if err := s.generateThumbnail(dbFile); err != nil {
+			golog.WithFields(golog.FromContext(s.logger, s.ctx), golog.Fields{"file_id": dbFile.FileID, "err": err}).
+				Warn("storage: generate thumbnail failed, retry with GenerateThumbnail")
+		}
+	}
+}
+
+// generateThumbnail 生成缩略图并保存为派生文件，成功后设置 dbFile.ThumbnailID
+func (s *dbStorage) generateThumbnail(dbFile *DBFile) error {
+	if s.thumbnail == nil {
+		return fmt.Errorf("%w: thumbnail is not configured", ErrInvalidConfig)
+	}
+
+	var src image.Image
+	var err error
+	switch {
+	case dbFile.Encrypted:
+		return fmt.Errorf("%w: no thumbnail for encrypted file", ErrUnsupportedMimeType)
+	case dbFile.FileType == FileTypeImage:
+		src, err = s.decodeImage(dbFile)
+	case dbFile.FileType == FileTypeVideo && s.thumbnail.VideoPoster != nil:
+		src, err = s.videoPoster(dbFile)
+	default:
+		return fmt.Errorf("%w: no thumbnail for %s", ErrUnsupportedMimeType, dbFile.MimeType)
+	}
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	thumb := resizeImage(src, s.thumbnail.MaxWidth, s.thumbnail.MaxHeight)
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: s.thumbnail.Quality}); err != nil {
+		return fmt.Errorf("encode thumbnail failed: %w", err)
+	}
+
+	thumbFile := &DBFile{
+		FileName: "thumb_" + strings.TrimSuffix(dbFile.FileName, filepath.Ext(dbFile.FileName)) + ".jpg",
+		MimeType: "image/jpeg",
+		Width:    thumb.Bounds().Dx(),
+		Height:   thumb.Bounds().Dy(),
+	}
+	if err := s.saveDerived(dbFile, thumbFile, buf.Bytes()); err != nil {
+		return err
+	}
+
+	// 替换旧缩略图
+	old := dbFile.ThumbnailID
+	if err := s.files().Model(&DBFile{}).Where("file_id = ?", dbFile.FileID).Update("thumbnail_id", thumbFile.FileID).Error; err != nil {
+		return err
+	}
+	if old != "" {
+		s.files().Model(&DBFile{}).Where("file_id = ?", old).Updates(statusUpdate(2))
+	}
+	dbFile.ThumbnailID = thumbFile.FileID
+	return nil
+}
+
+// decodeImage 解码图片，像素数超过 maxThumbnailSourcePixels 时不解码
+func (s *dbStorage) decodeImage(dbFile *DBFile) (image.Image, error) {
+	rc, err := s.openContent(dbFile)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	cfg, format, err := image.DecodeConfig(rc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode %s failed: %v", ErrUnsupportedMimeType, dbFile.MimeType, err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxThumbnailSourcePixels {
+		return nil, fmt.Errorf("%w: image too large for thumbnail: %dx%d", ErrFileTooLarge, cfg.Width, cfg.Height)
+	}
+	if _, err := rc.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(rc)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s image failed: %w", format, err)
+	}
+	return img, nil
+}
+
+// videoPoster 将视频写入临时文件后截取封面帧
+func (s *dbStorage) videoPoster(dbFile *DBFile) (image.Image, error) {
+	var img image.Image
+	err := s.withLocalFile(dbFile, func(path string) (err error) {
+		img, err = s.thumbnail.VideoPoster(s.ctx, path)
+		return err
+	})
+	return img, err
+}
+
+// saveDerived 保存派生文件（如缩略图），derived 提供文件名、MIME 类型等，其余字段按源文件填充
+// 派生文件与源文件属于同一用户并记录源文件ID，内容存储方式与新上传的文件一致
+func (s *dbStorage) saveDerived(source, derived *DBFile, data []byte) error {
+	derived.TenantID = s.tenant
+	derived.FileID = s.newFileID()
+	derived.UserID = source.UserID
+	derived.SourceID = source.FileID
+	derived.FileType = FileTypeImage
+	derived.FileSize = int64(len(data))
+	derived.FileData = []byte{}
+	derived.Status = 1 // 正常
+
+	var cleanup func()
+	if s.objects != nil {
+		derived.ObjectKey = s.objectKey(derived.FileID)
+		if err := s.objects.Put(s.ctx, derived.ObjectKey, bytes.NewReader(data), derived.FileSize, derived.MimeType); err != nil {
+			return fmt.Errorf("save file to object storage failed: %w", err)
+		}
+		cleanup = func() { s.objects.Delete(s.ctx, derived.ObjectKey) }
+	} else {
+		derived.ChunkSize = fileChunkSize
+		cleanup = func() { s.deleteChunks(derived.FileID) }
+		if _, err := s.writeChunks(derived.FileID, bytes.NewReader(data)); err != nil {
+			cleanup()
+			return err
+		}
+	}
+
+	if err := s.files().Create(derived).Error; err != nil {
+		cleanup()
+		return fmt.Errorf("save file to database failed: %w", err)
+	}
+	return nil
+}
+
+// deleteDerived 删除源文件的缩略图、头像尺寸等派生文件
+func (s *dbStorage) deleteDerived(sourceIDs ...string) {
+	if len(sourceIDs) == 0 {
+		return
+	}
+	s.files().Model(&DBFile{}).Where("source_id IN ? AND status = 1", sourceIDs).Updates(statusUpdate(2))
+}
+
+// resizeImage 按区域平均等比缩小到 maxWidth x maxHeight 以内，不放大
+// 透明像素合成到白色背景上，便于编码为 JPEG
+func resizeImage(src image.Image, maxWidth, maxHeight int) *image.RGBA {
+	b := src.Bounds()
+	tw, th := fitSize(b.Dx(), b.Dy(), maxWidth, maxHeight)
+	return scaleImage(src, b, tw, th, true)
+}
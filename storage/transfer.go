@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// MigrateOptions 存储后端迁移选项，零值字段使用默认值
+type MigrateOptions struct {
+	Concurrency int                     // 并发复制的文件数，默认 4
+	BatchSize   int                     // 每批查询的文件数，默认 500
+	Verify      bool                    // 写入后从目标读回内容校验 SHA-256，会多读一遍目标
+	OnProgress  func(p MigrateProgress) // 每个文件处理完成后调用（可选），调用是串行的
+}
+
+// withDefaults 填充默认值
+func (o MigrateOptions) withDefaults() MigrateOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 500
+	}
+	return o
+}
+
+// MigrateProgress 迁移进度，FileID、Err 为刚处理的文件及其错误
+type MigrateProgress struct {
+	Total   int64
+	Copied  int64
+	Skipped int64
+	Failed  int64
+	Bytes   int64
+	FileID  string
+	Err     error
+}
+
+// MigrateReport 存储后端迁移结果
+type MigrateReport struct {
+	Total    int64             `json:"total"`    // 开始时源中的文件数
+	Copied   int64             `json:"copied"`   // 复制的文件数
+	Skipped  int64             `json:"skipped"`  // 目标中已存在而跳过的文件数（如上次迁移已复制）
+	Failed   int64             `json:"failed"`   // 复制失败的文件数，重新执行迁移时重试
+	Bytes    int64             `json:"bytes"`    // 复制的字节数
+	Failures map[string]string `json:"failures"` // 失败的文件ID和错误信息
+}
+
+// Migrate 将 from 中的文件复制到 to，用于更换存储后端（如从数据库迁移到对象存储）或迁移到新的数据库
+// 与 Storage.Migrate（表结构迁移）无关；from、to 需使用不同的表或数据库，源数据不修改，确认后将应用切换到 to 即可
+//
+// 复制正常和待扫描的文件（包括缩略图等派生文件）及加密文件的密钥信封，保留文件ID、上传者、上传时间等信息，
+// 内容按 SHA-256 校验并在目标中重新去重；已删除的文件和未完成的上传任务不复制
+// 目标中已存在的文件跳过，迁移中断或有文件失败后重新执行即可继续；ctx 取消时停止并返回已完成的结果
+func Migrate(ctx context.Context, from, to Storage, opts *MigrateOptions) (*MigrateReport, error) {
+	src, ok := from.WithContext(ctx).(*dbStorage)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported source storage %T", ErrInvalidConfig, from)
+	}
+	dst, ok := to.WithContext(ctx).(*dbStorage)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported target storage %T", ErrInvalidConfig, to)
+	}
+	if src.db == dst.db && src.fileTable == dst.fileTable && src.tenant == dst.tenant {
+		return nil, fmt.Errorf("%w: source and target use the same file table", ErrInvalidConfig)
+	}
+	var o MigrateOptions
+	if opts != nil {
+		o = *opts
+	}
+	o = o.withDefaults()
+
+	report := &MigrateReport{Failures: make(map[string]string)}
+	if err := src.files().Model(&DBFile{}).Where("status IN (1, 3)").Count(&report.Total).Error; err != nil {
+		return nil, err
+	}
+
+	// done 记录一个文件的结果并回调进度
+	var mu sync.Mutex
+	done := func(dbFile *DBFile, skipped bool, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case skipped:
+			report.Skipped++
+		case err != nil:
+			report.Failed++
+			report.Failures[dbFile.FileID] = err.Error()
+		default:
+			report.Copied++
+			report.Bytes += dbFile.FileSize
+		}
+		if o.OnProgress != nil {
+			o.OnProgress(MigrateProgress{
+				Total:   report.Total,
+				Copied:  report.Copied,
+				Skipped: report.Skipped,
+				Failed:  report.Failed,
+				Bytes:   report.Bytes,
+				FileID:  dbFile.FileID,
+				Err:     err,
+			})
+		}
+	}
+
+	jobs := make(chan *DBFile)
+	var wg sync.WaitGroup
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dbFile := range jobs {
+				done(dbFile, false, src.copyFile(dst, dbFile, o.Verify))
+			}
+		}()
+	}
+
+	err := src.dispatchFiles(ctx, dst, o.BatchSize, jobs, done)
+	close(jobs)
+	wg.Wait()
+	return report, err
+}
+
+// dispatchFiles 按批查询源中的文件，目标中已存在的直接记为跳过，其余交给复制协程
+func (s *dbStorage) dispatchFiles(ctx context.Context, dst *dbStorage, batchSize int, jobs chan<- *DBFile,
+	done func(dbFile *DBFile, skipped bool, err error)) error {
+	var lastID int64
+	for {
+		var batch []DBFile
+		if err := s.files().Omit("file_data").Where("id > ? AND status IN (1, 3)", lastID).
+			Order("id").Limit(batchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+
+		fileIDs := make([]string, len(batch))
+		for i := range batch {
+			fileIDs[i] = batch[i].FileID
+		}
+		var existing []string
+		if err := dst.files().Model(&DBFile{}).Where("file_id IN ?", fileIDs).Pluck("file_id", &existing).Error; err != nil {
+			return err
+		}
+		copied := make(map[string]bool, len(existing))
+		for _, fileID := range existing {
+			copied[fileID] = true
+		}
+
+		for i := range batch {
+			if copied[batch[i].FileID] {
+				done(&batch[i], true, nil)
+				continue
+			}
+			select {
+			case jobs <- &batch[i]:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// copyFile 将文件内容和记录复制到 dst，记录在内容写入并校验后保存，失败时清理已写入的内容
+func (s *dbStorage) copyFile(dst *dbStorage, src *DBFile, verify bool) error {
+	rc, err := s.openContent(src)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	hash := sha256.New()
+	body := io.TeeReader(rc, hash)
+
+	dbFile := *src
+	dbFile.ID = 0
+	dbFile.TenantID = dst.tenant
+	dbFile.FileData = []byte{}
+	dbFile.ObjectKey, dbFile.ChunkSize, dbFile.ContentHash, dbFile.ContentID = "", 0, "", ""
+
+	// 写入内容，上次中断时可能留有部分内容，对象直接覆盖，分块先删除
+	var size int64
+	var cleanup func()
+	if dst.objects != nil {
+		key := dst.objectKey(src.FileID)
+		dbFile.ObjectKey = key
+		cleanup = func() { dst.objects.Delete(dst.ctx, key) }
+		size, err = dst.putObject(key, body, src.FileSize, src.MimeType)
+	} else {
+		dbFile.ChunkSize = fileChunkSize
+		cleanup = func() { dst.deleteChunks(src.FileID) }
+		if err = dst.deleteChunks(src.FileID); err == nil {
+			size, err = dst.writeChunks(src.FileID, body)
+		}
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	switch {
+	case err != nil:
+	case size != src.FileSize:
+		err = fmt.Errorf("content size mismatch: expected %d bytes, read %d", src.FileSize, size)
+	case src.ContentHash != "" && sum != src.ContentHash:
+		err = fmt.Errorf("content checksum mismatch: expected %s, got %s", src.ContentHash, sum)
+	case verify:
+		var written string
+		if written, err = dst.hashContent(&dbFile); err == nil && written != sum {
+			err = fmt.Errorf("verify target content failed: expected %s, got %s", sum, written)
+		}
+	}
+	if err != nil {
+		cleanup()
+		return err
+	}
+
+	var keys []DBFileKey
+	if src.Encrypted {
+		if err := s.fileKeys().Where("file_id = ?", src.FileID).Find(&keys).Error; err != nil {
+			cleanup()
+			return err
+		}
+	}
+
+	// 保存记录，源文件参与去重时在目标中同样去重
+	var duplicate bool
+	err = dst.db.WithContext(dst.ctx).Transaction(func(tx *gorm.DB) error {
+		ts := dst.withDB(tx)
+		if src.ContentHash != "" {
+			var err error
+			if duplicate, err = ts.dedupe(&dbFile, sum); err != nil {
+				return err
+			}
+		}
+		if err := ts.files().Create(&dbFile).Error; err != nil {
+			return err
+		}
+		for _, key := range keys {
+			key.ID = 0
+			key.TenantID = dst.tenant
+			if err := ts.fileKeys().Create(&key).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		cleanup()
+		return fmt.Errorf("save file to target database failed: %w", err)
+	}
+	if duplicate {
+		cleanup()
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UploadTTL 断点续传上传任务的有效期，每次上传分片后顺延
+const UploadTTL = 24 * time.Hour
+
+// UploadSession 断点续传上传任务
+type UploadSession struct {
+	UploadID  string    `json:"upload_id"`           // 上传任务ID
+	FileName  string    `json:"file_name"`           // 原始文件名
+	FileType  string    `json:"file_type"`           // 文件类型
+	MimeType  string    `json:"mime_type,omitempty"` // MIME类型，为空时完成上传时识别
+	Encrypted bool      `json:"encrypted,omitempty"` // 内容为客户端加密的密文
+	UserID    int64     `json:"user_id"`             // 上传用户ID
+	TotalSize int64     `json:"total_size"`          // 文件总大小（字节）
+	Received  int64     `json:"received"`            // 已接收字节数，续传时从此处继续
+	ExpiresAt time.Time `json:"expires_at"`          // 过期时间
+}
+
+// uploadTableDB 上传任务表，不限定租户，用于表结构迁移
+func (s *dbStorage) uploadTableDB() *gorm.DB {
+	return s.db.WithContext(s.ctx).Table(s.uploadTable)
+}
+
+// uploads 上传任务表，限定当前租户
+func (s *dbStorage) uploads() *gorm.DB {
+	return s.uploadTableDB().Where("tenant_id = ?", s.tenant)
+}
+
+// withDB 返回使用 db（如事务）的存储实例
+func (s *dbStorage) withDB(db *gorm.DB) *dbStorage {
+	clone := *s
+	clone.db = db
+	return &clone
+}
+
+// InitiateUpload 创建断点续传上传任务，totalSize 为文件总大小
+// 提供 MimeType 时立即校验，否则在 CompleteUpload 时按文件名和文件头识别
+func (s *dbStorage) InitiateUpload(meta *UploadMeta, totalSize int64) (*UploadSession, error) {
+	if meta == nil || meta.TTL < 0 || totalSize <= 0 {
+		return nil, ErrInvalidUploadRequest
+	}
+	maxSize, _, err := fileTypeLimit(meta.FileType)
+	if err != nil {
+		return nil, err
+	}
+	if meta.MimeType != "" {
+		if _, err := s.checkFileType(meta.FileType, meta.MimeType); err != nil {
+			return nil, err
+		}
+	}
+	if totalSize > maxSize {
+		return nil, &FileTooLargeError{Size: totalSize, MaxSize: maxSize}
+	}
+	if err := s.checkQuota(meta.UserID, totalSize); err != nil {
+		return nil, err
+	}
+
+	upload := &DBUpload{
+		TenantID:  s.tenant,
+		UploadID:  uuid.New().String(),
+		FileID:    s.newFileID(),
+		UserID:    meta.UserID,
+		FileName:  meta.FileName,
+		FileType:  meta.FileType,
+		MimeType:  meta.MimeType,
+		Encrypted: meta.Encrypted,
+		FileTTL:   int64(meta.TTL / time.Second),
+		TotalSize: totalSize,
+		ChunkSize: fileChunkSize,
+		ExpiresAt: time.Now().Add(UploadTTL),
+	}
+	if err := s.uploads().Create(upload).Error; err != nil {
+		return nil, fmt.Errorf("create upload failed: %w", err)
+	}
+	return upload.toSession(), nil
+}
+
+// GetUpload 获取上传任务，客户端中断后据此获取已接收的字节数
+func (s *dbStorage) GetUpload(uploadID string) (*UploadSession, error) {
+	upload, err := s.findUpload(uploadID, false)
+	if err != nil {
+		return nil, err
+	}
+	return upload.toSession(), nil
+}
+
+// UploadPart 从 offset 处上传一个分片，offset 需不大于已接收的字节数
+// offset 小于已接收的字节数时（如上次响应丢失后重传）跳过已接收的部分，分片写入失败时整个分片回滚
+func (s *dbStorage) UploadPart(uploadID string, offset int64, r io.Reader) (*UploadSession, error) {
+	if r == nil || offset < 0 {
+		return nil, ErrInvalidUploadRequest
+	}
+
+	var session *UploadSession
+	err := s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		ts := s.withDB(tx)
+		upload, err := ts.findUpload(uploadID, true)
+		if err != nil {
+			return err
+		}
+		if offset > upload.Received {
+			return fmt.Errorf("%w: offset %d, received %d", ErrUploadOffsetMismatch, offset, upload.Received)
+		}
+
+		// 跳过已接收的部分
+		if skip := upload.Received - offset; skip > 0 {
+			if _, err := io.CopyN(io.Discard, r, skip); err != nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("read part failed: %w", err)
+			}
+		}
+
+		remaining := upload.TotalSize - upload.Received
+		n, err := ts.appendChunks(upload.FileID, upload.ChunkSize, upload.Received, &io.LimitedReader{R: r, N: remaining + 1})
+		if err != nil {
+			return err
+		}
+		if n > remaining {
+			return fmt.Errorf("%w: part exceeds total size %d", ErrInvalidUploadRequest, upload.TotalSize)
+		}
+
+		upload.Received += n
+		upload.ExpiresAt = time.Now().Add(UploadTTL)
+		if err := ts.uploads().Where("id = ?", upload.ID).Updates(map[string]interface{}{
+			"received":   upload.Received,
+			"expires_at": upload.ExpiresAt,
+		}).Error; err != nil {
+			return err
+		}
+		session = upload.toSession()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CompleteUpload 完成上传：校验已接收全部内容、识别并校验 MIME 类型和大小，生成文件
+// 校验失败时上传任务被删除；使用对象存储时内容从分块表转存到对象存储，去除图片元数据时内容重新写入
+func (s *dbStorage) CompleteUpload(uploadID string) (*FileInfo, error) {
+	start := time.Now()
+	info, err := s.completeUpload(uploadID)
+	var fileID, fileType string
+	var size int64
+	if info != nil {
+		fileID, fileType, size = info.FileID, info.FileType, info.FileSize
+	}
+	s.observe(OpUpload, fileID, fileType, size, start, err)
+	return info, err
+}
+
+// completeUpload 校验并保存上传任务的内容
+func (s *dbStorage) completeUpload(uploadID string) (*FileInfo, error) {
+	upload, err := s.findUpload(uploadID, false)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Received != upload.TotalSize {
+		return nil, fmt.Errorf("%w: received %d of %d bytes", ErrUploadIncomplete, upload.Received, upload.TotalSize)
+	}
+
+	// 按文件头识别并校验文件类型，密文无法识别，使用声明的类型
+	mimeType := declaredMimeType(upload.FileName, upload.MimeType)
+	if !upload.Encrypted {
+		head := make([]byte, sniffLen)
+		n, err := io.ReadFull(newChunkReader(s, upload.FileID, upload.TotalSize, upload.ChunkSize), head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+		mimeType, err = resolveMimeType(upload.FileType, upload.FileName, upload.MimeType, head[:n])
+		if err != nil {
+			s.AbortUpload(uploadID)
+			return nil, err
+		}
+	}
+	var maxSize int64
+	if err == nil {
+		maxSize, err = s.checkFileType(upload.FileType, mimeType)
+	}
+	if err == nil && upload.TotalSize > maxSize {
+		err = &FileTooLargeError{Size: upload.TotalSize, MaxSize: maxSize}
+	}
+	if err != nil {
+		s.AbortUpload(uploadID)
+		return nil, err
+	}
+	// 配额不足时保留上传任务，释放空间后可重试
+	if err := s.checkQuota(upload.UserID, upload.TotalSize); err != nil {
+		return nil, err
+	}
+
+	dbFile := &DBFile{
+		TenantID:  s.tenant,
+		FileID:    upload.FileID,
+		UserID:    upload.UserID,
+		FileName:  upload.FileName,
+		FileType:  upload.FileType,
+		MimeType:  mimeType,
+		FileSize:  upload.TotalSize,
+		FileData:  []byte{},
+		Encrypted: upload.Encrypted,
+		ExpiresAt: s.expiresAt(upload.FileType, time.Duration(upload.FileTTL)*time.Second),
+		Status:    1, // 正常
+	}
+
+	// 分块直接作为文件内容；使用对象存储时转存，去除图片元数据时重新写入
+	content := newChunkReader(s, upload.FileID, upload.TotalSize, upload.ChunkSize)
+	strip := s.stripMetadata && upload.FileType == FileTypeImage && !upload.Encrypted
+	cleanup := func() {}
+	switch {
+	case s.objects != nil:
+		key := s.objectKey(upload.FileID)
+		dbFile.ObjectKey = key
+		cleanup = func() { s.objects.Delete(s.ctx, key) }
+		if strip {
+			stripped := stripImageMetadata(mimeType, content)
+			dbFile.FileSize, err = s.putObject(key, stripped, maxSize, mimeType)
+			stripped.Close()
+		} else if err = s.objects.Put(s.ctx, key, content, upload.TotalSize, mimeType); err != nil {
+			err = fmt.Errorf("save file to object storage failed: %w", err)
+		}
+	case strip:
+		fileID := s.newFileID()
+		dbFile.FileID = fileID
+		dbFile.ChunkSize = fileChunkSize
+		cleanup = func() { s.deleteChunks(fileID) }
+		stripped := stripImageMetadata(mimeType, content)
+		dbFile.FileSize, err = s.writeChunks(fileID, stripped)
+		stripped.Close()
+	default:
+		dbFile.ChunkSize = upload.ChunkSize
+	}
+	var hash string
+	if err == nil {
+		s.fillMediaInfo(dbFile)
+		hash, err = s.hashContent(dbFile)
+	}
+	if err == nil {
+		err = s.scanUpload(dbFile)
+	}
+	if err != nil {
+		cleanup()
+		// 检出恶意内容时删除上传任务，扫描出错时保留以便重试
+		if errors.Is(err, ErrMalwareDetected) {
+			s.AbortUpload(uploadID)
+		}
+		return nil, err
+	}
+
+	var duplicate bool
+	err = s.db.WithContext(s.ctx).Transaction(func(tx *gorm.DB) error {
+		ts := s.withDB(tx)
+		// 按已接收字节数删除，避免与并发的 CompleteUpload、AbortUpload 重复生成文件
+		result := ts.uploads().Where("id = ? AND received = ?", upload.ID, upload.TotalSize).Delete(&DBUpload{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUploadNotFound
+		}
+		var err error
+		if duplicate, err = ts.dedupe(dbFile, hash); err != nil {
+			return err
+		}
+		if err := ts.files().Create(dbFile).Error; err != nil {
+			return fmt.Errorf("save file to database failed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// 并发完成时对象键相同，由成功的一方保留
+		if !errors.Is(err, ErrUploadNotFound) || dbFile.FileID != upload.FileID {
+			cleanup()
+		}
+		return nil, err
+	}
+
+	// 内容重复时删除刚写入的内容；分块表中的内容未作为文件内容时删除
+	if duplicate {
+		cleanup()
+	}
+	if dbFile.ChunkSize == 0 || dbFile.contentFileID() != upload.FileID {
+		s.deleteChunks(upload.FileID)
+	}
+	s.afterSave(dbFile)
+	return s.toFileInfo(dbFile), nil
+}
+
+// AbortUpload 取消上传任务，删除已接收的内容
+func (s *dbStorage) AbortUpload(uploadID string) error {
+	var upload DBUpload
+	if err := s.uploads().Where("upload_id = ?", uploadID).First(&upload).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUploadNotFound
+		}
+		return err
+	}
+	if err := s.uploads().Where("id = ?", upload.ID).Delete(&DBUpload{}).Error; err != nil {
+		return err
+	}
+	return s.deleteChunks(upload.FileID)
+}
+
+// CleanupExpiredUploads 删除已过期的上传任务及其内容，返回删除的任务数
+// 可由主应用定时调用
+func (s *dbStorage) CleanupExpiredUploads() (int64, error) {
+	var expired []DBUpload
+	if err := s.uploads().Select("id, file_id").Where("expires_at <= ?", time.Now()).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for _, upload := range expired {
+		if err := s.deleteChunks(upload.FileID); err != nil {
+			return count, err
+		}
+		if err := s.uploads().Where("id = ?", upload.ID).Delete(&DBUpload{}).Error; err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// findUpload 查询未过期的上传任务，forUpdate 为 true 时加行锁（需在事务中）
+func (s *dbStorage) findUpload(uploadID string, forUpdate bool) (*DBUpload, error) {
+	query := s.uploads().Where("upload_id = ? AND expires_at > ?", uploadID, time.Now())
+	if forUpdate {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var upload DBUpload
+	if err := query.First(&upload).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// toSession 转换为上传任务
+func (u *DBUpload) toSession() *UploadSession {
+	return &UploadSession{
+		UploadID:  u.UploadID,
+		FileName:  u.FileName,
+		FileType:  u.FileType,
+		MimeType:  u.MimeType,
+		Encrypted: u.Encrypted,
+		UserID:    u.UserID,
+		TotalSize: u.TotalSize,
+		Received:  u.Received,
+		ExpiresAt: u.ExpiresAt,
+	}
+}
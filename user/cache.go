@@ -0,0 +1,23 @@
+package user
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bbadbeef/go-base/user/internal/cache"
+)
+
+// UserCache 用户缓存，按 ID 查询用户（如 GetUserByID、GetUserProfile、令牌刷新）时优先读取，用户信息变更后删除
+type UserCache = cache.Store
+
+// NewLRUUserCache 创建进程内 LRU 用户缓存，size 为最多缓存的用户数（默认 10000），ttl 为缓存时长（默认 5 分钟）
+// 仅能删除本实例的缓存，多实例部署时其他实例在 ttl 内可能读到旧数据，需使用 NewRedisUserCache
+func NewLRUUserCache(size int, ttl time.Duration) UserCache {
+	return cache.NewLRUStore(size, ttl)
+}
+
+// NewRedisUserCache 创建 Redis 用户缓存，prefix 为键前缀（默认 "user:cache:"），ttl 为缓存时长（默认 5 分钟）
+func NewRedisUserCache(client redis.UniversalClient, prefix string, ttl time.Duration) UserCache {
+	return cache.NewRedisStore(client, prefix, ttl)
+}
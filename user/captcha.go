@@ -0,0 +1,23 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/bbadbeef/go-base/user/internal/captcha"
+)
+
+// NewRecaptchaVerifier 创建 Google reCAPTCHA 校验，用于 Config.CaptchaVerifier
+// minScore 为 v3 的最低分数（0-1），v2 传 0；client 为 nil 时使用默认客户端
+func NewRecaptchaVerifier(secret string, minScore float64, client *http.Client) CaptchaVerifier {
+	return captcha.NewSiteVerifier(captcha.RecaptchaEndpoint, secret, minScore, client)
+}
+
+// NewHCaptchaVerifier 创建 hCaptcha 校验，用于 Config.CaptchaVerifier
+func NewHCaptchaVerifier(secret string, client *http.Client) CaptchaVerifier {
+	return captcha.NewSiteVerifier(captcha.HCaptchaEndpoint, secret, 0, client)
+}
+
+// NewTurnstileVerifier 创建 Cloudflare Turnstile 校验，用于 Config.CaptchaVerifier
+func NewTurnstileVerifier(secret string, client *http.Client) CaptchaVerifier {
+	return captcha.NewSiteVerifier(captcha.TurnstileEndpoint, secret, 0, client)
+}
@@ -0,0 +1,60 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bbadbeef/go-base/config"
+)
+
+// fileConfig 配置文件中 user 段的字段，env 标签为覆盖该字段的环境变量
+type fileConfig struct {
+	TenantID               string            `json:"tenant_id" env:"USER_TENANT_ID"`
+	JWTSecret              string            `json:"jwt_secret" env:"USER_JWT_SECRET"`
+	JWTSigningKeyID        string            `json:"jwt_signing_key_id" env:"USER_JWT_SIGNING_KEY_ID"`
+	JWTIssuer              string            `json:"jwt_issuer" env:"USER_JWT_ISSUER"`
+	JWTAudience            string            `json:"jwt_audience" env:"USER_JWT_AUDIENCE"`
+	TokenDuration          config.Duration   `json:"token_duration" env:"USER_TOKEN_DURATION"`
+	RefreshTokenDuration   config.Duration   `json:"refresh_token_duration" env:"USER_REFRESH_TOKEN_DURATION"`
+	TablePrefix            string            `json:"table_prefix" env:"USER_TABLE_PREFIX"`
+	TableNames             map[string]string `json:"table_names"`
+	AutoMigrate            *bool             `json:"auto_migrate" env:"USER_AUTO_MIGRATE"`
+	TOTPIssuer             string            `json:"totp_issuer" env:"USER_TOTP_ISSUER"`
+	DeletionGracePeriod    config.Duration   `json:"deletion_grace_period" env:"USER_DELETION_GRACE_PERIOD"`
+	UsernameChangeCooldown config.Duration   `json:"username_change_cooldown" env:"USER_USERNAME_CHANGE_COOLDOWN"`
+}
+
+// LoadConfig 从 YAML / JSON 配置文件加载配置，读取文件的 user 段（没有时读取整个文档），可与 im、storage 共用一个文档：
+//
+//	user:
+//	  tenant_id: app-a
+//	  jwt_secret: secret            # 建议通过环境变量 USER_JWT_SECRET 设置
+//	  jwt_issuer: https://auth.example.com
+//	  token_duration: 2h            # 时长支持 "30m"、"720h" 或秒数
+//	  refresh_token_duration: 720h
+//	  table_prefix: app_
+//	  auto_migrate: false
+//
+// 环境变量覆盖文件中的值，变量名为 USER_ 加大写的字段名，如 USER_JWT_SECRET、USER_TOKEN_DURATION
+// 数据库连接、回调、缓存等无法序列化的配置需在返回的 Config 上设置后再调用 NewService
+func LoadConfig(path string) (*Config, error) {
+	var fc fileConfig
+	if err := config.Load(path, "user", &fc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return &Config{
+		TenantID:               fc.TenantID,
+		JWTSecret:              fc.JWTSecret,
+		JWTSigningKeyID:        fc.JWTSigningKeyID,
+		JWTIssuer:              fc.JWTIssuer,
+		JWTAudience:            fc.JWTAudience,
+		TokenDuration:          time.Duration(fc.TokenDuration),
+		RefreshTokenDuration:   time.Duration(fc.RefreshTokenDuration),
+		TablePrefix:            fc.TablePrefix,
+		TableNames:             fc.TableNames,
+		DisableAutoMigrate:     fc.AutoMigrate != nil && !*fc.AutoMigrate,
+		TOTPIssuer:             fc.TOTPIssuer,
+		DeletionGracePeriod:    time.Duration(fc.DeletionGracePeriod),
+		UsernameChangeCooldown: time.Duration(fc.UsernameChangeCooldown),
+	}, nil
+}
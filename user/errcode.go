@@ -0,0 +1,44 @@
+package user
+
+import "github.com/bbadbeef/go-base/errcode"
+
+// 将用户模块的错误绑定到统一错误码目录
+func init() {
+	errcode.Bind(ErrInvalidConfig, errcode.InvalidConfig)
+	errcode.Bind(ErrInvalidParam, errcode.InvalidParam)
+	errcode.Bind(ErrUserNotFound, errcode.UserNotFound)
+	errcode.Bind(ErrUserDisabled, errcode.UserDisabled)
+	errcode.Bind(ErrPhoneExists, errcode.PhoneExists)
+	errcode.Bind(ErrUsernameExists, errcode.UsernameExists)
+	errcode.Bind(ErrInvalidCredentials, errcode.InvalidCredentials)
+	errcode.Bind(ErrInvalidOldPassword, errcode.InvalidOldPassword)
+	errcode.Bind(ErrInvalidCode, errcode.InvalidCode)
+	errcode.Bind(ErrCodeNotFound, errcode.CodeNotFound)
+	errcode.Bind(ErrCodeUsed, errcode.CodeUsed)
+	errcode.Bind(ErrCodeExpired, errcode.CodeExpired)
+	errcode.Bind(ErrPasswordResetRequired, errcode.PasswordResetRequired)
+	errcode.Bind(ErrUsernameChangeTooFrequent, errcode.UsernameChangeLimited)
+	errcode.Bind(ErrInvalidToken, errcode.InvalidToken)
+	errcode.Bind(ErrTokenExpired, errcode.TokenExpired)
+	errcode.Bind(ErrInvalidRefreshToken, errcode.InvalidToken)
+	errcode.Bind(ErrJWTKeyNotFound, errcode.NotFound)
+	errcode.Bind(ErrJWTKeyExists, errcode.InvalidParam)
+	errcode.Bind(ErrJWTKeyInUse, errcode.InvalidParam)
+	errcode.Bind(ErrSessionNotFound, errcode.NotFound)
+	errcode.Bind(ErrDeviceNotFound, errcode.NotFound)
+	errcode.Bind(ErrTwoFactorNotEnabled, errcode.TwoFactorDisabled)
+	errcode.Bind(ErrTwoFactorAlreadyEnabled, errcode.TwoFactorEnabled)
+	errcode.Bind(ErrInvalidTwoFactorCode, errcode.InvalidTwoFactor)
+	errcode.Bind(ErrLoginLocked, errcode.LoginLocked)
+	errcode.Bind(ErrCaptchaRequired, errcode.CaptchaRequired)
+	errcode.Bind(ErrInvalidCaptcha, errcode.InvalidCaptcha)
+	errcode.Bind(ErrCodeRateLimited, errcode.CodeRateLimited)
+	errcode.Bind(ErrTooManyCodeAttempts, errcode.TooManyCodeAttempts)
+	errcode.Bind(ErrRoleNotFound, errcode.RoleNotFound)
+	errcode.Bind(ErrRoleExists, errcode.RoleExists)
+	errcode.Bind(ErrPermissionDenied, errcode.Forbidden)
+	errcode.Bind(ErrOAuthProviderNotFound, errcode.OAuthUnsupported)
+	errcode.Bind(ErrOAuthFailed, errcode.OAuthFailed)
+	errcode.Bind(ErrOAuthAlreadyBound, errcode.OAuthAlreadyBound)
+	errcode.Bind(ErrOAuthNotBound, errcode.OAuthNotBound)
+}
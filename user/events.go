@@ -0,0 +1,70 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bbadbeef/go-base/user/internal/webhook"
+)
+
+// Webhook 请求头，接收方使用 VerifyWebhookSignature 校验
+const (
+	WebhookEventHeader     = webhook.EventHeader
+	WebhookTimestampHeader = webhook.TimestampHeader
+	WebhookSignatureHeader = webhook.SignatureHeader
+)
+
+// WebhookConfig 用户事件 webhook 配置，零值字段使用默认值
+type WebhookConfig struct {
+	URL     string                            // 接收地址，事件以 JSON（UserEvent）POST 到该地址
+	Secret  string                            // 签名密钥（可选），设置后请求头携带 X-Webhook-Signature
+	Events  []string                          // 推送的事件类型（可选），如 {UserEventRegistered}，为空时推送全部事件
+	Client  *http.Client                      // HTTP 客户端（可选），默认 10 秒超时
+	Retries int                               // 网络错误、429 和 5xx 响应的重试次数，默认 2，小于 0 表示不重试
+	OnError func(event *UserEvent, err error) // 重试后仍失败时回调（可选），如记录日志
+}
+
+// NewWebhookEventHandler 创建推送用户事件的 webhook，用于 Config.UserEventHandlers 或 OnUserEvent
+// 推送在后台异步执行，不阻塞触发事件的请求
+func NewWebhookEventHandler(config WebhookConfig) UserEventHandler {
+	sender := webhook.NewSender(webhook.Config{
+		URL:     config.URL,
+		Secret:  config.Secret,
+		Client:  config.Client,
+		Retries: config.Retries,
+	})
+	var events map[string]struct{}
+	if len(config.Events) > 0 {
+		events = make(map[string]struct{}, len(config.Events))
+		for _, e := range config.Events {
+			events[e] = struct{}{}
+		}
+	}
+
+	return func(ctx context.Context, event *UserEvent) {
+		if events != nil {
+			if _, ok := events[event.Type]; !ok {
+				return
+			}
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			if config.OnError != nil {
+				config.OnError(event, err)
+			}
+			return
+		}
+		go func() {
+			if err := sender.Send(context.Background(), event.Type, body); err != nil && config.OnError != nil {
+				config.OnError(event, err)
+			}
+		}()
+	}
+}
+
+// VerifyWebhookSignature 校验 webhook 请求的签名，maxAge 为允许的最大时间差（如 5 分钟），0 表示不校验时间
+func VerifyWebhookSignature(secret string, header http.Header, body []byte, maxAge time.Duration) bool {
+	return webhook.Verify(secret, header.Get(webhook.TimestampHeader), header.Get(webhook.SignatureHeader), body, maxAge)
+}
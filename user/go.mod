@@ -3,12 +3,41 @@ module github.com/bbadbeef/go-base/user
 go 1.21
 
 require (
+	github.com/bbadbeef/go-base/config v0.0.0
+	github.com/bbadbeef/go-base/errcode v0.0.0
+	github.com/bbadbeef/go-base/log v0.0.0
+	github.com/bbadbeef/go-base/observability v0.0.0
 	github.com/golang-jwt/jwt/v5 v5.2.0
+	github.com/redis/go-redis/v9 v9.7.0
 	golang.org/x/crypto v0.18.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231212172506-995d672761c0
+	google.golang.org/grpc v1.60.1
+	google.golang.org/protobuf v1.31.0
 	gorm.io/gorm v1.25.5
 )
 
 require (
+	github.com/bbadbeef/go-base/idgen v0.0.0
+	github.com/bbadbeef/go-base/migrate v0.0.0
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/bbadbeef/go-base/errcode => ../errcode
+
+replace github.com/bbadbeef/go-base/observability => ../observability
+
+replace github.com/bbadbeef/go-base/log => ../log
+
+replace github.com/bbadbeef/go-base/config => ../config
+
+replace github.com/bbadbeef/go-base/idgen => ../idgen
+
+replace github.com/bbadbeef/go-base/migrate => ../migrate
@@ -0,0 +1,127 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/user"
+	"github.com/bbadbeef/go-base/user/grpcapi/userpb"
+)
+
+// 元数据键
+const (
+	AuthorizationKey = "authorization" // Bearer <访问令牌>
+	APIKeyKey        = "x-api-key"     // 服务密钥
+	DeviceIDKey      = "x-device-id"   // 客户端设备 ID（可选），记录到登录历史和会话
+)
+
+// publicMethods 无需认证的方法
+var publicMethods = map[string]struct{}{
+	userpb.UserService_Register_FullMethodName:      {},
+	userpb.UserService_Login_FullMethodName:         {},
+	userpb.UserService_ValidateToken_FullMethodName: {},
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	// APIKeys 可信服务的密钥（可选），通过 x-api-key 元数据传递，可按 ID 获取任意用户的完整信息
+	APIKeys []string
+	// PublicMethods 其他无需认证的方法全名（可选），如 "/app.v1.Catalog/List"，拦截器用于同一服务器上的其他服务时使用
+	PublicMethods []string
+}
+
+// serviceCallKey ctx 中服务密钥调用标记的键
+type serviceCallKey struct{}
+
+// IsServiceCall 请求是否使用服务密钥认证
+func IsServiceCall(ctx context.Context) bool {
+	ok, _ := ctx.Value(serviceCallKey{}).(bool)
+	return ok
+}
+
+// UnaryAuthInterceptor 认证拦截器，将客户端 IP、User-Agent 和设备 ID 写入 ctx 用于登录记录
+// 非公开方法需携带 x-api-key 服务密钥或 authorization: Bearer <访问令牌>，通过后可用 user.ClaimsFromContext 获取 claims
+// 同一服务器上的其他服务也可复用此拦截器校验用户的访问令牌
+func UnaryAuthInterceptor(svc user.Service, config AuthConfig) grpc.UnaryServerInterceptor {
+	public := make(map[string]struct{}, len(publicMethods)+len(config.PublicMethods))
+	for m := range publicMethods {
+		public[m] = struct{}{}
+	}
+	for _, m := range config.PublicMethods {
+		public[m] = struct{}{}
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = user.WithClientInfo(ctx, clientInfo(ctx, md))
+
+		if _, ok := public[info.FullMethod]; ok {
+			return handler(ctx, req)
+		}
+
+		if key := first(md, APIKeyKey); key != "" {
+			if !validAPIKey(config.APIKeys, key) {
+				return nil, toStatus(ctx, errcode.New(errcode.Unauthorized, requestLang(ctx)))
+			}
+			return handler(context.WithValue(ctx, serviceCallKey{}, true), req)
+		}
+
+		token := bearerToken(first(md, AuthorizationKey))
+		if token == "" {
+			return nil, toStatus(ctx, errcode.New(errcode.Unauthorized, requestLang(ctx)))
+		}
+		claims, err := svc.WithContext(ctx).ValidateToken(token)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		return handler(user.ContextWithClaims(ctx, claims), req)
+	}
+}
+
+// validAPIKey 按常量时间比较服务密钥
+func validAPIKey(keys []string, key string) bool {
+	valid := false
+	for _, k := range keys {
+		if k != "" && subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// clientInfo 从连接和元数据中获取客户端信息
+func clientInfo(ctx context.Context, md metadata.MD) user.ClientInfo {
+	info := user.ClientInfo{
+		UserAgent: first(md, "user-agent"),
+		DeviceID:  first(md, DeviceIDKey),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		info.IP = p.Addr.String()
+		if i := strings.LastIndex(info.IP, ":"); i > 0 {
+			info.IP = strings.Trim(info.IP[:i], "[]")
+		}
+	}
+	return info
+}
+
+// bearerToken 从 authorization 元数据中获取访问令牌
+func bearerToken(auth string) string {
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// first 获取元数据的第一个值
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
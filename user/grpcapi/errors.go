@@ -0,0 +1,81 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/user"
+)
+
+// ErrorDomain 错误详情 ErrorInfo 的 domain，reason 为 errcode 错误码
+const ErrorDomain = "go-base"
+
+// toStatus 按 errcode 将错误转换为 gRPC 状态，错误码写入 ErrorInfo 详情
+// 参数错误附带 BadRequest 字段详情，频率限制附带 RetryInfo 详情
+func toStatus(ctx context.Context, err error) error {
+	apiErr, ok := err.(*errcode.APIError)
+	if !ok {
+		apiErr = errcode.Resolve(err, requestLang(ctx))
+	}
+
+	st := status.New(grpcCode(apiErr.HTTPStatus), apiErr.Message)
+	if s, e := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: strconv.Itoa(int(apiErr.Code)),
+		Domain: ErrorDomain,
+	}); e == nil {
+		st = s
+	}
+
+	var paramErr *user.ParamError
+	if errors.As(err, &paramErr) {
+		if s, e := st.WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: paramErr.Field, Description: paramErr.Message}},
+		}); e == nil {
+			st = s
+		}
+	}
+	var rateErr *user.RateLimitError
+	if errors.As(err, &rateErr) && rateErr.RetryAfter > 0 {
+		if s, e := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(rateErr.RetryAfter)}); e == nil {
+			st = s
+		}
+	}
+	return st.Err()
+}
+
+// grpcCode HTTP 状态码对应的 gRPC 状态码
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	}
+	if httpStatus >= 400 && httpStatus < 500 {
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
+
+// requestLang 按 accept-language 元数据选择错误信息的语言
+func requestLang(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return errcode.LangFromHeader(first(md, "accept-language"))
+}
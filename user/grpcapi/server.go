@@ -0,0 +1,185 @@
+// Package grpcapi 以 gRPC 暴露用户服务（注册、登录、校验令牌、获取用户），供同一部署中的非 Go 服务使用
+// 接口定义见 userpb/user.proto，其他语言可据此生成客户端
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/bbadbeef/go-base/user"
+	"github.com/bbadbeef/go-base/user/grpcapi/userpb"
+)
+
+// Server gRPC 用户服务，需配合 UnaryAuthInterceptor 使用
+type Server struct {
+	userpb.UnimplementedUserServiceServer
+	svc user.Service
+}
+
+// NewServer 创建 gRPC 用户服务
+func NewServer(svc user.Service) *Server {
+	return &Server{svc: svc}
+}
+
+// Register 将用户服务注册到 gRPC 服务器
+//
+//	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(grpcapi.UnaryAuthInterceptor(svc, grpcapi.AuthConfig{})))
+//	grpcapi.Register(srv, svc)
+func Register(registrar grpc.ServiceRegistrar, svc user.Service) {
+	userpb.RegisterUserServiceServer(registrar, NewServer(svc))
+}
+
+// Register 手机号注册
+func (s *Server) Register(ctx context.Context, req *userpb.RegisterRequest) (*userpb.AuthResponse, error) {
+	u, tokens, err := s.svc.WithContext(ctx).Register(&user.RegisterRequest{
+		Phone:        req.GetPhone(),
+		Password:     req.GetPassword(),
+		Code:         req.GetCode(),
+		CaptchaToken: req.GetCaptchaToken(),
+	})
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &userpb.AuthResponse{User: toUser(u), Tokens: toTokenPair(tokens)}, nil
+}
+
+// Login 密码或验证码登录
+func (s *Server) Login(ctx context.Context, req *userpb.LoginRequest) (*userpb.AuthResponse, error) {
+	u, tokens, err := s.svc.WithContext(ctx).Login(&user.LoginRequest{
+		Account:      req.GetAccount(),
+		Password:     req.GetPassword(),
+		Code:         req.GetCode(),
+		CaptchaToken: req.GetCaptchaToken(),
+	})
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &userpb.AuthResponse{User: toUser(u), Tokens: toTokenPair(tokens)}, nil
+}
+
+// ValidateToken 校验访问令牌
+func (s *Server) ValidateToken(ctx context.Context, req *userpb.ValidateTokenRequest) (*userpb.ValidateTokenResponse, error) {
+	claims, err := s.svc.WithContext(ctx).ValidateToken(req.GetAccessToken())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	resp := &userpb.ValidateTokenResponse{
+		UserId:    claims.UserID,
+		Username:  claims.Username,
+		Phone:     claims.Phone,
+		SessionId: claims.SessionID,
+		Roles:     claims.Roles,
+		TenantId:  claims.TenantID,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.UnixMilli()
+	}
+	if len(claims.Custom) > 0 {
+		custom, err := structpb.NewStruct(claims.Custom)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		resp.Custom = custom
+	}
+	return resp, nil
+}
+
+// GetUser 获取用户信息，服务密钥调用或查询自己时返回完整信息，否则按隐私设置返回公开资料
+func (s *Server) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	svc := s.svc.WithContext(ctx)
+	userID := req.GetUserId()
+
+	if IsServiceCall(ctx) {
+		if userID == 0 {
+			return nil, toStatus(ctx, &user.ParamError{Field: "user_id", Message: "user_id is required"})
+		}
+		u, err := svc.GetUserByID(userID)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		return &userpb.GetUserResponse{User: toUser(u)}, nil
+	}
+
+	claims, ok := user.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, toStatus(ctx, user.ErrInvalidToken)
+	}
+	if userID == 0 || userID == claims.UserID {
+		u, err := svc.GetUserByID(claims.UserID)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		return &userpb.GetUserResponse{User: toUser(u)}, nil
+	}
+
+	profile, err := svc.GetUserProfileForViewer(claims.UserID, userID)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &userpb.GetUserResponse{User: profileToUser(profile)}, nil
+}
+
+// toUser 转换用户信息
+func toUser(u *user.User) *userpb.User {
+	if u == nil {
+		return nil
+	}
+	return &userpb.User{
+		Id:          u.ID,
+		Username:    u.Username,
+		Phone:       u.Phone,
+		PhoneRegion: u.PhoneRegion,
+		Nickname:    u.Nickname,
+		Avatar:      u.Avatar,
+		Email:       u.Email,
+		Gender:      int32(u.Gender),
+		Birthday:    stringValue(u.Birthday),
+		Signature:   u.Signature,
+		Status:      int32(u.Status),
+		LastSeenAt:  u.LastSeenAt,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		TenantId:    u.TenantID,
+	}
+}
+
+// profileToUser 转换公开资料
+func profileToUser(p *user.UserProfile) *userpb.User {
+	return &userpb.User{
+		Id:         p.ID,
+		Username:   p.Username,
+		Nickname:   p.Nickname,
+		Avatar:     p.Avatar,
+		Gender:     int32(p.Gender),
+		Signature:  p.Signature,
+		Phone:      p.Phone,
+		Birthday:   stringValue(p.Birthday),
+		LastSeenAt: p.LastSeenAt,
+	}
+}
+
+// toTokenPair 转换令牌
+func toTokenPair(t *user.TokenPair) *userpb.TokenPair {
+	if t == nil {
+		return nil
+	}
+	return &userpb.TokenPair{
+		AccessToken:       t.AccessToken,
+		RefreshToken:      t.RefreshToken,
+		AccessExpiresAt:   t.AccessExpiresAt,
+		RefreshExpiresAt:  t.RefreshExpiresAt,
+		RequiresTwoFactor: t.Requires2FA,
+		ChallengeToken:    t.ChallengeToken,
+	}
+}
+
+// stringValue 取字符串指针的值，nil 时为空
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
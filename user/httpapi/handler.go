@@ -0,0 +1,182 @@
+// Package httpapi 提供可直接挂载的用户 REST 接口（注册、登录、验证码、用户信息、密码）
+//
+//	mux.Handle("/api/user/", http.StripPrefix("/api/user", httpapi.NewHandler(svc, &httpapi.Config{CodeSender: sendSMS})))
+package httpapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bbadbeef/go-base/user"
+)
+
+// CodeSender 发送验证码（如短信），codeType 为 user.CodeType* 常量
+type CodeSender func(ctx context.Context, phone string, codeType int, code string) error
+
+// Config HTTP 接口配置，零值字段使用默认值
+type Config struct {
+	CodeSender   CodeSender // 发送验证码（可选），未设置且未开启 ExposeCode 时不提供 /code/send
+	ExposeCode   bool       // 在 /code/send 响应中返回验证码，仅用于开发测试
+	TrustProxy   bool       // 按 X-Forwarded-For / X-Real-IP 获取客户端 IP，仅在可信的反向代理之后开启
+	MaxBodyBytes int64      // 请求体大小上限，默认 1 MiB
+}
+
+// withDefaults 填充默认值
+func (c Config) withDefaults() Config {
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+	return c
+}
+
+// route 路由
+type route struct {
+	handler http.HandlerFunc
+	auth    bool // 需要访问令牌
+}
+
+// Handler 用户 REST 接口
+type Handler struct {
+	svc    user.Service
+	config Config
+	routes map[string]map[string]route // path -> method -> route
+	auth   func(http.Handler) http.Handler
+}
+
+// NewHandler 创建用户 REST 接口，config 为 nil 时使用默认值
+// 路由相对于挂载点，通常配合 http.StripPrefix 使用：
+//
+//	POST /register          注册
+//	POST /login             密码或验证码登录
+//	POST /login/code        验证码登录
+//	POST /login/2fa         两步验证
+//	POST /code/send         发送验证码
+//	POST /code/verify       校验验证码
+//	POST /token/refresh     刷新令牌
+//	POST /logout            退出登录
+//	POST /password/reset    通过验证码重置密码
+//	POST /password/change   修改密码（需登录）
+//	GET  /profile           获取自己的信息（需登录）
+//	PUT  /profile           更新自己的信息（需登录，也支持 PATCH）
+//	POST /profile/phone     更换手机号（需登录）
+//	POST /profile/username  修改用户名（需登录）
+//	GET  /users/{id}        获取其他用户的公开资料（需登录）
+func NewHandler(svc user.Service, config *Config) *Handler {
+	var cfg Config
+	if config != nil {
+		cfg = *config
+	}
+	h := &Handler{
+		svc:    svc,
+		config: cfg.withDefaults(),
+		routes: make(map[string]map[string]route),
+		auth:   user.RequireAuth(svc),
+	}
+
+	h.handle(http.MethodPost, "/register", false, h.register)
+	h.handle(http.MethodPost, "/login", false, h.login)
+	h.handle(http.MethodPost, "/login/code", false, h.loginWithCode)
+	h.handle(http.MethodPost, "/login/2fa", false, h.verifyTwoFactor)
+	if h.config.CodeSender != nil || h.config.ExposeCode {
+		h.handle(http.MethodPost, "/code/send", false, h.sendCode)
+	}
+	h.handle(http.MethodPost, "/code/verify", false, h.verifyCode)
+	h.handle(http.MethodPost, "/token/refresh", false, h.refreshToken)
+	h.handle(http.MethodPost, "/logout", false, h.logout)
+	h.handle(http.MethodPost, "/password/reset", false, h.resetPassword)
+	h.handle(http.MethodPost, "/password/change", true, h.changePassword)
+	h.handle(http.MethodGet, "/profile", true, h.getProfile)
+	h.handle(http.MethodPut, "/profile", true, h.updateProfile)
+	h.handle(http.MethodPatch, "/profile", true, h.updateProfile)
+	h.handle(http.MethodPost, "/profile/phone", true, h.changePhone)
+	h.handle(http.MethodPost, "/profile/username", true, h.changeUsername)
+	h.handle(http.MethodGet, "/users/", true, h.getUser)
+	return h
+}
+
+// handle 注册路由，以 / 结尾的路径按前缀匹配
+func (h *Handler) handle(method, path string, auth bool, handler http.HandlerFunc) {
+	if h.routes[path] == nil {
+		h.routes[path] = make(map[string]route)
+	}
+	h.routes[path][method] = route{handler: handler, auth: auth}
+}
+
+// ServeHTTP 分发请求，未知路径返回 404，方法不支持返回 405
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	methods, ok := h.match(r.URL.Path)
+	if !ok {
+		writeStatus(w, r, http.StatusNotFound, "not found")
+		return
+	}
+	rt, ok := methods[r.Method]
+	if !ok {
+		allowed := make([]string, 0, len(methods))
+		for m := range methods {
+			allowed = append(allowed, m)
+		}
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeStatus(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	r = r.WithContext(h.clientContext(r))
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxBodyBytes)
+	}
+	if rt.auth {
+		h.auth(rt.handler).ServeHTTP(w, r)
+		return
+	}
+	rt.handler(w, r)
+}
+
+// match 按路径查找路由，先精确匹配再按前缀匹配
+func (h *Handler) match(path string) (map[string]route, bool) {
+	if path == "" {
+		path = "/"
+	} else if path[0] != '/' {
+		path = "/" + path
+	}
+	if methods, ok := h.routes[path]; ok {
+		return methods, true
+	}
+	for p, methods := range h.routes {
+		if strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) && len(path) > len(p) {
+			return methods, true
+		}
+	}
+	return nil, false
+}
+
+// clientContext 将客户端 IP、User-Agent 和设备信息写入 ctx，登录时记录到登录历史和会话
+func (h *Handler) clientContext(r *http.Request) context.Context {
+	return user.WithClientInfo(r.Context(), user.ClientInfo{
+		IP:         h.clientIP(r),
+		UserAgent:  r.UserAgent(),
+		DeviceID:   r.Header.Get("X-Device-ID"),
+		DeviceName: r.Header.Get("X-Device-Name"),
+	})
+}
+
+// clientIP 客户端 IP，开启 TrustProxy 时优先使用代理转发的地址
+func (h *Handler) clientIP(r *http.Request) string {
+	if h.config.TrustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return strings.TrimSpace(ip)
+		}
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
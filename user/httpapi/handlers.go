@@ -0,0 +1,275 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bbadbeef/go-base/user"
+)
+
+// authResponse 注册、登录响应
+type authResponse struct {
+	User   *user.User      `json:"user"`
+	Tokens *user.TokenPair `json:"tokens"`
+}
+
+// decode 解析 JSON 请求体
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &user.ParamError{Field: "body", Message: "request body too large"}
+		}
+		if errors.Is(err, io.EOF) {
+			return &user.ParamError{Field: "body", Message: "request body is required"}
+		}
+		return &user.ParamError{Field: "body", Message: "invalid JSON body"}
+	}
+	return nil
+}
+
+// service 绑定请求 ctx 的用户服务
+func (h *Handler) service(r *http.Request) user.Service {
+	return h.svc.WithContext(r.Context())
+}
+
+// claims 当前登录用户，路由经过 RequireAuth 后一定存在
+func claims(r *http.Request) *user.JWTClaims {
+	c, _ := user.ClaimsFromContext(r.Context())
+	return c
+}
+
+// writeAuth 写入注册、登录结果
+func writeAuth(w http.ResponseWriter, r *http.Request, u *user.User, tokens *user.TokenPair, err error) {
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, &authResponse{User: u, Tokens: tokens})
+}
+
+// register 注册
+func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
+	var req user.RegisterRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	u, tokens, err := h.service(r).Register(&req)
+	writeAuth(w, r, u, tokens, err)
+}
+
+// login 密码或验证码登录，开启两步验证时返回 requires_2fa 和 challenge_token
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	var req user.LoginRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	u, tokens, err := h.service(r).Login(&req)
+	writeAuth(w, r, u, tokens, err)
+}
+
+// loginWithCode 验证码登录
+func (h *Handler) loginWithCode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Phone string `json:"phone"`
+		Code  string `json:"code"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	u, tokens, err := h.service(r).LoginWithCode(req.Phone, req.Code)
+	writeAuth(w, r, u, tokens, err)
+}
+
+// verifyTwoFactor 两步验证
+func (h *Handler) verifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	u, tokens, err := h.service(r).VerifyTwoFactor(req.ChallengeToken, req.Code)
+	writeAuth(w, r, u, tokens, err)
+}
+
+// sendCode 发送验证码，开启 ExposeCode 时在响应中返回验证码
+func (h *Handler) sendCode(w http.ResponseWriter, r *http.Request) {
+	var req user.SendCodeRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	code, err := h.service(r).SendVerificationCode(&req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if h.config.CodeSender != nil {
+		if err := h.config.CodeSender(r.Context(), req.Phone, req.Type, code); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	if h.config.ExposeCode {
+		writeData(w, r, map[string]string{"code": code})
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// verifyCode 校验验证码
+func (h *Handler) verifyCode(w http.ResponseWriter, r *http.Request) {
+	var req user.VerifyCodeRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.service(r).VerifyCode(&req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// refreshToken 刷新令牌
+func (h *Handler) refreshToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	tokens, err := h.service(r).RefreshToken(req.RefreshToken)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, tokens)
+}
+
+// logout 退出登录，吊销刷新令牌对应的会话
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.service(r).Logout(req.RefreshToken); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// resetPassword 通过验证码重置密码
+func (h *Handler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var req user.ResetPasswordRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.service(r).ResetPassword(&req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// changePassword 修改密码
+func (h *Handler) changePassword(w http.ResponseWriter, r *http.Request) {
+	var req user.ChangePasswordRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req.SessionID = claims(r).SessionID
+	if err := h.service(r).ChangePassword(claims(r).UserID, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// getProfile 获取自己的信息
+func (h *Handler) getProfile(w http.ResponseWriter, r *http.Request) {
+	u, err := h.service(r).GetUserByID(claims(r).UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, u)
+}
+
+// updateProfile 更新自己的信息，只更新请求中出现的字段
+func (h *Handler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	var req user.UpdateProfileRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	u, err := h.service(r).UpdateProfile(claims(r).UserID, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, u)
+}
+
+// changePhone 更换手机号，验证码发送到新手机号
+func (h *Handler) changePhone(w http.ResponseWriter, r *http.Request) {
+	var req user.ChangePhoneRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.service(r).ChangePhone(claims(r).UserID, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// changeUsername 修改用户名
+func (h *Handler) changeUsername(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.service(r).ChangeUsername(claims(r).UserID, req.Username); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, nil)
+}
+
+// getUser 按隐私设置获取其他用户的公开资料
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/"), "users/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, &user.ParamError{Field: "id", Message: "invalid user id"})
+		return
+	}
+	profile, err := h.service(r).GetUserProfileForViewer(claims(r).UserID, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeData(w, r, profile)
+}
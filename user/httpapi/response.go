@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/bbadbeef/go-base/errcode"
+	"github.com/bbadbeef/go-base/user"
+)
+
+// Response 统一的 JSON 响应，成功时 code 为 0
+type Response struct {
+	Code    errcode.Code `json:"code"`
+	Message string       `json:"message"`
+	Data    interface{}  `json:"data,omitempty"`
+	Field   string       `json:"field,omitempty"` // 参数错误的字段
+}
+
+// requestLang 请求的语言
+func requestLang(r *http.Request) string {
+	return errcode.LangFromHeader(r.Header.Get("Accept-Language"))
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeData 写入成功响应
+func writeData(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJSON(w, http.StatusOK, &Response{
+		Code:    errcode.OK,
+		Message: errcode.Message(errcode.OK, requestLang(r)),
+		Data:    data,
+	})
+}
+
+// writeError 按 errcode 写入错误响应，参数错误附带字段，频率限制设置 Retry-After
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := errcode.Resolve(err, requestLang(r))
+	resp := &Response{Code: apiErr.Code, Message: apiErr.Message}
+
+	var paramErr *user.ParamError
+	if errors.As(err, &paramErr) {
+		resp.Field = paramErr.Field
+		resp.Message = paramErr.Message
+	}
+	var rateErr *user.RateLimitError
+	if errors.As(err, &rateErr) && rateErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+	}
+	writeJSON(w, apiErr.HTTPStatus, resp)
+}
+
+// writeStatus 写入路由错误（404、405），错误码为对应的通用错误码
+func writeStatus(w http.ResponseWriter, r *http.Request, status int, message string) {
+	code := errcode.NotFound
+	if status != http.StatusNotFound {
+		code = errcode.InvalidParam
+	}
+	writeJSON(w, status, &Response{Code: code, Message: message})
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// 进程内缓存默认值
+const (
+	DefaultLRUSize = 10000
+	DefaultTTL     = 5 * time.Minute
+)
+
+// lruEntry 缓存项
+type lruEntry struct {
+	user     *model.User
+	expireAt time.Time
+}
+
+// LRUStore 进程内 LRU 缓存，超出容量时淘汰最久未使用的用户
+type LRUStore struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	order *list.List
+	items map[int64]*list.Element
+}
+
+// NewLRUStore 创建进程内 LRU 缓存，size 为 0 时缓存 10000 个用户，ttl 为 0 时缓存 5 分钟
+func NewLRUStore(size int, ttl time.Duration) *LRUStore {
+	if size <= 0 {
+		size = DefaultLRUSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &LRUStore{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[int64]*list.Element),
+	}
+}
+
+// Get 获取缓存的用户
+func (s *LRUStore) Get(ctx context.Context, id int64) (*model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[id]
+	if !ok {
+		return nil, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if !time.Now().Before(entry.expireAt) {
+		s.remove(elem)
+		return nil, nil
+	}
+	s.order.MoveToFront(elem)
+	return clone(entry.user), nil
+}
+
+// Set 缓存用户
+func (s *LRUStore) Set(ctx context.Context, user *model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &lruEntry{user: clone(user), expireAt: time.Now().Add(s.ttl)}
+	if elem, ok := s.items[user.ID]; ok {
+		elem.Value = entry
+		s.order.MoveToFront(elem)
+		return nil
+	}
+	s.items[user.ID] = s.order.PushFront(entry)
+	if s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Delete 删除缓存
+func (s *LRUStore) Delete(ctx context.Context, ids ...int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if elem, ok := s.items[id]; ok {
+			s.remove(elem)
+		}
+	}
+	return nil
+}
+
+// remove 移除缓存项，调用方需持有锁
+func (s *LRUStore) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.items, elem.Value.(*lruEntry).user.ID)
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// redisUser Redis 中保存的用户，model.User 序列化时不含密码哈希
+type redisUser struct {
+	*model.User
+	PasswordHash string `json:"password_hash"`
+}
+
+// RedisStore 基于 Redis 的用户缓存，适用于多实例部署
+// 用户以 JSON 保存在 {prefix}{id}，包含密码哈希，Redis 需限制访问
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisStore 创建 Redis 用户缓存，prefix 为空时使用 "user:cache:"，ttl 为 0 时缓存 5 分钟
+func NewRedisStore(client redis.UniversalClient, prefix string, ttl time.Duration) *RedisStore {
+	if prefix == "" {
+		prefix = "user:cache:"
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &RedisStore{client: client, prefix: prefix, ttl: ttl}
+}
+
+// key 用户缓存键
+func (s *RedisStore) key(id int64) string {
+	return s.prefix + strconv.FormatInt(id, 10)
+}
+
+// Get 获取缓存的用户
+func (s *RedisStore) Get(ctx context.Context, id int64) (*model.User, error) {
+	data, err := s.client.Get(ctx, s.key(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached := redisUser{User: &model.User{}}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	cached.User.PasswordHash = cached.PasswordHash
+	return cached.User, nil
+}
+
+// Set 缓存用户
+func (s *RedisStore) Set(ctx context.Context, user *model.User) error {
+	data, err := json.Marshal(redisUser{User: user, PasswordHash: user.PasswordHash})
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.key(user.ID), data, s.ttl).Err()
+}
+
+// Delete 删除缓存
+func (s *RedisStore) Delete(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, s.key(id))
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,31 @@
+// Package cache 用户缓存，减少按 ID 查询用户时的数据库访问
+package cache
+
+import (
+	"context"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// Store 用户缓存
+// 多实例部署时进程内缓存只能删除本实例的数据，其他实例在过期前可能读到旧数据，需使用共享存储（如 Redis）
+type Store interface {
+	// Get 获取缓存的用户，未命中时返回 nil, nil
+	Get(ctx context.Context, id int64) (*model.User, error)
+
+	// Set 缓存用户
+	Set(ctx context.Context, user *model.User) error
+
+	// Delete 删除缓存，用户信息变更后调用
+	Delete(ctx context.Context, ids ...int64) error
+}
+
+// clone 复制用户，避免调用方修改缓存中的数据
+func clone(user *model.User) *model.User {
+	u := *user
+	if user.Birthday != nil {
+		birthday := *user.Birthday
+		u.Birthday = &birthday
+	}
+	return &u
+}
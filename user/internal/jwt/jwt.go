@@ -1,80 +1,303 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// 错误定义，可通过 errors.Is 判断
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
 // Claims JWT claims
 type Claims struct {
-	UserID   int64  `json:"user_id"`
-	Username string `json:"username"`
-	Phone    string `json:"phone"`
+	UserID    int64    `json:"user_id"`
+	Username  string   `json:"username"`
+	Phone     string   `json:"phone"`
+	SessionID string   `json:"sid,omitempty"`   // 会话 ID，与刷新令牌对应
+	Roles     []string `json:"roles,omitempty"` // 签发时用户拥有的角色
+	TenantID  string   `json:"tid,omitempty"`   // 租户 ID，未启用多租户时为空
+
+	// Custom 自定义 claims，如组织 ID，由签发时的 claims 回调写入
+	// 验证后按 JSON 解码，数字为 float64
+	Custom map[string]interface{} `json:"custom,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// JWTManager JWT管理器
+// HasRole 令牌中是否包含任一指定角色
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, have := range c.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// JWTManager JWT管理器，支持多个密钥同时生效，按令牌头部 kid 选择验证密钥
 type JWTManager struct {
-	secretKey     string
+	mu            sync.RWMutex
+	keys          map[string]*Key
+	signingKeyID  string
 	tokenDuration time.Duration
+	issuer        string
+	audience      string
+	tenant        string
 }
 
-// NewJWTManager 创建JWT管理器
+// NewJWTManager 创建使用单个 HS256 密钥的JWT管理器，签发的令牌不含 kid
 func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	return &JWTManager{
-		secretKey:     secretKey,
+		keys: map[string]*Key{
+			"": {Algorithm: AlgHS256, Secret: []byte(secretKey)},
+		},
 		tokenDuration: tokenDuration,
 	}
 }
 
-// GenerateToken 生成token
-func (m *JWTManager) GenerateToken(userID int64, username, phone string) (string, error) {
+// NewJWTManagerWithKeys 创建使用多个密钥的JWT管理器，signingKeyID 为签发令牌使用的密钥
+// ID 为空的密钥用于验证不含 kid 的令牌（如迁移前签发的令牌）
+func NewJWTManagerWithKeys(keys []Key, signingKeyID string, tokenDuration time.Duration) (*JWTManager, error) {
+	m := &JWTManager{
+		keys:          make(map[string]*Key, len(keys)),
+		tokenDuration: tokenDuration,
+	}
+	for _, key := range keys {
+		if err := m.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.SetSigningKey(signingKeyID); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AddKey 添加密钥，添加后即可用于验证令牌
+// 轮换时先在所有实例上添加新密钥，再切换签名密钥，避免其他实例无法验证新令牌
+func (m *JWTManager) AddKey(key Key) error {
+	k, err := key.normalize()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[k.ID]; ok {
+		return fmt.Errorf("%w: %q", ErrKeyExists, k.ID)
+	}
+	m.keys[k.ID] = k
+	return nil
+}
+
+// SetSigningKey 切换签发令牌使用的密钥，密钥须已添加且含私钥
+func (m *JWTManager) SetSigningKey(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[id]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+	if !k.canSign() {
+		return fmt.Errorf("jwt key %q has no private key", id)
+	}
+	m.signingKeyID = id
+	return nil
+}
+
+// RemoveKey 移除密钥，使用该密钥签发的令牌随即失效，不能移除当前签名密钥
+// 应在旧令牌全部过期后再移除
+func (m *JWTManager) RemoveKey(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+	if id == m.signingKeyID {
+		return fmt.Errorf("%w: %q", ErrKeyInUse, id)
+	}
+	delete(m.keys, id)
+	return nil
+}
+
+// SetIssuer 设置签发方和受众，签发时写入 iss、aud，验证时要求一致，为空时不写入也不校验
+// 需在签发令牌前调用
+func (m *JWTManager) SetIssuer(issuer, audience string) {
+	m.issuer = issuer
+	m.audience = audience
+}
+
+// SetTenant 设置租户 ID，签发时写入 tid，验证时要求一致，其他租户签发的令牌视为无效
+// 需在签发令牌前调用
+func (m *JWTManager) SetTenant(tenant string) {
+	m.tenant = tenant
+}
+
+// registeredClaims 按有效期生成标准 claims
+func (m *JWTManager) registeredClaims(now time.Time, ttl time.Duration) jwt.RegisteredClaims {
+	claims := jwt.RegisteredClaims{
+		Issuer:    m.issuer,
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+	if m.audience != "" {
+		claims.Audience = jwt.ClaimStrings{m.audience}
+	}
+	return claims
+}
+
+// parse 验证令牌签名和标准 claims
+func (m *JWTManager) parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	var opts []jwt.ParserOption
+	if m.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(m.issuer))
+	}
+	if m.audience != "" {
+		opts = append(opts, jwt.WithAudience(m.audience))
+	}
+	return jwt.ParseWithClaims(tokenString, claims, m.keyFunc, opts...)
+}
+
+// SigningKeyID 当前签名密钥 ID
+func (m *JWTManager) SigningKeyID() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.signingKeyID
+}
+
+// JWKS 返回 RS256、EdDSA 密钥的公钥集合，按密钥 ID 排序，HS256 密钥不公开
+func (m *JWTManager) JWKS() *JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	set := &JWKS{Keys: []JWK{}}
+	for _, k := range m.keys {
+		if jwk, ok := k.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// sign 使用当前签名密钥签发令牌，密钥 ID 非空时写入头部 kid
+func (m *JWTManager) sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	k := m.keys[m.signingKeyID]
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(k.method(), claims)
+	if k.ID != "" {
+		token.Header["kid"] = k.ID
+	}
+	return token.SignedString(k.signKey())
+}
+
+// keyFunc 按令牌头部 kid 选择验证密钥，并校验签名算法与密钥一致
+func (m *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	var id string
+	if kid, ok := token.Header["kid"]; ok {
+		if id, ok = kid.(string); !ok {
+			return nil, errors.New("invalid kid header")
+		}
+	}
+
+	m.mu.RLock()
+	k, ok := m.keys[id]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", id)
+	}
+	if token.Method.Alg() != k.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return k.verifyKey(), nil
+}
+
+// GenerateToken 生成访问令牌，返回令牌及其过期时间，custom 可为 nil
+func (m *JWTManager) GenerateToken(userID int64, username, phone, sessionID string, roles []string, custom map[string]interface{}) (string, time.Time, error) {
 	now := time.Now()
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Phone:    phone,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.tokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-		},
+		UserID:           userID,
+		Username:         username,
+		Phone:            phone,
+		SessionID:        sessionID,
+		Roles:            roles,
+		TenantID:         m.tenant,
+		Custom:           custom,
+		RegisteredClaims: m.registeredClaims(now, m.tokenDuration),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secretKey))
+	signed, err := m.sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, claims.ExpiresAt.Time, nil
 }
 
 // ValidateToken 验证token
 func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(m.secretKey), nil
-	})
+	token, err := m.parse(tokenString, &Claims{})
 
 	if err != nil {
-		return nil, fmt.Errorf("invalid token: %w", err)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, fmt.Errorf("%w: %w", ErrTokenExpired, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
 	}
 
 	claims, ok := token.Claims.(*Claims)
 	if !ok || !token.Valid {
-		return nil, fmt.Errorf("invalid token claims")
+		return nil, fmt.Errorf("%w: invalid claims", ErrInvalidToken)
+	}
+	if claims.TenantID != m.tenant {
+		return nil, fmt.Errorf("%w: tenant mismatch", ErrInvalidToken)
 	}
 
 	return claims, nil
 }
 
-// RefreshToken 刷新token
-func (m *JWTManager) RefreshToken(tokenString string) (string, error) {
-	claims, err := m.ValidateToken(tokenString)
-	if err != nil {
-		return "", err
+// challengeClaims 两步验证挑战令牌 claims
+type challengeClaims struct {
+	UserID  int64  `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// purposeTwoFactor 两步验证挑战令牌用途
+const purposeTwoFactor = "2fa"
+
+// GenerateChallengeToken 生成两步验证挑战令牌
+// 挑战令牌不含会话 ID，不能作为访问令牌使用
+func (m *JWTManager) GenerateChallengeToken(userID int64, ttl time.Duration) (string, error) {
+	claims := &challengeClaims{
+		UserID:           userID,
+		Purpose:          purposeTwoFactor,
+		RegisteredClaims: m.registeredClaims(time.Now(), ttl),
 	}
+	return m.sign(claims)
+}
 
-	// 生成新token
-	return m.GenerateToken(claims.UserID, claims.Username, claims.Phone)
+// ValidateChallengeToken 验证两步验证挑战令牌，返回用户 ID
+func (m *JWTManager) ValidateChallengeToken(tokenString string) (int64, error) {
+	claims := &challengeClaims{}
+	_, err := m.parse(tokenString, claims)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return 0, fmt.Errorf("%w: %w", ErrTokenExpired, err)
+		}
+		return 0, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if claims.Purpose != purposeTwoFactor || claims.UserID == 0 {
+		return 0, fmt.Errorf("%w: not a challenge token", ErrInvalidToken)
+	}
+	return claims.UserID, nil
 }
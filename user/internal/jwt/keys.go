@@ -0,0 +1,146 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// 密钥管理错误
+var (
+	ErrKeyNotFound = errors.New("jwt key not found")
+	ErrKeyExists   = errors.New("jwt key already exists")
+	ErrKeyInUse    = errors.New("jwt key is used for signing")
+)
+
+// Key 签名密钥
+type Key struct {
+	ID         string            // 密钥 ID，签发时写入令牌头部 kid，验证时据此选择密钥
+	Algorithm  string            // HS256（默认）、RS256 或 EdDSA
+	Secret     []byte            // HS256 密钥
+	PrivateKey crypto.PrivateKey // RS256 为 *rsa.PrivateKey，EdDSA 为 ed25519.PrivateKey；仅用于验证的密钥可不设置
+	PublicKey  crypto.PublicKey  // RS256 为 *rsa.PublicKey，EdDSA 为 ed25519.PublicKey；为 nil 时从 PrivateKey 获取
+}
+
+// normalize 校验密钥并补全算法和公钥
+func (k Key) normalize() (*Key, error) {
+	if k.Algorithm == "" {
+		k.Algorithm = AlgHS256
+	}
+	switch k.Algorithm {
+	case AlgHS256:
+		if len(k.Secret) == 0 {
+			return nil, fmt.Errorf("jwt key %q: secret is required for HS256", k.ID)
+		}
+	case AlgRS256:
+		if priv, ok := k.PrivateKey.(*rsa.PrivateKey); ok {
+			k.PublicKey = &priv.PublicKey
+		} else if k.PrivateKey != nil {
+			return nil, fmt.Errorf("jwt key %q: RS256 private key must be *rsa.PrivateKey", k.ID)
+		}
+		if _, ok := k.PublicKey.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwt key %q: RS256 requires *rsa.PrivateKey or *rsa.PublicKey", k.ID)
+		}
+	case AlgEdDSA:
+		if priv, ok := k.PrivateKey.(ed25519.PrivateKey); ok {
+			k.PublicKey = priv.Public()
+		} else if k.PrivateKey != nil {
+			return nil, fmt.Errorf("jwt key %q: EdDSA private key must be ed25519.PrivateKey", k.ID)
+		}
+		if _, ok := k.PublicKey.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("jwt key %q: EdDSA requires ed25519.PrivateKey or ed25519.PublicKey", k.ID)
+		}
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported algorithm %q", k.ID, k.Algorithm)
+	}
+	return &k, nil
+}
+
+// canSign 是否可用于签发令牌
+func (k *Key) canSign() bool {
+	return k.Algorithm == AlgHS256 || k.PrivateKey != nil
+}
+
+// method 签名方法
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// signKey 签名使用的密钥
+func (k *Key) signKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.Secret
+	}
+	return k.PrivateKey
+}
+
+// verifyKey 验证签名使用的密钥
+func (k *Key) verifyKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.Secret
+	}
+	return k.PublicKey
+}
+
+// JWK 公钥（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // EdDSA 曲线
+	X   string `json:"x,omitempty"`   // EdDSA 公钥
+}
+
+// JWKS 公钥集合，其他服务可据此验证令牌，无需共享密钥
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwk 转换为 JWK，HS256 密钥不公开
+func (k *Key) jwk() (JWK, bool) {
+	enc := base64.RawURLEncoding
+	switch pub := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.ID,
+			Alg: k.Algorithm,
+			Use: "sig",
+			N:   enc.EncodeToString(pub.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.ID,
+			Alg: k.Algorithm,
+			Use: "sig",
+			Crv: "Ed25519",
+			X:   enc.EncodeToString(pub),
+		}, true
+	}
+	return JWK{}, false
+}
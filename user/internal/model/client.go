@@ -0,0 +1,27 @@
+package model
+
+import "context"
+
+// ClientInfo 发起请求的客户端信息，登录时记录到会话
+type ClientInfo struct {
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+	DeviceID   string `json:"device_id"`
+	DeviceName string `json:"device_name"`
+
+	// CaptchaPassed 调用方已完成人机验证，登录失败次数较多时需要
+	CaptchaPassed bool `json:"captcha_passed"`
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo 将客户端信息绑定到 ctx
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFrom 获取 ctx 中的客户端信息，未设置时返回零值
+func ClientInfoFrom(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 错误定义，可通过 errors.Is 判断
+var (
+	ErrInvalidConfig      = errors.New("invalid config")
+	ErrInvalidParam       = errors.New("invalid parameter")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserDisabled       = errors.New("user is disabled")
+	ErrPhoneExists        = errors.New("phone already exists")
+	ErrUsernameExists     = errors.New("username already exists")
+	ErrInvalidCredentials = errors.New("invalid account or password")
+	ErrInvalidOldPassword = errors.New("invalid old password")
+	ErrCodeNotFound       = errors.New("verification code not found or expired")
+	ErrCodeUsed           = errors.New("verification code already used")
+	ErrCodeExpired        = errors.New("verification code expired")
+	ErrInvalidCode        = errors.New("invalid verification code")
+
+	ErrPasswordResetRequired = errors.New("password reset required")
+
+	ErrUsernameChangeTooFrequent = errors.New("username changed too recently")
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionNotFound     = errors.New("session not found or revoked")
+	ErrDeviceNotFound      = errors.New("device not found")
+
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+
+	ErrLoginLocked     = errors.New("too many failed login attempts")
+	ErrCaptchaRequired = errors.New("captcha required")
+	ErrInvalidCaptcha  = errors.New("captcha verification failed")
+
+	ErrCodeRateLimited     = errors.New("verification code requested too frequently")
+	ErrTooManyCodeAttempts = errors.New("too many verification attempts, please request a new code")
+
+	ErrRoleNotFound     = errors.New("role not found")
+	ErrRoleExists       = errors.New("role already exists")
+	ErrPermissionDenied = errors.New("permission denied")
+
+	ErrOAuthProviderNotFound = errors.New("oauth provider not found")
+	ErrOAuthFailed           = errors.New("oauth authorization failed")
+	ErrOAuthAlreadyBound     = errors.New("oauth account already bound")
+	ErrOAuthNotBound         = errors.New("oauth account not bound")
+)
+
+// ParamError 参数校验错误
+// errors.Is(err, ErrInvalidParam) 为 true，可通过 errors.As 获取出错字段
+type ParamError struct {
+	Field   string // 出错字段，如 "phone"
+	Message string // 错误描述
+}
+
+// NewParamError 创建参数校验错误
+func NewParamError(field, message string) *ParamError {
+	return &ParamError{Field: field, Message: message}
+}
+
+func (e *ParamError) Error() string {
+	return e.Message
+}
+
+// Is 支持 errors.Is(err, ErrInvalidParam)
+func (e *ParamError) Is(target error) bool {
+	return target == ErrInvalidParam
+}
+
+// RateLimitError 操作过于频繁被临时限制
+// 可通过 errors.Is 判断具体原因（ErrLoginLocked、ErrCodeRateLimited、ErrUsernameChangeTooFrequent），RetryAfter 为剩余限制时长
+type RateLimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err.Error(), e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap 返回限制原因
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
@@ -4,29 +4,45 @@ import "time"
 
 // User 用户模型
 type User struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"-"` // 不返回给前端
-	Nickname     string    `json:"nickname"`
-	Avatar       string    `json:"avatar"`
-	Email        string    `json:"email"`
-	Gender       int       `json:"gender"`        // 0-未知，1-男，2-女
-	Birthday     *string   `json:"birthday"`      // YYYY-MM-DD
-	Signature    string    `json:"signature"`
-	Status       int       `json:"status"`        // 0-禁用，1-正常
-	CreatedAt    int64     `json:"created_at"`    // 毫秒时间戳
-	UpdatedAt    int64     `json:"updated_at"`
+	ID                int64           `json:"id"`
+	TenantID          string          `json:"tenant_id,omitempty"` // 租户 ID，未启用多租户时为空
+	Username          string          `json:"username"`
+	Phone             string          `json:"phone"`                  // E.164 格式，如 +8613800138000
+	PhoneRegion       string          `json:"phone_region,omitempty"` // 手机号所属地区，如 CN
+	PasswordHash      string          `json:"-"`                      // 不返回给前端
+	Nickname          string          `json:"nickname"`
+	Avatar            string          `json:"avatar"`
+	Email             string          `json:"email"`
+	Gender            int             `json:"gender"`   // 0-未知，1-男，2-女
+	Birthday          *string         `json:"birthday"` // YYYY-MM-DD
+	Signature         string          `json:"signature"`
+	Status            int             `json:"status"`                        // 0-禁用，1-正常，2-注销冷静期，3-已注销
+	MustResetPassword bool            `json:"must_reset_password"`           // 管理员要求重置密码，重置前不能使用密码登录
+	DeactivatedAt     int64           `json:"deactivated_at,omitempty"`      // 申请注销时间(毫秒)，冷静期结束后注销
+	UsernameChangedAt int64           `json:"username_changed_at,omitempty"` // 最近一次修改用户名的时间(毫秒)
+	LastSeenAt        int64           `json:"last_seen_at,omitempty"`        // 最近活跃时间(毫秒)，登录或设备活跃时更新
+	Privacy           PrivacySettings `json:"privacy"`                       // 隐私设置
+	CreatedAt         int64           `json:"created_at"`                    // 毫秒时间戳
+	UpdatedAt         int64           `json:"updated_at"`
+}
+
+// CanLogin 用户是否可以登录，注销冷静期内登录会恢复账号
+func (u *User) CanLogin() bool {
+	return u.Status == UserStatusNormal || u.Status == UserStatusDeactivated
 }
 
 // UserProfile 用户公开信息（不包含敏感信息）
+// 手机号、生日、最近活跃时间按用户的隐私设置返回，对查看者不可见时为空
 type UserProfile struct {
-	ID        int64  `json:"id"`
-	Username  string `json:"username"`
-	Nickname  string `json:"nickname"`
-	Avatar    string `json:"avatar"`
-	Gender    int    `json:"gender"`
-	Signature string `json:"signature"`
+	ID         int64   `json:"id"`
+	Username   string  `json:"username"`
+	Nickname   string  `json:"nickname"`
+	Avatar     string  `json:"avatar"`
+	Gender     int     `json:"gender"`
+	Signature  string  `json:"signature"`
+	Phone      string  `json:"phone,omitempty"`
+	Birthday   *string `json:"birthday,omitempty"`
+	LastSeenAt int64   `json:"last_seen_at,omitempty"`
 }
 
 // VerificationCode 验证码模型
@@ -35,16 +51,194 @@ type VerificationCode struct {
 	Phone     string `json:"phone"`
 	Code      string `json:"code"`
 	Type      int    `json:"type"`      // 1-注册，2-登录，3-重置密码
-	Status    int    `json:"status"`    // 0-未使用，1-已使用，2-已过期
+	Status    int    `json:"status"`    // 0-未使用，1-已使用，2-已过期，3-校验次数过多已作废
+	Attempts  int    `json:"attempts"`  // 校验失败次数
 	ExpireAt  int64  `json:"expire_at"` // 过期时间(毫秒)
 	CreatedAt int64  `json:"created_at"`
 }
 
+// TokenPair 登录令牌对
+// AccessToken 为短期 JWT，过期后使用 RefreshToken 换取新的令牌对
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	AccessExpiresAt  int64  `json:"access_expires_at"`  // 访问令牌过期时间(毫秒)
+	RefreshExpiresAt int64  `json:"refresh_expires_at"` // 刷新令牌过期时间(毫秒)
+
+	// Requires2FA 为 true 时用户已开启两步验证，以上令牌均为空，
+	// 需使用 ChallengeToken 和验证码调用 VerifyTwoFactor 完成登录
+	Requires2FA    bool   `json:"requires_2fa,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
+
+// RefreshToken 刷新令牌（库中仅保存哈希）
+type RefreshToken struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	SessionID string `json:"session_id"` // 会话 ID，轮换后保持不变
+	TokenHash string `json:"-"`
+	ExpireAt  int64  `json:"expire_at"`  // 过期时间(毫秒)
+	RevokedAt int64  `json:"revoked_at"` // 吊销时间(毫秒)，0 表示有效
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Session 登录会话，一次登录对应一个会话，刷新令牌轮换后会话不变
+type Session struct {
+	ID           string `json:"id"`
+	UserID       int64  `json:"user_id"`
+	DeviceID     string `json:"device_id"`
+	DeviceName   string `json:"device_name"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"user_agent"`
+	CreatedAt    int64  `json:"created_at"`
+	LastActiveAt int64  `json:"last_active_at"` // 最近一次刷新令牌的时间(毫秒)
+	ExpireAt     int64  `json:"expire_at"`      // 过期时间(毫秒)
+}
+
+// 登录方式
+const (
+	LoginMethodRegister  = "register" // 注册后自动登录
+	LoginMethodPassword  = "password"
+	LoginMethodCode      = "code"
+	LoginMethodOAuth     = "oauth"
+	LoginMethodTwoFactor = "2fa" // 开启两步验证的用户，登录在两步验证通过后完成
+)
+
+// LoginRecord 登录记录
+type LoginRecord struct {
+	ID         int64  `json:"id"`
+	UserID     int64  `json:"user_id"` // 账号不存在时为 0
+	Account    string `json:"account"` // 登录使用的账号：手机号、用户名或第三方登录提供方
+	Method     string `json:"method"`  // 登录方式，见 LoginMethod 常量
+	Success    bool   `json:"success"`
+	Reason     string `json:"reason,omitempty"` // 失败原因
+	IP         string `json:"ip"`
+	DeviceID   string `json:"device_id"`
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	NewDevice  bool   `json:"new_device"` // 是否首次在该设备登录
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// LoginHistory 登录历史
+type LoginHistory struct {
+	Records  []*LoginRecord `json:"records"`
+	Total    int64          `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
+
+// 安全事件类型
+const (
+	SecurityEventLoginSucceeded = "login_succeeded"
+	SecurityEventLoginFailed    = "login_failed"
+	SecurityEventNewDeviceLogin = "new_device_login" // 在从未登录过的设备上登录成功，在 login_succeeded 之后触发
+)
+
+// SecurityEvent 安全事件
+type SecurityEvent struct {
+	Type   string       `json:"type"`
+	UserID int64        `json:"user_id"` // 账号不存在时为 0
+	Record *LoginRecord `json:"record"`
+}
+
+// 用户生命周期事件类型
+const (
+	UserEventRegistered      = "user.registered"
+	UserEventLogin           = "user.login"
+	UserEventProfileUpdated  = "user.profile_updated"
+	UserEventPasswordChanged = "user.password_changed"
+	UserEventDisabled        = "user.disabled"
+)
+
+// 修改密码的方式
+const (
+	PasswordChangeByUser  = "change" // 校验旧密码后修改
+	PasswordChangeByReset = "reset"  // 通过验证码重置
+)
+
+// UserEvent 用户生命周期事件
+type UserEvent struct {
+	Type     string   `json:"type"`
+	UserID   int64    `json:"user_id"`
+	TenantID string   `json:"tenant_id,omitempty"`
+	Method   string   `json:"method,omitempty"` // 注册、登录方式（LoginMethod*）或修改密码的方式（PasswordChangeBy*）
+	Fields   []string `json:"fields,omitempty"` // profile_updated 事件变更的字段，如 nickname、avatar、username、phone
+	User     *User    `json:"user,omitempty"`   // 事件发生后的用户信息，password_changed 和 disabled 事件为空
+	Time     int64    `json:"time"`             // 事件时间(毫秒)
+}
+
+// 设备平台
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWeb     = "web"
+	PlatformDesktop = "desktop"
+)
+
+// Device 用户的设备，用于离线推送和多端登录
+type Device struct {
+	UserID       int64  `json:"user_id"`
+	DeviceID     string `json:"device_id"` // 客户端生成的设备唯一标识
+	Platform     string `json:"platform"`  // ios、android、web、desktop
+	DeviceName   string `json:"device_name"`
+	PushToken    string `json:"push_token,omitempty"` // 推送令牌（APNs / FCM 等），为空表示不推送
+	LastActiveAt int64  `json:"last_active_at"`       // 最近活跃时间(毫秒)
+	CreatedAt    int64  `json:"created_at"`
+	UpdatedAt    int64  `json:"updated_at"`
+}
+
+// RegisterDeviceRequest 注册设备请求，设备已存在时更新
+type RegisterDeviceRequest struct {
+	DeviceID   string `json:"device_id"`
+	Platform   string `json:"platform"`
+	DeviceName string `json:"device_name,omitempty"`
+	PushToken  string `json:"push_token,omitempty"`
+}
+
+// TwoFactor 用户的两步验证（TOTP）配置
+type TwoFactor struct {
+	UserID        int64    `json:"user_id"`
+	Secret        string   `json:"-"`
+	Enabled       bool     `json:"enabled"`
+	RecoveryCodes []string `json:"-"` // 恢复码哈希
+	LastUsedStep  int64    `json:"-"` // 最近一次使用的验证码时间步，防止重放
+	CreatedAt     int64    `json:"created_at"`
+	UpdatedAt     int64    `json:"updated_at"`
+}
+
+// TOTPSetup 两步验证配置信息，用于客户端展示二维码
+type TOTPSetup struct {
+	Secret string `json:"secret"` // base32 密钥，可手动输入验证器
+	URI    string `json:"uri"`    // otpauth:// 地址，用于生成二维码
+}
+
+// OAuthBinding 第三方账号绑定
+type OAuthBinding struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	Provider  string `json:"provider"` // 提供方，如 wechat、google、apple
+	OpenID    string `json:"open_id"`  // 第三方账号 ID
+	UnionID   string `json:"union_id,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Role 角色
+type Role struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"` // 角色名，唯一，如 admin
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"` // 权限，如 user:read；"user:*" 匹配 user: 开头的全部权限，"*" 匹配全部权限
+	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   int64    `json:"updated_at"`
+}
+
 // 验证码类型
 const (
 	CodeTypeRegister      = 1
 	CodeTypeLogin         = 2
 	CodeTypeResetPassword = 3
+	CodeTypeChangePhone   = 4
 )
 
 // 验证码状态
@@ -52,12 +246,15 @@ const (
 	CodeStatusUnused  = 0
 	CodeStatusUsed    = 1
 	CodeStatusExpired = 2
+	CodeStatusInvalid = 3
 )
 
 // 用户状态
 const (
-	UserStatusDisabled = 0
-	UserStatusNormal   = 1
+	UserStatusDisabled    = 0
+	UserStatusNormal      = 1
+	UserStatusDeactivated = 2 // 注销冷静期，期间登录自动恢复
+	UserStatusDeleted     = 3 // 已注销，个人信息已匿名化
 )
 
 // 性别
@@ -67,11 +264,44 @@ const (
 	GenderFemale  = 2
 )
 
+// 隐私可见范围
+const (
+	VisibilityEveryone = "everyone" // 所有人可见
+	VisibilityContacts = "contacts" // 仅联系人可见
+	VisibilityNobody   = "nobody"   // 仅自己可见
+)
+
+// PrivacySettings 隐私设置，各字段为可见范围
+type PrivacySettings struct {
+	Phone    string `json:"phone"`     // 手机号，默认 nobody
+	Birthday string `json:"birthday"`  // 生日，默认 nobody
+	LastSeen string `json:"last_seen"` // 最近活跃时间，默认 contacts
+}
+
+// DefaultPrivacySettings 默认隐私设置
+func DefaultPrivacySettings() PrivacySettings {
+	return PrivacySettings{
+		Phone:    VisibilityNobody,
+		Birthday: VisibilityNobody,
+		LastSeen: VisibilityContacts,
+	}
+}
+
+// UpdatePrivacyRequest 更新隐私设置请求，nil 字段不修改
+type UpdatePrivacyRequest struct {
+	Phone    *string `json:"phone,omitempty"`
+	Birthday *string `json:"birthday,omitempty"`
+	LastSeen *string `json:"last_seen,omitempty"`
+}
+
 // RegisterRequest 注册请求
 type RegisterRequest struct {
 	Phone    string `json:"phone"`
 	Password string `json:"password,omitempty"` // 密码（密码注册时使用）
 	Code     string `json:"code,omitempty"`     // 验证码（验证码注册时使用）
+
+	// CaptchaToken 人机验证令牌，配置 CaptchaVerifier 后密码注册时必填
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
 // LoginRequest 登录请求
@@ -79,6 +309,9 @@ type LoginRequest struct {
 	Account  string `json:"account"`            // 账号：手机号或用户名
 	Password string `json:"password,omitempty"` // 密码登录时使用
 	Code     string `json:"code,omitempty"`     // 验证码登录时使用（仅手机号）
+
+	// CaptchaToken 人机验证令牌（可选），登录失败次数较多返回 ErrCaptchaRequired 后提交
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
 // UpdateProfileRequest 更新用户信息请求
@@ -91,10 +324,34 @@ type UpdateProfileRequest struct {
 	Signature *string `json:"signature,omitempty"`
 }
 
+// Fields 请求中要更新的字段名
+func (r *UpdateProfileRequest) Fields() []string {
+	var fields []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"nickname", r.Nickname != nil},
+		{"avatar", r.Avatar != nil},
+		{"email", r.Email != nil},
+		{"gender", r.Gender != nil},
+		{"birthday", r.Birthday != nil},
+		{"signature", r.Signature != nil},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
 // SendCodeRequest 发送验证码请求
 type SendCodeRequest struct {
 	Phone string `json:"phone"`
-	Type  int    `json:"type"` // 1-注册，2-登录，3-重置密码
+	Type  int    `json:"type"` // 1-注册，2-登录，3-重置密码，4-更换手机号（发送到新手机号）
+
+	// CaptchaToken 人机验证令牌，配置 CaptchaVerifier 后必填
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
 // VerifyCodeRequest 验证验证码请求
@@ -104,10 +361,81 @@ type VerifyCodeRequest struct {
 	Type  int    `json:"type"`
 }
 
+// ListUsersRequest 用户列表查询条件（管理后台使用）
+type ListUsersRequest struct {
+	Status      *int   `json:"status,omitempty"`       // 按状态过滤，nil 表示不过滤
+	CreatedFrom int64  `json:"created_from,omitempty"` // 注册时间下限(毫秒，含)，0 表示不限
+	CreatedTo   int64  `json:"created_to,omitempty"`   // 注册时间上限(毫秒，不含)，0 表示不限
+	Keyword     string `json:"keyword,omitempty"`      // 按用户名、手机号、昵称模糊匹配
+	Page        int    `json:"page"`                   // 页码，从 1 开始，默认 1
+	PageSize    int    `json:"page_size"`              // 每页数量，默认 20，最大 100
+}
+
+// UserList 用户列表
+type UserList struct {
+	Users    []*User `json:"users"`
+	Total    int64   `json:"total"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"page_size"`
+}
+
+// UserImport 批量导入的用户，ExportUsers 导出的 JSON 记录可直接导入
+// 密码优先使用 PasswordHash，其次 Password，均为空时生成随机密码，用户需通过验证码登录或重置密码
+type UserImport struct {
+	Username     string  `json:"username,omitempty"`      // 用户名（可选），字母开头，最长 50 个字符，为空时按手机号生成
+	Phone        string  `json:"phone,omitempty"`         // 手机号，按 PhonePolicy 转换为 E.164 格式；未提供用户名或密码时必填
+	PasswordHash string  `json:"password_hash,omitempty"` // 原系统的密码哈希，支持 bcrypt 和 argon2id（PHC 格式）
+	Password     string  `json:"password,omitempty"`      // 明文密码，按密码策略校验
+	Nickname     string  `json:"nickname,omitempty"`      // 昵称，为空时随机生成
+	Avatar       string  `json:"avatar,omitempty"`
+	Email        string  `json:"email,omitempty"`
+	Gender       int     `json:"gender,omitempty"`   // 0-未知，1-男，2-女
+	Birthday     *string `json:"birthday,omitempty"` // YYYY-MM-DD
+	Signature    string  `json:"signature,omitempty"`
+	Disabled     bool    `json:"disabled,omitempty"`   // 导入为禁用状态
+	CreatedAt    int64   `json:"created_at,omitempty"` // 原系统的注册时间(毫秒)，0 表示使用导入时间
+}
+
+// ImportResult 批量导入结果
+type ImportResult struct {
+	UserIDs []int64          `json:"user_ids"`         // 与导入列表按下标对应的用户 ID，导入失败时为 0
+	Failed  []*ImportFailure `json:"failed,omitempty"` // 导入失败的记录
+}
+
+// ImportFailure 导入失败的记录
+type ImportFailure struct {
+	Index   int    `json:"index"` // 在导入列表中的下标
+	Err     error  `json:"-"`     // 失败原因，可用 errors.Is / errors.As 判断
+	Message string `json:"error"`
+}
+
+// 导出格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// ExportUsersRequest 用户导出条件，过滤条件与 ListUsersRequest 一致
+type ExportUsersRequest struct {
+	Status              *int   `json:"status,omitempty"`                // 按状态过滤，nil 表示不过滤
+	CreatedFrom         int64  `json:"created_from,omitempty"`          // 注册时间下限(毫秒，含)，0 表示不限
+	CreatedTo           int64  `json:"created_to,omitempty"`            // 注册时间上限(毫秒，不含)，0 表示不限
+	Keyword             string `json:"keyword,omitempty"`               // 按用户名、手机号、昵称模糊匹配
+	Format              string `json:"format,omitempty"`                // csv（默认）或 json
+	IncludePasswordHash bool   `json:"include_password_hash,omitempty"` // 导出密码哈希，用于迁移到其他部署，导出文件需妥善保管
+}
+
+// ChangePhoneRequest 更换手机号请求
+type ChangePhoneRequest struct {
+	NewPhone string `json:"new_phone"`
+	Code     string `json:"code"` // 发送到新手机号的验证码（CodeTypeChangePhone）
+}
+
 // ChangePasswordRequest 修改密码请求
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password"`
 	NewPassword string `json:"new_password"`
+	SessionID   string `json:"-"` // 当前会话 ID（访问令牌的 sid），修改后保留该会话，为空时吊销全部会话
 }
 
 // ResetPasswordRequest 重置密码请求
@@ -0,0 +1,177 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Apple 登录接口地址
+const (
+	appleIssuer   = "https://appleid.apple.com"
+	appleTokenURL = "https://appleid.apple.com/auth/token"
+	appleKeysURL  = "https://appleid.apple.com/auth/keys"
+)
+
+// appleKeysTTL Apple 公钥缓存时间
+const appleKeysTTL = time.Hour
+
+// AppleConfig Apple 登录配置
+type AppleConfig struct {
+	TeamID      string // 开发者团队 ID
+	ClientID    string // Services ID 或 Bundle ID
+	KeyID       string // 私钥 Key ID
+	PrivateKey  string // .p8 私钥内容（PEM 格式）
+	RedirectURL string // 回调地址（Web 登录需要，App 登录可为空）
+}
+
+// AppleProvider Sign in with Apple
+type AppleProvider struct {
+	config     *AppleConfig
+	privateKey *ecdsa.PrivateKey
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	keysFetch time.Time
+}
+
+// NewAppleProvider 创建 Apple 登录提供方
+func NewAppleProvider(config *AppleConfig) (*AppleProvider, error) {
+	if config == nil {
+		return nil, fmt.Errorf("apple config is nil")
+	}
+	privateKey, err := jwt.ParseECPrivateKeyFromPEM([]byte(config.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse apple private key: %w", err)
+	}
+	return &AppleProvider{config: config, privateKey: privateKey}, nil
+}
+
+// Name 提供方名称
+func (p *AppleProvider) Name() string {
+	return ProviderApple
+}
+
+// Exchange 使用授权码换取 Apple 用户信息
+// Apple 只在首次授权时通过客户端回传昵称，这里仅能拿到 sub 和邮箱
+func (p *AppleProvider) Exchange(ctx context.Context, code string) (*UserInfo, error) {
+	secret, err := p.clientSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	form := url.Values{
+		"client_id":     {p.config.ClientID},
+		"client_secret": {secret},
+		"code":          {code},
+		"grant_type":    {"authorization_code"},
+	}
+	if p.config.RedirectURL != "" {
+		form.Set("redirect_uri", p.config.RedirectURL)
+	}
+	if err := postForm(ctx, appleTokenURL, form, &token); err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("apple token: empty id_token")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(token.IDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(appleIssuer),
+		jwt.WithAudience(p.config.ClientID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("verify apple id_token: %w", err)
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("apple id_token: empty sub")
+	}
+	email, _ := claims["email"].(string)
+
+	return &UserInfo{
+		Provider: ProviderApple,
+		OpenID:   sub,
+		Email:    email,
+	}, nil
+}
+
+// clientSecret 生成 ES256 签名的 client_secret
+func (p *AppleProvider) clientSecret() (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Issuer:    p.config.TeamID,
+		Subject:   p.config.ClientID,
+		Audience:  jwt.ClaimStrings{appleIssuer},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	})
+	token.Header["kid"] = p.config.KeyID
+	return token.SignedString(p.privateKey)
+}
+
+// publicKey 获取 Apple 公钥，kid 未命中或缓存过期时重新拉取
+func (p *AppleProvider) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok && time.Since(p.keysFetch) < appleKeysTTL {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, appleKeysURL, nil, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	p.keys = keys
+	p.keysFetch = time.Now()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("apple public key %q not found", kid)
+	}
+	return key, nil
+}
@@ -0,0 +1,83 @@
+// Package oauth 第三方登录（OAuth2 / OpenID Connect）提供方
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 内置提供方名称
+const (
+	ProviderWeChat = "wechat"
+	ProviderGoogle = "google"
+	ProviderApple  = "apple"
+)
+
+// UserInfo 第三方账号信息
+type UserInfo struct {
+	Provider string // 提供方名称
+	OpenID   string // 第三方账号在该应用下的唯一 ID
+	UnionID  string // 跨应用统一 ID（微信 unionid，其他提供方可为空）
+	Nickname string // 昵称
+	Avatar   string // 头像 URL
+	Email    string // 邮箱
+}
+
+// Provider 第三方登录提供方
+// 实现该接口即可接入新的第三方登录
+type Provider interface {
+	// Name 提供方名称，与账号绑定表中的 provider 字段对应
+	Name() string
+
+	// Exchange 使用客户端拿到的授权码换取第三方账号信息
+	Exchange(ctx context.Context, code string) (*UserInfo, error)
+}
+
+// httpClient 请求第三方接口使用的 HTTP 客户端
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// getJSON 发送 GET 请求并解析 JSON 响应
+func getJSON(ctx context.Context, endpoint string, header http.Header, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return doJSON(req, out)
+}
+
+// postForm 发送表单 POST 请求并解析 JSON 响应
+func postForm(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doJSON(req, out)
+}
+
+// doJSON 执行请求并解析 JSON 响应，非 2xx 响应返回错误
+func doJSON(req *http.Request, out interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: status %d: %s", req.Method, req.URL.Host, resp.StatusCode, body)
+	}
+	return json.Unmarshal(body, out)
+}
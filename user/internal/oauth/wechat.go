@@ -0,0 +1,82 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// 微信开放平台接口地址
+const (
+	wechatTokenURL    = "https://api.weixin.qq.com/sns/oauth2/access_token"
+	wechatUserInfoURL = "https://api.weixin.qq.com/sns/userinfo"
+)
+
+// WeChatProvider 微信登录（网站应用 / 移动应用）
+type WeChatProvider struct {
+	appID     string
+	appSecret string
+}
+
+// NewWeChatProvider 创建微信登录提供方
+func NewWeChatProvider(appID, appSecret string) *WeChatProvider {
+	return &WeChatProvider{appID: appID, appSecret: appSecret}
+}
+
+// Name 提供方名称
+func (p *WeChatProvider) Name() string {
+	return ProviderWeChat
+}
+
+// Exchange 使用授权码换取微信用户信息
+func (p *WeChatProvider) Exchange(ctx context.Context, code string) (*UserInfo, error) {
+	var token struct {
+		AccessToken string `json:"access_token"`
+		OpenID      string `json:"openid"`
+		UnionID     string `json:"unionid"`
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+	}
+	query := url.Values{
+		"appid":      {p.appID},
+		"secret":     {p.appSecret},
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	}
+	if err := getJSON(ctx, wechatTokenURL+"?"+query.Encode(), nil, &token); err != nil {
+		return nil, err
+	}
+	if token.ErrCode != 0 {
+		return nil, fmt.Errorf("wechat access_token: %d %s", token.ErrCode, token.ErrMsg)
+	}
+
+	var profile struct {
+		Nickname   string `json:"nickname"`
+		HeadImgURL string `json:"headimgurl"`
+		UnionID    string `json:"unionid"`
+		ErrCode    int    `json:"errcode"`
+		ErrMsg     string `json:"errmsg"`
+	}
+	query = url.Values{
+		"access_token": {token.AccessToken},
+		"openid":       {token.OpenID},
+	}
+	if err := getJSON(ctx, wechatUserInfoURL+"?"+query.Encode(), nil, &profile); err != nil {
+		return nil, err
+	}
+	if profile.ErrCode != 0 {
+		return nil, fmt.Errorf("wechat userinfo: %d %s", profile.ErrCode, profile.ErrMsg)
+	}
+
+	unionID := token.UnionID
+	if unionID == "" {
+		unionID = profile.UnionID
+	}
+	return &UserInfo{
+		Provider: ProviderWeChat,
+		OpenID:   token.OpenID,
+		UnionID:  unionID,
+		Nickname: profile.Nickname,
+		Avatar:   profile.HeadImgURL,
+	}, nil
+}
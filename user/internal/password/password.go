@@ -0,0 +1,193 @@
+// Package password 密码哈希，支持 bcrypt 和 argon2id
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 哈希算法
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// ErrMismatch 密码与哈希不匹配
+var ErrMismatch = errors.New("password does not match")
+
+// Config 密码哈希配置，零值字段使用默认值
+type Config struct {
+	Algorithm     string // 新密码使用的算法：bcrypt（默认）或 argon2id
+	BcryptCost    int    // bcrypt 计算成本，默认 10
+	Argon2Time    uint32 // argon2id 迭代次数，默认 3
+	Argon2Memory  uint32 // argon2id 内存（KiB），默认 65536（64 MiB）
+	Argon2Threads uint8  // argon2id 并行度，默认 2
+	Argon2KeyLen  uint32 // argon2id 哈希长度（字节），默认 32
+	Argon2SaltLen uint32 // argon2id 盐长度（字节），默认 16
+}
+
+// withDefaults 填充默认值
+func (c Config) withDefaults() Config {
+	if c.Algorithm == "" {
+		c.Algorithm = AlgorithmBcrypt
+	}
+	if c.BcryptCost == 0 {
+		c.BcryptCost = bcrypt.DefaultCost
+	}
+	if c.Argon2Time == 0 {
+		c.Argon2Time = 3
+	}
+	if c.Argon2Memory == 0 {
+		c.Argon2Memory = 64 * 1024
+	}
+	if c.Argon2Threads == 0 {
+		c.Argon2Threads = 2
+	}
+	if c.Argon2KeyLen == 0 {
+		c.Argon2KeyLen = 32
+	}
+	if c.Argon2SaltLen == 0 {
+		c.Argon2SaltLen = 16
+	}
+	return c
+}
+
+// Hasher 密码哈希器，按配置生成新哈希，同时兼容校验两种算法的已有哈希
+type Hasher struct {
+	config Config
+}
+
+// NewHasher 创建密码哈希器
+func NewHasher(config Config) (*Hasher, error) {
+	config = config.withDefaults()
+	switch config.Algorithm {
+	case AlgorithmBcrypt:
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", config.Algorithm)
+	}
+	return &Hasher{config: config}, nil
+}
+
+// Hash 计算密码哈希
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.config.Algorithm == AlgorithmArgon2id {
+		return h.hashArgon2id(password)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.config.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify 校验密码，不匹配时返回 ErrMismatch
+func (h *Hasher) Verify(hash, password string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return err
+		}
+		other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return ErrMismatch
+		}
+		return nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	return err
+}
+
+// NeedsRehash 哈希的算法或参数与当前配置不一致，需要用明文密码重新计算
+func (h *Hasher) NeedsRehash(hash string) bool {
+	if h.config.Algorithm == AlgorithmArgon2id {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.time != h.config.Argon2Time ||
+			params.memory != h.config.Argon2Memory ||
+			params.threads != h.config.Argon2Threads ||
+			uint32(len(salt)) != h.config.Argon2SaltLen ||
+			uint32(len(key)) != h.config.Argon2KeyLen
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.config.BcryptCost
+}
+
+// IsSupported 哈希是否为可校验的 bcrypt 或 argon2id（PHC 格式）哈希，用于导入其他系统的密码哈希
+func IsSupported(hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		_, _, _, err := decodeArgon2id(hash)
+		return err == nil
+	}
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+// argon2Params argon2id 参数
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// hashArgon2id 计算 argon2id 哈希，编码为 PHC 格式：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func (h *Hasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, h.config.Argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	c := h.config
+	key := argon2.IDKey([]byte(password), salt, c.Argon2Time, c.Argon2Memory, c.Argon2Threads, c.Argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, c.Argon2Memory, c.Argon2Time, c.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// decodeArgon2id 解析 PHC 格式的 argon2id 哈希
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("invalid argon2id hash")
+	}
+	return params, salt, key, nil
+}
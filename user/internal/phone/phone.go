@@ -0,0 +1,184 @@
+package phone
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 解析错误
+var (
+	ErrInvalidNumber = errors.New("invalid phone number")
+	ErrUnknownRegion = errors.New("unknown region")
+)
+
+// Number 解析后的手机号
+type Number struct {
+	E164        string // E.164 格式，如 +8613800138000
+	Region      string // ISO 3166-1 二位地区代码，如 CN；国家码不在内置列表中时为空
+	CountryCode string // 国家码，如 86；国家码不在内置列表中时为空
+	National    string // 国内号码，不含国家码和长途前缀；国家码不在内置列表中时为空
+}
+
+// region 地区号码规则
+type region struct {
+	code    string         // 地区代码
+	country string         // 国家码
+	trunk   string         // 国内长途前缀，按本地格式输入时去除
+	pattern *regexp.Regexp // 国内手机号格式
+}
+
+// regions 内置地区规则，共用国家码的地区中排在前面的为默认地区
+var regions = []region{
+	{"CN", "86", "", regexp.MustCompile(`^1[3-9]\d{9}$`)},
+	{"HK", "852", "", regexp.MustCompile(`^[4-9]\d{7}$`)},
+	{"MO", "853", "", regexp.MustCompile(`^6\d{7}$`)},
+	{"TW", "886", "0", regexp.MustCompile(`^9\d{8}$`)},
+	{"US", "1", "1", regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`)},
+	{"CA", "1", "1", regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`)},
+	{"GB", "44", "0", regexp.MustCompile(`^7\d{9}$`)},
+	{"JP", "81", "0", regexp.MustCompile(`^[789]0\d{8}$`)},
+	{"KR", "82", "0", regexp.MustCompile(`^1\d{8,9}$`)},
+	{"SG", "65", "", regexp.MustCompile(`^[89]\d{7}$`)},
+	{"MY", "60", "0", regexp.MustCompile(`^1\d{8,9}$`)},
+	{"TH", "66", "0", regexp.MustCompile(`^[689]\d{8}$`)},
+	{"VN", "84", "0", regexp.MustCompile(`^[35789]\d{8}$`)},
+	{"ID", "62", "0", regexp.MustCompile(`^8\d{8,11}$`)},
+	{"PH", "63", "0", regexp.MustCompile(`^9\d{9}$`)},
+	{"IN", "91", "0", regexp.MustCompile(`^[6-9]\d{9}$`)},
+	{"AU", "61", "0", regexp.MustCompile(`^4\d{8}$`)},
+	{"NZ", "64", "0", regexp.MustCompile(`^2\d{7,9}$`)},
+	{"DE", "49", "0", regexp.MustCompile(`^1[5-7]\d{8,9}$`)},
+	{"FR", "33", "0", regexp.MustCompile(`^[67]\d{8}$`)},
+	{"ES", "34", "", regexp.MustCompile(`^[67]\d{8}$`)},
+	{"IT", "39", "", regexp.MustCompile(`^3\d{8,9}$`)},
+	{"NL", "31", "0", regexp.MustCompile(`^6\d{8}$`)},
+	{"RU", "7", "8", regexp.MustCompile(`^9\d{9}$`)},
+	{"BR", "55", "0", regexp.MustCompile(`^[1-9]{2}9\d{8}$`)},
+	{"MX", "52", "", regexp.MustCompile(`^[1-9]\d{9}$`)},
+	{"AE", "971", "0", regexp.MustCompile(`^5\d{8}$`)},
+	{"SA", "966", "0", regexp.MustCompile(`^5\d{8}$`)},
+}
+
+// lookup 按地区代码查找规则
+func lookup(code string) (*region, bool) {
+	for i := range regions {
+		if regions[i].code == code {
+			return &regions[i], true
+		}
+	}
+	return nil, false
+}
+
+// IsKnownRegion 是否为内置地区
+func IsKnownRegion(code string) bool {
+	_, ok := lookup(strings.ToUpper(code))
+	return ok
+}
+
+// Regions 内置地区代码，按字母排序
+func Regions() []string {
+	codes := make([]string, len(regions))
+	for i, r := range regions {
+		codes[i] = r.code
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Parse 解析手机号并转换为 E.164 格式
+// 以 + 或 00 开头时按国际格式解析，否则按 defaultRegion 的国内格式解析；允许包含空格、短横线、括号和点
+// 国家码不在内置列表中时仅校验 E.164 长度（8-15 位数字）
+func Parse(raw, defaultRegion string) (*Number, error) {
+	digits, international := strip(raw)
+	if digits == "" {
+		return nil, ErrInvalidNumber
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, ErrInvalidNumber
+		}
+	}
+
+	defaultRegion = strings.ToUpper(defaultRegion)
+	if !international {
+		r, ok := lookup(defaultRegion)
+		if !ok {
+			return nil, ErrUnknownRegion
+		}
+		national := digits
+		if r.trunk != "" && strings.HasPrefix(national, r.trunk) && !r.pattern.MatchString(national) {
+			national = strings.TrimPrefix(national, r.trunk)
+		}
+		if !r.pattern.MatchString(national) {
+			return nil, ErrInvalidNumber
+		}
+		return newNumber(r, national), nil
+	}
+
+	// 国家码为 1-3 位，按长度依次匹配
+	for n := 1; n <= 3 && n < len(digits); n++ {
+		country, national := digits[:n], digits[n:]
+		candidates := regionsOf(country, defaultRegion)
+		if len(candidates) == 0 {
+			continue
+		}
+		for _, r := range candidates {
+			if r.pattern.MatchString(national) {
+				return newNumber(r, national), nil
+			}
+		}
+		return nil, ErrInvalidNumber
+	}
+
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return nil, ErrInvalidNumber
+	}
+	return &Number{E164: "+" + digits}, nil
+}
+
+// strip 去除分隔符和国际前缀，返回数字部分及是否为国际格式
+func strip(raw string) (string, bool) {
+	s := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	switch {
+	case strings.HasPrefix(s, "+"):
+		return s[1:], true
+	case strings.HasPrefix(s, "00"):
+		return s[2:], true
+	}
+	return s, false
+}
+
+// regionsOf 使用该国家码的地区，defaultRegion 共用该国家码时排在最前
+func regionsOf(country, defaultRegion string) []*region {
+	var matched []*region
+	for i := range regions {
+		if regions[i].country != country {
+			continue
+		}
+		if regions[i].code == defaultRegion {
+			matched = append([]*region{&regions[i]}, matched...)
+		} else {
+			matched = append(matched, &regions[i])
+		}
+	}
+	return matched
+}
+
+// newNumber 创建号码
+func newNumber(r *region, national string) *Number {
+	return &Number{
+		E164:        "+" + r.country + national,
+		Region:      r.code,
+		CountryCode: r.country,
+		National:    national,
+	}
+}
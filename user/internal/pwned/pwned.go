@@ -0,0 +1,64 @@
+// Package pwned 通过 Have I Been Pwned 的 Pwned Passwords 接口检查密码是否泄露
+// 使用 k-匿名查询，仅发送密码 SHA-1 的前 5 位，密码本身不会离开本机
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint 默认查询地址
+const DefaultEndpoint = "https://api.pwnedpasswords.com/range/"
+
+// Checker 泄露密码检查
+type Checker struct {
+	client   *http.Client
+	endpoint string
+}
+
+// NewChecker 创建泄露密码检查，client 为 nil 时使用 10 秒超时的默认客户端
+func NewChecker(client *http.Client) *Checker {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Checker{client: client, endpoint: DefaultEndpoint}
+}
+
+// Breached 密码是否出现在已知的泄露数据中
+func (c *Checker) Breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// 填充响应，避免通过响应大小推断查询内容
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("pwned passwords: unexpected status %d", resp.StatusCode)
+	}
+
+	// 每行格式为 "<SHA-1 后 35 位>:<出现次数>"，填充行的次数为 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		hashSuffix, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && hashSuffix == suffix && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
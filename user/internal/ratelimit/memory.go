@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval 过期数据清理间隔（按操作次数）
+const sweepInterval = 1024
+
+// entry 计数项
+type entry struct {
+	count    int64
+	expireAt time.Time
+}
+
+// MemoryStore 进程内计数存储，仅适用于单实例部署
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	ops     int
+}
+
+// NewMemoryStore 创建进程内计数存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*entry)}
+}
+
+// Incr 计数加一
+func (s *MemoryStore) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	e := s.get(key, now)
+	if e == nil {
+		e = &entry{expireAt: now.Add(window)}
+		s.entries[key] = e
+	}
+	e.count++
+	return e.count, nil
+}
+
+// Get 获取当前计数
+func (s *MemoryStore) Get(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e := s.get(key, time.Now()); e != nil {
+		return e.count, nil
+	}
+	return 0, nil
+}
+
+// Lock 设置锁定
+func (s *MemoryStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	s.entries[key] = &entry{count: 1, expireAt: now.Add(d)}
+	return nil
+}
+
+// TTL 返回剩余有效时长
+func (s *MemoryStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if e := s.get(key, now); e != nil {
+		return e.expireAt.Sub(now), nil
+	}
+	return 0, nil
+}
+
+// Reset 清除计数或锁定
+func (s *MemoryStore) Reset(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// get 获取未过期的计数项，调用方需持有锁
+func (s *MemoryStore) get(key string, now time.Time) *entry {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if !now.Before(e.expireAt) {
+		delete(s.entries, key)
+		return nil
+	}
+	return e
+}
+
+// sweep 定期清理过期数据，调用方需持有锁
+func (s *MemoryStore) sweep(now time.Time) {
+	s.ops++
+	if s.ops < sweepInterval {
+		return
+	}
+	s.ops = 0
+	for key, e := range s.entries {
+		if !now.Before(e.expireAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrScript 计数加一，首次计数时设置过期时间
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// RedisStore 基于 Redis 的计数存储，适用于多实例部署
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 计数存储，prefix 为空时使用 "user:ratelimit:"
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "user:ratelimit:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Incr 计数加一
+func (s *RedisStore) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return incrScript.Run(ctx, s.client, []string{s.prefix + key}, window.Milliseconds()).Int64()
+}
+
+// Get 获取当前计数
+func (s *RedisStore) Get(ctx context.Context, key string) (int64, error) {
+	n, err := s.client.Get(ctx, s.prefix+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Lock 设置锁定
+func (s *RedisStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, 1, d).Err()
+}
+
+// TTL 返回剩余有效时长
+func (s *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	d, err := s.client.PTTL(ctx, s.prefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// 键不存在时返回 -2，未设置过期时间时返回 -1
+	if d < 0 {
+		return 0, nil
+	}
+	return d, nil
+}
+
+// Reset 清除计数或锁定
+func (s *RedisStore) Reset(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.prefix + key
+	}
+	return s.client.Del(ctx, prefixed...).Err()
+}
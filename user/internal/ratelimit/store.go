@@ -0,0 +1,26 @@
+// Package ratelimit 计数和锁定存储，用于登录保护和验证码限流
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store 带过期时间的计数存储
+// 多实例部署时需使用共享存储（如 Redis），否则各实例分别计数
+type Store interface {
+	// Incr 计数加一并返回当前值，计数窗口从首次计数开始，到期后自动清零
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+
+	// Get 获取当前计数，不存在或已过期时返回 0
+	Get(ctx context.Context, key string) (int64, error)
+
+	// Lock 设置锁定，d 后自动解除
+	Lock(ctx context.Context, key string, d time.Duration) error
+
+	// TTL 返回计数或锁定的剩余有效时长，不存在时返回 0
+	TTL(ctx context.Context, key string) (time.Duration, error)
+
+	// Reset 清除计数或锁定
+	Reset(ctx context.Context, keys ...string) error
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"gorm.io/gorm"
 
 	"github.com/bbadbeef/go-base/user/internal/model"
@@ -9,10 +11,12 @@ import (
 // DBVerificationCode 验证码数据库模型
 type DBVerificationCode struct {
 	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	TenantID  string `gorm:"type:varchar(64);not null;default:''"`
 	Phone     string `gorm:"type:varchar(20);index:idx_phone_type;not null"`
 	Code      string `gorm:"type:varchar(10);not null"`
 	Type      int    `gorm:"type:tinyint;index:idx_phone_type;not null"`
 	Status    int    `gorm:"type:tinyint;default:0"`
+	Attempts  int    `gorm:"not null;default:0"`
 	ExpireAt  int64  `gorm:"type:bigint;not null"`
 	CreatedAt int64  `gorm:"index:idx_created_at;not null"`
 }
@@ -23,22 +27,58 @@ func (DBVerificationCode) TableName() string {
 
 // CodeRepository 验证码仓库
 type CodeRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	namer  *TableNamer
+	tenant string
 }
 
 // NewCodeRepository 创建验证码仓库
-func NewCodeRepository(db *gorm.DB) *CodeRepository {
-	return &CodeRepository{db: db}
+func NewCodeRepository(db *gorm.DB, namer *TableNamer) *CodeRepository {
+	return &CodeRepository{db: db, namer: namer}
+}
+
+// SetTenant 设置租户 ID，验证码仅在该租户内有效
+func (r *CodeRepository) SetTenant(tenant string) {
+	r.tenant = tenant
+}
+
+// table 验证码表，不限定租户，用于表结构迁移
+func (r *CodeRepository) table(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBVerificationCode{}.TableName()))
+}
+
+// codes 验证码表，限定当前租户
+func (r *CodeRepository) codes(ctx context.Context) *gorm.DB {
+	return r.table(ctx).Where("tenant_id = ?", r.tenant)
 }
 
 // InitTable 初始化数据库表
-func (r *CodeRepository) InitTable() error {
-	return r.db.AutoMigrate(&DBVerificationCode{})
+func (r *CodeRepository) InitTable(ctx context.Context) error {
+	return r.table(ctx).AutoMigrate(&DBVerificationCode{})
+}
+
+// AddAttemptsColumn 添加校验次数字段（v6 迁移）
+func (r *CodeRepository) AddAttemptsColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if migrator.HasColumn(&DBVerificationCode{}, "Attempts") {
+		return nil
+	}
+	return migrator.AddColumn(&DBVerificationCode{}, "Attempts")
+}
+
+// AddTenantColumn 添加租户字段（v14 迁移）
+func (r *CodeRepository) AddTenantColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if migrator.HasColumn(&DBVerificationCode{}, "TenantID") {
+		return nil
+	}
+	return migrator.AddColumn(&DBVerificationCode{}, "TenantID")
 }
 
 // Create 创建验证码
-func (r *CodeRepository) Create(code *model.VerificationCode) error {
+func (r *CodeRepository) Create(ctx context.Context, code *model.VerificationCode) error {
 	dbCode := &DBVerificationCode{
+		TenantID:  r.tenant,
 		Phone:     code.Phone,
 		Code:      code.Code,
 		Type:      code.Type,
@@ -47,7 +87,7 @@ func (r *CodeRepository) Create(code *model.VerificationCode) error {
 		CreatedAt: code.CreatedAt,
 	}
 
-	if err := r.db.Create(dbCode).Error; err != nil {
+	if err := r.codes(ctx).Create(dbCode).Error; err != nil {
 		return err
 	}
 
@@ -56,12 +96,12 @@ func (r *CodeRepository) Create(code *model.VerificationCode) error {
 }
 
 // GetLatest 获取最新的验证码
-func (r *CodeRepository) GetLatest(phone string, codeType int) (*model.VerificationCode, error) {
+func (r *CodeRepository) GetLatest(ctx context.Context, phone string, codeType int) (*model.VerificationCode, error) {
 	var dbCode DBVerificationCode
-	if err := r.db.Where("phone = ? AND type = ?", phone, codeType).
+	if err := r.codes(ctx).Where("phone = ? AND type = ?", phone, codeType).
 		Order("created_at DESC").
 		First(&dbCode).Error; err != nil {
-		return nil, err
+		return nil, notFoundAs(err, model.ErrCodeNotFound)
 	}
 
 	return &model.VerificationCode{
@@ -70,21 +110,44 @@ func (r *CodeRepository) GetLatest(phone string, codeType int) (*model.Verificat
 		Code:      dbCode.Code,
 		Type:      dbCode.Type,
 		Status:    dbCode.Status,
+		Attempts:  dbCode.Attempts,
 		ExpireAt:  dbCode.ExpireAt,
 		CreatedAt: dbCode.CreatedAt,
 	}, nil
 }
 
 // MarkAsUsed 标记为已使用
-func (r *CodeRepository) MarkAsUsed(id int64) error {
-	return r.db.Model(&DBVerificationCode{}).
+func (r *CodeRepository) MarkAsUsed(ctx context.Context, id int64) error {
+	return r.codes(ctx).Model(&DBVerificationCode{}).
 		Where("id = ?", id).
 		Update("status", model.CodeStatusUsed).Error
 }
 
 // MarkAsExpired 标记过期的验证码
-func (r *CodeRepository) MarkAsExpired(now int64) error {
-	return r.db.Model(&DBVerificationCode{}).
+func (r *CodeRepository) MarkAsExpired(ctx context.Context, now int64) error {
+	return r.codes(ctx).Model(&DBVerificationCode{}).
 		Where("expire_at < ? AND status = ?", now, model.CodeStatusUnused).
 		Update("status", model.CodeStatusExpired).Error
 }
+
+// IncrAttempts 校验失败次数加一，返回最新次数
+func (r *CodeRepository) IncrAttempts(ctx context.Context, id int64) (int, error) {
+	if err := r.codes(ctx).Model(&DBVerificationCode{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return 0, err
+	}
+
+	var dbCode DBVerificationCode
+	if err := r.codes(ctx).Select("attempts").Where("id = ?", id).First(&dbCode).Error; err != nil {
+		return 0, err
+	}
+	return dbCode.Attempts, nil
+}
+
+// MarkAsInvalid 校验次数过多，作废验证码
+func (r *CodeRepository) MarkAsInvalid(ctx context.Context, id int64) error {
+	return r.codes(ctx).Model(&DBVerificationCode{}).
+		Where("id = ? AND status = ?", id, model.CodeStatusUnused).
+		Update("status", model.CodeStatusInvalid).Error
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// DBDevice 设备数据库模型
+type DBDevice struct {
+	UserID       int64  `gorm:"primaryKey;autoIncrement:false"`
+	DeviceID     string `gorm:"type:varchar(128);primaryKey"`
+	Platform     string `gorm:"type:varchar(20);not null"`
+	DeviceName   string `gorm:"type:varchar(100)"`
+	PushToken    string `gorm:"type:varchar(255);index:idx_device_push_token"`
+	LastActiveAt int64  `gorm:"not null"`
+	CreatedAt    int64  `gorm:"not null"`
+	UpdatedAt    int64  `gorm:"not null"`
+}
+
+func (DBDevice) TableName() string {
+	return "user_devices"
+}
+
+// DeviceRepository 设备仓库
+type DeviceRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewDeviceRepository 创建设备仓库
+func NewDeviceRepository(db *gorm.DB, namer *TableNamer) *DeviceRepository {
+	return &DeviceRepository{db: db, namer: namer}
+}
+
+// devices 设备表
+func (r *DeviceRepository) devices(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBDevice{}.TableName()))
+}
+
+// InitTable 初始化数据库表
+func (r *DeviceRepository) InitTable(ctx context.Context) error {
+	return r.devices(ctx).AutoMigrate(&DBDevice{})
+}
+
+// Upsert 创建或更新设备，同一推送令牌只保留在当前设备上
+// 同一台手机切换账号后，旧账号不会再收到该设备的推送
+func (r *DeviceRepository) Upsert(ctx context.Context, device *model.Device) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := NewDeviceRepository(tx, r.namer)
+		if device.PushToken != "" {
+			if err := repo.devices(ctx).
+				Where("push_token = ? AND NOT (user_id = ? AND device_id = ?)", device.PushToken, device.UserID, device.DeviceID).
+				Updates(map[string]interface{}{"push_token": "", "updated_at": device.UpdatedAt}).Error; err != nil {
+				return err
+			}
+		}
+		return repo.devices(ctx).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "device_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"platform", "device_name", "push_token", "last_active_at", "updated_at"}),
+			}).
+			Create(&DBDevice{
+				UserID:       device.UserID,
+				DeviceID:     device.DeviceID,
+				Platform:     device.Platform,
+				DeviceName:   device.DeviceName,
+				PushToken:    device.PushToken,
+				LastActiveAt: device.LastActiveAt,
+				CreatedAt:    device.CreatedAt,
+				UpdatedAt:    device.UpdatedAt,
+			}).Error
+	})
+}
+
+// Get 获取用户的设备
+func (r *DeviceRepository) Get(ctx context.Context, userID int64, deviceID string) (*model.Device, error) {
+	var dbDevice DBDevice
+	if err := r.devices(ctx).Where("user_id = ? AND device_id = ?", userID, deviceID).First(&dbDevice).Error; err != nil {
+		return nil, notFoundAs(err, model.ErrDeviceNotFound)
+	}
+	return r.toModel(&dbDevice), nil
+}
+
+// ListByUser 获取用户的全部设备，按最近活跃时间倒序
+func (r *DeviceRepository) ListByUser(ctx context.Context, userID int64) ([]*model.Device, error) {
+	var dbDevices []DBDevice
+	if err := r.devices(ctx).Where("user_id = ?", userID).Order("last_active_at DESC").Find(&dbDevices).Error; err != nil {
+		return nil, err
+	}
+	devices := make([]*model.Device, 0, len(dbDevices))
+	for i := range dbDevices {
+		devices = append(devices, r.toModel(&dbDevices[i]))
+	}
+	return devices, nil
+}
+
+// Touch 更新设备最近活跃时间，返回设备是否存在
+func (r *DeviceRepository) Touch(ctx context.Context, userID int64, deviceID string, now int64) (bool, error) {
+	result := r.devices(ctx).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Update("last_active_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete 删除用户的设备
+func (r *DeviceRepository) Delete(ctx context.Context, userID int64, deviceID string) error {
+	return r.devices(ctx).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Delete(&DBDevice{}).Error
+}
+
+// ClearPushToken 清除推送令牌（推送服务返回令牌失效时调用），设备保留
+func (r *DeviceRepository) ClearPushToken(ctx context.Context, pushToken string, now int64) error {
+	return r.devices(ctx).
+		Where("push_token = ?", pushToken).
+		Updates(map[string]interface{}{"push_token": "", "updated_at": now}).Error
+}
+
+// toModel 转换为业务模型
+func (r *DeviceRepository) toModel(dbDevice *DBDevice) *model.Device {
+	return &model.Device{
+		UserID:       dbDevice.UserID,
+		DeviceID:     dbDevice.DeviceID,
+		Platform:     dbDevice.Platform,
+		DeviceName:   dbDevice.DeviceName,
+		PushToken:    dbDevice.PushToken,
+		LastActiveAt: dbDevice.LastActiveAt,
+		CreatedAt:    dbDevice.CreatedAt,
+		UpdatedAt:    dbDevice.UpdatedAt,
+	}
+}
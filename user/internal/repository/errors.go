@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// notFoundAs 将 gorm.ErrRecordNotFound 转换为对应的业务错误，其他错误原样返回
+func notFoundAs(err, target error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return target
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// DBLoginRecord 登录记录数据库模型
+type DBLoginRecord struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement"`
+	UserID     int64  `gorm:"index:idx_login_history_user_created,priority:1;not null"`
+	Account    string `gorm:"type:varchar(100)"`
+	Method     string `gorm:"type:varchar(32);not null"`
+	Success    bool   `gorm:"not null"`
+	Reason     string `gorm:"type:varchar(255)"`
+	IP         string `gorm:"type:varchar(64)"`
+	DeviceID   string `gorm:"type:varchar(128)"`
+	DeviceName string `gorm:"type:varchar(100)"`
+	UserAgent  string `gorm:"type:varchar(500)"`
+	NewDevice  bool   `gorm:"not null;default:false"`
+	CreatedAt  int64  `gorm:"index:idx_login_history_user_created,priority:2;not null"`
+}
+
+func (DBLoginRecord) TableName() string {
+	return "user_login_history"
+}
+
+// LoginHistoryRepository 登录历史仓库
+type LoginHistoryRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewLoginHistoryRepository 创建登录历史仓库
+func NewLoginHistoryRepository(db *gorm.DB, namer *TableNamer) *LoginHistoryRepository {
+	return &LoginHistoryRepository{db: db, namer: namer}
+}
+
+// records 登录记录表
+func (r *LoginHistoryRepository) records(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBLoginRecord{}.TableName()))
+}
+
+// InitTable 初始化数据库表
+func (r *LoginHistoryRepository) InitTable(ctx context.Context) error {
+	return r.records(ctx).AutoMigrate(&DBLoginRecord{})
+}
+
+// Create 写入登录记录
+func (r *LoginHistoryRepository) Create(ctx context.Context, record *model.LoginRecord) error {
+	dbRecord := &DBLoginRecord{
+		UserID:     record.UserID,
+		Account:    record.Account,
+		Method:     record.Method,
+		Success:    record.Success,
+		Reason:     record.Reason,
+		IP:         record.IP,
+		DeviceID:   record.DeviceID,
+		DeviceName: record.DeviceName,
+		UserAgent:  record.UserAgent,
+		NewDevice:  record.NewDevice,
+		CreatedAt:  record.CreatedAt,
+	}
+	if err := r.records(ctx).Create(dbRecord).Error; err != nil {
+		return err
+	}
+	record.ID = dbRecord.ID
+	return nil
+}
+
+// List 按时间倒序分页查询用户的登录记录，返回当前页记录和总数
+func (r *LoginHistoryRepository) List(ctx context.Context, userID int64, offset, limit int) ([]*model.LoginRecord, int64, error) {
+	query := r.records(ctx).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var dbRecords []DBLoginRecord
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&dbRecords).Error; err != nil {
+		return nil, 0, err
+	}
+	records := make([]*model.LoginRecord, 0, len(dbRecords))
+	for i := range dbRecords {
+		records = append(records, r.toModel(&dbRecords[i]))
+	}
+	return records, total, nil
+}
+
+// HasSucceeded 用户是否有过成功登录的记录
+func (r *LoginHistoryRepository) HasSucceeded(ctx context.Context, userID int64) (bool, error) {
+	var count int64
+	err := r.records(ctx).
+		Where("user_id = ? AND success = ?", userID, true).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// HasSucceededOnDevice 用户是否在该设备上成功登录过，有设备 ID 时按设备 ID 判断，否则按 User-Agent 判断
+func (r *LoginHistoryRepository) HasSucceededOnDevice(ctx context.Context, userID int64, deviceID, userAgent string) (bool, error) {
+	query := r.records(ctx).Where("user_id = ? AND success = ?", userID, true)
+	if deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	} else {
+		query = query.Where("device_id = '' AND user_agent = ?", userAgent)
+	}
+
+	var count int64
+	err := query.Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// toModel 转换为业务模型
+func (r *LoginHistoryRepository) toModel(dbRecord *DBLoginRecord) *model.LoginRecord {
+	return &model.LoginRecord{
+		ID:         dbRecord.ID,
+		UserID:     dbRecord.UserID,
+		Account:    dbRecord.Account,
+		Method:     dbRecord.Method,
+		Success:    dbRecord.Success,
+		Reason:     dbRecord.Reason,
+		IP:         dbRecord.IP,
+		DeviceID:   dbRecord.DeviceID,
+		DeviceName: dbRecord.DeviceName,
+		UserAgent:  dbRecord.UserAgent,
+		NewDevice:  dbRecord.NewDevice,
+		CreatedAt:  dbRecord.CreatedAt,
+	}
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/migrate"
+)
+
+// MigrationTable 迁移记录默认表名
+const MigrationTable = "user_schema_migrations"
+
+// Migrations 用户模块的全部数据库迁移
+// 已发布的迁移不可修改，表结构变更需追加新版本
+func Migrations(namer *TableNamer) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version: 1,
+			Name:    "init",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewUserRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init user table failed: %w", err)
+				}
+				if err := NewCodeRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init code table failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 2,
+			Name:    "oauth_bindings",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				// 手机号改为可空，第三方登录创建的用户没有手机号
+				if err := NewUserRepository(db, namer).AlterPhoneNullable(ctx); err != nil {
+					return fmt.Errorf("alter user phone failed: %w", err)
+				}
+				if err := NewOAuthRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init oauth binding table failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 3,
+			Name:    "refresh_tokens",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewRefreshTokenRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init refresh token table failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 4,
+			Name:    "sessions",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewSessionRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init session table failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 5,
+			Name:    "two_factor",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewTwoFactorRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init two factor table failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 6,
+			Name:    "code_attempts",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewCodeRepository(db, namer).AddAttemptsColumn(ctx); err != nil {
+					return fmt.Errorf("add code attempts column failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 7,
+			Name:    "rbac",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewRoleRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init role tables failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 8,
+			Name:    "must_reset_password",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewUserRepository(db, namer).AddMustResetPasswordColumn(ctx); err != nil {
+					return fmt.Errorf("add must reset password column failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 9,
+			Name:    "account_deletion",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewUserRepository(db, namer).AddDeactivatedAtColumn(ctx); err != nil {
+					return fmt.Errorf("add deactivated at column failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 10,
+			Name:    "username_changed_at",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewUserRepository(db, namer).AddUsernameChangedAtColumn(ctx); err != nil {
+					return fmt.Errorf("add username changed at column failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 11,
+			Name:    "login_history",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewLoginHistoryRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init login history table failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 12,
+			Name:    "devices",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewDeviceRepository(db, namer).InitTable(ctx); err != nil {
+					return fmt.Errorf("init device table failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 13,
+			Name:    "privacy_settings",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewUserRepository(db, namer).AddPrivacyColumns(ctx); err != nil {
+					return fmt.Errorf("add privacy columns failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 14,
+			Name:    "tenant_id",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewUserRepository(db, namer).AddTenantColumn(ctx); err != nil {
+					return fmt.Errorf("add user tenant column failed: %w", err)
+				}
+				if err := NewCodeRepository(db, namer).AddTenantColumn(ctx); err != nil {
+					return fmt.Errorf("add code tenant column failed: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Version: 15,
+			Name:    "phone_e164",
+			Up: func(ctx context.Context, db *gorm.DB) error {
+				if err := NewUserRepository(db, namer).AddPhoneRegionColumn(ctx); err != nil {
+					return fmt.Errorf("add phone region column failed: %w", err)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// NewMigrator 创建用户模块的迁移执行器
+func NewMigrator(db *gorm.DB, namer *TableNamer) *migrate.Migrator {
+	return migrate.New(db, namer.Table(MigrationTable), Migrations(namer))
+}
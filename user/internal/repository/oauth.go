@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// DBOAuthBinding 第三方账号绑定数据库模型
+type DBOAuthBinding struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	UserID    int64  `gorm:"index:idx_oauth_user_id;not null"`
+	Provider  string `gorm:"type:varchar(20);uniqueIndex:uk_provider_open_id,priority:1;not null"`
+	OpenID    string `gorm:"type:varchar(128);uniqueIndex:uk_provider_open_id,priority:2;not null"`
+	UnionID   string `gorm:"type:varchar(128)"`
+	CreatedAt int64  `gorm:"not null"`
+}
+
+func (DBOAuthBinding) TableName() string {
+	return "user_oauth_bindings"
+}
+
+// OAuthRepository 第三方账号绑定仓库
+type OAuthRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewOAuthRepository 创建第三方账号绑定仓库
+func NewOAuthRepository(db *gorm.DB, namer *TableNamer) *OAuthRepository {
+	return &OAuthRepository{db: db, namer: namer}
+}
+
+// bindings 绑定表
+func (r *OAuthRepository) bindings(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBOAuthBinding{}.TableName()))
+}
+
+// InitTable 初始化数据库表
+func (r *OAuthRepository) InitTable(ctx context.Context) error {
+	return r.bindings(ctx).AutoMigrate(&DBOAuthBinding{})
+}
+
+// Create 创建绑定
+func (r *OAuthRepository) Create(ctx context.Context, binding *model.OAuthBinding) error {
+	dbBinding := r.toDB(binding)
+	if err := r.bindings(ctx).Create(dbBinding).Error; err != nil {
+		return err
+	}
+	binding.ID = dbBinding.ID
+	return nil
+}
+
+// CreateWithUser 在同一事务中创建用户和绑定，用于第三方账号首次登录
+func (r *OAuthRepository) CreateWithUser(ctx context.Context, user *model.User, binding *model.OAuthBinding) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := NewUserRepository(tx, r.namer).Create(ctx, user); err != nil {
+			return err
+		}
+		binding.UserID = user.ID
+		return NewOAuthRepository(tx, r.namer).Create(ctx, binding)
+	})
+}
+
+// GetByOpenID 根据提供方和第三方账号 ID 获取绑定
+func (r *OAuthRepository) GetByOpenID(ctx context.Context, provider, openID string) (*model.OAuthBinding, error) {
+	var dbBinding DBOAuthBinding
+	err := r.bindings(ctx).
+		Where("provider = ? AND open_id = ?", provider, openID).
+		First(&dbBinding).Error
+	if err != nil {
+		return nil, notFoundAs(err, model.ErrOAuthNotBound)
+	}
+	return r.toModel(&dbBinding), nil
+}
+
+// GetByUserID 获取用户的全部绑定
+func (r *OAuthRepository) GetByUserID(ctx context.Context, userID int64) ([]*model.OAuthBinding, error) {
+	var dbBindings []DBOAuthBinding
+	if err := r.bindings(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&dbBindings).Error; err != nil {
+		return nil, err
+	}
+	bindings := make([]*model.OAuthBinding, 0, len(dbBindings))
+	for i := range dbBindings {
+		bindings = append(bindings, r.toModel(&dbBindings[i]))
+	}
+	return bindings, nil
+}
+
+// DeleteByUserProvider 删除用户在指定提供方的绑定
+func (r *OAuthRepository) DeleteByUserProvider(ctx context.Context, userID int64, provider string) (int64, error) {
+	result := r.bindings(ctx).
+		Where("user_id = ? AND provider = ?", userID, provider).
+		Delete(&DBOAuthBinding{})
+	return result.RowsAffected, result.Error
+}
+
+// toDB 转换为数据库模型
+func (r *OAuthRepository) toDB(binding *model.OAuthBinding) *DBOAuthBinding {
+	return &DBOAuthBinding{
+		ID:        binding.ID,
+		UserID:    binding.UserID,
+		Provider:  binding.Provider,
+		OpenID:    binding.OpenID,
+		UnionID:   binding.UnionID,
+		CreatedAt: binding.CreatedAt,
+	}
+}
+
+// toModel 转换为业务模型
+func (r *OAuthRepository) toModel(dbBinding *DBOAuthBinding) *model.OAuthBinding {
+	return &model.OAuthBinding{
+		ID:        dbBinding.ID,
+		UserID:    dbBinding.UserID,
+		Provider:  dbBinding.Provider,
+		OpenID:    dbBinding.OpenID,
+		UnionID:   dbBinding.UnionID,
+		CreatedAt: dbBinding.CreatedAt,
+	}
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// DBRefreshToken 刷新令牌数据库模型
+type DBRefreshToken struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	UserID    int64  `gorm:"index:idx_refresh_user_id;not null"`
+	SessionID string `gorm:"type:varchar(64);index:idx_refresh_session_id;not null"`
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex:uk_token_hash;not null"`
+	ExpireAt  int64  `gorm:"index:idx_refresh_expire_at;not null"`
+	RevokedAt int64  `gorm:"not null;default:0"`
+	CreatedAt int64  `gorm:"not null"`
+}
+
+func (DBRefreshToken) TableName() string {
+	return "user_refresh_tokens"
+}
+
+// RefreshTokenRepository 刷新令牌仓库
+type RefreshTokenRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewRefreshTokenRepository 创建刷新令牌仓库
+func NewRefreshTokenRepository(db *gorm.DB, namer *TableNamer) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db, namer: namer}
+}
+
+// tokens 刷新令牌表
+func (r *RefreshTokenRepository) tokens(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBRefreshToken{}.TableName()))
+}
+
+// InitTable 初始化数据库表
+func (r *RefreshTokenRepository) InitTable(ctx context.Context) error {
+	return r.tokens(ctx).AutoMigrate(&DBRefreshToken{})
+}
+
+// Create 创建刷新令牌
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	dbToken := &DBRefreshToken{
+		UserID:    token.UserID,
+		SessionID: token.SessionID,
+		TokenHash: token.TokenHash,
+		ExpireAt:  token.ExpireAt,
+		RevokedAt: token.RevokedAt,
+		CreatedAt: token.CreatedAt,
+	}
+	if err := r.tokens(ctx).Create(dbToken).Error; err != nil {
+		return err
+	}
+	token.ID = dbToken.ID
+	return nil
+}
+
+// GetByHash 根据令牌哈希获取刷新令牌
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var dbToken DBRefreshToken
+	if err := r.tokens(ctx).Where("token_hash = ?", tokenHash).First(&dbToken).Error; err != nil {
+		return nil, notFoundAs(err, model.ErrInvalidRefreshToken)
+	}
+	return r.toModel(&dbToken), nil
+}
+
+// Revoke 吊销单个令牌，返回是否由本次调用吊销（用于轮换时的并发控制）
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id int64, now int64) (bool, error) {
+	result := r.tokens(ctx).
+		Where("id = ? AND revoked_at = 0", id).
+		Update("revoked_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeSession 吊销会话下的全部令牌
+func (r *RefreshTokenRepository) RevokeSession(ctx context.Context, sessionID string, now int64) error {
+	return r.tokens(ctx).
+		Where("session_id = ? AND revoked_at = 0", sessionID).
+		Update("revoked_at", now).Error
+}
+
+// RevokeByUser 吊销用户的全部令牌
+func (r *RefreshTokenRepository) RevokeByUser(ctx context.Context, userID int64, now int64) error {
+	return r.tokens(ctx).
+		Where("user_id = ? AND revoked_at = 0", userID).
+		Update("revoked_at", now).Error
+}
+
+// RevokeByUserExcept 吊销用户除 sessionID 会话以外的全部令牌
+func (r *RefreshTokenRepository) RevokeByUserExcept(ctx context.Context, userID int64, sessionID string, now int64) error {
+	return r.tokens(ctx).
+		Where("user_id = ? AND session_id <> ? AND revoked_at = 0", userID, sessionID).
+		Update("revoked_at", now).Error
+}
+
+// DeleteExpired 删除指定时间之前过期的令牌
+func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context, before int64) (int64, error) {
+	result := r.tokens(ctx).Where("expire_at < ?", before).Delete(&DBRefreshToken{})
+	return result.RowsAffected, result.Error
+}
+
+// toModel 转换为业务模型
+func (r *RefreshTokenRepository) toModel(dbToken *DBRefreshToken) *model.RefreshToken {
+	return &model.RefreshToken{
+		ID:        dbToken.ID,
+		UserID:    dbToken.UserID,
+		SessionID: dbToken.SessionID,
+		TokenHash: dbToken.TokenHash,
+		ExpireAt:  dbToken.ExpireAt,
+		RevokedAt: dbToken.RevokedAt,
+		CreatedAt: dbToken.CreatedAt,
+	}
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// DBRole 角色数据库模型
+type DBRole struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement"`
+	Name        string `gorm:"type:varchar(64);uniqueIndex:uk_role_name;not null"`
+	Description string `gorm:"type:varchar(255)"`
+	CreatedAt   int64  `gorm:"not null"`
+	UpdatedAt   int64  `gorm:"not null"`
+}
+
+func (DBRole) TableName() string {
+	return "user_roles"
+}
+
+// DBRolePermission 角色权限数据库模型
+type DBRolePermission struct {
+	RoleID     int64  `gorm:"primaryKey;autoIncrement:false"`
+	Permission string `gorm:"type:varchar(128);primaryKey"`
+}
+
+func (DBRolePermission) TableName() string {
+	return "user_role_permissions"
+}
+
+// DBUserRole 用户角色数据库模型
+type DBUserRole struct {
+	UserID    int64 `gorm:"primaryKey;autoIncrement:false"`
+	RoleID    int64 `gorm:"primaryKey;autoIncrement:false;index:idx_user_role_role_id"`
+	CreatedAt int64 `gorm:"not null"`
+}
+
+func (DBUserRole) TableName() string {
+	return "user_user_roles"
+}
+
+// RoleRepository 角色仓库
+type RoleRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewRoleRepository 创建角色仓库
+func NewRoleRepository(db *gorm.DB, namer *TableNamer) *RoleRepository {
+	return &RoleRepository{db: db, namer: namer}
+}
+
+// roles 角色表
+func (r *RoleRepository) roles(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBRole{}.TableName()))
+}
+
+// permissions 角色权限表
+func (r *RoleRepository) permissions(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBRolePermission{}.TableName()))
+}
+
+// userRoles 用户角色表
+func (r *RoleRepository) userRoles(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBUserRole{}.TableName()))
+}
+
+// InitTable 初始化数据库表
+func (r *RoleRepository) InitTable(ctx context.Context) error {
+	if err := r.roles(ctx).AutoMigrate(&DBRole{}); err != nil {
+		return err
+	}
+	if err := r.permissions(ctx).AutoMigrate(&DBRolePermission{}); err != nil {
+		return err
+	}
+	return r.userRoles(ctx).AutoMigrate(&DBUserRole{})
+}
+
+// Create 创建角色及其权限
+func (r *RoleRepository) Create(ctx context.Context, role *model.Role) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := NewRoleRepository(tx, r.namer)
+		dbRole := &DBRole{
+			Name:        role.Name,
+			Description: role.Description,
+			CreatedAt:   role.CreatedAt,
+			UpdatedAt:   role.UpdatedAt,
+		}
+		if err := repo.roles(ctx).Create(dbRole).Error; err != nil {
+			return err
+		}
+		role.ID = dbRole.ID
+		return repo.insertPermissions(ctx, role.ID, role.Permissions)
+	})
+}
+
+// GetByName 根据角色名获取角色
+func (r *RoleRepository) GetByName(ctx context.Context, name string) (*model.Role, error) {
+	var dbRole DBRole
+	if err := r.roles(ctx).Where("name = ?", name).First(&dbRole).Error; err != nil {
+		return nil, notFoundAs(err, model.ErrRoleNotFound)
+	}
+	roles, err := r.withPermissions(ctx, []DBRole{dbRole})
+	if err != nil {
+		return nil, err
+	}
+	return roles[0], nil
+}
+
+// ExistsByName 检查角色名是否存在
+func (r *RoleRepository) ExistsByName(ctx context.Context, name string) (bool, error) {
+	var count int64
+	if err := r.roles(ctx).Where("name = ?", name).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// List 获取全部角色
+func (r *RoleRepository) List(ctx context.Context) ([]*model.Role, error) {
+	var dbRoles []DBRole
+	if err := r.roles(ctx).Order("id ASC").Find(&dbRoles).Error; err != nil {
+		return nil, err
+	}
+	return r.withPermissions(ctx, dbRoles)
+}
+
+// Delete 删除角色，同时删除其权限和用户关联
+func (r *RoleRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := NewRoleRepository(tx, r.namer)
+		if err := repo.userRoles(ctx).Where("role_id = ?", id).Delete(&DBUserRole{}).Error; err != nil {
+			return err
+		}
+		if err := repo.permissions(ctx).Where("role_id = ?", id).Delete(&DBRolePermission{}).Error; err != nil {
+			return err
+		}
+		return repo.roles(ctx).Where("id = ?", id).Delete(&DBRole{}).Error
+	})
+}
+
+// SetPermissions 覆盖角色的权限
+func (r *RoleRepository) SetPermissions(ctx context.Context, id int64, permissions []string, now int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := NewRoleRepository(tx, r.namer)
+		if err := repo.permissions(ctx).Where("role_id = ?", id).Delete(&DBRolePermission{}).Error; err != nil {
+			return err
+		}
+		if err := repo.insertPermissions(ctx, id, permissions); err != nil {
+			return err
+		}
+		return repo.roles(ctx).Where("id = ?", id).Update("updated_at", now).Error
+	})
+}
+
+// AssignToUser 为用户分配角色，已分配时忽略
+func (r *RoleRepository) AssignToUser(ctx context.Context, userID, roleID, now int64) error {
+	return r.userRoles(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&DBUserRole{UserID: userID, RoleID: roleID, CreatedAt: now}).Error
+}
+
+// RevokeFromUser 撤销用户的角色
+func (r *RoleRepository) RevokeFromUser(ctx context.Context, userID, roleID int64) error {
+	return r.userRoles(ctx).
+		Where("user_id = ? AND role_id = ?", userID, roleID).
+		Delete(&DBUserRole{}).Error
+}
+
+// GetByUserID 获取用户的全部角色
+func (r *RoleRepository) GetByUserID(ctx context.Context, userID int64) ([]*model.Role, error) {
+	var roleIDs []int64
+	if err := r.userRoles(ctx).Where("user_id = ?", userID).Pluck("role_id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(roleIDs) == 0 {
+		return []*model.Role{}, nil
+	}
+
+	var dbRoles []DBRole
+	if err := r.roles(ctx).Where("id IN ?", roleIDs).Order("id ASC").Find(&dbRoles).Error; err != nil {
+		return nil, err
+	}
+	return r.withPermissions(ctx, dbRoles)
+}
+
+// insertPermissions 批量写入角色权限
+func (r *RoleRepository) insertPermissions(ctx context.Context, roleID int64, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	rows := make([]DBRolePermission, 0, len(permissions))
+	for _, permission := range permissions {
+		rows = append(rows, DBRolePermission{RoleID: roleID, Permission: permission})
+	}
+	return r.permissions(ctx).Create(&rows).Error
+}
+
+// withPermissions 查询角色的权限并转换为业务模型
+func (r *RoleRepository) withPermissions(ctx context.Context, dbRoles []DBRole) ([]*model.Role, error) {
+	roles := make([]*model.Role, 0, len(dbRoles))
+	if len(dbRoles) == 0 {
+		return roles, nil
+	}
+
+	ids := make([]int64, 0, len(dbRoles))
+	for _, dbRole := range dbRoles {
+		ids = append(ids, dbRole.ID)
+	}
+	var rows []DBRolePermission
+	if err := r.permissions(ctx).Where("role_id IN ?", ids).Order("permission ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	permissions := make(map[int64][]string, len(dbRoles))
+	for _, row := range rows {
+		permissions[row.RoleID] = append(permissions[row.RoleID], row.Permission)
+	}
+
+	for _, dbRole := range dbRoles {
+		perms := permissions[dbRole.ID]
+		if perms == nil {
+			perms = []string{}
+		}
+		roles = append(roles, &model.Role{
+			ID:          dbRole.ID,
+			Name:        dbRole.Name,
+			Description: dbRole.Description,
+			Permissions: perms,
+			CreatedAt:   dbRole.CreatedAt,
+			UpdatedAt:   dbRole.UpdatedAt,
+		})
+	}
+	return roles, nil
+}
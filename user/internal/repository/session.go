@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// DBSession 会话数据库模型
+type DBSession struct {
+	ID           string `gorm:"type:varchar(64);primaryKey"`
+	UserID       int64  `gorm:"index:idx_session_user_id;not null"`
+	DeviceID     string `gorm:"type:varchar(128)"`
+	DeviceName   string `gorm:"type:varchar(100)"`
+	IP           string `gorm:"type:varchar(64)"`
+	UserAgent    string `gorm:"type:varchar(500)"`
+	CreatedAt    int64  `gorm:"not null"`
+	LastActiveAt int64  `gorm:"not null"`
+	ExpireAt     int64  `gorm:"index:idx_session_expire_at;not null"`
+	RevokedAt    int64  `gorm:"not null;default:0"`
+}
+
+func (DBSession) TableName() string {
+	return "user_sessions"
+}
+
+// SessionRepository 会话仓库，默认的会话存储
+type SessionRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewSessionRepository 创建会话仓库
+func NewSessionRepository(db *gorm.DB, namer *TableNamer) *SessionRepository {
+	return &SessionRepository{db: db, namer: namer}
+}
+
+// sessions 会话表
+func (r *SessionRepository) sessions(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBSession{}.TableName()))
+}
+
+// active 有效会话
+func (r *SessionRepository) active(ctx context.Context) *gorm.DB {
+	return r.sessions(ctx).Where("revoked_at = 0 AND expire_at > ?", model.NowMillis())
+}
+
+// InitTable 初始化数据库表
+func (r *SessionRepository) InitTable(ctx context.Context) error {
+	return r.sessions(ctx).AutoMigrate(&DBSession{})
+}
+
+// Create 创建会话
+func (r *SessionRepository) Create(ctx context.Context, session *model.Session) error {
+	return r.sessions(ctx).Create(&DBSession{
+		ID:           session.ID,
+		UserID:       session.UserID,
+		DeviceID:     session.DeviceID,
+		DeviceName:   session.DeviceName,
+		IP:           session.IP,
+		UserAgent:    session.UserAgent,
+		CreatedAt:    session.CreatedAt,
+		LastActiveAt: session.LastActiveAt,
+		ExpireAt:     session.ExpireAt,
+	}).Error
+}
+
+// Get 获取有效会话
+func (r *SessionRepository) Get(ctx context.Context, sessionID string) (*model.Session, error) {
+	var dbSession DBSession
+	if err := r.active(ctx).Where("id = ?", sessionID).First(&dbSession).Error; err != nil {
+		return nil, notFoundAs(err, model.ErrSessionNotFound)
+	}
+	return r.toModel(&dbSession), nil
+}
+
+// Touch 更新会话活跃时间和过期时间
+func (r *SessionRepository) Touch(ctx context.Context, sessionID string, lastActiveAt, expireAt int64) error {
+	return r.sessions(ctx).
+		Where("id = ? AND revoked_at = 0", sessionID).
+		Updates(map[string]interface{}{
+			"last_active_at": lastActiveAt,
+			"expire_at":      expireAt,
+		}).Error
+}
+
+// ListByUser 获取用户的全部有效会话，最近活跃的在前
+func (r *SessionRepository) ListByUser(ctx context.Context, userID int64) ([]*model.Session, error) {
+	var dbSessions []DBSession
+	if err := r.active(ctx).Where("user_id = ?", userID).Order("last_active_at DESC").Find(&dbSessions).Error; err != nil {
+		return nil, err
+	}
+	sessions := make([]*model.Session, 0, len(dbSessions))
+	for i := range dbSessions {
+		sessions = append(sessions, r.toModel(&dbSessions[i]))
+	}
+	return sessions, nil
+}
+
+// Revoke 吊销会话
+func (r *SessionRepository) Revoke(ctx context.Context, sessionID string) error {
+	return r.sessions(ctx).
+		Where("id = ? AND revoked_at = 0", sessionID).
+		Update("revoked_at", model.NowMillis()).Error
+}
+
+// RevokeByUser 吊销用户的全部会话
+func (r *SessionRepository) RevokeByUser(ctx context.Context, userID int64) error {
+	return r.sessions(ctx).
+		Where("user_id = ? AND revoked_at = 0", userID).
+		Update("revoked_at", model.NowMillis()).Error
+}
+
+// toModel 转换为业务模型
+func (r *SessionRepository) toModel(dbSession *DBSession) *model.Session {
+	return &model.Session{
+		ID:           dbSession.ID,
+		UserID:       dbSession.UserID,
+		DeviceID:     dbSession.DeviceID,
+		DeviceName:   dbSession.DeviceName,
+		IP:           dbSession.IP,
+		UserAgent:    dbSession.UserAgent,
+		CreatedAt:    dbSession.CreatedAt,
+		LastActiveAt: dbSession.LastActiveAt,
+		ExpireAt:     dbSession.ExpireAt,
+	}
+}
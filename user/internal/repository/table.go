@@ -0,0 +1,21 @@
+package repository
+
+// TableNamer 表名解析器，支持统一前缀和按表覆盖
+type TableNamer struct {
+	// Prefix 表名前缀，拼接在默认表名之前（如 "app_" -> "app_user_users"）
+	Prefix string
+
+	// Overrides 按默认表名覆盖实际表名（如 {"user_users": "accounts"}），优先于 Prefix
+	Overrides map[string]string
+}
+
+// Table 返回默认表名对应的实际表名
+func (n *TableNamer) Table(defaultName string) string {
+	if n == nil {
+		return defaultName
+	}
+	if name, ok := n.Overrides[defaultName]; ok && name != "" {
+		return name
+	}
+	return n.Prefix + defaultName
+}
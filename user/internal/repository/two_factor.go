@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// DBTwoFactor 两步验证数据库模型
+type DBTwoFactor struct {
+	UserID        int64  `gorm:"primaryKey;autoIncrement:false"`
+	Secret        string `gorm:"type:varchar(64);not null"`
+	Enabled       bool   `gorm:"not null;default:false"`
+	RecoveryCodes string `gorm:"type:text"` // 恢复码哈希 JSON 数组
+	LastUsedStep  int64  `gorm:"not null;default:0"`
+	CreatedAt     int64  `gorm:"not null"`
+	UpdatedAt     int64  `gorm:"not null"`
+}
+
+func (DBTwoFactor) TableName() string {
+	return "user_two_factor"
+}
+
+// TwoFactorRepository 两步验证仓库
+type TwoFactorRepository struct {
+	db    *gorm.DB
+	namer *TableNamer
+}
+
+// NewTwoFactorRepository 创建两步验证仓库
+func NewTwoFactorRepository(db *gorm.DB, namer *TableNamer) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db, namer: namer}
+}
+
+// twoFactors 两步验证表
+func (r *TwoFactorRepository) twoFactors(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBTwoFactor{}.TableName()))
+}
+
+// InitTable 初始化数据库表
+func (r *TwoFactorRepository) InitTable(ctx context.Context) error {
+	return r.twoFactors(ctx).AutoMigrate(&DBTwoFactor{})
+}
+
+// Get 获取用户的两步验证配置，不存在时返回 ErrTwoFactorNotEnabled
+func (r *TwoFactorRepository) Get(ctx context.Context, userID int64) (*model.TwoFactor, error) {
+	var dbTwoFactor DBTwoFactor
+	if err := r.twoFactors(ctx).Where("user_id = ?", userID).First(&dbTwoFactor).Error; err != nil {
+		return nil, notFoundAs(err, model.ErrTwoFactorNotEnabled)
+	}
+	return r.toModel(&dbTwoFactor)
+}
+
+// Save 创建或覆盖用户的两步验证配置
+func (r *TwoFactorRepository) Save(ctx context.Context, twoFactor *model.TwoFactor) error {
+	dbTwoFactor, err := r.toDB(twoFactor)
+	if err != nil {
+		return err
+	}
+	return r.twoFactors(ctx).Save(dbTwoFactor).Error
+}
+
+// UpdateIfUnchanged 更新配置，仅当上次使用的时间步和恢复码未被并发修改时生效，返回是否更新成功
+func (r *TwoFactorRepository) UpdateIfUnchanged(ctx context.Context, old, updated *model.TwoFactor) (bool, error) {
+	oldDB, err := r.toDB(old)
+	if err != nil {
+		return false, err
+	}
+	newDB, err := r.toDB(updated)
+	if err != nil {
+		return false, err
+	}
+	result := r.twoFactors(ctx).
+		Where("user_id = ? AND last_used_step = ? AND recovery_codes = ?", oldDB.UserID, oldDB.LastUsedStep, oldDB.RecoveryCodes).
+		Updates(map[string]interface{}{
+			"enabled":        newDB.Enabled,
+			"recovery_codes": newDB.RecoveryCodes,
+			"last_used_step": newDB.LastUsedStep,
+			"updated_at":     newDB.UpdatedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete 删除用户的两步验证配置
+func (r *TwoFactorRepository) Delete(ctx context.Context, userID int64) error {
+	return r.twoFactors(ctx).Where("user_id = ?", userID).Delete(&DBTwoFactor{}).Error
+}
+
+// toDB 转换为数据库模型
+func (r *TwoFactorRepository) toDB(twoFactor *model.TwoFactor) (*DBTwoFactor, error) {
+	codes := twoFactor.RecoveryCodes
+	if codes == nil {
+		codes = []string{}
+	}
+	data, err := json.Marshal(codes)
+	if err != nil {
+		return nil, err
+	}
+	return &DBTwoFactor{
+		UserID:        twoFactor.UserID,
+		Secret:        twoFactor.Secret,
+		Enabled:       twoFactor.Enabled,
+		RecoveryCodes: string(data),
+		LastUsedStep:  twoFactor.LastUsedStep,
+		CreatedAt:     twoFactor.CreatedAt,
+		UpdatedAt:     twoFactor.UpdatedAt,
+	}, nil
+}
+
+// toModel 转换为业务模型
+func (r *TwoFactorRepository) toModel(dbTwoFactor *DBTwoFactor) (*model.TwoFactor, error) {
+	var codes []string
+	if dbTwoFactor.RecoveryCodes != "" {
+		if err := json.Unmarshal([]byte(dbTwoFactor.RecoveryCodes), &codes); err != nil {
+			return nil, err
+		}
+	}
+	return &model.TwoFactor{
+		UserID:        dbTwoFactor.UserID,
+		Secret:        dbTwoFactor.Secret,
+		Enabled:       dbTwoFactor.Enabled,
+		RecoveryCodes: codes,
+		LastUsedStep:  dbTwoFactor.LastUsedStep,
+		CreatedAt:     dbTwoFactor.CreatedAt,
+		UpdatedAt:     dbTwoFactor.UpdatedAt,
+	}, nil
+}
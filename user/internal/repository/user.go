@@ -1,28 +1,41 @@
 package repository
 
 import (
+	"context"
+	"fmt"
 	"strings"
 	
 	"gorm.io/gorm"
 
+	"github.com/bbadbeef/go-base/idgen"
+	"github.com/bbadbeef/go-base/user/internal/cache"
 	"github.com/bbadbeef/go-base/user/internal/model"
 )
 
 // DBUser 用户数据库模型
 type DBUser struct {
-	ID           int64   `gorm:"primaryKey;autoIncrement"`
-	Username     string  `gorm:"type:varchar(50);uniqueIndex:uk_username;not null"`
-	Phone        string  `gorm:"type:varchar(20);uniqueIndex:uk_phone;not null"`
-	PasswordHash string  `gorm:"type:varchar(255);not null"`
-	Nickname     string  `gorm:"type:varchar(50)"`
-	Avatar       string  `gorm:"type:varchar(500)"`
-	Email        string  `gorm:"type:varchar(100)"`
-	Gender       int     `gorm:"type:tinyint;default:0"`
-	Birthday     *string `gorm:"type:date"`
-	Signature    string  `gorm:"type:varchar(255)"`
-	Status       int     `gorm:"type:tinyint;default:1"`
-	CreatedAt    int64   `gorm:"index:idx_created_at;not null"`
-	UpdatedAt    int64   `gorm:"not null"`
+	ID                 int64   `gorm:"primaryKey;autoIncrement"`
+	TenantID           string  `gorm:"type:varchar(64);not null;default:'';uniqueIndex:uk_tenant_username,priority:1;uniqueIndex:uk_tenant_phone,priority:1"`
+	Username           string  `gorm:"type:varchar(50);uniqueIndex:uk_tenant_username,priority:2;not null"`
+	Phone              *string `gorm:"type:varchar(20);uniqueIndex:uk_tenant_phone,priority:2"` // E.164 格式，第三方登录创建的用户可无手机号
+	PhoneRegion        string  `gorm:"type:varchar(2);not null;default:''"`                     // 手机号所属地区，如 CN
+	PasswordHash       string  `gorm:"type:varchar(255);not null"`
+	Nickname           string  `gorm:"type:varchar(50)"`
+	Avatar             string  `gorm:"type:varchar(500)"`
+	Email              string  `gorm:"type:varchar(100)"`
+	Gender             int     `gorm:"type:tinyint;default:0"`
+	Birthday           *string `gorm:"type:date"`
+	Signature          string  `gorm:"type:varchar(255)"`
+	Status             int     `gorm:"type:tinyint;default:1"`
+	MustResetPassword  bool    `gorm:"not null;default:false"`
+	DeactivatedAt      int64   `gorm:"not null;default:0;index:idx_user_deactivated_at"`
+	UsernameChangedAt  int64   `gorm:"not null;default:0"`
+	LastSeenAt         int64   `gorm:"not null;default:0"`
+	PhoneVisibility    string  `gorm:"type:varchar(10);not null;default:'nobody'"`
+	BirthdayVisibility string  `gorm:"type:varchar(10);not null;default:'nobody'"`
+	LastSeenVisibility string  `gorm:"type:varchar(10);not null;default:'contacts'"`
+	CreatedAt          int64   `gorm:"index:idx_created_at;not null"`
+	UpdatedAt          int64   `gorm:"not null"`
 }
 
 func (DBUser) TableName() string {
@@ -31,17 +44,54 @@ func (DBUser) TableName() string {
 
 // UserRepository 用户仓库
 type UserRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	namer  *TableNamer
+	cache  cache.Store
+	tenant string
+	ids    idgen.Generator
 }
 
 // NewUserRepository 创建用户仓库
-func NewUserRepository(db *gorm.DB) *UserRepository {
-	return &UserRepository{db: db}
+func NewUserRepository(db *gorm.DB, namer *TableNamer) *UserRepository {
+	return &UserRepository{db: db, namer: namer}
+}
+
+// SetCache 设置用户缓存，GetByID 优先读取缓存，用户变更后删除缓存，nil 表示不缓存
+func (r *UserRepository) SetCache(c cache.Store) {
+	r.cache = c
+}
+
+// SetTenant 设置租户 ID，创建的用户归属该租户，查询和修改仅限该租户的用户
+func (r *UserRepository) SetTenant(tenant string) {
+	r.tenant = tenant
+}
+
+// SetIDGenerator 设置用户 ID 生成器，nil 时使用数据库自增 ID
+func (r *UserRepository) SetIDGenerator(ids idgen.Generator) {
+	r.ids = ids
+}
+
+// invalidate 删除用户缓存
+func (r *UserRepository) invalidate(ctx context.Context, ids ...int64) error {
+	if r.cache == nil {
+		return nil
+	}
+	return r.cache.Delete(ctx, ids...)
+}
+
+// users 用户表，限定当前租户
+func (r *UserRepository) users(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBUser{}.TableName())).Where("tenant_id = ?", r.tenant)
+}
+
+// table 用户表，不限定租户，用于表结构迁移
+func (r *UserRepository) table(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table(r.namer.Table(DBUser{}.TableName()))
 }
 
 // InitTable 初始化数据库表
-func (r *UserRepository) InitTable() error {
-	err := r.db.AutoMigrate(&DBUser{})
+func (r *UserRepository) InitTable(ctx context.Context) error {
+	err := r.table(ctx).AutoMigrate(&DBUser{})
 	// 忽略DROP不存在的索引/外键错误（GORM迁移的已知问题）
 	if err != nil && (strings.Contains(err.Error(), "Can't DROP") || 
 		strings.Contains(err.Error(), "check that column/key exists")) {
@@ -50,120 +100,604 @@ func (r *UserRepository) InitTable() error {
 	return err
 }
 
+// AlterPhoneNullable 手机号字段改为可空（v2 迁移）
+func (r *UserRepository) AlterPhoneNullable(ctx context.Context) error {
+	return r.table(ctx).Migrator().AlterColumn(&DBUser{}, "Phone")
+}
+
+// AddMustResetPasswordColumn 添加强制重置密码字段（v8 迁移）
+func (r *UserRepository) AddMustResetPasswordColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if migrator.HasColumn(&DBUser{}, "MustResetPassword") {
+		return nil
+	}
+	return migrator.AddColumn(&DBUser{}, "MustResetPassword")
+}
+
+// AddDeactivatedAtColumn 添加申请注销时间字段及索引（v9 迁移）
+func (r *UserRepository) AddDeactivatedAtColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if !migrator.HasColumn(&DBUser{}, "DeactivatedAt") {
+		if err := migrator.AddColumn(&DBUser{}, "DeactivatedAt"); err != nil {
+			return err
+		}
+	}
+	if migrator.HasIndex(&DBUser{}, "idx_user_deactivated_at") {
+		return nil
+	}
+	return migrator.CreateIndex(&DBUser{}, "idx_user_deactivated_at")
+}
+
+// AddUsernameChangedAtColumn 添加用户名修改时间字段（v10 迁移）
+func (r *UserRepository) AddUsernameChangedAtColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if migrator.HasColumn(&DBUser{}, "UsernameChangedAt") {
+		return nil
+	}
+	return migrator.AddColumn(&DBUser{}, "UsernameChangedAt")
+}
+
+// AddPrivacyColumns 添加最近活跃时间和隐私设置字段（v13 迁移）
+func (r *UserRepository) AddPrivacyColumns(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	for _, field := range []string{"LastSeenAt", "PhoneVisibility", "BirthdayVisibility", "LastSeenVisibility"} {
+		if migrator.HasColumn(&DBUser{}, field) {
+			continue
+		}
+		if err := migrator.AddColumn(&DBUser{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddTenantColumn 添加租户字段，用户名和手机号改为租户内唯一（v14 迁移）
+func (r *UserRepository) AddTenantColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if !migrator.HasColumn(&DBUser{}, "TenantID") {
+		if err := migrator.AddColumn(&DBUser{}, "TenantID"); err != nil {
+			return err
+		}
+	}
+	for _, index := range []struct{ old, new string }{
+		{"uk_username", "uk_tenant_username"},
+		{"uk_phone", "uk_tenant_phone"},
+	} {
+		if migrator.HasIndex(&DBUser{}, index.old) {
+			if err := migrator.DropIndex(&DBUser{}, index.old); err != nil {
+				return err
+			}
+		}
+		if migrator.HasIndex(&DBUser{}, index.new) {
+			continue
+		}
+		if err := migrator.CreateIndex(&DBUser{}, index.new); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPhoneRegionColumn 添加手机号地区字段，已有手机号转换为 E.164 格式（v15 迁移）
+// 此前仅支持中国大陆手机号，不含国家码的号码均按 +86 转换
+func (r *UserRepository) AddPhoneRegionColumn(ctx context.Context) error {
+	migrator := r.table(ctx).Migrator()
+	if !migrator.HasColumn(&DBUser{}, "PhoneRegion") {
+		if err := migrator.AddColumn(&DBUser{}, "PhoneRegion"); err != nil {
+			return err
+		}
+	}
+
+	for {
+		var dbUsers []DBUser
+		if err := r.table(ctx).Select("id", "phone").
+			Where("phone IS NOT NULL AND phone NOT LIKE ?", "+%").
+			Limit(500).
+			Find(&dbUsers).Error; err != nil {
+			return err
+		}
+		if len(dbUsers) == 0 {
+			return nil
+		}
+		for _, dbUser := range dbUsers {
+			err := r.table(ctx).Where("id = ?", dbUser.ID).Updates(map[string]interface{}{
+				"phone":        "+86" + *dbUser.Phone,
+				"phone_region": "CN",
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // Create 创建用户
-func (r *UserRepository) Create(user *model.User) error {
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 	dbUser := &DBUser{
-		Username:     user.Username,
-		Phone:        user.Phone,
-		PasswordHash: user.PasswordHash,
-		Nickname:     user.Nickname,
-		Avatar:       user.Avatar,
-		Email:        user.Email,
-		Gender:       user.Gender,
-		Birthday:     user.Birthday,
-		Signature:    user.Signature,
-		Status:       user.Status,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-	}
-
-	if err := r.db.Create(dbUser).Error; err != nil {
+		TenantID:          r.tenant,
+		Username:          user.Username,
+		Phone:             nullString(user.Phone),
+		PhoneRegion:       user.PhoneRegion,
+		PasswordHash:      user.PasswordHash,
+		Nickname:          user.Nickname,
+		Avatar:            user.Avatar,
+		Email:             user.Email,
+		Gender:            user.Gender,
+		Birthday:          user.Birthday,
+		Signature:         user.Signature,
+		Status:            user.Status,
+		MustResetPassword: user.MustResetPassword,
+		DeactivatedAt:     user.DeactivatedAt,
+		UsernameChangedAt: user.UsernameChangedAt,
+		LastSeenAt:        user.LastSeenAt,
+		CreatedAt:         user.CreatedAt,
+		UpdatedAt:         user.UpdatedAt,
+	}
+	setPrivacy(dbUser, user.Privacy)
+	if r.ids != nil {
+		dbUser.ID = r.ids.NextID()
+	}
+
+	if err := r.users(ctx).Create(dbUser).Error; err != nil {
 		return err
 	}
 
 	user.ID = dbUser.ID
+	user.TenantID = dbUser.TenantID
 	return nil
 }
 
-// GetByID 根据 ID 获取用户
-func (r *UserRepository) GetByID(id int64) (*model.User, error) {
+// GetByID 根据 ID 获取用户，设置缓存时优先读取缓存，缓存不可用时直接查询数据库
+func (r *UserRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	if r.cache != nil {
+		if user, err := r.cache.Get(ctx, id); err == nil && user != nil && user.TenantID == r.tenant {
+			return user, nil
+		}
+	}
+
 	var dbUser DBUser
-	if err := r.db.First(&dbUser, id).Error; err != nil {
-		return nil, err
+	if err := r.users(ctx).First(&dbUser, id).Error; err != nil {
+		return nil, notFoundAs(err, model.ErrUserNotFound)
 	}
-	return r.toModel(&dbUser), nil
+	user := r.toModel(&dbUser)
+	if r.cache != nil {
+		_ = r.cache.Set(ctx, user)
+	}
+	return user, nil
 }
 
 // GetByUsername 根据用户名获取用户
-func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var dbUser DBUser
-	if err := r.db.Where("username = ?", username).First(&dbUser).Error; err != nil {
-		return nil, err
+	if err := r.users(ctx).Where("username = ?", username).First(&dbUser).Error; err != nil {
+		return nil, notFoundAs(err, model.ErrUserNotFound)
 	}
 	return r.toModel(&dbUser), nil
 }
 
 // GetByPhone 根据手机号获取用户
-func (r *UserRepository) GetByPhone(phone string) (*model.User, error) {
+func (r *UserRepository) GetByPhone(ctx context.Context, phone string) (*model.User, error) {
 	var dbUser DBUser
-	if err := r.db.Where("phone = ?", phone).First(&dbUser).Error; err != nil {
-		return nil, err
+	if err := r.users(ctx).Where("phone = ?", phone).First(&dbUser).Error; err != nil {
+		return nil, notFoundAs(err, model.ErrUserNotFound)
 	}
 	return r.toModel(&dbUser), nil
 }
 
 // ExistsByUsername 检查用户名是否存在
-func (r *UserRepository) ExistsByUsername(username string) (bool, error) {
+func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	var count int64
-	if err := r.db.Model(&DBUser{}).Where("username = ?", username).Count(&count).Error; err != nil {
+	if err := r.users(ctx).Model(&DBUser{}).Where("username = ?", username).Count(&count).Error; err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
 // ExistsByPhone 检查手机号是否存在
-func (r *UserRepository) ExistsByPhone(phone string) (bool, error) {
+func (r *UserRepository) ExistsByPhone(ctx context.Context, phone string) (bool, error) {
 	var count int64
-	if err := r.db.Model(&DBUser{}).Where("phone = ?", phone).Count(&count).Error; err != nil {
+	if err := r.users(ctx).Model(&DBUser{}).Where("phone = ?", phone).Count(&count).Error; err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
 // Update 更新用户信息
-func (r *UserRepository) Update(user *model.User) error {
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
 	dbUser := &DBUser{
-		ID:           user.ID,
-		Username:     user.Username,
-		Phone:        user.Phone,
-		PasswordHash: user.PasswordHash,
-		Nickname:     user.Nickname,
-		Avatar:       user.Avatar,
-		Email:        user.Email,
-		Gender:       user.Gender,
-		Birthday:     user.Birthday,
-		Signature:    user.Signature,
-		Status:       user.Status,
-		UpdatedAt:    user.UpdatedAt,
-	}
-	return r.db.Save(dbUser).Error
-}
-
-// UpdatePassword 更新密码
-func (r *UserRepository) UpdatePassword(userID int64, passwordHash string) error {
-	return r.db.Model(&DBUser{}).
+		ID:                user.ID,
+		TenantID:          r.tenant,
+		Username:          user.Username,
+		Phone:             nullString(user.Phone),
+		PhoneRegion:       user.PhoneRegion,
+		PasswordHash:      user.PasswordHash,
+		Nickname:          user.Nickname,
+		Avatar:            user.Avatar,
+		Email:             user.Email,
+		Gender:            user.Gender,
+		Birthday:          user.Birthday,
+		Signature:         user.Signature,
+		Status:            user.Status,
+		MustResetPassword: user.MustResetPassword,
+		DeactivatedAt:     user.DeactivatedAt,
+		UsernameChangedAt: user.UsernameChangedAt,
+		LastSeenAt:        user.LastSeenAt,
+		CreatedAt:         user.CreatedAt,
+		UpdatedAt:         user.UpdatedAt,
+	}
+	setPrivacy(dbUser, user.Privacy)
+	if err := r.users(ctx).Save(dbUser).Error; err != nil {
+		return err
+	}
+	return r.invalidate(ctx, user.ID)
+}
+
+// UpdatePassword 更新密码，同时清除强制重置密码标记
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	err := r.users(ctx).Model(&DBUser{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"password_hash":       passwordHash,
+			"must_reset_password": false,
+			"updated_at":          model.NowMillis(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// UpdatePasswordHash 仅更新密码哈希（登录时升级哈希算法），不改变重置密码标记
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, userID int64, passwordHash string) error {
+	err := r.users(ctx).Model(&DBUser{}).
+		Where("id = ?", userID).
+		Update("password_hash", passwordHash).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// List 按条件分页查询用户，返回当前页用户和总数
+func (r *UserRepository) List(ctx context.Context, req *model.ListUsersRequest, offset, limit int) ([]*model.User, int64, error) {
+	query := r.filter(r.users(ctx), req)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var dbUsers []DBUser
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&dbUsers).Error; err != nil {
+		return nil, 0, err
+	}
+	users := make([]*model.User, 0, len(dbUsers))
+	for i := range dbUsers {
+		users = append(users, r.toModel(&dbUsers[i]))
+	}
+	return users, total, nil
+}
+
+// ListAfter 按 ID 升序查询 afterID 之后符合条件的用户，用于分批导出
+func (r *UserRepository) ListAfter(ctx context.Context, req *model.ListUsersRequest, afterID int64, limit int) ([]*model.User, error) {
+	var dbUsers []DBUser
+	err := r.filter(r.users(ctx), req).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&dbUsers).Error
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*model.User, 0, len(dbUsers))
+	for i := range dbUsers {
+		users = append(users, r.toModel(&dbUsers[i]))
+	}
+	return users, nil
+}
+
+// filter 添加用户列表的查询条件
+func (r *UserRepository) filter(query *gorm.DB, req *model.ListUsersRequest) *gorm.DB {
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+	if req.CreatedFrom > 0 {
+		query = query.Where("created_at >= ?", req.CreatedFrom)
+	}
+	if req.CreatedTo > 0 {
+		query = query.Where("created_at < ?", req.CreatedTo)
+	}
+	if req.Keyword != "" {
+		like := "%" + escapeLike(req.Keyword) + "%"
+		query = query.Where("username LIKE ? ESCAPE '!' OR phone LIKE ? ESCAPE '!' OR nickname LIKE ? ESCAPE '!'", like, like, like)
+	}
+	return query
+}
+
+// UpdatePhone 更新手机号及其所属地区
+func (r *UserRepository) UpdatePhone(ctx context.Context, userID int64, phone, region string) error {
+	err := r.users(ctx).Model(&DBUser{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"phone":        nullString(phone),
+			"phone_region": region,
+			"updated_at":   model.NowMillis(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// UpdateUsername 更新用户名并记录修改时间
+func (r *UserRepository) UpdateUsername(ctx context.Context, userID int64, username string, now int64) error {
+	err := r.users(ctx).Model(&DBUser{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"username":            username,
+			"username_changed_at": now,
+			"updated_at":          now,
+		}).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// UpdatePrivacy 更新隐私设置
+func (r *UserRepository) UpdatePrivacy(ctx context.Context, userID int64, privacy model.PrivacySettings) error {
+	err := r.users(ctx).Model(&DBUser{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"phone_visibility":     privacy.Phone,
+			"birthday_visibility":  privacy.Birthday,
+			"last_seen_visibility": privacy.LastSeen,
+			"updated_at":           model.NowMillis(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// UpdateLastSeen 更新最近活跃时间，不改变 updated_at
+func (r *UserRepository) UpdateLastSeen(ctx context.Context, userID, now int64) error {
+	err := r.users(ctx).Model(&DBUser{}).
+		Where("id = ?", userID).
+		Update("last_seen_at", now).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// UpdateStatus 更新用户状态
+func (r *UserRepository) UpdateStatus(ctx context.Context, userID int64, status int) error {
+	err := r.users(ctx).Model(&DBUser{}).
 		Where("id = ?", userID).
 		Updates(map[string]interface{}{
-			"password_hash": passwordHash,
-			"updated_at":    model.NowMillis(),
+			"status":     status,
+			"updated_at": model.NowMillis(),
 		}).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// SetMustResetPassword 设置强制重置密码标记
+func (r *UserRepository) SetMustResetPassword(ctx context.Context, userID int64) error {
+	err := r.users(ctx).Model(&DBUser{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"must_reset_password": true,
+			"updated_at":          model.NowMillis(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// DeleteWithRelations 在同一事务中删除用户及其第三方绑定、刷新令牌、两步验证、角色、登录记录和设备
+func (r *UserRepository) DeleteWithRelations(ctx context.Context, userID int64) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Table(r.namer.Table(DBUser{}.TableName())).
+			Where("id = ? AND tenant_id = ?", userID, r.tenant).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return model.ErrUserNotFound
+		}
+		if err := r.deleteRelations(tx, userID); err != nil {
+			return err
+		}
+		return tx.Table(r.namer.Table(DBUser{}.TableName())).
+			Where("id = ? AND tenant_id = ?", userID, r.tenant).
+			Delete(&DBUser{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// Deactivate 进入注销冷静期
+func (r *UserRepository) Deactivate(ctx context.Context, userID, now int64) error {
+	err := r.users(ctx).Model(&DBUser{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"status":         model.UserStatusDeactivated,
+			"deactivated_at": now,
+			"updated_at":     now,
+		}).Error
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// Reactivate 冷静期内恢复账号，账号不在冷静期时返回 false
+func (r *UserRepository) Reactivate(ctx context.Context, userID int64) (bool, error) {
+	result := r.users(ctx).Model(&DBUser{}).
+		Where("id = ? AND status = ?", userID, model.UserStatusDeactivated).
+		Updates(map[string]interface{}{
+			"status":         model.UserStatusNormal,
+			"deactivated_at": 0,
+			"updated_at":     model.NowMillis(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	return true, r.invalidate(ctx, userID)
+}
+
+// ListDeactivatedBefore 获取申请注销时间早于 before 的冷静期用户 ID
+func (r *UserRepository) ListDeactivatedBefore(ctx context.Context, before int64, limit int) ([]int64, error) {
+	var ids []int64
+	err := r.users(ctx).
+		Where("status = ? AND deactivated_at <= ?", model.UserStatusDeactivated, before).
+		Order("deactivated_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Anonymize 在同一事务中匿名化用户并删除其验证码、第三方绑定、刷新令牌、两步验证、角色、登录记录和设备
+// 用户记录保留，已发送的消息等数据仍可关联到该用户 ID
+func (r *UserRepository) Anonymize(ctx context.Context, userID int64, nickname string, now int64) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		users := tx.Table(r.namer.Table(DBUser{}.TableName()))
+
+		var dbUser DBUser
+		if err := users.Where("id = ? AND tenant_id = ?", userID, r.tenant).First(&dbUser).Error; err != nil {
+			return notFoundAs(err, model.ErrUserNotFound)
+		}
+		if dbUser.Phone != nil {
+			if err := tx.Table(r.namer.Table(DBVerificationCode{}.TableName())).
+				Where("tenant_id = ? AND phone = ?", r.tenant, *dbUser.Phone).
+				Delete(&DBVerificationCode{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := r.deleteRelations(tx, userID); err != nil {
+			return err
+		}
+
+		return tx.Table(r.namer.Table(DBUser{}.TableName())).Model(&DBUser{}).
+			Where("id = ? AND tenant_id = ?", userID, r.tenant).
+			Updates(map[string]interface{}{
+				"username":            fmt.Sprintf("deleted_%d", userID),
+				"phone":               nil,
+				"phone_region":        "",
+				"password_hash":       "",
+				"nickname":            nickname,
+				"avatar":              "",
+				"email":               "",
+				"gender":              model.GenderUnknown,
+				"birthday":            nil,
+				"signature":           "",
+				"status":              model.UserStatusDeleted,
+				"must_reset_password": false,
+				"last_seen_at":        0,
+				"updated_at":          now,
+			}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return r.invalidate(ctx, userID)
+}
+
+// deleteRelations 删除用户的第三方绑定、刷新令牌、两步验证、角色、登录记录和设备
+func (r *UserRepository) deleteRelations(tx *gorm.DB, userID int64) error {
+	tables := []struct {
+		name  string
+		model interface{}
+	}{
+		{DBOAuthBinding{}.TableName(), &DBOAuthBinding{}},
+		{DBRefreshToken{}.TableName(), &DBRefreshToken{}},
+		{DBTwoFactor{}.TableName(), &DBTwoFactor{}},
+		{DBUserRole{}.TableName(), &DBUserRole{}},
+		{DBLoginRecord{}.TableName(), &DBLoginRecord{}},
+		{DBDevice{}.TableName(), &DBDevice{}},
+	}
+	for _, t := range tables {
+		if err := tx.Table(r.namer.Table(t.name)).Where("user_id = ?", userID).Delete(t.model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // toModel 转换为业务模型
 func (r *UserRepository) toModel(dbUser *DBUser) *model.User {
 	return &model.User{
-		ID:           dbUser.ID,
-		Username:     dbUser.Username,
-		Phone:        dbUser.Phone,
-		PasswordHash: dbUser.PasswordHash,
-		Nickname:     dbUser.Nickname,
-		Avatar:       dbUser.Avatar,
-		Email:        dbUser.Email,
-		Gender:       dbUser.Gender,
-		Birthday:     dbUser.Birthday,
-		Signature:    dbUser.Signature,
-		Status:       dbUser.Status,
-		CreatedAt:    dbUser.CreatedAt,
-		UpdatedAt:    dbUser.UpdatedAt,
+		ID:                dbUser.ID,
+		TenantID:          dbUser.TenantID,
+		Username:          dbUser.Username,
+		Phone:             stringValue(dbUser.Phone),
+		PhoneRegion:       dbUser.PhoneRegion,
+		PasswordHash:      dbUser.PasswordHash,
+		Nickname:          dbUser.Nickname,
+		Avatar:            dbUser.Avatar,
+		Email:             dbUser.Email,
+		Gender:            dbUser.Gender,
+		Birthday:          dbUser.Birthday,
+		Signature:         dbUser.Signature,
+		Status:            dbUser.Status,
+		MustResetPassword: dbUser.MustResetPassword,
+		DeactivatedAt:     dbUser.DeactivatedAt,
+		UsernameChangedAt: dbUser.UsernameChangedAt,
+		LastSeenAt:        dbUser.LastSeenAt,
+		CreatedAt:         dbUser.CreatedAt,
+		UpdatedAt:         dbUser.UpdatedAt,
+		Privacy: model.PrivacySettings{
+			Phone:    dbUser.PhoneVisibility,
+			Birthday: dbUser.BirthdayVisibility,
+			LastSeen: dbUser.LastSeenVisibility,
+		},
+	}
+}
+
+// setPrivacy 写入隐私设置，未设置的字段使用默认值
+func setPrivacy(dbUser *DBUser, privacy model.PrivacySettings) {
+	defaults := model.DefaultPrivacySettings()
+	dbUser.PhoneVisibility = orDefault(privacy.Phone, defaults.Phone)
+	dbUser.BirthdayVisibility = orDefault(privacy.Birthday, defaults.Birthday)
+	dbUser.LastSeenVisibility = orDefault(privacy.LastSeen, defaults.LastSeen)
+}
+
+// orDefault 空字符串时返回默认值
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// nullString 空字符串转为 NULL，避免唯一索引冲突
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// escapeLike 转义 LIKE 通配符，配合 ESCAPE '!' 使用
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// stringValue 读取可空字符串
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
 	}
+	return *s
 }
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+)
+
+// deletedNickname 注销后用户的昵称
+const deletedNickname = "已注销用户"
+
+// purgeBatchSize 每次清理冷静期届满账号的数量
+const purgeBatchSize = 100
+
+// AccountDeletionHook 账号注销钩子，在匿名化用户信息之前调用，用于清理其他模块中的用户数据
+// 钩子需可重复执行，返回错误时注销中止，下次重试会再次调用
+type AccountDeletionHook func(ctx context.Context, userID int64) error
+
+// AccountService 账号注销服务
+type AccountService struct {
+	userRepo    *repository.UserRepository
+	tokenSvc    *TokenService
+	gracePeriod time.Duration
+	hooks       []AccountDeletionHook
+}
+
+// NewAccountService 创建账号注销服务
+func NewAccountService(userRepo *repository.UserRepository, tokenSvc *TokenService, gracePeriod time.Duration, hooks []AccountDeletionHook) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		tokenSvc:    tokenSvc,
+		gracePeriod: gracePeriod,
+		hooks:       hooks,
+	}
+}
+
+// Deactivate 申请注销，进入冷静期并吊销全部会话
+func (s *AccountService) Deactivate(ctx context.Context, userID int64) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	switch user.Status {
+	case model.UserStatusDeleted:
+		return model.ErrUserNotFound
+	case model.UserStatusDeactivated:
+		return nil
+	case model.UserStatusDisabled:
+		return model.ErrUserDisabled
+	}
+
+	if err := s.userRepo.Deactivate(ctx, userID, model.NowMillis()); err != nil {
+		return err
+	}
+	return s.tokenSvc.RevokeAll(ctx, userID)
+}
+
+// Reactivate 冷静期内登录时恢复账号
+func (s *AccountService) Reactivate(ctx context.Context, user *model.User) error {
+	if user.Status != model.UserStatusDeactivated {
+		return nil
+	}
+	if _, err := s.userRepo.Reactivate(ctx, user.ID); err != nil {
+		return err
+	}
+	user.Status = model.UserStatusNormal
+	user.DeactivatedAt = 0
+	return nil
+}
+
+// Delete 立即注销：吊销会话，调用注销钩子，匿名化用户信息
+func (s *AccountService) Delete(ctx context.Context, userID int64) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Status == model.UserStatusDeleted {
+		return nil
+	}
+
+	if err := s.tokenSvc.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+	for _, hook := range s.hooks {
+		if err := hook(ctx, userID); err != nil {
+			return fmt.Errorf("account deletion hook failed: %w", err)
+		}
+	}
+	return s.userRepo.Anonymize(ctx, userID, deletedNickname, model.NowMillis())
+}
+
+// PurgeExpired 注销冷静期已届满的账号（每次最多 100 个），返回注销成功的数量
+// 单个账号注销失败不影响其他账号，错误合并返回
+func (s *AccountService) PurgeExpired(ctx context.Context) (int, error) {
+	before := model.NowMillis() - s.gracePeriod.Milliseconds()
+	ids, err := s.userRepo.ListDeactivatedBefore(ctx, before, purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		purged int
+		errs   []error
+	)
+	for _, id := range ids {
+		if err := s.Delete(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("delete account %d: %w", id, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+)
+
+// 用户列表分页
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// AdminService 用户管理服务，供管理后台使用
+type AdminService struct {
+	userRepo *repository.UserRepository
+	tokenSvc *TokenService
+}
+
+// NewAdminService 创建用户管理服务
+func NewAdminService(userRepo *repository.UserRepository, tokenSvc *TokenService) *AdminService {
+	return &AdminService{
+		userRepo: userRepo,
+		tokenSvc: tokenSvc,
+	}
+}
+
+// ListUsers 按条件分页查询用户
+func (s *AdminService) ListUsers(ctx context.Context, req *model.ListUsersRequest) (*model.UserList, error) {
+	if req == nil {
+		req = &model.ListUsersRequest{}
+	}
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	if req.CreatedFrom > 0 && req.CreatedTo > 0 && req.CreatedFrom >= req.CreatedTo {
+		return nil, model.NewParamError("created_to", "created_to must be after created_from")
+	}
+
+	users, total, err := s.userRepo.List(ctx, req, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserList{
+		Users:    users,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// DisableUser 禁用用户，并吊销其全部会话
+func (s *AdminService) DisableUser(ctx context.Context, userID int64) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdateStatus(ctx, userID, model.UserStatusDisabled); err != nil {
+		return err
+	}
+	return s.tokenSvc.RevokeAll(ctx, userID)
+}
+
+// EnableUser 启用用户
+func (s *AdminService) EnableUser(ctx context.Context, userID int64) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.userRepo.UpdateStatus(ctx, userID, model.UserStatusNormal)
+}
+
+// ForcePasswordReset 要求用户重置密码，吊销其全部会话
+// 重置前密码登录返回 ErrPasswordResetRequired，用户需通过验证码重置密码
+func (s *AdminService) ForcePasswordReset(ctx context.Context, userID int64) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.SetMustResetPassword(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokenSvc.RevokeAll(ctx, userID)
+}
+
+// DeleteUser 删除用户及其第三方绑定、令牌、两步验证、角色、登录记录和设备
+func (s *AdminService) DeleteUser(ctx context.Context, userID int64) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	// 先吊销会话，会话可能保存在 Redis 中，不在删除事务内
+	if err := s.tokenSvc.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+	return s.userRepo.DeleteWithRelations(ctx, userID)
+}
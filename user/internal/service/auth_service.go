@@ -1,44 +1,74 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
 	"fmt"
-	"regexp"
+	"math/big"
+	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
-
 	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/password"
+	"github.com/bbadbeef/go-base/user/internal/phone"
 	"github.com/bbadbeef/go-base/user/internal/repository"
 )
 
 // AuthService 认证服务
 type AuthService struct {
-	userRepo *repository.UserRepository
-	codeRepo *repository.CodeRepository
+	userRepo  *repository.UserRepository
+	codeRepo  *repository.CodeRepository
+	codeGuard *CodeGuard
+	captcha   *CaptchaGuard
+	passwords *PasswordValidator
+	phones    *PhoneValidator
+	hasher    *password.Hasher
 }
 
 // NewAuthService 创建认证服务
-func NewAuthService(userRepo *repository.UserRepository, codeRepo *repository.CodeRepository) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, codeRepo *repository.CodeRepository, codeGuard *CodeGuard, captcha *CaptchaGuard, passwords *PasswordValidator, phones *PhoneValidator, hasher *password.Hasher) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		codeRepo: codeRepo,
+		userRepo:  userRepo,
+		codeRepo:  codeRepo,
+		codeGuard: codeGuard,
+		captcha:   captcha,
+		passwords: passwords,
+		phones:    phones,
+		hasher:    hasher,
 	}
 }
 
+// NormalizeAccount 登录账号为手机号时转换为 E.164 格式，否则原样返回
+// 用户名以字母开头，不会被识别为手机号
+func (s *AuthService) NormalizeAccount(account string) string {
+	normalized, _ := s.phones.Normalize(account)
+	return normalized
+}
+
 // Register 用户注册
-func (s *AuthService) Register(req *model.RegisterRequest) (*model.User, error) {
+func (s *AuthService) Register(ctx context.Context, req *model.RegisterRequest) (*model.User, error) {
 	// 验证输入
-	if err := s.validateRegisterInput(req); err != nil {
+	number, err := s.validateRegisterInput(req)
+	if err != nil {
 		return nil, err
 	}
 
+	// 密码注册需要人机验证，验证码注册在发送验证码时已验证
+	if req.Password != "" {
+		if err := s.captcha.Verify(ctx, req.CaptchaToken); err != nil {
+			return nil, err
+		}
+	}
+
 	// 检查手机号是否存在
-	exists, err := s.userRepo.ExistsByPhone(req.Phone)
+	exists, err := s.userRepo.ExistsByPhone(ctx, number.E164)
 	if err != nil {
 		return nil, err
 	}
 	if exists {
-		return nil, fmt.Errorf("phone already exists")
+		return nil, model.ErrPhoneExists
 	}
 
 	var passwordHash string
@@ -46,7 +76,7 @@ func (s *AuthService) Register(req *model.RegisterRequest) (*model.User, error)
 	// 密码注册
 	if req.Password != "" {
 		// 验证密码
-		if err := s.validatePassword(req.Password); err != nil {
+		if err := s.passwords.Validate(ctx, "password", req.Password); err != nil {
 			return nil, err
 		}
 		// 加密密码
@@ -56,8 +86,8 @@ func (s *AuthService) Register(req *model.RegisterRequest) (*model.User, error)
 		}
 	} else if req.Code != "" {
 		// 验证码注册
-		if err := s.VerifyCode(req.Phone, req.Code, model.CodeTypeRegister); err != nil {
-			return nil, fmt.Errorf("invalid verification code: %w", err)
+		if err := s.VerifyCode(ctx, number.E164, req.Code, model.CodeTypeRegister); err != nil {
+			return nil, wrapCodeError(err)
 		}
 		// 验证码注册时，生成一个随机密码
 		randomPassword := s.generateRandomPassword()
@@ -66,19 +96,20 @@ func (s *AuthService) Register(req *model.RegisterRequest) (*model.User, error)
 			return nil, fmt.Errorf("hash password failed: %w", err)
 		}
 	} else {
-		return nil, fmt.Errorf("password or code is required")
+		return nil, model.NewParamError("password", "password or code is required")
 	}
 
 	// 生成随机昵称（user_开头+随机数）
 	nickname := s.generateRandomNickname()
-	// 生成用户名（基于手机号）
-	username := "u" + req.Phone
+	// 生成用户名（基于手机号，如 u8613800138000）
+	username := "u" + strings.TrimPrefix(number.E164, "+")
 
 	// 创建用户
 	now := model.NowMillis()
 	user := &model.User{
 		Username:     username,
-		Phone:        req.Phone,
+		Phone:        number.E164,
+		PhoneRegion:  number.Region,
 		PasswordHash: passwordHash,
 		Nickname:     nickname,
 		Status:       model.UserStatusNormal,
@@ -86,7 +117,7 @@ func (s *AuthService) Register(req *model.RegisterRequest) (*model.User, error)
 		UpdatedAt:    now,
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 
@@ -94,112 +125,148 @@ func (s *AuthService) Register(req *model.RegisterRequest) (*model.User, error)
 }
 
 // Login 密码登录（支持手机号或用户名）
-func (s *AuthService) Login(req *model.LoginRequest) (*model.User, error) {
+func (s *AuthService) Login(ctx context.Context, req *model.LoginRequest) (*model.User, error) {
 	if req.Account == "" {
-		return nil, fmt.Errorf("account is required")
+		return nil, model.NewParamError("account", "account is required")
 	}
 
 	var user *model.User
 	var err error
 
-	// 判断是否为手机号（如果是纯数字且长度为11，视为手机号）
-	isPhone := regexp.MustCompile(`^1[3-9]\d{9}$`).MatchString(req.Account)
+	// 判断是否为手机号，手机号转换为 E.164 格式后查询
+	account, isPhone := s.phones.Normalize(req.Account)
 
 	// 验证码登录（仅支持手机号）
 	if req.Code != "" {
 		if !isPhone {
-			return nil, fmt.Errorf("verification code login only supports phone number")
+			return nil, model.NewParamError("account", "verification code login only supports phone number")
 		}
 		// 验证验证码
-		if err := s.VerifyCode(req.Account, req.Code, model.CodeTypeLogin); err != nil {
-			return nil, fmt.Errorf("invalid verification code: %w", err)
+		if err := s.VerifyCode(ctx, account, req.Code, model.CodeTypeLogin); err != nil {
+			return nil, wrapCodeError(err)
 		}
 		// 获取用户
-		user, err = s.userRepo.GetByPhone(req.Account)
+		user, err = s.userRepo.GetByPhone(ctx, account)
 		if err != nil {
-			return nil, fmt.Errorf("user not found")
+			return nil, err
 		}
 	} else if req.Password != "" {
 		// 密码登录
 		// 尝试通过手机号或用户名获取用户
 		if isPhone {
-			user, err = s.userRepo.GetByPhone(req.Account)
+			user, err = s.userRepo.GetByPhone(ctx, account)
 		} else {
-			user, err = s.userRepo.GetByUsername(req.Account)
+			user, err = s.userRepo.GetByUsername(ctx, account)
 		}
 		
+		if errors.Is(err, model.ErrUserNotFound) {
+			return nil, model.ErrInvalidCredentials
+		}
 		if err != nil {
-			return nil, fmt.Errorf("invalid account or password")
+			return nil, err
 		}
 
 		// 验证密码
 		if err := s.verifyPassword(user.PasswordHash, req.Password); err != nil {
-			return nil, fmt.Errorf("invalid account or password")
+			return nil, model.ErrInvalidCredentials
 		}
+		s.rehashPassword(ctx, user, req.Password)
 	} else {
-		return nil, fmt.Errorf("password or code is required")
+		return nil, model.NewParamError("password", "password or code is required")
 	}
 
 	// 检查用户状态
-	if user.Status != model.UserStatusNormal {
-		return nil, fmt.Errorf("user is disabled")
+	if !user.CanLogin() {
+		return nil, model.ErrUserDisabled
+	}
+
+	// 管理员要求重置密码后，需通过验证码重置密码才能使用密码登录
+	if req.Code == "" && user.MustResetPassword {
+		return nil, model.ErrPasswordResetRequired
 	}
 
 	return user, nil
 }
 
 // LoginWithCode 验证码登录
-func (s *AuthService) LoginWithCode(phone, code string) (*model.User, error) {
+func (s *AuthService) LoginWithCode(ctx context.Context, phone, code string) (*model.User, error) {
 	if phone == "" || code == "" {
-		return nil, fmt.Errorf("phone and code are required")
+		return nil, model.NewParamError("phone", "phone and code are required")
 	}
+	phone, _ = s.phones.Normalize(phone)
 
 	// 验证验证码
-	if err := s.VerifyCode(phone, code, model.CodeTypeLogin); err != nil {
-		return nil, fmt.Errorf("invalid verification code: %w", err)
+	if err := s.VerifyCode(ctx, phone, code, model.CodeTypeLogin); err != nil {
+		return nil, wrapCodeError(err)
 	}
 
 	// 获取用户
-	user, err := s.userRepo.GetByPhone(phone)
+	user, err := s.userRepo.GetByPhone(ctx, phone)
 	if err != nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, err
 	}
 
 	// 检查用户状态
-	if user.Status != model.UserStatusNormal {
-		return nil, fmt.Errorf("user is disabled")
+	if !user.CanLogin() {
+		return nil, model.ErrUserDisabled
 	}
 
 	return user, nil
 }
 
+// VerifyCaptcha 校验人机验证令牌，未配置人机验证时返回 false
+func (s *AuthService) VerifyCaptcha(ctx context.Context, token string) (bool, error) {
+	if !s.captcha.Enabled() {
+		return false, nil
+	}
+	if err := s.captcha.Verify(ctx, token); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // VerifyCode 验证验证码
-func (s *AuthService) VerifyCode(phone, code string, codeType int) error {
-	// 获取最新验证码
-	latestCode, err := s.codeRepo.GetLatest(phone, codeType)
+func (s *AuthService) VerifyCode(ctx context.Context, phone, code string, codeType int) error {
+	// 获取最新验证码，验证码按 E.164 格式的手机号保存
+	phone, _ = s.phones.Normalize(phone)
+	latestCode, err := s.codeRepo.GetLatest(ctx, phone, codeType)
 	if err != nil {
-		return fmt.Errorf("verification code not found or expired")
+		return err
 	}
 
 	// 检查状态
-	if latestCode.Status != model.CodeStatusUnused {
-		return fmt.Errorf("verification code already used")
+	switch latestCode.Status {
+	case model.CodeStatusUnused:
+	case model.CodeStatusInvalid:
+		return model.ErrTooManyCodeAttempts
+	default:
+		return model.ErrCodeUsed
 	}
 
 	// 检查是否过期
 	now := model.NowMillis()
 	if now > latestCode.ExpireAt {
-		_ = s.codeRepo.MarkAsExpired(now)
-		return fmt.Errorf("verification code expired")
+		_ = s.codeRepo.MarkAsExpired(ctx, now)
+		return model.ErrCodeExpired
 	}
 
-	// 验证码匹配
-	if latestCode.Code != code {
-		return fmt.Errorf("invalid verification code")
+	// 验证码匹配，失败次数过多时作废
+	if subtle.ConstantTimeCompare([]byte(latestCode.Code), []byte(code)) != 1 {
+		attempts, err := s.codeRepo.IncrAttempts(ctx, latestCode.ID)
+		if err != nil {
+			return err
+		}
+		if max := s.codeGuard.MaxAttempts(); max > 0 && attempts >= max {
+			if err := s.codeRepo.MarkAsInvalid(ctx, latestCode.ID); err != nil {
+				return err
+			}
+			return model.ErrTooManyCodeAttempts
+		}
+		return model.ErrInvalidCode
 	}
 
 	// 标记为已使用
-	if err := s.codeRepo.MarkAsUsed(latestCode.ID); err != nil {
+	if err := s.codeRepo.MarkAsUsed(ctx, latestCode.ID); err != nil {
 		return err
 	}
 
@@ -207,20 +274,20 @@ func (s *AuthService) VerifyCode(phone, code string, codeType int) error {
 }
 
 // ChangePassword 修改密码
-func (s *AuthService) ChangePassword(userID int64, oldPassword, newPassword string) error {
+func (s *AuthService) ChangePassword(ctx context.Context, userID int64, oldPassword, newPassword string) error {
 	// 获取用户
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
 
 	// 验证旧密码
 	if err := s.verifyPassword(user.PasswordHash, oldPassword); err != nil {
-		return fmt.Errorf("invalid old password")
+		return model.ErrInvalidOldPassword
 	}
 
 	// 验证新密码
-	if err := s.validatePassword(newPassword); err != nil {
+	if err := s.passwords.Validate(ctx, "new_password", newPassword); err != nil {
 		return err
 	}
 
@@ -231,100 +298,147 @@ func (s *AuthService) ChangePassword(userID int64, oldPassword, newPassword stri
 	}
 
 	// 更新密码
-	return s.userRepo.UpdatePassword(userID, newPasswordHash)
+	return s.userRepo.UpdatePassword(ctx, userID, newPasswordHash)
 }
 
-// ResetPassword 重置密码（通过验证码）
-func (s *AuthService) ResetPassword(req *model.ResetPasswordRequest) error {
-	// 验证验证码
-	if err := s.VerifyCode(req.Phone, req.Code, model.CodeTypeResetPassword); err != nil {
+// ChangePhone 更换手机号，需校验发送到新手机号的验证码
+func (s *AuthService) ChangePhone(ctx context.Context, userID int64, newPhone, code string) error {
+	number, err := s.phones.Parse("new_phone", newPhone)
+	if err != nil {
 		return err
 	}
+	if code == "" {
+		return model.NewParamError("code", "code is required")
+	}
 
-	// 获取用户
-	user, err := s.userRepo.GetByPhone(req.Phone)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("user not found")
+		return err
+	}
+	if user.Phone == number.E164 {
+		return model.NewParamError("new_phone", "new phone is the same as current phone")
 	}
 
-	// 验证新密码
-	if err := s.validatePassword(req.NewPassword); err != nil {
+	// 检查新手机号是否已被使用
+	exists, err := s.userRepo.ExistsByPhone(ctx, number.E164)
+	if err != nil {
 		return err
 	}
+	if exists {
+		return model.ErrPhoneExists
+	}
+
+	// 验证新手机号的验证码
+	if err := s.VerifyCode(ctx, number.E164, code, model.CodeTypeChangePhone); err != nil {
+		return wrapCodeError(err)
+	}
+
+	return s.userRepo.UpdatePhone(ctx, userID, number.E164, number.Region)
+}
+
+// ResetPassword 重置密码（通过验证码），返回用户 ID
+func (s *AuthService) ResetPassword(ctx context.Context, req *model.ResetPasswordRequest) (int64, error) {
+	// 先验证新密码，不满足密码策略时不消耗验证码
+	if err := s.passwords.Validate(ctx, "new_password", req.NewPassword); err != nil {
+		return 0, err
+	}
+
+	// 验证验证码
+	phone, _ := s.phones.Normalize(req.Phone)
+	if err := s.VerifyCode(ctx, phone, req.Code, model.CodeTypeResetPassword); err != nil {
+		return 0, err
+	}
+
+	// 获取用户
+	user, err := s.userRepo.GetByPhone(ctx, phone)
+	if err != nil {
+		return 0, err
+	}
 
 	// 加密新密码
 	newPasswordHash, err := s.hashPassword(req.NewPassword)
 	if err != nil {
-		return fmt.Errorf("hash password failed: %w", err)
+		return 0, fmt.Errorf("hash password failed: %w", err)
 	}
 
 	// 更新密码
-	return s.userRepo.UpdatePassword(user.ID, newPasswordHash)
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, newPasswordHash); err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// wrapCodeError 包装验证码校验错误，errors.Is(err, ErrInvalidCode) 恒为 true
+func wrapCodeError(err error) error {
+	if errors.Is(err, model.ErrInvalidCode) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", model.ErrInvalidCode, err)
 }
 
 // hashPassword 加密密码
 func (s *AuthService) hashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hash), nil
+	return s.hasher.Hash(password)
 }
 
 // verifyPassword 验证密码
 func (s *AuthService) verifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return s.hasher.Verify(hashedPassword, password)
 }
 
-// validateRegisterInput 验证注册输入
-func (s *AuthService) validateRegisterInput(req *model.RegisterRequest) error {
-	if err := s.validatePhone(req.Phone); err != nil {
-		return err
+// rehashPassword 哈希算法或参数变更后，用登录时的明文密码升级已有哈希，失败不影响登录
+func (s *AuthService) rehashPassword(ctx context.Context, user *model.User, password string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
 	}
-
-	// 密码和验证码至少需要一个
-	if req.Password == "" && req.Code == "" {
-		return fmt.Errorf("password or verification code is required")
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return
+	}
+	if err := s.userRepo.UpdatePasswordHash(ctx, user.ID, hash); err == nil {
+		user.PasswordHash = hash
 	}
-
-	return nil
 }
 
-// validatePhone 验证手机号
-func (s *AuthService) validatePhone(phone string) error {
-	if phone == "" {
-		return fmt.Errorf("phone is required")
+// validateRegisterInput 验证注册输入，返回解析后的手机号
+func (s *AuthService) validateRegisterInput(req *model.RegisterRequest) (*phone.Number, error) {
+	number, err := s.phones.Parse("phone", req.Phone)
+	if err != nil {
+		return nil, err
 	}
 
-	if !regexp.MustCompile(`^1[3-9]\d{9}$`).MatchString(phone) {
-		return fmt.Errorf("invalid phone format")
+	// 密码和验证码至少需要一个
+	if req.Password == "" && req.Code == "" {
+		return nil, model.NewParamError("password", "password or verification code is required")
 	}
 
-	return nil
+	return number, nil
 }
 
-// validatePassword 验证密码
-func (s *AuthService) validatePassword(password string) error {
-	if password == "" {
-		return fmt.Errorf("password is required")
+// SendVerificationCode 发送验证码（需要外部实现短信发送），配置人机验证时需提供 captchaToken
+func (s *AuthService) SendVerificationCode(ctx context.Context, phone string, codeType int, captchaToken string) (string, error) {
+	// 验证手机号，按 E.164 格式计数和保存
+	number, err := s.phones.Parse("phone", phone)
+	if err != nil {
+		return "", err
 	}
+	phone = number.E164
 
-	if len(password) < 6 || len(password) > 20 {
-		return fmt.Errorf("password length must be between 6 and 20")
+	// 人机验证
+	if err := s.captcha.Verify(ctx, captchaToken); err != nil {
+		return "", err
 	}
 
-	return nil
-}
-
-// SendVerificationCode 发送验证码（需要外部实现短信发送）
-func (s *AuthService) SendVerificationCode(phone string, codeType int) (string, error) {
-	// 验证手机号
-	if err := s.validatePhone(phone); err != nil {
+	// 检查发送频率
+	if err := s.codeGuard.CheckSend(ctx, phone); err != nil {
 		return "", err
 	}
 
 	// 生成6位随机验证码
-	code := s.generateCode()
+	code, err := s.generateCode()
+	if err != nil {
+		return "", err
+	}
 
 	// 设置过期时间（5分钟）
 	expireAt := time.Now().Add(5 * time.Minute).UnixMilli()
@@ -339,7 +453,11 @@ func (s *AuthService) SendVerificationCode(phone string, codeType int) (string,
 		CreatedAt: model.NowMillis(),
 	}
 
-	if err := s.codeRepo.Create(verificationCode); err != nil {
+	if err := s.codeRepo.Create(ctx, verificationCode); err != nil {
+		return "", err
+	}
+
+	if err := s.codeGuard.RecordSend(ctx, phone); err != nil {
 		return "", err
 	}
 
@@ -347,8 +465,12 @@ func (s *AuthService) SendVerificationCode(phone string, codeType int) (string,
 }
 
 // generateCode 生成6位随机验证码
-func (s *AuthService) generateCode() string {
-	return fmt.Sprintf("%06d", time.Now().UnixNano()%1000000)
+func (s *AuthService) generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
 // generateRandomNickname 生成随机昵称（user_开头）
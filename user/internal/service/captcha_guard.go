@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// CaptchaVerifier 人机验证校验，如 NewRecaptchaVerifier，也可自行实现以接入极验等服务
+type CaptchaVerifier interface {
+	// Verify 校验客户端提交的人机验证令牌，remoteIP 为客户端 IP，可为空
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// CaptchaGuard 人机验证，注册和发送验证码前校验，防止短信轰炸和批量注册
+type CaptchaGuard struct {
+	verifier CaptchaVerifier
+}
+
+// NewCaptchaGuard 创建人机验证，verifier 为 nil 时不要求人机验证
+func NewCaptchaGuard(verifier CaptchaVerifier) *CaptchaGuard {
+	return &CaptchaGuard{verifier: verifier}
+}
+
+// Enabled 是否已配置人机验证
+func (g *CaptchaGuard) Enabled() bool {
+	return g.verifier != nil
+}
+
+// Verify 校验人机验证令牌，未配置时直接通过
+// 令牌为空时返回 ErrCaptchaRequired，校验不通过时返回 ErrInvalidCaptcha，校验服务出错时拒绝
+func (g *CaptchaGuard) Verify(ctx context.Context, token string) error {
+	if g.verifier == nil {
+		return nil
+	}
+	if token == "" {
+		return model.ErrCaptchaRequired
+	}
+	ok, err := g.verifier.Verify(ctx, token, model.ClientInfoFrom(ctx).IP)
+	if err != nil {
+		return fmt.Errorf("verify captcha failed: %w", err)
+	}
+	if !ok {
+		return model.ErrInvalidCaptcha
+	}
+	return nil
+}
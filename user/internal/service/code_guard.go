@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/ratelimit"
+)
+
+// CodeProtection 验证码防刷配置，零值字段使用默认值，负数表示不限制
+type CodeProtection struct {
+	Disabled         bool          // 关闭验证码防刷
+	ResendInterval   time.Duration // 同一手机号最小发送间隔，默认 60 秒
+	PhoneHourlyLimit int           // 同一手机号每小时最多发送次数，默认 5
+	PhoneDailyLimit  int           // 同一手机号每天最多发送次数，默认 10
+	IPHourlyLimit    int           // 同一 IP 每小时最多发送次数，默认 20
+	IPDailyLimit     int           // 同一 IP 每天最多发送次数，默认 50
+	MaxAttempts      int           // 单个验证码最多校验次数，超过后作废，默认 5
+}
+
+// withDefaults 填充默认值
+func (p CodeProtection) withDefaults() CodeProtection {
+	if p.ResendInterval == 0 {
+		p.ResendInterval = time.Minute
+	}
+	if p.PhoneHourlyLimit == 0 {
+		p.PhoneHourlyLimit = 5
+	}
+	if p.PhoneDailyLimit == 0 {
+		p.PhoneDailyLimit = 10
+	}
+	if p.IPHourlyLimit == 0 {
+		p.IPHourlyLimit = 20
+	}
+	if p.IPDailyLimit == 0 {
+		p.IPDailyLimit = 50
+	}
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 5
+	}
+	return p
+}
+
+// codeLimit 发送次数限制
+type codeLimit struct {
+	key    string
+	limit  int
+	window time.Duration
+}
+
+// CodeGuard 验证码防刷，限制发送频率和校验次数
+type CodeGuard struct {
+	store  ratelimit.Store
+	config CodeProtection
+}
+
+// NewCodeGuard 创建验证码防刷
+func NewCodeGuard(store ratelimit.Store, config CodeProtection) *CodeGuard {
+	return &CodeGuard{store: store, config: config.withDefaults()}
+}
+
+// MaxAttempts 单个验证码最多校验次数，0 表示不限制
+func (g *CodeGuard) MaxAttempts() int {
+	if g.config.Disabled || g.config.MaxAttempts < 0 {
+		return 0
+	}
+	return g.config.MaxAttempts
+}
+
+// CheckSend 发送前检查发送间隔和次数限制
+func (g *CodeGuard) CheckSend(ctx context.Context, phone string) error {
+	if g.config.Disabled {
+		return nil
+	}
+
+	if g.config.ResendInterval > 0 {
+		d, err := g.store.TTL(ctx, "code:cooldown:"+phone)
+		if err != nil {
+			return err
+		}
+		if d > 0 {
+			return &model.RateLimitError{Err: model.ErrCodeRateLimited, RetryAfter: d}
+		}
+	}
+
+	for _, l := range g.limits(ctx, phone) {
+		if l.limit < 0 {
+			continue
+		}
+		count, err := g.store.Get(ctx, l.key)
+		if err != nil {
+			return err
+		}
+		if count >= int64(l.limit) {
+			d, err := g.store.TTL(ctx, l.key)
+			if err != nil {
+				return err
+			}
+			return &model.RateLimitError{Err: model.ErrCodeRateLimited, RetryAfter: d}
+		}
+	}
+	return nil
+}
+
+// RecordSend 记录一次发送
+func (g *CodeGuard) RecordSend(ctx context.Context, phone string) error {
+	if g.config.Disabled {
+		return nil
+	}
+
+	if g.config.ResendInterval > 0 {
+		if err := g.store.Lock(ctx, "code:cooldown:"+phone, g.config.ResendInterval); err != nil {
+			return err
+		}
+	}
+	for _, l := range g.limits(ctx, phone) {
+		if l.limit < 0 {
+			continue
+		}
+		if _, err := g.store.Incr(ctx, l.key, l.window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limits 当前请求适用的发送次数限制
+func (g *CodeGuard) limits(ctx context.Context, phone string) []codeLimit {
+	limits := []codeLimit{
+		{"code:phone:hour:" + phone, g.config.PhoneHourlyLimit, time.Hour},
+		{"code:phone:day:" + phone, g.config.PhoneDailyLimit, 24 * time.Hour},
+	}
+	if ip := model.ClientInfoFrom(ctx).IP; ip != "" {
+		limits = append(limits,
+			codeLimit{"code:ip:hour:" + ip, g.config.IPHourlyLimit, time.Hour},
+			codeLimit{"code:ip:day:" + ip, g.config.IPDailyLimit, 24 * time.Hour},
+		)
+	}
+	return limits
+}
@@ -0,0 +1,27 @@
+package service
+
+// commonPasswords 常见弱密码（小写），PasswordPolicy.DenyCommon 开启时禁止使用
+var commonPasswords = func() map[string]struct{} {
+	list := []string{
+		"123456", "1234567", "12345678", "123456789", "1234567890", "12345678910",
+		"123123", "123321", "1234qwer", "123qwe", "123abc", "112233",
+		"111111", "000000", "666666", "888888", "999999", "121212",
+		"654321", "987654321", "0123456789", "147258", "147258369", "159357",
+		"5201314", "520520", "1314520", "woaini", "woaini1314", "iloveyou",
+		"password", "password1", "password123", "passw0rd", "p@ssw0rd", "p@ssword",
+		"qwerty", "qwerty123", "qwertyuiop", "qwe123", "qweasd", "qweasdzxc",
+		"asdfgh", "asdfghjkl", "asd123", "zxcvbn", "zxcvbnm", "1qaz2wsx",
+		"1q2w3e", "1q2w3e4r", "1q2w3e4r5t", "q1w2e3r4", "a123456", "a12345678",
+		"aa123456", "abc123", "abc12345", "abc123456", "abcd1234", "abcdef",
+		"admin", "admin123", "administrator", "root", "root123", "test123",
+		"letmein", "welcome", "welcome1", "monkey", "dragon", "master",
+		"sunshine", "princess", "football", "baseball", "superman", "batman",
+		"trustno1", "shadow", "michael", "charlie", "whatever", "freedom",
+		"starwars", "computer", "login", "hello123", "changeme", "secret",
+	}
+	m := make(map[string]struct{}, len(list))
+	for _, password := range list {
+		m[password] = struct{}{}
+	}
+	return m
+}()
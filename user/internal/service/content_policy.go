@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// 表情符号策略
+const (
+	EmojiAllow = "allow" // 允许表情符号
+	EmojiDeny  = "deny"  // 禁止表情符号
+)
+
+// ContentFilter 内容过滤，如接入第三方内容审核服务
+type ContentFilter interface {
+	// Blocked 内容是否违规，field 为 nickname 或 signature
+	Blocked(ctx context.Context, field, text string) (bool, error)
+}
+
+// ContentPolicy 昵称和个性签名校验策略，零值字段使用默认值
+type ContentPolicy struct {
+	NicknameMaxLength  int           // 昵称最大长度（字符数），默认 50，不能超过 50
+	SignatureMaxLength int           // 个性签名最大长度（字符数），默认 255，不能超过 255
+	BlockedWords       []string      // 屏蔽词，内容包含任一屏蔽词时拒绝（不区分大小写，忽略空白）
+	NicknameEmoji      string        // 昵称表情符号策略：allow（默认）或 deny
+	SignatureEmoji     string        // 个性签名表情符号策略：allow（默认）或 deny
+	Filter             ContentFilter // 自定义内容过滤（可选），检查出错时拒绝修改
+}
+
+// withDefaults 填充默认值，长度上限不超过数据库字段长度
+func (p ContentPolicy) withDefaults() ContentPolicy {
+	if p.NicknameMaxLength <= 0 || p.NicknameMaxLength > 50 {
+		p.NicknameMaxLength = 50
+	}
+	if p.SignatureMaxLength <= 0 || p.SignatureMaxLength > 255 {
+		p.SignatureMaxLength = 255
+	}
+	if p.NicknameEmoji == "" {
+		p.NicknameEmoji = EmojiAllow
+	}
+	if p.SignatureEmoji == "" {
+		p.SignatureEmoji = EmojiAllow
+	}
+	return p
+}
+
+// ContentValidator 按内容策略校验昵称和个性签名
+type ContentValidator struct {
+	policy  ContentPolicy
+	blocked []string
+}
+
+// NewContentValidator 创建内容校验器
+func NewContentValidator(policy ContentPolicy) *ContentValidator {
+	policy = policy.withDefaults()
+	blocked := make([]string, 0, len(policy.BlockedWords))
+	for _, word := range policy.BlockedWords {
+		if word = normalizeContent(word); word != "" {
+			blocked = append(blocked, word)
+		}
+	}
+	return &ContentValidator{policy: policy, blocked: blocked}
+}
+
+// ValidateNickname 校验昵称，不能为空，不能包含换行等控制字符
+func (v *ContentValidator) ValidateNickname(ctx context.Context, nickname string) error {
+	const field = "nickname"
+	if strings.TrimSpace(nickname) == "" {
+		return model.NewParamError(field, "nickname cannot be empty")
+	}
+	for _, r := range nickname {
+		if unicode.IsControl(r) {
+			return model.NewParamError(field, "nickname contains invalid characters")
+		}
+	}
+	return v.validate(ctx, field, nickname, v.policy.NicknameMaxLength, v.policy.NicknameEmoji)
+}
+
+// ValidateSignature 校验个性签名，可以为空，允许换行
+func (v *ContentValidator) ValidateSignature(ctx context.Context, signature string) error {
+	const field = "signature"
+	if signature == "" {
+		return nil
+	}
+	for _, r := range signature {
+		if unicode.IsControl(r) && r != '\n' {
+			return model.NewParamError(field, "signature contains invalid characters")
+		}
+	}
+	return v.validate(ctx, field, signature, v.policy.SignatureMaxLength, v.policy.SignatureEmoji)
+}
+
+// validate 校验长度、表情符号、屏蔽词和自定义过滤
+func (v *ContentValidator) validate(ctx context.Context, field, text string, maxLength int, emoji string) error {
+	if utf8.RuneCountInString(text) > maxLength {
+		return model.NewParamError(field, fmt.Sprintf("%s must be at most %d characters", field, maxLength))
+	}
+	if emoji == EmojiDeny && containsEmoji(text) {
+		return model.NewParamError(field, field+" cannot contain emoji")
+	}
+
+	normalized := normalizeContent(text)
+	for _, word := range v.blocked {
+		if strings.Contains(normalized, word) {
+			return model.NewParamError(field, field+" contains blocked words")
+		}
+	}
+
+	if v.policy.Filter != nil {
+		blocked, err := v.policy.Filter.Blocked(ctx, field, text)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return model.NewParamError(field, field+" contains blocked content")
+		}
+	}
+	return nil
+}
+
+// normalizeContent 转为小写并去除空白，避免通过大小写或插入空格绕过屏蔽词
+func normalizeContent(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// containsEmoji 是否包含表情符号
+func containsEmoji(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 0x1F000 && r <= 0x1FAFF, // 麻将、扑克、各类表情和象形符号
+			r >= 0x2600 && r <= 0x27BF,                // 杂项符号和装饰符号
+			r >= 0x2B00 && r <= 0x2BFF,                // 箭头和几何图形（如 ⭐）
+			r == 0x200D || r == 0xFE0F || r == 0x20E3: // 零宽连接符、表情变体选择符、组合键帽
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+)
+
+var deviceIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_.:-]{1,128}$`)
+
+// platforms 支持的设备平台
+var platforms = map[string]struct{}{
+	model.PlatformIOS:     {},
+	model.PlatformAndroid: {},
+	model.PlatformWeb:     {},
+	model.PlatformDesktop: {},
+}
+
+// DeviceService 设备服务，管理用户设备和推送令牌
+type DeviceService struct {
+	userRepo   *repository.UserRepository
+	deviceRepo *repository.DeviceRepository
+}
+
+// NewDeviceService 创建设备服务
+func NewDeviceService(userRepo *repository.UserRepository, deviceRepo *repository.DeviceRepository) *DeviceService {
+	return &DeviceService{
+		userRepo:   userRepo,
+		deviceRepo: deviceRepo,
+	}
+}
+
+// Register 注册设备，设备已存在时更新平台、名称和推送令牌
+func (s *DeviceService) Register(ctx context.Context, userID int64, req *model.RegisterDeviceRequest) (*model.Device, error) {
+	if !deviceIDRegex.MatchString(req.DeviceID) {
+		return nil, model.NewParamError("device_id", "device_id must be 1-128 letters, digits, '_', '.', ':' or '-'")
+	}
+	if _, ok := platforms[req.Platform]; !ok {
+		return nil, model.NewParamError("platform", "platform must be one of ios, android, web, desktop")
+	}
+	if len(req.PushToken) > 255 {
+		return nil, model.NewParamError("push_token", "push_token is too long")
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	now := model.NowMillis()
+	device := &model.Device{
+		UserID:       userID,
+		DeviceID:     req.DeviceID,
+		Platform:     req.Platform,
+		DeviceName:   truncateRunes(req.DeviceName, 100),
+		PushToken:    req.PushToken,
+		LastActiveAt: now,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.deviceRepo.Upsert(ctx, device); err != nil {
+		return nil, err
+	}
+	return s.deviceRepo.Get(ctx, userID, req.DeviceID)
+}
+
+// Unregister 注销设备（如退出登录时），设备不存在时忽略
+func (s *DeviceService) Unregister(ctx context.Context, userID int64, deviceID string) error {
+	return s.deviceRepo.Delete(ctx, userID, deviceID)
+}
+
+// List 获取用户的全部设备，按最近活跃时间倒序
+func (s *DeviceService) List(ctx context.Context, userID int64) ([]*model.Device, error) {
+	return s.deviceRepo.ListByUser(ctx, userID)
+}
+
+// Touch 更新设备最近活跃时间
+func (s *DeviceService) Touch(ctx context.Context, userID int64, deviceID string) error {
+	ok, err := s.deviceRepo.Touch(ctx, userID, deviceID, model.NowMillis())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.ErrDeviceNotFound
+	}
+	return nil
+}
+
+// RemovePushToken 清除失效的推送令牌，推送服务返回令牌无效时调用
+func (s *DeviceService) RemovePushToken(ctx context.Context, pushToken string) error {
+	if pushToken == "" {
+		return nil
+	}
+	return s.deviceRepo.ClearPushToken(ctx, pushToken, model.NowMillis())
+}
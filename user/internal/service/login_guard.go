@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/ratelimit"
+)
+
+// LoginProtection 登录保护配置，零值字段使用默认值，负数表示不限制
+type LoginProtection struct {
+	Disabled           bool          // 关闭登录保护
+	MaxAccountFailures int           // 同一账号失败多少次后锁定，默认 5
+	MaxIPFailures      int           // 同一 IP 失败多少次后锁定，默认 20
+	CaptchaAfter       int           // 同一账号失败多少次后要求人机验证，默认 3
+	FailureWindow      time.Duration // 失败计数窗口，默认 1 小时
+	LockoutDuration    time.Duration // 首次锁定时长，之后每多失败一次翻倍，默认 1 分钟
+	MaxLockoutDuration time.Duration // 最长锁定时长，默认 1 小时
+}
+
+// withDefaults 填充默认值
+func (p LoginProtection) withDefaults() LoginProtection {
+	if p.MaxAccountFailures == 0 {
+		p.MaxAccountFailures = 5
+	}
+	if p.MaxIPFailures == 0 {
+		p.MaxIPFailures = 20
+	}
+	if p.CaptchaAfter == 0 {
+		p.CaptchaAfter = 3
+	}
+	if p.FailureWindow == 0 {
+		p.FailureWindow = time.Hour
+	}
+	if p.LockoutDuration == 0 {
+		p.LockoutDuration = time.Minute
+	}
+	if p.MaxLockoutDuration == 0 {
+		p.MaxLockoutDuration = time.Hour
+	}
+	return p
+}
+
+// LoginGuard 登录保护，按账号和 IP 统计失败次数，超限后临时锁定
+type LoginGuard struct {
+	store  ratelimit.Store
+	config LoginProtection
+}
+
+// NewLoginGuard 创建登录保护
+func NewLoginGuard(store ratelimit.Store, config LoginProtection) *LoginGuard {
+	return &LoginGuard{store: store, config: config.withDefaults()}
+}
+
+// Check 登录前检查是否被锁定或需要人机验证
+func (g *LoginGuard) Check(ctx context.Context, account string) error {
+	if g.config.Disabled {
+		return nil
+	}
+	account = normalizeAccount(account)
+	client := model.ClientInfoFrom(ctx)
+
+	if err := g.checkLocked(ctx, "login:lock:account:"+account); err != nil {
+		return err
+	}
+	if client.IP != "" {
+		if err := g.checkLocked(ctx, "login:lock:ip:"+client.IP); err != nil {
+			return err
+		}
+	}
+
+	if g.config.CaptchaAfter > 0 && !client.CaptchaPassed {
+		failures, err := g.store.Get(ctx, "login:fail:account:"+account)
+		if err != nil {
+			return err
+		}
+		if failures >= int64(g.config.CaptchaAfter) {
+			return model.ErrCaptchaRequired
+		}
+	}
+	return nil
+}
+
+// Record 记录登录结果，认证失败时计数，成功时清除账号的失败记录
+func (g *LoginGuard) Record(ctx context.Context, account string, loginErr error) error {
+	if g.config.Disabled {
+		return nil
+	}
+	account = normalizeAccount(account)
+
+	if loginErr == nil {
+		return g.store.Reset(ctx, "login:fail:account:"+account, "login:lock:account:"+account)
+	}
+	if !isAuthFailure(loginErr) {
+		return nil
+	}
+
+	if err := g.fail(ctx, "account:"+account, g.config.MaxAccountFailures); err != nil {
+		return err
+	}
+	if ip := model.ClientInfoFrom(ctx).IP; ip != "" {
+		return g.fail(ctx, "ip:"+ip, g.config.MaxIPFailures)
+	}
+	return nil
+}
+
+// fail 失败计数，达到阈值后按指数增长的时长锁定
+func (g *LoginGuard) fail(ctx context.Context, subject string, max int) error {
+	failures, err := g.store.Incr(ctx, "login:fail:"+subject, g.config.FailureWindow)
+	if err != nil {
+		return err
+	}
+	if max < 0 || failures < int64(max) {
+		return nil
+	}
+
+	d := g.config.LockoutDuration
+	for i := int64(max); i < failures && d < g.config.MaxLockoutDuration; i++ {
+		d *= 2
+	}
+	if d > g.config.MaxLockoutDuration {
+		d = g.config.MaxLockoutDuration
+	}
+	return g.store.Lock(ctx, "login:lock:"+subject, d)
+}
+
+// checkLocked 检查锁定状态
+func (g *LoginGuard) checkLocked(ctx context.Context, key string) error {
+	d, err := g.store.TTL(ctx, key)
+	if err != nil {
+		return err
+	}
+	if d > 0 {
+		return &model.RateLimitError{Err: model.ErrLoginLocked, RetryAfter: d}
+	}
+	return nil
+}
+
+// isAuthFailure 是否为需要计数的认证失败（账号不存在、密码、旧密码、验证码或两步验证码错误）
+func isAuthFailure(err error) bool {
+	return errors.Is(err, model.ErrInvalidCredentials) ||
+		errors.Is(err, model.ErrInvalidOldPassword) ||
+		errors.Is(err, model.ErrInvalidCode) ||
+		errors.Is(err, model.ErrUserNotFound) ||
+		errors.Is(err, model.ErrInvalidTwoFactorCode)
+}
+
+// normalizeAccount 统一账号格式，避免大小写和空格绕过计数
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
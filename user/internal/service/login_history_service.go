@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+)
+
+// SecurityEventHandler 安全事件回调，在登录请求中同步调用，耗时操作应异步处理
+type SecurityEventHandler func(ctx context.Context, event *model.SecurityEvent)
+
+// LoginHistoryService 登录历史服务，记录登录结果并触发安全事件
+type LoginHistoryService struct {
+	userRepo    *repository.UserRepository
+	historyRepo *repository.LoginHistoryRepository
+	phones      *PhoneValidator
+	handler     SecurityEventHandler
+}
+
+// NewLoginHistoryService 创建登录历史服务，handler 可为 nil
+func NewLoginHistoryService(userRepo *repository.UserRepository, historyRepo *repository.LoginHistoryRepository, phones *PhoneValidator, handler SecurityEventHandler) *LoginHistoryService {
+	return &LoginHistoryService{
+		userRepo:    userRepo,
+		historyRepo: historyRepo,
+		phones:      phones,
+		handler:     handler,
+	}
+}
+
+// RecordSuccess 记录成功登录，首次在该设备登录时额外触发 new_device_login 事件
+// 用户第一次登录（如注册）不视为新设备
+func (s *LoginHistoryService) RecordSuccess(ctx context.Context, userID int64, account, method string) error {
+	record := newLoginRecord(ctx, userID, account, method)
+	record.Success = true
+
+	succeeded, err := s.historyRepo.HasSucceeded(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if succeeded {
+		known, err := s.historyRepo.HasSucceededOnDevice(ctx, userID, record.DeviceID, record.UserAgent)
+		if err != nil {
+			return err
+		}
+		record.NewDevice = !known
+	}
+
+	if err := s.historyRepo.Create(ctx, record); err != nil {
+		return err
+	}
+	s.emit(ctx, model.SecurityEventLoginSucceeded, record)
+	if record.NewDevice {
+		s.emit(ctx, model.SecurityEventNewDeviceLogin, record)
+	}
+	return nil
+}
+
+// RecordFailure 记录失败的登录，仅记录认证失败（账号或密码错误、验证码错误、账号被禁用等），参数错误和限流不记录
+// 密码或验证码登录时 userID 可为 0，按账号查找用户，账号不存在时记录到用户 0
+func (s *LoginHistoryService) RecordFailure(ctx context.Context, userID int64, account, method string, loginErr error) error {
+	if !isAuthFailure(loginErr) &&
+		!errors.Is(loginErr, model.ErrUserDisabled) &&
+		!errors.Is(loginErr, model.ErrPasswordResetRequired) {
+		return nil
+	}
+
+	if userID == 0 && (method == model.LoginMethodPassword || method == model.LoginMethodCode) {
+		id, err := s.resolveUserID(ctx, account)
+		if err != nil {
+			return err
+		}
+		userID = id
+	}
+
+	record := newLoginRecord(ctx, userID, account, method)
+	record.Reason = truncateRunes(loginErr.Error(), 255)
+	if err := s.historyRepo.Create(ctx, record); err != nil {
+		return err
+	}
+	s.emit(ctx, model.SecurityEventLoginFailed, record)
+	return nil
+}
+
+// List 分页查询用户的登录历史
+func (s *LoginHistoryService) List(ctx context.Context, userID int64, page, pageSize int) (*model.LoginHistory, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	records, total, err := s.historyRepo.List(ctx, userID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginHistory{
+		Records:  records,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// resolveUserID 按手机号或用户名查找用户 ID，不存在时返回 0
+func (s *LoginHistoryService) resolveUserID(ctx context.Context, account string) (int64, error) {
+	if account == "" {
+		return 0, nil
+	}
+
+	var (
+		user *model.User
+		err  error
+	)
+	if phone, ok := s.phones.Normalize(account); ok {
+		user, err = s.userRepo.GetByPhone(ctx, phone)
+	} else {
+		user, err = s.userRepo.GetByUsername(ctx, account)
+	}
+	if errors.Is(err, model.ErrUserNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// emit 触发安全事件
+func (s *LoginHistoryService) emit(ctx context.Context, eventType string, record *model.LoginRecord) {
+	if s.handler == nil {
+		return
+	}
+	s.handler(ctx, &model.SecurityEvent{
+		Type:   eventType,
+		UserID: record.UserID,
+		Record: record,
+	})
+}
+
+// newLoginRecord 根据 ctx 中的客户端信息创建登录记录
+func newLoginRecord(ctx context.Context, userID int64, account, method string) *model.LoginRecord {
+	client := model.ClientInfoFrom(ctx)
+	return &model.LoginRecord{
+		UserID:     userID,
+		Account:    truncateRunes(account, 100),
+		Method:     method,
+		IP:         client.IP,
+		DeviceID:   client.DeviceID,
+		DeviceName: truncateRunes(client.DeviceName, 100),
+		UserAgent:  truncateRunes(client.UserAgent, 500),
+		CreatedAt:  model.NowMillis(),
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/oauth"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+)
+
+// maxNicknameLength 昵称最大长度（与 user_users.nickname 字段一致）
+const maxNicknameLength = 50
+
+// OAuthService 第三方登录服务
+type OAuthService struct {
+	userRepo  *repository.UserRepository
+	oauthRepo *repository.OAuthRepository
+	authSvc   *AuthService
+	providers map[string]oauth.Provider
+}
+
+// NewOAuthService 创建第三方登录服务
+func NewOAuthService(userRepo *repository.UserRepository, oauthRepo *repository.OAuthRepository, authSvc *AuthService, providers []oauth.Provider) *OAuthService {
+	m := make(map[string]oauth.Provider, len(providers))
+	for _, p := range providers {
+		m[p.Name()] = p
+	}
+	return &OAuthService{
+		userRepo:  userRepo,
+		oauthRepo: oauthRepo,
+		authSvc:   authSvc,
+		providers: m,
+	}
+}
+
+// Login 第三方登录，首次登录自动创建用户并绑定，created 表示本次创建了用户
+func (s *OAuthService) Login(ctx context.Context, provider, code string) (user *model.User, created bool, err error) {
+	info, err := s.exchange(ctx, provider, code)
+	if err != nil {
+		return nil, false, err
+	}
+
+	binding, err := s.oauthRepo.GetByOpenID(ctx, info.Provider, info.OpenID)
+	if errors.Is(err, model.ErrOAuthNotBound) {
+		return s.createUser(ctx, info)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	user, err = s.userRepo.GetByID(ctx, binding.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 检查用户状态
+	if !user.CanLogin() {
+		return nil, false, model.ErrUserDisabled
+	}
+
+	return user, false, nil
+}
+
+// Bind 为已有用户绑定第三方账号
+func (s *OAuthService) Bind(ctx context.Context, userID int64, provider, code string) (*model.OAuthBinding, error) {
+	info, err := s.exchange(ctx, provider, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// 第三方账号已绑定
+	existing, err := s.oauthRepo.GetByOpenID(ctx, info.Provider, info.OpenID)
+	if err == nil {
+		if existing.UserID == userID {
+			return existing, nil
+		}
+		return nil, model.ErrOAuthAlreadyBound
+	}
+	if !errors.Is(err, model.ErrOAuthNotBound) {
+		return nil, err
+	}
+
+	// 同一提供方只允许绑定一个账号
+	bindings, err := s.oauthRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bindings {
+		if b.Provider == info.Provider {
+			return nil, model.ErrOAuthAlreadyBound
+		}
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	binding := &model.OAuthBinding{
+		UserID:    userID,
+		Provider:  info.Provider,
+		OpenID:    info.OpenID,
+		UnionID:   info.UnionID,
+		CreatedAt: model.NowMillis(),
+	}
+	if err := s.oauthRepo.Create(ctx, binding); err != nil {
+		return nil, err
+	}
+	return binding, nil
+}
+
+// Unbind 解除用户在指定提供方的绑定
+// 用户没有手机号且只剩这一个绑定时不允许解绑，避免账号无法再登录
+func (s *OAuthService) Unbind(ctx context.Context, userID int64, provider string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	bindings, err := s.oauthRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Phone == "" && len(bindings) <= 1 {
+		return model.NewParamError("provider", "cannot unbind the only login method")
+	}
+
+	affected, err := s.oauthRepo.DeleteByUserProvider(ctx, userID, provider)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.ErrOAuthNotBound
+	}
+	return nil
+}
+
+// GetBindings 获取用户的第三方账号绑定
+func (s *OAuthService) GetBindings(ctx context.Context, userID int64) ([]*model.OAuthBinding, error) {
+	return s.oauthRepo.GetByUserID(ctx, userID)
+}
+
+// exchange 使用授权码换取第三方账号信息
+func (s *OAuthService) exchange(ctx context.Context, provider, code string) (*oauth.UserInfo, error) {
+	if code == "" {
+		return nil, model.NewParamError("code", "code is required")
+	}
+
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", model.ErrOAuthProviderNotFound, provider)
+	}
+
+	info, err := p.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", model.ErrOAuthFailed, err)
+	}
+	if info.OpenID == "" {
+		return nil, fmt.Errorf("%w: empty open id", model.ErrOAuthFailed)
+	}
+	info.Provider = p.Name()
+	return info, nil
+}
+
+// createUser 第三方账号首次登录时创建用户
+func (s *OAuthService) createUser(ctx context.Context, info *oauth.UserInfo) (*model.User, bool, error) {
+	suffix, err := randomHex(6)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 第三方登录用户不使用密码登录，生成随机密码
+	passwordHash, err := s.authSvc.hashPassword(s.authSvc.generateRandomPassword())
+	if err != nil {
+		return nil, false, fmt.Errorf("hash password failed: %w", err)
+	}
+
+	nickname := truncateRunes(info.Nickname, maxNicknameLength)
+	if nickname == "" {
+		nickname = s.authSvc.generateRandomNickname()
+	}
+
+	now := model.NowMillis()
+	user := &model.User{
+		Username:     info.Provider + "_" + suffix,
+		PasswordHash: passwordHash,
+		Nickname:     nickname,
+		Avatar:       info.Avatar,
+		Email:        info.Email,
+		Status:       model.UserStatusNormal,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	binding := &model.OAuthBinding{
+		Provider:  info.Provider,
+		OpenID:    info.OpenID,
+		UnionID:   info.UnionID,
+		CreatedAt: now,
+	}
+
+	if err := s.oauthRepo.CreateWithUser(ctx, user, binding); err != nil {
+		// 并发首次登录时绑定已由其他请求创建
+		if existing, getErr := s.oauthRepo.GetByOpenID(ctx, info.Provider, info.OpenID); getErr == nil {
+			user, err := s.userRepo.GetByID(ctx, existing.UserID)
+			return user, false, err
+		}
+		return nil, false, err
+	}
+
+	return user, true, nil
+}
+
+// randomHex 生成 n 字节的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// PasswordBreachChecker 泄露密码检查，如 NewPwnedPasswordsChecker
+type PasswordBreachChecker interface {
+	// Breached 密码是否出现在已知的泄露数据中
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// PasswordPolicy 密码强度策略，零值字段使用默认值
+type PasswordPolicy struct {
+	MinLength       int                   // 最小长度（字符数），默认 6
+	MaxLength       int                   // 最大长度（字符数），默认 20
+	RequireUpper    bool                  // 必须包含大写字母
+	RequireLower    bool                  // 必须包含小写字母
+	RequireDigit    bool                  // 必须包含数字
+	RequireSymbol   bool                  // 必须包含符号
+	MinCharClasses  int                   // 至少包含几类字符（大写字母、小写字母、数字、符号），0 表示不限制
+	DenyCommon      bool                  // 禁止使用内置的常见弱密码
+	DeniedPasswords []string              // 额外禁止使用的密码（不区分大小写）
+	BreachChecker   PasswordBreachChecker // 泄露密码检查（可选），检查出错时不拦截
+}
+
+// withDefaults 填充默认值
+func (p PasswordPolicy) withDefaults() PasswordPolicy {
+	if p.MinLength == 0 {
+		p.MinLength = 6
+	}
+	if p.MaxLength == 0 {
+		p.MaxLength = 20
+	}
+	return p
+}
+
+// PasswordValidator 按密码策略校验密码
+type PasswordValidator struct {
+	policy PasswordPolicy
+	denied map[string]struct{}
+}
+
+// NewPasswordValidator 创建密码校验器
+func NewPasswordValidator(policy PasswordPolicy) *PasswordValidator {
+	policy = policy.withDefaults()
+	denied := make(map[string]struct{}, len(policy.DeniedPasswords))
+	for _, password := range policy.DeniedPasswords {
+		denied[strings.ToLower(password)] = struct{}{}
+	}
+	return &PasswordValidator{policy: policy, denied: denied}
+}
+
+// Validate 校验密码，field 为出错时 ParamError 中的字段名
+func (v *PasswordValidator) Validate(ctx context.Context, field, password string) error {
+	if password == "" {
+		return model.NewParamError(field, field+" is required")
+	}
+
+	p := v.policy
+	if n := utf8.RuneCountInString(password); n < p.MinLength || n > p.MaxLength {
+		return model.NewParamError(field, fmt.Sprintf("%s length must be between %d and %d", field, p.MinLength, p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsSpace(r):
+		default:
+			symbol = true
+		}
+	}
+	switch {
+	case p.RequireUpper && !upper:
+		return model.NewParamError(field, field+" must contain an uppercase letter")
+	case p.RequireLower && !lower:
+		return model.NewParamError(field, field+" must contain a lowercase letter")
+	case p.RequireDigit && !digit:
+		return model.NewParamError(field, field+" must contain a digit")
+	case p.RequireSymbol && !symbol:
+		return model.NewParamError(field, field+" must contain a symbol")
+	}
+	if p.MinCharClasses > 0 {
+		classes := 0
+		for _, ok := range []bool{upper, lower, digit, symbol} {
+			if ok {
+				classes++
+			}
+		}
+		if classes < p.MinCharClasses {
+			return model.NewParamError(field, fmt.Sprintf("%s must contain at least %d of uppercase letters, lowercase letters, digits and symbols", field, p.MinCharClasses))
+		}
+	}
+
+	lowered := strings.ToLower(password)
+	if _, ok := v.denied[lowered]; ok {
+		return model.NewParamError(field, field+" is too common")
+	}
+	if p.DenyCommon {
+		if _, ok := commonPasswords[lowered]; ok {
+			return model.NewParamError(field, field+" is too common")
+		}
+	}
+
+	// 泄露检查依赖外部服务，出错时放行，避免影响注册和修改密码
+	if p.BreachChecker != nil {
+		if breached, err := p.BreachChecker.Breached(ctx, password); err == nil && breached {
+			return model.NewParamError(field, field+" has appeared in a data breach")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/phone"
+)
+
+// PhonePolicy 手机号策略，零值字段使用默认值
+type PhonePolicy struct {
+	DefaultRegion  string   // 未带国家码的号码按此地区解析，默认 CN
+	AllowedRegions []string // 允许注册、绑定和接收验证码的地区（可选），如 {"CN", "HK"}，为空时不限制
+}
+
+// withDefaults 填充默认值
+func (p PhonePolicy) withDefaults() PhonePolicy {
+	if p.DefaultRegion == "" {
+		p.DefaultRegion = "CN"
+	}
+	p.DefaultRegion = strings.ToUpper(p.DefaultRegion)
+	return p
+}
+
+// PhoneValidator 按手机号策略解析和校验手机号
+type PhoneValidator struct {
+	policy  PhonePolicy
+	allowed map[string]struct{}
+}
+
+// NewPhoneValidator 创建手机号校验器，地区代码不在内置列表中时返回错误
+func NewPhoneValidator(policy PhonePolicy) (*PhoneValidator, error) {
+	policy = policy.withDefaults()
+	if !phone.IsKnownRegion(policy.DefaultRegion) {
+		return nil, fmt.Errorf("unknown default phone region %q", policy.DefaultRegion)
+	}
+
+	var allowed map[string]struct{}
+	if len(policy.AllowedRegions) > 0 {
+		allowed = make(map[string]struct{}, len(policy.AllowedRegions))
+		for _, code := range policy.AllowedRegions {
+			code = strings.ToUpper(code)
+			if !phone.IsKnownRegion(code) {
+				return nil, fmt.Errorf("unknown allowed phone region %q", code)
+			}
+			allowed[code] = struct{}{}
+		}
+	}
+	return &PhoneValidator{policy: policy, allowed: allowed}, nil
+}
+
+// Parse 解析手机号并校验地区，返回 E.164 格式的号码
+func (v *PhoneValidator) Parse(field, raw string) (*phone.Number, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, model.NewParamError(field, field+" is required")
+	}
+	number, err := phone.Parse(raw, v.policy.DefaultRegion)
+	if errors.Is(err, phone.ErrInvalidNumber) {
+		return nil, model.NewParamError(field, "invalid phone format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if v.allowed != nil {
+		if _, ok := v.allowed[number.Region]; !ok {
+			return nil, model.NewParamError(field, "phone region is not supported")
+		}
+	}
+	return number, nil
+}
+
+// Normalize 转换为 E.164 格式用于查询，不校验地区，无法解析时返回原值
+func (v *PhoneValidator) Normalize(raw string) (string, bool) {
+	number, err := phone.Parse(raw, v.policy.DefaultRegion)
+	if err != nil {
+		return raw, false
+	}
+	return number.E164, true
+}
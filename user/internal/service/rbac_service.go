@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+)
+
+var (
+	roleNameRegex   = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+	permissionRegex = regexp.MustCompile(`^[a-zA-Z0-9_.:*-]{1,128}$`)
+)
+
+// RBACService 角色权限服务
+type RBACService struct {
+	userRepo *repository.UserRepository
+	roleRepo *repository.RoleRepository
+}
+
+// NewRBACService 创建角色权限服务
+func NewRBACService(userRepo *repository.UserRepository, roleRepo *repository.RoleRepository) *RBACService {
+	return &RBACService{
+		userRepo: userRepo,
+		roleRepo: roleRepo,
+	}
+}
+
+// CreateRole 创建角色
+func (s *RBACService) CreateRole(ctx context.Context, name, description string, permissions []string) (*model.Role, error) {
+	if !roleNameRegex.MatchString(name) {
+		return nil, model.NewParamError("name", "role name must be 1-64 letters, digits, '_', '.' or '-'")
+	}
+	if len([]rune(description)) > 255 {
+		return nil, model.NewParamError("description", "description is too long")
+	}
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.roleRepo.ExistsByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, model.ErrRoleExists
+	}
+
+	now := model.NowMillis()
+	role := &model.Role{
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole 删除角色，已分配该角色的用户同时失去该角色
+func (s *RBACService) DeleteRole(ctx context.Context, name string) error {
+	role, err := s.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	return s.roleRepo.Delete(ctx, role.ID)
+}
+
+// GetRole 获取角色
+func (s *RBACService) GetRole(ctx context.Context, name string) (*model.Role, error) {
+	return s.roleRepo.GetByName(ctx, name)
+}
+
+// ListRoles 获取全部角色
+func (s *RBACService) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	return s.roleRepo.List(ctx)
+}
+
+// SetRolePermissions 覆盖角色的权限
+func (s *RBACService) SetRolePermissions(ctx context.Context, name string, permissions []string) error {
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return err
+	}
+	role, err := s.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	return s.roleRepo.SetPermissions(ctx, role.ID, permissions, model.NowMillis())
+}
+
+// AssignRole 为用户分配角色
+func (s *RBACService) AssignRole(ctx context.Context, userID int64, roleName string) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	role, err := s.roleRepo.GetByName(ctx, roleName)
+	if err != nil {
+		return err
+	}
+	return s.roleRepo.AssignToUser(ctx, userID, role.ID, model.NowMillis())
+}
+
+// RevokeRole 撤销用户的角色
+func (s *RBACService) RevokeRole(ctx context.Context, userID int64, roleName string) error {
+	role, err := s.roleRepo.GetByName(ctx, roleName)
+	if err != nil {
+		return err
+	}
+	return s.roleRepo.RevokeFromUser(ctx, userID, role.ID)
+}
+
+// GetUserRoles 获取用户的全部角色
+func (s *RBACService) GetUserRoles(ctx context.Context, userID int64) ([]*model.Role, error) {
+	return s.roleRepo.GetByUserID(ctx, userID)
+}
+
+// HasPermission 用户是否拥有指定权限（任一角色的权限匹配即可）
+func (s *RBACService) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	roles, err := s.roleRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		for _, granted := range role.Permissions {
+			if MatchPermission(granted, permission) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// MatchPermission 判断已授予的权限是否覆盖所需权限
+// "*" 匹配全部权限，"user:*" 匹配 user: 开头的全部权限
+func MatchPermission(granted, required string) bool {
+	if granted == "*" || granted == required {
+		return true
+	}
+	if strings.HasSuffix(granted, ":*") {
+		return strings.HasPrefix(required, granted[:len(granted)-1])
+	}
+	return false
+}
+
+// normalizePermissions 校验权限格式并去重排序
+func normalizePermissions(permissions []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(permissions))
+	result := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !permissionRegex.MatchString(permission) {
+			return nil, model.NewParamError("permissions", "invalid permission: "+permission)
+		}
+		if _, ok := seen[permission]; ok {
+			continue
+		}
+		seen[permission] = struct{}{}
+		result = append(result, permission)
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bbadbeef/go-base/user/internal/jwt"
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+	"github.com/bbadbeef/go-base/user/internal/session"
+)
+
+// ClaimsEnricher 签发访问令牌时添加自定义 claims（如租户 ID），返回错误时签发失败
+// 登录和刷新令牌时都会调用，值需可 JSON 序列化
+type ClaimsEnricher func(ctx context.Context, user *model.User) (map[string]interface{}, error)
+
+// TokenService 令牌服务，签发访问令牌并管理可吊销的刷新令牌和会话
+type TokenService struct {
+	userRepo        *repository.UserRepository
+	tokenRepo       *repository.RefreshTokenRepository
+	roleRepo        *repository.RoleRepository
+	sessions        session.Store
+	jwtManager      *jwt.JWTManager
+	refreshDuration time.Duration
+	enricher        ClaimsEnricher
+}
+
+// NewTokenService 创建令牌服务，enricher 可为 nil
+func NewTokenService(userRepo *repository.UserRepository, tokenRepo *repository.RefreshTokenRepository, roleRepo *repository.RoleRepository, sessions session.Store, jwtManager *jwt.JWTManager, refreshDuration time.Duration, enricher ClaimsEnricher) *TokenService {
+	return &TokenService{
+		userRepo:        userRepo,
+		tokenRepo:       tokenRepo,
+		roleRepo:        roleRepo,
+		sessions:        sessions,
+		jwtManager:      jwtManager,
+		refreshDuration: refreshDuration,
+		enricher:        enricher,
+	}
+}
+
+// Issue 为用户创建新会话并签发令牌对，ctx 中的客户端信息记录到会话
+func (s *TokenService) Issue(ctx context.Context, user *model.User) (*model.TokenPair, error) {
+	sessionID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := model.NowMillis()
+	client := model.ClientInfoFrom(ctx)
+	sess := &model.Session{
+		ID:           sessionID,
+		UserID:       user.ID,
+		DeviceID:     client.DeviceID,
+		DeviceName:   truncateRunes(client.DeviceName, 100),
+		IP:           client.IP,
+		UserAgent:    truncateRunes(client.UserAgent, 500),
+		CreatedAt:    now,
+		LastActiveAt: now,
+		ExpireAt:     now + s.refreshDuration.Milliseconds(),
+	}
+	if err := s.sessions.Create(ctx, sess); err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, user, sessionID)
+}
+
+// Validate 验证访问令牌，会话已吊销的令牌视为无效
+func (s *TokenService) Validate(ctx context.Context, accessToken string) (*jwt.Claims, error) {
+	claims, err := s.jwtManager.ValidateToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.SessionID == "" {
+		return nil, fmt.Errorf("%w: missing session", jwt.ErrInvalidToken)
+	}
+
+	if _, err := s.sessions.Get(ctx, claims.SessionID); err != nil {
+		if errors.Is(err, model.ErrSessionNotFound) {
+			return nil, fmt.Errorf("%w: %w", jwt.ErrInvalidToken, err)
+		}
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧刷新令牌随即失效
+// 已轮换的刷新令牌再次使用视为泄露，整个会话会被吊销
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*model.TokenPair, error) {
+	token, err := s.lookup(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	now := model.NowMillis()
+	if token.RevokedAt != 0 {
+		if err := s.revokeSession(ctx, token.SessionID, now); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: refresh token revoked", model.ErrInvalidRefreshToken)
+	}
+	if now > token.ExpireAt {
+		return nil, fmt.Errorf("%w: refresh token expired", model.ErrInvalidRefreshToken)
+	}
+
+	// 会话已被吊销（如在其他设备上踢下线）
+	if _, err := s.sessions.Get(ctx, token.SessionID); err != nil {
+		if errors.Is(err, model.ErrSessionNotFound) {
+			return nil, fmt.Errorf("%w: %w", model.ErrInvalidRefreshToken, err)
+		}
+		return nil, err
+	}
+
+	// 并发刷新时只有一个请求能完成轮换
+	revoked, err := s.tokenRepo.Revoke(ctx, token.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		if err := s.revokeSession(ctx, token.SessionID, now); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: refresh token reused", model.ErrInvalidRefreshToken)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != model.UserStatusNormal {
+		return nil, model.ErrUserDisabled
+	}
+
+	tokens, err := s.issue(ctx, user, token.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.Touch(ctx, token.SessionID, now, tokens.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Revoke 吊销刷新令牌所在的会话
+func (s *TokenService) Revoke(ctx context.Context, refreshToken string) error {
+	token, err := s.lookup(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	return s.revokeSession(ctx, token.SessionID, model.NowMillis())
+}
+
+// RevokeAll 吊销用户的全部会话
+func (s *TokenService) RevokeAll(ctx context.Context, userID int64) error {
+	if err := s.sessions.RevokeByUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeByUser(ctx, userID, model.NowMillis())
+}
+
+// RevokeOthers 吊销用户除 sessionID 以外的全部会话，sessionID 为空时吊销全部会话
+func (s *TokenService) RevokeOthers(ctx context.Context, userID int64, sessionID string) error {
+	if sessionID == "" {
+		return s.RevokeAll(ctx, userID)
+	}
+	sessions, err := s.sessions.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if sess.ID == sessionID {
+			continue
+		}
+		if err := s.sessions.Revoke(ctx, sess.ID); err != nil {
+			return err
+		}
+	}
+	return s.tokenRepo.RevokeByUserExcept(ctx, userID, sessionID, model.NowMillis())
+}
+
+// ListSessions 获取用户的有效会话
+func (s *TokenService) ListSessions(ctx context.Context, userID int64) ([]*model.Session, error) {
+	return s.sessions.ListByUser(ctx, userID)
+}
+
+// RevokeUserSession 吊销用户的指定会话，会话不属于该用户时返回 ErrSessionNotFound
+func (s *TokenService) RevokeUserSession(ctx context.Context, userID int64, sessionID string) error {
+	sess, err := s.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if sess.UserID != userID {
+		return model.ErrSessionNotFound
+	}
+	return s.revokeSession(ctx, sessionID, model.NowMillis())
+}
+
+// revokeSession 吊销会话及其刷新令牌
+func (s *TokenService) revokeSession(ctx context.Context, sessionID string, now int64) error {
+	if err := s.sessions.Revoke(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeSession(ctx, sessionID, now)
+}
+
+// issue 在指定会话下签发令牌对，访问令牌携带用户当前的角色和自定义 claims
+func (s *TokenService) issue(ctx context.Context, user *model.User, sessionID string) (*model.TokenPair, error) {
+	roles, err := s.roleRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	roleNames := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+
+	var custom map[string]interface{}
+	if s.enricher != nil {
+		if custom, err = s.enricher(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	accessToken, accessExpiresAt, err := s.jwtManager.GenerateToken(user.ID, user.Username, user.Phone, sessionID, roleNames, custom)
+	if err != nil {
+		return nil, fmt.Errorf("generate token failed: %w", err)
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := model.NowMillis()
+	token := &model.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpireAt:  now + s.refreshDuration.Milliseconds(),
+		CreatedAt: now,
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessExpiresAt:  accessExpiresAt.UnixMilli(),
+		RefreshExpiresAt: token.ExpireAt,
+	}, nil
+}
+
+// lookup 查找刷新令牌
+func (s *TokenService) lookup(ctx context.Context, refreshToken string) (*model.RefreshToken, error) {
+	if refreshToken == "" {
+		return nil, model.NewParamError("refresh_token", "refresh token is required")
+	}
+	return s.tokenRepo.GetByHash(ctx, hashToken(refreshToken))
+}
+
+// newRefreshToken 生成随机刷新令牌
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken 计算令牌哈希，库中不保存令牌明文
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/password"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+)
+
+// 批量导入导出
+const (
+	maxImportBatch  = 1000 // 单次导入的最大记录数
+	exportBatchSize = 500  // 导出时每批查询的用户数
+)
+
+// importUsernameRegex 导入的用户名：字母开头，最长 50 个字符，比注册规则宽松以兼容原系统的用户名
+var importUsernameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.@-]{0,49}$`)
+
+// exportColumns CSV 导出的列，与 UserImport 的 JSON 字段名一致
+var exportColumns = []string{"id", "username", "phone", "password_hash", "nickname", "avatar", "email", "gender", "birthday", "signature", "status", "created_at"}
+
+// exportRecord 导出的用户记录，JSON 格式可直接作为 UserImport 导入
+type exportRecord struct {
+	ID int64 `json:"id"`
+	model.UserImport
+	Status int `json:"status"`
+}
+
+// TransferService 用户批量导入导出服务，用于从其他系统迁移用户
+type TransferService struct {
+	userRepo *repository.UserRepository
+	authSvc  *AuthService
+	content  *ContentValidator
+}
+
+// NewTransferService 创建用户导入导出服务
+func NewTransferService(userRepo *repository.UserRepository, authSvc *AuthService, content *ContentValidator) *TransferService {
+	return &TransferService{
+		userRepo: userRepo,
+		authSvc:  authSvc,
+		content:  content,
+	}
+}
+
+// ImportUsers 逐条导入用户，单条记录失败不影响其他记录，失败原因记录在结果中
+func (s *TransferService) ImportUsers(ctx context.Context, users []model.UserImport) (*model.ImportResult, error) {
+	if len(users) > maxImportBatch {
+		return nil, model.NewParamError("users", fmt.Sprintf("at most %d users per import", maxImportBatch))
+	}
+
+	result := &model.ImportResult{UserIDs: make([]int64, len(users))}
+	for i := range users {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id, err := s.importUser(ctx, &users[i])
+		if err != nil {
+			result.Failed = append(result.Failed, &model.ImportFailure{Index: i, Err: err, Message: err.Error()})
+			continue
+		}
+		result.UserIDs[i] = id
+	}
+	return result, nil
+}
+
+// importUser 校验并创建单个用户
+func (s *TransferService) importUser(ctx context.Context, in *model.UserImport) (int64, error) {
+	user := &model.User{
+		Username:  in.Username,
+		Nickname:  in.Nickname,
+		Avatar:    in.Avatar,
+		Email:     in.Email,
+		Gender:    in.Gender,
+		Birthday:  in.Birthday,
+		Signature: in.Signature,
+		Status:    model.UserStatusNormal,
+		CreatedAt: in.CreatedAt,
+	}
+	if in.Disabled {
+		user.Status = model.UserStatusDisabled
+	}
+	now := model.NowMillis()
+	if user.CreatedAt <= 0 {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+
+	if in.Phone != "" {
+		number, err := s.authSvc.phones.Parse("phone", in.Phone)
+		if err != nil {
+			return 0, err
+		}
+		user.Phone = number.E164
+		user.PhoneRegion = number.Region
+	}
+	if user.Username == "" {
+		if user.Phone == "" {
+			return 0, model.NewParamError("username", "username or phone is required")
+		}
+		user.Username = "u" + strings.TrimPrefix(user.Phone, "+")
+	}
+	if err := s.validateImport(ctx, in, user); err != nil {
+		return 0, err
+	}
+
+	passwordHash, err := s.importPassword(ctx, in, user)
+	if err != nil {
+		return 0, err
+	}
+	user.PasswordHash = passwordHash
+	if user.Nickname == "" {
+		user.Nickname = s.authSvc.generateRandomNickname()
+	}
+
+	exists, err := s.userRepo.ExistsByUsername(ctx, user.Username)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, model.ErrUsernameExists
+	}
+	if user.Phone != "" {
+		exists, err := s.userRepo.ExistsByPhone(ctx, user.Phone)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, model.ErrPhoneExists
+		}
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return 0, err
+	}
+	// status 列默认值为正常，禁用状态（零值）创建后单独更新
+	if user.Status == model.UserStatusDisabled {
+		if err := s.userRepo.UpdateStatus(ctx, user.ID, model.UserStatusDisabled); err != nil {
+			return user.ID, err
+		}
+	}
+	return user.ID, nil
+}
+
+// validateImport 校验导入记录的资料字段
+func (s *TransferService) validateImport(ctx context.Context, in *model.UserImport, user *model.User) error {
+	if !importUsernameRegex.MatchString(user.Username) {
+		return model.NewParamError("username", "username must be at most 50 letters, digits or '_.@-' and start with a letter")
+	}
+	if strings.HasPrefix(strings.ToLower(user.Username), reservedUsernamePrefix) {
+		return model.NewParamError("username", "username is reserved")
+	}
+	if in.Nickname != "" {
+		if err := s.content.ValidateNickname(ctx, in.Nickname); err != nil {
+			return err
+		}
+	}
+	if in.Signature != "" {
+		if err := s.content.ValidateSignature(ctx, in.Signature); err != nil {
+			return err
+		}
+	}
+	if len(in.Email) > 100 {
+		return model.NewParamError("email", "email too long")
+	}
+	if len(in.Avatar) > 500 {
+		return model.NewParamError("avatar", "avatar too long")
+	}
+	if in.Gender < 0 || in.Gender > 2 {
+		return model.NewParamError("gender", "invalid gender value")
+	}
+	return nil
+}
+
+// importPassword 返回导入用户的密码哈希：原系统哈希原样保存，明文密码按当前配置计算哈希
+// 均未提供时生成随机密码，此时必须有手机号以便验证码登录或重置密码
+func (s *TransferService) importPassword(ctx context.Context, in *model.UserImport, user *model.User) (string, error) {
+	switch {
+	case in.PasswordHash != "":
+		if !password.IsSupported(in.PasswordHash) {
+			return "", model.NewParamError("password_hash", "password hash must be bcrypt or argon2id")
+		}
+		return in.PasswordHash, nil
+	case in.Password != "":
+		if err := s.authSvc.passwords.Validate(ctx, "password", in.Password); err != nil {
+			return "", err
+		}
+	default:
+		if user.Phone == "" {
+			return "", model.NewParamError("phone", "phone is required when password is not provided")
+		}
+	}
+
+	plain := in.Password
+	if plain == "" {
+		plain = s.authSvc.generateRandomPassword()
+	}
+	hash, err := s.authSvc.hashPassword(plain)
+	if err != nil {
+		return "", fmt.Errorf("hash password failed: %w", err)
+	}
+	return hash, nil
+}
+
+// ExportUsers 按条件分批查询用户并流式写入 w，格式为带表头的 CSV 或 JSON 数组
+func (s *TransferService) ExportUsers(ctx context.Context, req *model.ExportUsersRequest, w io.Writer) error {
+	if req == nil {
+		req = &model.ExportUsersRequest{}
+	}
+	if req.CreatedFrom > 0 && req.CreatedTo > 0 && req.CreatedFrom >= req.CreatedTo {
+		return model.NewParamError("created_to", "created_to must be after created_from")
+	}
+
+	var enc exportEncoder
+	switch req.Format {
+	case "", model.ExportFormatCSV:
+		enc = newCSVExporter(w)
+	case model.ExportFormatJSON:
+		enc = newJSONExporter(w)
+	default:
+		return model.NewParamError("format", "format must be csv or json")
+	}
+
+	filter := &model.ListUsersRequest{
+		Status:      req.Status,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		Keyword:     req.Keyword,
+	}
+	if err := enc.begin(); err != nil {
+		return err
+	}
+	var afterID int64
+	for {
+		users, err := s.userRepo.ListAfter(ctx, filter, afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := enc.write(toExportRecord(user, req.IncludePasswordHash)); err != nil {
+				return err
+			}
+		}
+		if len(users) < exportBatchSize {
+			break
+		}
+		afterID = users[len(users)-1].ID
+	}
+	return enc.end()
+}
+
+// toExportRecord 转换为导出记录
+func toExportRecord(user *model.User, includePasswordHash bool) *exportRecord {
+	rec := &exportRecord{
+		ID: user.ID,
+		UserImport: model.UserImport{
+			Username:  user.Username,
+			Phone:     user.Phone,
+			Nickname:  user.Nickname,
+			Avatar:    user.Avatar,
+			Email:     user.Email,
+			Gender:    user.Gender,
+			Birthday:  user.Birthday,
+			Signature: user.Signature,
+			Disabled:  user.Status == model.UserStatusDisabled,
+			CreatedAt: user.CreatedAt,
+		},
+		Status: user.Status,
+	}
+	if includePasswordHash {
+		rec.PasswordHash = user.PasswordHash
+	}
+	return rec
+}
+
+// exportEncoder 导出格式编码器
+type exportEncoder interface {
+	begin() error
+	write(rec *exportRecord) error
+	end() error
+}
+
+// csvExporter CSV 导出，首行为表头
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) *csvExporter {
+	return &csvExporter{w: csv.NewWriter(w)}
+}
+
+func (e *csvExporter) begin() error {
+	return e.w.Write(exportColumns)
+}
+
+func (e *csvExporter) write(rec *exportRecord) error {
+	birthday := ""
+	if rec.Birthday != nil {
+		birthday = *rec.Birthday
+	}
+	return e.w.Write([]string{
+		strconv.FormatInt(rec.ID, 10),
+		rec.Username,
+		rec.Phone,
+		rec.PasswordHash,
+		rec.Nickname,
+		rec.Avatar,
+		rec.Email,
+		strconv.Itoa(rec.Gender),
+		birthday,
+		rec.Signature,
+		strconv.Itoa(rec.Status),
+		strconv.FormatInt(rec.CreatedAt, 10),
+	})
+}
+
+func (e *csvExporter) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonExporter JSON 数组导出，逐条写入
+type jsonExporter struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONExporter(w io.Writer) *jsonExporter {
+	return &jsonExporter{w: w}
+}
+
+func (e *jsonExporter) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExporter) write(rec *exportRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExporter) end() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
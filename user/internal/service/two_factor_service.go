@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bbadbeef/go-base/user/internal/jwt"
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/repository"
+	"github.com/bbadbeef/go-base/user/internal/totp"
+)
+
+// 两步验证参数
+const (
+	recoveryCodeCount = 10              // 每次生成的恢复码数量
+	challengeTTL      = 5 * time.Minute // 挑战令牌有效期
+)
+
+// TwoFactorService 两步验证（TOTP）服务
+type TwoFactorService struct {
+	userRepo   *repository.UserRepository
+	repo       *repository.TwoFactorRepository
+	jwtManager *jwt.JWTManager
+	issuer     string
+}
+
+// NewTwoFactorService 创建两步验证服务，issuer 显示在验证器 App 中
+func NewTwoFactorService(userRepo *repository.UserRepository, repo *repository.TwoFactorRepository, jwtManager *jwt.JWTManager, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		userRepo:   userRepo,
+		repo:       repo,
+		jwtManager: jwtManager,
+		issuer:     issuer,
+	}
+}
+
+// Setup 生成新的 TOTP 密钥，需调用 Enable 校验验证码后才生效
+func (s *TwoFactorService) Setup(ctx context.Context, userID int64) (*model.TOTPSetup, error) {
+	existing, err := s.repo.Get(ctx, userID)
+	if err != nil && !errors.Is(err, model.ErrTwoFactorNotEnabled) {
+		return nil, err
+	}
+	if existing != nil && existing.Enabled {
+		return nil, model.ErrTwoFactorAlreadyEnabled
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := model.NowMillis()
+	if err := s.repo.Save(ctx, &model.TwoFactor{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	account := user.Phone
+	if account == "" {
+		account = user.Username
+	}
+	return &model.TOTPSetup{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, account, secret),
+	}, nil
+}
+
+// Enable 校验验证码并开启两步验证，返回恢复码明文（仅此一次）
+func (s *TwoFactorService) Enable(ctx context.Context, userID int64, code string) ([]string, error) {
+	tf, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, model.ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(tf.Secret, code, time.Now(), tf.LastUsedStep)
+	if !ok {
+		return nil, model.ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tf.Enabled = true
+	tf.RecoveryCodes = hashes
+	tf.LastUsedStep = step
+	tf.UpdatedAt = model.NowMillis()
+	if err := s.repo.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable 校验验证码（或恢复码）后关闭两步验证
+func (s *TwoFactorService) Disable(ctx context.Context, userID int64, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, userID)
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，旧恢复码全部失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	tf, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	tf.RecoveryCodes = hashes
+	tf.UpdatedAt = model.NowMillis()
+	if err := s.repo.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Enabled 用户是否已开启两步验证
+func (s *TwoFactorService) Enabled(ctx context.Context, userID int64) (bool, error) {
+	tf, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, model.ErrTwoFactorNotEnabled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return tf.Enabled, nil
+}
+
+// Verify 校验 TOTP 验证码或恢复码，验证码不可重复使用，恢复码使用后作废
+func (s *TwoFactorService) Verify(ctx context.Context, userID int64, code string) error {
+	tf, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled {
+		return model.ErrTwoFactorNotEnabled
+	}
+
+	updated := *tf
+	updated.UpdatedAt = model.NowMillis()
+	if step, ok := totp.Validate(tf.Secret, code, time.Now(), tf.LastUsedStep); ok {
+		updated.LastUsedStep = step
+	} else if i := indexOf(tf.RecoveryCodes, hashToken(normalizeRecoveryCode(code))); i >= 0 {
+		updated.RecoveryCodes = append(append([]string{}, tf.RecoveryCodes[:i]...), tf.RecoveryCodes[i+1:]...)
+	} else {
+		return model.ErrInvalidTwoFactorCode
+	}
+
+	// 同一验证码并发使用时只有一个请求成功
+	ok, err := s.repo.UpdateIfUnchanged(ctx, tf, &updated)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return model.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// Challenge 为已通过第一步认证的用户生成挑战令牌
+func (s *TwoFactorService) Challenge(userID int64) (string, error) {
+	return s.jwtManager.GenerateChallengeToken(userID, challengeTTL)
+}
+
+// ParseChallenge 校验挑战令牌，返回用户 ID
+func (s *TwoFactorService) ParseChallenge(challengeToken string) (int64, error) {
+	return s.jwtManager.ValidateChallengeToken(challengeToken)
+}
+
+// newRecoveryCodes 生成恢复码，返回明文和哈希
+func newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 统一恢复码格式（忽略大小写、空格和连字符）
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// indexOf 查找字符串在切片中的位置
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// UserEventHandler 用户生命周期事件回调，在触发事件的请求中同步调用，耗时操作应异步处理
+type UserEventHandler func(ctx context.Context, event *model.UserEvent)
+
+// UserEvents 用户生命周期事件分发，创建服务后仍可注册回调
+type UserEvents struct {
+	tenant   string
+	mu       sync.RWMutex
+	handlers []UserEventHandler
+}
+
+// NewUserEvents 创建事件分发，tenant 写入事件的 TenantID
+func NewUserEvents(tenant string, handlers []UserEventHandler) *UserEvents {
+	e := &UserEvents{tenant: tenant}
+	for _, h := range handlers {
+		e.Subscribe(h)
+	}
+	return e
+}
+
+// Subscribe 注册事件回调，按注册顺序调用
+func (e *UserEvents) Subscribe(handler UserEventHandler) {
+	if handler == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers = append(e.handlers, handler)
+}
+
+// Emit 填充租户和时间后依次调用回调
+func (e *UserEvents) Emit(ctx context.Context, event *model.UserEvent) {
+	e.mu.RLock()
+	handlers := e.handlers
+	e.mu.RUnlock()
+	if len(handlers) == 0 {
+		return
+	}
+
+	event.TenantID = e.tenant
+	if event.Time == 0 {
+		event.Time = model.NowMillis()
+	}
+	for _, h := range handlers {
+		h(ctx, event)
+	}
+}
@@ -1,49 +1,161 @@
 package service
 
 import (
-	"fmt"
+	"context"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/bbadbeef/go-base/user/internal/model"
 	"github.com/bbadbeef/go-base/user/internal/repository"
 )
 
+// usernameRegex 用户名：字母开头，4-20 位字母、数字或下划线
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{3,19}$`)
+
+// reservedUsernamePrefix 注销用户的用户名前缀，不允许用户使用
+const reservedUsernamePrefix = "deleted_"
+
+// visibilities 支持的隐私可见范围
+var visibilities = map[string]struct{}{
+	model.VisibilityEveryone: {},
+	model.VisibilityContacts: {},
+	model.VisibilityNobody:   {},
+}
+
+// ContactChecker 判断 viewerID 是否为 userID 的联系人（如 IM 好友），用于隐私设置中的 contacts 可见范围
+type ContactChecker func(ctx context.Context, userID, viewerID int64) (bool, error)
+
 // UserService 用户服务
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo         *repository.UserRepository
+	usernameCooldown time.Duration
+	contacts         ContactChecker
+	content          *ContentValidator
 }
 
-// NewUserService 创建用户服务
-func NewUserService(userRepo *repository.UserRepository) *UserService {
+// NewUserService 创建用户服务，usernameCooldown 为两次修改用户名的最小间隔，小于 0 表示不限制
+// contacts 为 nil 时 contacts 可见范围的字段仅自己可见
+func NewUserService(userRepo *repository.UserRepository, usernameCooldown time.Duration, contacts ContactChecker, content *ContentValidator) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:         userRepo,
+		usernameCooldown: usernameCooldown,
+		contacts:         contacts,
+		content:          content,
 	}
 }
 
 // GetUserByID 根据ID获取用户
-func (s *UserService) GetUserByID(id int64) (*model.User, error) {
-	return s.userRepo.GetByID(id)
+func (s *UserService) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
+	return s.userRepo.GetByID(ctx, id)
+}
+
+// GetUserProfile 获取用户公开信息，按隐私设置返回 viewerID 可见的手机号、生日和最近活跃时间
+// viewerID 为 0 表示未登录的查看者，仅返回所有人可见的字段
+func (s *UserService) GetUserProfile(ctx context.Context, viewerID, id int64) (*model.UserProfile, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	profile := user.ToProfile()
+
+	// 联系人关系按需查询，同一请求只查询一次
+	var isContact *bool
+	visible := func(visibility string) (bool, error) {
+		switch {
+		case viewerID != 0 && viewerID == user.ID:
+			return true, nil
+		case visibility == model.VisibilityEveryone:
+			return true, nil
+		case visibility != model.VisibilityContacts || viewerID == 0 || s.contacts == nil:
+			return false, nil
+		}
+		if isContact == nil {
+			ok, err := s.contacts(ctx, user.ID, viewerID)
+			if err != nil {
+				return false, err
+			}
+			isContact = &ok
+		}
+		return *isContact, nil
+	}
+
+	if ok, err := visible(user.Privacy.Phone); err != nil {
+		return nil, err
+	} else if ok {
+		profile.Phone = user.Phone
+	}
+	if ok, err := visible(user.Privacy.Birthday); err != nil {
+		return nil, err
+	} else if ok {
+		profile.Birthday = user.Birthday
+	}
+	if ok, err := visible(user.Privacy.LastSeen); err != nil {
+		return nil, err
+	} else if ok {
+		profile.LastSeenAt = user.LastSeenAt
+	}
+	return profile, nil
 }
 
-// GetUserProfile 获取用户公开信息
-func (s *UserService) GetUserProfile(id int64) (*model.UserProfile, error) {
-	user, err := s.userRepo.GetByID(id)
+// GetPrivacy 获取隐私设置
+func (s *UserService) GetPrivacy(ctx context.Context, userID int64) (*model.PrivacySettings, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	return user.ToProfile(), nil
+	return &user.Privacy, nil
+}
+
+// UpdatePrivacy 更新隐私设置，返回更新后的设置
+func (s *UserService) UpdatePrivacy(ctx context.Context, userID int64, req *model.UpdatePrivacyRequest) (*model.PrivacySettings, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	privacy := user.Privacy
+	fields := []struct {
+		name  string
+		value *string
+		dst   *string
+	}{
+		{"phone", req.Phone, &privacy.Phone},
+		{"birthday", req.Birthday, &privacy.Birthday},
+		{"last_seen", req.LastSeen, &privacy.LastSeen},
+	}
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		if _, ok := visibilities[*f.value]; !ok {
+			return nil, model.NewParamError(f.name, f.name+" must be one of everyone, contacts, nobody")
+		}
+		*f.dst = *f.value
+	}
+
+	if err := s.userRepo.UpdatePrivacy(ctx, userID, privacy); err != nil {
+		return nil, err
+	}
+	return &privacy, nil
+}
+
+// UpdateLastSeen 更新最近活跃时间
+func (s *UserService) UpdateLastSeen(ctx context.Context, userID int64) error {
+	return s.userRepo.UpdateLastSeen(ctx, userID, model.NowMillis())
 }
 
 // UpdateProfile 更新用户信息
-func (s *UserService) UpdateProfile(userID int64, req *model.UpdateProfileRequest) (*model.User, error) {
+func (s *UserService) UpdateProfile(ctx context.Context, userID int64, req *model.UpdateProfileRequest) (*model.User, error) {
 	// 获取用户
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	// 更新字段
 	if req.Nickname != nil {
-		if err := s.validateNickname(*req.Nickname); err != nil {
+		if err := s.content.ValidateNickname(ctx, *req.Nickname); err != nil {
 			return nil, err
 		}
 		user.Nickname = *req.Nickname
@@ -62,7 +174,7 @@ func (s *UserService) UpdateProfile(userID int64, req *model.UpdateProfileReques
 
 	if req.Gender != nil {
 		if *req.Gender < 0 || *req.Gender > 2 {
-			return nil, fmt.Errorf("invalid gender value")
+			return nil, model.NewParamError("gender", "invalid gender value")
 		}
 		user.Gender = *req.Gender
 	}
@@ -72,8 +184,8 @@ func (s *UserService) UpdateProfile(userID int64, req *model.UpdateProfileReques
 	}
 
 	if req.Signature != nil {
-		if len(*req.Signature) > 255 {
-			return nil, fmt.Errorf("signature too long")
+		if err := s.content.ValidateSignature(ctx, *req.Signature); err != nil {
+			return nil, err
 		}
 		user.Signature = *req.Signature
 	}
@@ -81,23 +193,60 @@ func (s *UserService) UpdateProfile(userID int64, req *model.UpdateProfileReques
 	user.UpdatedAt = model.NowMillis()
 
 	// 保存更新
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-// validateNickname 验证昵称
-func (s *UserService) validateNickname(nickname string) error {
-	if nickname == "" {
-		return fmt.Errorf("nickname cannot be empty")
+// ChangeUsername 修改用户名，两次修改需间隔 usernameCooldown，changed 表示用户名有变化
+func (s *UserService) ChangeUsername(ctx context.Context, userID int64, newUsername string) (changed bool, err error) {
+	if err := s.validateUsername(newUsername); err != nil {
+		return false, err
 	}
 
-	if len(nickname) > 50 {
-		return fmt.Errorf("nickname too long")
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if user.Username == newUsername {
+		return false, nil
 	}
 
+	now := model.NowMillis()
+	if s.usernameCooldown >= 0 && user.UsernameChangedAt > 0 {
+		next := user.UsernameChangedAt + s.usernameCooldown.Milliseconds()
+		if now < next {
+			return false, &model.RateLimitError{
+				Err:        model.ErrUsernameChangeTooFrequent,
+				RetryAfter: time.Duration(next-now) * time.Millisecond,
+			}
+		}
+	}
+
+	exists, err := s.userRepo.ExistsByUsername(ctx, newUsername)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, model.ErrUsernameExists
+	}
+
+	if err := s.userRepo.UpdateUsername(ctx, userID, newUsername, now); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// validateUsername 验证用户名
+func (s *UserService) validateUsername(username string) error {
+	if !usernameRegex.MatchString(username) {
+		return model.NewParamError("username", "username must be 4-20 letters, digits or '_' and start with a letter")
+	}
+	if strings.HasPrefix(strings.ToLower(username), reservedUsernamePrefix) {
+		return model.NewParamError("username", "username is reserved")
+	}
 	return nil
 }
 
@@ -109,7 +258,7 @@ func (s *UserService) validateEmail(email string) error {
 
 	// 简单的邮箱格式验证
 	if len(email) > 100 {
-		return fmt.Errorf("email too long")
+		return model.NewParamError("email", "email too long")
 	}
 
 	return nil
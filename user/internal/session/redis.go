@@ -0,0 +1,165 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// RedisStore 基于 Redis 的会话存储
+// 会话以 JSON 保存在 {prefix}session:{id}，过期时间与会话一致；
+// 用户的会话 ID 集合保存在 {prefix}user_sessions:{userID}
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore 创建 Redis 会话存储，prefix 为空时使用 "user:"
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "user:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// sessionKey 会话键
+func (s *RedisStore) sessionKey(sessionID string) string {
+	return s.prefix + "session:" + sessionID
+}
+
+// userKey 用户会话集合键
+func (s *RedisStore) userKey(userID int64) string {
+	return fmt.Sprintf("%suser_sessions:%d", s.prefix, userID)
+}
+
+// Create 创建会话
+func (s *RedisStore) Create(ctx context.Context, session *model.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	ttl := ttlOf(session.ExpireAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.sessionKey(session.ID), data, ttl)
+	pipe.SAdd(ctx, s.userKey(session.UserID), session.ID)
+	// 刚创建或刷新的会话过期时间最晚，集合随之续期
+	pipe.Expire(ctx, s.userKey(session.UserID), ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Get 获取有效会话
+func (s *RedisStore) Get(ctx context.Context, sessionID string) (*model.Session, error) {
+	data, err := s.client.Get(ctx, s.sessionKey(sessionID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, model.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session model.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Touch 更新会话活跃时间和过期时间
+func (s *RedisStore) Touch(ctx context.Context, sessionID string, lastActiveAt, expireAt int64) error {
+	session, err := s.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	session.LastActiveAt = lastActiveAt
+	session.ExpireAt = expireAt
+	return s.Create(ctx, session)
+}
+
+// ListByUser 获取用户的全部有效会话
+func (s *RedisStore) ListByUser(ctx context.Context, userID int64) ([]*model.Session, error) {
+	ids, err := s.client.SMembers(ctx, s.userKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []*model.Session{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.sessionKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*model.Session, 0, len(values))
+	var stale []interface{}
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var session model.Session
+		if err := json.Unmarshal([]byte(str), &session); err != nil {
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+
+	// 清理已过期的会话 ID
+	if len(stale) > 0 {
+		s.client.SRem(ctx, s.userKey(userID), stale...)
+	}
+	return sessions, nil
+}
+
+// Revoke 吊销会话
+func (s *RedisStore) Revoke(ctx context.Context, sessionID string) error {
+	session, err := s.Get(ctx, sessionID)
+	if errors.Is(err, model.ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, s.sessionKey(sessionID))
+	pipe.SRem(ctx, s.userKey(session.UserID), sessionID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RevokeByUser 吊销用户的全部会话
+func (s *RedisStore) RevokeByUser(ctx context.Context, userID int64) error {
+	ids, err := s.client.SMembers(ctx, s.userKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, s.sessionKey(id))
+	}
+	keys = append(keys, s.userKey(userID))
+	return s.client.Del(ctx, keys...).Err()
+}
+
+// ttlOf 计算距过期时间的剩余时长
+func ttlOf(expireAt int64) time.Duration {
+	return time.Until(time.UnixMilli(expireAt))
+}
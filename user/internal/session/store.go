@@ -0,0 +1,30 @@
+// Package session 登录会话存储
+package session
+
+import (
+	"context"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+)
+
+// Store 会话存储
+// ValidateToken 每次都会查询会话是否有效，高并发场景建议使用 Redis 实现
+type Store interface {
+	// Create 创建会话
+	Create(ctx context.Context, session *model.Session) error
+
+	// Get 获取有效会话，不存在、已吊销或已过期时返回 model.ErrSessionNotFound
+	Get(ctx context.Context, sessionID string) (*model.Session, error)
+
+	// Touch 刷新令牌时更新会话活跃时间和过期时间
+	Touch(ctx context.Context, sessionID string, lastActiveAt, expireAt int64) error
+
+	// ListByUser 获取用户的全部有效会话
+	ListByUser(ctx context.Context, userID int64) ([]*model.Session, error)
+
+	// Revoke 吊销会话
+	Revoke(ctx context.Context, sessionID string) error
+
+	// RevokeByUser 吊销用户的全部会话
+	RevokeByUser(ctx context.Context, userID int64) error
+}
@@ -0,0 +1,134 @@
+// Package webhook 以 HTTP POST 推送 JSON 事件，请求使用 HMAC-SHA256 签名
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 请求头
+const (
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp" // 秒级时间戳，接收方可据此拒绝过期请求
+	SignatureHeader = "X-Webhook-Signature" // sha256=<hex>，对 "<timestamp>.<body>" 计算的 HMAC-SHA256
+)
+
+// Config 推送配置，零值字段使用默认值
+type Config struct {
+	URL     string        // 接收地址
+	Secret  string        // 签名密钥，为空时不签名
+	Client  *http.Client  // HTTP 客户端，默认 10 秒超时
+	Retries int           // 失败重试次数，默认 2，小于 0 表示不重试
+	Backoff time.Duration // 首次重试间隔，之后每次翻倍，默认 1 秒
+}
+
+// withDefaults 填充默认值
+func (c Config) withDefaults() Config {
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if c.Retries == 0 {
+		c.Retries = 2
+	}
+	if c.Retries < 0 {
+		c.Retries = 0
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = time.Second
+	}
+	return c
+}
+
+// Sender 推送事件
+type Sender struct {
+	config Config
+}
+
+// NewSender 创建推送
+func NewSender(config Config) *Sender {
+	return &Sender{config: config.withDefaults()}
+}
+
+// Send 推送事件，网络错误、429 和 5xx 响应按配置重试，返回最后一次失败的错误
+func (s *Sender) Send(ctx context.Context, event string, body []byte) error {
+	backoff := s.config.Backoff
+	var err error
+	for attempt := 0; attempt <= s.config.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = s.post(ctx, event, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post 发送一次请求，返回失败时是否可以重试
+func (s *Sender) post(ctx context.Context, event string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	if s.config.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(s.config.Secret, timestamp, body))
+	}
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook: unexpected status %d", resp.StatusCode)
+}
+
+// Sign 计算签名（十六进制），接收方按相同方式计算后使用 hmac.Equal 比较
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验请求头中的时间戳和签名，maxAge 为 0 时不校验时间
+func Verify(secret, timestamp, signature string, body []byte, maxAge time.Duration) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if maxAge > 0 {
+		age := time.Since(time.Unix(ts, 0))
+		if age > maxAge || age < -maxAge {
+			return false
+		}
+	}
+	expected := "sha256=" + Sign(secret, ts, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
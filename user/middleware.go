@@ -0,0 +1,135 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/bbadbeef/go-base/errcode"
+)
+
+// claimsKey ctx 中访问令牌 claims 的键
+type claimsKey struct{}
+
+// ClaimsFromContext 获取 RequireAuth 等中间件写入请求 ctx 的访问令牌 claims
+func ClaimsFromContext(ctx context.Context) (*JWTClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*JWTClaims)
+	return claims, ok
+}
+
+// ContextWithClaims 将访问令牌 claims 写入 ctx，供其他协议的认证（如 gRPC 拦截器）与 ClaimsFromContext 配合使用
+func ContextWithClaims(ctx context.Context, claims *JWTClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// RequireAuth 校验 Authorization: Bearer <token> 访问令牌，通过后将 claims 写入请求 ctx
+// 失败时按 errcode 返回 JSON 错误，如 {"code":10003,"message":"unauthorized"}
+func RequireAuth(svc Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, ok := authenticate(svc, w, r)
+			if !ok {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole 要求访问令牌包含任一指定角色，如 RequireRole(svc, "admin")
+// 角色取自令牌，分配或撤销角色后需刷新令牌才生效
+func RequireRole(svc Service, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, ok := authenticate(svc, w, r)
+			if !ok {
+				return
+			}
+			claims, _ := ClaimsFromContext(r.Context())
+			if !claims.HasRole(roles...) {
+				writeError(w, r, ErrPermissionDenied)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission 要求用户拥有指定权限，如 RequirePermission(svc, "user:delete")
+// 每次请求实时查询用户角色，权限变更立即生效
+func RequirePermission(svc Service, permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, ok := authenticate(svc, w, r)
+			if !ok {
+				return
+			}
+			claims, _ := ClaimsFromContext(r.Context())
+			allowed, err := svc.WithContext(r.Context()).HasPermission(claims.UserID, permission)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if !allowed {
+				writeError(w, r, ErrPermissionDenied)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// JWKSHandler 以 JSON 返回 JWT 公钥集合，通常挂载到 /.well-known/jwks.json，供其他服务验证访问令牌
+func JWKSHandler(svc Service) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(svc.JWKS())
+	})
+}
+
+// authenticate 校验访问令牌，已由外层中间件校验时直接复用 ctx 中的 claims
+func authenticate(svc Service, w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if _, ok := ClaimsFromContext(r.Context()); ok {
+		return r, true
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		writeAPIError(w, errcode.New(errcode.Unauthorized, requestLang(r)))
+		return r, false
+	}
+	claims, err := svc.WithContext(r.Context()).ValidateToken(token)
+	if err != nil {
+		writeError(w, r, err)
+		return r, false
+	}
+	return r.WithContext(ContextWithClaims(r.Context(), claims)), true
+}
+
+// bearerToken 从 Authorization 请求头中获取访问令牌
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// requestLang 请求的语言
+func requestLang(r *http.Request) string {
+	return errcode.LangFromHeader(r.Header.Get("Accept-Language"))
+}
+
+// writeError 按 errcode 将错误写为 JSON 响应
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	writeAPIError(w, errcode.Resolve(err, requestLang(r)))
+}
+
+// writeAPIError 写入 JSON 错误响应
+func writeAPIError(w http.ResponseWriter, apiErr *errcode.APIError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.HTTPStatus)
+	json.NewEncoder(w).Encode(apiErr)
+}
@@ -0,0 +1,32 @@
+package user
+
+import "github.com/bbadbeef/go-base/user/internal/oauth"
+
+// 第三方登录类型
+type (
+	OAuthProvider    = oauth.Provider
+	OAuthUserInfo    = oauth.UserInfo
+	AppleOAuthConfig = oauth.AppleConfig
+)
+
+// 内置第三方登录提供方名称
+const (
+	OAuthProviderWeChat = oauth.ProviderWeChat
+	OAuthProviderGoogle = oauth.ProviderGoogle
+	OAuthProviderApple  = oauth.ProviderApple
+)
+
+// NewWeChatOAuthProvider 创建微信登录提供方
+func NewWeChatOAuthProvider(appID, appSecret string) OAuthProvider {
+	return oauth.NewWeChatProvider(appID, appSecret)
+}
+
+// NewGoogleOAuthProvider 创建 Google 登录提供方
+func NewGoogleOAuthProvider(clientID, clientSecret, redirectURL string) OAuthProvider {
+	return oauth.NewGoogleProvider(clientID, clientSecret, redirectURL)
+}
+
+// NewAppleOAuthProvider 创建 Apple 登录提供方
+func NewAppleOAuthProvider(config *AppleOAuthConfig) (OAuthProvider, error) {
+	return oauth.NewAppleProvider(config)
+}
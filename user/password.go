@@ -0,0 +1,13 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/bbadbeef/go-base/user/internal/pwned"
+)
+
+// NewPwnedPasswordsChecker 创建基于 Have I Been Pwned 的泄露密码检查，用于 PasswordPolicy.BreachChecker
+// 使用 k-匿名查询，仅发送密码 SHA-1 的前 5 位；client 为 nil 时使用默认客户端
+func NewPwnedPasswordsChecker(client *http.Client) PasswordBreachChecker {
+	return pwned.NewChecker(client)
+}
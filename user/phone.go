@@ -0,0 +1,10 @@
+package user
+
+import (
+	"github.com/bbadbeef/go-base/user/internal/phone"
+)
+
+// PhoneRegions 内置号码规则的地区代码，可用于 PhonePolicy.DefaultRegion 和 AllowedRegions
+func PhoneRegions() []string {
+	return phone.Regions()
+}
@@ -0,0 +1,20 @@
+package user
+
+import (
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bbadbeef/go-base/user/internal/ratelimit"
+)
+
+// RateLimitStore 计数存储，用于登录失败计数和锁定
+type RateLimitStore = ratelimit.Store
+
+// NewMemoryRateLimitStore 创建进程内计数存储（默认），仅适用于单实例部署
+func NewMemoryRateLimitStore() RateLimitStore {
+	return ratelimit.NewMemoryStore()
+}
+
+// NewRedisRateLimitStore 创建 Redis 计数存储，prefix 为键前缀（默认 "user:ratelimit:"）
+func NewRedisRateLimitStore(client redis.UniversalClient, prefix string) RateLimitStore {
+	return ratelimit.NewRedisStore(client, prefix)
+}
@@ -0,0 +1,26 @@
+package user
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/session"
+)
+
+// SessionStore 会话存储，ValidateToken 据此判断访问令牌是否已被吊销
+type SessionStore = session.Store
+
+// NewRedisSessionStore 创建 Redis 会话存储，prefix 为键前缀（默认 "user:"）
+func NewRedisSessionStore(client redis.UniversalClient, prefix string) SessionStore {
+	return session.NewRedisStore(client, prefix)
+}
+
+// WithClientInfo 将客户端信息绑定到 ctx，配合 WithContext 使用，登录时记录到会话
+//
+//	ctx := user.WithClientInfo(r.Context(), user.ClientInfo{IP: ip, UserAgent: r.UserAgent()})
+//	u, tokens, err := svc.WithContext(ctx).Login(req)
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return model.WithClientInfo(ctx, info)
+}
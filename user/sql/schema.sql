@@ -1,8 +1,10 @@
 -- 用户表
 CREATE TABLE IF NOT EXISTS `user_users` (
   `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '用户ID',
+  `tenant_id` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '租户ID，未启用多租户时为空',
   `username` VARCHAR(50) NOT NULL COMMENT '用户名',
-  `phone` VARCHAR(20) NOT NULL COMMENT '手机号',
+  `phone` VARCHAR(20) DEFAULT NULL COMMENT '手机号，E.164 格式（第三方登录创建的用户可为空）',
+  `phone_region` VARCHAR(2) NOT NULL DEFAULT '' COMMENT '手机号所属地区，如 CN',
   `password_hash` VARCHAR(255) NOT NULL COMMENT '密码哈希',
   `nickname` VARCHAR(50) DEFAULT NULL COMMENT '昵称',
   `avatar` VARCHAR(500) DEFAULT NULL COMMENT '头像URL',
@@ -10,25 +12,152 @@ CREATE TABLE IF NOT EXISTS `user_users` (
   `gender` TINYINT DEFAULT 0 COMMENT '性别：0-未知，1-男，2-女',
   `birthday` DATE DEFAULT NULL COMMENT '生日',
   `signature` VARCHAR(255) DEFAULT NULL COMMENT '个性签名',
-  `status` TINYINT DEFAULT 1 COMMENT '状态：0-禁用，1-正常',
+  `status` TINYINT DEFAULT 1 COMMENT '状态：0-禁用，1-正常，2-注销冷静期，3-已注销',
+  `must_reset_password` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否需要重置密码：0-否，1-是（重置前不能使用密码登录）',
+  `deactivated_at` BIGINT NOT NULL DEFAULT 0 COMMENT '申请注销时间(毫秒时间戳)，0-未申请',
+  `username_changed_at` BIGINT NOT NULL DEFAULT 0 COMMENT '最近一次修改用户名时间(毫秒时间戳)，0-未修改',
+  `last_seen_at` BIGINT NOT NULL DEFAULT 0 COMMENT '最近活跃时间(毫秒时间戳)',
+  `phone_visibility` VARCHAR(10) NOT NULL DEFAULT 'nobody' COMMENT '手机号可见范围：everyone、contacts、nobody',
+  `birthday_visibility` VARCHAR(10) NOT NULL DEFAULT 'nobody' COMMENT '生日可见范围：everyone、contacts、nobody',
+  `last_seen_visibility` VARCHAR(10) NOT NULL DEFAULT 'contacts' COMMENT '最近活跃时间可见范围：everyone、contacts、nobody',
   `created_at` BIGINT NOT NULL COMMENT '创建时间(毫秒时间戳)',
   `updated_at` BIGINT NOT NULL COMMENT '更新时间(毫秒时间戳)',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `uk_username` (`username`),
-  UNIQUE KEY `uk_phone` (`phone`),
-  KEY `idx_created_at` (`created_at`)
+  UNIQUE KEY `uk_tenant_username` (`tenant_id`, `username`),
+  UNIQUE KEY `uk_tenant_phone` (`tenant_id`, `phone`),
+  KEY `idx_created_at` (`created_at`),
+  KEY `idx_user_deactivated_at` (`deactivated_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户表';
 
 -- 验证码表
 CREATE TABLE IF NOT EXISTS `user_verification_codes` (
   `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'ID',
-  `phone` VARCHAR(20) NOT NULL COMMENT '手机号',
+  `tenant_id` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '租户ID，未启用多租户时为空',
+  `phone` VARCHAR(20) NOT NULL COMMENT '手机号，E.164 格式',
   `code` VARCHAR(10) NOT NULL COMMENT '验证码',
-  `type` TINYINT NOT NULL COMMENT '类型：1-注册，2-登录，3-重置密码',
-  `status` TINYINT DEFAULT 0 COMMENT '状态：0-未使用，1-已使用，2-已过期',
+  `type` TINYINT NOT NULL COMMENT '类型：1-注册，2-登录，3-重置密码，4-更换手机号',
+  `status` TINYINT DEFAULT 0 COMMENT '状态：0-未使用，1-已使用，2-已过期，3-校验次数过多已作废',
+  `attempts` INT NOT NULL DEFAULT 0 COMMENT '校验失败次数',
   `expire_at` BIGINT NOT NULL COMMENT '过期时间(毫秒时间戳)',
   `created_at` BIGINT NOT NULL COMMENT '创建时间(毫秒时间戳)',
   PRIMARY KEY (`id`),
   KEY `idx_phone_type` (`phone`, `type`),
   KEY `idx_created_at` (`created_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='验证码表';
+
+-- 第三方账号绑定表
+CREATE TABLE IF NOT EXISTS `user_oauth_bindings` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'ID',
+  `user_id` BIGINT NOT NULL COMMENT '用户ID',
+  `provider` VARCHAR(20) NOT NULL COMMENT '提供方：wechat、google、apple',
+  `open_id` VARCHAR(128) NOT NULL COMMENT '第三方账号ID',
+  `union_id` VARCHAR(128) DEFAULT NULL COMMENT '跨应用统一ID',
+  `created_at` BIGINT NOT NULL COMMENT '创建时间(毫秒时间戳)',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_provider_open_id` (`provider`, `open_id`),
+  KEY `idx_oauth_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='第三方账号绑定表';
+
+-- 刷新令牌表
+CREATE TABLE IF NOT EXISTS `user_refresh_tokens` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'ID',
+  `user_id` BIGINT NOT NULL COMMENT '用户ID',
+  `session_id` VARCHAR(64) NOT NULL COMMENT '会话ID（轮换后保持不变）',
+  `token_hash` VARCHAR(64) NOT NULL COMMENT '令牌SHA-256哈希',
+  `expire_at` BIGINT NOT NULL COMMENT '过期时间(毫秒时间戳)',
+  `revoked_at` BIGINT NOT NULL DEFAULT 0 COMMENT '吊销时间(毫秒时间戳)，0-有效',
+  `created_at` BIGINT NOT NULL COMMENT '创建时间(毫秒时间戳)',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_token_hash` (`token_hash`),
+  KEY `idx_refresh_user_id` (`user_id`),
+  KEY `idx_refresh_session_id` (`session_id`),
+  KEY `idx_refresh_expire_at` (`expire_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='刷新令牌表';
+
+-- 会话表（默认会话存储，配置 Redis 会话存储时不使用）
+CREATE TABLE IF NOT EXISTS `user_sessions` (
+  `id` VARCHAR(64) NOT NULL COMMENT '会话ID',
+  `user_id` BIGINT NOT NULL COMMENT '用户ID',
+  `device_id` VARCHAR(128) DEFAULT NULL COMMENT '设备ID',
+  `device_name` VARCHAR(100) DEFAULT NULL COMMENT '设备名称',
+  `ip` VARCHAR(64) DEFAULT NULL COMMENT '登录IP',
+  `user_agent` VARCHAR(500) DEFAULT NULL COMMENT 'User-Agent',
+  `created_at` BIGINT NOT NULL COMMENT '创建时间(毫秒时间戳)',
+  `last_active_at` BIGINT NOT NULL COMMENT '最近活跃时间(毫秒时间戳)',
+  `expire_at` BIGINT NOT NULL COMMENT '过期时间(毫秒时间戳)',
+  `revoked_at` BIGINT NOT NULL DEFAULT 0 COMMENT '吊销时间(毫秒时间戳)，0-有效',
+  PRIMARY KEY (`id`),
+  KEY `idx_session_user_id` (`user_id`),
+  KEY `idx_session_expire_at` (`expire_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='会话表';
+
+-- 两步验证表
+CREATE TABLE IF NOT EXISTS `user_two_factor` (
+  `user_id` BIGINT NOT NULL COMMENT '用户ID',
+  `secret` VARCHAR(64) NOT NULL COMMENT 'TOTP密钥(base32)',
+  `enabled` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否已开启：0-待校验，1-已开启',
+  `recovery_codes` TEXT COMMENT '恢复码哈希(JSON数组)',
+  `last_used_step` BIGINT NOT NULL DEFAULT 0 COMMENT '最近使用的验证码时间步(防重放)',
+  `created_at` BIGINT NOT NULL COMMENT '创建时间(毫秒时间戳)',
+  `updated_at` BIGINT NOT NULL COMMENT '更新时间(毫秒时间戳)',
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='两步验证表';
+
+-- 角色表
+CREATE TABLE IF NOT EXISTS `user_roles` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT '角色ID',
+  `name` VARCHAR(64) NOT NULL COMMENT '角色名',
+  `description` VARCHAR(255) DEFAULT NULL COMMENT '描述',
+  `created_at` BIGINT NOT NULL COMMENT '创建时间(毫秒时间戳)',
+  `updated_at` BIGINT NOT NULL COMMENT '更新时间(毫秒时间戳)',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_role_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='角色表';
+
+-- 角色权限表
+CREATE TABLE IF NOT EXISTS `user_role_permissions` (
+  `role_id` BIGINT NOT NULL COMMENT '角色ID',
+  `permission` VARCHAR(128) NOT NULL COMMENT '权限，如 user:read、user:*、*',
+  PRIMARY KEY (`role_id`, `permission`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='角色权限表';
+
+-- 用户角色表
+CREATE TABLE IF NOT EXISTS `user_user_roles` (
+  `user_id` BIGINT NOT NULL COMMENT '用户ID',
+  `role_id` BIGINT NOT NULL COMMENT '角色ID',
+  `created_at` BIGINT NOT NULL COMMENT '分配时间(毫秒时间戳)',
+  PRIMARY KEY (`user_id`, `role_id`),
+  KEY `idx_user_role_role_id` (`role_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户角色表';
+
+-- 登录历史表
+CREATE TABLE IF NOT EXISTS `user_login_history` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT COMMENT 'ID',
+  `user_id` BIGINT NOT NULL COMMENT '用户ID，账号不存在时为0',
+  `account` VARCHAR(100) DEFAULT NULL COMMENT '登录账号：手机号、用户名或第三方登录提供方',
+  `method` VARCHAR(32) NOT NULL COMMENT '登录方式：register、password、code、oauth、2fa',
+  `success` TINYINT(1) NOT NULL COMMENT '是否成功：0-失败，1-成功',
+  `reason` VARCHAR(255) DEFAULT NULL COMMENT '失败原因',
+  `ip` VARCHAR(64) DEFAULT NULL COMMENT '登录IP',
+  `device_id` VARCHAR(128) DEFAULT NULL COMMENT '设备ID',
+  `device_name` VARCHAR(100) DEFAULT NULL COMMENT '设备名称',
+  `user_agent` VARCHAR(500) DEFAULT NULL COMMENT 'User-Agent',
+  `new_device` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否首次在该设备登录：0-否，1-是',
+  `created_at` BIGINT NOT NULL COMMENT '登录时间(毫秒时间戳)',
+  PRIMARY KEY (`id`),
+  KEY `idx_login_history_user_created` (`user_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='登录历史表';
+
+-- 设备表
+CREATE TABLE IF NOT EXISTS `user_devices` (
+  `user_id` BIGINT NOT NULL COMMENT '用户ID',
+  `device_id` VARCHAR(128) NOT NULL COMMENT '设备ID',
+  `platform` VARCHAR(20) NOT NULL COMMENT '平台：ios、android、web、desktop',
+  `device_name` VARCHAR(100) DEFAULT NULL COMMENT '设备名称',
+  `push_token` VARCHAR(255) DEFAULT NULL COMMENT '推送令牌，为空表示不推送',
+  `last_active_at` BIGINT NOT NULL COMMENT '最近活跃时间(毫秒时间戳)',
+  `created_at` BIGINT NOT NULL COMMENT '创建时间(毫秒时间戳)',
+  `updated_at` BIGINT NOT NULL COMMENT '更新时间(毫秒时间戳)',
+  PRIMARY KEY (`user_id`, `device_id`),
+  KEY `idx_device_push_token` (`push_token`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='设备表';
@@ -3,13 +3,24 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
 
+	"github.com/bbadbeef/go-base/idgen"
+	golog "github.com/bbadbeef/go-base/log"
+	"github.com/bbadbeef/go-base/migrate"
+	"github.com/bbadbeef/go-base/observability"
+	"github.com/bbadbeef/go-base/observability/gormmetrics"
 	"github.com/bbadbeef/go-base/user/internal/jwt"
 	"github.com/bbadbeef/go-base/user/internal/model"
+	"github.com/bbadbeef/go-base/user/internal/password"
+	"github.com/bbadbeef/go-base/user/internal/ratelimit"
 	"github.com/bbadbeef/go-base/user/internal/repository"
 	"github.com/bbadbeef/go-base/user/internal/service"
 )
@@ -25,7 +36,48 @@ type (
 	VerifyCodeRequest      = model.VerifyCodeRequest
 	ChangePasswordRequest  = model.ChangePasswordRequest
 	ResetPasswordRequest   = model.ResetPasswordRequest
+	ChangePhoneRequest     = model.ChangePhoneRequest
 	JWTClaims              = jwt.Claims
+	JWTKey                 = jwt.Key
+	JWKS                   = jwt.JWKS
+	JWK                    = jwt.JWK
+	ParamError             = model.ParamError
+	MigrationStatus        = migrate.Status
+	OAuthBinding           = model.OAuthBinding
+	TokenPair              = model.TokenPair
+	Session                = model.Session
+	ClientInfo             = model.ClientInfo
+	TOTPSetup              = model.TOTPSetup
+	LoginProtection        = service.LoginProtection
+	RateLimitError         = model.RateLimitError
+	CodeProtection         = service.CodeProtection
+	PasswordPolicy         = service.PasswordPolicy
+	PasswordBreachChecker  = service.PasswordBreachChecker
+	PhonePolicy            = service.PhonePolicy
+	ContentPolicy          = service.ContentPolicy
+	ContentFilter          = service.ContentFilter
+	CaptchaVerifier        = service.CaptchaVerifier
+	PasswordHashing        = password.Config
+	Role                   = model.Role
+	ListUsersRequest       = model.ListUsersRequest
+	UserList               = model.UserList
+	UserImport             = model.UserImport
+	ImportResult           = model.ImportResult
+	ImportFailure          = model.ImportFailure
+	ExportUsersRequest     = model.ExportUsersRequest
+	AccountDeletionHook    = service.AccountDeletionHook
+	LoginRecord            = model.LoginRecord
+	LoginHistory           = model.LoginHistory
+	SecurityEvent          = model.SecurityEvent
+	SecurityEventHandler   = service.SecurityEventHandler
+	UserEvent              = model.UserEvent
+	UserEventHandler       = service.UserEventHandler
+	ClaimsEnricher         = service.ClaimsEnricher
+	Device                 = model.Device
+	RegisterDeviceRequest  = model.RegisterDeviceRequest
+	PrivacySettings        = model.PrivacySettings
+	UpdatePrivacyRequest   = model.UpdatePrivacyRequest
+	ContactChecker         = service.ContactChecker
 )
 
 // 重新导出常量
@@ -33,31 +85,191 @@ const (
 	CodeTypeRegister      = model.CodeTypeRegister
 	CodeTypeLogin         = model.CodeTypeLogin
 	CodeTypeResetPassword = model.CodeTypeResetPassword
+	CodeTypeChangePhone   = model.CodeTypeChangePhone
 
 	UserStatusDisabled = model.UserStatusDisabled
 	UserStatusNormal   = model.UserStatusNormal
 
+	UserStatusDeactivated = model.UserStatusDeactivated
+	UserStatusDeleted     = model.UserStatusDeleted
+
+	LoginMethodRegister  = model.LoginMethodRegister
+	LoginMethodPassword  = model.LoginMethodPassword
+	LoginMethodCode      = model.LoginMethodCode
+	LoginMethodOAuth     = model.LoginMethodOAuth
+	LoginMethodTwoFactor = model.LoginMethodTwoFactor
+
+	SecurityEventLoginSucceeded = model.SecurityEventLoginSucceeded
+	SecurityEventLoginFailed    = model.SecurityEventLoginFailed
+	SecurityEventNewDeviceLogin = model.SecurityEventNewDeviceLogin
+
+	PlatformIOS     = model.PlatformIOS
+	PlatformAndroid = model.PlatformAndroid
+	PlatformWeb     = model.PlatformWeb
+	PlatformDesktop = model.PlatformDesktop
+
+	PasswordHashBcrypt   = password.AlgorithmBcrypt
+	PasswordHashArgon2id = password.AlgorithmArgon2id
+
 	GenderUnknown = model.GenderUnknown
 	GenderMale    = model.GenderMale
 	GenderFemale  = model.GenderFemale
+
+	UserEventRegistered      = model.UserEventRegistered
+	UserEventLogin           = model.UserEventLogin
+	UserEventProfileUpdated  = model.UserEventProfileUpdated
+	UserEventPasswordChanged = model.UserEventPasswordChanged
+	UserEventDisabled        = model.UserEventDisabled
+
+	PasswordChangeByUser  = model.PasswordChangeByUser
+	PasswordChangeByReset = model.PasswordChangeByReset
+)
+
+// 重新导出错误，可通过 errors.Is / errors.As 判断
+var (
+	ErrInvalidConfig      = model.ErrInvalidConfig
+	ErrInvalidParam       = model.ErrInvalidParam
+	ErrUserNotFound       = model.ErrUserNotFound
+	ErrUserDisabled       = model.ErrUserDisabled
+	ErrPhoneExists        = model.ErrPhoneExists
+	ErrUsernameExists     = model.ErrUsernameExists
+	ErrInvalidCredentials = model.ErrInvalidCredentials
+	ErrInvalidOldPassword = model.ErrInvalidOldPassword
+	ErrCodeNotFound       = model.ErrCodeNotFound
+	ErrCodeUsed           = model.ErrCodeUsed
+	ErrCodeExpired        = model.ErrCodeExpired
+	ErrInvalidCode        = model.ErrInvalidCode
+
+	ErrPasswordResetRequired = model.ErrPasswordResetRequired
+
+	ErrUsernameChangeTooFrequent = model.ErrUsernameChangeTooFrequent
+	ErrInvalidToken              = jwt.ErrInvalidToken
+	ErrTokenExpired              = jwt.ErrTokenExpired
+
+	ErrJWTKeyNotFound = jwt.ErrKeyNotFound
+	ErrJWTKeyExists   = jwt.ErrKeyExists
+	ErrJWTKeyInUse    = jwt.ErrKeyInUse
+
+	ErrInvalidRefreshToken = model.ErrInvalidRefreshToken
+	ErrSessionNotFound     = model.ErrSessionNotFound
+	ErrDeviceNotFound      = model.ErrDeviceNotFound
+
+	ErrTwoFactorNotEnabled     = model.ErrTwoFactorNotEnabled
+	ErrTwoFactorAlreadyEnabled = model.ErrTwoFactorAlreadyEnabled
+	ErrInvalidTwoFactorCode    = model.ErrInvalidTwoFactorCode
+
+	ErrLoginLocked     = model.ErrLoginLocked
+	ErrCaptchaRequired = model.ErrCaptchaRequired
+	ErrInvalidCaptcha  = model.ErrInvalidCaptcha
+
+	ErrCodeRateLimited     = model.ErrCodeRateLimited
+	ErrTooManyCodeAttempts = model.ErrTooManyCodeAttempts
+
+	ErrRoleNotFound     = model.ErrRoleNotFound
+	ErrRoleExists       = model.ErrRoleExists
+	ErrPermissionDenied = model.ErrPermissionDenied
+
+	ErrOAuthProviderNotFound = model.ErrOAuthProviderNotFound
+	ErrOAuthFailed           = model.ErrOAuthFailed
+	ErrOAuthAlreadyBound     = model.ErrOAuthAlreadyBound
+	ErrOAuthNotBound         = model.ErrOAuthNotBound
+)
+
+// 表情符号策略
+const (
+	EmojiAllow = service.EmojiAllow
+	EmojiDeny  = service.EmojiDeny
+)
+
+// 隐私可见范围
+const (
+	VisibilityEveryone = model.VisibilityEveryone
+	VisibilityContacts = model.VisibilityContacts
+	VisibilityNobody   = model.VisibilityNobody
+)
+
+// 用户导出格式
+const (
+	ExportFormatCSV  = model.ExportFormatCSV
+	ExportFormatJSON = model.ExportFormatJSON
+)
+
+// JWT 签名算法
+const (
+	JWTAlgHS256 = jwt.AlgHS256
+	JWTAlgRS256 = jwt.AlgRS256
+	JWTAlgEdDSA = jwt.AlgEdDSA
+)
+
+// SlowQuery 慢 SQL 信息，SlowQueryFunc 慢 SQL 回调，见 observability/gormmetrics
+type (
+	SlowQuery     = gormmetrics.SlowQuery
+	SlowQueryFunc = gormmetrics.SlowQueryFunc
 )
 
 // Config 用户模块配置
 type Config struct {
-	DB            *gorm.DB       // 数据库连接
-	JWTSecret     string         // JWT密钥
-	TokenDuration time.Duration  // Token有效期，默认7天
+	DB                     *gorm.DB              // 数据库连接
+	TenantID               string                // 租户 ID（可选），最长 64 个字符，设置后用户、验证码和令牌仅在该租户内有效，多个租户可共用同一数据库
+	JWTSecret              string                // JWT密钥，设置 JWTKeys 时可选，仅用于验证不含 kid 的旧令牌
+	JWTKeys                []JWTKey              // JWT 签名密钥（可选），支持 HS256、RS256、EdDSA，按令牌头部 kid 选择验证密钥
+	JWTSigningKeyID        string                // 签发令牌使用的密钥 ID，默认 JWTKeys 中第一个含私钥的密钥
+	JWTIssuer              string                // 访问令牌签发方 iss（可选），设置后验证时要求一致
+	JWTAudience            string                // 访问令牌受众 aud（可选），设置后验证时要求包含
+	ClaimsEnricher         ClaimsEnricher        // 签发访问令牌时添加自定义 claims（可选），写入 JWTClaims.Custom
+	TokenDuration          time.Duration         // 访问令牌有效期，默认2小时
+	RefreshTokenDuration   time.Duration         // 刷新令牌有效期，默认30天，每次刷新后重新计算
+	TablePrefix            string                // 表名前缀（可选），如 "app_" -> "app_user_users"
+	TableNames             map[string]string     // 按默认表名覆盖表名（可选），如 {"user_users": "accounts"}，优先于 TablePrefix
+	DisableAutoMigrate     bool                  // 禁止创建服务时自动执行数据库迁移（可选），禁止后需显式调用 Migrate
+	OAuthProviders         []OAuthProvider       // 第三方登录提供方（可选），如 NewWeChatOAuthProvider
+	SessionStore           SessionStore          // 会话存储（可选），默认使用数据库 user_sessions 表，可替换为 NewRedisSessionStore
+	TOTPIssuer             string                // 两步验证在验证器 App 中显示的应用名（可选），默认 "go-base"
+	LoginProtection        *LoginProtection      // 登录保护配置（可选），nil 时使用默认值
+	CodeProtection         *CodeProtection       // 验证码防刷配置（可选），nil 时使用默认值
+	CaptchaVerifier        CaptchaVerifier       // 人机验证（可选），如 NewRecaptchaVerifier，设置后发送验证码和密码注册需提供 CaptchaToken
+	PasswordPolicy         *PasswordPolicy       // 密码强度策略（可选），nil 时仅限制长度 6-20
+	PhonePolicy            *PhonePolicy          // 手机号策略（可选），nil 时未带国家码的号码按中国大陆解析，不限制地区
+	ContentPolicy          *ContentPolicy        // 昵称和个性签名校验策略（可选），nil 时仅按字符数限制长度
+	PasswordHashing        *PasswordHashing      // 密码哈希配置（可选），nil 时使用 bcrypt 默认成本，算法或参数变更后登录时自动升级已有哈希
+	DeletionGracePeriod    time.Duration         // 注销冷静期，默认15天，期间登录自动恢复账号
+	UsernameChangeCooldown time.Duration         // 两次修改用户名的最小间隔，默认30天，小于0表示不限制
+	AccountDeletionHooks   []AccountDeletionHook // 账号注销钩子（可选），用于清理 IM、存储等模块中的用户数据
+	SecurityEventHandler   SecurityEventHandler  // 安全事件回调（可选），如登录失败、新设备登录时提醒用户
+	UserEventHandlers      []UserEventHandler    // 用户生命周期事件回调（可选），如 NewWebhookEventHandler，也可在创建后通过 OnUserEvent 注册
+	ContactChecker         ContactChecker        // 联系人判断（可选），用于隐私设置中的 contacts 可见范围，nil 时仅自己可见
+	UserCache              UserCache             // 用户缓存（可选），如 NewLRUUserCache、NewRedisUserCache，默认每次查询数据库
+	RateLimitStore         RateLimitStore        // 登录失败和验证码发送计数存储（可选），默认进程内存，多实例部署需使用 NewRedisRateLimitStore
+	Metrics                observability.Metrics // 登录、刷新令牌、发送验证码的监控指标（可选），如 observability.NewPrometheus()
+	Logger                 golog.Logger          // 日志（可选），记录登录结果和写入登录历史等不影响请求结果的失败，默认写入标准库 log，可与 im、storage 模块共用
+	SlowQueryThreshold     time.Duration         // 慢 SQL 阈值（可选），默认 200ms，小于 0 时不回调 SlowQueryFunc
+	SlowQueryFunc          SlowQueryFunc         // 慢 SQL 回调（可选），附带发起查询的仓库方法；设置 Metrics 或 SlowQueryFunc 时按方法记录 user_db_query_duration_seconds
+	IDGenerator            idgen.Generator       // 用户 ID 生成器（可选），如 idgen.NewSnowflake(node)，默认数据库自增 ID，多库写入或分库分表时使用
 }
 
 // Service 用户服务接口
 type Service interface {
 	// 认证相关
-	Register(req *RegisterRequest) (*User, string, error)
-	Login(req *LoginRequest) (*User, string, error)
-	LoginWithCode(phone, code string) (*User, string, error)
+	Register(req *RegisterRequest) (*User, *TokenPair, error)
+	Login(req *LoginRequest) (*User, *TokenPair, error)
+	LoginWithCode(phone, code string) (*User, *TokenPair, error)
 	ChangePassword(userID int64, req *ChangePasswordRequest) error
 	ResetPassword(req *ResetPasswordRequest) error
 
+	// 两步验证相关（登录返回 TokenPair.Requires2FA 时调用 VerifyTwoFactor 完成登录）
+	VerifyTwoFactor(challengeToken, code string) (*User, *TokenPair, error)
+	SetupTOTP(userID int64) (*TOTPSetup, error)
+	EnableTOTP(userID int64, code string) ([]string, error)
+	DisableTOTP(userID int64, code string) error
+	RegenerateRecoveryCodes(userID int64, code string) ([]string, error)
+	IsTwoFactorEnabled(userID int64) (bool, error)
+
+	// 第三方登录相关
+	LoginWithOAuth(provider, code string) (*User, *TokenPair, error)
+	BindOAuth(userID int64, provider, code string) (*OAuthBinding, error)
+	UnbindOAuth(userID int64, provider string) error
+	GetOAuthBindings(userID int64) ([]*OAuthBinding, error)
+
 	// 验证码相关
 	SendVerificationCode(req *SendCodeRequest) (string, error)
 	VerifyCode(req *VerifyCodeRequest) error
@@ -65,154 +277,837 @@ type Service interface {
 	// 用户信息相关
 	GetUserByID(id int64) (*User, error)
 	GetUserProfile(id int64) (*UserProfile, error)
+	GetUserProfileForViewer(viewerID, id int64) (*UserProfile, error)
 	UpdateProfile(userID int64, req *UpdateProfileRequest) (*User, error)
+	ChangePhone(userID int64, req *ChangePhoneRequest) error
+	ChangeUsername(userID int64, newUsername string) error
+	GetPrivacySettings(userID int64) (*PrivacySettings, error)
+	UpdatePrivacySettings(userID int64, req *UpdatePrivacyRequest) (*PrivacySettings, error)
+	UpdateLastSeen(userID int64) error
 
-	// JWT相关
+	// 令牌相关
 	ValidateToken(token string) (*JWTClaims, error)
-	RefreshToken(token string) (string, error)
+	RefreshToken(refreshToken string) (*TokenPair, error)
+	Logout(refreshToken string) error
+	RevokeAllSessions(userID int64) error
+
+	// 会话相关
+	ListSessions(userID int64) ([]*Session, error)
+	RevokeSession(userID int64, sessionID string) error
+
+	// 登录历史相关
+	GetLoginHistory(userID int64, page, pageSize int) (*LoginHistory, error)
+
+	// 设备相关（推送令牌供 IM 离线推送等使用）
+	RegisterDevice(userID int64, req *RegisterDeviceRequest) (*Device, error)
+	UnregisterDevice(userID int64, deviceID string) error
+	ListDevices(userID int64) ([]*Device, error)
+	TouchDevice(userID int64, deviceID string) error
+	RemovePushToken(pushToken string) error
+
+	// JWT 密钥相关（多实例部署时需在每个实例上执行，轮换时先全部 AddJWTKey 再 SetJWTSigningKey）
+	AddJWTKey(key JWTKey) error
+	SetJWTSigningKey(keyID string) error
+	RemoveJWTKey(keyID string) error
+	JWKS() *JWKS
+
+	// 角色权限相关（角色变更在刷新令牌后写入访问令牌）
+	CreateRole(name, description string, permissions []string) (*Role, error)
+	DeleteRole(name string) error
+	ListRoles() ([]*Role, error)
+	SetRolePermissions(name string, permissions []string) error
+	AssignRole(userID int64, roleName string) error
+	RevokeRole(userID int64, roleName string) error
+	GetUserRoles(userID int64) ([]*Role, error)
+	HasPermission(userID int64, permission string) (bool, error)
+
+	// 账号注销相关
+	DeactivateAccount(userID int64) error
+	DeleteAccount(userID int64) error
+	PurgeDeactivatedAccounts() (int, error)
+
+	// 用户管理相关（管理后台使用，调用方需自行校验权限）
+	ListUsers(req *ListUsersRequest) (*UserList, error)
+	DisableUser(userID int64) error
+	EnableUser(userID int64) error
+	ForcePasswordReset(userID int64) error
+	DeleteUser(userID int64) error
+	ImportUsers(users []UserImport) (*ImportResult, error)
+	ExportUsers(req *ExportUsersRequest, w io.Writer) error
+
+	// 事件相关
+	OnUserEvent(handler UserEventHandler)
+
+	// 数据库迁移相关
+	Migrate() error
+	MigrationStatus() ([]*MigrationStatus, error)
+
+	// WithContext 返回绑定 ctx 的服务实例，数据库操作随 ctx 超时或取消而中止
+	WithContext(ctx context.Context) Service
 }
 
 // userService 用户服务实现
 type userService struct {
-	authService *service.AuthService
-	userService *service.UserService
-	jwtManager  *jwt.JWTManager
+	authService  *service.AuthService
+	userService  *service.UserService
+	oauthSvc     *service.OAuthService
+	tokenSvc     *service.TokenService
+	twoFactorSvc *service.TwoFactorService
+	rbacSvc      *service.RBACService
+	adminSvc     *service.AdminService
+	transferSvc  *service.TransferService
+	accountSvc   *service.AccountService
+	loginGuard   *service.LoginGuard
+	historySvc   *service.LoginHistoryService
+	deviceSvc    *service.DeviceService
+	events       *service.UserEvents
+	jwtManager   *jwt.JWTManager
+	migrator     *migrate.Migrator
+	metrics      observability.Metrics
+	logger       golog.Logger
+	ctx          context.Context
 }
 
 // NewService 创建用户服务实例
 func NewService(config *Config) (Service, error) {
 	if config == nil {
-		return nil, fmt.Errorf("config is required")
+		return nil, fmt.Errorf("%w: config is required", ErrInvalidConfig)
 	}
 
 	if config.DB == nil {
-		return nil, fmt.Errorf("database connection is required")
+		return nil, fmt.Errorf("%w: database connection is required", ErrInvalidConfig)
+	}
+
+	if config.JWTSecret == "" && len(config.JWTKeys) == 0 {
+		return nil, fmt.Errorf("%w: JWT secret or keys are required", ErrInvalidConfig)
 	}
 
-	if config.JWTSecret == "" {
-		return nil, fmt.Errorf("JWT secret is required")
+	if len(config.TenantID) > 64 {
+		return nil, fmt.Errorf("%w: tenant id must be at most 64 characters", ErrInvalidConfig)
 	}
 
-	// 设置默认token有效期
+	// 设置默认令牌有效期
 	if config.TokenDuration == 0 {
-		config.TokenDuration = 7 * 24 * time.Hour // 7天
+		config.TokenDuration = 2 * time.Hour
+	}
+	if config.RefreshTokenDuration == 0 {
+		config.RefreshTokenDuration = 30 * 24 * time.Hour // 30天
+	}
+	if config.DeletionGracePeriod == 0 {
+		config.DeletionGracePeriod = 15 * 24 * time.Hour // 15天
+	}
+	if config.UsernameChangeCooldown == 0 {
+		config.UsernameChangeCooldown = 30 * 24 * time.Hour // 30天
+	}
+	if config.TOTPIssuer == "" {
+		config.TOTPIssuer = "go-base"
+	}
+
+	// SQL 耗时指标和慢查询回调
+	if config.Metrics != nil || config.SlowQueryFunc != nil {
+		err := gormmetrics.Register(config.DB, gormmetrics.Config{
+			Module:        "user",
+			Package:       "github.com/bbadbeef/go-base/user/",
+			Metrics:       config.Metrics,
+			SlowThreshold: config.SlowQueryThreshold,
+			OnSlowQuery:   config.SlowQueryFunc,
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// 初始化仓库层
-	userRepo := repository.NewUserRepository(config.DB)
-	codeRepo := repository.NewCodeRepository(config.DB)
+	namer := &repository.TableNamer{
+		Prefix:    config.TablePrefix,
+		Overrides: config.TableNames,
+	}
+	userRepo := repository.NewUserRepository(config.DB, namer)
+	userRepo.SetCache(config.UserCache)
+	userRepo.SetTenant(config.TenantID)
+	userRepo.SetIDGenerator(config.IDGenerator)
+	codeRepo := repository.NewCodeRepository(config.DB, namer)
+	codeRepo.SetTenant(config.TenantID)
+	oauthRepo := repository.NewOAuthRepository(config.DB, namer)
+	tokenRepo := repository.NewRefreshTokenRepository(config.DB, namer)
+	twoFactorRepo := repository.NewTwoFactorRepository(config.DB, namer)
+	roleRepo := repository.NewRoleRepository(config.DB, namer)
+	historyRepo := repository.NewLoginHistoryRepository(config.DB, namer)
+	deviceRepo := repository.NewDeviceRepository(config.DB, namer)
+	sessionStore := config.SessionStore
+	if sessionStore == nil {
+		sessionStore = repository.NewSessionRepository(config.DB, namer)
+	}
+
+	// 自动执行数据库迁移
+	migrator := repository.NewMigrator(config.DB, namer)
+	if !config.DisableAutoMigrate {
+		if err := migrator.Migrate(context.Background()); err != nil {
+			return nil, err
+		}
+	}
 
-	// 自动创建表
-	if err := userRepo.InitTable(); err != nil {
-		return nil, fmt.Errorf("init user table failed: %w", err)
+	// 初始化登录保护、验证码防刷、密码策略和内容策略
+	rateLimitStore := config.RateLimitStore
+	if rateLimitStore == nil {
+		rateLimitStore = ratelimit.NewMemoryStore()
+	}
+	var protection LoginProtection
+	if config.LoginProtection != nil {
+		protection = *config.LoginProtection
 	}
-	if err := codeRepo.InitTable(); err != nil {
-		return nil, fmt.Errorf("init code table failed: %w", err)
+	loginGuard := service.NewLoginGuard(rateLimitStore, protection)
+	var codeProtection CodeProtection
+	if config.CodeProtection != nil {
+		codeProtection = *config.CodeProtection
+	}
+	codeGuard := service.NewCodeGuard(rateLimitStore, codeProtection)
+	captchaGuard := service.NewCaptchaGuard(config.CaptchaVerifier)
+	var passwordPolicy PasswordPolicy
+	if config.PasswordPolicy != nil {
+		passwordPolicy = *config.PasswordPolicy
+	}
+	passwords := service.NewPasswordValidator(passwordPolicy)
+	var phonePolicy PhonePolicy
+	if config.PhonePolicy != nil {
+		phonePolicy = *config.PhonePolicy
+	}
+	phones, err := service.NewPhoneValidator(phonePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	var contentPolicy ContentPolicy
+	if config.ContentPolicy != nil {
+		contentPolicy = *config.ContentPolicy
+	}
+	content := service.NewContentValidator(contentPolicy)
+	var passwordHashing PasswordHashing
+	if config.PasswordHashing != nil {
+		passwordHashing = *config.PasswordHashing
+	}
+	hasher, err := password.NewHasher(passwordHashing)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 	}
 
 	// 初始化服务层
-	authService := service.NewAuthService(userRepo, codeRepo)
-	userSvc := service.NewUserService(userRepo)
+	authService := service.NewAuthService(userRepo, codeRepo, codeGuard, captchaGuard, passwords, phones, hasher)
+	userSvc := service.NewUserService(userRepo, config.UsernameChangeCooldown, config.ContactChecker, content)
+	oauthSvc := service.NewOAuthService(userRepo, oauthRepo, authService, config.OAuthProviders)
+	rbacSvc := service.NewRBACService(userRepo, roleRepo)
+	historySvc := service.NewLoginHistoryService(userRepo, historyRepo, phones, config.SecurityEventHandler)
+	deviceSvc := service.NewDeviceService(userRepo, deviceRepo)
+	events := service.NewUserEvents(config.TenantID, config.UserEventHandlers)
 
 	// 初始化JWT管理器
-	jwtMgr := jwt.NewJWTManager(config.JWTSecret, config.TokenDuration)
+	jwtMgr, err := newJWTManager(config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	jwtMgr.SetIssuer(config.JWTIssuer, config.JWTAudience)
+	jwtMgr.SetTenant(config.TenantID)
+	tokenSvc := service.NewTokenService(userRepo, tokenRepo, roleRepo, sessionStore, jwtMgr, config.RefreshTokenDuration, config.ClaimsEnricher)
+	adminSvc := service.NewAdminService(userRepo, tokenSvc)
+	transferSvc := service.NewTransferService(userRepo, authService, content)
+	accountSvc := service.NewAccountService(userRepo, tokenSvc, config.DeletionGracePeriod, config.AccountDeletionHooks)
+	twoFactorSvc := service.NewTwoFactorService(userRepo, twoFactorRepo, jwtMgr, config.TOTPIssuer)
 
 	return &userService{
-		authService: authService,
-		userService: userSvc,
-		jwtManager:  jwtMgr,
+		authService:  authService,
+		userService:  userSvc,
+		oauthSvc:     oauthSvc,
+		tokenSvc:     tokenSvc,
+		twoFactorSvc: twoFactorSvc,
+		rbacSvc:      rbacSvc,
+		adminSvc:     adminSvc,
+		transferSvc:  transferSvc,
+		accountSvc:   accountSvc,
+		loginGuard:   loginGuard,
+		historySvc:   historySvc,
+		deviceSvc:    deviceSvc,
+		events:       events,
+		jwtManager:   jwtMgr,
+		migrator:     migrator,
+		metrics:      observability.OrNop(config.Metrics),
+		logger:       golog.OrStd(config.Logger),
+		ctx:          context.Background(),
 	}, nil
 }
 
+// newJWTManager 按配置创建JWT管理器，未设置 JWTKeys 时使用 JWTSecret 签发 HS256 令牌
+func newJWTManager(config *Config) (*jwt.JWTManager, error) {
+	if len(config.JWTKeys) == 0 {
+		return jwt.NewJWTManager(config.JWTSecret, config.TokenDuration), nil
+	}
+
+	keys := make([]JWTKey, 0, len(config.JWTKeys)+1)
+	if config.JWTSecret != "" {
+		keys = append(keys, JWTKey{Algorithm: JWTAlgHS256, Secret: []byte(config.JWTSecret)})
+	}
+	signingKeyID := config.JWTSigningKeyID
+	for i, key := range config.JWTKeys {
+		if key.ID == "" {
+			return nil, fmt.Errorf("JWT key %d: id is required", i)
+		}
+		if signingKeyID == "" && (key.PrivateKey != nil || len(key.Secret) > 0) {
+			signingKeyID = key.ID
+		}
+		keys = append(keys, key)
+	}
+	if signingKeyID == "" {
+		return nil, errors.New("JWT signing key is required")
+	}
+	return jwt.NewJWTManagerWithKeys(keys, signingKeyID, config.TokenDuration)
+}
+
 // Register 用户注册
-func (s *userService) Register(req *RegisterRequest) (*User, string, error) {
-	user, err := s.authService.Register(req)
+func (s *userService) Register(req *RegisterRequest) (*User, *TokenPair, error) {
+	user, err := s.authService.Register(s.ctx, req)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	// 生成token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Username, user.Phone)
+	// 签发令牌对
+	tokens, err := s.tokenSvc.Issue(s.ctx, user)
 	if err != nil {
-		return nil, "", fmt.Errorf("generate token failed: %w", err)
+		return nil, nil, err
+	}
+	s.recordLogin(user.ID, user.Phone, LoginMethodRegister, nil)
+
+	method := LoginMethodCode
+	if req.Password != "" {
+		method = LoginMethodPassword
 	}
+	s.emit(&UserEvent{Type: UserEventRegistered, UserID: user.ID, Method: method, User: user})
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-// Login 密码登录
-func (s *userService) Login(req *LoginRequest) (*User, string, error) {
-	user, err := s.authService.Login(req)
-	if err != nil {
-		return nil, "", err
+// Login 密码登录，登录失败次数较多时需在 req.CaptchaToken 提交人机验证令牌
+func (s *userService) Login(req *LoginRequest) (*User, *TokenPair, error) {
+	ctx := s.ctx
+	if req.CaptchaToken != "" {
+		passed, err := s.authService.VerifyCaptcha(ctx, req.CaptchaToken)
+		if err != nil {
+			return nil, nil, err
+		}
+		if passed {
+			client := model.ClientInfoFrom(ctx)
+			client.CaptchaPassed = true
+			ctx = model.WithClientInfo(ctx, client)
+		}
+	}
+	// 手机号按 E.164 格式计数，避免通过变换号码格式绕过失败锁定
+	account := s.authService.NormalizeAccount(req.Account)
+	if err := s.loginGuard.Check(ctx, account); err != nil {
+		return nil, nil, err
 	}
 
-	// 生成token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Username, user.Phone)
+	method := LoginMethodPassword
+	if req.Code != "" {
+		method = LoginMethodCode
+	}
+	user, err := s.authService.Login(s.ctx, req)
+	if recordErr := s.loginGuard.Record(s.ctx, account, err); recordErr != nil && err == nil {
+		err = recordErr
+	}
 	if err != nil {
-		return nil, "", fmt.Errorf("generate token failed: %w", err)
+		s.recordLogin(0, account, method, err)
+		return nil, nil, err
 	}
 
-	return user, token, nil
+	return s.completeLogin(user, account, method)
 }
 
 // LoginWithCode 验证码登录
-func (s *userService) LoginWithCode(phone, code string) (*User, string, error) {
-	user, err := s.authService.LoginWithCode(phone, code)
+func (s *userService) LoginWithCode(phone, code string) (*User, *TokenPair, error) {
+	phone = s.authService.NormalizeAccount(phone)
+	if err := s.loginGuard.Check(s.ctx, phone); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.authService.LoginWithCode(s.ctx, phone, code)
+	if recordErr := s.loginGuard.Record(s.ctx, phone, err); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	if err != nil {
+		s.recordLogin(0, phone, LoginMethodCode, err)
+		return nil, nil, err
+	}
+
+	return s.completeLogin(user, phone, LoginMethodCode)
+}
+
+// completeLogin 登录认证通过后签发令牌并记录登录，开启两步验证的用户只返回挑战令牌
+func (s *userService) completeLogin(user *User, account, method string) (*User, *TokenPair, error) {
+	enabled, err := s.twoFactorSvc.Enabled(s.ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if enabled {
+		challenge, err := s.twoFactorSvc.Challenge(user.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("generate challenge token failed: %w", err)
+		}
+		return user, &TokenPair{Requires2FA: true, ChallengeToken: challenge}, nil
+	}
+
+	// 注销冷静期内登录，恢复账号
+	if err := s.accountSvc.Reactivate(s.ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	// 签发令牌对
+	tokens, err := s.tokenSvc.Issue(s.ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.recordLogin(user.ID, account, method, nil)
+	s.emit(&UserEvent{Type: UserEventLogin, UserID: user.ID, Method: method, User: user})
+
+	return user, tokens, nil
+}
+
+// emit 触发用户生命周期事件
+func (s *userService) emit(event *UserEvent) {
+	s.events.Emit(s.ctx, event)
+}
+
+// emitProfileUpdated 触发资料更新事件，user 为空时重新查询
+func (s *userService) emitProfileUpdated(userID int64, user *User, fields ...string) {
+	if user == nil {
+		user, _ = s.userService.GetUserByID(s.ctx, userID)
+	}
+	s.emit(&UserEvent{Type: UserEventProfileUpdated, UserID: userID, Fields: fields, User: user})
+}
+
+// recordLogin 记录登录历史、日志和 user_logins_total{method, result} 指标，登录成功时更新最近活跃时间和已注册设备的活跃时间
+// 写入失败不影响登录结果，只记录错误日志；日志不含账号，避免泄露手机号
+func (s *userService) recordLogin(userID int64, account, method string, loginErr error) {
+	s.metrics.Add("user_logins_total", 1, observability.L("method", method), observability.L("result", observability.Result(loginErr)))
+	logger := golog.WithFields(golog.FromContext(s.logger, s.ctx), golog.Fields{"user_id": userID, "method": method})
+	if loginErr != nil {
+		golog.WithField(logger, "err", loginErr).Info("user: login failed")
+		if err := s.historySvc.RecordFailure(s.ctx, userID, account, method, loginErr); err != nil {
+			golog.WithField(logger, "err", err).Error("user: record login history failed")
+		}
+		return
+	}
+	logger.Info("user: login succeeded")
+	if err := s.historySvc.RecordSuccess(s.ctx, userID, account, method); err != nil {
+		golog.WithField(logger, "err", err).Error("user: record login history failed")
+	}
+	if err := s.userService.UpdateLastSeen(s.ctx, userID); err != nil {
+		golog.WithField(logger, "err", err).Error("user: update last seen failed")
+	}
+	if deviceID := model.ClientInfoFrom(s.ctx).DeviceID; deviceID != "" {
+		if err := s.deviceSvc.Touch(s.ctx, userID, deviceID); err != nil {
+			golog.WithFields(logger, golog.Fields{"device_id": deviceID, "err": err}).Error("user: touch device failed")
+		}
+	}
+}
+
+// VerifyTwoFactor 使用挑战令牌和验证码（或恢复码）完成两步验证登录
+func (s *userService) VerifyTwoFactor(challengeToken, code string) (*User, *TokenPair, error) {
+	userID, err := s.twoFactorSvc.ParseChallenge(challengeToken)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	// 两步验证码同样计入失败次数，防止暴力破解
+	account := fmt.Sprintf("2fa:%d", userID)
+	if err := s.loginGuard.Check(s.ctx, account); err != nil {
+		return nil, nil, err
+	}
+	err = s.twoFactorSvc.Verify(s.ctx, userID, code)
+	if recordErr := s.loginGuard.Record(s.ctx, account, err); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	if err != nil {
+		s.recordLogin(userID, "", LoginMethodTwoFactor, err)
+		return nil, nil, err
+	}
+
+	user, err := s.userService.GetUserByID(s.ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.CanLogin() {
+		s.recordLogin(userID, user.Username, LoginMethodTwoFactor, ErrUserDisabled)
+		return nil, nil, ErrUserDisabled
+	}
+	if err := s.accountSvc.Reactivate(s.ctx, user); err != nil {
+		return nil, nil, err
 	}
 
-	// 生成token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Username, user.Phone)
+	tokens, err := s.tokenSvc.Issue(s.ctx, user)
 	if err != nil {
-		return nil, "", fmt.Errorf("generate token failed: %w", err)
+		return nil, nil, err
 	}
+	s.recordLogin(userID, user.Username, LoginMethodTwoFactor, nil)
+	s.emit(&UserEvent{Type: UserEventLogin, UserID: userID, Method: LoginMethodTwoFactor, User: user})
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-// ChangePassword 修改密码
+// SetupTOTP 生成两步验证密钥和二维码地址，调用 EnableTOTP 校验后生效
+func (s *userService) SetupTOTP(userID int64) (*TOTPSetup, error) {
+	return s.twoFactorSvc.Setup(s.ctx, userID)
+}
+
+// EnableTOTP 校验验证码并开启两步验证，返回恢复码（仅返回一次，需提示用户保存）
+func (s *userService) EnableTOTP(userID int64, code string) ([]string, error) {
+	return s.twoFactorSvc.Enable(s.ctx, userID, code)
+}
+
+// DisableTOTP 校验验证码（或恢复码）后关闭两步验证
+func (s *userService) DisableTOTP(userID int64, code string) error {
+	return s.twoFactorSvc.Disable(s.ctx, userID, code)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部失效
+func (s *userService) RegenerateRecoveryCodes(userID int64, code string) ([]string, error) {
+	return s.twoFactorSvc.RegenerateRecoveryCodes(s.ctx, userID, code)
+}
+
+// IsTwoFactorEnabled 用户是否已开启两步验证
+func (s *userService) IsTwoFactorEnabled(userID int64) (bool, error) {
+	return s.twoFactorSvc.Enabled(s.ctx, userID)
+}
+
+// ChangePassword 修改密码，并吊销除 req.SessionID 以外的全部会话
 func (s *userService) ChangePassword(userID int64, req *ChangePasswordRequest) error {
-	return s.authService.ChangePassword(userID, req.OldPassword, req.NewPassword)
+	// 旧密码错误同样计入失败次数，防止盗用的访问令牌暴力破解当前密码
+	account := fmt.Sprintf("pwd:%d", userID)
+	if err := s.loginGuard.Check(s.ctx, account); err != nil {
+		return err
+	}
+	err := s.authService.ChangePassword(s.ctx, userID, req.OldPassword, req.NewPassword)
+	if recordErr := s.loginGuard.Record(s.ctx, account, err); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.tokenSvc.RevokeOthers(s.ctx, userID, req.SessionID); err != nil {
+		return err
+	}
+	s.emit(&UserEvent{Type: UserEventPasswordChanged, UserID: userID, Method: PasswordChangeByUser})
+	return nil
 }
 
-// ResetPassword 重置密码
+// ResetPassword 重置密码，并吊销全部会话
 func (s *userService) ResetPassword(req *ResetPasswordRequest) error {
-	return s.authService.ResetPassword(req)
+	userID, err := s.authService.ResetPassword(s.ctx, req)
+	if err != nil {
+		return err
+	}
+	if err := s.tokenSvc.RevokeAll(s.ctx, userID); err != nil {
+		return err
+	}
+	s.emit(&UserEvent{Type: UserEventPasswordChanged, UserID: userID, Method: PasswordChangeByReset})
+	return nil
+}
+
+// LoginWithOAuth 第三方登录，首次登录自动创建用户
+func (s *userService) LoginWithOAuth(provider, code string) (*User, *TokenPair, error) {
+	user, created, err := s.oauthSvc.Login(s.ctx, provider, code)
+	if err != nil {
+		s.recordLogin(0, provider, LoginMethodOAuth, err)
+		return nil, nil, err
+	}
+	if created {
+		s.emit(&UserEvent{Type: UserEventRegistered, UserID: user.ID, Method: LoginMethodOAuth, User: user})
+	}
+
+	return s.completeLogin(user, provider, LoginMethodOAuth)
+}
+
+// BindOAuth 为已登录用户绑定第三方账号
+func (s *userService) BindOAuth(userID int64, provider, code string) (*OAuthBinding, error) {
+	return s.oauthSvc.Bind(s.ctx, userID, provider, code)
+}
+
+// UnbindOAuth 解除第三方账号绑定
+func (s *userService) UnbindOAuth(userID int64, provider string) error {
+	return s.oauthSvc.Unbind(s.ctx, userID, provider)
+}
+
+// GetOAuthBindings 获取用户的第三方账号绑定
+func (s *userService) GetOAuthBindings(userID int64) ([]*OAuthBinding, error) {
+	return s.oauthSvc.GetBindings(s.ctx, userID)
 }
 
-// SendVerificationCode 发送验证码
+// SendVerificationCode 发送验证码，配置 Config.CaptchaVerifier 时需提供 req.CaptchaToken
 func (s *userService) SendVerificationCode(req *SendCodeRequest) (string, error) {
-	return s.authService.SendVerificationCode(req.Phone, req.Type)
+	code, err := s.authService.SendVerificationCode(s.ctx, req.Phone, req.Type, req.CaptchaToken)
+	s.metrics.Add("user_verification_codes_total", 1, observability.L("type", strconv.Itoa(req.Type)), observability.L("result", observability.Result(err)))
+	return code, err
 }
 
 // VerifyCode 验证验证码
 func (s *userService) VerifyCode(req *VerifyCodeRequest) error {
-	return s.authService.VerifyCode(req.Phone, req.Code, req.Type)
+	return s.authService.VerifyCode(s.ctx, req.Phone, req.Code, req.Type)
 }
 
 // GetUserByID 根据ID获取用户
 func (s *userService) GetUserByID(id int64) (*User, error) {
-	return s.userService.GetUserByID(id)
+	return s.userService.GetUserByID(s.ctx, id)
 }
 
-// GetUserProfile 获取用户公开信息
+// GetUserProfile 获取用户公开信息，手机号、生日、最近活跃时间仅在隐私设置为所有人可见时返回
 func (s *userService) GetUserProfile(id int64) (*UserProfile, error) {
-	return s.userService.GetUserProfile(id)
+	return s.userService.GetUserProfile(s.ctx, 0, id)
+}
+
+// GetUserProfileForViewer 获取 viewerID 可见的用户公开信息，按隐私设置和 Config.ContactChecker 判断可见范围
+func (s *userService) GetUserProfileForViewer(viewerID, id int64) (*UserProfile, error) {
+	return s.userService.GetUserProfile(s.ctx, viewerID, id)
 }
 
 // UpdateProfile 更新用户信息
 func (s *userService) UpdateProfile(userID int64, req *UpdateProfileRequest) (*User, error) {
-	return s.userService.UpdateProfile(userID, req)
+	user, err := s.userService.UpdateProfile(s.ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	s.emitProfileUpdated(userID, user, req.Fields()...)
+	return user, nil
+}
+
+// ChangePhone 更换手机号，需先向新手机号发送 CodeTypeChangePhone 验证码
+func (s *userService) ChangePhone(userID int64, req *ChangePhoneRequest) error {
+	if err := s.authService.ChangePhone(s.ctx, userID, req.NewPhone, req.Code); err != nil {
+		return err
+	}
+	s.emitProfileUpdated(userID, nil, "phone")
+	return nil
+}
+
+// ChangeUsername 修改用户名，两次修改需间隔 Config.UsernameChangeCooldown
+func (s *userService) ChangeUsername(userID int64, newUsername string) error {
+	changed, err := s.userService.ChangeUsername(s.ctx, userID, newUsername)
+	if err != nil || !changed {
+		return err
+	}
+	s.emitProfileUpdated(userID, nil, "username")
+	return nil
+}
+
+// GetPrivacySettings 获取隐私设置
+func (s *userService) GetPrivacySettings(userID int64) (*PrivacySettings, error) {
+	return s.userService.GetPrivacy(s.ctx, userID)
+}
+
+// UpdatePrivacySettings 更新隐私设置，可见范围为 everyone、contacts 或 nobody
+func (s *userService) UpdatePrivacySettings(userID int64, req *UpdatePrivacyRequest) (*PrivacySettings, error) {
+	return s.userService.UpdatePrivacy(s.ctx, userID, req)
+}
+
+// UpdateLastSeen 更新最近活跃时间，登录和 TouchDevice 时自动更新，也可在 IM 断开连接等场景调用
+func (s *userService) UpdateLastSeen(userID int64) error {
+	return s.userService.UpdateLastSeen(s.ctx, userID)
 }
 
-// ValidateToken 验证token
+// ValidateToken 验证访问令牌，所属会话已吊销时返回 ErrInvalidToken
 func (s *userService) ValidateToken(token string) (*JWTClaims, error) {
-	return s.jwtManager.ValidateToken(token)
+	return s.tokenSvc.Validate(s.ctx, token)
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌对（刷新令牌轮换，旧令牌失效）
+func (s *userService) RefreshToken(refreshToken string) (*TokenPair, error) {
+	tokens, err := s.tokenSvc.Refresh(s.ctx, refreshToken)
+	s.metrics.Add("user_token_refreshes_total", 1, observability.L("result", observability.Result(err)))
+	return tokens, err
+}
+
+// Logout 退出登录，吊销刷新令牌所在的会话
+func (s *userService) Logout(refreshToken string) error {
+	return s.tokenSvc.Revoke(s.ctx, refreshToken)
+}
+
+// RevokeAllSessions 吊销用户的全部会话，所有设备需重新登录
+func (s *userService) RevokeAllSessions(userID int64) error {
+	return s.tokenSvc.RevokeAll(s.ctx, userID)
+}
+
+// ListSessions 获取用户的有效会话（登录设备）
+func (s *userService) ListSessions(userID int64) ([]*Session, error) {
+	return s.tokenSvc.ListSessions(s.ctx, userID)
+}
+
+// RevokeSession 吊销用户的指定会话，该会话的访问令牌和刷新令牌立即失效
+func (s *userService) RevokeSession(userID int64, sessionID string) error {
+	return s.tokenSvc.RevokeUserSession(s.ctx, userID, sessionID)
+}
+
+// GetLoginHistory 分页查询用户的登录历史（含失败记录），按时间倒序，pageSize 默认 20，最大 100
+func (s *userService) GetLoginHistory(userID int64, page, pageSize int) (*LoginHistory, error) {
+	return s.historySvc.List(s.ctx, userID, page, pageSize)
+}
+
+// RegisterDevice 注册设备及推送令牌，设备已存在时更新；同一推送令牌只保留在最近注册的设备上
+func (s *userService) RegisterDevice(userID int64, req *RegisterDeviceRequest) (*Device, error) {
+	return s.deviceSvc.Register(s.ctx, userID, req)
+}
+
+// UnregisterDevice 注销设备（如退出登录时），之后不再向该设备推送
+func (s *userService) UnregisterDevice(userID int64, deviceID string) error {
+	return s.deviceSvc.Unregister(s.ctx, userID, deviceID)
+}
+
+// ListDevices 获取用户的全部设备，按最近活跃时间倒序
+func (s *userService) ListDevices(userID int64) ([]*Device, error) {
+	return s.deviceSvc.List(s.ctx, userID)
+}
+
+// TouchDevice 更新设备和用户的最近活跃时间，登录时会自动更新 ctx 中 DeviceID 对应的设备
+func (s *userService) TouchDevice(userID int64, deviceID string) error {
+	if err := s.deviceSvc.Touch(s.ctx, userID, deviceID); err != nil {
+		return err
+	}
+	return s.userService.UpdateLastSeen(s.ctx, userID)
+}
+
+// RemovePushToken 清除失效的推送令牌，推送服务返回令牌无效时调用
+func (s *userService) RemovePushToken(pushToken string) error {
+	return s.deviceSvc.RemovePushToken(s.ctx, pushToken)
+}
+
+// AddJWTKey 添加 JWT 密钥，添加后即可验证使用该密钥签发的令牌
+func (s *userService) AddJWTKey(key JWTKey) error {
+	return s.jwtManager.AddKey(key)
+}
+
+// SetJWTSigningKey 切换签发访问令牌使用的密钥，旧密钥签发的令牌在移除前仍然有效
+func (s *userService) SetJWTSigningKey(keyID string) error {
+	return s.jwtManager.SetSigningKey(keyID)
+}
+
+// RemoveJWTKey 移除 JWT 密钥，应在旧令牌全部过期（TokenDuration）后调用
+func (s *userService) RemoveJWTKey(keyID string) error {
+	return s.jwtManager.RemoveKey(keyID)
+}
+
+// JWKS 返回 RS256、EdDSA 密钥的公钥集合，供其他服务验证访问令牌
+func (s *userService) JWKS() *JWKS {
+	return s.jwtManager.JWKS()
+}
+
+// CreateRole 创建角色，permissions 如 "user:read"，"user:*" 匹配 user: 开头的全部权限，"*" 匹配全部权限
+func (s *userService) CreateRole(name, description string, permissions []string) (*Role, error) {
+	return s.rbacSvc.CreateRole(s.ctx, name, description, permissions)
+}
+
+// DeleteRole 删除角色，已分配该角色的用户同时失去该角色
+func (s *userService) DeleteRole(name string) error {
+	return s.rbacSvc.DeleteRole(s.ctx, name)
+}
+
+// ListRoles 获取全部角色
+func (s *userService) ListRoles() ([]*Role, error) {
+	return s.rbacSvc.ListRoles(s.ctx)
+}
+
+// SetRolePermissions 覆盖角色的权限
+func (s *userService) SetRolePermissions(name string, permissions []string) error {
+	return s.rbacSvc.SetRolePermissions(s.ctx, name, permissions)
+}
+
+// AssignRole 为用户分配角色
+func (s *userService) AssignRole(userID int64, roleName string) error {
+	return s.rbacSvc.AssignRole(s.ctx, userID, roleName)
+}
+
+// RevokeRole 撤销用户的角色
+func (s *userService) RevokeRole(userID int64, roleName string) error {
+	return s.rbacSvc.RevokeRole(s.ctx, userID, roleName)
+}
+
+// GetUserRoles 获取用户的全部角色
+func (s *userService) GetUserRoles(userID int64) ([]*Role, error) {
+	return s.rbacSvc.GetUserRoles(s.ctx, userID)
+}
+
+// HasPermission 用户是否拥有指定权限，实时查询数据库
+func (s *userService) HasPermission(userID int64, permission string) (bool, error) {
+	return s.rbacSvc.HasPermission(s.ctx, userID, permission)
+}
+
+// DeactivateAccount 申请注销账号，吊销全部会话并进入冷静期（Config.DeletionGracePeriod）
+// 冷静期内登录自动恢复账号，届满后由 PurgeDeactivatedAccounts 注销
+func (s *userService) DeactivateAccount(userID int64) error {
+	return s.accountSvc.Deactivate(s.ctx, userID)
+}
+
+// DeleteAccount 立即注销账号：调用注销钩子清理其他模块数据，匿名化个人信息，删除第三方绑定、令牌等
+// 用户记录保留（状态为 UserStatusDeleted），已发送的消息等仍可关联到该用户 ID
+func (s *userService) DeleteAccount(userID int64) error {
+	return s.accountSvc.Delete(s.ctx, userID)
+}
+
+// PurgeDeactivatedAccounts 注销冷静期已届满的账号，返回注销数量，需由应用定时调用
+func (s *userService) PurgeDeactivatedAccounts() (int, error) {
+	return s.accountSvc.PurgeExpired(s.ctx)
+}
+
+// ListUsers 按状态、注册时间、关键字分页查询用户
+func (s *userService) ListUsers(req *ListUsersRequest) (*UserList, error) {
+	return s.adminSvc.ListUsers(s.ctx, req)
+}
+
+// DisableUser 禁用用户，并吊销其全部会话
+func (s *userService) DisableUser(userID int64) error {
+	if err := s.adminSvc.DisableUser(s.ctx, userID); err != nil {
+		return err
+	}
+	s.emit(&UserEvent{Type: UserEventDisabled, UserID: userID})
+	return nil
+}
+
+// EnableUser 启用用户
+func (s *userService) EnableUser(userID int64) error {
+	return s.adminSvc.EnableUser(s.ctx, userID)
+}
+
+// ForcePasswordReset 要求用户通过验证码重置密码，并吊销其全部会话
+func (s *userService) ForcePasswordReset(userID int64) error {
+	return s.adminSvc.ForcePasswordReset(s.ctx, userID)
+}
+
+// DeleteUser 删除用户及其关联数据（第三方绑定、令牌、两步验证、角色、登录记录、设备）
+func (s *userService) DeleteUser(userID int64) error {
+	return s.adminSvc.DeleteUser(s.ctx, userID)
+}
+
+// ImportUsers 批量导入用户（单次最多 1000 条），单条失败不影响其他记录
+func (s *userService) ImportUsers(users []UserImport) (*ImportResult, error) {
+	return s.transferSvc.ImportUsers(s.ctx, users)
+}
+
+// ExportUsers 按条件导出用户，分批查询并流式写入 w
+func (s *userService) ExportUsers(req *ExportUsersRequest, w io.Writer) error {
+	return s.transferSvc.ExportUsers(s.ctx, req, w)
+}
+
+// OnUserEvent 注册用户生命周期事件回调，可在创建服务后调用，如 IM 模块创建后注册发送欢迎消息
+func (s *userService) OnUserEvent(handler UserEventHandler) {
+	s.events.Subscribe(handler)
+}
+
+// Migrate 执行所有未执行的数据库迁移
+func (s *userService) Migrate() error {
+	return s.migrator.Migrate(s.ctx)
+}
+
+// MigrationStatus 获取所有数据库迁移的执行状态
+func (s *userService) MigrationStatus() ([]*MigrationStatus, error) {
+	return s.migrator.Status(s.ctx)
 }
 
-// RefreshToken 刷新token
-func (s *userService) RefreshToken(token string) (string, error) {
-	return s.jwtManager.RefreshToken(token)
+// WithContext 返回绑定 ctx 的服务实例
+func (s *userService) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
 }